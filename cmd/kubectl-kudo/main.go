@@ -15,13 +15,26 @@
 package main
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
 )
 
 func main() {
-	if err := cmd.NewKudoctlCmd().Execute(); err != nil {
-		os.Exit(-1)
+	shutdown, err := tracing.Setup("kubectl-kudo")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Tracing is disabled: %v\n", err)
+	}
+
+	started := time.Now()
+	executed, err := cmd.NewKudoctlCmd().ExecuteC()
+	tracing.EndRootSpan(err)
+	shutdown()
+	cmd.ReportUsage(executed, err, time.Since(started))
+	if err != nil {
+		os.Exit(1)
 	}
 }
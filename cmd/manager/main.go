@@ -16,33 +16,156 @@ limitations under the License.
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis"
+	"github.com/kudobuilder/kudo/pkg/catalog"
 	"github.com/kudobuilder/kudo/pkg/controller/instance"
 	"github.com/kudobuilder/kudo/pkg/controller/operator"
 	"github.com/kudobuilder/kudo/pkg/controller/operatorversion"
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
+	"github.com/kudobuilder/kudo/pkg/healthz"
+	"github.com/kudobuilder/kudo/pkg/metrics"
 	util "github.com/kudobuilder/kudo/pkg/test/utils"
+	"github.com/kudobuilder/kudo/pkg/util/apiwarnings"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+	"github.com/kudobuilder/kudo/pkg/util/exec"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
 	"github.com/kudobuilder/kudo/pkg/version"
+	"github.com/kudobuilder/kudo/pkg/webhook"
 	apiextenstionsv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 func main() {
+	var (
+		enableLeaderElection    bool
+		leaderElectionNamespace string
+		leaderElectionID        string
+		leaseDuration           time.Duration
+		renewDeadline           time.Duration
+		retryPeriod             time.Duration
+		enableWebhooks          bool
+		webhookCertDir          string
+		watchNamespace          string
+		syncPeriod              time.Duration
+		queueOptions            queue.Options
+		driftInterval           time.Duration
+		driftAutoCorrect        bool
+		scheduleInterval        time.Duration
+		healthAddr              string
+		metricsAddr             string
+		encryptionConfig        string
+		catalogAddr             string
+		catalogCertDir          string
+		executionRetention      int
+	)
+	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
+		"Enable leader election so that multiple replicas of the manager can run and only the leader executes plans.")
+	flag.StringVar(&leaderElectionNamespace, "leader-election-namespace", "",
+		"Namespace of the leader election lock, defaults to the namespace the manager is running in.")
+	flag.StringVar(&leaderElectionID, "leader-election-id", "kudo-controller-manager-leader", "Name of the leader election lock.")
+	flag.DurationVar(&leaseDuration, "leader-election-lease-duration", 15*time.Second,
+		"Duration non-leader replicas wait before they try to acquire the leadership of a leader that stopped renewing it.")
+	flag.DurationVar(&renewDeadline, "leader-election-renew-deadline", 10*time.Second,
+		"Duration the leader retries to renew its leadership before giving it up.")
+	flag.DurationVar(&retryPeriod, "leader-election-retry-period", 2*time.Second,
+		"Duration between the attempts to acquire or renew the leadership.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Enable the admission webhooks, the serving certificate has to be provided in the webhook certificate directory.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/cert", "Directory containing the tls.crt and tls.key of the webhook server.")
+	flag.StringVar(&watchNamespace, "watch-namespace", "",
+		"Namespace the manager watches and caches objects of, all namespaces are watched if empty.")
+	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Hour,
+		"Minimum interval at which the cached objects are resynced and all instances are reconciled.")
+	flag.DurationVar(&queueOptions.BaseDelay, "queue-base-delay", queue.DefaultBaseDelay,
+		"Delay of the first retry of a failed reconcile, the delay doubles with every retry.")
+	flag.DurationVar(&queueOptions.MaxDelay, "queue-max-delay", queue.DefaultMaxDelay,
+		"Maximum delay between retries of a failed reconcile.")
+	flag.IntVar(&queueOptions.MaxRetries, "queue-max-retries", 0,
+		"Number of retries of a failing reconcile before it is dropped until the object changes again, 0 retries forever.")
+	flag.IntVar(&queueOptions.MaxConcurrentReconciles, "max-concurrent-reconciles", queue.DefaultMaxConcurrentReconciles,
+		"Number of reconciles each controller runs in parallel.")
+	flag.Float64Var(&queueOptions.ObjectQPS, "object-reconcile-qps", queue.DefaultObjectQPS,
+		"Number of reconciles of a single object per second, further reconciles are delayed so that every object gets its share of the workers.")
+	flag.IntVar(&queueOptions.ObjectBurst, "object-reconcile-burst", queue.DefaultObjectBurst,
+		"Number of reconciles of a single object allowed at once before --object-reconcile-qps applies.")
+	flag.DurationVar(&driftInterval, "drift-detection-interval", 0,
+		"Interval at which the objects applied by the last plan of every instance are compared with the live objects, 0 disables drift detection.")
+	flag.BoolVar(&driftAutoCorrect, "drift-auto-correct", false,
+		"Apply objects again that were changed out-of-band, requires drift detection to be enabled.")
+	flag.DurationVar(&scheduleInterval, "schedule-check-interval", 30*time.Second,
+		"Interval at which the plan schedules of the instances are checked, 0 disables scheduled plans.")
+	flag.StringVar(&healthAddr, "health-addr", fmt.Sprintf(":%d", healthz.DefaultPort),
+		"Address the liveness and readiness endpoints /healthz and /readyz are served at.")
+	flag.StringVar(&metricsAddr, "metrics-addr", fmt.Sprintf(":%d", metrics.DefaultPort),
+		"Address the Prometheus metrics of the controllers and the KUDO objects are served at, 0 disables metrics.")
+	flag.StringVar(&encryptionConfig, "encryption-config", "",
+		"Encryption configuration file with the providers that decrypt the values of sensitive parameters.")
+	flag.StringVar(&catalogAddr, "catalog-addr", "",
+		"Address the read-only catalog API of the operators, operatorversions and instances is served at, empty disables the API.")
+	flag.StringVar(&catalogCertDir, "catalog-cert-dir", "",
+		"Directory containing the tls.crt and tls.key the catalog API is served with, plain HTTP is served if empty.")
+	flag.IntVar(&executionRetention, "plan-execution-retention", instance.DefaultExecutionRetention,
+		"Number of PlanExecutions recording the runs of plans kept for every instance, 0 disables recording them.")
+	flag.Parse()
+
 	logf.SetLogger(logf.ZapLogger(false))
 	log := logf.Log.WithName("entrypoint")
 
 	// Get version of KUDO
 	log.Info(fmt.Sprintf("KUDO Version: %s", fmt.Sprintf("%#v", version.Get())))
 
+	// reconciles and plan executions are traced if an OTLP endpoint is configured in the environment
+	shutdownTracing, err := tracing.Setup("kudo-manager")
+	if err != nil {
+		log.Error(err, "unable to set up tracing")
+	}
+	if tracing.Enabled() {
+		log.Info("tracing enabled")
+	}
+
+	var keyring *encryption.Keyring
+	if encryptionConfig != "" {
+		keyring, err = encryption.LoadConfig(encryptionConfig)
+		if err != nil {
+			log.Error(err, "unable to load encryption config")
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("decrypting sensitive parameters with the providers of %s", encryptionConfig))
+	}
+
 	// create new controller-runtime manager
 	log.Info("setting up manager")
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		MapperProvider: util.NewDynamicRESTMapper,
+	if enableLeaderElection {
+		log.Info(fmt.Sprintf("leader election enabled with lock %s", leaderElectionID))
+	}
+	if watchNamespace != "" {
+		log.Info(fmt.Sprintf("watching namespace %s", watchNamespace))
+	}
+	// warnings of the API server, e.g. for deprecated APIs, are recorded in the status of the instances
+	config := ctrl.GetConfigOrDie()
+	apiwarnings.WrapConfig(config)
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
+		MapperProvider:          util.NewDynamicRESTMapper,
+		LeaderElection:          enableLeaderElection,
+		LeaderElectionNamespace: leaderElectionNamespace,
+		LeaderElectionID:        leaderElectionID,
+		LeaseDuration:           &leaseDuration,
+		RenewDeadline:           &renewDeadline,
+		RetryPeriod:             &retryPeriod,
+		Port:                    9876,
+		Namespace:               watchNamespace,
+		SyncPeriod:              &syncPeriod,
+		MetricsBindAddress:      metricsAddr,
 	})
 	if err != nil {
 		log.Error(err, "unable to start manager")
@@ -66,6 +189,7 @@ func main() {
 	log.Info("Setting up operator controller")
 	err = (&operator.Reconciler{
 		Client: mgr.GetClient(),
+		Queue:  queueOptions,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		log.Error(err, "unable to register operator controller to the manager")
@@ -75,26 +199,124 @@ func main() {
 	log.Info("Setting up operator version controller")
 	err = (&operatorversion.Reconciler{
 		Client: mgr.GetClient(),
+		Queue:  queueOptions,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		log.Error(err, "unable to register operator controller to the manager")
 		os.Exit(1)
 	}
 
+	// the instance controller, the drift detector and the plan cron never change the same instance at the same time
+	instanceLocks := &queue.KeyedLock{}
+
 	log.Info("Setting up instance controller")
 	err = (&instance.Reconciler{
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("instance-controller"),
-		Scheme:   mgr.GetScheme(),
+		Client:             mgr.GetClient(),
+		Recorder:           mgr.GetEventRecorderFor("instance-controller"),
+		Scheme:             mgr.GetScheme(),
+		Executor:           exec.NewExecutor(mgr.GetConfig()),
+		Config:             mgr.GetConfig(),
+		Queue:              queueOptions,
+		Keyring:            keyring,
+		ExecutionRetention: executionRetention,
+		Locks:              instanceLocks,
 	}).SetupWithManager(mgr)
 	if err != nil {
 		log.Error(err, "unable to register instance controller to the manager")
 		os.Exit(1)
 	}
 
+	if driftInterval > 0 {
+		log.Info(fmt.Sprintf("Setting up drift detection every %s", driftInterval))
+		err = mgr.Add(&instance.DriftDetector{
+			Client:      mgr.GetClient(),
+			Recorder:    mgr.GetEventRecorderFor("instance-drift-detector"),
+			Scheme:      mgr.GetScheme(),
+			Interval:    driftInterval,
+			AutoCorrect: driftAutoCorrect,
+			Keyring:     keyring,
+			Config:      mgr.GetConfig(),
+			Locks:       instanceLocks,
+		})
+		if err != nil {
+			log.Error(err, "unable to register drift detector to the manager")
+			os.Exit(1)
+		}
+	}
+
+	if scheduleInterval > 0 {
+		log.Info(fmt.Sprintf("Setting up scheduled plans checked every %s", scheduleInterval))
+		err = mgr.Add(&instance.PlanCron{
+			Client:   mgr.GetClient(),
+			Recorder: mgr.GetEventRecorderFor("instance-plan-cron"),
+			Interval: scheduleInterval,
+			Locks:    instanceLocks,
+		})
+		if err != nil {
+			log.Error(err, "unable to register plan cron to the manager")
+			os.Exit(1)
+		}
+	}
+
+	if enableWebhooks {
+		log.Info("Setting up webhooks")
+		server := mgr.GetWebhookServer()
+		server.CertDir = webhookCertDir
+		server.Register(webhook.InstanceDefaulterPath, &admission.Webhook{Handler: &webhook.InstanceDefaulter{}})
+		server.Register(webhook.InstanceProtectorPath, &admission.Webhook{Handler: &webhook.InstanceProtector{}})
+		server.Register(webhook.InstanceValidatorPath, &admission.Webhook{Handler: &webhook.InstanceValidator{}})
+	}
+
+	if metricsAddr != "0" {
+		log.Info(fmt.Sprintf("Serving metrics at %s", metricsAddr))
+		if err := metrics.Register(mgr.GetClient()); err != nil {
+			log.Error(err, "unable to register metrics of the KUDO objects")
+			os.Exit(1)
+		}
+	}
+
+	if catalogAddr != "" {
+		log.Info(fmt.Sprintf("Serving the catalog API at %s", catalogAddr))
+		kubeClient, err := kubernetes.NewForConfig(mgr.GetConfig())
+		if err != nil {
+			log.Error(err, "unable to create kubernetes client for the catalog API")
+			os.Exit(1)
+		}
+		err = mgr.Add(&catalog.Server{
+			Addr:       catalogAddr,
+			CertDir:    catalogCertDir,
+			Client:     mgr.GetClient(),
+			KubeClient: kubeClient,
+		})
+		if err != nil {
+			log.Error(err, "unable to register catalog server to the manager")
+			os.Exit(1)
+		}
+	}
+
+	log.Info(fmt.Sprintf("Serving health checks at %s", healthAddr))
+	readiness := []healthz.Check{
+		healthz.InformerCache(mgr.GetCache()),
+		healthz.CRDs(mgr.GetAPIReader(), healthz.KUDOCRDs...),
+	}
+	if enableWebhooks {
+		readiness = append(readiness, healthz.WebhookCertificate(webhookCertDir))
+	}
+	err = mgr.Add(&healthz.Server{
+		Addr:      healthAddr,
+		Liveness:  []healthz.Check{healthz.Ping()},
+		Readiness: readiness,
+	})
+	if err != nil {
+		log.Error(err, "unable to register health server to the manager")
+		os.Exit(1)
+	}
+
 	// Start the Cmd
 	log.Info("Starting the Cmd.")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+	shutdownTracing()
+	if err != nil {
 		log.Error(err, "unable to run the manager")
 		os.Exit(1)
 	}
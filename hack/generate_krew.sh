@@ -5,8 +5,10 @@ set -o nounset
 set -o pipefail
 
 # This script generates a Krew-compatible plugin manifest. It should be run after goreleaser.
+# The checksums are computed from the archives in DIST_DIR if they exist, otherwise the released archives are downloaded.
 
 VERSION=${VERSION:-$(git describe --tags | sed 's/^v//g')}
+DIST_DIR=${DIST_DIR:-dist}
 
 # Generate the manifest for a single platform.
 function generate_platform {
@@ -17,15 +19,20 @@ function generate_platform {
         ARCH=i386
     fi
 
+    local archive="kudo_${VERSION}_${1}_${ARCH}.tar.gz"
     local sha
-    sha=$(curl -L https://github.com/kudobuilder/kudo/releases/download/v"${VERSION}"/kudo_"${VERSION}"_"${1}"_"${ARCH}".tar.gz | sha256sum - | awk '{print $1}')
+    if [ -f "${DIST_DIR}/${archive}" ]; then
+        sha=$(sha256sum "${DIST_DIR}/${archive}" | awk '{print $1}')
+    else
+        sha=$(curl -L https://github.com/kudobuilder/kudo/releases/download/v"${VERSION}"/"${archive}" | sha256sum - | awk '{print $1}')
+    fi
 
     cat <<EOF
   - selector:
       matchLabels:
         os: "${1}"
         arch: "${2}"
-    uri: https://github.com/kudobuilder/kudo/releases/download/v${VERSION}/kudo_${VERSION}_${1}_${ARCH}.tar.gz
+    uri: https://github.com/kudobuilder/kudo/releases/download/v${VERSION}/${archive}
     sha256: "${sha}"
     bin: "${3}"
     files:
@@ -55,9 +62,8 @@ spec:
     Kubernetes.
   caveats: |
     Requires the KUDO controller to be installed:
-      kubectl create -f https://raw.githubusercontent.com/kudobuilder/kudo/v0.4.0/docs/deployment/00-prereqs.yaml
-      kubectl create -f https://raw.githubusercontent.com/kudobuilder/kudo/v0.4.0/docs/deployment/10-crds.yaml
-      kubectl create -f https://raw.githubusercontent.com/kudobuilder/kudo/v0.4.0/docs/deployment/20-deployment.yaml
+      kubectl kudo init
+    The standard kubectl flags --kubeconfig, --context and --namespace are supported.
     Example usage:
       Install kafka:
         kubectl kudo install kafka
@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	"strings"
+)
+
+// ApprovalsAnnotation lists the approved manual gates of the active plan of an Instance, see Phase.Manual and
+// Step.Manual. The gates are separated by commas and named by GateName. It is removed once a plan starts, so that every
+// execution of a plan has to be approved again. kudoctl adds gates with 'plan approve'.
+const ApprovalsAnnotation = "kudo.dev/approvals"
+
+// GateName returns the name of the manual gate of a phase, or of a step of the phase if step is not empty
+func GateName(plan, phase, step string) string {
+	if step == "" {
+		return plan + "/" + phase
+	}
+	return plan + "/" + phase + "/" + step
+}
+
+// IsApproved returns true if the manual gate of the phase, or of the step if step is not empty, was approved
+func (i *Instance) IsApproved(plan, phase, step string) bool {
+	gate := GateName(plan, phase, step)
+	for _, approved := range i.Approvals() {
+		if approved == gate {
+			return true
+		}
+	}
+	return false
+}
+
+// Approvals returns the names of the approved manual gates
+func (i *Instance) Approvals() []string {
+	value := i.Annotations[ApprovalsAnnotation]
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// Approve approves the manual gate of the phase, or of the step if step is not empty. It returns false if the gate was
+// already approved.
+func (i *Instance) Approve(plan, phase, step string) bool {
+	if i.IsApproved(plan, phase, step) {
+		return false
+	}
+	if i.Annotations == nil {
+		i.Annotations = map[string]string{}
+	}
+	i.Annotations[ApprovalsAnnotation] = strings.Join(append(i.Approvals(), GateName(plan, phase, step)), ",")
+	return true
+}
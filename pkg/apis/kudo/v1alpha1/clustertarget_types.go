@@ -0,0 +1,75 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultKubeconfigKey is the key of the kubeconfig in the secret of a ClusterTarget if none is set
+const DefaultKubeconfigKey = "kubeconfig"
+
+// ClusterTargetSpec defines how the KUDO manager connects to a cluster
+type ClusterTargetSpec struct {
+	// KubeconfigSecret references the secret in the namespace of the ClusterTarget containing the kubeconfig of the
+	// cluster. The current context of the kubeconfig is used. The kubeconfig may only contain inline tokens and
+	// certificates, exec plugins, auth providers and file references are rejected.
+	KubeconfigSecret SecretKeyReference `json:"kubeconfigSecret"`
+}
+
+// SecretKeyReference references a key of a secret in the same namespace
+type SecretKeyReference struct {
+	// Name is the name of the secret
+	Name string `json:"name"`
+	// Key is the key of the value in the secret
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+// KeyOrDefault returns the key of the reference or the default key if none is set
+func (r SecretKeyReference) KeyOrDefault(defaultKey string) string {
+	if r.Key == "" {
+		return defaultKey
+	}
+	return r.Key
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterTarget is the Schema for a remote cluster that instances in its namespace can target. The plans of an
+// Instance targeting a ClusterTarget apply the resources of the instance to the remote cluster, while the Instance and
+// its status stay in the management cluster the KUDO manager runs in.
+// +k8s:openapi-gen=true
+type ClusterTarget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterTargetSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterTargetList contains a list of ClusterTarget
+type ClusterTargetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterTarget `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterTarget{}, &ClusterTargetList{})
+}
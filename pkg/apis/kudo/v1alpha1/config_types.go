@@ -0,0 +1,243 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// KudoConfigName is the name of the KudoConfig the manager reads, KudoConfigs with other names are ignored
+const KudoConfigName = "kudo"
+
+const (
+	// ImageRegistryAnnotation overrides the image registry of the KudoConfig for the objects of an Instance
+	ImageRegistryAnnotation = "kudo.dev/image-registry"
+	// ImagePullSecretsAnnotation overrides the image pull secrets of the KudoConfig for the objects of an Instance,
+	// the names of the secrets are separated by commas
+	ImagePullSecretsAnnotation = "kudo.dev/image-pull-secrets"
+)
+
+// KudoConfigSpec defines the settings of the KUDO manager
+type KudoConfigSpec struct {
+	// Concurrency limits how many plans the manager executes at the same time
+	Concurrency ConcurrencyLimits `json:"concurrency,omitempty"`
+	// LabelPropagation copies labels and annotations of Instances to the objects applied for them
+	LabelPropagation LabelPropagationPolicy `json:"labelPropagation,omitempty"`
+	// Images redirects the images of all operators to a registry, e.g. a mirror in an air-gapped cluster
+	Images ImageSettings `json:"images,omitempty"`
+	// PostRender mutates or rejects the rendered objects of all instances before they are applied, e.g. to enforce
+	// organization-wide policies
+	PostRender PostRenderSettings `json:"postRender,omitempty"`
+}
+
+// ConcurrencyLimits limit the number of plans in progress. A plan that would exceed a limit is queued until another
+// plan finished. A limit of 0 means no limit.
+type ConcurrencyLimits struct {
+	// PerNamespace is the maximum number of plans in progress in each namespace
+	PerNamespace int `json:"perNamespace,omitempty"`
+	// PerOperator is the maximum number of plans in progress of the instances of each operator
+	PerOperator int `json:"perOperator,omitempty"`
+	// Namespaces overrides PerNamespace for the namespaces by name
+	Namespaces map[string]int `json:"namespaces,omitempty"`
+	// Operators overrides PerOperator for the operators by name
+	Operators map[string]int `json:"operators,omitempty"`
+}
+
+// NamespaceLimit returns the maximum number of plans in progress in the namespace, 0 if there is no limit
+func (l ConcurrencyLimits) NamespaceLimit(namespace string) int {
+	if limit, ok := l.Namespaces[namespace]; ok {
+		return limit
+	}
+	return l.PerNamespace
+}
+
+// OperatorLimit returns the maximum number of plans in progress of the instances of the operator, 0 if there is no
+// limit
+func (l ConcurrencyLimits) OperatorLimit(operator string) int {
+	if limit, ok := l.Operators[operator]; ok {
+		return limit
+	}
+	return l.PerOperator
+}
+
+// LabelPropagationPolicy selects the labels and annotations of Instances that are copied to all objects applied for
+// them, e.g. a team or cost-center label that chargeback and policy tools attribute the workload with. They are
+// copied to the metadata of the objects and to the pod templates of workloads, but not to selectors. Labels and
+// annotations set by the templates of an object take precedence.
+type LabelPropagationPolicy struct {
+	// Labels are the keys of the Instance labels that are copied as labels
+	Labels []string `json:"labels,omitempty"`
+	// Annotations are the keys of the Instance annotations that are copied as annotations
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// PropagatedLabels returns the labels of the Instance that are propagated to its objects
+func (p LabelPropagationPolicy) PropagatedLabels(instance *Instance) map[string]string {
+	return selectKeys(instance.Labels, p.Labels)
+}
+
+// PropagatedAnnotations returns the annotations of the Instance that are propagated to its objects
+func (p LabelPropagationPolicy) PropagatedAnnotations(instance *Instance) map[string]string {
+	return selectKeys(instance.Annotations, p.Annotations)
+}
+
+// ImageSettings redirect the images of the pods KUDO applies. The registry host of every container image is replaced
+// with the registry, images from Docker Hub are prefixed with it, and the pull secrets are added to the pod specs.
+// Images that already refer to the registry are kept. The settings are available in templates as .ImageRegistry and
+// .ImagePullSecrets as well.
+type ImageSettings struct {
+	// Registry is the registry host, optionally followed by a path, e.g. registry.example.com:5000/mirror
+	Registry string `json:"registry,omitempty"`
+	// PullSecrets are the names of the secrets in the namespace of an Instance used to pull its images
+	PullSecrets []string `json:"pullSecrets,omitempty"`
+}
+
+// ForInstance returns the settings for the objects of the Instance, its ImageRegistryAnnotation and
+// ImagePullSecretsAnnotation take precedence
+func (s ImageSettings) ForInstance(instance *Instance) ImageSettings {
+	result := ImageSettings{Registry: s.Registry, PullSecrets: s.PullSecrets}
+	if registry, ok := instance.Annotations[ImageRegistryAnnotation]; ok {
+		result.Registry = registry
+	}
+	if secrets, ok := instance.Annotations[ImagePullSecretsAnnotation]; ok {
+		result.PullSecrets = nil
+		for _, secret := range strings.Split(secrets, ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				result.PullSecrets = append(result.PullSecrets, secret)
+			}
+		}
+	}
+	result.Registry = strings.TrimSuffix(result.Registry, "/")
+	return result
+}
+
+// Image returns the image pulled from the registry, see ImageSettings. The image is returned unchanged if no
+// registry is set.
+func (s ImageSettings) Image(image string) string {
+	registry := strings.TrimSuffix(s.Registry, "/")
+	if registry == "" || image == "" || strings.HasPrefix(image, registry+"/") {
+		return image
+	}
+	if i := strings.Index(image, "/"); i > 0 {
+		host := image[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			image = image[i+1:]
+		}
+	}
+	return registry + "/" + image
+}
+
+func selectKeys(values map[string]string, keys []string) map[string]string {
+	var selected map[string]string
+	for _, k := range keys {
+		v, ok := values[k]
+		if !ok {
+			continue
+		}
+		if selected == nil {
+			selected = make(map[string]string)
+		}
+		selected[k] = v
+	}
+	return selected
+}
+
+// PostRenderFailurePolicy defines how errors calling a post-render webhook are handled
+type PostRenderFailurePolicy string
+
+const (
+	// PostRenderFail fails the step if the webhook can not be called, the step is retried
+	PostRenderFail PostRenderFailurePolicy = "Fail"
+	// PostRenderIgnore applies the objects unchanged if the webhook can not be called
+	PostRenderIgnore PostRenderFailurePolicy = "Ignore"
+)
+
+// PostRenderSettings configure the post-render stage. It runs after the templates of a task were rendered and the
+// KUDO conventions were applied, and before the objects are applied. The built-in mutators run first, followed by the
+// webhooks in their order.
+type PostRenderSettings struct {
+	// Labels are added to all objects and to the pod templates of workloads, existing labels are kept
+	Labels map[string]string `json:"labels,omitempty"`
+	// ImageRewrites replace the prefix of the container images of all pods, the first matching rewrite is applied
+	ImageRewrites []ImageRewrite `json:"imageRewrites,omitempty"`
+	// Webhooks are called with the objects of every task, they may mutate or reject them
+	Webhooks []PostRenderWebhook `json:"webhooks,omitempty"`
+}
+
+// ImageRewrite replaces the prefix From of container images with To, e.g. docker.io/library/ with
+// registry.example.com/hub/
+type ImageRewrite struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Rewrite returns the image with the prefix replaced and true, or the unchanged image and false if it does not match
+func (r ImageRewrite) Rewrite(image string) (string, bool) {
+	if r.From == "" || !strings.HasPrefix(image, r.From) {
+		return image, false
+	}
+	return r.To + strings.TrimPrefix(image, r.From), true
+}
+
+// PostRenderWebhook is an HTTPS endpoint, e.g. a service evaluating OPA policies, that receives the objects of a task
+// in a PostRenderReview and returns the mutated objects or rejects them. Webhooks are also called by dry-runs and the
+// drift detection, so they must not have side effects.
+type PostRenderWebhook struct {
+	// Name identifies the webhook in errors and events
+	Name string `json:"name"`
+	// URL is the URL the reviews are posted to
+	URL string `json:"url"`
+	// CABundle is the PEM encoded CA bundle verifying the serving certificate of the webhook, the system roots are
+	// used if it is empty
+	// +optional
+	CABundle []byte `json:"caBundle,omitempty"`
+	// TimeoutSeconds is the timeout of a call, defaults to 10 seconds
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// FailurePolicy defines how errors calling the webhook are handled, defaults to Fail. Rejected objects always
+	// fail the step.
+	// +optional
+	FailurePolicy PostRenderFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KudoConfig is the Schema for the configuration of the KUDO manager. It is cluster scoped, only the KudoConfig named
+// kudo is used.
+// +k8s:openapi-gen=true
+type KudoConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec KudoConfigSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// KudoConfigList contains a list of KudoConfig
+type KudoConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []KudoConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&KudoConfig{}, &KudoConfigList{})
+}
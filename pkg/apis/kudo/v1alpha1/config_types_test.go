@@ -0,0 +1,70 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageSettings_Image(t *testing.T) {
+	tests := []struct {
+		registry string
+		image    string
+		expected string
+	}{
+		{registry: "", image: "nginx:1.17", expected: "nginx:1.17"},
+		{registry: "mirror.local", image: "nginx:1.17", expected: "mirror.local/nginx:1.17"},
+		{registry: "mirror.local/", image: "bitnami/kafka:2.3", expected: "mirror.local/bitnami/kafka:2.3"},
+		{registry: "mirror.local:5000/kudo", image: "gcr.io/google-containers/pause:3.1", expected: "mirror.local:5000/kudo/google-containers/pause:3.1"},
+		{registry: "mirror.local", image: "localhost/app", expected: "mirror.local/app"},
+		{registry: "mirror.local", image: "registry:5000/app@sha256:abc", expected: "mirror.local/app@sha256:abc"},
+		{registry: "mirror.local", image: "mirror.local/nginx:1.17", expected: "mirror.local/nginx:1.17"},
+	}
+
+	for _, tt := range tests {
+		if actual := (ImageSettings{Registry: tt.registry}).Image(tt.image); actual != tt.expected {
+			t.Errorf("image %s with registry %q: expected %s but got %s", tt.image, tt.registry, tt.expected, actual)
+		}
+	}
+}
+
+func TestImageSettings_ForInstance(t *testing.T) {
+	config := ImageSettings{Registry: "mirror.local/", PullSecrets: []string{"mirror"}}
+
+	instance := &Instance{}
+	expected := ImageSettings{Registry: "mirror.local", PullSecrets: []string{"mirror"}}
+	if actual := config.ForInstance(instance); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	instance = &Instance{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		ImageRegistryAnnotation:    "team.registry",
+		ImagePullSecretsAnnotation: "team, backup,",
+	}}}
+	expected = ImageSettings{Registry: "team.registry", PullSecrets: []string{"team", "backup"}}
+	if actual := config.ForInstance(instance); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v but got %v", expected, actual)
+	}
+
+	instance = &Instance{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ImageRegistryAnnotation: ""}}}
+	expected = ImageSettings{PullSecrets: []string{"mirror"}}
+	if actual := config.ForInstance(instance); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("an empty annotation disables the registry: expected %v but got %v", expected, actual)
+	}
+}
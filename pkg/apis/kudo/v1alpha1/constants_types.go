@@ -0,0 +1,58 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"sigs.k8s.io/yaml"
+)
+
+// ParseConstants parses the YAML or JSON object of the constants of an operator package
+func ParseConstants(b []byte) (*apiextv1beta1.JSON, error) {
+	raw, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	constants := &apiextv1beta1.JSON{Raw: raw}
+	if _, err := templateConstants(constants); err != nil {
+		return nil, err
+	}
+	return constants, nil
+}
+
+// TemplateConstants returns the constants of the OperatorVersion as they are passed to templates, it is empty if the
+// OperatorVersion declares no constants
+func (ov *OperatorVersion) TemplateConstants() (map[string]interface{}, error) {
+	return templateConstants(ov.Spec.Constants)
+}
+
+func templateConstants(constants *apiextv1beta1.JSON) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	if constants == nil || len(constants.Raw) == 0 || string(constants.Raw) == "null" {
+		return values, nil
+	}
+	// numbers are kept as written, e.g. 1000000 is not rendered as 1e+06
+	d := json.NewDecoder(bytes.NewReader(constants.Raw))
+	d.UseNumber()
+	if err := d.Decode(&values); err != nil {
+		return nil, fmt.Errorf("constants have to be an object: %v", err)
+	}
+	return values, nil
+}
@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// DefaultPlans declares the plans of an OperatorVersion that KUDO executes on its own. A plan that is not declared
+// defaults to the plan with the conventional name, e.g. the deploy plan of an OperatorVersion that declares no
+// DefaultPlans is named "deploy".
+type DefaultPlans struct {
+	// Deploy is executed when an instance is created, it defaults to "deploy"
+	// +optional
+	Deploy string `json:"deploy,omitempty"`
+	// Update is executed when parameters without a trigger of an instance change, it defaults to "update". If the
+	// OperatorVersion has no update plan, the deploy plan is executed.
+	// +optional
+	Update string `json:"update,omitempty"`
+	// Upgrade is executed when an instance is upgraded to the OperatorVersion, it defaults to "upgrade". If the
+	// OperatorVersion has no upgrade plan, the update plan is executed.
+	// +optional
+	Upgrade string `json:"upgrade,omitempty"`
+}
+
+// DeployPlan returns the name of the plan executed when an instance is created
+func (p *DefaultPlans) DeployPlan() string {
+	if p == nil || p.Deploy == "" {
+		return DeployPlanName
+	}
+	return p.Deploy
+}
+
+// UpdatePlan returns the name of the plan executed when parameters without a trigger change
+func (p *DefaultPlans) UpdatePlan() string {
+	if p == nil || p.Update == "" {
+		return UpdatePlanName
+	}
+	return p.Update
+}
+
+// UpgradePlan returns the name of the plan executed when an instance is upgraded
+func (p *DefaultPlans) UpgradePlan() string {
+	if p == nil || p.Upgrade == "" {
+		return UpgradePlanName
+	}
+	return p.Upgrade
+}
+
+// updatePlans returns the plans that can be executed when parameters without a trigger change, in order of preference
+func (p *DefaultPlans) updatePlans() []string {
+	return []string{p.UpdatePlan(), p.DeployPlan()}
+}
+
+// upgradePlans returns the plans that can be executed when an instance is upgraded, in order of preference
+func (p *DefaultPlans) upgradePlans() []string {
+	return append([]string{p.UpgradePlan()}, p.updatePlans()...)
+}
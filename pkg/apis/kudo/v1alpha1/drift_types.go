@@ -0,0 +1,60 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DriftStatus is the result of comparing the objects applied by the last executed plan of an instance with the live
+// objects in the cluster
+type DriftStatus struct {
+	// CheckedAt is the time of the last drift detection
+	CheckedAt metav1.Time `json:"checkedAt,omitempty"`
+	// Plan is the plan whose applied objects were compared
+	Plan string `json:"plan,omitempty"`
+	// Resources lists the objects that were changed out-of-band, it is empty if no drift was detected
+	Resources []ResourceDrift `json:"resources,omitempty"`
+}
+
+// ResourceDrift describes how a live object differs from the object applied by KUDO
+type ResourceDrift struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// Missing is true if the object was deleted
+	Missing bool `json:"missing,omitempty"`
+	// Fields lists the fields of the live object that differ from the applied values
+	Fields []FieldDrift `json:"fields,omitempty"`
+	// CorrectedAt is the time the object was applied again by the automatic drift correction
+	CorrectedAt *metav1.Time `json:"correctedAt,omitempty"`
+}
+
+// FieldDrift is a field of a live object that differs from the applied value
+type FieldDrift struct {
+	// Path is the path of the field, e.g. spec.template.spec.containers[0].image
+	Path string `json:"path"`
+	// Expected is the JSON encoded value applied by KUDO, it is empty if the field is not set by KUDO
+	Expected string `json:"expected,omitempty"`
+	// Actual is the JSON encoded live value, it is empty if the field was removed
+	Actual string `json:"actual,omitempty"`
+}
+
+// Drifted returns true if the last drift detection found objects that were changed out-of-band
+func (s *DriftStatus) Drifted() bool {
+	return s != nil && len(s.Resources) > 0
+}
@@ -0,0 +1,112 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DryRunPlanAnnotation requests a dry-run of the plan of an instance, the result is reported in Status.DryRun
+	DryRunPlanAnnotation = "kudo.dev/dry-run-plan"
+	// DryRunRequestAnnotation identifies a dry-run request, it has to change for every request of the same plan
+	DryRunRequestAnnotation = "kudo.dev/dry-run-request"
+	// DryRunParametersAnnotation holds the parameters that override the parameters of the instance for the dry-run as
+	// JSON object, e.g. to preview an update. Parameters with a null value are removed, so that their default is used.
+	DryRunParametersAnnotation = "kudo.dev/dry-run-parameters"
+)
+
+// DryRunStatus is the report of the last dry-run of a plan. The templates of the plan were rendered with the current
+// parameters of the instance and the resulting objects sent to the API server in dry-run mode, nothing was changed.
+type DryRunStatus struct {
+	// Request is the dry-run request answered by this report
+	Request string `json:"request,omitempty"`
+	// Plan is the plan that was executed in dry-run mode
+	Plan string `json:"plan,omitempty"`
+	// CompletedAt is the time the dry-run finished
+	CompletedAt metav1.Time `json:"completedAt,omitempty"`
+	// Changes lists the objects the plan would change, objects that would not change are omitted
+	Changes []ResourceChange `json:"changes,omitempty"`
+	// SkippedTasks lists the tasks that can not be executed in dry-run mode, e.g. Exec tasks
+	SkippedTasks []string `json:"skippedTasks,omitempty"`
+	// Message is the error that ended the dry-run, it is empty if the dry-run succeeded
+	Message string `json:"message,omitempty"`
+}
+
+// ResourceAction is the change a plan would make to an object
+type ResourceAction string
+
+const (
+	// ResourceCreated objects do not exist yet
+	ResourceCreated ResourceAction = "Create"
+	// ResourceUpdated objects exist and would be patched
+	ResourceUpdated ResourceAction = "Update"
+	// ResourceDeleted objects exist and would be deleted
+	ResourceDeleted ResourceAction = "Delete"
+)
+
+// ResourceChange describes how a plan would change an object
+type ResourceChange struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	// Step is the step of the plan changing the object in the form phase.step
+	Step string `json:"step,omitempty"`
+	// Task is the task changing the object
+	Task   string         `json:"task,omitempty"`
+	Action ResourceAction `json:"action"`
+	// Fields lists the fields an update would change, Expected is the new and Actual the current value
+	Fields []FieldDrift `json:"fields,omitempty"`
+}
+
+// GetPendingDryRun returns the plan and the request of a dry-run that was requested but not answered yet. The plan is
+// empty if no dry-run is pending.
+func (i *Instance) GetPendingDryRun() (plan string, request string) {
+	plan, request = i.Annotations[DryRunPlanAnnotation], i.Annotations[DryRunRequestAnnotation]
+	if plan == "" || (i.Status.DryRun != nil && i.Status.DryRun.Request == request) {
+		return "", ""
+	}
+	return plan, request
+}
+
+// GetDryRunParameters returns the parameters of the instance with the overrides of the DryRunParametersAnnotation
+// applied
+func (i *Instance) GetDryRunParameters() (map[string]string, error) {
+	parameters := make(map[string]string, len(i.Spec.Parameters))
+	for k, v := range i.Spec.Parameters {
+		parameters[k] = v
+	}
+	overrides, ok := i.Annotations[DryRunParametersAnnotation]
+	if !ok {
+		return parameters, nil
+	}
+	var changes map[string]*string
+	if err := json.Unmarshal([]byte(overrides), &changes); err != nil {
+		return nil, fmt.Errorf("invalid annotation %s: %v", DryRunParametersAnnotation, err)
+	}
+	for k, v := range changes {
+		if v == nil {
+			delete(parameters, k)
+		} else {
+			parameters[k] = *v
+		}
+	}
+	return parameters, nil
+}
@@ -0,0 +1,50 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import "fmt"
+
+// AccessEndpoint is a way to connect to an instance of the operator, e.g. the bootstrap servers of Kafka. The value
+// is a template that is rendered like the templates of the OperatorVersion once a plan of the instance completed.
+type AccessEndpoint struct {
+	// Name identifies the endpoint, e.g. "bootstrap-servers"
+	Name string `json:"name"`
+	// Description explains what the endpoint is used for
+	Description string `json:"description,omitempty"`
+	// Value is the templated connection string or URL, e.g. "{{ .Name }}-svc.{{ .Namespace }}:{{ .Params.PORT }}"
+	Value string `json:"value"`
+}
+
+// Validate returns an error if the endpoint has no name or no value
+func (e *AccessEndpoint) Validate() error {
+	switch {
+	case e.Name == "":
+		return fmt.Errorf("endpoint has no name")
+	case e.Value == "":
+		return fmt.Errorf("endpoint %s has no value", e.Name)
+	}
+	return nil
+}
+
+// EndpointStatus is an AccessEndpoint of the OperatorVersion rendered for an instance
+type EndpointStatus struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Value is the rendered connection string or URL, it is empty if the endpoint failed to render
+	Value string `json:"value,omitempty"`
+	// Message explains why the endpoint failed to render
+	Message string `json:"message,omitempty"`
+}
@@ -0,0 +1,176 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EventRecorder records an event about an object. It is satisfied by the record.EventRecorder of client-go.
+type EventRecorder interface {
+	Event(object runtime.Object, eventtype, reason, message string)
+}
+
+// IsTerminal returns true if the plan is complete or failed
+func (s *PlanStatus) IsTerminal() bool {
+	return s.Status.IsTerminal()
+}
+
+// IsRunning returns true if the plan is being executed
+func (s *PlanStatus) IsRunning() bool {
+	return s.Status.IsRunning()
+}
+
+// IsFinished returns true if the plan is complete
+func (s *PlanStatus) IsFinished() bool {
+	return s.Status.IsFinished()
+}
+
+// Set sets the status of the plan and keeps its message
+func (s *PlanStatus) Set(status ExecutionStatus) {
+	s.Status = status
+}
+
+// SetWithMessage sets the status and the message of the plan
+func (s *PlanStatus) SetWithMessage(status ExecutionStatus, message string) {
+	s.Status = status
+	s.Message = message
+}
+
+// SetWithEvent sets the status and the message of the plan and records them as an event about the given object,
+// usually the instance executing the plan
+func (s *PlanStatus) SetWithEvent(status ExecutionStatus, message string, recorder EventRecorder, object runtime.Object, reason string) {
+	s.SetWithMessage(status, message)
+	recordStatusEvent(status, message, recorder, object, reason)
+}
+
+// IsTerminal returns true if the phase is complete or failed
+func (s *PhaseStatus) IsTerminal() bool {
+	return s.Status.IsTerminal()
+}
+
+// IsRunning returns true if the phase is being executed
+func (s *PhaseStatus) IsRunning() bool {
+	return s.Status.IsRunning()
+}
+
+// IsFinished returns true if the phase is complete
+func (s *PhaseStatus) IsFinished() bool {
+	return s.Status.IsFinished()
+}
+
+// Set sets the status of the phase and keeps its message
+func (s *PhaseStatus) Set(status ExecutionStatus) {
+	s.Status = status
+}
+
+// SetWithMessage sets the status and the message of the phase
+func (s *PhaseStatus) SetWithMessage(status ExecutionStatus, message string) {
+	s.Status = status
+	s.Message = message
+}
+
+// SetWithEvent sets the status and the message of the phase and records them as an event about the given object
+func (s *PhaseStatus) SetWithEvent(status ExecutionStatus, message string, recorder EventRecorder, object runtime.Object, reason string) {
+	s.SetWithMessage(status, message)
+	recordStatusEvent(status, message, recorder, object, reason)
+}
+
+// IsTerminal returns true if the step is complete or failed
+func (s *StepStatus) IsTerminal() bool {
+	return s.Status.IsTerminal()
+}
+
+// IsRunning returns true if the step is being executed
+func (s *StepStatus) IsRunning() bool {
+	return s.Status.IsRunning()
+}
+
+// IsFinished returns true if the step is complete
+func (s *StepStatus) IsFinished() bool {
+	return s.Status.IsFinished()
+}
+
+// Set sets the status of the step and keeps its message
+func (s *StepStatus) Set(status ExecutionStatus) {
+	s.Status = status
+}
+
+// SetWithMessage sets the status and the message of the step
+func (s *StepStatus) SetWithMessage(status ExecutionStatus, message string) {
+	s.Status = status
+	s.Message = message
+}
+
+// SetWithEvent sets the status and the message of the step and records them as an event about the given object
+func (s *StepStatus) SetWithEvent(status ExecutionStatus, message string, recorder EventRecorder, object runtime.Object, reason string) {
+	s.SetWithMessage(status, message)
+	recordStatusEvent(status, message, recorder, object, reason)
+}
+
+// recordStatusEvent records a warning event for errors and a normal event for any other status. Nothing is recorded
+// without a recorder.
+func recordStatusEvent(status ExecutionStatus, message string, recorder EventRecorder, object runtime.Object, reason string) {
+	if recorder == nil {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if status == ErrorStatus || status == ExecutionFatalError {
+		eventType = corev1.EventTypeWarning
+	}
+	recorder.Event(object, eventType, reason, message)
+}
+
+// AggregateStepStatuses derives the status of a phase from the statuses of its steps
+func AggregateStepStatuses(steps []StepStatus) ExecutionStatus {
+	statuses := make([]ExecutionStatus, 0, len(steps))
+	for _, s := range steps {
+		statuses = append(statuses, s.Status)
+	}
+	return aggregateStatuses(statuses)
+}
+
+// AggregatePhaseStatuses derives the status of a plan from the statuses of its phases
+func AggregatePhaseStatuses(phases []PhaseStatus) ExecutionStatus {
+	statuses := make([]ExecutionStatus, 0, len(phases))
+	for _, p := range phases {
+		statuses = append(statuses, p.Status)
+	}
+	return aggregateStatuses(statuses)
+}
+
+// aggregateStatuses derives the status of a parent from the statuses of its children. A fatal error of any child
+// fails the parent. The parent is complete, pending or never run if all of its children are, a parent without children
+// is complete. Otherwise the parent is in progress, as transient errors of children are retried.
+func aggregateStatuses(statuses []ExecutionStatus) ExecutionStatus {
+	counts := map[ExecutionStatus]int{}
+	for _, s := range statuses {
+		if s == ExecutionFatalError {
+			return ExecutionFatalError
+		}
+		counts[s]++
+	}
+	switch len(statuses) {
+	case counts[ExecutionComplete]:
+		return ExecutionComplete
+	case counts[ExecutionPending]:
+		return ExecutionPending
+	case counts[ExecutionNeverRun]:
+		return ExecutionNeverRun
+	}
+	return ExecutionInProgress
+}
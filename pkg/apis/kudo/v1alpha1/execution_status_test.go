@@ -0,0 +1,98 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestAggregateStepStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []ExecutionStatus
+		want     ExecutionStatus
+	}{
+		{"no steps", nil, ExecutionComplete},
+		{"all complete", []ExecutionStatus{ExecutionComplete, ExecutionComplete}, ExecutionComplete},
+		{"all pending", []ExecutionStatus{ExecutionPending, ExecutionPending}, ExecutionPending},
+		{"never run", []ExecutionStatus{ExecutionNeverRun}, ExecutionNeverRun},
+		{"partially complete", []ExecutionStatus{ExecutionComplete, ExecutionPending}, ExecutionInProgress},
+		{"transient error", []ExecutionStatus{ExecutionComplete, ErrorStatus}, ExecutionInProgress},
+		{"fatal error", []ExecutionStatus{ExecutionInProgress, ExecutionFatalError, ExecutionComplete}, ExecutionFatalError},
+	}
+
+	for _, tt := range tests {
+		var steps []StepStatus
+		var phases []PhaseStatus
+		for _, s := range tt.statuses {
+			steps = append(steps, StepStatus{Status: s})
+			phases = append(phases, PhaseStatus{Status: s})
+		}
+		if got := AggregateStepStatuses(steps); got != tt.want {
+			t.Errorf("%s: expected step aggregate %s, got %s", tt.name, tt.want, got)
+		}
+		if got := AggregatePhaseStatuses(phases); got != tt.want {
+			t.Errorf("%s: expected phase aggregate %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+type fakeRecorder struct {
+	events []string
+}
+
+func (r *fakeRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	r.events = append(r.events, eventtype+" "+reason+" "+message)
+}
+
+func TestStepStatus_SetWithEvent(t *testing.T) {
+	recorder := &fakeRecorder{}
+	instance := &Instance{}
+
+	step := &StepStatus{Status: ExecutionInProgress}
+	step.SetWithEvent(ExecutionFatalError, "step failed", recorder, instance, "StepFailed")
+	if !step.IsTerminal() || step.IsRunning() || step.Message != "step failed" {
+		t.Errorf("unexpected step status %+v", step)
+	}
+
+	plan := &PlanStatus{Status: ExecutionInProgress}
+	plan.SetWithEvent(ExecutionComplete, "plan completed", recorder, instance, "PlanComplete")
+	if !plan.IsFinished() {
+		t.Errorf("unexpected plan status %+v", plan)
+	}
+
+	phase := &PhaseStatus{Status: ExecutionPending}
+	phase.SetWithEvent(ExecutionInProgress, "", nil, instance, "PhaseStarted")
+	if !phase.IsRunning() {
+		t.Errorf("unexpected phase status %+v", phase)
+	}
+
+	want := []string{
+		corev1.EventTypeWarning + " StepFailed step failed",
+		corev1.EventTypeNormal + " PlanComplete plan completed",
+	}
+	if len(recorder.events) != len(want) {
+		t.Fatalf("expected events %v, got %v", want, recorder.events)
+	}
+	for i := range want {
+		if recorder.events[i] != want[i] {
+			t.Errorf("expected event %q, got %q", want[i], recorder.events[i])
+		}
+	}
+}
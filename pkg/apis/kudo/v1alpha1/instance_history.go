@@ -0,0 +1,215 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// HistoryAnnotation holds the audit trail of the instance as JSON encoded list of InstanceRevisions
+	HistoryAnnotation = "kudo.dev/history"
+
+	// MaxInstanceRevisions is the number of revisions kept in the history of an instance
+	MaxInstanceRevisions = 20
+)
+
+// InstanceRevision records a change of the instance spec, who made it and the outcome of the plan it triggered.
+// Revisions are recorded by kudoctl, the plan and its outcome are filled in by the controller.
+type InstanceRevision struct {
+	// Revision is the number of the revision, it is incremented with every change
+	Revision int `json:"revision"`
+	// Time is the time of the change
+	Time metav1.Time `json:"time"`
+	// User is the user of the kubeconfig context the change was made with
+	User string `json:"user,omitempty"`
+	// ClientVersion is the version of kudoctl the change was made with
+	ClientVersion string `json:"clientVersion,omitempty"`
+	// Action is the kudoctl command that made the change, e.g. install, update or upgrade
+	Action string `json:"action"`
+	// RolledBackTo is the revision that was restored by a rollback
+	RolledBackTo int `json:"rolledBackTo,omitempty"`
+	// OperatorVersion is the name of the operatorversion the instance uses after the change
+	OperatorVersion string            `json:"operatorVersion,omitempty"`
+	Parameters      []ParameterChange `json:"parameters,omitempty"`
+	Plan            string            `json:"plan,omitempty"`
+	PlanStatus      ExecutionStatus   `json:"planStatus,omitempty"`
+	PlanMessage     string            `json:"planMessage,omitempty"`
+	PlanFinishedAt  *metav1.Time      `json:"planFinishedAt,omitempty"`
+}
+
+// ParameterChange is a single parameter changed by a revision. Old is nil for added and New is nil for removed
+// parameters.
+type ParameterChange struct {
+	Name string  `json:"name"`
+	Old  *string `json:"old,omitempty"`
+	New  *string `json:"new,omitempty"`
+}
+
+// ParameterChanges returns the changes between the old and new parameters sorted by name
+func ParameterChanges(old, new map[string]string) []ParameterChange {
+	var changes []ParameterChange
+	for k, v := range new {
+		v := v
+		if o, ok := old[k]; !ok {
+			changes = append(changes, ParameterChange{Name: k, New: &v})
+		} else if o != v {
+			o := o
+			changes = append(changes, ParameterChange{Name: k, Old: &o, New: &v})
+		}
+	}
+	for k, v := range old {
+		v := v
+		if _, ok := new[k]; !ok {
+			changes = append(changes, ParameterChange{Name: k, Old: &v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// History returns the recorded revisions of the instance, oldest first
+func (i *Instance) History() ([]InstanceRevision, error) {
+	history, ok := i.Annotations[HistoryAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var revisions []InstanceRevision
+	if err := json.Unmarshal([]byte(history), &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// AddRevision appends the revision to the history of the instance and assigns it the next revision number. Only the
+// last MaxInstanceRevisions are kept.
+func (i *Instance) AddRevision(revision InstanceRevision) error {
+	revisions, err := i.History()
+	if err != nil {
+		return err
+	}
+	revision.Revision = 1
+	if len(revisions) > 0 {
+		revision.Revision = revisions[len(revisions)-1].Revision + 1
+	}
+	revisions = append(revisions, revision)
+	if len(revisions) > MaxInstanceRevisions {
+		revisions = revisions[len(revisions)-MaxInstanceRevisions:]
+	}
+	return i.saveHistory(revisions)
+}
+
+// SpecAtRevision returns the spec the instance had after the given revision. The parameters are restored by reverting
+// the changes of all later revisions on the current parameters.
+func (i *Instance) SpecAtRevision(revision int) (*InstanceSpec, error) {
+	revisions, err := i.History()
+	if err != nil {
+		return nil, err
+	}
+	index := -1
+	for k, r := range revisions {
+		if r.Revision == revision {
+			index = k
+		}
+	}
+	if index == -1 {
+		if len(revisions) == 0 {
+			return nil, fmt.Errorf("no history recorded for instance %s/%s", i.Namespace, i.Name)
+		}
+		return nil, fmt.Errorf("revision %d not found in history of instance %s/%s, available revisions are %d to %d",
+			revision, i.Namespace, i.Name, revisions[0].Revision, revisions[len(revisions)-1].Revision)
+	}
+
+	parameters := make(map[string]string, len(i.Spec.Parameters))
+	for k, v := range i.Spec.Parameters {
+		parameters[k] = v
+	}
+	for k := len(revisions) - 1; k > index; k-- {
+		for _, c := range revisions[k].Parameters {
+			if c.Old == nil {
+				delete(parameters, c.Name)
+			} else {
+				parameters[c.Name] = *c.Old
+			}
+		}
+	}
+
+	spec := i.Spec.DeepCopy()
+	spec.OperatorVersion.Name = revisions[index].OperatorVersion
+	spec.Parameters = parameters
+	return spec, nil
+}
+
+func (i *Instance) saveHistory(revisions []InstanceRevision) error {
+	jsonBytes, err := json.Marshal(revisions)
+	if err != nil {
+		return err
+	}
+	if i.Annotations == nil {
+		i.Annotations = make(map[string]string)
+	}
+	i.Annotations[HistoryAnnotation] = string(jsonBytes)
+	return nil
+}
+
+// recordPlanStarted assigns the plan to the latest revision if no plan was assigned to it yet
+func (i *Instance) recordPlanStarted(planName string) {
+	i.updateLatestRevision(func(r *InstanceRevision) bool {
+		if r.Plan != "" {
+			return false
+		}
+		r.Plan = planName
+		r.PlanStatus = ExecutionPending
+		return true
+	})
+}
+
+// recordPlanStatus updates the outcome of the plan on the latest revision that triggered it
+func (i *Instance) recordPlanStatus(planStatus *PlanStatus) {
+	i.updateLatestRevision(func(r *InstanceRevision) bool {
+		if r.Plan != planStatus.Name || r.PlanStatus.IsTerminal() || r.PlanStatus == planStatus.Status {
+			return false
+		}
+		r.PlanStatus = planStatus.Status
+		r.PlanMessage = planStatus.Message
+		if planStatus.Status.IsTerminal() {
+			finished := metav1.Now()
+			r.PlanFinishedAt = &finished
+		}
+		return true
+	})
+}
+
+// updateLatestRevision calls update with the latest revision and saves the history if update returns true.
+// A broken history is logged and otherwise ignored as it must never block the plan execution.
+func (i *Instance) updateLatestRevision(update func(r *InstanceRevision) bool) {
+	revisions, err := i.History()
+	if err != nil {
+		log.Printf("Instance: could not read history of instance %s/%s: %v", i.Namespace, i.Name, err)
+		return
+	}
+	if len(revisions) == 0 || !update(&revisions[len(revisions)-1]) {
+		return
+	}
+	if err := i.saveHistory(revisions); err != nil {
+		log.Printf("Instance: could not save history of instance %s/%s: %v", i.Namespace, i.Name, err)
+	}
+}
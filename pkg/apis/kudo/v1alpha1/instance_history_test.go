@@ -0,0 +1,131 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+)
+
+func TestParameterChanges(t *testing.T) {
+	changes := ParameterChanges(
+		map[string]string{"A": "1", "B": "2", "C": "3"},
+		map[string]string{"A": "1", "B": "5", "D": "4"},
+	)
+	expected := []ParameterChange{
+		{Name: "B", Old: kudo.String("2"), New: kudo.String("5")},
+		{Name: "C", Old: kudo.String("3")},
+		{Name: "D", New: kudo.String("4")},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %v but got %v", expected, changes)
+	}
+}
+
+func TestAddRevision_KeepsLatest(t *testing.T) {
+	instance := &Instance{}
+	for i := 0; i < MaxInstanceRevisions+5; i++ {
+		if err := instance.AddRevision(InstanceRevision{Action: fmt.Sprintf("update-%d", i)}); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+	}
+	revisions, err := instance.History()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if len(revisions) != MaxInstanceRevisions {
+		t.Fatalf("expected %d revisions but got %d", MaxInstanceRevisions, len(revisions))
+	}
+	if revisions[0].Action != "update-5" || revisions[len(revisions)-1].Action != fmt.Sprintf("update-%d", MaxInstanceRevisions+4) {
+		t.Errorf("expected the latest revisions to be kept but got %s to %s", revisions[0].Action, revisions[len(revisions)-1].Action)
+	}
+}
+
+func TestInstanceHistory_PlanOutcome(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Plans: map[string]Plan{"deploy": {}}}}
+	instance := &Instance{}
+	if err := instance.AddRevision(InstanceRevision{Action: "install"}); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := instance.StartPlanExecution("deploy", ov); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	instance.UpdateInstanceStatus(&PlanStatus{Name: "deploy", Status: ExecutionInProgress})
+	instance.UpdateInstanceStatus(&PlanStatus{Name: "deploy", Status: ExecutionFatalError, Message: "plan deploy timed out after 1m0s"})
+
+	// a plan started without a new revision must not be attributed to the last one
+	if err := instance.StartPlanExecution("deploy", ov); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	instance.UpdateInstanceStatus(&PlanStatus{Name: "deploy", Status: ExecutionComplete})
+
+	revisions, err := instance.History()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if len(revisions) != 1 {
+		t.Fatalf("expected 1 revision but got %d", len(revisions))
+	}
+	r := revisions[0]
+	if r.Plan != "deploy" || r.PlanStatus != ExecutionFatalError || r.PlanMessage != "plan deploy timed out after 1m0s" || r.PlanFinishedAt == nil {
+		t.Errorf("unexpected plan outcome recorded: %+v", r)
+	}
+}
+
+func TestSpecAtRevision(t *testing.T) {
+	instance := &Instance{Spec: InstanceSpec{Parameters: map[string]string{"A": "3", "C": "1"}}}
+	revisions := []InstanceRevision{
+		{Action: "install", OperatorVersion: "test-1.0", Parameters: []ParameterChange{{Name: "A", New: kudo.String("1")}, {Name: "B", New: kudo.String("1")}}},
+		{Action: "update", OperatorVersion: "test-1.0", Parameters: []ParameterChange{{Name: "A", Old: kudo.String("1"), New: kudo.String("2")}, {Name: "B", Old: kudo.String("1")}}},
+		{Action: "upgrade", OperatorVersion: "test-1.1", Parameters: []ParameterChange{{Name: "A", Old: kudo.String("2"), New: kudo.String("3")}, {Name: "C", New: kudo.String("1")}}},
+	}
+	for _, r := range revisions {
+		if err := instance.AddRevision(r); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+	}
+
+	tests := []struct {
+		revision int
+		ov       string
+		params   map[string]string
+		err      string
+	}{
+		{revision: 1, ov: "test-1.0", params: map[string]string{"A": "1", "B": "1"}},
+		{revision: 2, ov: "test-1.0", params: map[string]string{"A": "2"}},
+		{revision: 3, ov: "test-1.1", params: map[string]string{"A": "3", "C": "1"}},
+		{revision: 4, err: "revision 4 not found in history of instance /, available revisions are 1 to 3"},
+	}
+	for _, tt := range tests {
+		spec, err := instance.SpecAtRevision(tt.revision)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("revision %d: expected error %q but got %v", tt.revision, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("revision %d: expected no error but got %v", tt.revision, err)
+		}
+		if spec.OperatorVersion.Name != tt.ov || !reflect.DeepEqual(spec.Parameters, tt.params) {
+			t.Errorf("revision %d: expected %s %v but got %s %v", tt.revision, tt.ov, tt.params, spec.OperatorVersion.Name, spec.Parameters)
+		}
+	}
+}
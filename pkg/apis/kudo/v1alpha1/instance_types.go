@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"strings"
 
 	"github.com/kudobuilder/kudo/pkg/util/kudo"
 
@@ -33,19 +34,91 @@ type InstanceSpec struct {
 	OperatorVersion corev1.ObjectReference `json:"operatorVersion,omitempty"`
 
 	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Schedules trigger plans of the instance periodically
+	// +optional
+	Schedules []PlanSchedule `json:"schedules,omitempty"`
+
+	// Overlay selects the overlay of the OperatorVersion whose patches are applied to the rendered templates
+	// +optional
+	Overlay string `json:"overlay,omitempty"`
+
+	// Patches are applied to the rendered templates after the patches of the overlay
+	// +optional
+	Patches []Patch `json:"patches,omitempty"`
+
+	// Placement constrains the nodes all pods of the instance are scheduled on
+	// +optional
+	Placement *Placement `json:"placement,omitempty"`
+
+	// PodMetadata are labels and annotations added to all pods of the instance
+	// +optional
+	PodMetadata *PodMetadata `json:"podMetadata,omitempty"`
+
+	// ClusterTarget is the name of the ClusterTarget in the namespace of the instance whose cluster the resources of
+	// the instance are applied to. The resources are applied to the cluster of the instance if it is empty.
+	// +optional
+	ClusterTarget string `json:"clusterTarget,omitempty"`
+
+	// ServiceAccountName is the name of a ServiceAccount in the namespace of the instance. The resources of the
+	// instance are applied impersonating it, so that they are bounded by the RBAC permissions granted to it. The
+	// resources are applied with the permissions of the KUDO manager if it is empty.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
 }
 
 // InstanceStatus defines the observed state of Instance
 type InstanceStatus struct {
+	// ObservedGeneration is the most recent generation of the Instance spec that was observed by the controller.
+	// When it is lower than the generation in the metadata, the controller did not react to the latest spec change yet.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
 	// slice would be enough here but we cannot use slice because order of sequence in yaml is considered significant while here it's not
 	PlanStatus       map[string]PlanStatus `json:"planStatus,omitempty"`
 	AggregatedStatus AggregatedStatus      `json:"aggregatedStatus,omitempty"`
+	// Conditions are the latest available observations of the instance state
+	Conditions []InstanceCondition `json:"conditions,omitempty"`
+	// Drift is the result of the last drift detection, it is only set if drift detection is enabled in the manager
+	Drift *DriftStatus `json:"drift,omitempty"`
+	// Schedules tracks the executions of the schedules of the spec by their name
+	Schedules map[string]ScheduleStatus `json:"schedules,omitempty"`
+	// DryRun is the report of the last requested dry-run of a plan
+	DryRun *DryRunStatus `json:"dryRun,omitempty"`
+	// PreUpgradeCheck reports the results of the last requested pre-upgrade checks
+	PreUpgradeCheck *PreUpgradeCheckStatus `json:"preUpgradeCheck,omitempty"`
+	// Endpoints are the endpoints of the OperatorVersion, rendered when the last plan completed
+	Endpoints []EndpointStatus `json:"endpoints,omitempty"`
+}
+
+// InstanceConditionType is a valid value for InstanceCondition.Type
+type InstanceConditionType string
+
+const (
+	// InstanceReady is true when the last executed plan completed successfully
+	InstanceReady InstanceConditionType = "Ready"
+
+	// InstanceProgressing is true while a plan is being executed on the instance
+	InstanceProgressing InstanceConditionType = "Progressing"
+)
+
+// InstanceCondition describes the state of an instance at a certain point
+type InstanceCondition struct {
+	Type   InstanceConditionType  `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a one-word CamelCase reason for the condition's last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message indicating details about the transition
+	Message string `json:"message,omitempty"`
 }
 
 // AggregatedStatus is overview of an instance status derived from the plan status
 type AggregatedStatus struct {
 	Status         ExecutionStatus `json:"status,omitempty"`
 	ActivePlanName string          `json:"activePlanName,omitempty"`
+	// LastPlanName is the name of the plan that was started last, unlike the active plan it is kept once the plan
+	// finished
+	LastPlanName string `json:"lastPlanName,omitempty"`
 }
 
 // PlanStatus is representing status of a plan
@@ -74,21 +147,62 @@ type AggregatedStatus struct {
 type PlanStatus struct {
 	Name            string          `json:"name,omitempty"`
 	Status          ExecutionStatus `json:"status,omitempty"`
+	Message         string          `json:"message,omitempty"`
+	StartedAt       metav1.Time     `json:"startedAt,omitempty"`
 	LastFinishedRun metav1.Time     `json:"lastFinishedRun,omitempty"`
 	Phases          []PhaseStatus   `json:"phases,omitempty"`
+	// Execution is the name of the PlanExecution recording the last run of the plan, it is empty if the run is not
+	// recorded
+	Execution string `json:"execution,omitempty"`
 }
 
 // PhaseStatus is representing status of a phase
 type PhaseStatus struct {
-	Name   string          `json:"name,omitempty"`
-	Status ExecutionStatus `json:"status,omitempty"`
-	Steps  []StepStatus    `json:"steps,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Status    ExecutionStatus `json:"status,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	StartedAt metav1.Time     `json:"startedAt,omitempty"`
+	Steps     []StepStatus    `json:"steps,omitempty"`
 }
 
 // StepStatus is representing status of a step
 type StepStatus struct {
-	Name   string          `json:"name,omitempty"`
-	Status ExecutionStatus `json:"status,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Status    ExecutionStatus `json:"status,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	StartedAt metav1.Time     `json:"startedAt,omitempty"`
+	// Resources lists the objects applied by the tasks of the step with their health as of the last execution
+	Resources []ResourceStatus `json:"resources,omitempty"`
+	// Warnings are the warnings the API server returned when the objects were applied, e.g. for deprecated APIs
+	Warnings []string `json:"warnings,omitempty"`
+	// CompletedTasks are the tasks of the step in progress that succeeded. They are not run again until the step is
+	// executed anew, e.g. commands of Exec tasks are not repeated while other tasks of the step are running.
+	CompletedTasks []string `json:"completedTasks,omitempty"`
+}
+
+// ResourceHealth is the health of an object applied by a step
+type ResourceHealth string
+
+const (
+	// ResourceReady is used for objects that were applied and are healthy
+	ResourceReady ResourceHealth = "Ready"
+	// ResourceProgressing is used for objects that were applied but are not healthy yet
+	ResourceProgressing ResourceHealth = "Progressing"
+	// ResourceFailed is used for objects that could not be applied or failed permanently
+	ResourceFailed ResourceHealth = "Failed"
+)
+
+// ResourceStatus is the health of an object applied by a step
+type ResourceStatus struct {
+	// Task is the task of the step that applied the object
+	Task       string         `json:"task,omitempty"`
+	APIVersion string         `json:"apiVersion,omitempty"`
+	Kind       string         `json:"kind,omitempty"`
+	Namespace  string         `json:"namespace,omitempty"`
+	Name       string         `json:"name,omitempty"`
+	Health     ResourceHealth `json:"health,omitempty"`
+	// Message is the last error of the API server or the health check of the object
+	Message string `json:"message,omitempty"`
 }
 
 // ExecutionStatus captures the state of the rollout.
@@ -200,6 +314,10 @@ func (i *Instance) EnsurePlanStatusInitialized(ov *OperatorVersion) {
 	}
 
 	for planName, plan := range ov.Spec.Plans {
+		// the status of a plan referencing other plans holds the steps of the referenced plans
+		if resolved, err := ResolvePlan(ov.Spec.Plans, planName); err == nil {
+			plan = resolved
+		}
 		planStatus := &PlanStatus{
 			Name:   planName,
 			Status: ExecutionNeverRun,
@@ -248,7 +366,7 @@ func (i *Instance) EnsurePlanStatusInitialized(ov *OperatorVersion) {
 
 // StartPlanExecution mark plan as to be executed
 func (i *Instance) StartPlanExecution(planName string, ov *OperatorVersion) error {
-	if i.NoPlanEverExecuted() || isUpgradePlan(planName) {
+	if i.NoPlanEverExecuted() || isUpgradePlan(planName, ov) {
 		i.EnsurePlanStatusInitialized(ov)
 	}
 
@@ -272,6 +390,10 @@ func (i *Instance) StartPlanExecution(planName string, ov *OperatorVersion) erro
 			// update activePlan and instance status
 			i.Status.AggregatedStatus.Status = ExecutionPending
 			i.Status.AggregatedStatus.ActivePlanName = planName
+			i.Status.AggregatedStatus.LastPlanName = planName
+			i.Status.updateConditions(&planStatus)
+			i.recordPlanStarted(planName)
+			i.recordScheduledPlanStarted(planName)
 
 			break
 		}
@@ -280,6 +402,13 @@ func (i *Instance) StartPlanExecution(planName string, ov *OperatorVersion) erro
 		return &InstanceError{fmt.Errorf("asked to execute a plan %s but no such plan found in instance %s/%s", planName, i.Namespace, i.Name), kudo.String("PlanNotFound")}
 	}
 
+	// a requested update plan only applies to the parameter change it was requested with
+	delete(i.Annotations, UpdatePlanAnnotation)
+	// approvals only apply to the execution they were given for
+	delete(i.Annotations, ApprovalsAnnotation)
+	// skipped pre-upgrade checks only apply to the upgrade they were skipped for
+	delete(i.Annotations, SkipPreUpgradeChecksAnnotation)
+
 	err := i.SaveSnapshot()
 	if err != nil {
 		return err
@@ -289,11 +418,11 @@ func (i *Instance) StartPlanExecution(planName string, ov *OperatorVersion) erro
 }
 
 // isUpgradePlan returns true if this could be an upgrade plan - this is just an approximation because deploy plan can be used for both
-func isUpgradePlan(planName string) bool {
-	return planName == DeployPlanName || planName == UpgradePlanName
+func isUpgradePlan(planName string, ov *OperatorVersion) bool {
+	return planName == ov.Spec.DefaultPlans.DeployPlan() || planName == ov.Spec.DefaultPlans.UpgradePlan()
 }
 
-// UpdateInstanceStatus updates `Status.PlanStatus` and `Status.AggregatedStatus` property based on the given plan
+// UpdateInstanceStatus updates `Status.PlanStatus`, `Status.AggregatedStatus` and `Status.Conditions` property based on the given plan
 func (i *Instance) UpdateInstanceStatus(planStatus *PlanStatus) {
 	for k, v := range i.Status.PlanStatus {
 		if v.Name == planStatus.Name {
@@ -302,10 +431,56 @@ func (i *Instance) UpdateInstanceStatus(planStatus *PlanStatus) {
 			if planStatus.Status.IsTerminal() {
 				i.Status.AggregatedStatus.ActivePlanName = ""
 			}
+			i.Status.updateConditions(planStatus)
+			i.recordPlanStatus(planStatus)
 		}
 	}
 }
 
+// updateConditions derives the instance conditions from the status of the given plan
+func (s *InstanceStatus) updateConditions(planStatus *PlanStatus) {
+	switch {
+	case planStatus.Status.IsRunning():
+		s.SetCondition(InstanceProgressing, corev1.ConditionTrue, "PlanInProgress", fmt.Sprintf("plan %s is being executed", planStatus.Name))
+	case planStatus.Status == ExecutionComplete:
+		s.SetCondition(InstanceProgressing, corev1.ConditionFalse, "PlanComplete", fmt.Sprintf("plan %s completed", planStatus.Name))
+		s.SetCondition(InstanceReady, corev1.ConditionTrue, "PlanComplete", fmt.Sprintf("plan %s completed", planStatus.Name))
+	case planStatus.Status == ExecutionFatalError:
+		message := fmt.Sprintf("plan %s failed", planStatus.Name)
+		if planStatus.Message != "" {
+			message = fmt.Sprintf("%s: %s", message, planStatus.Message)
+		}
+		s.SetCondition(InstanceProgressing, corev1.ConditionFalse, "PlanFailed", message)
+		s.SetCondition(InstanceReady, corev1.ConditionFalse, "PlanFailed", message)
+	}
+}
+
+// GetCondition returns the condition of the given type or nil if no such condition exists
+func (s *InstanceStatus) GetCondition(t InstanceConditionType) *InstanceCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or updates the condition of the given type. The transition time is only changed when the
+// status of the condition changes.
+func (s *InstanceStatus) SetCondition(t InstanceConditionType, status corev1.ConditionStatus, reason, message string) {
+	c := s.GetCondition(t)
+	if c == nil {
+		s.Conditions = append(s.Conditions, InstanceCondition{Type: t})
+		c = &s.Conditions[len(s.Conditions)-1]
+	}
+	if c.Status != status {
+		c.Status = status
+		c.LastTransitionTime = metav1.Now()
+	}
+	c.Reason = reason
+	c.Message = message
+}
+
 const snapshotAnnotation = "kudo.dev/last-applied-instance-state"
 
 // SaveSnapshot stores the current spec of Instance into the snapshot annotation
@@ -355,7 +530,7 @@ func (i *Instance) GetPlanToBeExecuted(ov *OperatorVersion) (*string, error) {
 
 	// new instance, need to run deploy plan
 	if i.NoPlanEverExecuted() {
-		return kudo.String(DeployPlanName), nil
+		return kudo.String(ov.Spec.DefaultPlans.DeployPlan()), nil
 	}
 
 	// did the instance change so that we need to run deploy/upgrade/update plan?
@@ -370,9 +545,10 @@ func (i *Instance) GetPlanToBeExecuted(ov *OperatorVersion) (*string, error) {
 	if instanceSnapshot.OperatorVersion.Name != i.Spec.OperatorVersion.Name {
 		// this instance was upgraded to newer version
 		log.Printf("Instance: instance %s/%s was upgraded from %s to %s operatorversion", i.Namespace, i.Name, instanceSnapshot.OperatorVersion.Name, i.Spec.OperatorVersion.Name)
-		plan := selectPlan([]string{UpgradePlanName, UpdatePlanName, DeployPlanName}, ov)
+		plans := ov.Spec.DefaultPlans.upgradePlans()
+		plan := selectPlan(plans, ov)
 		if plan == nil {
-			return nil, &InstanceError{fmt.Errorf("supposed to execute plan because instance %s/%s was upgraded but none of the deploy, upgrade, update plans found in linked operatorVersion", i.Namespace, i.Name), kudo.String("PlanNotFound")}
+			return nil, &InstanceError{fmt.Errorf("supposed to execute plan because instance %s/%s was upgraded but none of the %s plans found in linked operatorVersion", i.Namespace, i.Name, strings.Join(plans, ", ")), kudo.String("PlanNotFound")}
 		}
 		return plan, nil
 	}
@@ -380,36 +556,53 @@ func (i *Instance) GetPlanToBeExecuted(ov *OperatorVersion) (*string, error) {
 	if !reflect.DeepEqual(instanceSnapshot.Parameters, i.Spec.Parameters) {
 		// instance updated
 		log.Printf("Instance: instance %s/%s has updated parameters from %v to %v", i.Namespace, i.Name, instanceSnapshot.Parameters, i.Spec.Parameters)
-		paramDiff := parameterDifference(instanceSnapshot.Parameters, i.Spec.Parameters)
-		paramDefinitions := getParamDefinitions(paramDiff, ov)
-		plan := planNameFromParameters(paramDefinitions, ov)
+		if requested := i.Annotations[UpdatePlanAnnotation]; requested != "" {
+			// the plan requested with the update overrides the triggers of the parameters
+			if selectPlan([]string{requested}, ov) == nil {
+				return nil, &InstanceError{fmt.Errorf("supposed to execute plan %s requested for the update of instance %s/%s but the plan is not found in linked operatorVersion", requested, i.Namespace, i.Name), kudo.String("PlanNotFound")}
+			}
+			return kudo.String(requested), nil
+		}
+		plan := TriggeredPlan(ov, instanceSnapshot.Parameters, i.Spec.Parameters)
 		if plan == nil {
-			return nil, &InstanceError{fmt.Errorf("supposed to execute plan because instance %s/%s was updatet but none of the deploy, update plans found in linked operatorVersion", i.Namespace, i.Name), kudo.String("PlanNotFound")}
+			return nil, &InstanceError{fmt.Errorf("supposed to execute plan because instance %s/%s was updatet but none of the %s plans found in linked operatorVersion", i.Namespace, i.Name, strings.Join(ov.Spec.DefaultPlans.updatePlans(), ", ")), kudo.String("PlanNotFound")}
+		}
+		return plan, nil
+	}
+	// is a schedule of the instance due?
+	if plan := i.GetScheduledPlan(); plan != nil {
+		if selectPlan([]string{*plan}, ov) == nil {
+			return nil, &InstanceError{fmt.Errorf("supposed to execute scheduled plan %s on instance %s/%s but the plan is not found in linked operatorVersion", *plan, i.Namespace, i.Name), kudo.String("PlanNotFound")}
 		}
 		return plan, nil
 	}
 	return nil, nil
 }
 
+// TriggeredPlan returns the plan that is executed when the parameters of an instance of the OperatorVersion change from
+// old to new. If changed parameters trigger different plans, the trigger of the first of them in the order of the
+// OperatorVersion wins. It returns nil if no plan can be executed.
+func TriggeredPlan(ov *OperatorVersion, old, new map[string]string) *string {
+	return planNameFromParameters(getParamDefinitions(parameterDifference(old, new), ov), ov)
+}
+
 // planNameFromParameters determines what plan to run based on params that changed and the related trigger plans
 func planNameFromParameters(params []Parameter, ov *OperatorVersion) *string {
 	for _, p := range params {
-		// TODO: if the params have different trigger plans, we always select first here which might not be ideal
+		// if the params have different trigger plans, the first one is selected
 		if p.Trigger != "" && selectPlan([]string{p.Trigger}, ov) != nil {
 			return kudo.String(p.Trigger)
 		}
 	}
-	return selectPlan([]string{UpdatePlanName, DeployPlanName}, ov)
+	return selectPlan(ov.Spec.DefaultPlans.updatePlans(), ov)
 }
 
-// getParamDefinitions retrieves parameter metadata from OperatorVersion CRD
+// getParamDefinitions retrieves parameter metadata from OperatorVersion CRD in the order of the OperatorVersion
 func getParamDefinitions(params map[string]string, ov *OperatorVersion) []Parameter {
 	defs := []Parameter{}
-	for p1 := range params {
-		for _, p2 := range ov.Spec.Parameters {
-			if p2.Name == p1 {
-				defs = append(defs, p2)
-			}
+	for _, p := range ov.Spec.Parameters {
+		if _, ok := params[p.Name]; ok {
+			defs = append(defs, p)
 		}
 	}
 	return defs
@@ -441,6 +634,7 @@ func parameterDifference(old, new map[string]string) map[string]string {
 
 // Instance is the Schema for the instances API.
 // +k8s:openapi-gen=true
+// +kubebuilder:subresource:status
 type Instance struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -449,6 +643,11 @@ type Instance struct {
 	Status InstanceStatus `json:"status,omitempty"`
 }
 
+// IsSpecObserved returns true if the controller has already observed the latest change of the Instance spec.
+func (i *Instance) IsSpecObserved() bool {
+	return i.Status.ObservedGeneration >= i.Generation
+}
+
 // OperatorVersionNamespace returns the namespace of the OperatorVersion that the Instance references.
 func (i *Instance) OperatorVersionNamespace() string {
 	if i.Spec.OperatorVersion.Namespace == "" {
@@ -457,6 +656,20 @@ func (i *Instance) OperatorVersionNamespace() string {
 	return i.Spec.OperatorVersion.Namespace
 }
 
+// ProtectedAnnotation protects an Instance from accidental deletion when set to "true". Deletions of protected Instances
+// are rejected by the admission webhook of the manager, kudoctl removes the protection when forced to uninstall them.
+// Without the webhooks only kudoctl respects the protection, 'kubectl kudo doctor' reports such Instances.
+const ProtectedAnnotation = "kudo.dev/protected"
+
+// IsProtected returns true if the Instance is protected from deletion.
+func (i *Instance) IsProtected() bool {
+	return i.Annotations[ProtectedAnnotation] == "true"
+}
+
+// UpdatePlanAnnotation names the plan that is executed for the next parameter change of an Instance instead of the
+// plan its parameters trigger. It is removed once a plan starts, kudoctl sets it with 'update --plan'.
+const UpdatePlanAnnotation = "kudo.dev/update-plan"
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // InstanceList contains a list of Instance.
@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -74,3 +75,227 @@ func TestGetLastExecutedPlanStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdateInstanceStatus_Conditions(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      ExecutionStatus
+		progressing corev1.ConditionStatus
+		ready       corev1.ConditionStatus
+	}{
+		{"plan in progress", ExecutionInProgress, corev1.ConditionTrue, ""},
+		{"plan complete", ExecutionComplete, corev1.ConditionFalse, corev1.ConditionTrue},
+		{"plan failed", ExecutionFatalError, corev1.ConditionFalse, corev1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		i := Instance{}
+		i.Status.PlanStatus = map[string]PlanStatus{"deploy": {Name: "deploy", Status: ExecutionPending}}
+		i.UpdateInstanceStatus(&PlanStatus{Name: "deploy", Status: tt.status})
+
+		progressing := i.Status.GetCondition(InstanceProgressing)
+		if progressing == nil || progressing.Status != tt.progressing {
+			t.Errorf("%s: expected Progressing condition to be %s but got %v", tt.name, tt.progressing, progressing)
+		}
+		ready := i.Status.GetCondition(InstanceReady)
+		if tt.ready == "" {
+			if ready != nil {
+				t.Errorf("%s: expected no Ready condition but got %v", tt.name, ready)
+			}
+		} else if ready == nil || ready.Status != tt.ready {
+			t.Errorf("%s: expected Ready condition to be %s but got %v", tt.name, tt.ready, ready)
+		}
+	}
+}
+
+func TestStartPlanExecution_LastPlanName(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Plans: map[string]Plan{"deploy": {}}}}
+	i := Instance{}
+	if err := i.StartPlanExecution("deploy", ov); err != nil {
+		t.Fatal(err)
+	}
+	i.UpdateInstanceStatus(&PlanStatus{Name: "deploy", Status: ExecutionComplete})
+
+	if i.Status.AggregatedStatus.ActivePlanName != "" {
+		t.Errorf("expected no active plan once the plan finished but got %s", i.Status.AggregatedStatus.ActivePlanName)
+	}
+	if i.Status.AggregatedStatus.LastPlanName != "deploy" {
+		t.Errorf("expected the last plan to be kept once the plan finished but got %q", i.Status.AggregatedStatus.LastPlanName)
+	}
+}
+
+func TestSetCondition_TransitionTime(t *testing.T) {
+	s := InstanceStatus{}
+	s.SetCondition(InstanceReady, corev1.ConditionFalse, "PlanFailed", "plan deploy failed")
+	transitionTime := v1.Time{Time: time.Now().Add(-time.Hour)}
+	s.Conditions[0].LastTransitionTime = transitionTime
+
+	s.SetCondition(InstanceReady, corev1.ConditionFalse, "PlanFailed", "plan update failed")
+	if len(s.Conditions) != 1 {
+		t.Fatalf("expected exactly one condition but got %v", s.Conditions)
+	}
+	if !s.Conditions[0].LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("expected transition time to stay unchanged when status does not change")
+	}
+	if s.Conditions[0].Message != "plan update failed" {
+		t.Errorf("expected message to be updated but got %s", s.Conditions[0].Message)
+	}
+
+	s.SetCondition(InstanceReady, corev1.ConditionTrue, "PlanComplete", "plan deploy completed")
+	if s.Conditions[0].LastTransitionTime.Equal(&transitionTime) {
+		t.Errorf("expected transition time to change when status changes")
+	}
+}
+
+func TestGetPlanToBeExecuted_Schedule(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Plans: map[string]Plan{"deploy": {}, "backup": {}}}}
+	i := &Instance{Spec: InstanceSpec{Schedules: []PlanSchedule{{Name: "nightly", Plan: "backup", Schedule: "@daily"}}}}
+	i.Status.PlanStatus = map[string]PlanStatus{
+		"deploy": {Name: "deploy", Status: ExecutionComplete},
+		"backup": {Name: "backup", Status: ExecutionNeverRun},
+	}
+	if err := i.SaveSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := i.GetPlanToBeExecuted(ov)
+	if err != nil || plan != nil {
+		t.Fatalf("expected no plan without pending schedule but got %v, %v", plan, err)
+	}
+
+	i.Status.Schedules = map[string]ScheduleStatus{"nightly": {Pending: true}}
+	plan, err = i.GetPlanToBeExecuted(ov)
+	if err != nil || plan == nil || *plan != "backup" {
+		t.Fatalf("expected scheduled plan backup but got %v, %v", plan, err)
+	}
+
+	if err := i.StartPlanExecution(*plan, ov); err != nil {
+		t.Fatal(err)
+	}
+	if status := i.Status.Schedules["nightly"]; status.Pending || status.LastExecutionTime == nil {
+		t.Errorf("expected the schedule to be executed but got %+v", status)
+	}
+}
+
+func TestStartPlanExecution_SkipPreUpgradeChecks(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Plans: map[string]Plan{"upgrade": {}}}}
+	i := &Instance{ObjectMeta: v1.ObjectMeta{Annotations: map[string]string{SkipPreUpgradeChecksAnnotation: "true"}}}
+	i.Status.PlanStatus = map[string]PlanStatus{"upgrade": {Name: "upgrade", Status: ExecutionNeverRun}}
+
+	if err := i.StartPlanExecution("upgrade", ov); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.Annotations[SkipPreUpgradeChecksAnnotation]; ok {
+		t.Errorf("expected the skipped pre-upgrade checks to be removed once the upgrade plan started")
+	}
+}
+
+func TestGetPlanToBeExecuted_UpdatePlan(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{
+		Plans:      map[string]Plan{"deploy": {}, "update": {}, "rolling-restart": {}},
+		Parameters: []Parameter{{Name: "MEMORY"}},
+	}}
+	i := &Instance{Spec: InstanceSpec{Parameters: map[string]string{"MEMORY": "1Gi"}}}
+	i.Status.PlanStatus = map[string]PlanStatus{
+		"deploy":          {Name: "deploy", Status: ExecutionComplete},
+		"update":          {Name: "update", Status: ExecutionNeverRun},
+		"rolling-restart": {Name: "rolling-restart", Status: ExecutionNeverRun},
+	}
+	if err := i.SaveSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+
+	i.Spec.Parameters = map[string]string{"MEMORY": "2Gi"}
+	i.Annotations[UpdatePlanAnnotation] = "restart"
+	if _, err := i.GetPlanToBeExecuted(ov); err == nil {
+		t.Errorf("expected a requested plan that does not exist to fail")
+	}
+
+	i.Annotations[UpdatePlanAnnotation] = "rolling-restart"
+	plan, err := i.GetPlanToBeExecuted(ov)
+	if err != nil || plan == nil || *plan != "rolling-restart" {
+		t.Fatalf("expected the requested plan rolling-restart but got %v, %v", plan, err)
+	}
+	if err := i.StartPlanExecution(*plan, ov); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := i.Annotations[UpdatePlanAnnotation]; ok {
+		t.Errorf("expected the requested plan to be removed once it started")
+	}
+	i.Status.PlanStatus["rolling-restart"] = PlanStatus{Name: "rolling-restart", Status: ExecutionComplete}
+	i.Status.AggregatedStatus.ActivePlanName = ""
+
+	// the next update triggers the plan of its parameters again
+	i.Spec.Parameters = map[string]string{"MEMORY": "4Gi"}
+	plan, err = i.GetPlanToBeExecuted(ov)
+	if err != nil || plan == nil || *plan != "update" {
+		t.Errorf("expected the triggered plan update but got %v, %v", plan, err)
+	}
+}
+
+func TestTriggeredPlan(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{
+		Plans: map[string]Plan{"deploy": {}, "update": {}, "restart": {}},
+		Parameters: []Parameter{
+			{Name: "REPLICAS"},
+			{Name: "MEMORY", Trigger: "restart"},
+			{Name: "CPU", Trigger: "restart"},
+			{Name: "LOG_LEVEL", Trigger: "reload"},
+		},
+	}}
+	old := map[string]string{"REPLICAS": "3", "MEMORY": "1Gi"}
+
+	tests := []struct {
+		name     string
+		new      map[string]string
+		expected string
+	}{
+		{"parameter without trigger", map[string]string{"REPLICAS": "5", "MEMORY": "1Gi"}, "update"},
+		{"trigger", map[string]string{"REPLICAS": "3", "MEMORY": "2Gi"}, "restart"},
+		{"removed parameter", map[string]string{"REPLICAS": "3"}, "restart"},
+		{"trigger of a missing plan", map[string]string{"REPLICAS": "3", "MEMORY": "1Gi", "LOG_LEVEL": "debug"}, "update"},
+		{"first trigger in the order of the operatorversion", map[string]string{"REPLICAS": "5", "MEMORY": "2Gi", "LOG_LEVEL": "debug"}, "restart"},
+	}
+	for _, tt := range tests {
+		plan := TriggeredPlan(ov, old, tt.new)
+		if plan == nil || *plan != tt.expected {
+			t.Errorf("%s: expected plan %s but got %v", tt.name, tt.expected, plan)
+		}
+	}
+
+	if plan := TriggeredPlan(&OperatorVersion{}, old, map[string]string{}); plan != nil {
+		t.Errorf("expected no plan without deploy and update plans but got %s", *plan)
+	}
+}
+
+func TestGetPlanToBeExecuted_DefaultPlans(t *testing.T) {
+	ov := &OperatorVersion{
+		ObjectMeta: v1.ObjectMeta{Name: "kafka-1.1.0"},
+		Spec: OperatorVersionSpec{
+			Plans:        map[string]Plan{"install": {}, "reconfigure": {}, "update": {}},
+			DefaultPlans: &DefaultPlans{Deploy: "install", Update: "reconfigure"},
+		},
+	}
+	i := &Instance{Spec: InstanceSpec{OperatorVersion: corev1.ObjectReference{Name: "kafka-1.0.0"}}}
+
+	plan, err := i.GetPlanToBeExecuted(ov)
+	if err != nil || plan == nil || *plan != "install" {
+		t.Fatalf("expected the declared deploy plan install for a new instance but got %v, %v", plan, err)
+	}
+	if err := i.StartPlanExecution(*plan, ov); err != nil {
+		t.Fatal(err)
+	}
+	i.Status.PlanStatus["install"] = PlanStatus{Name: "install", Status: ExecutionComplete}
+	i.Status.AggregatedStatus.ActivePlanName = ""
+
+	i.Spec.Parameters = map[string]string{"REPLICAS": "3"}
+	if plan := TriggeredPlan(ov, nil, i.Spec.Parameters); plan == nil || *plan != "reconfigure" {
+		t.Errorf("expected the declared update plan reconfigure but got %v", plan)
+	}
+
+	i.Spec.OperatorVersion.Name = "kafka-1.1.0"
+	plan, err = i.GetPlanToBeExecuted(ov)
+	if err != nil || plan == nil || *plan != "reconfigure" {
+		t.Errorf("expected an upgrade without upgrade plan to fall back to the update plan reconfigure but got %v, %v", plan, err)
+	}
+}
@@ -26,6 +26,19 @@ type OperatorSpec struct {
 	KubernetesVersion string        `json:"kubernetesVersion,omitempty"`
 	Maintainers       []*Maintainer `json:"maintainers,omitempty"`
 	URL               string        `json:"url,omitempty"`
+
+	// Icon is the URL of an icon that represents the operator in catalogs.
+	Icon string `json:"icon,omitempty"`
+	// Keywords are used to find the operator in catalogs.
+	Keywords []string `json:"keywords,omitempty"`
+	// Categories the operator is listed under in catalogs, e.g. database or messaging.
+	Categories []string `json:"categories,omitempty"`
+	// Links are additional resources of the operator like documentation or source code.
+	Links []*Link `json:"links,omitempty"`
+
+	// Requirements are the capabilities of the cluster the operator needs beyond the Kubernetes version.
+	// +optional
+	Requirements *ClusterRequirements `json:"requirements,omitempty"`
 }
 
 // Maintainer describes an Operator maintainer.
@@ -37,6 +50,15 @@ type Maintainer struct {
 	Email string `json:"email,omitempty"`
 }
 
+// Link describes an additional resource of an Operator.
+type Link struct {
+	// Name is a short description of the resource, e.g. Documentation.
+	Name string `json:"name,omitempty"`
+
+	// URL is the location of the resource.
+	URL string `json:"url,omitempty"`
+}
+
 // OperatorStatus defines the observed state of Operator
 type OperatorStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
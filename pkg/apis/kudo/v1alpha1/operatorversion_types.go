@@ -17,6 +17,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -26,6 +27,9 @@ type OperatorVersionSpec struct {
 	// +optional
 	Operator corev1.ObjectReference `json:"operator,omitempty"`
 	Version  string                 `json:"version,omitempty"`
+	// AppVersion is the version of the application the operator manages, e.g. the Kafka version
+	// +optional
+	AppVersion string `json:"appVersion,omitempty"`
 
 	// Yaml captures a templated yaml list of elements that define the application operator instance.
 	Templates map[string]string `json:"templates,omitempty"`
@@ -40,11 +44,41 @@ type OperatorVersionSpec struct {
 	// +optional
 	ConnectionString string `json:"connectionString,omitempty"`
 
+	// Constants are values of the operator that templates reference as .Constants, e.g. internal tunables. Unlike
+	// parameters, they can not be set on an instance. The value is a YAML or JSON object.
+	// +optional
+	Constants *apiextv1beta1.JSON `json:"constants,omitempty"`
+
+	// Endpoints are the ways to connect to an instance of the operator. They are rendered for an instance once a plan
+	// of it completed and reported in its status.
+	// +optional
+	Endpoints []AccessEndpoint `json:"endpoints,omitempty"`
+
 	// Dependencies a list of all dependencies of the operator.
 	Dependencies []OperatorDependency `json:"dependencies,omitempty"`
 
 	// UpgradableFrom lists all OperatorVersions that can upgrade to this OperatorVersion.
 	UpgradableFrom []OperatorVersion `json:"upgradableFrom,omitempty"`
+
+	// CRDs maps the file names of the CustomResourceDefinitions bundled with the operator to their manifests.
+	// They are applied before a plan of an instance starts and are never deleted.
+	// +optional
+	CRDs map[string]string `json:"crds,omitempty"`
+
+	// PreUpgradeChecks have to pass before an instance is upgraded to this OperatorVersion. The admission webhook
+	// rejects upgrades unless the checks passed recently.
+	// +optional
+	PreUpgradeChecks []UpgradeCheck `json:"preUpgradeChecks,omitempty"`
+
+	// Overlays maps an environment name to the patches that are applied to the rendered templates of the instances
+	// selecting it, e.g. a production overlay with more replicas.
+	// +optional
+	Overlays map[string][]Patch `json:"overlays,omitempty"`
+
+	// DefaultPlans declares the plans KUDO executes when an instance is created, updated or upgraded. Plans that are
+	// not declared default to the deploy, update and upgrade plans.
+	// +optional
+	DefaultPlans *DefaultPlans `json:"defaultPlans,omitempty"`
 }
 
 // Ordering specifies how the subitems in this plan/phase should be rolled out.
@@ -64,6 +98,8 @@ type Plan struct {
 	Strategy Ordering `json:"strategy" validate:"required"` // makes field mandatory and checks if set and non empty
 	// Phases maps a phase name to a Phase object.
 	Phases []Phase `json:"phases" validate:"required,gt=0,dive"` // makes field mandatory and checks if its gt 0
+	// Timeout is the maximum duration of the plan execution. A plan exceeding it fails with a fatal error.
+	Timeout *metav1.Duration `json:"timeout,omitempty"` // no checks needed
 }
 
 // Parameter captures the variability of an OperatorVersion being instantiated in an instance.
@@ -84,13 +120,34 @@ type Parameter struct {
 	// Required specifies if the parameter is required to be provided by all instances, or whether a default can suffice.
 	Required bool `json:"required,omitempty"`
 
-	// Default is a default value if no parameter is provided by the instance.
+	// Default is a default value if no parameter is provided by the instance. A default containing a template, e.g.
+	// `{{ mul .Params.NODE_COUNT 2 }}`, is computed from the other parameters when the instance is rendered.
 	Default *string `json:"default,omitempty"`
 
 	// Trigger identifies the plan that gets executed when this parameter changes in the Instance object.
 	// Default is `update` if a plan with that name exists, otherwise it's `deploy`
 	Trigger string `json:"trigger,omitempty"`
 
+	// Type is the type of the parameter value, either `string` (the default), `array` or `object`. The value of an
+	// array parameter is a YAML list, which templates can iterate over with `range`, the value of an object parameter
+	// is a YAML map.
+	Type ParameterType `json:"type,omitempty"`
+
+	// Items is the schema the items of an array parameter must conform to.
+	Items *ParameterItems `json:"items,omitempty"`
+
+	// Schema is the OpenAPI v3 schema the value of an array or object parameter must conform to. It is published
+	// with the OperatorVersion, so that UIs can generate forms for the parameter.
+	Schema *apiextv1beta1.JSONSchemaProps `json:"schema,omitempty"`
+
+	// Deprecated marks the parameter as deprecated, values set for it are moved to the parameter replacing it.
+	Deprecated *ParameterDeprecation `json:"deprecated,omitempty"`
+
+	// Sensitive marks parameters holding credentials or keys. If kudoctl is configured with an encryption provider,
+	// their values are encrypted before they are stored in the Instance and only decrypted by the manager when
+	// templates are rendered. Sensitive parameters have to be of type `string`.
+	Sensitive bool `json:"sensitive,omitempty"`
+
 	// TODO: Add generated parameters (e.g. passwords).
 	// These values should be saved off in a secret instead of updating the spec
 	// with values that viewing the instance does not return credentials.
@@ -104,13 +161,24 @@ type Phase struct {
 
 	// Steps maps a step name to a list of templated Kubernetes objects stored as a string.
 	Steps []Step `json:"steps" validate:"required,gt=0,dive"` // makes field mandatory and checks if its gt 0
+	// Timeout is the maximum duration of the phase execution. A phase exceeding it fails with a fatal error.
+	Timeout *metav1.Duration `json:"timeout,omitempty"` // no checks needed
+	// Manual phases are not started before they are approved, e.g. with 'kubectl kudo plan approve'.
+	Manual bool `json:"manual,omitempty"` // no checks needed
 }
 
 // Step defines a specific set of operations that occur.
 type Step struct {
-	Name   string   `json:"name" validate:"required"`            // makes field mandatory and checks if set and non empty
-	Tasks  []string `json:"tasks" validate:"required,gt=0,dive"` // makes field mandatory and checks if non empty
-	Delete bool     `json:"delete,omitempty"`                    // no checks needed
+	Name   string   `json:"name" validate:"required"`                    // makes field mandatory and checks if set and non empty
+	Tasks  []string `json:"tasks" validate:"required_without=Plan,dive"` // makes field mandatory unless the step references a plan
+	Delete bool     `json:"delete,omitempty"`                            // no checks needed
+	// Plan references another plan of the OperatorVersion whose steps are executed in place of this step. A step
+	// referencing a plan has no tasks.
+	Plan string `json:"plan,omitempty"` // no checks needed
+	// Timeout is the maximum duration of the step execution. A step exceeding it fails with a fatal error.
+	Timeout *metav1.Duration `json:"timeout,omitempty"` // no checks needed
+	// Manual steps are not started before they are approved, e.g. with 'kubectl kudo plan approve'.
+	Manual bool `json:"manual,omitempty"` // no checks needed
 
 	// Objects will be serialized for each instance as the params and defaults are provided.
 	Objects []runtime.Object `json:"-"` // no checks needed
@@ -130,11 +198,19 @@ type Task struct {
 type TaskSpec struct {
 	ResourceTaskSpec
 	DummyTaskSpec
+	InstanceTaskSpec
+	ExecTaskSpec
+	RolloutTaskSpec
 }
 
 // ResourceTaskSpec is referencing a list of resources
 type ResourceTaskSpec struct {
 	Resources []string `json:"resources"`
+	// KindOrder lists kinds that are applied before all other kinds, in the given order. The resources of the other
+	// kinds are applied in the default order of KUDO, e.g. ConfigMaps before workloads. Delete tasks delete the
+	// resources in the reverse order.
+	// +optional
+	KindOrder []string `json:"kindOrder,omitempty"`
 }
 
 // DummyTaskSpec can succeed of fail on demand and is very useful for testing operators
@@ -144,6 +220,62 @@ type DummyTaskSpec struct {
 	Done    bool `json:"done"`
 }
 
+// InstanceTaskSpec creates an Instance of another operator that is owned by the current instance
+type InstanceTaskSpec struct {
+	// OperatorVersion is the name of the OperatorVersion to instantiate. It has to be installed in the namespace of the instance.
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// InstanceName is the name of the created instance. It defaults to the task name and is prefixed with the name of the parent instance.
+	InstanceName string `json:"instanceName,omitempty"`
+	// Parameters of the created instance. Values are templates rendered with the parameters of the parent instance.
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// ExecOrder defines the order an Exec task runs its command in the selected pods
+type ExecOrder string
+
+const (
+	// ExecSequential runs the command in one pod after the other, ordered by pod name
+	ExecSequential ExecOrder = "Sequential"
+	// ExecParallel runs the command in all pods at the same time
+	ExecParallel ExecOrder = "Parallel"
+)
+
+// ExecFailurePolicy defines how an Exec task handles a command that fails in a pod
+type ExecFailurePolicy string
+
+const (
+	// ExecFail fails the plan with a fatal error
+	ExecFail ExecFailurePolicy = "Fail"
+	// ExecRetry retries the task, the command is run again in all selected pods
+	ExecRetry ExecFailurePolicy = "Retry"
+	// ExecIgnore records the failure in the step status and completes the task
+	ExecIgnore ExecFailurePolicy = "Ignore"
+)
+
+// ExecTaskSpec runs a command in the pods of the instance that are selected by labels
+type ExecTaskSpec struct {
+	// PodSelector are the labels of the pods in the namespace of the instance the command is run in. Values are
+	// templates rendered with the parameters of the instance.
+	PodSelector map[string]string `json:"podSelector,omitempty"`
+	// Container is the container the command is run in. It defaults to the first container of the pod.
+	Container string `json:"container,omitempty"`
+	// Command is the command with its arguments. Arguments are templates rendered with the parameters of the instance.
+	Command []string `json:"command,omitempty"`
+	// Order is Sequential (default) or Parallel
+	Order ExecOrder `json:"order,omitempty"`
+	// FailurePolicy is Fail (default), Retry or Ignore
+	FailurePolicy ExecFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+// RolloutTaskSpec applies the resources of the task like an Apply task, but rolls out changes to StatefulSets in
+// batches using the partition of their rolling update. The resources are referenced by the ResourceTaskSpec.
+type RolloutTaskSpec struct {
+	// BatchSize is the number of pods updated at a time. It defaults to 1.
+	BatchSize *int32 `json:"batchSize,omitempty"`
+	// Pause is the time to wait after the pods of a batch are ready before the next batch is updated
+	Pause *metav1.Duration `json:"pause,omitempty"`
+}
+
 // OperatorVersionStatus defines the observed state of OperatorVersion.
 type OperatorVersionStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
@@ -0,0 +1,230 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+)
+
+// The schema of a parameter is a subset of the OpenAPI v3 schema CustomResourceDefinitions are validated with. It
+// supports the type, enum, numeric, string, array and object validations. Composition (allOf, anyOf, oneOf, not),
+// references and pattern properties are not supported. Formats are only informational.
+
+// validateSchemaDefinition checks that the schema only uses supported keywords, that its types are known and that its
+// patterns compile
+func validateSchemaDefinition(path string, s *apiextv1beta1.JSONSchemaProps) []string {
+	var errs []string
+	switch s.Type {
+	case "", "string", "integer", "number", "boolean", "object", "array":
+	default:
+		errs = append(errs, fmt.Sprintf("%s has unknown type %s", path, s.Type))
+	}
+	if s.Ref != nil || len(s.AllOf) > 0 || len(s.AnyOf) > 0 || len(s.OneOf) > 0 || s.Not != nil || len(s.PatternProperties) > 0 {
+		errs = append(errs, fmt.Sprintf("%s uses unsupported keywords, $ref, allOf, anyOf, oneOf, not and patternProperties can not be used", path))
+	}
+	if s.Pattern != "" {
+		if _, err := regexp.Compile(s.Pattern); err != nil {
+			errs = append(errs, fmt.Sprintf("%s has an invalid pattern: %v", path, err))
+		}
+	}
+	for _, e := range s.Enum {
+		var v interface{}
+		if err := json.Unmarshal(e.Raw, &v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s has an invalid enum value %s: %v", path, string(e.Raw), err))
+		}
+	}
+	if s.Items != nil {
+		if len(s.Items.JSONSchemas) > 0 {
+			errs = append(errs, fmt.Sprintf("%s declares a list of item schemas, only a single item schema is supported", path))
+		}
+		if s.Items.Schema != nil {
+			errs = append(errs, validateSchemaDefinition(path+"[]", s.Items.Schema)...)
+		}
+	}
+	for _, name := range sortedKeys(s.Properties) {
+		p := s.Properties[name]
+		errs = append(errs, validateSchemaDefinition(path+"."+name, &p)...)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		errs = append(errs, validateSchemaDefinition(path+".*", s.AdditionalProperties.Schema)...)
+	}
+	return errs
+}
+
+// validateSchema checks that the value conforms to the schema. Values are parsed from YAML via JSON, so numbers are
+// float64. The path describes the value in the returned errors.
+func validateSchema(path string, s *apiextv1beta1.JSONSchemaProps, value interface{}) []string {
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s must not be null", path)}
+	}
+
+	var errs []string
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, path+" "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		allowed := make([]string, 0, len(s.Enum))
+		for _, e := range s.Enum {
+			allowed = append(allowed, string(e.Raw))
+		}
+		fail("must be one of %s", strings.Join(allowed, ", "))
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.Type != "" && s.Type != "string" {
+			fail("is not of type %s", s.Type)
+			return errs
+		}
+		length := int64(len([]rune(v)))
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if re, err := regexp.Compile(s.Pattern); err == nil && !re.MatchString(v) {
+				fail("does not match the pattern %s", s.Pattern)
+			}
+		}
+	case float64:
+		switch s.Type {
+		case "", "number":
+		case "integer":
+			if v != math.Trunc(v) {
+				fail("is not an integer")
+				return errs
+			}
+		default:
+			fail("is not of type %s", s.Type)
+			return errs
+		}
+		if s.Minimum != nil && (v < *s.Minimum || s.ExclusiveMinimum && v == *s.Minimum) {
+			fail("must be %s %v", comparison("greater than", s.ExclusiveMinimum), *s.Minimum)
+		}
+		if s.Maximum != nil && (v > *s.Maximum || s.ExclusiveMaximum && v == *s.Maximum) {
+			fail("must be %s %v", comparison("less than", s.ExclusiveMaximum), *s.Maximum)
+		}
+		if s.MultipleOf != nil && *s.MultipleOf != 0 && math.Mod(v, *s.MultipleOf) != 0 {
+			fail("must be a multiple of %v", *s.MultipleOf)
+		}
+	case bool:
+		if s.Type != "" && s.Type != "boolean" {
+			fail("is not of type %s", s.Type)
+		}
+	case []interface{}:
+		if s.Type != "" && s.Type != "array" {
+			fail("is not of type %s", s.Type)
+			return errs
+		}
+		if s.MinItems != nil && int64(len(v)) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && int64(len(v)) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.UniqueItems {
+			for i := range v {
+				for j := 0; j < i; j++ {
+					if reflect.DeepEqual(v[i], v[j]) {
+						fail("must have unique items, item %d equals item %d", i, j)
+					}
+				}
+			}
+		}
+		if s.Items != nil && s.Items.Schema != nil {
+			for i, item := range v {
+				errs = append(errs, validateSchema(fmt.Sprintf("%s[%d]", path, i), s.Items.Schema, item)...)
+			}
+		}
+	case map[string]interface{}:
+		if s.Type != "" && s.Type != "object" {
+			fail("is not of type %s", s.Type)
+			return errs
+		}
+		if s.MinProperties != nil && int64(len(v)) < *s.MinProperties {
+			fail("must have at least %d keys", *s.MinProperties)
+		}
+		if s.MaxProperties != nil && int64(len(v)) > *s.MaxProperties {
+			fail("must have at most %d keys", *s.MaxProperties)
+		}
+		var missing []string
+		for _, key := range s.Required {
+			if _, ok := v[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			fail("is missing required keys: %s", strings.Join(missing, ","))
+		}
+		for _, key := range sortedKeys(v) {
+			if p, ok := s.Properties[key]; ok {
+				errs = append(errs, validateSchema(path+"."+key, &p, v[key])...)
+				continue
+			}
+			switch additional := s.AdditionalProperties; {
+			case additional == nil:
+			case additional.Schema != nil:
+				errs = append(errs, validateSchema(path+"."+key, additional.Schema, v[key])...)
+			case !additional.Allows:
+				fail("has unknown key %s", key)
+			}
+		}
+	}
+	return errs
+}
+
+// inEnum returns true if the value equals one of the JSON encoded enum values
+func inEnum(enum []apiextv1beta1.JSON, value interface{}) bool {
+	for _, e := range enum {
+		var allowed interface{}
+		if err := json.Unmarshal(e.Raw, &allowed); err == nil && reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func comparison(relation string, exclusive bool) string {
+	if exclusive {
+		return relation
+	}
+	return relation + " or equal to"
+}
+
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	sorted := make([]string, 0, len(keys))
+	for _, k := range keys {
+		sorted = append(sorted, k.String())
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,352 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"sigs.k8s.io/yaml"
+)
+
+// ParameterType is the type of a parameter value or of the items of an array parameter
+type ParameterType string
+
+const (
+	// StringParameterType is the type of plain string values, it is the default type of parameters
+	StringParameterType ParameterType = "string"
+	// ArrayParameterType is the type of parameters whose value is a YAML list
+	ArrayParameterType ParameterType = "array"
+	// IntegerParameterType is the type of integer items of array parameters
+	IntegerParameterType ParameterType = "integer"
+	// BooleanParameterType is the type of boolean items of array parameters
+	BooleanParameterType ParameterType = "boolean"
+	// ObjectParameterType is the type of parameters whose value is a YAML map and of map items of array parameters
+	ObjectParameterType ParameterType = "object"
+)
+
+// ParameterItems is the schema the items of an array parameter must conform to
+type ParameterItems struct {
+	// Type is the type of the items: string, integer, boolean or object. Items of any type are allowed if it is empty.
+	Type ParameterType `json:"type,omitempty"`
+
+	// Required are the keys items of type object must define.
+	Required []string `json:"required,omitempty"`
+}
+
+// ParameterDeprecation marks a parameter as deprecated. Instances setting a deprecated parameter get a warning when
+// they are installed or upgraded, and its value is moved to the parameter replacing it. Once the operator reaches the
+// grace version, instances setting the deprecated parameter are rejected.
+type ParameterDeprecation struct {
+	// ReplacedBy is the name of the parameter replacing the deprecated one.
+	ReplacedBy string `json:"replacedBy,omitempty"`
+
+	// GraceVersion is the first version of the operator that rejects instances setting the deprecated parameter.
+	// Deprecated parameters are accepted with a warning forever if it is empty.
+	GraceVersion string `json:"graceVersion,omitempty"`
+
+	// Message is shown to users setting the deprecated parameter, e.g. to explain how to migrate.
+	Message string `json:"message,omitempty"`
+}
+
+// HasComputedDefault returns true if the default of the parameter is a template that is evaluated with the other
+// parameters of the instance when it is rendered, e.g. `{{ mul .Params.NODE_COUNT 2 }}`
+func (p *Parameter) HasComputedDefault() bool {
+	return p.Default != nil && strings.Contains(*p.Default, "{{")
+}
+
+// IsArray returns true if the value of the parameter is a list
+func (p *Parameter) IsArray() bool {
+	return p.Type == ArrayParameterType
+}
+
+// IsObject returns true if the value of the parameter is a map
+func (p *Parameter) IsObject() bool {
+	return p.Type == ObjectParameterType
+}
+
+// isStructured returns true if the value of the parameter is parsed from YAML before it is passed to templates
+func (p *Parameter) isStructured() bool {
+	return p.IsArray() || p.IsObject()
+}
+
+// ValidateDefinition checks that the type, item schema and schema of the parameter are valid and that its default
+// conforms to them
+func (p *Parameter) ValidateDefinition() error {
+	switch p.Type {
+	case "", StringParameterType:
+		if p.Items != nil {
+			return fmt.Errorf("parameter %s declares items but is not of type %s", p.Name, ArrayParameterType)
+		}
+		if p.Schema != nil {
+			return fmt.Errorf("parameter %s declares a schema but is not of type %s or %s", p.Name, ArrayParameterType, ObjectParameterType)
+		}
+	case ObjectParameterType:
+		if p.Items != nil {
+			return fmt.Errorf("parameter %s declares items but is not of type %s", p.Name, ArrayParameterType)
+		}
+	case ArrayParameterType:
+		if p.Items != nil && p.Schema != nil {
+			return fmt.Errorf("parameter %s declares both items and a schema, declare the items in the schema instead", p.Name)
+		}
+		if p.Items != nil {
+			switch p.Items.Type {
+			case "", StringParameterType, IntegerParameterType, BooleanParameterType:
+				if len(p.Items.Required) > 0 {
+					return fmt.Errorf("parameter %s declares required keys for items that are not of type %s", p.Name, ObjectParameterType)
+				}
+			case ObjectParameterType:
+			default:
+				return fmt.Errorf("parameter %s has items of unknown type %s", p.Name, p.Items.Type)
+			}
+		}
+	default:
+		return fmt.Errorf("parameter %s has unknown type %s", p.Name, p.Type)
+	}
+	if p.Sensitive && p.isStructured() {
+		// encrypted values can not be validated against the type of the parameter before they are decrypted
+		return fmt.Errorf("parameter %s is sensitive but not of type %s", p.Name, StringParameterType)
+	}
+	if p.Schema != nil {
+		if p.Schema.Type != "" && p.Schema.Type != string(p.Type) {
+			return fmt.Errorf("parameter %s is of type %s but its schema is of type %s", p.Name, p.Type, p.Schema.Type)
+		}
+		if errs := validateSchemaDefinition("schema", p.Schema); len(errs) > 0 {
+			return fmt.Errorf("parameter %s has an invalid schema: %s", p.Name, strings.Join(errs, ", "))
+		}
+	}
+	if d := p.Deprecated; d != nil {
+		if p.Required {
+			return fmt.Errorf("parameter %s is deprecated and can not be required", p.Name)
+		}
+		if d.ReplacedBy == p.Name {
+			return fmt.Errorf("parameter %s can not be replaced by itself", p.Name)
+		}
+		if d.GraceVersion != "" {
+			if _, err := semver.NewVersion(d.GraceVersion); err != nil {
+				return fmt.Errorf("parameter %s has an invalid grace version %s: %v", p.Name, d.GraceVersion, err)
+			}
+		}
+	}
+	if p.HasComputedDefault() {
+		// the computed value is only known when the instance is rendered
+		if p.isStructured() {
+			return fmt.Errorf("parameter %s has a computed default but is not of type %s", p.Name, StringParameterType)
+		}
+		return nil
+	}
+	if p.Default != nil {
+		if _, err := p.TypedValue(*p.Default); err != nil {
+			return fmt.Errorf("default of %v", err)
+		}
+	}
+	return nil
+}
+
+// TypedValue returns the value of the parameter as it is passed to templates. Values of string parameters are
+// returned unchanged. The YAML list of an array parameter is parsed and its items are validated against the item
+// schema, an empty value is an empty list. The YAML map of an object parameter is parsed, an empty value is an empty
+// map. Values of array and object parameters are validated against the schema of the parameter.
+func (p *Parameter) TypedValue(value string) (interface{}, error) {
+	var typed interface{}
+	var errs []string
+	switch {
+	case p.IsArray():
+		var items []interface{}
+		if err := yaml.Unmarshal([]byte(value), &items); err != nil {
+			return nil, fmt.Errorf("parameter %s is not a list: %v", p.Name, err)
+		}
+		if items == nil {
+			items = []interface{}{}
+		}
+		if p.Items != nil {
+			for i, item := range items {
+				if err := p.Items.validate(item); err != nil {
+					errs = append(errs, fmt.Sprintf("item %d %v", i, err))
+				}
+			}
+		}
+		typed = items
+	case p.IsObject():
+		var m map[string]interface{}
+		if err := yaml.Unmarshal([]byte(value), &m); err != nil {
+			return nil, fmt.Errorf("parameter %s is not a map: %v", p.Name, err)
+		}
+		if m == nil {
+			m = map[string]interface{}{}
+		}
+		typed = m
+	default:
+		return value, nil
+	}
+	if p.Schema != nil {
+		errs = append(errs, validateSchema("value", p.Schema, typed)...)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("parameter %s is invalid: %s", p.Name, strings.Join(errs, ", "))
+	}
+	return typed, nil
+}
+
+// validate checks that the item conforms to the schema. Items are parsed from YAML via JSON, so numbers are float64.
+func (s *ParameterItems) validate(item interface{}) error {
+	switch s.Type {
+	case StringParameterType:
+		if _, ok := item.(string); !ok {
+			return errors.New("is not a string")
+		}
+	case IntegerParameterType:
+		if f, ok := item.(float64); !ok || f != math.Trunc(f) {
+			return errors.New("is not an integer")
+		}
+	case BooleanParameterType:
+		if _, ok := item.(bool); !ok {
+			return errors.New("is not a boolean")
+		}
+	case ObjectParameterType:
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return errors.New("is not an object")
+		}
+		var missing []string
+		for _, key := range s.Required {
+			if _, ok := m[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Errorf("is missing required keys: %s", strings.Join(missing, ","))
+		}
+	}
+	return nil
+}
+
+// TemplateParameters returns the parameters as they are passed to templates: parameters defined as arrays are parsed
+// into lists, parameters defined as objects into maps, all other parameters are strings.
+func TemplateParameters(definitions []Parameter, params map[string]string) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		values[k] = v
+	}
+	var errs []string
+	for i := range definitions {
+		p := &definitions[i]
+		v, ok := params[p.Name]
+		if !ok || !p.isStructured() {
+			continue
+		}
+		typed, err := p.TypedValue(v)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		values[p.Name] = typed
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return values, nil
+}
+
+// ValidateParameters validates the complete set of parameters an instance is executed with: the required parameters of
+// the OperatorVersion must be set or have a default and all values must conform to the type and schema of their
+// parameter. All problems are reported at once.
+func ValidateParameters(ov *OperatorVersion, params map[string]string) error {
+	effective, missing := EffectiveParameters(ov, params)
+	var errs []string
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Sprintf("missing required parameters: %s", strings.Join(missing, ",")))
+	}
+	if _, err := TemplateParameters(ov.Spec.Parameters, effective); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// TranslateDeprecatedParameters returns a copy of the parameters in which the values of the deprecated parameters of
+// the OperatorVersion are moved to the parameters replacing them, together with a warning for every deprecated
+// parameter that is set. A value set for the replacing parameter takes precedence over the one of the deprecated
+// parameter. Setting a deprecated parameter fails once the OperatorVersion reached the grace version of the
+// deprecation.
+func TranslateDeprecatedParameters(ov *OperatorVersion, params map[string]string) (map[string]string, []string, error) {
+	translated := make(map[string]string, len(params))
+	for k, v := range params {
+		translated[k] = v
+	}
+
+	var warnings, errs []string
+	for _, p := range ov.Spec.Parameters {
+		d := p.Deprecated
+		v, ok := params[p.Name]
+		if d == nil || !ok {
+			continue
+		}
+		if d.graceExpired(ov.Spec.Version) {
+			err := fmt.Sprintf("parameter %s is no longer supported since version %s", p.Name, d.GraceVersion)
+			if d.ReplacedBy != "" {
+				err += fmt.Sprintf(", use parameter %s instead", d.ReplacedBy)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		warning := fmt.Sprintf("parameter %s is deprecated", p.Name)
+		if d.ReplacedBy != "" {
+			delete(translated, p.Name)
+			if _, ok := params[d.ReplacedBy]; ok {
+				warning += fmt.Sprintf(", its value is ignored as parameter %s is set", d.ReplacedBy)
+			} else {
+				translated[d.ReplacedBy] = v
+				warning += fmt.Sprintf(", its value was moved to parameter %s", d.ReplacedBy)
+			}
+		}
+		if d.Message != "" {
+			warning += ": " + d.Message
+		}
+		warnings = append(warnings, warning)
+	}
+
+	if len(errs) > 0 {
+		return nil, warnings, errors.New(strings.Join(errs, "; "))
+	}
+	return translated, warnings, nil
+}
+
+// graceExpired returns true if the operator version reached the grace version of the deprecation. Versions that are
+// not valid semantic versions never expire.
+func (d *ParameterDeprecation) graceExpired(version string) bool {
+	if d.GraceVersion == "" {
+		return false
+	}
+	grace, err := semver.NewVersion(d.GraceVersion)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return !v.LessThan(grace)
+}
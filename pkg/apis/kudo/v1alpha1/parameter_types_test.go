@@ -0,0 +1,223 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+)
+
+func TestParameter_TypedValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		param    Parameter
+		value    string
+		expected interface{}
+		err      string
+	}{
+		{name: "string", param: Parameter{Name: "P"}, value: "- a", expected: "- a"},
+		{name: "empty array", param: Parameter{Name: "P", Type: ArrayParameterType}, value: "", expected: []interface{}{}},
+		{name: "untyped items", param: Parameter{Name: "P", Type: ArrayParameterType}, value: "[a, 1]", expected: []interface{}{"a", float64(1)}},
+		{name: "not a list", param: Parameter{Name: "P", Type: ArrayParameterType}, value: "a: b", err: "parameter P is not a list"},
+		{name: "integers", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: IntegerParameterType}}, value: "[1, 2]", expected: []interface{}{float64(1), float64(2)}},
+		{name: "invalid integers", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: IntegerParameterType}}, value: "[1.5, a]", err: "parameter P is invalid: item 0 is not an integer, item 1 is not an integer"},
+		{name: "invalid booleans", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: BooleanParameterType}}, value: "[true, a]", err: "parameter P is invalid: item 1 is not a boolean"},
+		{name: "invalid strings", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: StringParameterType}}, value: "[a, {b: c}]", err: "parameter P is invalid: item 1 is not a string"},
+		{
+			name:     "objects",
+			param:    Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: ObjectParameterType, Required: []string{"name"}}},
+			value:    "- name: a\n  port: 1",
+			expected: []interface{}{map[string]interface{}{"name": "a", "port": float64(1)}},
+		},
+		{name: "invalid objects", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: ObjectParameterType, Required: []string{"port", "name"}}}, value: "[a, {}]", err: "parameter P is invalid: item 0 is not an object, item 1 is missing required keys: name,port"},
+		{name: "empty map", param: Parameter{Name: "P", Type: ObjectParameterType}, value: "", expected: map[string]interface{}{}},
+		{name: "map", param: Parameter{Name: "P", Type: ObjectParameterType}, value: "a: 1", expected: map[string]interface{}{"a": float64(1)}},
+		{name: "not a map", param: Parameter{Name: "P", Type: ObjectParameterType}, value: "[a]", err: "parameter P is not a map"},
+		{name: "map schema", param: Parameter{Name: "P", Type: ObjectParameterType, Schema: brokerSchema()}, value: "name: a\nports: [1]", expected: map[string]interface{}{"name": "a", "ports": []interface{}{float64(1)}}},
+		{
+			name:  "invalid map schema",
+			param: Parameter{Name: "P", Type: ObjectParameterType, Schema: brokerSchema()},
+			value: "ports: [0, 1.5, 1]\nmode: fast\nother: x",
+			err:   "parameter P is invalid: value is missing required keys: name, value.mode must be one of \"leader\", \"follower\", value has unknown key other, value.ports[0] must be greater than or equal to 1, value.ports[1] is not an integer",
+		},
+		{name: "list schema", param: Parameter{Name: "P", Type: ArrayParameterType, Schema: &apiextv1beta1.JSONSchemaProps{MaxItems: int64Ptr(1)}}, value: "[a, b]", err: "parameter P is invalid: value must have at most 1 items"},
+	}
+
+	for _, tt := range tests {
+		value, err := tt.param.TypedValue(tt.value)
+		if tt.err != "" {
+			if err == nil || len(err.Error()) < len(tt.err) || err.Error()[:len(tt.err)] != tt.err {
+				t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(value, tt.expected) {
+			t.Errorf("%s: expected %#v but got %#v", tt.name, tt.expected, value)
+		}
+	}
+}
+
+func TestParameter_ValidateDefinition(t *testing.T) {
+	tests := []struct {
+		name  string
+		param Parameter
+		err   string
+	}{
+		{name: "string", param: Parameter{Name: "P", Type: StringParameterType}},
+		{name: "unknown type", param: Parameter{Name: "P", Type: "map"}, err: "parameter P has unknown type map"},
+		{name: "items of string", param: Parameter{Name: "P", Items: &ParameterItems{}}, err: "parameter P declares items but is not of type array"},
+		{name: "unknown item type", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: "map"}}, err: "parameter P has items of unknown type map"},
+		{name: "required keys of strings", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{Type: StringParameterType, Required: []string{"a"}}}, err: "parameter P declares required keys for items that are not of type object"},
+		{name: "deprecated", param: Parameter{Name: "P", Deprecated: &ParameterDeprecation{ReplacedBy: "Q", GraceVersion: "2.0.0"}}},
+		{name: "deprecated required", param: Parameter{Name: "P", Required: true, Deprecated: &ParameterDeprecation{}}, err: "parameter P is deprecated and can not be required"},
+		{name: "replaced by itself", param: Parameter{Name: "P", Deprecated: &ParameterDeprecation{ReplacedBy: "P"}}, err: "parameter P can not be replaced by itself"},
+		{name: "invalid grace version", param: Parameter{Name: "P", Deprecated: &ParameterDeprecation{GraceVersion: "next"}}, err: "parameter P has an invalid grace version next: Invalid Semantic Version"},
+		{name: "object schema", param: Parameter{Name: "P", Type: ObjectParameterType, Schema: brokerSchema()}},
+		{name: "schema of string", param: Parameter{Name: "P", Schema: &apiextv1beta1.JSONSchemaProps{}}, err: "parameter P declares a schema but is not of type array or object"},
+		{name: "items and schema", param: Parameter{Name: "P", Type: ArrayParameterType, Items: &ParameterItems{}, Schema: &apiextv1beta1.JSONSchemaProps{}}, err: "parameter P declares both items and a schema, declare the items in the schema instead"},
+		{name: "schema type mismatch", param: Parameter{Name: "P", Type: ArrayParameterType, Schema: &apiextv1beta1.JSONSchemaProps{Type: "object"}}, err: "parameter P is of type array but its schema is of type object"},
+		{
+			name: "invalid schema",
+			param: Parameter{Name: "P", Type: ObjectParameterType, Schema: &apiextv1beta1.JSONSchemaProps{Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"a": {Type: "float"},
+				"b": {Pattern: "("},
+				"c": {AnyOf: []apiextv1beta1.JSONSchemaProps{{}}},
+			}}},
+			err: "parameter P has an invalid schema: schema.a has unknown type float, schema.b has an invalid pattern: error parsing regexp: missing closing ): `(`, schema.c uses unsupported keywords, $ref, allOf, anyOf, oneOf, not and patternProperties can not be used",
+		},
+		{name: "sensitive", param: Parameter{Name: "P", Sensitive: true}},
+		{name: "sensitive object", param: Parameter{Name: "P", Type: ObjectParameterType, Sensitive: true}, err: "parameter P is sensitive but not of type string"},
+		{name: "default violates schema", param: Parameter{Name: "P", Type: ObjectParameterType, Schema: brokerSchema(), Default: kudo.String("{}")}, err: "default of parameter P is invalid: value is missing required keys: name"},
+		{name: "computed default", param: Parameter{Name: "P", Default: kudo.String("{{ mul .Params.NODE_COUNT 2 }}")}},
+		{name: "computed default of object", param: Parameter{Name: "P", Type: ObjectParameterType, Default: kudo.String("{{ .Params.CONFIG }}")}, err: "parameter P has a computed default but is not of type string"},
+	}
+
+	for _, tt := range tests {
+		err := tt.param.ValidateDefinition()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestTemplateParameters(t *testing.T) {
+	definitions := []Parameter{
+		{Name: "NAME"},
+		{Name: "PORTS", Type: ArrayParameterType, Items: &ParameterItems{Type: IntegerParameterType}},
+		{Name: "UNSET", Type: ArrayParameterType},
+	}
+
+	values, err := TemplateParameters(definitions, map[string]string{"NAME": "kafka", "PORTS": "[9092]", "OTHER": "x"})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := map[string]interface{}{"NAME": "kafka", "PORTS": []interface{}{float64(9092)}, "OTHER": "x"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v but got %v", expected, values)
+	}
+
+	if _, err := TemplateParameters(definitions, map[string]string{"PORTS": "[a]"}); err == nil {
+		t.Errorf("expected invalid array parameter to fail")
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Parameters: []Parameter{
+		{Name: "NAME", Required: true},
+		{Name: "BROKER", Type: ObjectParameterType, Schema: brokerSchema(), Default: kudo.String("name: a")},
+	}}}
+
+	if err := ValidateParameters(ov, map[string]string{"NAME": "kafka"}); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	err := ValidateParameters(ov, map[string]string{"BROKER": "name: 1"})
+	expected := "missing required parameters: NAME; parameter BROKER is invalid: value.name is not of type string"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, err)
+	}
+}
+
+func brokerSchema() *apiextv1beta1.JSONSchemaProps {
+	return &apiextv1beta1.JSONSchemaProps{
+		Type:     "object",
+		Required: []string{"name"},
+		Properties: map[string]apiextv1beta1.JSONSchemaProps{
+			"name":  {Type: "string"},
+			"mode":  {Type: "string", Enum: []apiextv1beta1.JSON{{Raw: []byte(`"leader"`)}, {Raw: []byte(`"follower"`)}}},
+			"ports": {Type: "array", Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "integer", Minimum: float64Ptr(1)}}},
+		},
+		AdditionalProperties: &apiextv1beta1.JSONSchemaPropsOrBool{Allows: false},
+	}
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestTranslateDeprecatedParameters(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{
+		Version: "1.5.0",
+		Parameters: []Parameter{
+			{Name: "NODE_COUNT", Deprecated: &ParameterDeprecation{ReplacedBy: "BROKER_COUNT", GraceVersion: "2.0.0"}},
+			{Name: "BROKER_COUNT", Default: kudo.String("3")},
+			{Name: "LEGACY", Deprecated: &ParameterDeprecation{Message: "it has no effect"}},
+		},
+	}}
+
+	tests := []struct {
+		name       string
+		version    string
+		params     map[string]string
+		translated map[string]string
+		warnings   []string
+		err        string
+	}{
+		{"not deprecated", "1.5.0", map[string]string{"BROKER_COUNT": "5"}, map[string]string{"BROKER_COUNT": "5"}, nil, ""},
+		{"renamed", "1.5.0", map[string]string{"NODE_COUNT": "5"}, map[string]string{"BROKER_COUNT": "5"},
+			[]string{"parameter NODE_COUNT is deprecated, its value was moved to parameter BROKER_COUNT"}, ""},
+		{"replacement set", "1.5.0", map[string]string{"NODE_COUNT": "5", "BROKER_COUNT": "7"}, map[string]string{"BROKER_COUNT": "7"},
+			[]string{"parameter NODE_COUNT is deprecated, its value is ignored as parameter BROKER_COUNT is set"}, ""},
+		{"deprecated without replacement", "1.5.0", map[string]string{"LEGACY": "x"}, map[string]string{"LEGACY": "x"},
+			[]string{"parameter LEGACY is deprecated: it has no effect"}, ""},
+		{"grace version reached", "2.0.0", map[string]string{"NODE_COUNT": "5"}, nil, nil,
+			"parameter NODE_COUNT is no longer supported since version 2.0.0, use parameter BROKER_COUNT instead"},
+	}
+
+	for _, tt := range tests {
+		ov.Spec.Version = tt.version
+		translated, warnings, err := TranslateDeprecatedParameters(ov, tt.params)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+		if !reflect.DeepEqual(translated, tt.translated) && (len(translated) > 0 || len(tt.translated) > 0) {
+			t.Errorf("%s: expected parameters %v but got %v", tt.name, tt.translated, translated)
+		}
+		if !reflect.DeepEqual(warnings, tt.warnings) {
+			t.Errorf("%s: expected warnings %v but got %v", tt.name, tt.warnings, warnings)
+		}
+	}
+}
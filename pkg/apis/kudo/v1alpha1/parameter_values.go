@@ -0,0 +1,92 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+)
+
+// ParameterSource describes where the effective value of a parameter comes from
+type ParameterSource string
+
+const (
+	// ParameterSourceInstance is used for parameters explicitly set on the instance
+	ParameterSourceInstance ParameterSource = "instance"
+	// ParameterSourceDefault is used for parameters inherited from the default of the OperatorVersion. Optional
+	// parameters without a default have an empty value.
+	ParameterSourceDefault ParameterSource = "default"
+	// ParameterSourceMissing is used for required parameters that have neither a value nor a default
+	ParameterSourceMissing ParameterSource = "missing"
+)
+
+// ParameterValue is the effective value of a parameter of an instance
+type ParameterValue struct {
+	Name   string
+	Value  string
+	Source ParameterSource
+	// Definition is the definition of the parameter in the OperatorVersion, it is nil if the parameter is set on the
+	// instance but not defined in the OperatorVersion
+	Definition *Parameter
+}
+
+// ParameterValues merges the parameters set on an instance with the defaults of the OperatorVersion. This is the
+// single source of truth for the values plans are executed with. The values are sorted by name.
+func ParameterValues(ov *OperatorVersion, params map[string]string) []ParameterValue {
+	values := make([]ParameterValue, 0, len(ov.Spec.Parameters))
+	defined := make(map[string]bool, len(ov.Spec.Parameters))
+	for i := range ov.Spec.Parameters {
+		p := &ov.Spec.Parameters[i]
+		defined[p.Name] = true
+		value := ParameterValue{Name: p.Name, Definition: p}
+		v, ok := params[p.Name]
+		switch {
+		case ok:
+			value.Value, value.Source = v, ParameterSourceInstance
+		case p.Deprecated != nil && p.Deprecated.ReplacedBy != "":
+			// replaced parameters are not defaulted, the parameter replacing them is
+			continue
+		case p.Required && p.Default == nil:
+			value.Source = ParameterSourceMissing
+		default:
+			value.Value, value.Source = kudo.StringValue(p.Default), ParameterSourceDefault
+		}
+		values = append(values, value)
+	}
+	for k, v := range params {
+		if !defined[k] {
+			values = append(values, ParameterValue{Name: k, Value: v, Source: ParameterSourceInstance})
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+	return values
+}
+
+// EffectiveParameters returns the parameters an instance with the given parameters is executed with and the sorted
+// names of the required parameters that are missing
+func EffectiveParameters(ov *OperatorVersion, params map[string]string) (map[string]string, []string) {
+	effective := make(map[string]string)
+	var missing []string
+	for _, v := range ParameterValues(ov, params) {
+		if v.Source == ParameterSourceMissing {
+			missing = append(missing, v.Name)
+			continue
+		}
+		effective[v.Name] = v.Value
+	}
+	return effective, missing
+}
@@ -0,0 +1,66 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+)
+
+func TestParameterValues(t *testing.T) {
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Parameters: []Parameter{
+		{Name: "REPLICAS", Default: kudo.String("3")},
+		{Name: "PASSWORD", Required: true},
+		{Name: "OPTIONAL"},
+		{Name: "MEMORY", Required: true, Default: kudo.String("1Gi")},
+		{Name: "NODES", Default: kudo.String("3"), Deprecated: &ParameterDeprecation{ReplacedBy: "REPLICAS"}},
+	}}}
+	params := map[string]string{"REPLICAS": "5", "UNDEFINED": "value"}
+
+	values := ParameterValues(ov, params)
+	expected := []struct {
+		name    string
+		value   string
+		source  ParameterSource
+		defined bool
+	}{
+		{"MEMORY", "1Gi", ParameterSourceDefault, true},
+		{"OPTIONAL", "", ParameterSourceDefault, true},
+		{"PASSWORD", "", ParameterSourceMissing, true},
+		{"REPLICAS", "5", ParameterSourceInstance, true},
+		{"UNDEFINED", "value", ParameterSourceInstance, false},
+	}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d values but got %v", len(expected), values)
+	}
+	for i, e := range expected {
+		v := values[i]
+		if v.Name != e.name || v.Value != e.value || v.Source != e.source || (v.Definition != nil) != e.defined {
+			t.Errorf("expected %+v but got %+v", e, v)
+		}
+	}
+
+	effective, missing := EffectiveParameters(ov, params)
+	expectedEffective := map[string]string{"MEMORY": "1Gi", "OPTIONAL": "", "REPLICAS": "5", "UNDEFINED": "value"}
+	if !reflect.DeepEqual(effective, expectedEffective) {
+		t.Errorf("expected effective parameters %v but got %v", expectedEffective, effective)
+	}
+	if !reflect.DeepEqual(missing, []string{"PASSWORD"}) {
+		t.Errorf("expected PASSWORD to be missing but got %v", missing)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Patch changes an object of the rendered templates of an instance before it is applied. Objects are selected by
+// the names they have in the templates, i.e. without the instance name prefix. Exactly one of StrategicMerge and
+// JSON6902 has to be set.
+type Patch struct {
+	// StrategicMerge is a strategic merge patch in YAML, the object is selected by the apiVersion, kind and name of
+	// the patch
+	// +optional
+	StrategicMerge string `json:"strategicMerge,omitempty"`
+	// JSON6902 is a JSON patch (RFC 6902) in JSON or YAML that is applied to the Target
+	// +optional
+	JSON6902 string `json:"json6902,omitempty"`
+	// Target selects the object of a JSON patch
+	// +optional
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget selects the object a JSON patch is applied to
+type PatchTarget struct {
+	// Group is empty for the core API group
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+}
+
+// patchedObject is the part of a strategic merge patch that selects the object
+type patchedObject struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Metadata   struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+}
+
+// Validate checks that the patch is either a strategic merge patch that selects an object or a JSON patch with a
+// complete target
+func (p Patch) Validate() error {
+	if p.StrategicMerge != "" && p.JSON6902 != "" {
+		return errors.New("patch can not be a strategic merge and a JSON patch at the same time")
+	}
+	if p.StrategicMerge == "" && p.JSON6902 == "" {
+		return errors.New("patch has to define either a strategic merge or a JSON patch")
+	}
+	gvk, name, err := p.Selector()
+	if err != nil {
+		return err
+	}
+	if p.JSON6902 != "" {
+		var ops []map[string]interface{}
+		if err := yaml.Unmarshal([]byte(p.JSON6902), &ops); err != nil {
+			return fmt.Errorf("JSON patch of %s %s is invalid: %v", gvk.Kind, name, err)
+		}
+	}
+	return nil
+}
+
+// Selector returns the group, version, kind and name of the object the patch is applied to
+func (p Patch) Selector() (schema.GroupVersionKind, string, error) {
+	if p.JSON6902 != "" {
+		if p.Target == nil || p.Target.Version == "" || p.Target.Kind == "" || p.Target.Name == "" {
+			return schema.GroupVersionKind{}, "", errors.New("JSON patch has to define the version, kind and name of its target")
+		}
+		return schema.GroupVersionKind{Group: p.Target.Group, Version: p.Target.Version, Kind: p.Target.Kind}, p.Target.Name, nil
+	}
+
+	var obj patchedObject
+	if err := yaml.Unmarshal([]byte(p.StrategicMerge), &obj); err != nil {
+		return schema.GroupVersionKind{}, "", fmt.Errorf("strategic merge patch is invalid: %v", err)
+	}
+	if obj.APIVersion == "" || obj.Kind == "" || obj.Metadata.Name == "" {
+		return schema.GroupVersionKind{}, "", errors.New("strategic merge patch has to define the apiVersion, kind and metadata.name of the object")
+	}
+	gv, err := schema.ParseGroupVersion(obj.APIVersion)
+	if err != nil {
+		return schema.GroupVersionKind{}, "", fmt.Errorf("strategic merge patch is invalid: %v", err)
+	}
+	return gv.WithKind(obj.Kind), obj.Metadata.Name, nil
+}
+
+// ParsePatches parses a YAML list of patches and validates them
+func ParsePatches(b []byte) ([]Patch, error) {
+	var patches []Patch
+	if err := yaml.UnmarshalStrict(b, &patches); err != nil {
+		return nil, err
+	}
+	for i, p := range patches {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("patch %d: %v", i+1, err)
+		}
+	}
+	return patches, nil
+}
+
+// EffectivePatches returns the patches of the overlay of the instance in the OperatorVersion followed by the patches
+// of the instance itself
+func (i *Instance) EffectivePatches(ov *OperatorVersion) ([]Patch, error) {
+	var patches []Patch
+	if i.Spec.Overlay != "" {
+		overlay, ok := ov.Spec.Overlays[i.Spec.Overlay]
+		if !ok {
+			return nil, fmt.Errorf("overlay %s is not defined by operatorversion %s", i.Spec.Overlay, ov.Name)
+		}
+		patches = append(patches, overlay...)
+	}
+	return append(patches, i.Spec.Patches...), nil
+}
@@ -0,0 +1,103 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatch_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch Patch
+		err   string
+	}{
+		{"strategic merge", Patch{StrategicMerge: "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n"}, ""},
+		{"json", Patch{JSON6902: `[{"op": "remove", "path": "/spec/replicas"}]`, Target: &PatchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "app"}}, ""},
+		{"empty", Patch{}, "patch has to define either a strategic merge or a JSON patch"},
+		{"both", Patch{StrategicMerge: "kind: Service", JSON6902: "[]"}, "patch can not be a strategic merge and a JSON patch at the same time"},
+		{"no name", Patch{StrategicMerge: "apiVersion: v1\nkind: Service\n"}, "strategic merge patch has to define the apiVersion, kind and metadata.name of the object"},
+		{"no target", Patch{JSON6902: "[]"}, "JSON patch has to define the version, kind and name of its target"},
+		{"no operations", Patch{JSON6902: "op: remove", Target: &PatchTarget{Version: "v1", Kind: "Service", Name: "app"}}, "JSON patch of Service app is invalid: error unmarshaling JSON: while decoding JSON: json: cannot unmarshal object into Go value of type []map[string]interface {}"},
+	}
+	for _, tt := range tests {
+		err := tt.patch.Validate()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		case tt.err != "" && (err == nil || err.Error() != tt.err):
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestParsePatches(t *testing.T) {
+	patches, err := ParsePatches([]byte(`- strategicMerge: |
+    apiVersion: apps/v1
+    kind: StatefulSet
+    metadata:
+      name: zk
+    spec:
+      replicas: 5
+- target:
+    group: apps
+    version: v1
+    kind: StatefulSet
+    name: zk
+  json6902: |
+    - op: add
+      path: /metadata/annotations/backup
+      value: "true"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &PatchTarget{Group: "apps", Version: "v1", Kind: "StatefulSet", Name: "zk"}
+	if len(patches) != 2 || !reflect.DeepEqual(expected, patches[1].Target) {
+		t.Errorf("expected two patches with the target %v of the second, got %v", expected, patches)
+	}
+
+	_, err = ParsePatches([]byte("- strategicMerge: |\n    kind: StatefulSet\n"))
+	if err == nil || err.Error() != "patch 1: strategic merge patch has to define the apiVersion, kind and metadata.name of the object" {
+		t.Errorf("expected an error for the incomplete patch, got %v", err)
+	}
+	if _, err = ParsePatches([]byte("- jsonPatch: []\n")); err == nil {
+		t.Errorf("expected an error for the unknown field")
+	}
+}
+
+func TestInstance_EffectivePatches(t *testing.T) {
+	production := Patch{StrategicMerge: "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\nspec:\n  type: LoadBalancer\n"}
+	own := Patch{StrategicMerge: "apiVersion: v1\nkind: Service\nmetadata:\n  name: app\n  annotations:\n    team: data\n"}
+	ov := &OperatorVersion{Spec: OperatorVersionSpec{Overlays: map[string][]Patch{"production": {production}}}}
+	ov.Name = "app-1.0"
+
+	i := &Instance{Spec: InstanceSpec{Overlay: "production", Patches: []Patch{own}}}
+	if patches, err := i.EffectivePatches(ov); err != nil || !reflect.DeepEqual([]Patch{production, own}, patches) {
+		t.Errorf("expected the overlay patches followed by the instance patches, got %v, %v", patches, err)
+	}
+
+	i.Spec.Overlay = "staging"
+	if _, err := i.EffectivePatches(ov); err == nil || err.Error() != "overlay staging is not defined by operatorversion app-1.0" {
+		t.Errorf("expected an error for the unknown overlay, got %v", err)
+	}
+
+	i.Spec.Overlay = ""
+	if patches, err := i.EffectivePatches(ov); err != nil || !reflect.DeepEqual([]Patch{own}, patches) {
+		t.Errorf("expected only the instance patches, got %v, %v", patches, err)
+	}
+}
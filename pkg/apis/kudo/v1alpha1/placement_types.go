@@ -0,0 +1,40 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Placement constrains the nodes the pods of an instance are scheduled on. It is injected into all pods and pod
+// templates of the rendered templates, so that platform teams can enforce the placement of an instance without
+// every operator exposing parameters for it.
+type Placement struct {
+	// NodeSelector entries are added to the node selector of every pod, replacing entries with the same key
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations are added to the tolerations of every pod
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity replaces the node affinity, pod affinity and pod anti-affinity of every pod, as far as they are set
+	// +optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+}
+
+// IsEmpty returns true if the placement does not constrain the pods
+func (p *Placement) IsEmpty() bool {
+	return p == nil || (len(p.NodeSelector) == 0 && len(p.Tolerations) == 0 && p.Affinity == nil)
+}
@@ -0,0 +1,106 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxPlanReferenceDepth is the maximum number of plans a chain of plan references may pass through, e.g. with a
+// depth of 3 the upgrade plan can reference a restart plan which references a drain plan which references a fourth
+// plan, but that plan can not reference any other plan.
+const MaxPlanReferenceDepth = 3
+
+// ResolvePlan returns the plan with the given name in which every step referencing another plan is replaced by the
+// steps of the referenced plan. The steps of all phases of the referenced plan are executed in order in the phase of
+// the referencing step and are named "<step>.<phase>.<step of referenced plan>", so that the flattened plan can be
+// executed and reported like any other plan. The first step of a manual referenced phase and the first step
+// replacing a manual step need an approval.
+// A plan without references is returned as is. ResolvePlan fails if a plan does not exist, if a step of a phase
+// that is not serial references a plan, if the references form a cycle or if they are nested deeper than
+// MaxPlanReferenceDepth.
+func ResolvePlan(plans map[string]Plan, name string) (Plan, error) {
+	return resolvePlan(plans, name, []string{name})
+}
+
+// resolvePlan resolves the plan with the given name, path holds the names of the plans that reference it, ending
+// with the name itself
+func resolvePlan(plans map[string]Plan, name string, path []string) (Plan, error) {
+	plan, ok := plans[name]
+	if !ok {
+		return Plan{}, fmt.Errorf("plan %s does not exist", name)
+	}
+	if !hasPlanReferences(plan) {
+		return plan, nil
+	}
+
+	resolved := plan
+	resolved.Phases = make([]Phase, 0, len(plan.Phases))
+	for _, phase := range plan.Phases {
+		steps := make([]Step, 0, len(phase.Steps))
+		for _, step := range phase.Steps {
+			if step.Plan == "" {
+				steps = append(steps, step)
+				continue
+			}
+			if phase.Strategy != Serial {
+				return Plan{}, fmt.Errorf("step %s.%s.%s references plan %s, only steps of serial phases can reference plans", name, phase.Name, step.Name, step.Plan)
+			}
+			if _, ok := plans[step.Plan]; !ok {
+				return Plan{}, fmt.Errorf("step %s.%s.%s references plan %s which does not exist", name, phase.Name, step.Name, step.Plan)
+			}
+			refPath := append(append([]string{}, path...), step.Plan)
+			for _, p := range path {
+				if p == step.Plan {
+					return Plan{}, fmt.Errorf("plan references form a cycle: %s", strings.Join(refPath, " -> "))
+				}
+			}
+			if len(refPath)-1 > MaxPlanReferenceDepth {
+				return Plan{}, fmt.Errorf("plan references are nested deeper than %d plans: %s", MaxPlanReferenceDepth, strings.Join(refPath, " -> "))
+			}
+
+			ref, err := resolvePlan(plans, step.Plan, refPath)
+			if err != nil {
+				return Plan{}, err
+			}
+			first := true
+			for _, refPhase := range ref.Phases {
+				for i, refStep := range refPhase.Steps {
+					refStep.Name = fmt.Sprintf("%s.%s.%s", step.Name, refPhase.Name, refStep.Name)
+					refStep.Manual = refStep.Manual || (i == 0 && refPhase.Manual) || (first && step.Manual)
+					steps = append(steps, refStep)
+					first = false
+				}
+			}
+		}
+		phase.Steps = steps
+		resolved.Phases = append(resolved.Phases, phase)
+	}
+	return resolved, nil
+}
+
+// hasPlanReferences reports whether a step of the plan references another plan
+func hasPlanReferences(plan Plan) bool {
+	for _, phase := range plan.Phases {
+		for _, step := range phase.Steps {
+			if step.Plan != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
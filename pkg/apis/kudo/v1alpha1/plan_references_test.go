@@ -0,0 +1,120 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+)
+
+func serialPlan(phases ...Phase) Plan {
+	return Plan{Strategy: Serial, Phases: phases}
+}
+
+func TestResolvePlan(t *testing.T) {
+	plans := map[string]Plan{
+		"restart": serialPlan(
+			Phase{Name: "brokers", Strategy: Parallel, Steps: []Step{{Name: "restart", Tasks: []string{"restart"}}}},
+			Phase{Name: "verify", Strategy: Serial, Manual: true, Steps: []Step{
+				{Name: "health", Tasks: []string{"health"}},
+				{Name: "metrics", Tasks: []string{"metrics"}},
+			}},
+		),
+		"upgrade": serialPlan(
+			Phase{Name: "upgrade", Strategy: Serial, Steps: []Step{
+				{Name: "config", Tasks: []string{"config"}},
+				{Name: "rolling", Plan: "restart", Manual: true},
+				{Name: "cleanup", Tasks: []string{"cleanup"}, Delete: true},
+			}},
+		),
+	}
+
+	restart, err := ResolvePlan(plans, "restart")
+	if err != nil || !reflect.DeepEqual(restart, plans["restart"]) {
+		t.Errorf("expected a plan without references to be returned as is but got %v, %v", restart, err)
+	}
+
+	expected := serialPlan(
+		Phase{Name: "upgrade", Strategy: Serial, Steps: []Step{
+			{Name: "config", Tasks: []string{"config"}},
+			{Name: "rolling.brokers.restart", Tasks: []string{"restart"}, Manual: true},
+			{Name: "rolling.verify.health", Tasks: []string{"health"}, Manual: true},
+			{Name: "rolling.verify.metrics", Tasks: []string{"metrics"}},
+			{Name: "cleanup", Tasks: []string{"cleanup"}, Delete: true},
+		}},
+	)
+	upgrade, err := ResolvePlan(plans, "upgrade")
+	if err != nil {
+		t.Fatalf("failed to resolve the upgrade plan: %v", err)
+	}
+	if !reflect.DeepEqual(upgrade, expected) {
+		t.Errorf("expected the resolved upgrade plan\n%v\nbut got\n%v", expected, upgrade)
+	}
+	if name := plans["upgrade"].Phases[0].Steps[1].Name; name != "rolling" {
+		t.Errorf("expected the referencing plan not to be modified but its step is named %s", name)
+	}
+}
+
+func TestResolvePlan_Nested(t *testing.T) {
+	reference := func(plan string) Plan {
+		return serialPlan(Phase{Name: "main", Strategy: Serial, Steps: []Step{{Name: "call", Plan: plan}}})
+	}
+	plans := map[string]Plan{
+		"a": reference("b"),
+		"b": reference("c"),
+		"c": reference("d"),
+		"d": serialPlan(Phase{Name: "main", Strategy: Serial, Steps: []Step{{Name: "work", Tasks: []string{"work"}}}}),
+		"e": reference("a"),
+	}
+
+	a, err := ResolvePlan(plans, "a")
+	if err != nil {
+		t.Fatalf("failed to resolve plan a: %v", err)
+	}
+	if name := a.Phases[0].Steps[0].Name; name != "call.main.call.main.call.main.work" {
+		t.Errorf("expected the nested step to be named after all referencing steps but got %s", name)
+	}
+
+	cycle := reference("b")
+	self := reference("d")
+	tests := []struct {
+		name string
+		plan string
+		d    *Plan
+		err  string
+	}{
+		{name: "too deep", plan: "e", err: "plan references are nested deeper than 3 plans: e -> a -> b -> c -> d"},
+		{name: "cycle", plan: "a", d: &cycle, err: "plan references form a cycle: a -> b -> c -> d -> b"},
+		{name: "self reference", plan: "d", d: &self, err: "plan references form a cycle: d -> d"},
+		{name: "parallel phase", plan: "parallel", err: "step parallel.main.call references plan d, only steps of serial phases can reference plans"},
+		{name: "missing plan", plan: "missing", err: "step missing.main.call references plan unknown which does not exist"},
+		{name: "unknown plan", plan: "unknown", err: "plan unknown does not exist"},
+	}
+	plans["parallel"] = serialPlan(Phase{Name: "main", Strategy: Parallel, Steps: []Step{{Name: "call", Plan: "d"}}})
+	plans["missing"] = reference("unknown")
+	d := plans["d"]
+
+	for _, tt := range tests {
+		plans["d"] = d
+		if tt.d != nil {
+			plans["d"] = *tt.d
+		}
+		_, err := ResolvePlan(plans, tt.plan)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
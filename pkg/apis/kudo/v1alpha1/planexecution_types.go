@@ -0,0 +1,88 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlanExecutionSpec identifies the run of a plan and snapshots the inputs it was started with
+type PlanExecutionSpec struct {
+	// Instance is the name of the instance in the namespace of the PlanExecution the plan runs on
+	Instance string `json:"instance"`
+	// InstanceGeneration is the generation of the instance spec the plan was started for
+	// +optional
+	InstanceGeneration int64 `json:"instanceGeneration,omitempty"`
+	// OperatorVersion is the name of the operatorversion the plan is defined in
+	OperatorVersion string `json:"operatorVersion"`
+	// Plan is the name of the plan
+	Plan string `json:"plan"`
+	// Parameters are the parameters of the instance when the plan was started. The values of sensitive parameters are
+	// kept encrypted if they are encrypted in the instance.
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// PlanExecutionStatus is the outcome of the run of a plan, it is updated by the controller while the plan is executed
+type PlanExecutionStatus struct {
+	Status  ExecutionStatus `json:"status,omitempty"`
+	Message string          `json:"message,omitempty"`
+	// StartedAt is the time the plan was started
+	StartedAt metav1.Time `json:"startedAt,omitempty"`
+	// FinishedAt is the time the plan reached a terminal status, it is not set while the plan is executed
+	FinishedAt *metav1.Time `json:"finishedAt,omitempty"`
+	// Phases are the results of the phases and their steps
+	Phases []PhaseStatus `json:"phases,omitempty"`
+	// Logs references the objects applied by the steps whose logs hold the output of the steps, e.g. pods and jobs
+	Logs []LogReference `json:"logs,omitempty"`
+}
+
+// LogReference references an object applied by a step whose logs hold the output of the step
+type LogReference struct {
+	Phase     string `json:"phase"`
+	Step      string `json:"step"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PlanExecution is the Schema for the record of a single run of a plan on an instance. Records are created by the
+// controller when a plan is started and kept independently of the status of the Instance, which only shows the last
+// run of each plan. They are owned by the instance and only the most recent ones are retained.
+// +k8s:openapi-gen=true
+type PlanExecution struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PlanExecutionSpec   `json:"spec,omitempty"`
+	Status PlanExecutionStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PlanExecutionList contains a list of PlanExecution
+type PlanExecutionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlanExecution `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PlanExecution{}, &PlanExecutionList{})
+}
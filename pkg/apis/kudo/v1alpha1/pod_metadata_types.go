@@ -0,0 +1,33 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// PodMetadata are labels and annotations injected into all pods and pod templates of the rendered templates, e.g. the
+// scrape annotations of Prometheus or the sidecar injection flags of a service mesh, without every operator exposing
+// parameters for them.
+type PodMetadata struct {
+	// Labels are added to the labels of every pod, replacing labels with the same key
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations are added to the annotations of every pod, replacing annotations with the same key
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IsEmpty returns true if no labels or annotations are injected
+func (m *PodMetadata) IsEmpty() bool {
+	return m == nil || (len(m.Labels) == 0 && len(m.Annotations) == 0)
+}
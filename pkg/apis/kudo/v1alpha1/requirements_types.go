@@ -0,0 +1,74 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ClusterRequirements are the capabilities of the cluster an operator needs beyond the Kubernetes version. They are
+// checked before the operator is installed or upgraded.
+type ClusterRequirements struct {
+	// APIs are the API groups, versions or resources the operator uses, e.g. VolumeSnapshots
+	APIs []APIRequirement `json:"apis,omitempty"`
+	// FeatureGates are the Kubernetes feature gates that have to be enabled, e.g. VolumeSnapshotDataSource
+	FeatureGates []string `json:"featureGates,omitempty"`
+}
+
+// APIRequirement requires the API server to serve an API group, a version of it or a resource
+type APIRequirement struct {
+	// Group is the API group, e.g. snapshot.storage.k8s.io, it is empty for the core group
+	Group string `json:"group,omitempty"`
+	// Version is the version of the group, any served version satisfies the requirement if it is empty
+	Version string `json:"version,omitempty"`
+	// Resource is the plural name of the resource, e.g. poddisruptionbudgets, the group version is sufficient if it
+	// is empty
+	Resource string `json:"resource,omitempty"`
+}
+
+func (r APIRequirement) String() string {
+	s := "API group " + r.Group
+	if r.Group == "" {
+		s = "core API"
+	}
+	if r.Version != "" {
+		s = fmt.Sprintf("API %s", strings.TrimPrefix(r.Group+"/"+r.Version, "/"))
+	}
+	if r.Resource != "" {
+		s = fmt.Sprintf("resource %s of the %s", r.Resource, s)
+	}
+	return s
+}
+
+// Validate returns an error if a requirement can not be checked
+func (r *ClusterRequirements) Validate() error {
+	var errs []string
+	for _, api := range r.APIs {
+		if api.Group == "" && api.Version == "" {
+			errs = append(errs, "API requirements of the core group need a version")
+		}
+	}
+	for _, gate := range r.FeatureGates {
+		if strings.TrimSpace(gate) == "" {
+			errs = append(errs, "feature gate requirements need a name")
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid requirements: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
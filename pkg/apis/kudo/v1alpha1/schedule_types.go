@@ -0,0 +1,81 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlanSchedule triggers a plan of an instance on a cron schedule, e.g. a nightly backup
+type PlanSchedule struct {
+	// Name identifies the schedule in the status of the instance
+	Name string `json:"name"`
+	// Plan is the name of the plan that is triggered
+	Plan string `json:"plan"`
+	// Schedule is in cron format, e.g. "0 2 * * *", and evaluated in UTC
+	Schedule string `json:"schedule"`
+	// ConcurrencyPolicy specifies what happens if another plan is in progress when the schedule is due,
+	// defaults to Forbid
+	// +optional
+	ConcurrencyPolicy ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+}
+
+// ConcurrencyPolicy describes how a scheduled plan is handled if another plan is in progress
+type ConcurrencyPolicy string
+
+const (
+	// ForbidConcurrent skips the scheduled execution if another plan is in progress
+	ForbidConcurrent ConcurrencyPolicy = "Forbid"
+	// ReplaceConcurrent stops the plan in progress and starts the scheduled plan instead
+	ReplaceConcurrent ConcurrencyPolicy = "Replace"
+)
+
+// ScheduleStatus tracks the executions of a schedule
+type ScheduleStatus struct {
+	// LastScheduleTime is the last time the schedule was due
+	LastScheduleTime metav1.Time `json:"lastScheduleTime,omitempty"`
+	// LastExecutionTime is the last time the plan was started by the schedule
+	LastExecutionTime *metav1.Time `json:"lastExecutionTime,omitempty"`
+	// Pending is true while the plan waits to be started by the instance controller
+	Pending bool `json:"pending,omitempty"`
+	// Message describes the outcome of the last time the schedule was due, e.g. why it was skipped
+	Message string `json:"message,omitempty"`
+}
+
+// GetScheduledPlan returns the plan of the first pending schedule or nil if no schedule is pending
+func (i *Instance) GetScheduledPlan() *string {
+	for _, s := range i.Spec.Schedules {
+		if status, ok := i.Status.Schedules[s.Name]; ok && status.Pending {
+			return &s.Plan
+		}
+	}
+	return nil
+}
+
+// recordScheduledPlanStarted marks the pending schedules of the plan as executed
+func (i *Instance) recordScheduledPlanStarted(planName string) {
+	for _, s := range i.Spec.Schedules {
+		status, ok := i.Status.Schedules[s.Name]
+		if !ok || !status.Pending || s.Plan != planName {
+			continue
+		}
+		now := metav1.Now()
+		status.Pending = false
+		status.LastExecutionTime = &now
+		status.Message = ""
+		i.Status.Schedules[s.Name] = status
+	}
+}
@@ -0,0 +1,168 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Masterminds/semver"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// PreUpgradeCheckAnnotation requests the pre-upgrade checks of the named OperatorVersion for an instance, the
+	// results are reported in Status.PreUpgradeCheck
+	PreUpgradeCheckAnnotation = "kudo.dev/pre-upgrade-check"
+	// PreUpgradeCheckRequestAnnotation identifies a pre-upgrade check request, it has to change for every request
+	PreUpgradeCheckRequestAnnotation = "kudo.dev/pre-upgrade-check-request"
+	// SkipPreUpgradeChecksAnnotation lets the next upgrade of an instance skip the pre-upgrade checks when set to
+	// "true". The controller removes it once the next plan starts, kudoctl sets it with 'upgrade --skip-pre-upgrade-checks'
+	// and for rollbacks.
+	SkipPreUpgradeChecksAnnotation = "kudo.dev/skip-pre-upgrade-checks"
+
+	// PreUpgradeCheckValidity is how long after they passed the pre-upgrade checks allow an upgrade
+	PreUpgradeCheckValidity = 10 * time.Minute
+)
+
+// UpgradeCheck is a check an OperatorVersion declares, which has to pass before an instance is switched to it.
+// Exactly one kind of check has to be set.
+type UpgradeCheck struct {
+	// Name identifies the check in the results
+	Name string `json:"name"`
+	// VersionGap limits how far the current version of the instance may be behind
+	VersionGap *VersionGapCheck `json:"versionGap,omitempty"`
+	// HealthyReplicas requires a minimum of ready replicas of a workload of the instance
+	HealthyReplicas *HealthyReplicasCheck `json:"healthyReplicas,omitempty"`
+}
+
+// VersionGapCheck limits the versions an instance can be upgraded from directly
+type VersionGapCheck struct {
+	// MinVersion is the oldest version that can be upgraded directly
+	MinVersion string `json:"minVersion,omitempty"`
+	// MaxMajor is the maximum number of major versions an upgrade may advance
+	MaxMajor *int64 `json:"maxMajor,omitempty"`
+	// MaxMinor is the maximum number of minor versions an upgrade within the same major version may advance
+	MaxMinor *int64 `json:"maxMinor,omitempty"`
+}
+
+// HealthyReplicasCheck requires a minimum of ready replicas of a Deployment or StatefulSet of the instance
+type HealthyReplicasCheck struct {
+	// Kind is the kind of the workload, Deployment or StatefulSet
+	Kind string `json:"kind"`
+	// Name is the name of the workload as declared in its template, it is prefixed with the name of the instance like
+	// all objects KUDO applies
+	Name string `json:"name"`
+	// MinReady is the number or the percentage of the desired replicas that have to be ready
+	MinReady intstr.IntOrString `json:"minReady"`
+}
+
+// UpgradeCheckResult is the result of a single pre-upgrade check
+type UpgradeCheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Message explains the result
+	Message string `json:"message,omitempty"`
+}
+
+// PreUpgradeCheckStatus reports the results of the last requested pre-upgrade checks
+type PreUpgradeCheckStatus struct {
+	// Request is the pre-upgrade check request answered by this report
+	Request string `json:"request,omitempty"`
+	// OperatorVersion is the OperatorVersion whose checks were executed
+	OperatorVersion string `json:"operatorVersion,omitempty"`
+	// CompletedAt is the time the checks finished
+	CompletedAt metav1.Time `json:"completedAt,omitempty"`
+	// Results are the results of the checks in the order they are declared
+	Results []UpgradeCheckResult `json:"results,omitempty"`
+	// Message is the error that prevented the checks from being executed, it is empty if they were executed
+	Message string `json:"message,omitempty"`
+}
+
+// Passed returns true if the checks could be executed and all of them passed
+func (s *PreUpgradeCheckStatus) Passed() bool {
+	if s.Message != "" {
+		return false
+	}
+	for _, r := range s.Results {
+		if !r.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// GetPendingPreUpgradeCheck returns the OperatorVersion and the request of pre-upgrade checks that were requested but
+// not answered yet. The OperatorVersion is empty if no checks are pending.
+func (i *Instance) GetPendingPreUpgradeCheck() (operatorVersion string, request string) {
+	operatorVersion, request = i.Annotations[PreUpgradeCheckAnnotation], i.Annotations[PreUpgradeCheckRequestAnnotation]
+	if operatorVersion == "" || (i.Status.PreUpgradeCheck != nil && i.Status.PreUpgradeCheck.Request == request) {
+		return "", ""
+	}
+	return operatorVersion, request
+}
+
+// Validate checks that exactly one kind of check is set and that it is valid
+func (c *UpgradeCheck) Validate() error {
+	switch {
+	case c.Name == "":
+		return fmt.Errorf("pre-upgrade check has no name")
+	case c.VersionGap != nil && c.HealthyReplicas != nil, c.VersionGap == nil && c.HealthyReplicas == nil:
+		return fmt.Errorf("pre-upgrade check %s has to declare exactly one of versionGap and healthyReplicas", c.Name)
+	case c.VersionGap != nil && c.VersionGap.MinVersion != "":
+		if _, err := semver.NewVersion(c.VersionGap.MinVersion); err != nil {
+			return fmt.Errorf("pre-upgrade check %s has an invalid minimum version %s: %v", c.Name, c.VersionGap.MinVersion, err)
+		}
+	case c.HealthyReplicas != nil:
+		if c.HealthyReplicas.Kind != "Deployment" && c.HealthyReplicas.Kind != "StatefulSet" {
+			return fmt.Errorf("pre-upgrade check %s checks unsupported kind %s, only Deployment and StatefulSet are supported", c.Name, c.HealthyReplicas.Kind)
+		}
+		if c.HealthyReplicas.Name == "" {
+			return fmt.Errorf("pre-upgrade check %s does not name the %s to check", c.Name, c.HealthyReplicas.Kind)
+		}
+	}
+	return nil
+}
+
+// CheckVersionGap returns an error if the instance can not be upgraded from the current version to the new version
+// directly
+func (c *VersionGapCheck) CheckVersionGap(current, next string) error {
+	cv, err := semver.NewVersion(current)
+	if err != nil {
+		return fmt.Errorf("current version %s is not a semantic version", current)
+	}
+	nv, err := semver.NewVersion(next)
+	if err != nil {
+		return fmt.Errorf("new version %s is not a semantic version", next)
+	}
+	if c.MinVersion != "" {
+		min, err := semver.NewVersion(c.MinVersion)
+		if err != nil {
+			return err
+		}
+		if cv.LessThan(min) {
+			return fmt.Errorf("version %s can not be upgraded to %s directly, upgrade to at least %s first", current, next, c.MinVersion)
+		}
+	}
+	if gap := nv.Major() - cv.Major(); c.MaxMajor != nil && gap > *c.MaxMajor {
+		return fmt.Errorf("upgrading from %s to %s advances %d major versions, at most %d are supported", current, next, gap, *c.MaxMajor)
+	}
+	if gap := nv.Minor() - cv.Minor(); c.MaxMinor != nil && nv.Major() == cv.Major() && gap > *c.MaxMinor {
+		return fmt.Errorf("upgrading from %s to %s advances %d minor versions, at most %d are supported", current, next, gap, *c.MaxMinor)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVersionGapCheck_CheckVersionGap(t *testing.T) {
+	one := int64(1)
+	tests := []struct {
+		name    string
+		check   VersionGapCheck
+		current string
+		next    string
+		err     string
+	}{
+		{name: "no limits", current: "1.0.0", next: "5.0.0"},
+		{name: "minimum version", check: VersionGapCheck{MinVersion: "1.2.0"}, current: "1.1.0", next: "2.0.0", err: "version 1.1.0 can not be upgraded to 2.0.0 directly, upgrade to at least 1.2.0 first"},
+		{name: "major gap", check: VersionGapCheck{MaxMajor: &one}, current: "1.4.0", next: "3.0.0", err: "upgrading from 1.4.0 to 3.0.0 advances 2 major versions, at most 1 are supported"},
+		{name: "minor gap", check: VersionGapCheck{MaxMinor: &one}, current: "1.1.0", next: "1.3.0", err: "upgrading from 1.1.0 to 1.3.0 advances 2 minor versions, at most 1 are supported"},
+		{name: "minor gap across majors", check: VersionGapCheck{MaxMinor: &one}, current: "1.1.0", next: "2.3.0"},
+		{name: "invalid version", current: "latest", next: "2.0.0", err: "current version latest is not a semantic version"},
+	}
+
+	for _, tt := range tests {
+		err := tt.check.CheckVersionGap(tt.current, tt.next)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestUpgradeCheck_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		check UpgradeCheck
+		err   string
+	}{
+		{name: "version gap", check: UpgradeCheck{Name: "gap", VersionGap: &VersionGapCheck{MinVersion: "1.0.0"}}},
+		{name: "healthy replicas", check: UpgradeCheck{Name: "healthy", HealthyReplicas: &HealthyReplicasCheck{Kind: "StatefulSet", Name: "broker"}}},
+		{name: "no name", check: UpgradeCheck{VersionGap: &VersionGapCheck{}}, err: "pre-upgrade check has no name"},
+		{name: "no check", check: UpgradeCheck{Name: "empty"}, err: "pre-upgrade check empty has to declare exactly one of versionGap and healthyReplicas"},
+		{name: "invalid minimum version", check: UpgradeCheck{Name: "gap", VersionGap: &VersionGapCheck{MinVersion: "one"}}, err: "pre-upgrade check gap has an invalid minimum version one: Invalid Semantic Version"},
+		{name: "unsupported kind", check: UpgradeCheck{Name: "healthy", HealthyReplicas: &HealthyReplicasCheck{Kind: "DaemonSet", Name: "agent"}}, err: "pre-upgrade check healthy checks unsupported kind DaemonSet, only Deployment and StatefulSet are supported"},
+	}
+
+	for _, tt := range tests {
+		err := tt.check.Validate()
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestInstance_GetPendingPreUpgradeCheck(t *testing.T) {
+	i := &Instance{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		PreUpgradeCheckAnnotation:        "kafka-2.0.0",
+		PreUpgradeCheckRequestAnnotation: "abc",
+	}}}
+	if ov, request := i.GetPendingPreUpgradeCheck(); ov != "kafka-2.0.0" || request != "abc" {
+		t.Errorf("expected pending checks of kafka-2.0.0 but got %q, %q", ov, request)
+	}
+
+	i.Status.PreUpgradeCheck = &PreUpgradeCheckStatus{Request: "abc"}
+	if ov, _ := i.GetPendingPreUpgradeCheck(); ov != "" {
+		t.Errorf("expected answered checks not to be pending but got %q", ov)
+	}
+}
@@ -19,79 +19,107 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	v1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *AggregatedStatus) DeepCopyInto(out *AggregatedStatus) {
+func (in *APIRequirement) DeepCopyInto(out *APIRequirement) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AggregatedStatus.
-func (in *AggregatedStatus) DeepCopy() *AggregatedStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APIRequirement.
+func (in *APIRequirement) DeepCopy() *APIRequirement {
 	if in == nil {
 		return nil
 	}
-	out := new(AggregatedStatus)
+	out := new(APIRequirement)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Command) DeepCopyInto(out *Command) {
+func (in *AccessEndpoint) DeepCopyInto(out *AccessEndpoint) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Command.
-func (in *Command) DeepCopy() *Command {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessEndpoint.
+func (in *AccessEndpoint) DeepCopy() *AccessEndpoint {
 	if in == nil {
 		return nil
 	}
-	out := new(Command)
+	out := new(AccessEndpoint)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DummyTaskSpec) DeepCopyInto(out *DummyTaskSpec) {
+func (in *AggregatedStatus) DeepCopyInto(out *AggregatedStatus) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DummyTaskSpec.
-func (in *DummyTaskSpec) DeepCopy() *DummyTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AggregatedStatus.
+func (in *AggregatedStatus) DeepCopy() *AggregatedStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DummyTaskSpec)
+	out := new(AggregatedStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Instance) DeepCopyInto(out *Instance) {
+func (in *ClusterRequirements) DeepCopyInto(out *ClusterRequirements) {
+	*out = *in
+	if in.APIs != nil {
+		in, out := &in.APIs, &out.APIs
+		*out = make([]APIRequirement, len(*in))
+		copy(*out, *in)
+	}
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRequirements.
+func (in *ClusterRequirements) DeepCopy() *ClusterRequirements {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRequirements)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterTarget) DeepCopyInto(out *ClusterTarget) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	in.Status.DeepCopyInto(&out.Status)
+	out.Spec = in.Spec
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Instance.
-func (in *Instance) DeepCopy() *Instance {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTarget.
+func (in *ClusterTarget) DeepCopy() *ClusterTarget {
 	if in == nil {
 		return nil
 	}
-	out := new(Instance)
+	out := new(ClusterTarget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Instance) DeepCopyObject() runtime.Object {
+func (in *ClusterTarget) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -99,13 +127,13 @@ func (in *Instance) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InstanceList) DeepCopyInto(out *InstanceList) {
+func (in *ClusterTargetList) DeepCopyInto(out *ClusterTargetList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.ListMeta = in.ListMeta
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Instance, len(*in))
+		*out = make([]ClusterTarget, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -113,18 +141,18 @@ func (in *InstanceList) DeepCopyInto(out *InstanceList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceList.
-func (in *InstanceList) DeepCopy() *InstanceList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTargetList.
+func (in *ClusterTargetList) DeepCopy() *ClusterTargetList {
 	if in == nil {
 		return nil
 	}
-	out := new(InstanceList)
+	out := new(ClusterTargetList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *InstanceList) DeepCopyObject() runtime.Object {
+func (in *ClusterTargetList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -132,12 +160,51 @@ func (in *InstanceList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
+func (in *ClusterTargetSpec) DeepCopyInto(out *ClusterTargetSpec) {
 	*out = *in
-	out.OperatorVersion = in.OperatorVersion
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make(map[string]string, len(*in))
+	out.KubeconfigSecret = in.KubeconfigSecret
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterTargetSpec.
+func (in *ClusterTargetSpec) DeepCopy() *ClusterTargetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterTargetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Command) DeepCopyInto(out *Command) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Command.
+func (in *Command) DeepCopy() *Command {
+	if in == nil {
+		return nil
+	}
+	out := new(Command)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConcurrencyLimits) DeepCopyInto(out *ConcurrencyLimits) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Operators != nil {
+		in, out := &in.Operators, &out.Operators
+		*out = make(map[string]int, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
@@ -145,102 +212,237 @@ func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceSpec.
-func (in *InstanceSpec) DeepCopy() *InstanceSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConcurrencyLimits.
+func (in *ConcurrencyLimits) DeepCopy() *ConcurrencyLimits {
 	if in == nil {
 		return nil
 	}
-	out := new(InstanceSpec)
+	out := new(ConcurrencyLimits)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
+func (in *DefaultPlans) DeepCopyInto(out *DefaultPlans) {
 	*out = *in
-	if in.PlanStatus != nil {
-		in, out := &in.PlanStatus, &out.PlanStatus
-		*out = make(map[string]PlanStatus, len(*in))
-		for key, val := range *in {
-			(*out)[key] = *val.DeepCopy()
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DefaultPlans.
+func (in *DefaultPlans) DeepCopy() *DefaultPlans {
+	if in == nil {
+		return nil
+	}
+	out := new(DefaultPlans)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftStatus) DeepCopyInto(out *DriftStatus) {
+	*out = *in
+	in.CheckedAt.DeepCopyInto(&out.CheckedAt)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceDrift, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	out.AggregatedStatus = in.AggregatedStatus
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
-func (in *InstanceStatus) DeepCopy() *InstanceStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftStatus.
+func (in *DriftStatus) DeepCopy() *DriftStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(InstanceStatus)
+	out := new(DriftStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Maintainer) DeepCopyInto(out *Maintainer) {
+func (in *DryRunStatus) DeepCopyInto(out *DryRunStatus) {
 	*out = *in
+	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
+	if in.Changes != nil {
+		in, out := &in.Changes, &out.Changes
+		*out = make([]ResourceChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.SkippedTasks != nil {
+		in, out := &in.SkippedTasks, &out.SkippedTasks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Maintainer.
-func (in *Maintainer) DeepCopy() *Maintainer {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DryRunStatus.
+func (in *DryRunStatus) DeepCopy() *DryRunStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(Maintainer)
+	out := new(DryRunStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+func (in *DummyTaskSpec) DeepCopyInto(out *DummyTaskSpec) {
 	*out = *in
-	out.ObjectReference = in.ObjectReference
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DummyTaskSpec.
+func (in *DummyTaskSpec) DeepCopy() *DummyTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DummyTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EndpointStatus) DeepCopyInto(out *EndpointStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EndpointStatus.
+func (in *EndpointStatus) DeepCopy() *EndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExecTaskSpec) DeepCopyInto(out *ExecTaskSpec) {
+	*out = *in
+	if in.PodSelector != nil {
+		in, out := &in.PodSelector, &out.PodSelector
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
-func (in *ObjectReference) DeepCopy() *ObjectReference {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExecTaskSpec.
+func (in *ExecTaskSpec) DeepCopy() *ExecTaskSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ObjectReference)
+	out := new(ExecTaskSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Operator) DeepCopyInto(out *Operator) {
+func (in *FieldDrift) DeepCopyInto(out *FieldDrift) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldDrift.
+func (in *FieldDrift) DeepCopy() *FieldDrift {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldDrift)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthyReplicasCheck) DeepCopyInto(out *HealthyReplicasCheck) {
+	*out = *in
+	out.MinReady = in.MinReady
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthyReplicasCheck.
+func (in *HealthyReplicasCheck) DeepCopy() *HealthyReplicasCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthyReplicasCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageRewrite) DeepCopyInto(out *ImageRewrite) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageRewrite.
+func (in *ImageRewrite) DeepCopy() *ImageRewrite {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageRewrite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageSettings) DeepCopyInto(out *ImageSettings) {
+	*out = *in
+	if in.PullSecrets != nil {
+		in, out := &in.PullSecrets, &out.PullSecrets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageSettings.
+func (in *ImageSettings) DeepCopy() *ImageSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Instance) DeepCopyInto(out *Instance) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Operator.
-func (in *Operator) DeepCopy() *Operator {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Instance.
+func (in *Instance) DeepCopy() *Instance {
 	if in == nil {
 		return nil
 	}
-	out := new(Operator)
+	out := new(Instance)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Operator) DeepCopyObject() runtime.Object {
+func (in *Instance) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -248,30 +450,30 @@ func (in *Operator) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorDependency) DeepCopyInto(out *OperatorDependency) {
+func (in *InstanceCondition) DeepCopyInto(out *InstanceCondition) {
 	*out = *in
-	out.ObjectReference = in.ObjectReference
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorDependency.
-func (in *OperatorDependency) DeepCopy() *OperatorDependency {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceCondition.
+func (in *InstanceCondition) DeepCopy() *InstanceCondition {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorDependency)
+	out := new(InstanceCondition)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorList) DeepCopyInto(out *OperatorList) {
+func (in *InstanceList) DeepCopyInto(out *InstanceList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	out.ListMeta = in.ListMeta
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Operator, len(*in))
+		*out = make([]Instance, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
@@ -279,18 +481,18 @@ func (in *OperatorList) DeepCopyInto(out *OperatorList) {
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorList.
-func (in *OperatorList) DeepCopy() *OperatorList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceList.
+func (in *InstanceList) DeepCopy() *InstanceList {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorList)
+	out := new(InstanceList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatorList) DeepCopyObject() runtime.Object {
+func (in *InstanceList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -298,311 +500,1350 @@ func (in *OperatorList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorSpec) DeepCopyInto(out *OperatorSpec) {
+func (in *InstanceRevision) DeepCopyInto(out *InstanceRevision) {
 	*out = *in
-	if in.Maintainers != nil {
-		in, out := &in.Maintainers, &out.Maintainers
-		*out = make([]*Maintainer, len(*in))
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]ParameterChange, len(*in))
 		for i := range *in {
-			if (*in)[i] != nil {
-				in, out := &(*in)[i], &(*out)[i]
-				*out = new(Maintainer)
-				**out = **in
-			}
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PlanFinishedAt != nil {
+		in, out := &in.PlanFinishedAt, &out.PlanFinishedAt
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceRevision.
+func (in *InstanceRevision) DeepCopy() *InstanceRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceSpec) DeepCopyInto(out *InstanceSpec) {
+	*out = *in
+	out.OperatorVersion = in.OperatorVersion
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make([]PlanSchedule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(Placement)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodMetadata != nil {
+		in, out := &in.PodMetadata, &out.PodMetadata
+		*out = new(PodMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceSpec.
+func (in *InstanceSpec) DeepCopy() *InstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceStatus) DeepCopyInto(out *InstanceStatus) {
+	*out = *in
+	if in.PlanStatus != nil {
+		in, out := &in.PlanStatus, &out.PlanStatus
+		*out = make(map[string]PlanStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	out.AggregatedStatus = in.AggregatedStatus
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]InstanceCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Drift != nil {
+		in, out := &in.Drift, &out.Drift
+		*out = new(DriftStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schedules != nil {
+		in, out := &in.Schedules, &out.Schedules
+		*out = make(map[string]ScheduleStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.DryRun != nil {
+		in, out := &in.DryRun, &out.DryRun
+		*out = new(DryRunStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreUpgradeCheck != nil {
+		in, out := &in.PreUpgradeCheck, &out.PreUpgradeCheck
+		*out = new(PreUpgradeCheckStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]EndpointStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceStatus.
+func (in *InstanceStatus) DeepCopy() *InstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InstanceTaskSpec) DeepCopyInto(out *InstanceTaskSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InstanceTaskSpec.
+func (in *InstanceTaskSpec) DeepCopy() *InstanceTaskSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InstanceTaskSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KudoConfig) DeepCopyInto(out *KudoConfig) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KudoConfig.
+func (in *KudoConfig) DeepCopy() *KudoConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(KudoConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KudoConfig) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KudoConfigList) DeepCopyInto(out *KudoConfigList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]KudoConfig, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KudoConfigList.
+func (in *KudoConfigList) DeepCopy() *KudoConfigList {
+	if in == nil {
+		return nil
+	}
+	out := new(KudoConfigList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *KudoConfigList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KudoConfigSpec) DeepCopyInto(out *KudoConfigSpec) {
+	*out = *in
+	in.Concurrency.DeepCopyInto(&out.Concurrency)
+	in.LabelPropagation.DeepCopyInto(&out.LabelPropagation)
+	in.Images.DeepCopyInto(&out.Images)
+	in.PostRender.DeepCopyInto(&out.PostRender)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KudoConfigSpec.
+func (in *KudoConfigSpec) DeepCopy() *KudoConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KudoConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelPropagationPolicy) DeepCopyInto(out *LabelPropagationPolicy) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelPropagationPolicy.
+func (in *LabelPropagationPolicy) DeepCopy() *LabelPropagationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelPropagationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Link) DeepCopyInto(out *Link) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Link.
+func (in *Link) DeepCopy() *Link {
+	if in == nil {
+		return nil
+	}
+	out := new(Link)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogReference) DeepCopyInto(out *LogReference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogReference.
+func (in *LogReference) DeepCopy() *LogReference {
+	if in == nil {
+		return nil
+	}
+	out := new(LogReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Maintainer) DeepCopyInto(out *Maintainer) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Maintainer.
+func (in *Maintainer) DeepCopy() *Maintainer {
+	if in == nil {
+		return nil
+	}
+	out := new(Maintainer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+	*out = *in
+	out.ObjectReference = in.ObjectReference
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
+func (in *ObjectReference) DeepCopy() *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Operator) DeepCopyInto(out *Operator) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Operator.
+func (in *Operator) DeepCopy() *Operator {
+	if in == nil {
+		return nil
+	}
+	out := new(Operator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Operator) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorDependency) DeepCopyInto(out *OperatorDependency) {
+	*out = *in
+	out.ObjectReference = in.ObjectReference
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorDependency.
+func (in *OperatorDependency) DeepCopy() *OperatorDependency {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorDependency)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorList) DeepCopyInto(out *OperatorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Operator, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorList.
+func (in *OperatorList) DeepCopy() *OperatorList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorSpec) DeepCopyInto(out *OperatorSpec) {
+	*out = *in
+	if in.Maintainers != nil {
+		in, out := &in.Maintainers, &out.Maintainers
+		*out = make([]*Maintainer, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(Maintainer)
+				**out = **in
+			}
+		}
+	}
+	if in.Keywords != nil {
+		in, out := &in.Keywords, &out.Keywords
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Categories != nil {
+		in, out := &in.Categories, &out.Categories
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Links != nil {
+		in, out := &in.Links, &out.Links
+		*out = make([]*Link, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(Link)
+				**out = **in
+			}
+		}
+	}
+	if in.Requirements != nil {
+		in, out := &in.Requirements, &out.Requirements
+		*out = new(ClusterRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSpec.
+func (in *OperatorSpec) DeepCopy() *OperatorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorStatus) DeepCopyInto(out *OperatorStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatus.
+func (in *OperatorStatus) DeepCopy() *OperatorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorVersion) DeepCopyInto(out *OperatorVersion) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersion.
+func (in *OperatorVersion) DeepCopy() *OperatorVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorVersion) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorVersionList) DeepCopyInto(out *OperatorVersionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OperatorVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionList.
+func (in *OperatorVersionList) DeepCopy() *OperatorVersionList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorVersionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatorVersionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorVersionSpec) DeepCopyInto(out *OperatorVersionSpec) {
+	*out = *in
+	out.Operator = in.Operator
+	if in.Templates != nil {
+		in, out := &in.Templates, &out.Templates
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tasks != nil {
+		in, out := &in.Tasks, &out.Tasks
+		*out = make([]Task, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]Parameter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plans != nil {
+		in, out := &in.Plans, &out.Plans
+		*out = make(map[string]Plan, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+	if in.Constants != nil {
+		in, out := &in.Constants, &out.Constants
+		*out = new(v1beta1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]AccessEndpoint, len(*in))
+		copy(*out, *in)
+	}
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = make([]OperatorDependency, len(*in))
+		copy(*out, *in)
+	}
+	if in.UpgradableFrom != nil {
+		in, out := &in.UpgradableFrom, &out.UpgradableFrom
+		*out = make([]OperatorVersion, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CRDs != nil {
+		in, out := &in.CRDs, &out.CRDs
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.PreUpgradeChecks != nil {
+		in, out := &in.PreUpgradeChecks, &out.PreUpgradeChecks
+		*out = make([]UpgradeCheck, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Overlays != nil {
+		in, out := &in.Overlays, &out.Overlays
+		*out = make(map[string][]Patch, len(*in))
+		for key, val := range *in {
+			var outVal []Patch
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make([]Patch, len(*in))
+				for i := range *in {
+					(*in)[i].DeepCopyInto(&(*out)[i])
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.DefaultPlans != nil {
+		in, out := &in.DefaultPlans, &out.DefaultPlans
+		*out = new(DefaultPlans)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionSpec.
+func (in *OperatorVersionSpec) DeepCopy() *OperatorVersionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorVersionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatorVersionStatus) DeepCopyInto(out *OperatorVersionStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionStatus.
+func (in *OperatorVersionStatus) DeepCopy() *OperatorVersionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatorVersionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Parameter) DeepCopyInto(out *Parameter) {
+	*out = *in
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = new(string)
+		**out = **in
+	}
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = new(ParameterItems)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Schema != nil {
+		in, out := &in.Schema, &out.Schema
+		*out = new(v1beta1.JSONSchemaProps)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Deprecated != nil {
+		in, out := &in.Deprecated, &out.Deprecated
+		*out = new(ParameterDeprecation)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Parameter.
+func (in *Parameter) DeepCopy() *Parameter {
+	if in == nil {
+		return nil
+	}
+	out := new(Parameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterChange) DeepCopyInto(out *ParameterChange) {
+	*out = *in
+	if in.Old != nil {
+		in, out := &in.Old, &out.Old
+		*out = new(string)
+		**out = **in
+	}
+	if in.New != nil {
+		in, out := &in.New, &out.New
+		*out = new(string)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterChange.
+func (in *ParameterChange) DeepCopy() *ParameterChange {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterDeprecation) DeepCopyInto(out *ParameterDeprecation) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterDeprecation.
+func (in *ParameterDeprecation) DeepCopy() *ParameterDeprecation {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterDeprecation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterItems) DeepCopyInto(out *ParameterItems) {
+	*out = *in
+	if in.Required != nil {
+		in, out := &in.Required, &out.Required
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterItems.
+func (in *ParameterItems) DeepCopy() *ParameterItems {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterItems)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParameterValue) DeepCopyInto(out *ParameterValue) {
+	*out = *in
+	if in.Definition != nil {
+		in, out := &in.Definition, &out.Definition
+		*out = new(Parameter)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParameterValue.
+func (in *ParameterValue) DeepCopy() *ParameterValue {
+	if in == nil {
+		return nil
+	}
+	out := new(ParameterValue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Patch) DeepCopyInto(out *Patch) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(PatchTarget)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Patch.
+func (in *Patch) DeepCopy() *Patch {
+	if in == nil {
+		return nil
+	}
+	out := new(Patch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Phase) DeepCopyInto(out *Phase) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]Step, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Phase.
+func (in *Phase) DeepCopy() *Phase {
+	if in == nil {
+		return nil
+	}
+	out := new(Phase)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PhaseStatus) DeepCopyInto(out *PhaseStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]StepStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseStatus.
+func (in *PhaseStatus) DeepCopy() *PhaseStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PhaseStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Placement) DeepCopyInto(out *Placement) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Placement.
+func (in *Placement) DeepCopy() *Placement {
+	if in == nil {
+		return nil
+	}
+	out := new(Placement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Plan) DeepCopyInto(out *Plan) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]Phase, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Plan.
+func (in *Plan) DeepCopy() *Plan {
+	if in == nil {
+		return nil
+	}
+	out := new(Plan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanExecution) DeepCopyInto(out *PlanExecution) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanExecution.
+func (in *PlanExecution) DeepCopy() *PlanExecution {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanExecution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlanExecution) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanExecutionList) DeepCopyInto(out *PlanExecutionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlanExecution, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanExecutionList.
+func (in *PlanExecutionList) DeepCopy() *PlanExecutionList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanExecutionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlanExecutionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanExecutionSpec) DeepCopyInto(out *PlanExecutionSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanExecutionSpec.
+func (in *PlanExecutionSpec) DeepCopy() *PlanExecutionSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanExecutionSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanExecutionStatus) DeepCopyInto(out *PlanExecutionStatus) {
+	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.FinishedAt != nil {
+		in, out := &in.FinishedAt, &out.FinishedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]PhaseStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Logs != nil {
+		in, out := &in.Logs, &out.Logs
+		*out = make([]LogReference, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorSpec.
-func (in *OperatorSpec) DeepCopy() *OperatorSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanExecutionStatus.
+func (in *PlanExecutionStatus) DeepCopy() *PlanExecutionStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorSpec)
+	out := new(PlanExecutionStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorStatus) DeepCopyInto(out *OperatorStatus) {
+func (in *PlanSchedule) DeepCopyInto(out *PlanSchedule) {
 	*out = *in
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorStatus.
-func (in *OperatorStatus) DeepCopy() *OperatorStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanSchedule.
+func (in *PlanSchedule) DeepCopy() *PlanSchedule {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorStatus)
+	out := new(PlanSchedule)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorVersion) DeepCopyInto(out *OperatorVersion) {
+func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	in.LastFinishedRun.DeepCopyInto(&out.LastFinishedRun)
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make([]PhaseStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersion.
-func (in *OperatorVersion) DeepCopy() *OperatorVersion {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
+func (in *PlanStatus) DeepCopy() *PlanStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorVersion)
+	out := new(PlanStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatorVersion) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorVersionList) DeepCopyInto(out *OperatorVersionList) {
+func (in *PodMetadata) DeepCopyInto(out *PodMetadata) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	out.ListMeta = in.ListMeta
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]OperatorVersion, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
 		}
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionList.
-func (in *OperatorVersionList) DeepCopy() *OperatorVersionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodMetadata.
+func (in *PodMetadata) DeepCopy() *PodMetadata {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorVersionList)
+	out := new(PodMetadata)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatorVersionList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorVersionSpec) DeepCopyInto(out *OperatorVersionSpec) {
+func (in *PostRenderSettings) DeepCopyInto(out *PostRenderSettings) {
 	*out = *in
-	out.Operator = in.Operator
-	if in.Templates != nil {
-		in, out := &in.Templates, &out.Templates
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	if in.Tasks != nil {
-		in, out := &in.Tasks, &out.Tasks
-		*out = make([]Task, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.ImageRewrites != nil {
+		in, out := &in.ImageRewrites, &out.ImageRewrites
+		*out = make([]ImageRewrite, len(*in))
+		copy(*out, *in)
 	}
-	if in.Parameters != nil {
-		in, out := &in.Parameters, &out.Parameters
-		*out = make([]Parameter, len(*in))
+	if in.Webhooks != nil {
+		in, out := &in.Webhooks, &out.Webhooks
+		*out = make([]PostRenderWebhook, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
-	if in.Plans != nil {
-		in, out := &in.Plans, &out.Plans
-		*out = make(map[string]Plan, len(*in))
-		for key, val := range *in {
-			(*out)[key] = *val.DeepCopy()
-		}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderSettings.
+func (in *PostRenderSettings) DeepCopy() *PostRenderSettings {
+	if in == nil {
+		return nil
 	}
-	if in.Dependencies != nil {
-		in, out := &in.Dependencies, &out.Dependencies
-		*out = make([]OperatorDependency, len(*in))
+	out := new(PostRenderSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderWebhook) DeepCopyInto(out *PostRenderWebhook) {
+	*out = *in
+	if in.CABundle != nil {
+		in, out := &in.CABundle, &out.CABundle
+		*out = make([]byte, len(*in))
 		copy(*out, *in)
 	}
-	if in.UpgradableFrom != nil {
-		in, out := &in.UpgradableFrom, &out.UpgradableFrom
-		*out = make([]OperatorVersion, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionSpec.
-func (in *OperatorVersionSpec) DeepCopy() *OperatorVersionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderWebhook.
+func (in *PostRenderWebhook) DeepCopy() *PostRenderWebhook {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorVersionSpec)
+	out := new(PostRenderWebhook)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatorVersionStatus) DeepCopyInto(out *OperatorVersionStatus) {
+func (in *PreUpgradeCheckStatus) DeepCopyInto(out *PreUpgradeCheckStatus) {
 	*out = *in
+	in.CompletedAt.DeepCopyInto(&out.CompletedAt)
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]UpgradeCheckResult, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatorVersionStatus.
-func (in *OperatorVersionStatus) DeepCopy() *OperatorVersionStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreUpgradeCheckStatus.
+func (in *PreUpgradeCheckStatus) DeepCopy() *PreUpgradeCheckStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatorVersionStatus)
+	out := new(PreUpgradeCheckStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Parameter) DeepCopyInto(out *Parameter) {
+func (in *ResourceChange) DeepCopyInto(out *ResourceChange) {
 	*out = *in
-	if in.Default != nil {
-		in, out := &in.Default, &out.Default
-		*out = new(string)
-		**out = **in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]FieldDrift, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Parameter.
-func (in *Parameter) DeepCopy() *Parameter {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceChange.
+func (in *ResourceChange) DeepCopy() *ResourceChange {
 	if in == nil {
 		return nil
 	}
-	out := new(Parameter)
+	out := new(ResourceChange)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Phase) DeepCopyInto(out *Phase) {
+func (in *ResourceDrift) DeepCopyInto(out *ResourceDrift) {
 	*out = *in
-	if in.Steps != nil {
-		in, out := &in.Steps, &out.Steps
-		*out = make([]Step, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]FieldDrift, len(*in))
+		copy(*out, *in)
+	}
+	if in.CorrectedAt != nil {
+		in, out := &in.CorrectedAt, &out.CorrectedAt
+		*out = (*in).DeepCopy()
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Phase.
-func (in *Phase) DeepCopy() *Phase {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceDrift.
+func (in *ResourceDrift) DeepCopy() *ResourceDrift {
 	if in == nil {
 		return nil
 	}
-	out := new(Phase)
+	out := new(ResourceDrift)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PhaseStatus) DeepCopyInto(out *PhaseStatus) {
+func (in *ResourceStatus) DeepCopyInto(out *ResourceStatus) {
 	*out = *in
-	if in.Steps != nil {
-		in, out := &in.Steps, &out.Steps
-		*out = make([]StepStatus, len(*in))
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceStatus.
+func (in *ResourceStatus) DeepCopy() *ResourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceTaskSpec) DeepCopyInto(out *ResourceTaskSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.KindOrder != nil {
+		in, out := &in.KindOrder, &out.KindOrder
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PhaseStatus.
-func (in *PhaseStatus) DeepCopy() *PhaseStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTaskSpec.
+func (in *ResourceTaskSpec) DeepCopy() *ResourceTaskSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PhaseStatus)
+	out := new(ResourceTaskSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Plan) DeepCopyInto(out *Plan) {
+func (in *RolloutTaskSpec) DeepCopyInto(out *RolloutTaskSpec) {
 	*out = *in
-	if in.Phases != nil {
-		in, out := &in.Phases, &out.Phases
-		*out = make([]Phase, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.BatchSize != nil {
+		in, out := &in.BatchSize, &out.BatchSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Pause != nil {
+		in, out := &in.Pause, &out.Pause
+		*out = new(v1.Duration)
+		**out = **in
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Plan.
-func (in *Plan) DeepCopy() *Plan {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutTaskSpec.
+func (in *RolloutTaskSpec) DeepCopy() *RolloutTaskSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(Plan)
+	out := new(RolloutTaskSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+func (in *ScheduleStatus) DeepCopyInto(out *ScheduleStatus) {
 	*out = *in
-	in.LastFinishedRun.DeepCopyInto(&out.LastFinishedRun)
-	if in.Phases != nil {
-		in, out := &in.Phases, &out.Phases
-		*out = make([]PhaseStatus, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	in.LastScheduleTime.DeepCopyInto(&out.LastScheduleTime)
+	if in.LastExecutionTime != nil {
+		in, out := &in.LastExecutionTime, &out.LastExecutionTime
+		*out = (*in).DeepCopy()
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
-func (in *PlanStatus) DeepCopy() *PlanStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScheduleStatus.
+func (in *ScheduleStatus) DeepCopy() *ScheduleStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PlanStatus)
+	out := new(ScheduleStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ResourceTaskSpec) DeepCopyInto(out *ResourceTaskSpec) {
+func (in *SecretKeyReference) DeepCopyInto(out *SecretKeyReference) {
 	*out = *in
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]string, len(*in))
-		copy(*out, *in)
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTaskSpec.
-func (in *ResourceTaskSpec) DeepCopy() *ResourceTaskSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretKeyReference.
+func (in *SecretKeyReference) DeepCopy() *SecretKeyReference {
 	if in == nil {
 		return nil
 	}
-	out := new(ResourceTaskSpec)
+	out := new(SecretKeyReference)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -624,6 +1865,11 @@ func (in *Step) DeepCopyInto(out *Step) {
 			}
 		}
 	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 	return
 }
 
@@ -640,6 +1886,22 @@ func (in *Step) DeepCopy() *Step {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *StepStatus) DeepCopyInto(out *StepStatus) {
 	*out = *in
+	in.StartedAt.DeepCopyInto(&out.StartedAt)
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletedTasks != nil {
+		in, out := &in.CompletedTasks, &out.CompletedTasks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -675,6 +1937,9 @@ func (in *TaskSpec) DeepCopyInto(out *TaskSpec) {
 	*out = *in
 	in.ResourceTaskSpec.DeepCopyInto(&out.ResourceTaskSpec)
 	out.DummyTaskSpec = in.DummyTaskSpec
+	in.InstanceTaskSpec.DeepCopyInto(&out.InstanceTaskSpec)
+	in.ExecTaskSpec.DeepCopyInto(&out.ExecTaskSpec)
+	in.RolloutTaskSpec.DeepCopyInto(&out.RolloutTaskSpec)
 	return
 }
 
@@ -801,3 +2066,71 @@ func (in *TestSuite) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCheck) DeepCopyInto(out *UpgradeCheck) {
+	*out = *in
+	if in.VersionGap != nil {
+		in, out := &in.VersionGap, &out.VersionGap
+		*out = new(VersionGapCheck)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HealthyReplicas != nil {
+		in, out := &in.HealthyReplicas, &out.HealthyReplicas
+		*out = new(HealthyReplicasCheck)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCheck.
+func (in *UpgradeCheck) DeepCopy() *UpgradeCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeCheckResult) DeepCopyInto(out *UpgradeCheckResult) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeCheckResult.
+func (in *UpgradeCheckResult) DeepCopy() *UpgradeCheckResult {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeCheckResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionGapCheck) DeepCopyInto(out *VersionGapCheck) {
+	*out = *in
+	if in.MaxMajor != nil {
+		in, out := &in.MaxMajor, &out.MaxMajor
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxMinor != nil {
+		in, out := &in.MaxMinor, &out.MaxMinor
+		*out = new(int64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionGapCheck.
+func (in *VersionGapCheck) DeepCopy() *VersionGapCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionGapCheck)
+	in.DeepCopyInto(out)
+	return out
+}
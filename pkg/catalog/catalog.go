@@ -0,0 +1,169 @@
+package catalog
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Operator is the catalog entry of an installed operator
+type Operator struct {
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	Description string   `json:"description,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Categories  []string `json:"categories,omitempty"`
+	// Versions are the versions of the installed operatorversions of the operator
+	Versions []string `json:"versions"`
+}
+
+// OperatorVersion is the catalog entry of an installed operatorversion. Templates and parameters are not exposed,
+// default values of parameters may be sensitive.
+type OperatorVersion struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Operator   string `json:"operator"`
+	Version    string `json:"version"`
+	AppVersion string `json:"appVersion,omitempty"`
+	// Plans are the names of the plans of the operatorversion
+	Plans []string `json:"plans"`
+}
+
+// Instance is the catalog entry of an instance with the status of its plans. Parameters are not exposed, they may be
+// sensitive.
+type Instance struct {
+	Name                     string                   `json:"name"`
+	Namespace                string                   `json:"namespace"`
+	Operator                 string                   `json:"operator,omitempty"`
+	OperatorVersion          string                   `json:"operatorVersion"`
+	OperatorVersionNamespace string                   `json:"operatorVersionNamespace"`
+	Status                   v1alpha1.ExecutionStatus `json:"status,omitempty"`
+	ActivePlan               string                   `json:"activePlan,omitempty"`
+	Plans                    []PlanStatus             `json:"plans,omitempty"`
+}
+
+// PlanStatus is the status of a plan of an instance
+type PlanStatus struct {
+	Name            string                   `json:"name"`
+	Status          v1alpha1.ExecutionStatus `json:"status"`
+	Message         string                   `json:"message,omitempty"`
+	LastFinishedRun *metav1.Time             `json:"lastFinishedRun,omitempty"`
+}
+
+// listOperators returns the operators of the namespace, or of all namespaces if it is empty, sorted by namespace and
+// name
+func listOperators(c client.Reader, namespace string) ([]Operator, error) {
+	operators := &v1alpha1.OperatorList{}
+	if err := c.List(context.TODO(), operators, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	ovs := &v1alpha1.OperatorVersionList{}
+	if err := c.List(context.TODO(), ovs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	versions := map[string][]string{}
+	for _, ov := range ovs.Items {
+		key := ov.Namespace + "/" + ov.Spec.Operator.Name
+		versions[key] = append(versions[key], ov.Spec.Version)
+	}
+	result := make([]Operator, 0, len(operators.Items))
+	for _, o := range operators.Items {
+		v := versions[o.Namespace+"/"+o.Name]
+		sort.Strings(v)
+		if v == nil {
+			v = []string{}
+		}
+		result = append(result, Operator{
+			Name:        o.Name,
+			Namespace:   o.Namespace,
+			Description: o.Spec.Description,
+			URL:         o.Spec.URL,
+			Categories:  o.Spec.Categories,
+			Versions:    v,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i].Namespace, result[i].Name, result[j].Namespace, result[j].Name)
+	})
+	return result, nil
+}
+
+// listOperatorVersions returns the operatorversions of the namespace, or of all namespaces if it is empty, sorted by
+// namespace and name
+func listOperatorVersions(c client.Reader, namespace string) ([]OperatorVersion, error) {
+	ovs := &v1alpha1.OperatorVersionList{}
+	if err := c.List(context.TODO(), ovs, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	result := make([]OperatorVersion, 0, len(ovs.Items))
+	for _, ov := range ovs.Items {
+		plans := make([]string, 0, len(ov.Spec.Plans))
+		for name := range ov.Spec.Plans {
+			plans = append(plans, name)
+		}
+		sort.Strings(plans)
+		result = append(result, OperatorVersion{
+			Name:       ov.Name,
+			Namespace:  ov.Namespace,
+			Operator:   ov.Spec.Operator.Name,
+			Version:    ov.Spec.Version,
+			AppVersion: ov.Spec.AppVersion,
+			Plans:      plans,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i].Namespace, result[i].Name, result[j].Namespace, result[j].Name)
+	})
+	return result, nil
+}
+
+// listInstances returns the instances of the namespace, or of all namespaces if it is empty, sorted by namespace and
+// name
+func listInstances(c client.Reader, namespace string) ([]Instance, error) {
+	instances := &v1alpha1.InstanceList{}
+	if err := c.List(context.TODO(), instances, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	result := make([]Instance, 0, len(instances.Items))
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		plans := make([]PlanStatus, 0, len(instance.Status.PlanStatus))
+		for _, p := range instance.Status.PlanStatus {
+			plan := PlanStatus{Name: p.Name, Status: p.Status, Message: p.Message}
+			if !p.LastFinishedRun.IsZero() {
+				plan.LastFinishedRun = p.LastFinishedRun.DeepCopy()
+			}
+			plans = append(plans, plan)
+		}
+		sort.Slice(plans, func(i, j int) bool { return plans[i].Name < plans[j].Name })
+		result = append(result, Instance{
+			Name:                     instance.Name,
+			Namespace:                instance.Namespace,
+			Operator:                 instance.Labels[kudo.OperatorLabel],
+			OperatorVersion:          instance.Spec.OperatorVersion.Name,
+			OperatorVersionNamespace: instance.OperatorVersionNamespace(),
+			Status:                   instance.Status.AggregatedStatus.Status,
+			ActivePlan:               instance.Status.AggregatedStatus.ActivePlanName,
+			Plans:                    plans,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return less(result[i].Namespace, result[i].Name, result[j].Namespace, result[j].Name)
+	})
+	return result, nil
+}
+
+func less(namespace1, name1, namespace2, name2 string) bool {
+	if namespace1 != namespace2 {
+		return namespace1 < namespace2
+	}
+	return name1 < name2
+}
@@ -0,0 +1,217 @@
+// Package catalog serves a read-only JSON API of the installed operators, operatorversions and instances and the
+// status of their plans, e.g. for internal portals that display the KUDO state without access to the API server.
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// OperatorsPath is the path the operators are served at
+	OperatorsPath = "/catalog/v1/operators"
+	// OperatorVersionsPath is the path the operatorversions are served at
+	OperatorVersionsPath = "/catalog/v1/operatorversions"
+	// InstancesPath is the path the instances and the status of their plans are served at
+	InstancesPath = "/catalog/v1/instances"
+)
+
+// Server serves the catalog API. Every request has to carry the bearer token of a user or service account, it is
+// authenticated with a TokenReview. The user has to be allowed to list the KUDO resources of an endpoint in the
+// requested namespace, which is checked with a SubjectAccessReview. The namespace is selected with the "namespace"
+// query parameter, all namespaces are listed without it.
+// It implements manager.Runnable and runs on all replicas, not only on the leader.
+type Server struct {
+	// Addr is the TCP address the server listens on, e.g. ":8443"
+	Addr string
+	// CertDir is the directory with the tls.crt and tls.key the server is served with, plain HTTP is served if it is
+	// empty. Bearer tokens are sent with every request, so plain HTTP should only be used behind a TLS terminating
+	// proxy.
+	CertDir string
+	// Client reads the KUDO objects, usually from the cache of the manager
+	Client client.Reader
+	// KubeClient creates the TokenReviews and SubjectAccessReviews
+	KubeClient kubernetes.Interface
+}
+
+// endpoint lists the catalog entries of a namespace, it requires the permission to list the resources
+type endpoint struct {
+	resources []string
+	list      func(c client.Reader, namespace string) (interface{}, error)
+}
+
+// response is the body of successful responses
+type response struct {
+	Items interface{} `json:"items"`
+}
+
+// errorResponse is the body of failed responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Handler returns the handler serving the catalog API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(OperatorsPath, s.handle(endpoint{
+		resources: []string{"operators", "operatorversions"},
+		list: func(c client.Reader, namespace string) (interface{}, error) {
+			return listOperators(c, namespace)
+		},
+	}))
+	mux.Handle(OperatorVersionsPath, s.handle(endpoint{
+		resources: []string{"operatorversions"},
+		list: func(c client.Reader, namespace string) (interface{}, error) {
+			return listOperatorVersions(c, namespace)
+		},
+	}))
+	mux.Handle(InstancesPath, s.handle(endpoint{
+		resources: []string{"instances"},
+		list: func(c client.Reader, namespace string) (interface{}, error) {
+			return listInstances(c, namespace)
+		},
+	}))
+	return mux
+}
+
+func (s *Server) handle(e endpoint) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeJSON(w, http.StatusMethodNotAllowed, errorResponse{Error: fmt.Sprintf("method %s is not allowed", r.Method)})
+			return
+		}
+		namespace := r.URL.Query().Get("namespace")
+
+		user, status, err := s.authenticate(r)
+		if err != nil {
+			writeJSON(w, status, errorResponse{Error: err.Error()})
+			return
+		}
+		for _, resource := range e.resources {
+			if status, err := s.authorize(user, resource, namespace); err != nil {
+				writeJSON(w, status, errorResponse{Error: err.Error()})
+				return
+			}
+		}
+
+		items, err := e.list(s.Client, namespace)
+		if err != nil {
+			log.Printf("CatalogServer: Error listing %s: %v", r.URL.Path, err)
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: fmt.Sprintf("failed to list %s", strings.Join(e.resources, " and "))})
+			return
+		}
+		writeJSON(w, http.StatusOK, response{Items: items})
+	})
+}
+
+// authenticate returns the user of the bearer token of the request, or the status and error of the response if the
+// token is missing or invalid
+func (s *Server) authenticate(r *http.Request) (authenticationv1.UserInfo, int, error) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") || strings.TrimSpace(strings.TrimPrefix(header, "Bearer ")) == "" {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, fmt.Errorf("a bearer token is required")
+	}
+	review, err := s.KubeClient.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))},
+	})
+	if err != nil {
+		log.Printf("CatalogServer: Error reviewing token: %v", err)
+		return authenticationv1.UserInfo{}, http.StatusInternalServerError, fmt.Errorf("failed to authenticate the request")
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, http.StatusUnauthorized, fmt.Errorf("the bearer token is invalid")
+	}
+	return review.Status.User, http.StatusOK, nil
+}
+
+// authorize returns the status and error of the response if the user is not allowed to list the KUDO resource in the
+// namespace
+func (s *Server) authorize(user authenticationv1.UserInfo, resource, namespace string) (int, error) {
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review, err := s.KubeClient.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Group:     v1alpha1.SchemeGroupVersion.Group,
+				Resource:  resource,
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("CatalogServer: Error reviewing access of %s: %v", user.Username, err)
+		return http.StatusInternalServerError, fmt.Errorf("failed to authorize the request")
+	}
+	if !review.Status.Allowed {
+		scope := "all namespaces"
+		if namespace != "" {
+			scope = fmt.Sprintf("namespace %s", namespace)
+		}
+		return http.StatusForbidden, fmt.Errorf("user %s can not list %s in %s", user.Username, resource, scope)
+	}
+	return http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("CatalogServer: Error writing response: %v", err)
+	}
+}
+
+// Start serves the catalog API until the stop channel is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for the catalog API: %v", s.Addr, err)
+	}
+	server := &http.Server{Handler: s.Handler()}
+
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("CatalogServer: Error shutting down: %v", err)
+		}
+	}()
+
+	if s.CertDir != "" {
+		err = server.ServeTLS(listener, filepath.Join(s.CertDir, "tls.crt"), filepath.Join(s.CertDir, "tls.key"))
+	} else {
+		err = server.Serve(listener)
+	}
+	if err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, all replicas serve the catalog API
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,113 @@
+package catalog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestServer_Handler(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	finished := metav1.NewTime(time.Unix(1577836800, 0))
+	objs := []runtime.Object{
+		&v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "team-a", Labels: map[string]string{kudo.OperatorLabel: "kafka"}},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: corev1.ObjectReference{Name: "kafka-1.2.0", Namespace: "kudo-catalog"}},
+			Status: v1alpha1.InstanceStatus{
+				AggregatedStatus: v1alpha1.AggregatedStatus{Status: v1alpha1.ExecutionInProgress, ActivePlanName: "update"},
+				PlanStatus: map[string]v1alpha1.PlanStatus{
+					"update": {Name: "update", Status: v1alpha1.ExecutionInProgress},
+					"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete, LastFinishedRun: finished},
+				},
+			},
+		},
+		&v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.2.0", Namespace: "kudo-catalog"},
+			Spec: v1alpha1.OperatorVersionSpec{
+				Operator:   corev1.ObjectReference{Name: "kafka"},
+				Version:    "1.2.0",
+				AppVersion: "2.4.0",
+				Plans:      map[string]v1alpha1.Plan{"update": {}, "deploy": {}},
+				Parameters: []v1alpha1.Parameter{{Name: "PASSWORD", Default: kudo.String("secret")}},
+			},
+		},
+		&v1alpha1.Operator{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "kudo-catalog"},
+			Spec:       v1alpha1.OperatorSpec{Description: "Apache Kafka", Categories: []string{"messaging"}},
+		},
+	}
+
+	// the token "admin" may list everything, "viewer" only the instances of team-a
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor("create", "tokenreviews", func(action testcore.Action) (bool, runtime.Object, error) {
+		review := action.(testcore.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		switch review.Spec.Token {
+		case "admin", "viewer":
+			review.Status = authenticationv1.TokenReviewStatus{Authenticated: true, User: authenticationv1.UserInfo{Username: review.Spec.Token}}
+		}
+		return true, review, nil
+	})
+	kubeClient.PrependReactor("create", "subjectaccessreviews", func(action testcore.Action) (bool, runtime.Object, error) {
+		review := action.(testcore.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		review.Status.Allowed = attrs.Verb == "list" && attrs.Group == "kudo.dev" &&
+			(review.Spec.User == "admin" || attrs.Resource == "instances" && attrs.Namespace == "team-a")
+		return true, review, nil
+	})
+	handler := (&Server{Client: fake.NewFakeClientWithScheme(s, objs...), KubeClient: kubeClient}).Handler()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{"operators", http.MethodGet, OperatorsPath, "admin", http.StatusOK,
+			`{"items":[{"name":"kafka","namespace":"kudo-catalog","description":"Apache Kafka","categories":["messaging"],"versions":["1.2.0"]}]}`},
+		{"operatorversions without parameters", http.MethodGet, OperatorVersionsPath + "?namespace=kudo-catalog", "admin", http.StatusOK,
+			`{"items":[{"name":"kafka-1.2.0","namespace":"kudo-catalog","operator":"kafka","version":"1.2.0","appVersion":"2.4.0","plans":["deploy","update"]}]}`},
+		{"instances of a namespace", http.MethodGet, InstancesPath + "?namespace=team-a", "viewer", http.StatusOK,
+			`{"items":[{"name":"kafka","namespace":"team-a","operator":"kafka","operatorVersion":"kafka-1.2.0","operatorVersionNamespace":"kudo-catalog","status":"IN_PROGRESS","activePlan":"update","plans":[{"name":"deploy","status":"COMPLETE","lastFinishedRun":"2020-01-01T00:00:00Z"},{"name":"update","status":"IN_PROGRESS"}]}]}`},
+		{"empty namespace", http.MethodGet, InstancesPath + "?namespace=empty", "admin", http.StatusOK, `{"items":[]}`},
+		{"missing token", http.MethodGet, InstancesPath, "", http.StatusUnauthorized, `{"error":"a bearer token is required"}`},
+		{"invalid token", http.MethodGet, InstancesPath, "invalid", http.StatusUnauthorized, `{"error":"the bearer token is invalid"}`},
+		{"other namespace", http.MethodGet, InstancesPath + "?namespace=team-b", "viewer", http.StatusForbidden,
+			`{"error":"user viewer can not list instances in namespace team-b"}`},
+		{"all namespaces", http.MethodGet, InstancesPath, "viewer", http.StatusForbidden,
+			`{"error":"user viewer can not list instances in all namespaces"}`},
+		{"write", http.MethodPost, InstancesPath, "admin", http.StatusMethodNotAllowed, `{"error":"method POST is not allowed"}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d but got %d", tt.name, tt.status, rec.Code)
+		}
+		if body := strings.TrimSpace(rec.Body.String()); body != tt.body {
+			t.Errorf("%s: expected body\n%s\nbut got\n%s", tt.name, tt.body, body)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// kudoConfig returns the spec of the KudoConfig, the defaults are used if it does not exist
+func kudoConfig(c client.Reader) (kudov1alpha1.KudoConfigSpec, error) {
+	config := &kudov1alpha1.KudoConfig{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: kudov1alpha1.KudoConfigName}, config)
+	switch {
+	case apierrors.IsNotFound(err) || meta.IsNoMatchError(err):
+		return kudov1alpha1.KudoConfigSpec{}, nil
+	case err != nil:
+		return kudov1alpha1.KudoConfigSpec{}, fmt.Errorf("failed to read KudoConfig %s: %w", kudov1alpha1.KudoConfigName, err)
+	}
+	return config.Spec, nil
+}
+
+// setPropagation adds the labels and annotations of the instance that the KudoConfig propagates to its objects, the
+// image settings of the instance and the post-render stage to the metadata of the execution
+func setPropagation(metadata *task.EngineMetadata, instance *kudov1alpha1.Instance, c client.Reader) error {
+	config, err := kudoConfig(c)
+	if err != nil {
+		return err
+	}
+	metadata.PropagatedLabels = config.LabelPropagation.PropagatedLabels(instance)
+	metadata.PropagatedAnnotations = config.LabelPropagation.PropagatedAnnotations(instance)
+	metadata.Images = config.Images.ForInstance(instance)
+	metadata.PostRenderers = task.PostRenderers(config.PostRender)
+	return nil
+}
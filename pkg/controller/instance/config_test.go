@@ -0,0 +1,55 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSetPropagation(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	i := instance()
+	i.Labels = map[string]string{"team": "data", "kudo.dev/operator": "kafka"}
+	i.Annotations = map[string]string{"cost-center": "42"}
+
+	metadata := &task.EngineMetadata{}
+	assert.NoError(t, setPropagation(metadata, i, fake.NewFakeClientWithScheme(s)))
+	assert.Nil(t, metadata.PropagatedLabels, "nothing is propagated without a KudoConfig")
+
+	config := &v1alpha1.KudoConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.KudoConfigName},
+		Spec: v1alpha1.KudoConfigSpec{LabelPropagation: v1alpha1.LabelPropagationPolicy{
+			Labels:      []string{"team", "cost-center"},
+			Annotations: []string{"cost-center"},
+		}},
+	}
+	assert.NoError(t, setPropagation(metadata, i, fake.NewFakeClientWithScheme(s, config)))
+	assert.Equal(t, map[string]string{"team": "data"}, metadata.PropagatedLabels)
+	assert.Equal(t, map[string]string{"cost-center": "42"}, metadata.PropagatedAnnotations)
+}
+
+func TestSetPropagation_Images(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	config := &v1alpha1.KudoConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.KudoConfigName},
+		Spec:       v1alpha1.KudoConfigSpec{Images: v1alpha1.ImageSettings{Registry: "mirror.local", PullSecrets: []string{"mirror"}}},
+	}
+	i := instance()
+	i.Annotations = map[string]string{v1alpha1.ImageRegistryAnnotation: "team.registry"}
+
+	metadata := &task.EngineMetadata{}
+	assert.NoError(t, setPropagation(metadata, i, fake.NewFakeClientWithScheme(s, config)))
+	assert.Equal(t, v1alpha1.ImageSettings{Registry: "team.registry", PullSecrets: []string{"mirror"}}, metadata.Images)
+}
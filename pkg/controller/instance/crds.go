@@ -0,0 +1,120 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Masterminds/semver"
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// applyCRDs creates or updates the CRDs bundled with the operator version before a plan starts. CRDs are cluster
+// scoped and shared by all instances of the operator, which is why they are handled carefully:
+// - a CRD is owned by the operator that created it, CRDs of other operators or created without KUDO are a conflict
+// - a CRD is never deleted, neither here nor when the instance is removed
+// - a CRD applied by a newer version of the operator is not downgraded
+// - an update may not remove versions that objects are stored in
+func applyCRDs(c client.Client, ov *v1alpha1.OperatorVersion) error {
+	names := make([]string, 0, len(ov.Spec.CRDs))
+	for name := range ov.Spec.CRDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		crd := &apiextv1beta1.CustomResourceDefinition{}
+		if err := yaml.Unmarshal([]byte(ov.Spec.CRDs[name]), crd); err != nil {
+			return &ExecutionError{Err: fmt.Errorf("crd %s of operator version %s is invalid: %v", name, ov.Name, err), Fatal: true, EventName: kudo.String("InvalidCRD")}
+		}
+		if err := applyCRD(c, crd, ov); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyCRD(c client.Client, crd *apiextv1beta1.CustomResourceDefinition, ov *v1alpha1.OperatorVersion) error {
+	operator := ov.Spec.Operator.Name
+	if crd.Labels == nil {
+		crd.Labels = map[string]string{}
+	}
+	crd.Labels[kudo.HeritageLabel] = "kudo"
+	crd.Labels[kudo.OperatorLabel] = operator
+	if crd.Annotations == nil {
+		crd.Annotations = map[string]string{}
+	}
+	crd.Annotations[kudo.OperatorVersionAnnotation] = ov.Spec.Version
+
+	existing := &apiextv1beta1.CustomResourceDefinition{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: crd.Name}, existing)
+	if apierrors.IsNotFound(err) {
+		log.Printf("InstanceController: Creating CRD %s of operator %s", crd.Name, operator)
+		return c.Create(context.TODO(), crd)
+	}
+	if err != nil {
+		return err
+	}
+
+	if owner := existing.Labels[kudo.OperatorLabel]; owner != operator {
+		if owner == "" {
+			owner = "a different source than KUDO"
+		} else {
+			owner = fmt.Sprintf("operator %s", owner)
+		}
+		return &ExecutionError{Err: fmt.Errorf("crd %s already exists and is owned by %s", crd.Name, owner), Fatal: true, EventName: kudo.String("CRDConflict")}
+	}
+	if isNewerVersion(existing.Annotations[kudo.OperatorVersionAnnotation], ov.Spec.Version) {
+		log.Printf("InstanceController: Not updating CRD %s, it was applied by the newer version %s of operator %s", crd.Name, existing.Annotations[kudo.OperatorVersionAnnotation], operator)
+		return nil
+	}
+	if equality.Semantic.DeepDerivative(crd.Spec, existing.Spec) &&
+		existing.Annotations[kudo.OperatorVersionAnnotation] == ov.Spec.Version {
+		return nil
+	}
+
+	served := map[string]bool{crd.Spec.Version: true}
+	for _, v := range crd.Spec.Versions {
+		served[v.Name] = true
+	}
+	for _, stored := range existing.Status.StoredVersions {
+		if !served[stored] {
+			return &ExecutionError{Err: fmt.Errorf("crd %s can not be updated as it would remove version %s that objects are stored in", crd.Name, stored), Fatal: true, EventName: kudo.String("CRDConflict")}
+		}
+	}
+
+	log.Printf("InstanceController: Updating CRD %s of operator %s to version %s", crd.Name, operator, ov.Spec.Version)
+	existing.Spec = crd.Spec
+	for k, v := range crd.Labels {
+		existing.Labels[k] = v
+	}
+	if existing.Annotations == nil {
+		existing.Annotations = map[string]string{}
+	}
+	for k, v := range crd.Annotations {
+		existing.Annotations[k] = v
+	}
+	return c.Update(context.TODO(), existing)
+}
+
+// isNewerVersion returns whether applied is a newer semantic version than version. Versions that can not be parsed
+// are never newer, so the CRD is updated.
+func isNewerVersion(applied, version string) bool {
+	a, err := semver.NewVersion(applied)
+	if err != nil {
+		return false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return a.GreaterThan(v)
+}
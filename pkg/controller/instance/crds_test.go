@@ -0,0 +1,94 @@
+package instance
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const topicsCRD = `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: topics.kafka.example.com
+spec:
+  group: kafka.example.com
+  names:
+    kind: Topic
+    plural: topics
+  scope: Namespaced
+  version: v2
+`
+
+func crdOperatorVersion(operator, version string) *v1alpha1.OperatorVersion {
+	return &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: operator + "-" + version, Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Operator: corev1.ObjectReference{Name: operator},
+			Version:  version,
+			CRDs:     map[string]string{"topics.yaml": topicsCRD},
+		},
+	}
+}
+
+func existingCRD(operator, version string, storedVersions ...string) *apiextv1beta1.CustomResourceDefinition {
+	crd := &apiextv1beta1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "topics.kafka.example.com"},
+		Spec:       apiextv1beta1.CustomResourceDefinitionSpec{Group: "kafka.example.com", Version: "v1"},
+		Status:     apiextv1beta1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+	if operator != "" {
+		crd.Labels = map[string]string{kudo.OperatorLabel: operator}
+		crd.Annotations = map[string]string{kudo.OperatorVersionAnnotation: version}
+	}
+	return crd
+}
+
+func TestApplyCRDs(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := apiextv1beta1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		existing []runtime.Object
+		ov       *v1alpha1.OperatorVersion
+		fatal    bool
+		version  string // spec.version of the CRD afterwards
+	}{
+		{"create", nil, crdOperatorVersion("kafka", "1.0.0"), false, "v2"},
+		{"update", []runtime.Object{existingCRD("kafka", "0.9.0")}, crdOperatorVersion("kafka", "1.0.0"), false, "v2"},
+		{"no downgrade", []runtime.Object{existingCRD("kafka", "1.1.0")}, crdOperatorVersion("kafka", "1.0.0"), false, "v1"},
+		{"stored version", []runtime.Object{existingCRD("kafka", "0.9.0", "v1")}, crdOperatorVersion("kafka", "1.0.0"), true, "v1"},
+		{"other operator", []runtime.Object{existingCRD("zookeeper", "0.9.0")}, crdOperatorVersion("kafka", "1.0.0"), true, "v1"},
+		{"not managed by kudo", []runtime.Object{existingCRD("", "")}, crdOperatorVersion("kafka", "1.0.0"), true, "v1"},
+	}
+	for _, tt := range tests {
+		c := fake.NewFakeClientWithScheme(s, tt.existing...)
+		err := applyCRDs(c, tt.ov)
+		if tt.fatal {
+			exErr, ok := err.(*ExecutionError)
+			assert.True(t, ok && exErr.Fatal, "%s: expected a fatal error but got %v", tt.name, err)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+
+		crd := &apiextv1beta1.CustomResourceDefinition{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: "topics.kafka.example.com"}, crd); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		assert.Equal(t, tt.version, crd.Spec.Version, tt.name)
+		if !tt.fatal {
+			assert.Equal(t, "kafka", crd.Labels[kudo.OperatorLabel], tt.name)
+		}
+	}
+}
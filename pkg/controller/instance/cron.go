@@ -0,0 +1,164 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
+	"github.com/kudobuilder/kudo/pkg/util/cron"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PlanCron triggers the scheduled plans of all instances. Every interval it marks the schedules that became due as
+// pending in the instance status and the instance controller starts their plans like any other plan. Activations
+// missed e.g. while the manager was down are executed once, activations before a schedule was added are not.
+// It implements manager.Runnable and only runs on the leader.
+type PlanCron struct {
+	client.Client
+	Recorder record.EventRecorder
+	// Interval is the time between two checks of the schedules, it limits their precision
+	Interval time.Duration
+	// Locks serializes the changes of an instance, it has to be the one of the instance controller. Instances are not
+	// locked if nil.
+	Locks *queue.KeyedLock
+}
+
+// Start checks the schedules every interval until the stop channel is closed
+func (c *PlanCron) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			c.triggerAll(time.Now().UTC())
+		}
+	}
+}
+
+func (c *PlanCron) triggerAll(now time.Time) {
+	instances := &kudov1alpha1.InstanceList{}
+	if err := c.List(context.TODO(), instances); err != nil {
+		log.Printf("PlanCron: Error listing instances: %v", err)
+		return
+	}
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+		if c.Locks != nil {
+			// reconciles only hold the lock briefly, so the schedules wait for it instead of being delayed
+			c.Locks.Lock(key)
+		}
+		err := c.trigger(instance, now)
+		if c.Locks != nil {
+			c.Locks.Unlock(key)
+		}
+		if err != nil {
+			log.Printf("PlanCron: Error triggering scheduled plans of instance %s/%s: %v", instance.Namespace, instance.Name, err)
+		}
+	}
+}
+
+// trigger marks the schedules of the instance that became due since their last activation as pending. If another
+// plan is in progress, the ConcurrencyPolicy of the schedule decides whether the activation is skipped or the plan in
+// progress is stopped.
+func (c *PlanCron) trigger(instance *kudov1alpha1.Instance, now time.Time) error {
+	changed := false
+	for _, s := range instance.Spec.Schedules {
+		status := instance.Status.Schedules[s.Name]
+
+		schedule, err := cron.Parse(s.Schedule)
+		if err != nil {
+			message := fmt.Sprintf("Invalid schedule: %v", err)
+			if status.Message != message {
+				c.Recorder.Event(instance, "Warning", "InvalidSchedule", fmt.Sprintf("Schedule %s is invalid: %v", s.Name, err))
+				status.Message = message
+				setScheduleStatus(instance, s.Name, status)
+				changed = true
+			}
+			continue
+		}
+
+		if status.LastScheduleTime.IsZero() {
+			// a new schedule only triggers activations from now on
+			status.LastScheduleTime = metav1.NewTime(now)
+			setScheduleStatus(instance, s.Name, status)
+			changed = true
+			continue
+		}
+		due := lastActivation(schedule, status.LastScheduleTime.Time, now)
+		if due.IsZero() {
+			continue
+		}
+		status.LastScheduleTime = metav1.NewTime(due)
+		changed = true
+
+		running := instance.GetPlanInProgress()
+		switch {
+		case status.Pending:
+			// the plan of a previous activation did not start yet, it covers this activation as well
+		case running != nil && s.ConcurrencyPolicy != kudov1alpha1.ReplaceConcurrent:
+			status.Message = fmt.Sprintf("Skipped as plan %s was in progress", running.Name)
+			c.Recorder.Event(instance, "Normal", "ScheduledPlanSkipped", fmt.Sprintf("Schedule %s skipped plan %s as plan %s is in progress", s.Name, s.Plan, running.Name))
+		default:
+			if running != nil {
+				stopPlan(instance, running, fmt.Sprintf("replaced by plan %s of schedule %s", s.Plan, s.Name))
+				c.Recorder.Event(instance, "Normal", "PlanReplaced", fmt.Sprintf("Plan %s was stopped to execute plan %s of schedule %s", running.Name, s.Plan, s.Name))
+			}
+			status.Pending = true
+			status.Message = ""
+			c.Recorder.Event(instance, "Normal", "ScheduledPlanTriggered", fmt.Sprintf("Schedule %s triggered plan %s", s.Name, s.Plan))
+		}
+		setScheduleStatus(instance, s.Name, status)
+	}
+
+	if !changed {
+		return nil
+	}
+	return c.Status().Update(context.TODO(), instance)
+}
+
+// lastActivation returns the last activation of the schedule after since and not after now, or the zero time if the
+// schedule was not due in between
+func lastActivation(schedule *cron.Schedule, since, now time.Time) time.Time {
+	var last time.Time
+	for next := schedule.Next(since); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		last = next
+	}
+	return last
+}
+
+func setScheduleStatus(instance *kudov1alpha1.Instance, name string, status kudov1alpha1.ScheduleStatus) {
+	if instance.Status.Schedules == nil {
+		instance.Status.Schedules = map[string]kudov1alpha1.ScheduleStatus{}
+	}
+	instance.Status.Schedules[name] = status
+}
+
+// stopPlan ends the execution of the plan with a fatal error, the steps that were not executed yet are not started
+func stopPlan(instance *kudov1alpha1.Instance, planStatus *kudov1alpha1.PlanStatus, reason string) {
+	planStatus.Status = kudov1alpha1.ExecutionFatalError
+	planStatus.Message = reason
+	for i, ph := range planStatus.Phases {
+		if !ph.Status.IsRunning() {
+			continue
+		}
+		planStatus.Phases[i].Status = kudov1alpha1.ExecutionFatalError
+		planStatus.Phases[i].Message = reason
+		for j, st := range ph.Steps {
+			if st.Status.IsRunning() {
+				planStatus.Phases[i].Steps[j].Status = kudov1alpha1.ExecutionFatalError
+				planStatus.Phases[i].Steps[j].Message = reason
+			}
+		}
+	}
+	instance.UpdateInstanceStatus(planStatus)
+}
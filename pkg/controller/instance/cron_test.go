@@ -0,0 +1,79 @@
+package instance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPlanCron_Trigger(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	added := time.Date(2019, 12, 1, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		policy  v1alpha1.ConcurrencyPolicy
+		running bool
+		now     time.Time
+		pending bool
+		stopped bool
+	}{
+		{"not due", "", false, added.Add(30 * time.Minute), false, false},
+		{"due", "", false, added.Add(90 * time.Minute), true, false},
+		{"missed activations", "", false, added.Add(72 * time.Hour), true, false},
+		{"forbid", v1alpha1.ForbidConcurrent, true, added.Add(90 * time.Minute), false, false},
+		{"replace", v1alpha1.ReplaceConcurrent, true, added.Add(90 * time.Minute), true, true},
+	}
+	for _, tt := range tests {
+		i := instance()
+		i.Spec.Schedules = []v1alpha1.PlanSchedule{{Name: "nightly", Plan: "backup", Schedule: "0 2 * * *", ConcurrencyPolicy: tt.policy}}
+		i.Status.Schedules = map[string]v1alpha1.ScheduleStatus{"nightly": {LastScheduleTime: metav1.NewTime(added)}}
+		if tt.running {
+			i.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionInProgress}}
+		}
+		c := &PlanCron{Client: fake.NewFakeClientWithScheme(s, i), Recorder: record.NewFakeRecorder(10)}
+
+		if err := c.trigger(i, tt.now); err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.name, err)
+		}
+		status := i.Status.Schedules["nightly"]
+		if status.Pending != tt.pending {
+			t.Errorf("%s: expected pending to be %v but got %+v", tt.name, tt.pending, status)
+		}
+		if tt.pending && status.LastScheduleTime.Hour() != 2 {
+			t.Errorf("%s: expected the last activation at 2:00 but got %v", tt.name, status.LastScheduleTime)
+		}
+		if stopped := tt.running && i.GetPlanInProgress() == nil; stopped != tt.stopped {
+			t.Errorf("%s: expected plan in progress to be stopped %v but got %+v", tt.name, tt.stopped, i.Status.PlanStatus)
+		}
+	}
+}
+
+func TestPlanCron_NewSchedule(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	i := instance()
+	i.Spec.Schedules = []v1alpha1.PlanSchedule{{Name: "nightly", Plan: "backup", Schedule: "0 2 * * *"}, {Name: "invalid", Plan: "backup", Schedule: "0 25 * * *"}}
+	c := &PlanCron{Client: fake.NewFakeClientWithScheme(s, i), Recorder: record.NewFakeRecorder(10)}
+
+	now := time.Date(2019, 12, 1, 3, 0, 0, 0, time.UTC)
+	if err := c.trigger(i, now); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if status := i.Status.Schedules["nightly"]; status.Pending || !status.LastScheduleTime.Time.Equal(now) {
+		t.Errorf("expected a new schedule to start at %v but got %+v", now, status)
+	}
+	if status := i.Status.Schedules["invalid"]; status.Message == "" {
+		t.Errorf("expected the invalid schedule to be reported but got %+v", status)
+	}
+}
@@ -0,0 +1,179 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DriftDetector periodically compares the objects applied by the last executed plan of every instance with the live
+// objects and records the drift, i.e. fields changed out-of-band, in the instance status and as events. Drifted objects
+// are applied again if AutoCorrect is set. It implements manager.Runnable and only runs on the leader.
+type DriftDetector struct {
+	client.Client
+	Recorder record.EventRecorder
+	Scheme   *runtime.Scheme
+	// Interval is the time between two detections
+	Interval time.Duration
+	// AutoCorrect applies drifted objects again
+	AutoCorrect bool
+	// Keyring decrypts the encrypted values of sensitive parameters
+	Keyring *encryption.Keyring
+	// Config is the client configuration of the manager, the ServiceAccounts of instances are impersonated with it
+	Config *rest.Config
+	// Locks serializes the changes of an instance, it has to be the one of the instance controller. Instances are not
+	// locked if nil.
+	Locks *queue.KeyedLock
+
+	// targets connects to the clusters of the instances targeting a ClusterTarget
+	targets clusterTargets
+	// accounts connects impersonating the ServiceAccounts of instances
+	accounts serviceAccounts
+	// templates caches the parsed templates of the operatorversions
+	templates engine.Cache
+}
+
+// Start runs the drift detection every interval until the stop channel is closed
+func (d *DriftDetector) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			d.detectAll(time.Now())
+		}
+	}
+}
+
+func (d *DriftDetector) detectAll(now time.Time) {
+	instances := &kudov1alpha1.InstanceList{}
+	if err := d.List(context.TODO(), instances); err != nil {
+		log.Printf("DriftDetector: Error listing instances: %v", err)
+		return
+	}
+	for i := range instances.Items {
+		instance := &instances.Items[i]
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+		if d.Locks != nil {
+			// an instance that is being reconciled is checked in the next detection, its objects may just change
+			if !d.Locks.TryLock(key) {
+				continue
+			}
+		}
+		err := d.detect(instance, now)
+		if d.Locks != nil {
+			d.Locks.Unlock(key)
+		}
+		if err != nil {
+			log.Printf("DriftDetector: Error detecting drift of instance %s/%s: %v", instance.Namespace, instance.Name, err)
+		}
+	}
+}
+
+// detect records the drift of the objects applied by the last executed plan of the instance. Instances executing a
+// plan are skipped as their objects are expected to change, as are instances whose last plan did not complete, as
+// their objects do not reflect the desired state.
+func (d *DriftDetector) detect(instance *kudov1alpha1.Instance, now time.Time) error {
+	if instance.GetPlanInProgress() != nil {
+		return nil
+	}
+	planStatus := instance.GetLastExecutedPlanStatus()
+	if planStatus == nil || !planStatus.IsFinished() {
+		return nil
+	}
+
+	ov := &kudov1alpha1.OperatorVersion{}
+	key := types.NamespacedName{Name: instance.Spec.OperatorVersion.Name, Namespace: instance.OperatorVersionNamespace()}
+	if err := d.Get(context.TODO(), key, ov); err != nil {
+		return fmt.Errorf("failed to get operatorversion %s: %v", key, err)
+	}
+	plan, metadata, err := preparePlanExecution(instance, ov, planStatus, d.Keyring)
+	if err != nil {
+		return err
+	}
+	if err := setPropagation(metadata, instance, d.Client); err != nil {
+		return err
+	}
+	plan.parsed = d.templates.Templates(string(ov.UID), ov.ResourceVersion)
+
+	targetClient, _, err := resourceTarget(instance, d.Client, nil, d.Config, d.Scheme, &d.targets, &d.accounts)
+	if err != nil {
+		return err
+	}
+
+	checkedAt := metav1.NewTime(now)
+	drift := &kudov1alpha1.DriftStatus{CheckedAt: checkedAt, Plan: plan.name}
+	for _, ph := range plan.spec.Phases {
+		for _, st := range ph.Steps {
+			for _, tn := range st.Tasks {
+				t, ok := plan.taskByName(tn)
+				if !ok {
+					return fmt.Errorf("failed to find task %s for operator version %s", tn, ov.Name)
+				}
+				tasker, err := task.Build(t)
+				if err != nil {
+					return err
+				}
+				apply, ok := tasker.(task.ApplyTask)
+				if !ok {
+					continue
+				}
+				ctx := task.Context{
+					Client:       d.Client,
+					TargetClient: targetClient,
+					Enhancer:     &task.KustomizeEnhancer{Scheme: d.Scheme},
+					Meta: task.ExecutionMetadata{
+						EngineMetadata: *metadata,
+						PlanName:       plan.name,
+						PhaseName:      ph.Name,
+						StepName:       st.Name,
+						TaskName:       tn,
+					},
+					Templates:            plan.templates,
+					Parameters:           plan.params,
+					ParameterDefinitions: plan.paramDefs,
+					ParsedTemplates:      plan.parsed,
+				}
+				resources, err := apply.Drift(ctx, d.AutoCorrect, checkedAt)
+				if err != nil {
+					return fmt.Errorf("failed to detect drift of task %s: %v", tn, err)
+				}
+				drift.Resources = append(drift.Resources, resources...)
+			}
+		}
+	}
+
+	if drift.Drifted() {
+		d.Recorder.Event(instance, "Warning", "DriftDetected", fmt.Sprintf("Objects of plan %s were changed out-of-band: %s", drift.Plan, driftedObjects(drift)))
+		if d.AutoCorrect {
+			d.Recorder.Event(instance, "Normal", "DriftCorrected", fmt.Sprintf("Objects of plan %s were applied again", drift.Plan))
+		}
+	}
+	instance.Status.Drift = drift
+	return d.Status().Update(context.TODO(), instance)
+}
+
+func driftedObjects(drift *kudov1alpha1.DriftStatus) string {
+	objects := make([]string, 0, len(drift.Resources))
+	for _, r := range drift.Resources {
+		objects = append(objects, fmt.Sprintf("%s %s", r.Kind, r.Name))
+	}
+	return strings.Join(objects, ", ")
+}
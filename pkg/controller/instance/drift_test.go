@@ -0,0 +1,132 @@
+package instance
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestDriftDetector_Detect(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Templates: map[string]string{"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  foo: bar\n"},
+			Tasks: []v1alpha1.Task{{
+				Name: "config",
+				Kind: "Apply",
+				Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"config.yaml"}}},
+			}},
+			Plans: map[string]v1alpha1.Plan{"deploy": {Phases: []v1alpha1.Phase{{Name: "main", Steps: []v1alpha1.Step{{Name: "config", Tasks: []string{"config"}}}}}}},
+		},
+	}
+	instance := instance()
+	instance.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionInProgress}}
+
+	c := fake.NewFakeClientWithScheme(s, ov, instance)
+	recorder := record.NewFakeRecorder(10)
+	d := &DriftDetector{Client: c, Recorder: recorder, Scheme: s}
+	now := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+
+	// instances executing a plan are skipped
+	if err := d.detect(instance, now); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if instance.Status.Drift != nil {
+		t.Errorf("expected no drift detection for a running plan but got %+v", instance.Status.Drift)
+	}
+
+	instance.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete}}
+	if err := d.detect(instance, now); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	updated := &v1alpha1.Instance{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: instance.Name}, updated); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	drift := updated.Status.Drift
+	if !drift.Drifted() || drift.Plan != "deploy" || !drift.CheckedAt.Time.Equal(now) {
+		t.Fatalf("expected drift of plan deploy checked at %v but got %+v", now, drift)
+	}
+	if r := drift.Resources[0]; len(drift.Resources) != 1 || r.Kind != "ConfigMap" || r.Name != "test-instance-config" || !r.Missing {
+		t.Errorf("expected the deleted config map to be reported but got %+v", drift.Resources)
+	}
+
+	select {
+	case e := <-recorder.Events:
+		if !strings.Contains(e, "DriftDetected") || !strings.Contains(e, "ConfigMap test-instance-config") {
+			t.Errorf("unexpected event %q", e)
+		}
+	default:
+		t.Errorf("expected a drift event")
+	}
+}
+
+func TestDriftDetector_Locked(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{"deploy": {}},
+		},
+	}
+	instance := instance()
+	instance.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete}}
+	c := fake.NewFakeClientWithScheme(s, ov, instance)
+	locks := &queue.KeyedLock{}
+	d := &DriftDetector{Client: c, Recorder: record.NewFakeRecorder(10), Scheme: s, Locks: locks}
+	r := &Reconciler{Client: c, Recorder: record.NewFakeRecorder(10), Locks: locks}
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	driftOf := func() *v1alpha1.DriftStatus {
+		updated := &v1alpha1.Instance{}
+		if err := c.Get(context.TODO(), key, updated); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		return updated.Status.Drift
+	}
+
+	locks.Lock(key)
+	d.detectAll(time.Now())
+	if drift := driftOf(); drift != nil {
+		t.Errorf("expected a locked instance to be skipped but got %+v", drift)
+	}
+	result, err := r.Reconcile(reconcile.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter != lockedRetryInterval {
+		t.Errorf("expected the reconcile of a locked instance to be retried after %v but got %+v, %v", lockedRetryInterval, result, err)
+	}
+
+	locks.Unlock(key)
+	d.detectAll(time.Now())
+	if drift := driftOf(); drift == nil {
+		t.Errorf("expected the drift of the unlocked instance to be detected")
+	}
+	if !locks.TryLock(key) {
+		t.Errorf("expected the drift detector to unlock the instance")
+	}
+}
@@ -0,0 +1,89 @@
+package instance
+
+import (
+	"fmt"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	engtask "github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dryRunPlan executes the plan of the instance in dry-run mode and reports the changes it would make. The status of
+// the instance is not touched, except for the report. As nothing has to become healthy, all phases and steps are
+// executed in order regardless of their strategy. Tasks that can not be dry-run are skipped and the dry-run ends at
+// the first error. The parameters requested for the dry-run override the parameters of the instance.
+func dryRunPlan(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, planName string, c, target client.Client, enh engtask.KubernetesObjectEnhancer, keyring *encryption.Keyring, currentTime time.Time) *kudov1alpha1.DryRunStatus {
+	report := &kudov1alpha1.DryRunStatus{Plan: planName, CompletedAt: metav1.NewTime(currentTime)}
+
+	parameters, err := instance.GetDryRunParameters()
+	if err != nil {
+		report.Message = fmt.Sprintf("failed to prepare plan %s: %v", planName, err)
+		return report
+	}
+	instance = instance.DeepCopy()
+	instance.Spec.Parameters = parameters
+
+	pl, em, err := preparePlanExecution(instance, ov, &kudov1alpha1.PlanStatus{Name: planName}, keyring)
+	if err != nil {
+		report.Message = fmt.Sprintf("failed to prepare plan %s: %v", planName, err)
+		return report
+	}
+	if err := setPropagation(em, instance, c); err != nil {
+		report.Message = fmt.Sprintf("failed to prepare plan %s: %v", planName, err)
+		return report
+	}
+
+	skipped := map[string]bool{}
+	for _, ph := range pl.spec.Phases {
+		for _, st := range ph.Steps {
+			for _, tn := range st.Tasks {
+				t, ok := pl.taskByName(tn)
+				if !ok {
+					report.Message = fmt.Sprintf("failed to find task %s for operator version %s", tn, em.OperatorVersionName)
+					return report
+				}
+				task, err := engtask.Build(t)
+				if err != nil {
+					report.Message = fmt.Sprintf("failed to resolve task %s for operator version %s: %v", tn, em.OperatorVersionName, err)
+					return report
+				}
+				dryRunner, ok := task.(engtask.DryRunner)
+				if !ok {
+					if !skipped[tn] {
+						skipped[tn] = true
+						report.SkippedTasks = append(report.SkippedTasks, tn)
+					}
+					continue
+				}
+
+				ctx := engtask.Context{
+					Client:       c,
+					TargetClient: target,
+					Enhancer:     enh,
+					Meta: engtask.ExecutionMetadata{
+						EngineMetadata: *em,
+						PlanName:       pl.name,
+						PhaseName:      ph.Name,
+						StepName:       st.Name,
+						TaskName:       tn,
+					},
+					Templates:            pl.templates,
+					Parameters:           pl.params,
+					ParameterDefinitions: pl.paramDefs,
+					RecordChange: func(change kudov1alpha1.ResourceChange) {
+						report.Changes = append(report.Changes, change)
+					},
+				}
+				if err := dryRunner.DryRun(ctx); err != nil {
+					report.Message = fmt.Sprintf("task %s of step %s.%s failed: %v", tn, ph.Name, st.Name, err)
+					return report
+				}
+			}
+		}
+	}
+	return report
+}
@@ -0,0 +1,119 @@
+package instance
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRunPlan(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{"upgrade": {
+				Strategy: "serial",
+				Phases: []v1alpha1.Phase{
+					{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "app", Tasks: []string{"app", "migrate", "migrate"}}}},
+				},
+			}},
+			Tasks: []v1alpha1.Task{
+				{Name: "app", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"pod.yaml"}}}},
+				{Name: "migrate", Kind: "Dummy", Spec: v1alpha1.TaskSpec{DummyTaskSpec: v1alpha1.DummyTaskSpec{Done: true}}},
+			},
+			Templates: map[string]string{
+				"pod.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod\n  namespace: default\n",
+			},
+		},
+	}
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(s)
+
+	report := dryRunPlan(instance(), ov, "upgrade", c, nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if report.Message != "" {
+		t.Fatalf("expected no error but got %s", report.Message)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Action != v1alpha1.ResourceCreated || report.Changes[0].Step != "main.app" {
+		t.Errorf("expected the pod to be created by step main.app but got %+v", report.Changes)
+	}
+	if len(report.SkippedTasks) != 1 || report.SkippedTasks[0] != "migrate" {
+		t.Errorf("expected task migrate to be skipped once but got %v", report.SkippedTasks)
+	}
+	if err := c.Get(context.TODO(), client.ObjectKey{Name: "pod", Namespace: "default"}, &corev1.Pod{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the pod not to be created but got %v", err)
+	}
+
+	report = dryRunPlan(instance(), ov, "missing", c, nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if report.Message == "" {
+		t.Errorf("expected the dry-run of a missing plan to fail")
+	}
+}
+
+func TestDryRunPlan_Parameters(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{"update": {
+				Strategy: "serial",
+				Phases: []v1alpha1.Phase{
+					{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "config", Tasks: []string{"config"}}}},
+				},
+			}},
+			Tasks: []v1alpha1.Task{
+				{Name: "config", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"config.yaml"}}}},
+			},
+			Parameters: []v1alpha1.Parameter{{Name: "SIZE", Required: true}},
+			Templates: map[string]string{
+				"config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: default\ndata:\n  size: \"{{ .Params.SIZE }}\"\n",
+			},
+		},
+	}
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(s)
+
+	// the plan can not be rendered without the required parameter
+	report := dryRunPlan(instance(), ov, "update", c, nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if !strings.Contains(report.Message, "parameters are missing when evaluating template: SIZE") {
+		t.Errorf("expected the dry-run to fail without parameter SIZE but got %q", report.Message)
+	}
+
+	i := instance()
+	i.Annotations = map[string]string{v1alpha1.DryRunParametersAnnotation: `{"SIZE": "3"}`}
+	report = dryRunPlan(i, ov, "update", c, nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if report.Message != "" {
+		t.Fatalf("expected the dry-run with parameter SIZE to succeed but got %s", report.Message)
+	}
+	if len(report.Changes) != 1 || report.Changes[0].Kind != "ConfigMap" {
+		t.Errorf("expected the config map to be created but got %+v", report.Changes)
+	}
+	if len(i.Spec.Parameters) != 0 {
+		t.Errorf("expected the parameters of the instance not to change but got %v", i.Spec.Parameters)
+	}
+
+	i.Annotations[v1alpha1.DryRunParametersAnnotation] = "SIZE=3"
+	report = dryRunPlan(i, ov, "update", c, nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if report.Message == "" {
+		t.Errorf("expected the dry-run with invalid parameters to fail")
+	}
+}
@@ -0,0 +1,175 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultExecutionRetention is the number of PlanExecutions kept for every instance by default
+const DefaultExecutionRetention = 20
+
+// logKinds are the kinds of the objects applied by steps whose logs are referenced by PlanExecutions
+var logKinds = map[string]bool{"Pod": true, "Job": true}
+
+// executionName returns the name of the PlanExecution recording the run of the plan started at the given time
+func executionName(instance, plan string, startedAt time.Time) string {
+	return kudo.HelperName(instance, plan, startedAt.UTC().Format("20060102-150405"), "", "")
+}
+
+// newPlanExecution returns the PlanExecution recording the run of the plan that was just started on the instance
+func newPlanExecution(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, planStatus *kudov1alpha1.PlanStatus, startedAt time.Time) *kudov1alpha1.PlanExecution {
+	parameters := make(map[string]string, len(instance.Spec.Parameters))
+	for k, v := range instance.Spec.Parameters {
+		parameters[k] = v
+	}
+	execution := &kudov1alpha1.PlanExecution{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      executionName(instance.Name, planStatus.Name, startedAt),
+			Namespace: instance.Namespace,
+			Labels: map[string]string{
+				kudo.HeritageLabel: "kudo",
+				kudo.OperatorLabel: ov.Spec.Operator.Name,
+				kudo.InstanceLabel: instance.Name,
+				kudo.PlanLabel:     planStatus.Name,
+			},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(instance, kudov1alpha1.SchemeGroupVersion.WithKind("Instance"))},
+		},
+		Spec: kudov1alpha1.PlanExecutionSpec{
+			Instance:           instance.Name,
+			InstanceGeneration: instance.Generation,
+			OperatorVersion:    ov.Name,
+			Plan:               planStatus.Name,
+			Parameters:         parameters,
+		},
+	}
+	execution.Status = executionStatus(planStatus, metav1.NewTime(startedAt), nil, startedAt)
+	return execution
+}
+
+// executionStatus returns the status of a PlanExecution reflecting the status of its plan. The finish time is set once
+// when the plan reaches a terminal status.
+func executionStatus(planStatus *kudov1alpha1.PlanStatus, startedAt metav1.Time, finishedAt *metav1.Time, now time.Time) kudov1alpha1.PlanExecutionStatus {
+	status := kudov1alpha1.PlanExecutionStatus{
+		Status:     planStatus.Status,
+		Message:    planStatus.Message,
+		StartedAt:  startedAt,
+		FinishedAt: finishedAt,
+	}
+	if status.FinishedAt == nil && planStatus.Status.IsTerminal() {
+		finished := metav1.NewTime(now)
+		status.FinishedAt = &finished
+	}
+	for _, ph := range planStatus.Phases {
+		status.Phases = append(status.Phases, *ph.DeepCopy())
+		for _, st := range ph.Steps {
+			for _, r := range st.Resources {
+				if logKinds[r.Kind] {
+					status.Logs = append(status.Logs, kudov1alpha1.LogReference{Phase: ph.Name, Step: st.Name, Kind: r.Kind, Namespace: r.Namespace, Name: r.Name})
+				}
+			}
+		}
+	}
+	return status
+}
+
+// startExecution records the run of the plan that was just started on the instance in a new PlanExecution and prunes
+// the oldest PlanExecutions of the instance beyond the retention. Recording is best effort, the plan is executed even
+// if its run can not be recorded.
+func (r *Reconciler) startExecution(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, plan string, now time.Time) {
+	planStatus, ok := instance.Status.PlanStatus[plan]
+	if !ok {
+		return
+	}
+	// the execution of the previous run must not be updated with the status of this run
+	planStatus.Execution = ""
+	instance.Status.PlanStatus[plan] = planStatus
+	if r.ExecutionRetention <= 0 {
+		return
+	}
+
+	execution := newPlanExecution(instance, ov, &planStatus, now)
+	if err := r.Client.Create(context.TODO(), execution); err != nil {
+		log.Printf("InstanceController: Error recording execution of plan %s on instance %s/%s: %v", plan, instance.Namespace, instance.Name, err)
+		r.Recorder.Event(instance, "Warning", "PlanExecutionNotRecorded", fmt.Sprintf("Execution of plan %s could not be recorded: %v", plan, err))
+	} else {
+		planStatus.Execution = execution.Name
+	}
+	instance.Status.PlanStatus[plan] = planStatus
+
+	if err := pruneExecutions(r.Client, instance, execution.Name, r.ExecutionRetention); err != nil {
+		log.Printf("InstanceController: Error pruning plan executions of instance %s/%s: %v", instance.Namespace, instance.Name, err)
+	}
+}
+
+// recordExecutions updates the PlanExecutions of the last runs of the plans of the instance with the status of the
+// plans. The active plan is reset once it finished, so the PlanExecutions of all plans are compared with their status.
+func (r *Reconciler) recordExecutions(instance *kudov1alpha1.Instance, now time.Time) {
+	for _, planStatus := range instance.Status.PlanStatus {
+		planStatus := planStatus
+		if planStatus.Execution == "" {
+			continue
+		}
+
+		execution := &kudov1alpha1.PlanExecution{}
+		if err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: planStatus.Execution}, execution); err != nil {
+			if !apierrors.IsNotFound(err) {
+				log.Printf("InstanceController: Error getting plan execution %s/%s: %v", instance.Namespace, planStatus.Execution, err)
+			}
+			continue
+		}
+		status := executionStatus(&planStatus, execution.Status.StartedAt, execution.Status.FinishedAt, now)
+		if equality.Semantic.DeepEqual(execution.Status, status) {
+			continue
+		}
+		execution.Status = status
+		if err := r.Client.Update(context.TODO(), execution); err != nil {
+			log.Printf("InstanceController: Error updating plan execution %s/%s: %v", instance.Namespace, execution.Name, err)
+		}
+	}
+}
+
+// pruneExecutions deletes the oldest PlanExecutions of the instance, so that only the given number of them is kept
+// including the current one
+func pruneExecutions(c client.Client, instance *kudov1alpha1.Instance, current string, retention int) error {
+	executions := &kudov1alpha1.PlanExecutionList{}
+	if err := c.List(context.TODO(), executions, client.InNamespace(instance.Namespace), client.MatchingLabels{kudo.InstanceLabel: instance.Name}); err != nil {
+		return err
+	}
+
+	// the current execution may not be listed yet, it is always kept
+	var older []kudov1alpha1.PlanExecution
+	for _, e := range executions.Items {
+		if e.Name != current {
+			older = append(older, e)
+		}
+	}
+	if len(older) < retention {
+		return nil
+	}
+	sort.Slice(older, func(i, j int) bool {
+		if older[i].Status.StartedAt.Equal(&older[j].Status.StartedAt) {
+			return older[i].Name > older[j].Name
+		}
+		return older[j].Status.StartedAt.Before(&older[i].Status.StartedAt)
+	})
+	for i := range older[retention-1:] {
+		e := &older[retention-1+i]
+		if err := c.Delete(context.TODO(), e); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		log.Printf("InstanceController: Pruned plan execution %s/%s", e.Namespace, e.Name)
+	}
+	return nil
+}
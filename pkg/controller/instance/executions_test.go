@@ -0,0 +1,114 @@
+package instance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconciler_RecordExecutions(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.0.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Operator: corev1.ObjectReference{Name: "kafka"},
+			Plans: map[string]v1alpha1.Plan{"deploy": {Phases: []v1alpha1.Phase{{Name: "main", Steps: []v1alpha1.Step{{Name: "job"}}}}}},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default", UID: "1234", Generation: 3},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: corev1.ObjectReference{Name: "kafka-1.0.0"},
+			Parameters:      map[string]string{"BROKERS": "3"},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(s, instance)
+	r := &Reconciler{Client: c, Recorder: record.NewFakeRecorder(10), ExecutionRetention: 2}
+
+	started := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for run := 0; run < 3; run++ {
+		now := started.Add(time.Duration(run) * time.Minute)
+		assert.NoError(t, instance.StartPlanExecution("deploy", ov))
+		r.startExecution(instance, ov, "deploy", now)
+
+		planStatus := instance.Status.PlanStatus["deploy"]
+		assert.Equal(t, executionName("kafka", "deploy", now), planStatus.Execution)
+
+		planStatus.Status = v1alpha1.ExecutionComplete
+		planStatus.Phases[0].Status = v1alpha1.ExecutionComplete
+		planStatus.Phases[0].Steps[0].Resources = []v1alpha1.ResourceStatus{
+			{Kind: "Job", Namespace: "default", Name: "migrate"},
+			{Kind: "Service", Namespace: "default", Name: "kafka"},
+		}
+		instance.UpdateInstanceStatus(&planStatus)
+		r.recordExecutions(instance, now.Add(time.Second))
+	}
+
+	// only the last two runs are retained
+	executions := &v1alpha1.PlanExecutionList{}
+	assert.NoError(t, c.List(context.TODO(), executions, client.MatchingLabels{kudo.InstanceLabel: "kafka"}))
+	names := []string{}
+	for _, e := range executions.Items {
+		names = append(names, e.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		executionName("kafka", "deploy", started.Add(time.Minute)),
+		executionName("kafka", "deploy", started.Add(2*time.Minute)),
+	}, names)
+
+	last := executions.Items[0]
+	if last.Name != instance.Status.PlanStatus["deploy"].Execution {
+		last = executions.Items[1]
+	}
+	assert.Equal(t, v1alpha1.PlanExecutionSpec{
+		Instance:           "kafka",
+		InstanceGeneration: 3,
+		OperatorVersion:    "kafka-1.0.0",
+		Plan:               "deploy",
+		Parameters:         map[string]string{"BROKERS": "3"},
+	}, last.Spec)
+	assert.Equal(t, "kafka", last.Labels[kudo.OperatorLabel])
+	assert.Equal(t, "deploy", last.Labels[kudo.PlanLabel])
+	assert.Equal(t, "1234", string(last.OwnerReferences[0].UID))
+	assert.Equal(t, v1alpha1.ExecutionComplete, last.Status.Status)
+	assert.True(t, last.Status.StartedAt.Time.Equal(started.Add(2*time.Minute)))
+	if assert.NotNil(t, last.Status.FinishedAt) {
+		assert.True(t, last.Status.FinishedAt.Time.Equal(started.Add(2*time.Minute+time.Second)))
+	}
+	assert.Equal(t, []v1alpha1.LogReference{{Phase: "main", Step: "job", Kind: "Job", Namespace: "default", Name: "migrate"}}, last.Status.Logs)
+}
+
+func TestReconciler_RecordExecutionsDisabled(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Status: v1alpha1.InstanceStatus{PlanStatus: map[string]v1alpha1.PlanStatus{
+			"deploy": {Name: "deploy", Status: v1alpha1.ExecutionPending, Execution: "kafka-deploy-previous"},
+		}},
+	}
+	c := fake.NewFakeClientWithScheme(s, instance)
+	r := &Reconciler{Client: c, Recorder: record.NewFakeRecorder(10)}
+
+	r.startExecution(instance, &v1alpha1.OperatorVersion{}, "deploy", time.Now())
+
+	assert.Equal(t, "", instance.Status.PlanStatus["deploy"].Execution, "the execution of the previous run must be unset")
+	executions := &v1alpha1.PlanExecutionList{}
+	assert.NoError(t, c.List(context.TODO(), executions))
+	assert.Empty(t, executions.Items)
+}
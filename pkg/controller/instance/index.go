@@ -0,0 +1,54 @@
+package instance
+
+import (
+	"fmt"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// OperatorVersionIndex is the cache index of instances by the namespace/name of their OperatorVersion
+	OperatorVersionIndex = "spec.operatorVersion"
+	// OperatorIndex is the cache index of instances by the value of their operator label
+	OperatorIndex = "metadata.labels.operator"
+)
+
+// addIndexes registers the indexes of instances with the cache of the manager, so instances related to an
+// OperatorVersion or an Operator are looked up in the cache instead of filtering a list of all instances
+func addIndexes(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(&kudov1alpha1.Instance{}, OperatorVersionIndex, indexByOperatorVersion); err != nil {
+		return fmt.Errorf("failed to add index %s: %w", OperatorVersionIndex, err)
+	}
+	if err := mgr.GetFieldIndexer().IndexField(&kudov1alpha1.Instance{}, OperatorIndex, indexByOperator); err != nil {
+		return fmt.Errorf("failed to add index %s: %w", OperatorIndex, err)
+	}
+	return nil
+}
+
+// operatorVersionKey is the value of the OperatorVersionIndex for the OperatorVersion with the given namespace and name
+func operatorVersionKey(namespace, name string) string {
+	return fmt.Sprintf("%s/%s", namespace, name)
+}
+
+func indexByOperatorVersion(obj runtime.Object) []string {
+	instance, ok := obj.(*kudov1alpha1.Instance)
+	if !ok || instance.Spec.OperatorVersion.Name == "" {
+		return nil
+	}
+	return []string{operatorVersionKey(instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name)}
+}
+
+func indexByOperator(obj runtime.Object) []string {
+	instance, ok := obj.(*kudov1alpha1.Instance)
+	if !ok {
+		return nil
+	}
+	if operator, ok := instance.Labels[kudo.OperatorLabel]; ok && operator != "" {
+		return []string{operator}
+	}
+	return nil
+}
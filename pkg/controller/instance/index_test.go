@@ -0,0 +1,47 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIndexes(t *testing.T) {
+	tests := []struct {
+		name             string
+		instance         *v1alpha1.Instance
+		operatorVersions []string
+		operators        []string
+	}{
+		{
+			name: "operatorversion in namespace of instance",
+			instance: &v1alpha1.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: map[string]string{kudo.OperatorLabel: "foo-operator"}},
+				Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "foo-operator-1.0"}},
+			},
+			operatorVersions: []string{"default/foo-operator-1.0"},
+			operators:        []string{"foo-operator"},
+		},
+		{
+			name: "operatorversion in other namespace",
+			instance: &v1alpha1.Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+				Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "foo-operator-1.0", Namespace: "kudo"}},
+			},
+			operatorVersions: []string{"kudo/foo-operator-1.0"},
+		},
+		{
+			name:     "no operatorversion",
+			instance: &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.operatorVersions, indexByOperatorVersion(tt.instance), tt.name)
+		assert.Equal(t, tt.operators, indexByOperator(tt.instance), tt.name)
+	}
+}
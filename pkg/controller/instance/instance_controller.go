@@ -23,15 +23,21 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
+	"github.com/kudobuilder/kudo/pkg/engine"
 	"github.com/kudobuilder/kudo/pkg/engine/task"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"k8s.io/apimachinery/pkg/runtime"
 
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
 	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 
 	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
@@ -43,41 +49,72 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
+// lockedRetryInterval is the interval at which the reconcile of an instance is retried while another component, e.g.
+// the drift detector, holds its lock
+const lockedRetryInterval = time.Second
+
 // Reconciler reconciles an Instance object.
 type Reconciler struct {
 	client.Client
 	Recorder record.EventRecorder
 	Scheme   *runtime.Scheme
+	Queue    queue.Options
+	// Executor runs the commands of Exec tasks in pods
+	Executor task.PodExecutor
+	// Config is the client configuration of the manager, the ServiceAccounts of instances are impersonated with it
+	Config *rest.Config
+	// Keyring decrypts the encrypted values of sensitive parameters, see encryption.Keyring
+	Keyring *encryption.Keyring
+	// ExecutionRetention is the number of PlanExecutions recording the runs of plans kept for every instance, runs are
+	// not recorded if it is 0
+	ExecutionRetention int
+	// Locks serializes the changes of an instance by the reconciler, the drift detector and the plan cron, they have to
+	// share it. A lock of its own is used if nil.
+	Locks *queue.KeyedLock
+
+	// scheduler limits the number of plans in progress, all plans are started right away without it
+	scheduler *planScheduler
+	// targets connects to the clusters of the instances targeting a ClusterTarget
+	targets clusterTargets
+	// accounts connects impersonating the ServiceAccounts of instances
+	accounts serviceAccounts
+	// templates caches the parsed templates of the operatorversions, they are parsed again once an operatorversion
+	// changes
+	templates engine.Cache
 }
 
 // SetupWithManager registers this reconciler with the controller manager
 func (r *Reconciler) SetupWithManager(
 	mgr ctrl.Manager) error {
+	if err := addIndexes(mgr); err != nil {
+		return err
+	}
+	r.scheduler = newPlanScheduler(mgr.GetClient())
+	if r.Locks == nil {
+		r.Locks = &queue.KeyedLock{}
+	}
+
 	addOvRelatedInstancesToReconcile := handler.ToRequestsFunc(
 		func(obj handler.MapObject) []reconcile.Request {
-			requests := make([]reconcile.Request, 0)
+			// only instances of the OperatorVersion are looked up in the index of the cache
 			instances := &kudov1alpha1.InstanceList{}
-			// we are listing all instances here, which could come with some performance penalty
-			// obj possible optimization is to introduce filtering based on operatorversion (or operator)
 			err := mgr.GetClient().List(
 				context.TODO(),
 				instances,
+				client.MatchingFields{OperatorVersionIndex: operatorVersionKey(obj.Meta.GetNamespace(), obj.Meta.GetName())},
 			)
 			if err != nil {
 				log.Printf("InstanceController: Error fetching instances list for operator %v: %v", obj.Meta.GetName(), err)
 				return nil
 			}
+			requests := make([]reconcile.Request, 0, len(instances.Items))
 			for _, instance := range instances.Items {
-				// we need to pick only those instances, that belong to the OperatorVersion we're reconciling
-				if instance.Spec.OperatorVersion.Name == obj.Meta.GetName() &&
-					instance.OperatorVersionNamespace() == obj.Meta.GetNamespace() {
-					requests = append(requests, reconcile.Request{
-						NamespacedName: types.NamespacedName{
-							Name:      instance.Name,
-							Namespace: instance.Namespace,
-						},
-					})
-				}
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{
+						Name:      instance.Name,
+						Namespace: instance.Namespace,
+					},
+				})
 			}
 			return requests
 		})
@@ -90,7 +127,8 @@ func (r *Reconciler) SetupWithManager(
 		Owns(&batchv1.Job{}).
 		Owns(&appsv1.StatefulSet{}).
 		Watches(&source.Kind{Type: &kudov1alpha1.OperatorVersion{}}, &handler.EnqueueRequestsFromMapFunc{ToRequests: addOvRelatedInstancesToReconcile}).
-		Complete(r)
+		WithOptions(r.Queue.ControllerOptions()).
+		Complete(queue.NewReconciler("InstanceController", r, r.Queue))
 }
 
 // Reconcile is the main controller method that gets called every time something about the instance changes
@@ -119,7 +157,20 @@ func (r *Reconciler) SetupWithManager(
 //   +-------------------------------+
 //
 // Automatically generate RBAC rules to allow the Controller to read and write Deployments
-func (r *Reconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
+func (r *Reconciler) Reconcile(request ctrl.Request) (_ ctrl.Result, err error) {
+	ctx, span := tracing.Start(context.Background(), "instance.Reconcile", attribute.String("kudo.instance", request.NamespacedName.String()))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	// the worker does not wait for an instance that is changed by another component, so that the other instances are
+	// still reconciled meanwhile
+	if r.Locks != nil {
+		if !r.Locks.TryLock(request.NamespacedName) {
+			log.Printf("InstanceController: Instance %s is locked, retrying in %v", request.NamespacedName, lockedRetryInterval)
+			return reconcile.Result{RequeueAfter: lockedRetryInterval}, nil
+		}
+		defer r.Locks.Unlock(request.NamespacedName)
+	}
+
 	// ---------- 1. Query the current state ----------
 
 	log.Printf("InstanceController: Received Reconcile request for instance \"%+v\"", request.Name)
@@ -127,16 +178,58 @@ func (r *Reconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
 	if err != nil {
 		if apierrors.IsNotFound(err) { // not retrying if instance not found, probably someone manually removed it?
 			log.Printf("Instances in namespace %s not found, not retrying reconcile since this error is usually not recoverable (without manual intervention).", request.NamespacedName)
+			r.scheduler.done(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		return reconcile.Result{}, err
 	}
 
+	if instance.DeletionTimestamp != nil {
+		r.scheduler.done(request.NamespacedName)
+		return reconcile.Result{}, r.finalize(instance)
+	}
+	if instance.Spec.ClusterTarget != "" && !hasFinalizer(instance, clusterTargetFinalizer) {
+		// the objects in the cluster of the ClusterTarget are not owned by the instance, they are deleted by finalize
+		instance.Finalizers = append(instance.Finalizers, clusterTargetFinalizer)
+		if err := r.Client.Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	ov, err := r.getOperatorVersion(instance)
 	if err != nil {
 		return reconcile.Result{}, err // OV not found has to be retried because it can really have been created after Instance
 	}
 
+	targetClient, executor, err := r.targetOf(instance)
+	if err != nil {
+		return reconcile.Result{}, r.handleError(err, instance)
+	}
+
+	// a requested dry-run is answered on its own, it does not change the objects or the plans of the instance
+	if plan, dryRunRequest := instance.GetPendingDryRun(); plan != "" {
+		log.Printf("InstanceController: Going to execute plan %s on instance %s/%s in dry-run mode", plan, instance.Namespace, instance.Name)
+		report := dryRunPlan(instance, ov, plan, r.Client, targetClient, &task.KustomizeEnhancer{Scheme: r.Scheme}, r.Keyring, time.Now())
+		report.Request = dryRunRequest
+		instance.Status.DryRun = report
+		r.Recorder.Event(instance, "Normal", "PlanDryRun", fmt.Sprintf("Plan %s was executed in dry-run mode", plan))
+		return reconcile.Result{}, r.updateInstance(instance)
+	}
+
+	// requested pre-upgrade checks are answered on their own as well, the instance is upgraded once they passed
+	if next, checkRequest := instance.GetPendingPreUpgradeCheck(); next != "" {
+		log.Printf("InstanceController: Going to execute the pre-upgrade checks of operatorversion %s on instance %s/%s", next, instance.Namespace, instance.Name)
+		report := preUpgradeCheck(instance, ov, next, r.Client, time.Now())
+		report.Request = checkRequest
+		instance.Status.PreUpgradeCheck = report
+		r.Recorder.Event(instance, "Normal", "PreUpgradeCheck", fmt.Sprintf("Pre-upgrade checks of operatorversion %s were executed, passed: %t", next, report.Passed()))
+		return reconcile.Result{}, r.updateInstance(instance)
+	}
+
+	// the spec changes of this generation are reflected in the plan status from now on
+	specObserved := instance.IsSpecObserved()
+	instance.Status.ObservedGeneration = instance.Generation
+
 	// ---------- 2. First check if we should start execution of new plan ----------
 
 	planToBeExecuted, err := instance.GetPlanToBeExecuted(ov)
@@ -144,11 +237,31 @@ func (r *Reconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
 		return reconcile.Result{}, err
 	}
 	if planToBeExecuted != nil {
+		admitted, reason, err := r.scheduler.admit(instance, ov.Spec.Operator.Name)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !admitted {
+			// nothing is persisted, the plan to be executed is determined again once the plan can start
+			log.Printf("InstanceController: Execution of plan %s on instance %s/%s is queued: %s", kudo.StringValue(planToBeExecuted), instance.Namespace, instance.Name, reason)
+			r.Recorder.Event(instance, "Normal", "PlanQueued", fmt.Sprintf("Execution of plan %s is queued because %s", kudo.StringValue(planToBeExecuted), reason))
+			return reconcile.Result{RequeueAfter: queuedPlanRetryInterval}, nil
+		}
+		if err := applyCRDs(targetClient, ov); err != nil {
+			if instance.GetPlanInProgress() == nil {
+				r.scheduler.done(request.NamespacedName)
+			}
+			return reconcile.Result{}, r.handleError(err, instance)
+		}
 		log.Printf("InstanceController: Going to start execution of plan %s on instance %s/%s", kudo.StringValue(planToBeExecuted), instance.Namespace, instance.Name)
 		err = instance.StartPlanExecution(kudo.StringValue(planToBeExecuted), ov)
 		if err != nil {
+			if instance.GetPlanInProgress() == nil {
+				r.scheduler.done(request.NamespacedName)
+			}
 			return reconcile.Result{}, r.handleError(err, instance)
 		}
+		r.startExecution(instance, ov, kudo.StringValue(planToBeExecuted), time.Now())
 		r.Recorder.Event(instance, "Normal", "PlanStarted", fmt.Sprintf("Execution of plan %s started", kudo.StringValue(planToBeExecuted)))
 	}
 
@@ -157,48 +270,121 @@ func (r *Reconciler) Reconcile(request ctrl.Request) (ctrl.Result, error) {
 	activePlanStatus := instance.GetPlanInProgress()
 	if activePlanStatus == nil { // we have no plan in progress
 		log.Printf("InstanceController: Nothing to do, no plan in progress for instance %s/%s", instance.Namespace, instance.Name)
+		r.scheduler.done(request.NamespacedName)
+		if !specObserved {
+			// persist the observed generation even though there was nothing to execute
+			return reconcile.Result{}, r.updateInstance(instance)
+		}
 		return reconcile.Result{}, nil
 	}
 
-	activePlan, metadata, err := preparePlanExecution(instance, ov, activePlanStatus)
+	activePlan, metadata, err := preparePlanExecution(instance, ov, activePlanStatus, r.Keyring)
 	if err != nil {
 		err = r.handleError(err, instance)
 		return reconcile.Result{}, err
 	}
+	if err := setPropagation(metadata, instance, r.Client); err != nil {
+		log.Printf("InstanceController: %v", err)
+		return reconcile.Result{}, err
+	}
+	activePlan.parsed = r.templates.Templates(string(ov.UID), ov.ResourceVersion)
 	log.Printf("InstanceController: Going to proceed in execution of active plan %s on instance %s/%s", activePlan.name, instance.Namespace, instance.Name)
-	newStatus, err := executePlan(activePlan, metadata, r.Client, &task.KustomizeEnhancer{Scheme: r.Scheme}, time.Now())
+	now := time.Now()
+	newStatus, err := executePlan(ctx, activePlan, metadata, r.Client, targetClient, &task.KustomizeEnhancer{Scheme: r.Scheme}, executor, now)
 
 	// ---------- 4. Update status of instance after the execution proceeded ----------
 	if newStatus != nil {
+		for _, w := range newWarnings(activePlanStatus, newStatus) {
+			r.Recorder.Event(instance, "Warning", "APIWarning", w)
+		}
 		instance.UpdateInstanceStatus(newStatus)
 	}
 	if err != nil {
 		err = r.handleError(err, instance)
 		return reconcile.Result{}, err
 	}
+	if newStatus != nil && newStatus.Status == kudov1alpha1.ExecutionComplete {
+		// the endpoints may depend on parameters changed by the plan, so they are rendered again after every plan
+		instance.Status.Endpoints = task.RenderEndpoints(r.Client, ov, activePlan.params, *metadata)
+	}
 
-	err = r.Client.Update(context.TODO(), instance)
+	err = r.updateInstance(instance)
 	if err != nil {
 		log.Printf("InstanceController: Error when updating instance state. %v", err)
 		return reconcile.Result{}, err
 	}
 
 	if instance.Status.AggregatedStatus.Status.IsTerminal() {
+		r.scheduler.done(request.NamespacedName)
 		r.Recorder.Event(instance, "Normal", "PlanFinished", fmt.Sprintf("Execution of plan %s finished with status %s", activePlanStatus.Name, instance.Status.AggregatedStatus.Status))
 	}
 
+	// make sure the timeouts are enforced even when nothing else triggers a reconciliation
+	if deadline, ok := nextDeadline(activePlan.spec, newStatus, now); ok {
+		// requeue shortly after the deadline as an execution only times out once its deadline is exceeded
+		requeueAfter := deadline + time.Second
+		if instance.Spec.ClusterTarget != "" && clusterTargetPollInterval < requeueAfter {
+			requeueAfter = clusterTargetPollInterval
+		}
+		return reconcile.Result{RequeueAfter: requeueAfter}, nil
+	}
+	if instance.Spec.ClusterTarget != "" && !instance.Status.AggregatedStatus.Status.IsTerminal() {
+		// the objects in the target cluster are not watched, their health is polled instead
+		return reconcile.Result{RequeueAfter: clusterTargetPollInterval}, nil
+	}
+
 	return reconcile.Result{}, nil
 }
 
-func preparePlanExecution(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, activePlanStatus *kudov1alpha1.PlanStatus) (*activePlan, *task.EngineMetadata, error) {
-	params, err := getParameters(instance, ov)
+// targetOf returns the client and the executor the resources of the instance are applied with, see resourceTarget
+func (r *Reconciler) targetOf(instance *kudov1alpha1.Instance) (client.Client, task.PodExecutor, error) {
+	return resourceTarget(instance, r.Client, r.Executor, r.Config, r.Scheme, &r.targets, &r.accounts)
+}
+
+// newWarnings returns the warnings of the API server in the new plan status that the old one did not contain yet, so
+// that a warning is published as event once and not with every execution of its step
+func newWarnings(old, new *kudov1alpha1.PlanStatus) []string {
+	known := map[string]bool{}
+	for _, ph := range old.Phases {
+		for _, st := range ph.Steps {
+			for _, w := range st.Warnings {
+				known[w] = true
+			}
+		}
+	}
+	var warnings []string
+	for _, ph := range new.Phases {
+		for _, st := range ph.Steps {
+			for _, w := range st.Warnings {
+				if !known[w] {
+					known[w] = true
+					warnings = append(warnings, w)
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+func preparePlanExecution(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, activePlanStatus *kudov1alpha1.PlanStatus, keyring *encryption.Keyring) (*activePlan, *task.EngineMetadata, error) {
+	params, err := getParameters(instance, ov, keyring)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	planSpec, ok := ov.Spec.Plans[activePlanStatus.Name]
-	if !ok {
-		return nil, nil, &ExecutionError{fmt.Errorf("could not find required plan (%v)", activePlanStatus.Name), false, kudo.String("InvalidPlan")}
+	planSpec, err := kudov1alpha1.ResolvePlan(ov.Spec.Plans, activePlanStatus.Name)
+	if err != nil {
+		return nil, nil, &ExecutionError{err, false, kudo.String("InvalidPlan")}
+	}
+
+	patches, err := instance.EffectivePatches(ov)
+	if err != nil {
+		return nil, nil, &ExecutionError{err, false, kudo.String("InvalidOverlay")}
+	}
+
+	constants, err := ov.TemplateConstants()
+	if err != nil {
+		return nil, nil, &ExecutionError{err, false, kudo.String("InvalidConstants")}
 	}
 
 	return &activePlan{
@@ -208,13 +394,23 @@ func preparePlanExecution(instance *kudov1alpha1.Instance, ov *kudov1alpha1.Oper
 			tasks:      ov.Spec.Tasks,
 			templates:  ov.Spec.Templates,
 			params:     params,
+			paramDefs:  ov.Spec.Parameters,
+			isApproved: func(phase, step string) bool {
+				return instance.IsApproved(activePlanStatus.Name, phase, step)
+			},
 		}, &task.EngineMetadata{
 			OperatorVersionName: ov.Name,
 			OperatorVersion:     ov.Spec.Version,
+			Constants:           constants,
 			ResourcesOwner:      instance,
 			OperatorName:        ov.Spec.Operator.Name,
 			InstanceNamespace:   instance.Namespace,
 			InstanceName:        instance.Name,
+			Patches:             patches,
+			Placement:           instance.Spec.Placement,
+			PodMetadata:         instance.Spec.PodMetadata,
+			TargetCluster:       instance.Spec.ClusterTarget,
+			ServiceAccountName:  instance.Spec.ServiceAccountName,
 		}, nil
 }
 
@@ -225,7 +421,7 @@ func (r *Reconciler) handleError(err error, instance *kudov1alpha1.Instance) err
 	log.Printf("InstanceController: %v", err)
 
 	// first update instance as we want to propagate errors also to the `Instance.Status.PlanStatus`
-	clientErr := r.Client.Update(context.TODO(), instance)
+	clientErr := r.updateInstance(instance)
 	if clientErr != nil {
 		log.Printf("InstanceController: Error when updating instance state. %v", clientErr)
 		return clientErr
@@ -252,6 +448,23 @@ func (r *Reconciler) handleError(err error, instance *kudov1alpha1.Instance) err
 	return err
 }
 
+// updateInstance persists the instance metadata (e.g. the snapshot annotation) and its status. As the status is
+// a subresource, it is ignored when updating the main resource and has to be updated separately. The PlanExecutions of
+// the plans are updated with the persisted status.
+func (r *Reconciler) updateInstance(instance *kudov1alpha1.Instance) error {
+	status := instance.Status.DeepCopy()
+	if err := r.Client.Update(context.TODO(), instance); err != nil {
+		return err
+	}
+	// the update response carries the status as stored on the server, so we restore the new status here
+	instance.Status = *status
+	if err := r.Client.Status().Update(context.TODO(), instance); err != nil {
+		return err
+	}
+	r.recordExecutions(instance, time.Now())
+	return nil
+}
+
 // getInstance retrieves the instance by namespaced name
 // returns nil, nil when instance is not found (not found is not considered an error)
 func (r *Reconciler) getInstance(request ctrl.Request) (instance *kudov1alpha1.Instance, err error) {
@@ -288,28 +501,24 @@ func (r *Reconciler) getOperatorVersion(instance *kudov1alpha1.Instance) (ov *ku
 	return ov, nil
 }
 
-func getParameters(instance *kudov1alpha1.Instance, operatorVersion *kudov1alpha1.OperatorVersion) (map[string]string, error) {
-	params := make(map[string]string)
-
-	for k, v := range instance.Spec.Parameters {
-		params[k] = v
+func getParameters(instance *kudov1alpha1.Instance, operatorVersion *kudov1alpha1.OperatorVersion, keyring *encryption.Keyring) (map[string]string, error) {
+	// sensitive parameters are only decrypted for the execution, the instance keeps the encrypted values. Failures are
+	// retried as they are usually caused by an unavailable KMS or a provider that is not configured yet.
+	decrypted, err := keyring.DecryptValues(instance.Spec.Parameters)
+	if err != nil {
+		return nil, &ExecutionError{Err: fmt.Errorf("parameters can not be decrypted: %v", err), Fatal: false, EventName: kudo.String("DecryptionFailed")}
 	}
-
-	missingRequiredParameters := make([]string, 0)
-	// Merge defaults with customizations
-	for _, param := range operatorVersion.Spec.Parameters {
-		_, ok := params[param.Name]
-		if !ok && param.Required && param.Default == nil {
-			// instance does not define this parameter and there is no default while the parameter is required -> error
-			missingRequiredParameters = append(missingRequiredParameters, param.Name)
-
-		} else if !ok {
-			params[param.Name] = kudo.StringValue(param.Default)
-		}
+	params, missing := kudov1alpha1.EffectiveParameters(operatorVersion, decrypted)
+	if len(missing) != 0 {
+		// instance does not define these parameters and there is no default while the parameters are required -> error
+		return nil, &ExecutionError{Err: fmt.Errorf("parameters are missing when evaluating template: %s", strings.Join(missing, ",")), Fatal: true, EventName: kudo.String("Missing parameter")}
 	}
-
-	if len(missingRequiredParameters) != 0 {
-		return nil, &ExecutionError{Err: fmt.Errorf("parameters are missing when evaluating template: %s", strings.Join(missingRequiredParameters, ",")), Fatal: true, EventName: kudo.String("Missing parameter")}
+	params, err = task.ComputeDefaults(operatorVersion.Spec.Parameters, decrypted, params)
+	if err != nil {
+		return nil, &ExecutionError{Err: fmt.Errorf("parameters are invalid: %v", err), Fatal: true, EventName: kudo.String("InvalidParameter")}
+	}
+	if _, err := kudov1alpha1.TemplateParameters(operatorVersion.Spec.Parameters, params); err != nil {
+		return nil, &ExecutionError{Err: fmt.Errorf("parameters are invalid: %v", err), Fatal: true, EventName: kudo.String("InvalidParameter")}
 	}
 
 	return params, nil
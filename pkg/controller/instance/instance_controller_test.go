@@ -108,3 +108,18 @@ func startTestManager(t *testing.T) (chan struct{}, *sync.WaitGroup, client.Clie
 	}()
 	return stop, wg, mgr.GetClient()
 }
+
+func TestNewWarnings(t *testing.T) {
+	status := func(warnings ...[]string) *v1alpha1.PlanStatus {
+		ph := v1alpha1.PhaseStatus{Name: "main"}
+		for _, w := range warnings {
+			ph.Steps = append(ph.Steps, v1alpha1.StepStatus{Warnings: w})
+		}
+		return &v1alpha1.PlanStatus{Name: "deploy", Phases: []v1alpha1.PhaseStatus{ph}}
+	}
+	deprecated := "extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"
+	psp := "policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+"
+
+	assert.Equal(t, []string{deprecated, psp}, newWarnings(status(), status([]string{deprecated}, []string{deprecated, psp})))
+	assert.Nil(t, newWarnings(status([]string{deprecated}), status([]string{deprecated})), "known warnings are not published again")
+}
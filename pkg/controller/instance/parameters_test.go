@@ -0,0 +1,53 @@
+package instance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetParameters_Encrypted(t *testing.T) {
+	keyring := encryption.NewKeyring()
+	if err := keyring.Add("team-key", &encryption.ExecProvider{EncryptCommand: []string{"cat"}, DecryptCommand: []string{"cat"}}); err != nil {
+		t.Fatal(err)
+	}
+	password, err := keyring.Encrypt("PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{Parameters: []v1alpha1.Parameter{
+			{Name: "PASSWORD", Required: true, Sensitive: true},
+			{Name: "USER", Default: kudo.String("admin")},
+		}},
+	}
+	instance := &v1alpha1.Instance{Spec: v1alpha1.InstanceSpec{Parameters: map[string]string{"PASSWORD": password}}}
+
+	params, err := getParameters(instance, ov, keyring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params["PASSWORD"] != "hunter2" || params["USER"] != "admin" {
+		t.Errorf("unexpected parameters %v", params)
+	}
+	if instance.Spec.Parameters["PASSWORD"] != password {
+		t.Errorf("expected the instance to keep the encrypted value but got %s", instance.Spec.Parameters["PASSWORD"])
+	}
+
+	_, err = getParameters(instance, ov, nil)
+	var execErr *ExecutionError
+	if !errors.As(err, &execErr) || execErr.Fatal {
+		t.Fatalf("expected a retryable execution error but got %v", err)
+	}
+	expected := "parameters can not be decrypted: PASSWORD: value is encrypted with provider team-key which is not configured"
+	if execErr.Err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, execErr.Err)
+	}
+}
@@ -1,13 +1,17 @@
 package instance
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
 	engtask "github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -18,6 +22,7 @@ var (
 	fatalTaskExecutionErrorEventName = "FatalTaskExecutionError"
 	missingPhaseStatus               = "MissingPhaseStatus"
 	missingStepStatus                = "MissingStepStatus"
+	executionTimeoutEventName        = "ExecutionTimeout"
 )
 
 type activePlan struct {
@@ -27,6 +32,16 @@ type activePlan struct {
 	tasks     []v1alpha1.Task
 	templates map[string]string
 	params    map[string]string
+	paramDefs []v1alpha1.Parameter
+	// parsed caches the parsed templates, it may be nil
+	parsed *engine.Templates
+	// isApproved returns whether the manual gate of a phase, or of a step if step is not empty, was approved. It may
+	// be nil if nothing was approved.
+	isApproved func(phase, step string) bool
+}
+
+func (ap *activePlan) approved(phase, step string) bool {
+	return ap.isApproved != nil && ap.isApproved(phase, step)
 }
 
 func (ap *activePlan) taskByName(name string) (*v1alpha1.Task, bool) {
@@ -56,72 +71,163 @@ func (ap *activePlan) taskByName(name string) (*v1alpha1.Task, bool) {
 //
 // Furthermore, a transient ERROR during a step execution, means that the next step may be executed if the step strategy
 // is "parallel". In case of a fatal error, it is returned alongside with the new plan status and published on the event bus.
-func executePlan(pl *activePlan, em *engtask.EngineMetadata, c client.Client, enh engtask.KubernetesObjectEnhancer, currentTime time.Time) (*v1alpha1.PlanStatus, error) {
+//
+// Manual phases and steps stay PENDING with a message until their gate is approved, see v1alpha1.ApprovalsAnnotation.
+// Like an unfinished step, a gate blocks the following phases or steps of a serial strategy only.
+//
+// Plans, phases and steps may define a timeout. An execution that is still running after its timeout is marked as
+// FATAL_ERROR with a timeout message and no further work is scheduled.
+//
+// The execution and every task run are traced as children of the span in the context.
+//
+// The resources of the instance are applied with the target client, which differs from c for an instance targeting a
+// ClusterTarget. Everything else, e.g. other instances and the persisted values, is read and written with c.
+func executePlan(ctx context.Context, pl *activePlan, em *engtask.EngineMetadata, c, target client.Client, enh engtask.KubernetesObjectEnhancer, executor engtask.PodExecutor, currentTime time.Time) (_ *v1alpha1.PlanStatus, err error) {
+	ctx, span := tracing.Start(ctx, "plan.Execute", attribute.String("kudo.plan", pl.name), attribute.String("kudo.operatorversion", em.OperatorVersionName))
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if pl.Status.IsTerminal() {
 		log.Printf("PlanExecution: Plan %s for instance %s is terminal, nothing to do", pl.name, em.InstanceName)
 		return pl.PlanStatus, nil
 	}
 
 	planStatus := pl.PlanStatus.DeepCopy()
-	planStatus.Status = v1alpha1.ExecutionInProgress
+	startExecution(&planStatus.StartedAt, &planStatus.Message, planStatus.Status, currentTime)
+	if timedOut(planStatus.StartedAt, pl.spec.Timeout, currentTime) {
+		planStatus.SetWithMessage(v1alpha1.ExecutionFatalError, fmt.Sprintf("plan %s timed out after %s", pl.name, pl.spec.Timeout.Duration))
+		return planStatus, &ExecutionError{
+			Err:       fmt.Errorf("%s for operator version %s", planStatus.Message, em.OperatorVersionName),
+			Fatal:     true,
+			EventName: &executionTimeoutEventName,
+		}
+	}
+	planStatus.Set(v1alpha1.ExecutionInProgress)
 
-	phasesLeft := len(pl.spec.Phases)
 	// --- 1. Iterate over plan phases ---
 	for _, ph := range pl.spec.Phases {
 		phaseStatus := getPhaseStatus(ph.Name, planStatus)
 		if phaseStatus == nil {
-			planStatus.Status = v1alpha1.ExecutionFatalError
-			return planStatus, ExecutionError{
+			planStatus.Set(v1alpha1.ExecutionFatalError)
+			return planStatus, &ExecutionError{
 				Err:       fmt.Errorf("failed to find phase %s for operator version %s", ph.Name, em.OperatorVersionName),
 				Fatal:     true,
 				EventName: &missingPhaseStatus,
 			}
 		}
 
+		// a manual phase is not started before it is approved, a serial plan waits for it
+		if ph.Manual && phaseStatus.Status == v1alpha1.ExecutionPending && !pl.approved(ph.Name, "") {
+			phaseStatus.Message = fmt.Sprintf("waiting for approval, approve with 'kubectl kudo plan approve %s --name %s --phase %s'", em.InstanceName, pl.name, ph.Name)
+			if pl.spec.Strategy == v1alpha1.Serial {
+				break
+			}
+			continue
+		}
+
 		// Check current phase status: skip if finished, proceed if in progress, break out if a fatal error has occurred
-		if isFinished(phaseStatus.Status) {
-			phasesLeft = phasesLeft - 1
+		if phaseStatus.IsFinished() {
 			continue
-		} else if isInProgress(phaseStatus.Status) {
-			phaseStatus.Status = v1alpha1.ExecutionInProgress
+		} else if phaseStatus.IsRunning() {
+			startExecution(&phaseStatus.StartedAt, &phaseStatus.Message, phaseStatus.Status, currentTime)
+			phaseStatus.Set(v1alpha1.ExecutionInProgress)
 		} else {
 			break
 		}
 
-		stepsLeft := len(ph.Steps)
+		if timedOut(phaseStatus.StartedAt, ph.Timeout, currentTime) {
+			phaseStatus.SetWithMessage(v1alpha1.ExecutionFatalError, fmt.Sprintf("phase %s timed out after %s", ph.Name, ph.Timeout.Duration))
+			planStatus.SetWithMessage(v1alpha1.ExecutionFatalError, phaseStatus.Message)
+			return planStatus, &ExecutionError{
+				Err:       fmt.Errorf("%s for operator version %s", phaseStatus.Message, em.OperatorVersionName),
+				Fatal:     true,
+				EventName: &executionTimeoutEventName,
+			}
+		}
+
 		// --- 2. Iterate over phase steps ---
 		for _, st := range ph.Steps {
 			stepStatus := getStepStatus(st.Name, phaseStatus)
 			if stepStatus == nil {
-				phaseStatus.Status = v1alpha1.ExecutionFatalError
-				planStatus.Status = v1alpha1.ExecutionFatalError
-				return planStatus, ExecutionError{
+				failExecution(planStatus, phaseStatus, nil)
+				return planStatus, &ExecutionError{
 					Err:       fmt.Errorf("failed to find step %s for operator version %s", st.Name, em.OperatorVersionName),
 					Fatal:     true,
 					EventName: &missingStepStatus,
 				}
 			}
 
+			// a manual step is not started before it is approved, a serial phase waits for it
+			if st.Manual && stepStatus.Status == v1alpha1.ExecutionPending && !pl.approved(ph.Name, st.Name) {
+				stepStatus.Message = fmt.Sprintf("waiting for approval, approve with 'kubectl kudo plan approve %s --name %s --phase %s --step %s'", em.InstanceName, pl.name, ph.Name, st.Name)
+				if ph.Strategy == v1alpha1.Serial {
+					break
+				}
+				continue
+			}
+
 			// Check current phase status: skip if finished, proceed if in progress, break out if a fatal error has occurred
-			if isFinished(stepStatus.Status) {
-				stepsLeft = stepsLeft - 1
+			if stepStatus.IsFinished() {
 				continue
-			} else if isInProgress(stepStatus.Status) {
-				stepStatus.Status = v1alpha1.ExecutionInProgress
+			} else if stepStatus.IsRunning() {
+				if stepStatus.Status == v1alpha1.ExecutionPending {
+					// the tasks of a new execution of the step run again
+					stepStatus.CompletedTasks = nil
+				}
+				startExecution(&stepStatus.StartedAt, &stepStatus.Message, stepStatus.Status, currentTime)
+				stepStatus.Set(v1alpha1.ExecutionInProgress)
 			} else {
 				// we are not in progress and not finished. An unexpected error occurred so that we can not proceed to the next phase
 				break
 			}
 
+			if timedOut(stepStatus.StartedAt, st.Timeout, currentTime) {
+				stepStatus.SetWithMessage(v1alpha1.ExecutionFatalError, fmt.Sprintf("step %s.%s timed out after %s", ph.Name, st.Name, st.Timeout.Duration))
+				phaseStatus.SetWithMessage(v1alpha1.ExecutionFatalError, stepStatus.Message)
+				planStatus.SetWithMessage(v1alpha1.ExecutionFatalError, stepStatus.Message)
+				return planStatus, &ExecutionError{
+					Err:       fmt.Errorf("%s for operator version %s", stepStatus.Message, em.OperatorVersionName),
+					Fatal:     true,
+					EventName: &executionTimeoutEventName,
+				}
+			}
+
 			tasksLeft := len(st.Tasks)
+			// the tasks record the health of their resources and the warnings of the API server anew with every execution,
+			// the resources of the completed tasks are kept as these tasks are not run again
+			completed := make(map[string]bool, len(stepStatus.CompletedTasks))
+			for _, tn := range stepStatus.CompletedTasks {
+				completed[tn] = true
+			}
+			resources := stepStatus.Resources
+			stepStatus.Resources = nil
+			for _, r := range resources {
+				if completed[r.Task] {
+					stepStatus.Resources = append(stepStatus.Resources, r)
+				}
+			}
+			stepStatus.Warnings = nil
+			recordResource := func(status v1alpha1.ResourceStatus) {
+				stepStatus.Resources = append(stepStatus.Resources, status)
+			}
+			recordWarning := func(warning string) {
+				for _, w := range stepStatus.Warnings {
+					if w == warning {
+						return
+					}
+				}
+				stepStatus.Warnings = append(stepStatus.Warnings, warning)
+			}
 			// --- 3. Iterate over step tasks ---
 			for _, tn := range st.Tasks {
+				// a task that succeeded is not run again, e.g. a non-idempotent command of an Exec task
+				if completed[tn] {
+					tasksLeft = tasksLeft - 1
+					continue
+				}
 				t, ok := pl.taskByName(tn)
 				if !ok {
-					phaseStatus.Status = v1alpha1.ExecutionFatalError
-					stepStatus.Status = v1alpha1.ExecutionFatalError
-					planStatus.Status = v1alpha1.ExecutionFatalError
-					return planStatus, ExecutionError{
+					failExecution(planStatus, phaseStatus, stepStatus)
+					return planStatus, &ExecutionError{
 						Err:       fmt.Errorf("failed to find task %s for operator version %s", tn, em.OperatorVersionName),
 						Fatal:     true,
 						EventName: &unknownTaskNameEventName,
@@ -139,10 +245,8 @@ func executePlan(pl *activePlan, em *engtask.EngineMetadata, c client.Client, en
 				// - 3.b build the engine task -
 				task, err := engtask.Build(t)
 				if err != nil {
-					stepStatus.Status = v1alpha1.ExecutionFatalError
-					phaseStatus.Status = v1alpha1.ExecutionFatalError
-					planStatus.Status = v1alpha1.ExecutionFatalError
-					return planStatus, ExecutionError{
+					failExecution(planStatus, phaseStatus, stepStatus)
+					return planStatus, &ExecutionError{
 						Err:       fmt.Errorf("failed to resolve task %s for operator version %s: %w", tn, em.OperatorVersionName, err),
 						Fatal:     true,
 						EventName: &unknownTaskKindEventName,
@@ -150,35 +254,49 @@ func executePlan(pl *activePlan, em *engtask.EngineMetadata, c client.Client, en
 				}
 
 				// - 3.c build task context -
-				ctx := engtask.Context{
-					Client:     c,
-					Enhancer:   enh,
-					Meta:       exm,
-					Templates:  pl.templates,
-					Parameters: pl.params,
+				taskCtx := engtask.Context{
+					Client:               c,
+					TargetClient:         target,
+					Enhancer:             enh,
+					Meta:                 exm,
+					Templates:            pl.templates,
+					Parameters:           pl.params,
+					ParameterDefinitions: pl.paramDefs,
+					ParsedTemplates:      pl.parsed,
+					RecordResource:       recordResource,
+					RecordWarning:        recordWarning,
+					Executor:             executor,
 				}
 
 				// --- 4. Execute the engine task ---
-				done, err := task.Run(ctx)
+				_, taskSpan := tracing.Start(ctx, "task.Run",
+					attribute.String("kudo.phase", ph.Name),
+					attribute.String("kudo.step", st.Name),
+					attribute.String("kudo.task", tn),
+					attribute.String("kudo.task.kind", t.Kind),
+				)
+				done, err := task.Run(taskCtx)
+				taskSpan.SetAttributes(attribute.Bool("kudo.task.done", done))
+				tracing.EndSpan(taskSpan, err)
 
 				// a fatal error is propagated through the plan/phase/step statuses and the plan execution will be
 				// stopped in the spirit of "fail-loud-and-proud".
 				switch {
 				case errors.Is(err, engtask.ErrFatalExecution):
 					log.Printf("PlanExecution: error during task %s execution for operator version %s: %v", exm.TaskName, exm.OperatorVersionName, err)
-					phaseStatus.Status = v1alpha1.ExecutionFatalError
-					stepStatus.Status = v1alpha1.ExecutionFatalError
-					planStatus.Status = v1alpha1.ExecutionFatalError
-					return planStatus, ExecutionError{
+					failExecution(planStatus, phaseStatus, stepStatus)
+					return planStatus, &ExecutionError{
 						Err:       fmt.Errorf("error during task %s execution for operator version %s: %w", tn, em.OperatorVersionName, err),
 						Fatal:     true,
 						EventName: &fatalTaskExecutionErrorEventName,
 					}
 				case err != nil:
 					log.Printf("PlanExecution: error during task %s execution for operator version %s: %v", exm.TaskName, exm.OperatorVersionName, err)
-					stepStatus.Status = v1alpha1.ErrorStatus
+					stepStatus.Set(v1alpha1.ErrorStatus)
 				case done:
 					tasksLeft = tasksLeft - 1
+					completed[tn] = true
+					stepStatus.CompletedTasks = append(stepStatus.CompletedTasks, tn)
 				}
 			}
 
@@ -191,35 +309,88 @@ func executePlan(pl *activePlan, em *engtask.EngineMetadata, c client.Client, en
 					break
 				}
 			} else {
-				stepStatus.Status = v1alpha1.ExecutionComplete
-				stepsLeft = stepsLeft - 1
+				stepStatus.Set(v1alpha1.ExecutionComplete)
+				stepStatus.CompletedTasks = nil
 			}
 		}
 
 		// --- 6. Check if all STEPs are finished ---
 		// if some STEPs aren't ready yet and PHASEs strategy is serial we can not proceed
 		// otherwise, if PHASEs strategy is parallel or all STEPs are finished, we can go to the next PHASE
-		if stepsLeft > 0 {
+		if v1alpha1.AggregateStepStatuses(phaseStatus.Steps) != v1alpha1.ExecutionComplete {
 			if pl.spec.Strategy == v1alpha1.Serial {
 				log.Printf("PlanExecution: some steps of the %s.%s, operator version %s are not ready", pl.Name, ph.Name, em.OperatorVersionName)
 				break
 			}
 		} else {
-			phaseStatus.Status = v1alpha1.ExecutionComplete
-			phasesLeft = phasesLeft - 1
+			phaseStatus.Set(v1alpha1.ExecutionComplete)
 		}
 	}
 
 	// --- 7. Check if all PHASEs are finished ---
-	if phasesLeft == 0 {
+	if v1alpha1.AggregatePhaseStatuses(planStatus.Phases) == v1alpha1.ExecutionComplete {
 		log.Printf("PlanExecution: All phases on plan %s and instance %s are healthy", pl.name, em.InstanceName)
-		planStatus.Status = v1alpha1.ExecutionComplete
+		planStatus.Set(v1alpha1.ExecutionComplete)
 		planStatus.LastFinishedRun = v1.Time{Time: currentTime}
 	}
 
 	return planStatus, nil
 }
 
+// startExecution records the start time of a pending plan, phase or step, i.e. one that has just been started, and
+// clears the message of a previous execution.
+func startExecution(startedAt *v1.Time, message *string, status v1alpha1.ExecutionStatus, currentTime time.Time) {
+	if status == v1alpha1.ExecutionPending {
+		*startedAt = v1.Time{Time: currentTime}
+		*message = ""
+	}
+}
+
+// timedOut returns true if an execution started at startedAt exceeded its timeout
+func timedOut(startedAt v1.Time, timeout *v1.Duration, currentTime time.Time) bool {
+	return timeout != nil && !startedAt.IsZero() && currentTime.Sub(startedAt.Time) > timeout.Duration
+}
+
+// nextDeadline returns the time until the earliest timeout of the running plan and its running phases and steps
+// expires. The second return value is false if none of them has a timeout.
+func nextDeadline(spec *v1alpha1.Plan, status *v1alpha1.PlanStatus, currentTime time.Time) (time.Duration, bool) {
+	var next time.Duration
+	found := false
+	check := func(startedAt v1.Time, timeout *v1.Duration) {
+		if timeout == nil || startedAt.IsZero() {
+			return
+		}
+		remaining := startedAt.Add(timeout.Duration).Sub(currentTime)
+		if !found || remaining < next {
+			next = remaining
+			found = true
+		}
+	}
+
+	if !status.Status.IsRunning() {
+		return 0, false
+	}
+	check(status.StartedAt, spec.Timeout)
+	for _, ph := range spec.Phases {
+		phaseStatus := getPhaseStatus(ph.Name, status)
+		if phaseStatus == nil || !phaseStatus.Status.IsRunning() {
+			continue
+		}
+		check(phaseStatus.StartedAt, ph.Timeout)
+		for _, st := range ph.Steps {
+			stepStatus := getStepStatus(st.Name, phaseStatus)
+			if stepStatus == nil || !stepStatus.Status.IsRunning() {
+				continue
+			}
+			check(stepStatus.StartedAt, st.Timeout)
+		}
+	}
+	if found && next < 0 {
+		next = 0
+	}
+	return next, found
+}
+
 func getStepStatus(stepName string, phaseStatus *v1alpha1.PhaseStatus) *v1alpha1.StepStatus {
 	for i, p := range phaseStatus.Steps {
 		if p.Name == stepName {
@@ -240,10 +411,13 @@ func getPhaseStatus(phaseName string, planStatus *v1alpha1.PlanStatus) *v1alpha1
 	return nil
 }
 
-func isFinished(state v1alpha1.ExecutionStatus) bool {
-	return state == v1alpha1.ExecutionComplete
-}
-
-func isInProgress(state v1alpha1.ExecutionStatus) bool {
-	return state == v1alpha1.ExecutionInProgress || state == v1alpha1.ExecutionPending || state == v1alpha1.ErrorStatus
+// failExecution marks the plan and the given phase and step as failed, phase and step are skipped if nil
+func failExecution(planStatus *v1alpha1.PlanStatus, phaseStatus *v1alpha1.PhaseStatus, stepStatus *v1alpha1.StepStatus) {
+	if stepStatus != nil {
+		stepStatus.Set(v1alpha1.ExecutionFatalError)
+	}
+	if phaseStatus != nil {
+		phaseStatus.Set(v1alpha1.ExecutionFatalError)
+	}
+	planStatus.Set(v1alpha1.ExecutionFatalError)
 }
@@ -1,13 +1,16 @@
 package instance
 
 import (
+	"context"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/engine/task"
 	engtask "github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
 	"github.com/kudobuilder/kudo/pkg/util/template"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -15,6 +18,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -107,9 +111,10 @@ func TestExecutePlan(t *testing.T) {
 			metadata: meta,
 			expectedStatus: &v1alpha1.PlanStatus{
 				Status:          v1alpha1.ExecutionComplete,
+				StartedAt:       v1.Time{Time: timeNow},
 				LastFinishedRun: v1.Time{Time: timeNow},
 				Name:            "test",
-				Phases:          []v1alpha1.PhaseStatus{{Name: "phase", Status: v1alpha1.ExecutionComplete, Steps: []v1alpha1.StepStatus{{Status: v1alpha1.ExecutionComplete, Name: "step"}}}},
+				Phases:          []v1alpha1.PhaseStatus{{Name: "phase", Status: v1alpha1.ExecutionComplete, StartedAt: v1.Time{Time: timeNow}, Steps: []v1alpha1.StepStatus{{Status: v1alpha1.ExecutionComplete, Name: "step", StartedAt: v1.Time{Time: timeNow}}}}},
 			},
 			enhancer: testEnhancer,
 		},
@@ -590,9 +595,10 @@ func TestExecutePlan(t *testing.T) {
 			},
 			metadata: meta,
 			expectedStatus: &v1alpha1.PlanStatus{
-				Status: v1alpha1.ExecutionFatalError,
-				Name:   "test",
-				Phases: []v1alpha1.PhaseStatus{{Name: "phase", Status: v1alpha1.ExecutionFatalError, Steps: []v1alpha1.StepStatus{{Status: v1alpha1.ExecutionFatalError, Name: "step"}}}}},
+				Status:    v1alpha1.ExecutionFatalError,
+				StartedAt: v1.Time{Time: timeNow},
+				Name:      "test",
+				Phases:    []v1alpha1.PhaseStatus{{Name: "phase", Status: v1alpha1.ExecutionFatalError, StartedAt: v1.Time{Time: timeNow}, Steps: []v1alpha1.StepStatus{{Status: v1alpha1.ExecutionFatalError, Name: "step", StartedAt: v1.Time{Time: timeNow}}}}}},
 			wantErr:  true,
 			enhancer: testEnhancer,
 		},
@@ -600,7 +606,7 @@ func TestExecutePlan(t *testing.T) {
 
 	for _, tt := range tests {
 		testClient := fake.NewFakeClientWithScheme(scheme.Scheme)
-		newStatus, err := executePlan(tt.activePlan, tt.metadata, testClient, tt.enhancer, timeNow)
+		newStatus, err := executePlan(context.TODO(), tt.activePlan, tt.metadata, testClient, nil, tt.enhancer, nil, timeNow)
 
 		if !tt.wantErr && err != nil {
 			t.Errorf("%s: Expecting no error but got one: %v", tt.name, err)
@@ -616,6 +622,309 @@ func TestExecutePlan(t *testing.T) {
 	}
 }
 
+func TestExecutePlan_Timeouts(t *testing.T) {
+	timeNow := time.Now()
+	started := v1.Time{Time: timeNow.Add(-2 * time.Minute)}
+	minute := &v1.Duration{Duration: time.Minute}
+	hour := &v1.Duration{Duration: time.Hour}
+	meta := &engtask.EngineMetadata{OperatorVersionName: "first-operator-1.0"}
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+
+	plan := func(planTimeout, phaseTimeout, stepTimeout *v1.Duration) *v1alpha1.Plan {
+		return &v1alpha1.Plan{
+			Strategy: "serial",
+			Timeout:  planTimeout,
+			Phases: []v1alpha1.Phase{
+				{Name: "phase", Strategy: "serial", Timeout: phaseTimeout, Steps: []v1alpha1.Step{{Name: "step", Tasks: []string{"task"}, Timeout: stepTimeout}}},
+			},
+		}
+	}
+	status := func(s v1alpha1.ExecutionStatus, startedAt v1.Time) *v1alpha1.PlanStatus {
+		return &v1alpha1.PlanStatus{
+			Name:      "deploy",
+			Status:    s,
+			StartedAt: startedAt,
+			Phases: []v1alpha1.PhaseStatus{{Name: "phase", Status: s, StartedAt: startedAt, Steps: []v1alpha1.StepStatus{
+				{Name: "step", Status: s, StartedAt: startedAt},
+			}}},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		spec            *v1alpha1.Plan
+		status          *v1alpha1.PlanStatus
+		expectedStatus  v1alpha1.ExecutionStatus
+		expectedMessage string
+		fatal           bool
+	}{
+		{name: "plan without timeout keeps running", spec: plan(nil, nil, nil), status: status(v1alpha1.ExecutionInProgress, started), expectedStatus: v1alpha1.ExecutionInProgress},
+		{name: "plan within its timeout keeps running", spec: plan(hour, hour, hour), status: status(v1alpha1.ExecutionInProgress, started), expectedStatus: v1alpha1.ExecutionInProgress},
+		{name: "plan exceeding its timeout fails", spec: plan(minute, nil, nil), status: status(v1alpha1.ExecutionInProgress, started), expectedStatus: v1alpha1.ExecutionFatalError,
+			expectedMessage: "plan deploy timed out after 1m0s", fatal: true},
+		{name: "phase exceeding its timeout fails", spec: plan(hour, minute, nil), status: status(v1alpha1.ErrorStatus, started), expectedStatus: v1alpha1.ExecutionFatalError,
+			expectedMessage: "phase phase timed out after 1m0s", fatal: true},
+		{name: "step exceeding its timeout fails", spec: plan(nil, nil, minute), status: status(v1alpha1.ExecutionInProgress, started), expectedStatus: v1alpha1.ExecutionFatalError,
+			expectedMessage: "step phase.step timed out after 1m0s", fatal: true},
+		{name: "restarted plan resets its start time", spec: plan(minute, minute, minute), status: status(v1alpha1.ExecutionPending, started), expectedStatus: v1alpha1.ExecutionInProgress},
+	}
+
+	for _, tt := range tests {
+		pl := &activePlan{
+			name:       "deploy",
+			PlanStatus: tt.status,
+			spec:       tt.spec,
+			tasks:      []v1alpha1.Task{{Name: "task", Kind: "Dummy", Spec: v1alpha1.TaskSpec{DummyTaskSpec: v1alpha1.DummyTaskSpec{Done: false}}}},
+		}
+		newStatus, err := executePlan(context.TODO(), pl, meta, fake.NewFakeClientWithScheme(scheme.Scheme), nil, &testKubernetesObjectEnhancer{}, nil, timeNow)
+
+		exErr, isExErr := err.(*ExecutionError)
+		if tt.fatal != (isExErr && exErr.Fatal && *exErr.EventName == executionTimeoutEventName) {
+			t.Errorf("%s: expected fatal timeout error = %v but got %v", tt.name, tt.fatal, err)
+		}
+		if tt.fatal {
+			// the controller records the timeout and does not retry the plan
+			instance := &v1alpha1.Instance{ObjectMeta: v1.ObjectMeta{Name: "test", Namespace: "default"}}
+			recorder := record.NewFakeRecorder(1)
+			r := &Reconciler{Client: fake.NewFakeClientWithScheme(s, instance), Recorder: recorder}
+			if err := r.handleError(err, instance); err != nil {
+				t.Errorf("%s: expected timeout to not be retried but got %v", tt.name, err)
+			}
+			if len(recorder.Events) != 1 || !strings.Contains(<-recorder.Events, executionTimeoutEventName) {
+				t.Errorf("%s: expected %s event to be recorded", tt.name, executionTimeoutEventName)
+			}
+		}
+		if newStatus.Status != tt.expectedStatus {
+			t.Errorf("%s: expected status %s but got %s", tt.name, tt.expectedStatus, newStatus.Status)
+		}
+		if newStatus.Message != tt.expectedMessage {
+			t.Errorf("%s: expected message %q but got %q", tt.name, tt.expectedMessage, newStatus.Message)
+		}
+		if tt.status.Status == v1alpha1.ExecutionPending && !newStatus.StartedAt.Equal(&v1.Time{Time: timeNow}) {
+			t.Errorf("%s: expected start time to be reset but got %v", tt.name, newStatus.StartedAt)
+		}
+	}
+}
+
+func TestExecutePlan_Resources(t *testing.T) {
+	meta := &engtask.EngineMetadata{OperatorVersionName: "first-operator-1.0", InstanceNamespace: "default"}
+	pl := &activePlan{
+		name: "deploy",
+		PlanStatus: &v1alpha1.PlanStatus{
+			Name:   "deploy",
+			Status: v1alpha1.ExecutionInProgress,
+			Phases: []v1alpha1.PhaseStatus{{Name: "phase", Status: v1alpha1.ExecutionInProgress, Steps: []v1alpha1.StepStatus{
+				{Name: "step", Status: v1alpha1.ExecutionInProgress, Resources: []v1alpha1.ResourceStatus{{Kind: "Pod", Name: "stale"}}},
+			}}},
+		},
+		spec: &v1alpha1.Plan{
+			Strategy: "serial",
+			Phases: []v1alpha1.Phase{
+				{Name: "phase", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "step", Tasks: []string{"app"}}}},
+			},
+		},
+		tasks: []v1alpha1.Task{{Name: "app", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"pod.yaml", "job.yaml"}}}}},
+		templates: map[string]string{
+			"pod.yaml": "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod\n  namespace: default\n",
+			"job.yaml": "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: job\n  namespace: default\n",
+		},
+	}
+
+	newStatus, err := executePlan(context.TODO(), pl, meta, fake.NewFakeClientWithScheme(scheme.Scheme), nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	resources := newStatus.Phases[0].Steps[0].Resources
+	byName := map[string]v1alpha1.ResourceStatus{}
+	for _, r := range resources {
+		byName[r.Name] = r
+	}
+	if len(resources) != 2 {
+		t.Fatalf("expected the stale resources to be replaced by the pod and the job but got %v", resources)
+	}
+	if pod := byName["pod"]; pod.APIVersion != "v1" || pod.Kind != "Pod" || pod.Namespace != "default" || pod.Health != v1alpha1.ResourceReady {
+		t.Errorf("expected a ready pod but got %v", pod)
+	}
+	if job := byName["job"]; job.Kind != "Job" || job.Health != v1alpha1.ResourceProgressing || job.Message == "" {
+		t.Errorf("expected a progressing job with a message but got %v", job)
+	}
+}
+
+func TestExecutePlan_ManualGates(t *testing.T) {
+	meta := &engtask.EngineMetadata{OperatorVersionName: "first-operator-1.0", InstanceName: "kafka"}
+	spec := &v1alpha1.Plan{
+		Strategy: "serial",
+		Phases: []v1alpha1.Phase{
+			{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{
+				{Name: "prepare", Tasks: []string{"task"}},
+				{Name: "switch", Tasks: []string{"task"}, Manual: true},
+			}},
+			{Name: "cleanup", Strategy: "serial", Manual: true, Steps: []v1alpha1.Step{{Name: "remove", Tasks: []string{"task"}}}},
+		},
+	}
+	pending := func() *v1alpha1.PlanStatus {
+		return &v1alpha1.PlanStatus{
+			Name:   "upgrade",
+			Status: v1alpha1.ExecutionPending,
+			Phases: []v1alpha1.PhaseStatus{
+				{Name: "main", Status: v1alpha1.ExecutionPending, Steps: []v1alpha1.StepStatus{
+					{Name: "prepare", Status: v1alpha1.ExecutionPending},
+					{Name: "switch", Status: v1alpha1.ExecutionPending},
+				}},
+				{Name: "cleanup", Status: v1alpha1.ExecutionPending, Steps: []v1alpha1.StepStatus{{Name: "remove", Status: v1alpha1.ExecutionPending}}},
+			},
+		}
+	}
+	tasks := []v1alpha1.Task{{Name: "task", Kind: "Dummy", Spec: v1alpha1.TaskSpec{DummyTaskSpec: v1alpha1.DummyTaskSpec{Done: true}}}}
+	execute := func(approvals ...string) *v1alpha1.PlanStatus {
+		instance := &v1alpha1.Instance{}
+		for _, a := range approvals {
+			parts := strings.SplitN(a, ".", 2)
+			if len(parts) == 1 {
+				parts = append(parts, "")
+			}
+			instance.Approve("upgrade", parts[0], parts[1])
+		}
+		pl := &activePlan{
+			name:       "upgrade",
+			PlanStatus: pending(),
+			spec:       spec,
+			tasks:      tasks,
+			isApproved: func(phase, step string) bool { return instance.IsApproved("upgrade", phase, step) },
+		}
+		newStatus, err := executePlan(context.TODO(), pl, meta, fake.NewFakeClientWithScheme(scheme.Scheme), nil, &testKubernetesObjectEnhancer{}, nil, time.Now())
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		return newStatus
+	}
+
+	status := execute()
+	if status.Status != v1alpha1.ExecutionInProgress {
+		t.Errorf("expected the plan waiting for the approval to be in progress but got %s", status.Status)
+	}
+	main := status.Phases[0]
+	if main.Steps[0].Status != v1alpha1.ExecutionComplete {
+		t.Errorf("expected the step before the gate to be complete but got %s", main.Steps[0].Status)
+	}
+	expected := "waiting for approval, approve with 'kubectl kudo plan approve kafka --name upgrade --phase main --step switch'"
+	if main.Steps[1].Status != v1alpha1.ExecutionPending || main.Steps[1].Message != expected {
+		t.Errorf("expected the manual step to wait for its approval but got %s: %s", main.Steps[1].Status, main.Steps[1].Message)
+	}
+	if status.Phases[1].Status != v1alpha1.ExecutionPending || status.Phases[1].Message != "" {
+		t.Errorf("expected the next phase not to be reached but got %s: %s", status.Phases[1].Status, status.Phases[1].Message)
+	}
+
+	status = execute("main.switch")
+	if status.Phases[0].Status != v1alpha1.ExecutionComplete {
+		t.Errorf("expected the approved step to be executed but got %s", status.Phases[0].Status)
+	}
+	expected = "waiting for approval, approve with 'kubectl kudo plan approve kafka --name upgrade --phase cleanup'"
+	if status.Phases[1].Status != v1alpha1.ExecutionPending || status.Phases[1].Message != expected {
+		t.Errorf("expected the manual phase to wait for its approval but got %s: %s", status.Phases[1].Status, status.Phases[1].Message)
+	}
+
+	status = execute("main.switch", "cleanup")
+	if status.Status != v1alpha1.ExecutionComplete {
+		t.Errorf("expected the approved plan to be complete but got %s", status.Status)
+	}
+}
+
+// countingExecutor counts the commands it runs
+type countingExecutor struct {
+	runs int
+}
+
+func (e *countingExecutor) Exec(namespace, pod, container string, command []string) (string, string, error) {
+	e.runs++
+	return "migrated", "", nil
+}
+
+func TestExecutePlan_CompletedTasks(t *testing.T) {
+	meta := &engtask.EngineMetadata{OperatorVersionName: "first-operator-1.0", InstanceName: "db", InstanceNamespace: "default"}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-0", Namespace: "default", Labels: map[string]string{"app": "db", kudo.InstanceLabel: "db"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "db"}}},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	pl := &activePlan{
+		name: "upgrade",
+		PlanStatus: &v1alpha1.PlanStatus{
+			Name:   "upgrade",
+			Status: v1alpha1.ExecutionPending,
+			Phases: []v1alpha1.PhaseStatus{{Name: "main", Status: v1alpha1.ExecutionPending, Steps: []v1alpha1.StepStatus{
+				{Name: "migrate", Status: v1alpha1.ExecutionPending},
+			}}},
+		},
+		spec: &v1alpha1.Plan{
+			Strategy: "serial",
+			Phases: []v1alpha1.Phase{
+				{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "migrate", Tasks: []string{"migrate", "wait"}}}},
+			},
+		},
+		tasks: []v1alpha1.Task{
+			{Name: "migrate", Kind: "Exec", Spec: v1alpha1.TaskSpec{ExecTaskSpec: v1alpha1.ExecTaskSpec{PodSelector: map[string]string{"app": "db"}, Command: []string{"migrate"}}}},
+			{Name: "wait", Kind: "Dummy", Spec: v1alpha1.TaskSpec{DummyTaskSpec: v1alpha1.DummyTaskSpec{Done: false}}},
+		},
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, pod)
+	executor := &countingExecutor{}
+
+	for i := 0; i < 3; i++ {
+		newStatus, err := executePlan(context.TODO(), pl, meta, c, nil, &testKubernetesObjectEnhancer{}, executor, time.Now())
+		if err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+		pl.PlanStatus = newStatus
+	}
+
+	step := pl.PlanStatus.Phases[0].Steps[0]
+	if executor.runs != 1 {
+		t.Errorf("expected the command to run once while the step is in progress but it ran %d times", executor.runs)
+	}
+	if !reflect.DeepEqual(step.CompletedTasks, []string{"migrate"}) {
+		t.Errorf("expected the exec task to be completed but got %v", step.CompletedTasks)
+	}
+	if len(step.Resources) != 1 || step.Resources[0].Task != "migrate" || step.Resources[0].Message != "migrated" {
+		t.Errorf("expected the output of the completed task to be kept but got %v", step.Resources)
+	}
+}
+
+func TestNextDeadline(t *testing.T) {
+	timeNow := time.Now()
+	spec := &v1alpha1.Plan{
+		Timeout: &v1.Duration{Duration: time.Hour},
+		Phases: []v1alpha1.Phase{
+			{Name: "done", Timeout: &v1.Duration{Duration: time.Minute}, Steps: []v1alpha1.Step{{Name: "step"}}},
+			{Name: "running", Steps: []v1alpha1.Step{{Name: "step", Timeout: &v1.Duration{Duration: 10 * time.Minute}}}},
+		},
+	}
+	status := &v1alpha1.PlanStatus{
+		Status:    v1alpha1.ExecutionInProgress,
+		StartedAt: v1.Time{Time: timeNow.Add(-5 * time.Minute)},
+		Phases: []v1alpha1.PhaseStatus{
+			{Name: "done", Status: v1alpha1.ExecutionComplete, StartedAt: v1.Time{Time: timeNow.Add(-5 * time.Minute)}},
+			{Name: "running", Status: v1alpha1.ExecutionInProgress, StartedAt: v1.Time{Time: timeNow.Add(-2 * time.Minute)}, Steps: []v1alpha1.StepStatus{
+				{Name: "step", Status: v1alpha1.ErrorStatus, StartedAt: v1.Time{Time: timeNow.Add(-2 * time.Minute)}},
+			}},
+		},
+	}
+
+	deadline, ok := nextDeadline(spec, status, timeNow)
+	if !ok || deadline != 8*time.Minute {
+		t.Errorf("expected next deadline in 8m but got %v (%v)", deadline, ok)
+	}
+
+	status.Status = v1alpha1.ExecutionComplete
+	if _, ok := nextDeadline(spec, status, timeNow); ok {
+		t.Errorf("expected no deadline for a finished plan")
+	}
+}
+
 func instance() *v1alpha1.Instance {
 	return &v1alpha1.Instance{
 		TypeMeta: metav1.TypeMeta{
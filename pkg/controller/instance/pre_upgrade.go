@@ -0,0 +1,87 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// preUpgradeCheck executes the pre-upgrade checks of the OperatorVersion the instance is going to be upgraded to and
+// reports their results. The instance and its objects are not changed.
+func preUpgradeCheck(instance *kudov1alpha1.Instance, current *kudov1alpha1.OperatorVersion, next string, c client.Reader, currentTime time.Time) *kudov1alpha1.PreUpgradeCheckStatus {
+	report := &kudov1alpha1.PreUpgradeCheckStatus{OperatorVersion: next, CompletedAt: metav1.NewTime(currentTime)}
+
+	ov := &kudov1alpha1.OperatorVersion{}
+	err := c.Get(context.TODO(), types.NamespacedName{Name: next, Namespace: instance.OperatorVersionNamespace()}, ov)
+	if err != nil {
+		report.Message = fmt.Sprintf("failed to get operatorversion %s: %v", next, err)
+		return report
+	}
+
+	for _, check := range ov.Spec.PreUpgradeChecks {
+		result := kudov1alpha1.UpgradeCheckResult{Name: check.Name}
+		if err := check.Validate(); err != nil {
+			result.Message = err.Error()
+		} else {
+			result.Passed, result.Message = runUpgradeCheck(check, instance, current, ov, c)
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// runUpgradeCheck executes a single valid pre-upgrade check
+func runUpgradeCheck(check kudov1alpha1.UpgradeCheck, instance *kudov1alpha1.Instance, current, next *kudov1alpha1.OperatorVersion, c client.Reader) (bool, string) {
+	if check.VersionGap != nil {
+		if err := check.VersionGap.CheckVersionGap(current.Spec.Version, next.Spec.Version); err != nil {
+			return false, err.Error()
+		}
+		return true, fmt.Sprintf("version %s can be upgraded to %s directly", current.Spec.Version, next.Spec.Version)
+	}
+	return checkHealthyReplicas(check.HealthyReplicas, instance, c)
+}
+
+// checkHealthyReplicas checks that the workload of the instance has enough ready replicas
+func checkHealthyReplicas(check *kudov1alpha1.HealthyReplicasCheck, instance *kudov1alpha1.Instance, c client.Reader) (bool, string) {
+	key := types.NamespacedName{Name: fmt.Sprintf("%s-%s", instance.Name, check.Name), Namespace: instance.Namespace}
+
+	var desired *int32
+	var ready int32
+	var err error
+	switch check.Kind {
+	case "Deployment":
+		d := &appsv1.Deployment{}
+		err = c.Get(context.TODO(), key, d)
+		desired, ready = d.Spec.Replicas, d.Status.ReadyReplicas
+	case "StatefulSet":
+		s := &appsv1.StatefulSet{}
+		err = c.Get(context.TODO(), key, s)
+		desired, ready = s.Spec.Replicas, s.Status.ReadyReplicas
+	}
+	switch {
+	case apierrors.IsNotFound(err):
+		return false, fmt.Sprintf("%s %s does not exist", check.Kind, key.Name)
+	case err != nil:
+		return false, fmt.Sprintf("failed to get %s %s: %v", check.Kind, key.Name, err)
+	}
+
+	replicas := 1
+	if desired != nil {
+		replicas = int(*desired)
+	}
+	minReady, err := intstr.GetValueFromIntOrPercent(&check.MinReady, replicas, true)
+	if err != nil {
+		return false, fmt.Sprintf("invalid minimum of ready replicas %s: %v", check.MinReady.String(), err)
+	}
+	message := fmt.Sprintf("%d of %d replicas of %s %s are ready, at least %d are required", ready, replicas, check.Kind, key.Name, minReady)
+	return int(ready) >= minReady, message
+}
@@ -0,0 +1,65 @@
+package instance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPreUpgradeCheck(t *testing.T) {
+	maxMajor := int64(0)
+	current := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator", Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Version: "1.0.0"},
+	}
+	next := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "first-operator-2.0.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Version: "2.0.0",
+			PreUpgradeChecks: []v1alpha1.UpgradeCheck{
+				{Name: "healthy-brokers", HealthyReplicas: &v1alpha1.HealthyReplicasCheck{Kind: "StatefulSet", Name: "broker", MinReady: intstr.FromString("50%")}},
+				{Name: "healthy-gateway", HealthyReplicas: &v1alpha1.HealthyReplicasCheck{Kind: "Deployment", Name: "gateway", MinReady: intstr.FromInt(1)}},
+				{Name: "version-gap", VersionGap: &v1alpha1.VersionGapCheck{MaxMajor: &maxMajor}},
+				{Name: "invalid"},
+			},
+		},
+	}
+	replicas := int32(3)
+	broker := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-instance-broker", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+		Status:     appsv1.StatefulSetStatus{ReadyReplicas: 2},
+	}
+
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewFakeClientWithScheme(s, next, broker)
+
+	report := preUpgradeCheck(instance(), current, "first-operator-2.0.0", c, time.Now())
+	assert.Equal(t, "", report.Message)
+	assert.Equal(t, []v1alpha1.UpgradeCheckResult{
+		{Name: "healthy-brokers", Passed: true, Message: "2 of 3 replicas of StatefulSet test-instance-broker are ready, at least 2 are required"},
+		{Name: "healthy-gateway", Passed: false, Message: "Deployment test-instance-gateway does not exist"},
+		{Name: "version-gap", Passed: false, Message: "upgrading from 1.0.0 to 2.0.0 advances 1 major versions, at most 0 are supported"},
+		{Name: "invalid", Passed: false, Message: "pre-upgrade check invalid has to declare exactly one of versionGap and healthyReplicas"},
+	}, report.Results)
+	assert.False(t, report.Passed())
+
+	report = preUpgradeCheck(instance(), current, "missing", c, time.Now())
+	assert.Contains(t, report.Message, "failed to get operatorversion missing")
+	assert.False(t, report.Passed())
+}
@@ -0,0 +1,121 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// queuedPlanRetryInterval is the interval at which an instance whose plan is queued checks again whether it can start
+const queuedPlanRetryInterval = 10 * time.Second
+
+// planScheduler limits the number of plans in progress per namespace and per operator as configured by the
+// KudoConfig, e.g. so that a sync touching hundreds of instances does not restart all of them at the same time.
+//
+// Plans in progress are counted from the instances in the cache and from the plans the scheduler admitted itself, as
+// the cache does not contain the status of a plan started a moment ago yet. The instances of an operator are looked up
+// by the OperatorIndex. A plan that would exceed a limit is not started, the instance is reconciled again after
+// queuedPlanRetryInterval instead.
+type planScheduler struct {
+	client client.Reader
+
+	mu sync.Mutex
+	// running are the plans in progress the scheduler knows of by their instance
+	running map[types.NamespacedName]scheduledPlan
+}
+
+type scheduledPlan struct {
+	namespace string
+	operator  string
+}
+
+func newPlanScheduler(c client.Reader) *planScheduler {
+	return &planScheduler{client: c, running: map[types.NamespacedName]scheduledPlan{}}
+}
+
+// admit returns whether the instance can start a plan, and why not if it can't. An instance with a plan in progress is
+// always admitted, a new plan replacing the one in progress does not change the number of plans in progress. Without
+// scheduler all plans are admitted.
+func (s *planScheduler) admit(instance *kudov1alpha1.Instance, operator string) (bool, string, error) {
+	if s == nil {
+		return true, "", nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+	plan := scheduledPlan{namespace: instance.Namespace, operator: operator}
+	if _, ok := s.running[key]; ok || instance.GetPlanInProgress() != nil {
+		s.running[key] = plan
+		return true, "", nil
+	}
+
+	limits, err := s.limits()
+	if err != nil {
+		return false, "", err
+	}
+
+	if limit := limits.NamespaceLimit(plan.namespace); limit > 0 {
+		running, err := s.countRunning(func(p scheduledPlan) bool { return p.namespace == plan.namespace }, client.InNamespace(plan.namespace))
+		if err != nil {
+			return false, "", err
+		}
+		if running >= limit {
+			return false, fmt.Sprintf("%d plans are in progress in namespace %s, the limit is %d", running, plan.namespace, limit), nil
+		}
+	}
+	if limit := limits.OperatorLimit(plan.operator); limit > 0 && plan.operator != "" {
+		running, err := s.countRunning(func(p scheduledPlan) bool { return p.operator == plan.operator }, client.MatchingFields{OperatorIndex: plan.operator})
+		if err != nil {
+			return false, "", err
+		}
+		if running >= limit {
+			return false, fmt.Sprintf("%d plans of operator %s are in progress, the limit is %d", running, plan.operator, limit), nil
+		}
+	}
+
+	s.running[key] = plan
+	return true, "", nil
+}
+
+// done releases the place of the instance once it has no plan in progress anymore
+func (s *planScheduler) done(key types.NamespacedName) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, key)
+}
+
+// limits returns the limits of the KudoConfig, there are no limits if it does not exist
+func (s *planScheduler) limits() (kudov1alpha1.ConcurrencyLimits, error) {
+	config, err := kudoConfig(s.client)
+	return config.Concurrency, err
+}
+
+// countRunning counts the plans in progress of the listed instances and the admitted plans matching the filter
+func (s *planScheduler) countRunning(match func(scheduledPlan) bool, opts ...client.ListOption) (int, error) {
+	instances := &kudov1alpha1.InstanceList{}
+	if err := s.client.List(context.TODO(), instances, opts...); err != nil {
+		return 0, fmt.Errorf("failed to list instances with plans in progress: %w", err)
+	}
+	running := map[types.NamespacedName]bool{}
+	for _, i := range instances.Items {
+		if i.GetPlanInProgress() != nil {
+			running[types.NamespacedName{Namespace: i.Namespace, Name: i.Name}] = true
+		}
+	}
+	for key, p := range s.running {
+		if match(p) {
+			running[key] = true
+		}
+	}
+	return len(running), nil
+}
@@ -0,0 +1,89 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func schedulerInstance(namespace, name, operator string, running bool) *v1alpha1.Instance {
+	i := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{
+		Name:      name,
+		Namespace: namespace,
+		Labels:    map[string]string{kudo.OperatorLabel: operator},
+	}}
+	if running {
+		i.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionInProgress}}
+	}
+	return i
+}
+
+func TestPlanScheduler_Admit(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	config := &v1alpha1.KudoConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: v1alpha1.KudoConfigName},
+		Spec: v1alpha1.KudoConfigSpec{Concurrency: v1alpha1.ConcurrencyLimits{
+			PerNamespace: 2,
+			Namespaces:   map[string]int{"unlimited": 0},
+			Operators:    map[string]int{"kafka": 1},
+		}},
+	}
+	c := fake.NewFakeClientWithScheme(s, config,
+		schedulerInstance("default", "running", "zookeeper", true),
+		schedulerInstance("other", "kafka-running", "kafka", true),
+		schedulerInstance("unlimited", "running-1", "zookeeper", true),
+		schedulerInstance("unlimited", "running-2", "zookeeper", true),
+	)
+	scheduler := newPlanScheduler(c)
+
+	admit := func(i *v1alpha1.Instance, operator string) bool {
+		admitted, reason, err := scheduler.admit(i, operator)
+		assert.NoError(t, err)
+		assert.Equal(t, admitted, reason == "", "expected a reason only for queued plans")
+		return admitted
+	}
+
+	// one plan of the namespace is in progress, the second one is admitted, the third one has to wait
+	assert.True(t, admit(schedulerInstance("default", "zk-1", "zookeeper", false), "zookeeper"))
+	assert.False(t, admit(schedulerInstance("default", "zk-2", "zookeeper", false), "zookeeper"))
+
+	// the place of an admitted plan is released once it is done
+	scheduler.done(types.NamespacedName{Namespace: "default", Name: "zk-1"})
+	assert.True(t, admit(schedulerInstance("default", "zk-2", "zookeeper", false), "zookeeper"))
+
+	// a new plan of an instance with a plan in progress does not need another place
+	assert.True(t, admit(schedulerInstance("default", "running", "zookeeper", true), "zookeeper"))
+
+	// namespace overrides
+	assert.True(t, admit(schedulerInstance("unlimited", "zk", "zookeeper", false), "zookeeper"))
+
+	// operator limits apply across namespaces
+	assert.False(t, admit(schedulerInstance("unlimited", "kafka", "kafka", false), "kafka"))
+}
+
+func TestPlanScheduler_NoConfig(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	scheduler := newPlanScheduler(fake.NewFakeClientWithScheme(s, schedulerInstance("default", "running", "zookeeper", true)))
+
+	admitted, _, err := scheduler.admit(schedulerInstance("default", "zk", "zookeeper", false), "zookeeper")
+	assert.NoError(t, err)
+	assert.True(t, admitted)
+
+	// without scheduler every plan is admitted
+	var none *planScheduler
+	admitted, _, err = none.admit(schedulerInstance("default", "zk", "zookeeper", false), "zookeeper")
+	assert.NoError(t, err)
+	assert.True(t, admitted)
+}
@@ -0,0 +1,96 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// serviceAccounts connects to the cluster of the manager impersonating the ServiceAccounts of instances. The
+// connections are cached for the lifetime of the manager. The zero value is ready to use.
+type serviceAccounts struct {
+	// connect creates the client and the executor of a config, connectConfig is used if nil
+	connect func(config *rest.Config, scheme *runtime.Scheme) (client.Client, task.PodExecutor, error)
+
+	mu          sync.Mutex
+	connections map[string]accountConnection
+}
+
+type accountConnection struct {
+	client   client.Client
+	executor task.PodExecutor
+}
+
+// get returns the client and the executor impersonating the ServiceAccount in the namespace. Every request is made
+// with config impersonating the ServiceAccount, the API server adds the groups of ServiceAccounts. A missing
+// ServiceAccount is returned as ExecutionError which is retried, as it may be created later.
+func (s *serviceAccounts) get(c client.Client, config *rest.Config, scheme *runtime.Scheme, namespace, name string) (client.Client, task.PodExecutor, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	if config == nil {
+		return nil, nil, invalidServiceAccount(fmt.Errorf("can not impersonate serviceaccount %s, the manager has no client configuration", key))
+	}
+	if err := c.Get(context.TODO(), key, &corev1.ServiceAccount{}); err != nil {
+		return nil, nil, invalidServiceAccount(fmt.Errorf("failed to get serviceaccount %s: %v", key, err))
+	}
+
+	user := serviceAccountUser(namespace, name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if conn, ok := s.connections[user]; ok {
+		return conn.client, conn.executor, nil
+	}
+	impersonating := rest.CopyConfig(config)
+	impersonating.Impersonate = rest.ImpersonationConfig{UserName: user}
+	connect := s.connect
+	if connect == nil {
+		connect = connectConfig
+	}
+	accountClient, executor, err := connect(impersonating, scheme)
+	if err != nil {
+		return nil, nil, invalidServiceAccount(fmt.Errorf("failed to connect impersonating serviceaccount %s: %v", key, err))
+	}
+	if s.connections == nil {
+		s.connections = map[string]accountConnection{}
+	}
+	s.connections[user] = accountConnection{client: accountClient, executor: executor}
+	return accountClient, executor, nil
+}
+
+// serviceAccountUser returns the name of the user the API server authenticates the ServiceAccount as
+func serviceAccountUser(namespace, name string) string {
+	return fmt.Sprintf("system:serviceaccount:%s:%s", namespace, name)
+}
+
+func invalidServiceAccount(err error) error {
+	return &ExecutionError{Err: err, Fatal: false, EventName: kudo.String("InvalidServiceAccount")}
+}
+
+// resourceTarget returns the client and the executor the resources of the instance are applied with. These are c and
+// executor of the cluster of the manager, unless the instance targets a ClusterTarget or names a ServiceAccount to
+// impersonate. A ServiceAccount can not be impersonated in the cluster of a ClusterTarget, the permissions there are
+// bounded by its kubeconfig.
+func resourceTarget(instance *kudov1alpha1.Instance, c client.Client, executor task.PodExecutor, config *rest.Config, scheme *runtime.Scheme, targets *clusterTargets, accounts *serviceAccounts) (client.Client, task.PodExecutor, error) {
+	switch {
+	case instance.Spec.ClusterTarget != "" && instance.Spec.ServiceAccountName != "":
+		return nil, nil, &ExecutionError{
+			Err:       fmt.Errorf("instance %s/%s can not impersonate a serviceaccount in the cluster of clustertarget %s", instance.Namespace, instance.Name, instance.Spec.ClusterTarget),
+			Fatal:     true,
+			EventName: kudo.String("InvalidServiceAccount"),
+		}
+	case instance.Spec.ClusterTarget != "":
+		return targets.get(c, scheme, instance.Namespace, instance.Spec.ClusterTarget)
+	case instance.Spec.ServiceAccountName != "":
+		return accounts.get(c, config, scheme, instance.Namespace, instance.Spec.ServiceAccountName)
+	}
+	return c, executor, nil
+}
@@ -0,0 +1,80 @@
+package instance
+
+import (
+	"errors"
+	"testing"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestServiceAccounts_Get(t *testing.T) {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "kafka-deployer", Namespace: "team-a"}}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, sa)
+	config := &rest.Config{Host: "https://kubernetes.default.svc"}
+
+	var users []string
+	impersonating := fake.NewFakeClientWithScheme(scheme.Scheme)
+	accounts := serviceAccounts{
+		connect: func(config *rest.Config, _ *runtime.Scheme) (client.Client, task.PodExecutor, error) {
+			users = append(users, config.Impersonate.UserName)
+			return impersonating, nil, nil
+		},
+	}
+
+	got, _, err := accounts.get(c, config, scheme.Scheme, "team-a", "kafka-deployer")
+	assert.NoError(t, err)
+	assert.Equal(t, impersonating, got)
+	_, _, err = accounts.get(c, config, scheme.Scheme, "team-a", "kafka-deployer")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"system:serviceaccount:team-a:kafka-deployer"}, users, "the connection is cached")
+	assert.Empty(t, config.Impersonate.UserName, "the config of the manager is not changed")
+
+	_, _, err = accounts.get(c, config, scheme.Scheme, "team-b", "kafka-deployer")
+	var exErr *ExecutionError
+	assert.True(t, errors.As(err, &exErr))
+	assert.False(t, exErr.Fatal, "a missing serviceaccount is retried")
+
+	_, _, err = accounts.get(c, nil, scheme.Scheme, "team-a", "kafka-deployer")
+	assert.Error(t, err, "a serviceaccount can not be impersonated without a config")
+}
+
+func TestResourceTarget(t *testing.T) {
+	sa := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "kafka-deployer", Namespace: "team-a"}}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, sa)
+	impersonating := fake.NewFakeClientWithScheme(scheme.Scheme)
+	accounts := &serviceAccounts{
+		connect: func(*rest.Config, *runtime.Scheme) (client.Client, task.PodExecutor, error) {
+			return impersonating, nil, nil
+		},
+	}
+	instance := func(clusterTarget, serviceAccount string) *kudov1alpha1.Instance {
+		return &kudov1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "team-a"},
+			Spec:       kudov1alpha1.InstanceSpec{ClusterTarget: clusterTarget, ServiceAccountName: serviceAccount},
+		}
+	}
+	config := &rest.Config{}
+
+	got, _, err := resourceTarget(instance("", ""), c, nil, config, scheme.Scheme, &clusterTargets{}, accounts)
+	assert.NoError(t, err)
+	assert.Equal(t, c, got, "resources are applied with the client of the manager")
+
+	got, _, err = resourceTarget(instance("", "kafka-deployer"), c, nil, config, scheme.Scheme, &clusterTargets{}, accounts)
+	assert.NoError(t, err)
+	assert.Equal(t, impersonating, got, "resources are applied impersonating the serviceaccount")
+
+	_, _, err = resourceTarget(instance("edge", "kafka-deployer"), c, nil, config, scheme.Scheme, &clusterTargets{}, accounts)
+	var exErr *ExecutionError
+	assert.True(t, errors.As(err, &exErr))
+	assert.True(t, exErr.Fatal, "a serviceaccount can not be impersonated in another cluster")
+}
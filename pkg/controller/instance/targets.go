@@ -0,0 +1,266 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/apiwarnings"
+	"github.com/kudobuilder/kudo/pkg/util/exec"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// clusterTargetPollInterval is the interval at which an instance executing a plan in another cluster is reconciled, as
+// changes of the objects in that cluster are not watched
+const clusterTargetPollInterval = 10 * time.Second
+
+// clusterTargetFinalizer is the finalizer of instances targeting a ClusterTarget. The objects in the other cluster can
+// not be owned by the instance, so they are deleted by the instance controller before the finalizer is removed.
+const clusterTargetFinalizer = "kudo.dev/cluster-target-cleanup"
+
+// clusterTargets connects to the clusters of ClusterTargets. The connections are cached and only established again
+// once the kubeconfig secret changed, they are evicted when an instance targeting the ClusterTarget is finalized. The
+// zero value is ready to use.
+type clusterTargets struct {
+	// connect creates the client and the executor of the cluster of a kubeconfig, connectKubeconfig is used if nil
+	connect func(kubeconfig []byte, scheme *runtime.Scheme) (client.Client, task.PodExecutor, error)
+
+	mu          sync.Mutex
+	connections map[types.NamespacedName]clusterConnection
+}
+
+type clusterConnection struct {
+	// source is the version of the kubeconfig the connection was created from
+	source   kubeconfigSource
+	client   client.Client
+	executor task.PodExecutor
+}
+
+// kubeconfigSource identifies the version of a kubeconfig read from a secret
+type kubeconfigSource struct {
+	secret          string
+	key             string
+	resourceVersion string
+}
+
+// get returns the client and the executor of the cluster of the ClusterTarget in the namespace. It is read with c,
+// the client of the management cluster. A missing or invalid ClusterTarget is returned as ExecutionError which is
+// retried, as the ClusterTarget or its secret may be created or fixed later.
+func (t *clusterTargets) get(c client.Client, scheme *runtime.Scheme, namespace, name string) (client.Client, task.PodExecutor, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: name}
+	target := &kudov1alpha1.ClusterTarget{}
+	if err := c.Get(context.TODO(), key, target); err != nil {
+		return nil, nil, invalidClusterTarget(fmt.Errorf("failed to get clustertarget %s: %v", key, err))
+	}
+	secretKey := types.NamespacedName{Namespace: namespace, Name: target.Spec.KubeconfigSecret.Name}
+	secret := &corev1.Secret{}
+	if err := c.Get(context.TODO(), secretKey, secret); err != nil {
+		return nil, nil, invalidClusterTarget(fmt.Errorf("failed to get the kubeconfig secret of clustertarget %s: %v", key, err))
+	}
+	dataKey := target.Spec.KubeconfigSecret.KeyOrDefault(kudov1alpha1.DefaultKubeconfigKey)
+	kubeconfig, ok := secret.Data[dataKey]
+	if !ok {
+		return nil, nil, invalidClusterTarget(fmt.Errorf("secret %s of clustertarget %s has no key %s", secretKey, key, dataKey))
+	}
+
+	source := kubeconfigSource{secret: secretKey.Name, key: dataKey, resourceVersion: secret.ResourceVersion}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if conn, ok := t.connections[key]; ok && conn.source == source {
+		return conn.client, conn.executor, nil
+	}
+	connect := t.connect
+	if connect == nil {
+		connect = connectKubeconfig
+	}
+	targetClient, executor, err := connect(kubeconfig, scheme)
+	if err != nil {
+		return nil, nil, invalidClusterTarget(fmt.Errorf("failed to connect to the cluster of clustertarget %s: %v", key, err))
+	}
+	if t.connections == nil {
+		t.connections = map[types.NamespacedName]clusterConnection{}
+	}
+	t.connections[key] = clusterConnection{source: source, client: targetClient, executor: executor}
+	return targetClient, executor, nil
+}
+
+// evict removes the cached connection to the cluster of the ClusterTarget in the namespace
+func (t *clusterTargets) evict(namespace, name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.connections, types.NamespacedName{Namespace: namespace, Name: name})
+}
+
+// connectKubeconfig creates a client and an executor for the current context of the kubeconfig
+func connectKubeconfig(kubeconfig []byte, scheme *runtime.Scheme) (client.Client, task.PodExecutor, error) {
+	raw, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := validateKubeconfig(raw); err != nil {
+		return nil, nil, err
+	}
+	config, err := clientcmd.NewDefaultClientConfig(*raw, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	apiwarnings.WrapConfig(config)
+	return connectConfig(config, scheme)
+}
+
+// validateKubeconfig makes sure that the kubeconfig only holds inline credentials. The kubeconfig is read from a secret
+// of the tenant but used by the manager, so exec plugins and auth providers would run in the manager and files would
+// be read from the manager, e.g. its own service account token.
+func validateKubeconfig(config *clientcmdapi.Config) error {
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return fmt.Errorf("cluster %s references the file %s, only certificate-authority-data is supported", name, cluster.CertificateAuthority)
+		}
+	}
+	for name, user := range config.AuthInfos {
+		switch {
+		case user.Exec != nil:
+			return fmt.Errorf("user %s uses an exec credential plugin, only inline tokens and certificates are supported", name)
+		case user.AuthProvider != nil:
+			return fmt.Errorf("user %s uses the auth provider %s, only inline tokens and certificates are supported", name, user.AuthProvider.Name)
+		case user.TokenFile != "":
+			return fmt.Errorf("user %s references the token file %s, only inline tokens are supported", name, user.TokenFile)
+		case user.ClientCertificate != "" || user.ClientKey != "":
+			return fmt.Errorf("user %s references a client certificate or key file, only client-certificate-data and client-key-data are supported", name)
+		}
+	}
+	return nil
+}
+
+// connectConfig creates a client and an executor for the config
+func connectConfig(config *rest.Config, scheme *runtime.Scheme) (client.Client, task.PodExecutor, error) {
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, exec.NewExecutor(config), nil
+}
+
+func invalidClusterTarget(err error) error {
+	return &ExecutionError{Err: err, Fatal: false, EventName: kudo.String("InvalidClusterTarget")}
+}
+
+// finalize deletes the objects of a deleted instance in the cluster of its ClusterTarget and removes the finalizer. The
+// objects are left behind if the ClusterTarget does not exist anymore, as the cluster can not be reached without it.
+// The cached connection to the cluster is evicted, it is established again by the next instance targeting it.
+func (r *Reconciler) finalize(instance *kudov1alpha1.Instance) error {
+	if !hasFinalizer(instance, clusterTargetFinalizer) {
+		return nil
+	}
+	if instance.Spec.ClusterTarget != "" {
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Spec.ClusterTarget}
+		err := r.Client.Get(context.TODO(), key, &kudov1alpha1.ClusterTarget{})
+		switch {
+		case apierrors.IsNotFound(err):
+			log.Printf("InstanceController: ClusterTarget %s of deleted instance %s/%s does not exist, its objects in that cluster are not deleted", key, instance.Namespace, instance.Name)
+		case err != nil:
+			return err
+		default:
+			targetClient, _, err := r.targets.get(r.Client, r.Scheme, instance.Namespace, instance.Spec.ClusterTarget)
+			if err != nil {
+				return err
+			}
+			if err := deleteTargetResources(targetClient, r.Scheme, instance); err != nil {
+				return err
+			}
+			log.Printf("InstanceController: Deleted the objects of instance %s/%s in the cluster of ClusterTarget %s", instance.Namespace, instance.Name, key)
+		}
+		r.targets.evict(instance.Namespace, instance.Spec.ClusterTarget)
+	}
+	removeFinalizer(instance, clusterTargetFinalizer)
+	return r.Client.Update(context.TODO(), instance)
+}
+
+// hasFinalizer returns whether the finalizer is set on the instance
+func hasFinalizer(instance *kudov1alpha1.Instance, finalizer string) bool {
+	for _, f := range instance.Finalizers {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFinalizer removes the finalizer from the instance
+func removeFinalizer(instance *kudov1alpha1.Instance, finalizer string) {
+	finalizers := instance.Finalizers[:0]
+	for _, f := range instance.Finalizers {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	instance.Finalizers = finalizers
+}
+
+// deleteTargetResources deletes the objects of the instance in the cluster of its ClusterTarget with c, the client of
+// that cluster. The kinds and namespaces of the objects are taken from the resources recorded in the plan status, the
+// objects themselves are found by the labels of the instance, so that objects applied by earlier executions of a plan
+// are deleted as well. Kinds known to the scheme are listed typed, all others as unstructured objects.
+func deleteTargetResources(c client.Client, scheme *runtime.Scheme, instance *kudov1alpha1.Instance) error {
+	type location struct {
+		apiVersion, kind, namespace string
+	}
+	locations := map[location]bool{}
+	for _, plan := range instance.Status.PlanStatus {
+		for _, phase := range plan.Phases {
+			for _, step := range phase.Steps {
+				for _, r := range step.Resources {
+					locations[location{r.APIVersion, r.Kind, r.Namespace}] = true
+				}
+			}
+		}
+	}
+
+	labels := client.MatchingLabels{kudo.InstanceLabel: instance.Name, kudo.HeritageLabel: "kudo"}
+	for l := range locations {
+		listGVK := schema.FromAPIVersionAndKind(l.apiVersion, l.kind+"List")
+		list, err := scheme.New(listGVK)
+		if err != nil {
+			u := &unstructured.UnstructuredList{}
+			u.SetGroupVersionKind(listGVK)
+			list = u
+		}
+		if err := c.List(context.TODO(), list, client.InNamespace(l.namespace), labels); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to list %s of instance %s/%s: %v", l.kind, instance.Namespace, instance.Name, err)
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		for _, obj := range items {
+			if err := c.Delete(context.TODO(), obj, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil && !apierrors.IsNotFound(err) {
+				name := ""
+				if m, mErr := meta.Accessor(obj); mErr == nil {
+					name = m.GetName()
+				}
+				return fmt.Errorf("failed to delete %s %s of instance %s/%s: %v", l.kind, name, instance.Namespace, instance.Name, err)
+			}
+		}
+	}
+	return nil
+}
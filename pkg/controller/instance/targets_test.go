@@ -0,0 +1,170 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterTargets_Get(t *testing.T) {
+	assert.NoError(t, kudov1alpha1.AddToScheme(scheme.Scheme))
+	target := &kudov1alpha1.ClusterTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec:       kudov1alpha1.ClusterTargetSpec{KubeconfigSecret: kudov1alpha1.SecretKeyReference{Name: "edge-kubeconfig"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-kubeconfig", Namespace: "default", ResourceVersion: "1"},
+		Data:       map[string][]byte{"kubeconfig": []byte("edge")},
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, target, secret)
+
+	var connected []string
+	remote := fake.NewFakeClientWithScheme(scheme.Scheme)
+	targets := clusterTargets{
+		connect: func(kubeconfig []byte, _ *runtime.Scheme) (client.Client, task.PodExecutor, error) {
+			connected = append(connected, string(kubeconfig))
+			return remote, nil, nil
+		},
+	}
+
+	got, _, err := targets.get(c, scheme.Scheme, "default", "edge")
+	assert.NoError(t, err)
+	assert.Equal(t, remote, got)
+	_, _, err = targets.get(c, scheme.Scheme, "default", "edge")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"edge"}, connected, "the connection is cached")
+
+	secret.Data["kubeconfig"] = []byte("edge-rotated")
+	secret.ResourceVersion = "2"
+	assert.NoError(t, c.Update(context.TODO(), secret))
+	_, _, err = targets.get(c, scheme.Scheme, "default", "edge")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"edge", "edge-rotated"}, connected, "a changed secret connects again")
+
+	targets.evict("default", "edge")
+	_, _, err = targets.get(c, scheme.Scheme, "default", "edge")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"edge", "edge-rotated", "edge-rotated"}, connected, "an evicted connection connects again")
+
+	_, _, err = targets.get(c, scheme.Scheme, "default", "missing")
+	var exErr *ExecutionError
+	assert.True(t, errors.As(err, &exErr))
+	assert.False(t, exErr.Fatal, "a missing clustertarget is retried")
+
+	target.Spec.KubeconfigSecret.Key = "config"
+	assert.NoError(t, c.Update(context.TODO(), target))
+	_, _, err = targets.get(c, scheme.Scheme, "default", "edge")
+	assert.EqualError(t, err, "Error during execution: secret default/edge-kubeconfig of clustertarget default/edge has no key config")
+}
+
+func TestValidateKubeconfig(t *testing.T) {
+	kubeconfig := func(cluster, user string) []byte {
+		return []byte(`apiVersion: v1
+kind: Config
+current-context: edge
+contexts:
+- name: edge
+  context:
+    cluster: edge
+    user: edge
+clusters:
+- name: edge
+  cluster:
+    server: https://edge.example.com
+` + cluster + `
+users:
+- name: edge
+  user:
+` + user)
+	}
+	tests := []struct {
+		name    string
+		cluster string
+		user    string
+		err     string
+	}{
+		{name: "inline credentials", cluster: "    certificate-authority-data: Y2E=", user: "    token: secret"},
+		{name: "exec plugin", user: "    exec:\n      apiVersion: client.authentication.k8s.io/v1beta1\n      command: sh", err: "user edge uses an exec credential plugin, only inline tokens and certificates are supported"},
+		{name: "auth provider", user: "    auth-provider:\n      name: gcp", err: "user edge uses the auth provider gcp, only inline tokens and certificates are supported"},
+		{name: "token file", user: "    tokenFile: /var/run/secrets/kubernetes.io/serviceaccount/token", err: "user edge references the token file /var/run/secrets/kubernetes.io/serviceaccount/token, only inline tokens are supported"},
+		{name: "client key file", user: "    client-key: /etc/kudo/key.pem", err: "user edge references a client certificate or key file, only client-certificate-data and client-key-data are supported"},
+		{name: "certificate authority file", cluster: "    certificate-authority: /var/run/secrets/kubernetes.io/serviceaccount/ca.crt", user: "    token: secret", err: "cluster edge references the file /var/run/secrets/kubernetes.io/serviceaccount/ca.crt, only certificate-authority-data is supported"},
+	}
+
+	for _, tt := range tests {
+		config, err := clientcmd.Load(kubeconfig(tt.cluster, tt.user))
+		assert.NoError(t, err, tt.name)
+		err = validateKubeconfig(config)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.name)
+		}
+	}
+}
+
+func TestReconciler_Finalize(t *testing.T) {
+	assert.NoError(t, kudov1alpha1.AddToScheme(scheme.Scheme))
+	now := metav1.Now()
+	instance := &kudov1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default", DeletionTimestamp: &now, Finalizers: []string{"other", clusterTargetFinalizer}},
+		Spec:       kudov1alpha1.InstanceSpec{ClusterTarget: "edge"},
+		Status: kudov1alpha1.InstanceStatus{PlanStatus: map[string]kudov1alpha1.PlanStatus{
+			"deploy": {Name: "deploy", Phases: []kudov1alpha1.PhaseStatus{{Name: "main", Steps: []kudov1alpha1.StepStatus{{
+				Name:      "config",
+				Resources: []kudov1alpha1.ResourceStatus{{APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "kafka-config"}},
+			}}}}},
+		}},
+	}
+	target := &kudov1alpha1.ClusterTarget{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge", Namespace: "default"},
+		Spec:       kudov1alpha1.ClusterTargetSpec{KubeconfigSecret: kudov1alpha1.SecretKeyReference{Name: "edge-kubeconfig"}},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "edge-kubeconfig", Namespace: "default"},
+		Data:       map[string][]byte{"kubeconfig": []byte("edge")},
+	}
+	configMap := func(name, instance string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{kudo.InstanceLabel: instance, kudo.HeritageLabel: "kudo"},
+		}}
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme, instance, target, secret)
+	remote := fake.NewFakeClientWithScheme(scheme.Scheme, configMap("kafka-config", "kafka"), configMap("kafka-old", "kafka"), configMap("zk-config", "zk"))
+	r := &Reconciler{Client: c, Scheme: scheme.Scheme, targets: clusterTargets{
+		connect: func([]byte, *runtime.Scheme) (client.Client, task.PodExecutor, error) { return remote, nil, nil },
+	}}
+
+	assert.NoError(t, r.finalize(instance))
+	configMaps := &corev1.ConfigMapList{}
+	assert.NoError(t, remote.List(context.TODO(), configMaps))
+	if assert.Equal(t, 1, len(configMaps.Items), "the config maps of the instance are deleted") {
+		assert.Equal(t, "zk-config", configMaps.Items[0].Name)
+	}
+	updated := &kudov1alpha1.Instance{}
+	assert.NoError(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "default", Name: "kafka"}, updated))
+	assert.Equal(t, []string{"other"}, updated.Finalizers)
+	assert.Empty(t, r.targets.connections, "the connection is evicted")
+
+	// the objects can not be deleted without the ClusterTarget, the instance is not blocked by them
+	assert.NoError(t, c.Delete(context.TODO(), target))
+	updated.Finalizers = append(updated.Finalizers, clusterTargetFinalizer)
+	assert.NoError(t, r.finalize(updated))
+	assert.Equal(t, []string{"other"}, updated.Finalizers)
+}
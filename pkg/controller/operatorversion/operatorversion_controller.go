@@ -20,6 +20,7 @@ import (
 	"log"
 
 	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/controller/queue"
 	"k8s.io/apimachinery/pkg/api/errors"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -29,6 +30,7 @@ import (
 // Reconciler reconciles an OperatorVersion object
 type Reconciler struct {
 	client.Client
+	Queue queue.Options
 }
 
 // SetupWithManager registers this reconciler with the controller manager
@@ -36,7 +38,8 @@ func (r *Reconciler) SetupWithManager(
 	mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kudov1alpha1.OperatorVersion{}).
-		Complete(r)
+		WithOptions(r.Queue.ControllerOptions()).
+		Complete(queue.NewReconciler("OperatorVersionController", r, r.Queue))
 }
 
 // Reconcile reads that state of the cluster for an OperatorVersion object and makes changes based on the state read
@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// KeyedLock serializes the work on an object while the work on different objects runs in parallel. The work queue of
+// a controller never reconciles an object in parallel, a KeyedLock extends this to the other components changing the
+// same objects, e.g. a periodic drift detection. Waiters acquire a lock in the order they started to wait, a lock is
+// never taken by TryLock while others wait for it. The zero value is ready to use.
+type KeyedLock struct {
+	mu sync.Mutex
+	// locks holds the locked keys, a key that is not locked has no entry
+	locks map[types.NamespacedName]*keyLock
+}
+
+type keyLock struct {
+	// waiters are closed in order to hand the lock over to the next waiter
+	waiters []chan struct{}
+}
+
+// TryLock locks the key if it is not locked and reports whether it did
+func (l *KeyedLock) TryLock(key types.NamespacedName) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, locked := l.locks[key]; locked {
+		return false
+	}
+	l.lock(key)
+	return true
+}
+
+// Lock locks the key, it blocks until the lock is handed over if the key is locked
+func (l *KeyedLock) Lock(key types.NamespacedName) {
+	l.mu.Lock()
+	kl, locked := l.locks[key]
+	if !locked {
+		l.lock(key)
+		l.mu.Unlock()
+		return
+	}
+	handover := make(chan struct{})
+	kl.waiters = append(kl.waiters, handover)
+	l.mu.Unlock()
+	<-handover
+}
+
+// Unlock unlocks the key, handing the lock over to the longest waiting Lock call. It panics if the key is not locked.
+func (l *KeyedLock) Unlock(key types.NamespacedName) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	kl, locked := l.locks[key]
+	if !locked {
+		panic(fmt.Sprintf("queue: unlock of unlocked key %s", key))
+	}
+	if len(kl.waiters) == 0 {
+		delete(l.locks, key)
+		return
+	}
+	next := kl.waiters[0]
+	kl.waiters = kl.waiters[1:]
+	close(next)
+}
+
+// lock adds the entry of a key that is not locked, l.mu has to be held
+func (l *KeyedLock) lock(key types.NamespacedName) {
+	if l.locks == nil {
+		l.locks = map[types.NamespacedName]*keyLock{}
+	}
+	l.locks[key] = &keyLock{}
+}
@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestKeyedLock(t *testing.T) {
+	var l KeyedLock
+	kafka := types.NamespacedName{Namespace: "default", Name: "kafka"}
+	zk := types.NamespacedName{Namespace: "default", Name: "zk"}
+
+	assert.True(t, l.TryLock(kafka))
+	assert.False(t, l.TryLock(kafka), "a locked key can not be locked again")
+	assert.True(t, l.TryLock(zk), "other keys are not affected")
+	l.Unlock(zk)
+	locked := make(chan struct{})
+	go func() {
+		l.Lock(zk)
+		close(locked)
+	}()
+	select {
+	case <-locked:
+		l.Unlock(zk)
+	case <-time.After(5 * time.Second):
+		t.Fatal("locking another key waited for the locked key")
+	}
+
+	// waiters acquire the lock in the order they started to wait
+	var order []int
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			l.Lock(kafka)
+			order = append(order, i)
+			l.Unlock(kafka)
+		}(i)
+		waitForWaiters(t, &l, kafka, i+1)
+	}
+	assert.False(t, l.TryLock(kafka), "the lock is handed over to the waiters before anybody else")
+	l.Unlock(kafka)
+	wg.Wait()
+	assert.Equal(t, []int{0, 1, 2}, order)
+
+	assert.Empty(t, l.locks, "unlocked keys are dropped")
+	assert.Panics(t, func() { l.Unlock(kafka) })
+}
+
+func waitForWaiters(t *testing.T, l *KeyedLock, key types.NamespacedName, n int) {
+	for i := 0; i < 1000; i++ {
+		l.mu.Lock()
+		waiting := len(l.locks[key].waiters)
+		l.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d waiters of %s", n, key)
+}
+
+func TestKeyedLock_Stress(t *testing.T) {
+	const instances, workers, rounds = 300, 16, 20
+
+	var l KeyedLock
+	holders := make([]int32, instances)
+	counts := make([]int, instances)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for i := 0; i < instances; i++ {
+					// the workers visit the instances in different orders
+					n := (i + w*instances/workers) % instances
+					key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("instance-%d", n)}
+					if w%2 == 0 {
+						l.Lock(key)
+					} else if !l.TryLock(key) {
+						continue
+					}
+					if atomic.AddInt32(&holders[n], 1) != 1 {
+						t.Errorf("instance %d is changed by two workers at the same time", n)
+					}
+					counts[n]++
+					atomic.AddInt32(&holders[n], -1)
+					l.Unlock(key)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for n, c := range counts {
+		if c < rounds*workers/2 {
+			t.Errorf("instance %d was only changed %d times, the workers locking it have to change it at least %d times", n, c, rounds*workers/2)
+		}
+	}
+	assert.Empty(t, l.locks, "all keys are unlocked")
+}
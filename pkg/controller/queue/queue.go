@@ -0,0 +1,185 @@
+// Package queue tunes how the KUDO controllers retry failed reconciles and how many reconciles they run in parallel.
+//
+// The work queue of a controller is created by controller-runtime with a fixed rate limiter. Reconcilers wrapped by
+// NewReconciler therefore never return errors to the controller, instead they request to be requeued after the delay
+// of their own rate limiter, which is configured by Options. They also limit the reconciles of every single object, so
+// that the workers are shared fairly between the objects.
+package queue
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	// DefaultBaseDelay is the delay of the first retry of a failed reconcile
+	DefaultBaseDelay = 5 * time.Millisecond
+	// DefaultMaxDelay is the maximum delay between retries of a failed reconcile
+	DefaultMaxDelay = 1000 * time.Second
+	// DefaultMaxConcurrentReconciles is the number of reconciles a controller runs in parallel
+	DefaultMaxConcurrentReconciles = 1
+	// DefaultObjectQPS is the number of reconciles of a single object per second
+	DefaultObjectQPS = 10
+	// DefaultObjectBurst is the number of reconciles of a single object allowed at once
+	DefaultObjectBurst = 20
+)
+
+// Options are the settings of the work queue of a controller. The zero value uses the defaults of controller-runtime.
+type Options struct {
+	// BaseDelay is the delay of the first retry of a failed reconcile, the delay doubles with every retry
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between retries of a failed reconcile
+	MaxDelay time.Duration
+	// MaxRetries is the number of retries of a failing reconcile before it is dropped until the object changes
+	// again, failing reconciles are retried forever if it is 0
+	MaxRetries int
+	// MaxConcurrentReconciles is the number of reconciles a controller runs in parallel
+	MaxConcurrentReconciles int
+	// ObjectQPS limits the reconciles of a single object per second. Reconciles exceeding it are delayed, so that an
+	// object that is reconciled over and over again, e.g. because its status changes constantly, can not starve the
+	// other objects of the controller.
+	ObjectQPS float64
+	// ObjectBurst is the number of reconciles of a single object allowed at once before ObjectQPS applies
+	ObjectBurst int
+}
+
+// ControllerOptions returns the options of the controller the reconciler is registered with
+func (o Options) ControllerOptions() controller.Options {
+	if o.MaxConcurrentReconciles <= 0 {
+		return controller.Options{MaxConcurrentReconciles: DefaultMaxConcurrentReconciles}
+	}
+	return controller.Options{MaxConcurrentReconciles: o.MaxConcurrentReconciles}
+}
+
+func (o Options) rateLimiter() workqueue.RateLimiter {
+	base, max := o.BaseDelay, o.MaxDelay
+	if base <= 0 {
+		base = DefaultBaseDelay
+	}
+	if max <= 0 {
+		max = DefaultMaxDelay
+	}
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(base, max),
+		// overall rate limit of the default controller rate limiter, 10 qps with a burst of 100
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
+}
+
+func (o Options) objectLimiter() *objectLimiter {
+	qps, burst := o.ObjectQPS, o.ObjectBurst
+	if qps <= 0 {
+		qps = DefaultObjectQPS
+	}
+	if burst <= 0 {
+		burst = DefaultObjectBurst
+	}
+	return &objectLimiter{qps: rate.Limit(qps), burst: burst}
+}
+
+// reconciler retries failed reconciles with the delays of its own rate limiter
+type reconciler struct {
+	name       string
+	reconciler reconcile.Reconciler
+	limiter    workqueue.RateLimiter
+	maxRetries int
+	// objects delays the reconciles of objects exceeding their share
+	objects *objectLimiter
+}
+
+// NewReconciler wraps the reconciler of the named controller so failed reconciles are retried as configured by the
+// options
+func NewReconciler(name string, r reconcile.Reconciler, o Options) reconcile.Reconciler {
+	return &reconciler{
+		name:       name,
+		reconciler: r,
+		limiter:    o.rateLimiter(),
+		maxRetries: o.MaxRetries,
+		objects:    o.objectLimiter(),
+	}
+}
+
+// Reconcile calls the wrapped reconciler. Errors and requests to be requeued without a delay are turned into
+// requeues after the delay of the rate limiter. The retries of a request are reset once it succeeds. Requests of an
+// object exceeding its share of reconciles are requeued without calling the wrapped reconciler.
+func (r *reconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	if delay := r.objects.delay(request, time.Now()); delay > 0 {
+		return reconcile.Result{RequeueAfter: delay}, nil
+	}
+	result, err := r.reconciler.Reconcile(request)
+	if err == nil && (!result.Requeue || result.RequeueAfter > 0) {
+		r.limiter.Forget(request)
+		return result, nil
+	}
+
+	if r.maxRetries > 0 && r.limiter.NumRequeues(request) >= r.maxRetries {
+		log.Printf("%s: Dropping %s after %d retries: %v", r.name, request, r.maxRetries, err)
+		r.limiter.Forget(request)
+		return reconcile.Result{}, nil
+	}
+	delay := r.limiter.When(request)
+	if err != nil {
+		log.Printf("%s: Reconciling %s failed, retrying in %v: %v", r.name, request, delay, err)
+	}
+	return reconcile.Result{RequeueAfter: delay}, nil
+}
+
+// objectLimiter limits the reconciles of every object with a token bucket of its own
+type objectLimiter struct {
+	qps   rate.Limit
+	burst int
+
+	mu      sync.Mutex
+	buckets map[reconcile.Request]*objectBucket
+	pruned  time.Time
+}
+
+type objectBucket struct {
+	limiter *rate.Limiter
+	used    time.Time
+}
+
+// delay returns how long the reconcile of the request has to be delayed, it takes a token of the object if it does not
+// have to be delayed
+func (o *objectLimiter) delay(request reconcile.Request, now time.Time) time.Duration {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.prune(now)
+	b, ok := o.buckets[request]
+	if !ok {
+		if o.buckets == nil {
+			o.buckets = map[reconcile.Request]*objectBucket{}
+		}
+		b = &objectBucket{limiter: rate.NewLimiter(o.qps, o.burst)}
+		o.buckets[request] = b
+	}
+	b.used = now
+	reservation := b.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		// the token is taken once the delayed request is reconciled
+		reservation.CancelAt(now)
+	}
+	return delay
+}
+
+// prune drops the buckets of the objects that were not reconciled for the time a bucket takes to refill, as they
+// behave like new buckets. o.mu has to be held.
+func (o *objectLimiter) prune(now time.Time) {
+	refill := time.Duration(float64(o.burst) / float64(o.qps) * float64(time.Second))
+	if now.Sub(o.pruned) < refill {
+		return
+	}
+	o.pruned = now
+	for request, b := range o.buckets {
+		if now.Sub(b.used) >= refill {
+			delete(o.buckets, request)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type fakeReconciler struct {
+	results []reconcile.Result
+	errs    []error
+	calls   int
+}
+
+func (f *fakeReconciler) Reconcile(reconcile.Request) (reconcile.Result, error) {
+	i := f.calls
+	f.calls++
+	return f.results[i], f.errs[i]
+}
+
+func TestReconciler(t *testing.T) {
+	failure := errors.New("failure")
+	request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "foo"}}
+
+	tests := []struct {
+		name     string
+		options  Options
+		results  []reconcile.Result
+		errs     []error
+		expected []reconcile.Result
+	}{
+		{
+			name:     "success is passed through",
+			results:  []reconcile.Result{{}, {RequeueAfter: time.Minute}},
+			errs:     []error{nil, nil},
+			expected: []reconcile.Result{{}, {RequeueAfter: time.Minute}},
+		},
+		{
+			name:     "errors are retried with exponential delays",
+			options:  Options{BaseDelay: time.Second, MaxDelay: 3 * time.Second},
+			results:  []reconcile.Result{{}, {}, {Requeue: true}, {}},
+			errs:     []error{failure, failure, nil, failure},
+			expected: []reconcile.Result{{RequeueAfter: time.Second}, {RequeueAfter: 2 * time.Second}, {RequeueAfter: 3 * time.Second}, {RequeueAfter: 3 * time.Second}},
+		},
+		{
+			name:     "retries are reset after success",
+			options:  Options{BaseDelay: time.Second},
+			results:  []reconcile.Result{{}, {}, {}, {}},
+			errs:     []error{failure, failure, nil, failure},
+			expected: []reconcile.Result{{RequeueAfter: time.Second}, {RequeueAfter: 2 * time.Second}, {}, {RequeueAfter: time.Second}},
+		},
+		{
+			name:     "request is dropped after max retries",
+			options:  Options{BaseDelay: time.Second, MaxRetries: 2},
+			results:  []reconcile.Result{{}, {}, {}, {}},
+			errs:     []error{failure, failure, failure, failure},
+			expected: []reconcile.Result{{RequeueAfter: time.Second}, {RequeueAfter: 2 * time.Second}, {}, {RequeueAfter: time.Second}},
+		},
+	}
+
+	for _, tt := range tests {
+		r := NewReconciler("TestController", &fakeReconciler{results: tt.results, errs: tt.errs}, tt.options)
+		for i, expected := range tt.expected {
+			result, err := r.Reconcile(request)
+			assert.NoError(t, err, "%s: call %d", tt.name, i)
+			assert.Equal(t, expected, result, "%s: call %d", tt.name, i)
+		}
+	}
+}
+
+func TestControllerOptions(t *testing.T) {
+	assert.Equal(t, DefaultMaxConcurrentReconciles, Options{}.ControllerOptions().MaxConcurrentReconciles)
+	assert.Equal(t, 4, Options{MaxConcurrentReconciles: 4}.ControllerOptions().MaxConcurrentReconciles)
+}
+
+func TestObjectLimiter(t *testing.T) {
+	o := Options{ObjectQPS: 1, ObjectBurst: 2}.objectLimiter()
+	hot := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hot"}}
+	other := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "other"}}
+	now := time.Now()
+
+	assert.Zero(t, o.delay(hot, now))
+	assert.Zero(t, o.delay(hot, now))
+	assert.Equal(t, time.Second, o.delay(hot, now), "an object exceeding its burst is delayed")
+	assert.Equal(t, time.Second, o.delay(hot, now), "delayed reconciles do not take tokens")
+	assert.Zero(t, o.delay(other, now), "other objects are not delayed")
+	assert.Zero(t, o.delay(hot, now.Add(time.Second)))
+
+	assert.Equal(t, 2, len(o.buckets))
+	o.delay(other, now.Add(2*time.Second))
+	o.delay(other, now.Add(4*time.Second))
+	assert.Equal(t, 1, len(o.buckets), "buckets of objects that were not reconciled until they refilled are dropped")
+}
+
+// stressReconciler counts the reconciles of every instance and fails if an instance is reconciled twice at the same
+// time
+type stressReconciler struct {
+	t        *testing.T
+	mu       sync.Mutex
+	active   map[reconcile.Request]bool
+	counts   map[reconcile.Request]int
+	duration time.Duration
+}
+
+func (s *stressReconciler) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	s.mu.Lock()
+	if s.active[request] {
+		s.t.Errorf("%s is reconciled twice at the same time", request)
+	}
+	s.active[request] = true
+	s.counts[request]++
+	s.mu.Unlock()
+
+	time.Sleep(s.duration)
+
+	s.mu.Lock()
+	s.active[request] = false
+	s.mu.Unlock()
+	return reconcile.Result{}, nil
+}
+
+func TestReconciler_Fairness(t *testing.T) {
+	const instances, workers = 300, 8
+	hot := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "hot"}}
+	s := &stressReconciler{t: t, active: map[reconcile.Request]bool{}, counts: map[reconcile.Request]int{}, duration: time.Millisecond}
+	r := NewReconciler("TestController", s, Options{ObjectQPS: 20, ObjectBurst: 5})
+
+	// the work queue of controller-runtime, a key is never processed by two workers at the same time
+	q := workqueue.NewDelayingQueue()
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				item, shutdown := q.Get()
+				if shutdown {
+					return
+				}
+				request := item.(reconcile.Request)
+				result, _ := r.Reconcile(request)
+				if result.RequeueAfter > 0 {
+					q.AddAfter(request, result.RequeueAfter)
+				}
+				q.Done(item)
+			}
+		}()
+	}
+	// the hot instance is changed constantly, e.g. by a broken operator updating the objects of the instance
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				q.Add(hot)
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+
+	start := time.Now()
+	for i := 0; i < instances; i++ {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("instance-%d", i)}})
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		s.mu.Lock()
+		reconciled := len(s.counts)
+		s.mu.Unlock()
+		if reconciled == instances+1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d instances were reconciled", reconciled-1, instances)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	elapsed := time.Since(start)
+	close(stop)
+	q.ShutDown()
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the hot instance gets its burst and then 20 reconciles per second at most
+	maxHot := 5 + int(elapsed.Seconds()*20) + 1
+	assert.True(t, s.counts[hot] <= maxHot, "the hot instance was reconciled %d times in %v, expected at most %d", s.counts[hot], elapsed, maxHot)
+	for i := 0; i < instances; i++ {
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("instance-%d", i)}}
+		assert.Equal(t, 1, s.counts[request], "%s is reconciled once", request)
+	}
+}
@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"sync"
+	"text/template"
+)
+
+// maxCachedVersions is the number of OperatorVersions whose parsed templates are cached. The templates of the least
+// recently used OperatorVersion are dropped beyond, e.g. those of a deleted OperatorVersion.
+const maxCachedVersions = 256
+
+// Cache caches the parsed templates of OperatorVersions, so that plans that are executed on every reconcile do not
+// parse the same templates again and again. The zero value is ready to use. It is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	// clock orders the entries by their last use
+	clock uint64
+}
+
+type cacheEntry struct {
+	version   string
+	templates *Templates
+	used      uint64
+}
+
+// Templates returns the parsed templates of the OperatorVersion with the given id, usually its UID. The templates
+// parsed for a different version of it, usually its resourceVersion, are dropped, so a changed OperatorVersion is
+// never rendered with its previous templates.
+func (c *Cache) Templates(id, version string) *Templates {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clock++
+	if e, ok := c.entries[id]; ok && e.version == version {
+		e.used = c.clock
+		return e.templates
+	}
+	if c.entries == nil {
+		c.entries = map[string]*cacheEntry{}
+	}
+	if _, ok := c.entries[id]; !ok && len(c.entries) >= maxCachedVersions {
+		c.evict()
+	}
+	e := &cacheEntry{version: version, templates: &Templates{}, used: c.clock}
+	c.entries[id] = e
+	return e.templates
+}
+
+// evict drops the least recently used entry
+func (c *Cache) evict() {
+	var oldest string
+	var used uint64
+	for id, e := range c.entries {
+		if oldest == "" || e.used < used {
+			oldest, used = id, e.used
+		}
+	}
+	delete(c.entries, oldest)
+}
+
+// Templates are the parsed templates of one version of an OperatorVersion by name, see Engine.RenderTemplate. The
+// zero value is ready to use. It is safe for concurrent use.
+type Templates struct {
+	mu     sync.Mutex
+	parsed map[string]*template.Template
+}
+
+// get returns the parsed template with the given name, parsing it if it is not cached yet. Templates that fail to
+// parse are not cached.
+func (t *Templates) get(name string, parse func() (*template.Template, error)) (*template.Template, error) {
+	t.mu.Lock()
+	parsed, ok := t.parsed[name]
+	t.mu.Unlock()
+	if ok {
+		return parsed, nil
+	}
+
+	// parse without holding the lock, a template parsed concurrently is simply parsed twice
+	parsed, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.parsed == nil {
+		t.parsed = map[string]*template.Template{}
+	}
+	t.parsed[name] = parsed
+	return parsed, nil
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRenderTemplateCached(t *testing.T) {
+	cache := &Cache{}
+	vals := map[string]interface{}{"Name": "kafka"}
+
+	engine := New()
+	engine.Partials = map[string]string{"_helpers.tpl": `{{ define "name" }}{{ .Name }}-svc{{ end }}`}
+	engine.Templates = cache.Templates("uid-1", "1")
+	rendered, err := engine.RenderTemplate("service.yaml", `name: {{ include "name" . | upper }}`, vals)
+	if err != nil || rendered != "name: KAFKA-SVC" {
+		t.Fatalf("expected the rendered template but got %q, %v", rendered, err)
+	}
+
+	// the template parsed before is rendered by another engine with its own functions, even if the source differs
+	store := mapStore{"password": "secret"}
+	other := New()
+	other.Values = store
+	other.Templates = cache.Templates("uid-1", "1")
+	rendered, err = other.RenderTemplate("service.yaml", `changed`, vals)
+	if err != nil || rendered != "name: KAFKA-SVC" {
+		t.Errorf("expected the cached template but got %q, %v", rendered, err)
+	}
+	rendered, err = other.RenderTemplate("secret.yaml", `{{ randAlphaNumPersisted "password" 16 }}`, vals)
+	if err != nil || rendered != "secret" {
+		t.Errorf("expected the persisted value but got %q, %v", rendered, err)
+	}
+
+	// templates that fail to parse are not cached
+	if _, err := other.RenderTemplate("broken.yaml", `{{ .Name `, vals); err == nil {
+		t.Errorf("expected a broken template to fail")
+	}
+	if rendered, err := other.RenderTemplate("broken.yaml", `{{ .Name }}`, vals); err != nil || rendered != "kafka" {
+		t.Errorf("expected the fixed template to render but got %q, %v", rendered, err)
+	}
+
+	// a new version of the operatorversion parses its templates again
+	engine.Templates = cache.Templates("uid-1", "2")
+	rendered, err = engine.RenderTemplate("service.yaml", `changed`, vals)
+	if err != nil || rendered != "changed" {
+		t.Errorf("expected the template of the new version but got %q, %v", rendered, err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache := &Cache{}
+	first := cache.Templates("uid-0", "1")
+	for i := 1; i < maxCachedVersions; i++ {
+		cache.Templates(fmt.Sprintf("uid-%d", i), "1")
+	}
+	// using the first entry again makes the second one the least recently used
+	if cache.Templates("uid-0", "1") != first {
+		t.Errorf("expected the cached templates of uid-0")
+	}
+	cache.Templates("uid-new", "1")
+
+	if len(cache.entries) != maxCachedVersions {
+		t.Errorf("expected %d cached versions but got %d", maxCachedVersions, len(cache.entries))
+	}
+	if _, ok := cache.entries["uid-1"]; ok {
+		t.Errorf("expected the least recently used uid-1 to be evicted")
+	}
+	if cache.Templates("uid-0", "1") != first {
+		t.Errorf("expected the recently used uid-0 to be kept")
+	}
+}
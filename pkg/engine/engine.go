@@ -2,7 +2,12 @@ package engine
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
 	"text/template"
 
 	"github.com/masterminds/sprig"
@@ -11,6 +16,27 @@ import (
 // Engine is the control struct for parsing and templating Kubernetes resources in an ordered fashion
 type Engine struct {
 	FuncMap template.FuncMap
+	// Partials are templates (e.g. templates/_helpers.tpl) that only define named templates. They are parsed before
+	// each rendered template, so the named templates can be used via `template` or `include`.
+	Partials map[string]string
+	// Instances resolves references to other instances, see instanceFuncs. Templates using them fail to render if it
+	// is not set.
+	Instances InstanceResolver
+	// Values persists the values generated by the persisted random functions, see persistedFuncs. Templates using
+	// them fail to render if it is not set.
+	Values ValueStore
+	// Templates caches the templates parsed by RenderTemplate, they are parsed on every rendering if it is not set
+	Templates *Templates
+	// Files are the templates that can be hashed with `checksum`, usually all templates of the OperatorVersion
+	Files map[string]string
+}
+
+// partialPrefix is the file name prefix marking a template as a partial
+const partialPrefix = "_"
+
+// IsPartial returns true if the template with the given name is a partial that is not rendered on its own
+func IsPartial(name string) bool {
+	return strings.HasPrefix(filepath.Base(name), partialPrefix)
 }
 
 // New creates an engine with a default function map, using a modified Sprig func map. Because these
@@ -34,19 +60,127 @@ func New() *Engine {
 // Render creates a fully rendered template based on a set of values. It parses these in strict mode,
 // returning errors when keys are missing.
 func (e *Engine) Render(tpl string, vals map[string]interface{}) (string, error) {
+	t, err := e.parse(tpl)
+	if err != nil {
+		return "", err
+	}
+	return e.execute(t, vals, nil)
+}
+
+// RenderTemplate renders the template with the given name like Render. The parsed template is taken from Templates
+// if it is set, so a template is only parsed once for all renderings. Templates must only be shared by engines with
+// the same partials, e.g. those of one OperatorVersion.
+func (e *Engine) RenderTemplate(name, tpl string, vals map[string]interface{}) (string, error) {
+	t, err := e.parseTemplate(name, tpl)
+	if err != nil {
+		return "", err
+	}
+	return e.execute(t, vals, []string{name})
+}
+
+// parseTemplate returns the parsed template with the given name from Templates, or parses it if Templates is not set
+func (e *Engine) parseTemplate(name, tpl string) (*template.Template, error) {
+	if e.Templates == nil {
+		return e.parse(tpl)
+	}
+	return e.Templates.get(name, func() (*template.Template, error) { return e.parse(tpl) })
+}
+
+// parse parses the template and the partials. The functions depending on the engine are only bound when the template
+// is executed, so the parsed template can be executed by other engines too.
+func (e *Engine) parse(tpl string) (*template.Template, error) {
 	t := template.New("gotpl")
 	t.Option("missingkey=error")
+	t.Funcs(e.funcs(nil, nil))
 
-	var buf bytes.Buffer
-	t = t.New("tpl").Funcs(e.FuncMap)
+	// parse partials in a stable order so that redefinitions of the same named template behave predictably
+	names := make([]string, 0, len(e.Partials))
+	for name := range e.Partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := t.New(name).Parse(e.Partials[name]); err != nil {
+			return nil, fmt.Errorf("error parsing partial %s: %s", name, err)
+		}
+	}
 
+	t = t.New("tpl")
 	if _, err := t.Parse(tpl); err != nil {
-		return "", fmt.Errorf("error parsing template: %s", err)
+		return nil, fmt.Errorf("error parsing template: %s", err)
+	}
+	return t, nil
+}
+
+// execute executes a copy of the parsed template with the functions of this engine. Rendering is the stack of the
+// names of the templates being rendered, checksum renders the templates it hashes on top of it.
+func (e *Engine) execute(parsed *template.Template, vals map[string]interface{}, rendering []string) (string, error) {
+	t, err := parsed.Clone()
+	if err != nil {
+		return "", fmt.Errorf("error rendering template: %s", err)
 	}
+	t.Funcs(e.funcs(e.Instances, e.Values))
+	// include is like the `template` action but its output can be piped to other functions, e.g. indent
+	t.Funcs(template.FuncMap{"include": func(name string, data interface{}) (string, error) {
+		var buf bytes.Buffer
+		if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}})
+	// checksum renders another template with the same values and returns its SHA-256 checksum, e.g. to roll the pods
+	// of a workload whenever the rendered ConfigMap they use changes
+	t.Funcs(template.FuncMap{"checksum": func(name string) (string, error) {
+		return e.checksum(name, vals, rendering)
+	}})
 
+	var buf bytes.Buffer
 	if err := t.ExecuteTemplate(&buf, "tpl", vals); err != nil {
 		return "", fmt.Errorf("error rendering template: %s", err)
 	}
 
 	return buf.String(), nil
 }
+
+// funcs returns the functions available in templates, include and checksum are placeholders that are replaced on
+// execution
+func (e *Engine) funcs(instances InstanceResolver, values ValueStore) template.FuncMap {
+	funcs := template.FuncMap{}
+	for k, v := range e.FuncMap {
+		funcs[k] = v
+	}
+	for k, v := range instanceFuncs(instances) {
+		funcs[k] = v
+	}
+	for k, v := range persistedFuncs(values, e.FuncMap) {
+		funcs[k] = v
+	}
+	funcs["include"] = func(string, interface{}) (string, error) { return "", nil }
+	funcs["checksum"] = func(string) (string, error) { return "", nil }
+	return funcs
+}
+
+// checksum renders the file with the given name and returns the hex encoded SHA-256 checksum of the result. A file
+// can not be hashed while it is being rendered, e.g. by a checksum of itself.
+func (e *Engine) checksum(name string, vals map[string]interface{}, rendering []string) (string, error) {
+	tpl, ok := e.Files[name]
+	if !ok {
+		return "", fmt.Errorf("template %s to compute the checksum of does not exist", name)
+	}
+	for _, r := range rendering {
+		if r == name {
+			return "", fmt.Errorf("template %s can not contain its own checksum", name)
+		}
+	}
+
+	t, err := e.parseTemplate(name, tpl)
+	if err != nil {
+		return "", err
+	}
+	rendered, err := e.execute(t, vals, append(append([]string{}, rendering...), name))
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(rendered))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -1,7 +1,10 @@
 package engine
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -62,3 +65,200 @@ func TestUnsafeFuncs(t *testing.T) {
 	}
 
 }
+
+func TestRenderPartials(t *testing.T) {
+	engine := New()
+	engine.Partials = map[string]string{
+		"_helpers.tpl": `{{- define "labels" }}app: {{ .Name }}
+operator: {{ .OperatorName }}{{ end -}}`,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{name: "template action", template: "{{ template \"labels\" . }}", expected: "app: zk\noperator: zookeeper"},
+		{name: "include with pipe", template: "labels:\n{{ include \"labels\" . | indent 2 }}", expected: "labels:\n  app: zk\n  operator: zookeeper"},
+	}
+
+	for _, test := range tests {
+		rendered, err := engine.Render(test.template, map[string]interface{}{"Name": "zk", "OperatorName": "zookeeper"})
+		if err != nil {
+			t.Errorf("%s: error rendering template: %s", test.name, err)
+		}
+		if rendered != test.expected {
+			t.Errorf("%s: template mismatch, expected: %+v, got: %+v", test.name, test.expected, rendered)
+		}
+	}
+
+	if _, err := engine.Render("{{ include \"missing\" . }}", nil); err == nil {
+		t.Errorf("expected error when including undefined template, got none")
+	}
+}
+
+func TestIsPartial(t *testing.T) {
+	for name, expected := range map[string]bool{"_helpers.tpl": true, "sub/_labels.tpl": true, "deployment.yaml": false} {
+		if IsPartial(name) != expected {
+			t.Errorf("expected IsPartial(%s) to be %v", name, expected)
+		}
+	}
+}
+
+func TestParameterReferences(t *testing.T) {
+	tpl := `{{ define "labels" }}app: {{ $.Params.APP }}{{ end }}
+replicas: {{ .Params.REPLICAS }}
+{{ if eq .Params.DEBUG "true" }}level: {{ index .Params "LOG_LEVEL" | default "debug" }}{{ else }}{{ .Params.REPLICAS }}{{ end }}
+{{ range .Pods }}{{ $.Params.MEMORY }}{{ end }}
+name: {{ .Name }}`
+
+	refs, err := New().ParameterReferences(tpl)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := "APP,DEBUG,LOG_LEVEL,MEMORY,REPLICAS"
+	if strings.Join(refs, ",") != expected {
+		t.Errorf("expected references %s but got %v", expected, refs)
+	}
+
+	if _, err := New().ParameterReferences("{{ .Params.A | unknown }}"); err == nil {
+		t.Errorf("expected error for unknown function")
+	}
+}
+
+func TestConstantReferences(t *testing.T) {
+	tpl := `image: {{ .Constants.image.repository }}:{{ $.Constants.image.tag }}
+{{ range .Constants.ports }}- {{ . }}{{ end }}
+{{ index .Constants "jvm" }} {{ .Params.MEMORY }}`
+
+	refs, err := New().ConstantReferences(tpl)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := "image,jvm,ports"
+	if strings.Join(refs, ",") != expected {
+		t.Errorf("expected references %s but got %v", expected, refs)
+	}
+}
+
+type fakeResolver struct{}
+
+func (fakeResolver) Parameter(instance, parameter string) (string, error) {
+	if instance == "zk" && parameter == "CLIENT_PORT" {
+		return "2181", nil
+	}
+	return "", fmt.Errorf("instance %s has no parameter %s", instance, parameter)
+}
+
+func (fakeResolver) Endpoints(instance, port string) ([]string, error) {
+	return []string{"zk-0.default.svc:2181", "zk-1.default.svc:2181"}, nil
+}
+
+func TestRenderInstanceFuncs(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolver InstanceResolver
+		template string
+		expected string
+		err      string
+	}{
+		{name: "parameter", resolver: fakeResolver{}, template: `port: {{ instanceParam "zk" "CLIENT_PORT" }}`, expected: "port: 2181"},
+		{name: "endpoints", resolver: fakeResolver{}, template: `zk: {{ instanceEndpoints "zk" "client" }}`, expected: "zk: zk-0.default.svc:2181,zk-1.default.svc:2181"},
+		{name: "lookup error", resolver: fakeResolver{}, template: `{{ instanceParam "zk" "OTHER" }}`, err: "instance zk has no parameter OTHER"},
+		{name: "no resolver", template: `{{ instanceParam "zk" "CLIENT_PORT" }}`, err: "other instances can not be referenced here"},
+	}
+
+	for _, tt := range tests {
+		engine := New()
+		engine.Instances = tt.resolver
+		rendered, err := engine.Render(tt.template, map[string]interface{}{})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error containing %q but got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		}
+		if rendered != tt.expected {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.expected, rendered)
+		}
+	}
+}
+
+type mapStore map[string]string
+
+func (s mapStore) Value(key string) (string, bool, error) {
+	v, ok := s[key]
+	return v, ok, nil
+}
+
+func (s mapStore) SetValue(key, value string) error {
+	s[key] = value
+	return nil
+}
+
+func TestRenderPersistedFuncs(t *testing.T) {
+	store := mapStore{}
+	engine := New()
+	engine.Values = store
+
+	tpl := `{{ randAlphaNumPersisted "password" 16 }}`
+	first, err := engine.Render(tpl, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if len(first) != 16 || store["password"] != first {
+		t.Errorf("expected a persisted password of length 16 but got %q, store %v", first, store)
+	}
+
+	// a later rendering returns the persisted value, even if the arguments changed
+	second, err := engine.Render(`{{ randAlphaNumPersisted "password" 32 }}`, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if second != first {
+		t.Errorf("expected the persisted value %q but got %q", first, second)
+	}
+
+	key, err := engine.Render(`{{ genPrivateKeyPersisted "tls.key" "ecdsa" }}`, map[string]interface{}{})
+	if err != nil || !strings.Contains(key, "EC PRIVATE KEY") {
+		t.Errorf("expected a persisted private key but got %q, %v", key, err)
+	}
+
+	if _, err := engine.Render(`{{ randNumericPersisted "pin/1" 4 }}`, map[string]interface{}{}); err == nil {
+		t.Errorf("expected an invalid key to fail")
+	}
+
+	engine.Values = nil
+	if _, err := engine.Render(tpl, map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "persisted values can not be generated here") {
+		t.Errorf("expected rendering without a store to fail but got %v", err)
+	}
+}
+
+func TestRenderChecksum(t *testing.T) {
+	engine := New()
+	engine.Files = map[string]string{
+		"cm.yaml":         "data:\n  name: {{ .Name }}",
+		"self.yaml":       `{{ checksum "self.yaml" }}`,
+		"deployment.yaml": `checksum: {{ checksum "cm.yaml" }}`,
+	}
+	vals := map[string]interface{}{"Name": "zk"}
+
+	rendered, err := engine.RenderTemplate("deployment.yaml", engine.Files["deployment.yaml"], vals)
+	if err != nil {
+		t.Fatalf("error rendering template: %s", err)
+	}
+	sum := sha256.Sum256([]byte("data:\n  name: zk"))
+	if expected := "checksum: " + hex.EncodeToString(sum[:]); rendered != expected {
+		t.Errorf("template mismatch, expected: %+v, got: %+v", expected, rendered)
+	}
+
+	if _, err := engine.RenderTemplate("self.yaml", engine.Files["self.yaml"], vals); err == nil || !strings.Contains(err.Error(), "can not contain its own checksum") {
+		t.Errorf("expected rendering the checksum of the template itself to fail but got %v", err)
+	}
+	if _, err := engine.Render(`{{ checksum "missing.yaml" }}`, vals); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected rendering the checksum of a missing template to fail but got %v", err)
+	}
+}
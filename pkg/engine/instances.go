@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"text/template"
+)
+
+// InstanceResolver looks up other Instances in the namespace of the rendered Instance, so that templates of dependent
+// operators can reference them without copying their parameters
+type InstanceResolver interface {
+	// Parameter returns the value of the parameter of the instance, or the default of its OperatorVersion if not set
+	Parameter(instance, parameter string) (string, error)
+	// Endpoints returns the host:port endpoints of the services of the instance exposing a port with the given name
+	Endpoints(instance, port string) ([]string, error)
+}
+
+var errNoInstanceResolver = errors.New("other instances can not be referenced here")
+
+// instanceFuncs returns the template functions referencing other instances:
+//
+//	instanceParam "zookeeper" "CLIENT_PORT" returns the value of the parameter CLIENT_PORT of instance zookeeper
+//	instanceEndpoints "zookeeper" "client" returns the comma separated endpoints of the client port of instance zookeeper
+//
+// The functions fail if no resolver is given.
+func instanceFuncs(r InstanceResolver) template.FuncMap {
+	return template.FuncMap{
+		"instanceParam": func(instance, parameter string) (string, error) {
+			if r == nil {
+				return "", errNoInstanceResolver
+			}
+			return r.Parameter(instance, parameter)
+		},
+		"instanceEndpoints": func(instance, port string) (string, error) {
+			if r == nil {
+				return "", errNoInstanceResolver
+			}
+			endpoints, err := r.Endpoints(instance, port)
+			if err != nil {
+				return "", err
+			}
+			return strings.Join(endpoints, ","), nil
+		},
+	}
+}
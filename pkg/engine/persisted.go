@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+// ValueStore persists the values generated by templates of an Instance, so that rendering its templates again, e.g.
+// when a plan is executed again, returns the values generated the first time instead of new ones
+type ValueStore interface {
+	// Value returns the value stored under the key and whether one is stored
+	Value(key string) (string, bool, error)
+	// SetValue stores the value under the key
+	SetValue(key, value string) error
+}
+
+var errNoValueStore = errors.New("persisted values can not be generated here")
+
+// persistedKey restricts the keys of persisted values to the characters allowed in the keys of Secret data
+var persistedKey = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+
+// persistedFuncs returns the template functions generating random values once per instance. They take the key the
+// value is persisted under as first argument, followed by the arguments of the Sprig function generating the value:
+//
+//	randAlphaNumPersisted "password" 16 returns the same random alphanumeric string of length 16 in every rendering
+//	genPrivateKeyPersisted "tls-key" "rsa" returns the same PEM encoded RSA private key in every rendering
+//
+// The arguments after the key are only used when the value is generated, changing them later does not change the
+// persisted value. The same key used in different templates of an instance returns the same value. The functions fail
+// if no store is given.
+func persistedFuncs(s ValueStore, sprigFuncs template.FuncMap) template.FuncMap {
+	randFunc := func(name string) func(string, int) (string, error) {
+		generate, _ := sprigFuncs[name].(func(int) string)
+		return func(key string, count int) (string, error) {
+			return persisted(s, key, func() string { return generate(count) })
+		}
+	}
+	genPrivateKey, _ := sprigFuncs["genPrivateKey"].(func(string) string)
+
+	return template.FuncMap{
+		"randAlphaNumPersisted": randFunc("randAlphaNum"),
+		"randAlphaPersisted":    randFunc("randAlpha"),
+		"randNumericPersisted":  randFunc("randNumeric"),
+		"randAsciiPersisted":    randFunc("randAscii"),
+		"genPrivateKeyPersisted": func(key string, typ string) (string, error) {
+			return persisted(s, key, func() string { return genPrivateKey(typ) })
+		},
+	}
+}
+
+// persisted returns the value stored under the key, or generates and stores it if there is none
+func persisted(s ValueStore, key string, generate func() string) (string, error) {
+	if s == nil {
+		return "", errNoValueStore
+	}
+	if !persistedKey.MatchString(key) {
+		return "", fmt.Errorf("invalid key %q of persisted value, only alphanumeric characters, '-', '_' and '.' are allowed", key)
+	}
+	value, ok, err := s.Value(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to load persisted value %s: %v", key, err)
+	}
+	if ok {
+		return value, nil
+	}
+	value = generate()
+	if err := s.SetValue(key, value); err != nil {
+		return "", fmt.Errorf("failed to persist value %s: %v", key, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// Fields of the render values that hold named values
+const (
+	// paramsField holds the instance parameters
+	paramsField = "Params"
+	// constantsField holds the constants of the OperatorVersion
+	constantsField = "Constants"
+)
+
+// ParameterReferences parses the template and returns the sorted names of the parameters it references, either as
+// `.Params.NAME`, `$.Params.NAME` or `index .Params "NAME"`. The template is not executed, so references are found
+// in all branches.
+func (e *Engine) ParameterReferences(tpl string) ([]string, error) {
+	return e.references(tpl, paramsField)
+}
+
+// ConstantReferences parses the template and returns the sorted names of the top-level constants it references, in
+// the same forms as ParameterReferences.
+func (e *Engine) ConstantReferences(tpl string) ([]string, error) {
+	return e.references(tpl, constantsField)
+}
+
+// references returns the sorted names of the values of the field the template references
+func (e *Engine) references(tpl string, field string) ([]string, error) {
+	t, err := template.New("tpl").Funcs(e.funcs(nil, nil)).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing template: %s", err)
+	}
+
+	refs := map[string]bool{}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			collectReferences(tt.Tree.Root, field, refs)
+		}
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// collectReferences walks the parse tree and adds the names of all referenced values of the field to refs
+func collectReferences(node parse.Node, field string, refs map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectReferences(c, field, refs)
+		}
+	case *parse.ActionNode:
+		collectReferences(n.Pipe, field, refs)
+	case *parse.IfNode:
+		collectBranchReferences(&n.BranchNode, field, refs)
+	case *parse.RangeNode:
+		collectBranchReferences(&n.BranchNode, field, refs)
+	case *parse.WithNode:
+		collectBranchReferences(&n.BranchNode, field, refs)
+	case *parse.TemplateNode:
+		collectReferences(n.Pipe, field, refs)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Cmds {
+			collectReferences(c, field, refs)
+		}
+	case *parse.CommandNode:
+		if name, ok := indexedValue(n, field); ok {
+			refs[name] = true
+		}
+		for _, a := range n.Args {
+			collectReferences(a, field, refs)
+		}
+	case *parse.FieldNode:
+		if len(n.Ident) > 1 && n.Ident[0] == field {
+			refs[n.Ident[1]] = true
+		}
+	case *parse.VariableNode:
+		if len(n.Ident) > 2 && n.Ident[0] == "$" && n.Ident[1] == field {
+			refs[n.Ident[2]] = true
+		}
+	}
+}
+
+func collectBranchReferences(n *parse.BranchNode, field string, refs map[string]bool) {
+	collectReferences(n.Pipe, field, refs)
+	collectReferences(n.List, field, refs)
+	collectReferences(n.ElseList, field, refs)
+}
+
+// indexedValue returns the name of an `index .FIELD "NAME"` command, e.g. the parameter of `index .Params "NAME"`
+func indexedValue(n *parse.CommandNode, field string) (string, bool) {
+	if len(n.Args) < 3 {
+		return "", false
+	}
+	if fn, ok := n.Args[0].(*parse.IdentifierNode); !ok || fn.Ident != "index" {
+		return "", false
+	}
+	switch params := n.Args[1].(type) {
+	case *parse.FieldNode:
+		if len(params.Ident) != 1 || params.Ident[0] != field {
+			return "", false
+		}
+	case *parse.VariableNode:
+		if len(params.Ident) != 2 || params.Ident[0] != "$" || params.Ident[1] != field {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	if name, ok := n.Args[2].(*parse.StringNode); ok {
+		return name.Text, true
+	}
+	return "", false
+}
@@ -16,7 +16,6 @@ import (
 	"sigs.k8s.io/kustomize/k8sdeps/transformer"
 	"sigs.k8s.io/kustomize/pkg/fs"
 	"sigs.k8s.io/kustomize/pkg/loader"
-	apipatch "sigs.k8s.io/kustomize/pkg/patch"
 	"sigs.k8s.io/kustomize/pkg/resmap"
 	"sigs.k8s.io/kustomize/pkg/resource"
 	"sigs.k8s.io/kustomize/pkg/target"
@@ -52,6 +51,11 @@ func (k *KustomizeEnhancer) ApplyConventionsToTemplates(templates map[string]str
 		}
 	}
 
+	strategicMerge, json6902, err := kustomizePatches(fsys, templates, metadata.Patches)
+	if err != nil {
+		return nil, err
+	}
+
 	kustomization := &ktypes.Kustomization{
 		NamePrefix: metadata.InstanceName + "-",
 		Namespace:  metadata.InstanceNamespace,
@@ -70,7 +74,8 @@ func (k *KustomizeEnhancer) ApplyConventionsToTemplates(templates map[string]str
 			DisableNameSuffixHash: true,
 		},
 		Resources:             templateNames,
-		PatchesStrategicMerge: []apipatch.StrategicMerge{},
+		PatchesStrategicMerge: strategicMerge,
+		PatchesJson6902:       json6902,
 	}
 
 	yamlBytes, err := yaml.Marshal(kustomization)
@@ -114,6 +119,11 @@ func (k *KustomizeEnhancer) ApplyConventionsToTemplates(templates map[string]str
 		return nil, errors.Wrapf(err, "error parsing kubernetes objects after applying kustomize")
 	}
 
+	// owner references can't refer to an instance in another cluster, these objects are found by the labels of the
+	// instance and deleted by the instance controller before the finalizer of the instance is removed
+	if metadata.TargetCluster != "" {
+		return objsToAdd, nil
+	}
 	for _, o := range objsToAdd {
 		err = setControllerReference(metadata.ResourcesOwner, o, k.Scheme)
 		if err != nil {
@@ -132,8 +142,33 @@ func setControllerReference(owner v1.Object, obj runtime.Object, scheme *runtime
 }
 
 // kustomize method takes a slice of rendered templates, applies conventions using KubernetesObjectEnhancer and
-// returns a slice of k8s objects.
+// returns a slice of k8s objects with the propagated labels and annotations, the redirected images, the placement and
+// the pod metadata of the instance. The objects are passed through the post-renderers before workloads rolling on
+// config changes get the checksum of their configs.
 func kustomize(rendered map[string]string, meta ExecutionMetadata, enhancer KubernetesObjectEnhancer) ([]runtime.Object, error) {
 	enhanced, err := enhancer.ApplyConventionsToTemplates(rendered, meta)
-	return enhanced, err
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range enhanced {
+		if err := propagate(obj, meta.PropagatedLabels, meta.PropagatedAnnotations); err != nil {
+			return nil, err
+		}
+		if err := redirectImages(obj, meta.Images); err != nil {
+			return nil, err
+		}
+		if err := place(obj, meta.Placement); err != nil {
+			return nil, err
+		}
+		if err := injectPodMetadata(obj, meta.PodMetadata); err != nil {
+			return nil, err
+		}
+	}
+	if enhanced, err = postRender(enhanced, meta, meta.PostRenderers); err != nil {
+		return nil, err
+	}
+	if err := injectConfigChecksums(enhanced); err != nil {
+		return nil, err
+	}
+	return enhanced, nil
 }
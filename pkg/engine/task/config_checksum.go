@@ -0,0 +1,207 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// configFields are the fields of the ConfigMaps and Secrets whose content is part of the config checksum
+var configFields = map[string][]string{
+	"ConfigMap": {"data", "binaryData"},
+	"Secret":    {"data", "stringData"},
+}
+
+// injectConfigChecksums sets the config checksum annotation on the pod templates of the workloads that roll on config
+// changes, see kudo.RollOnConfigChangeAnnotation. The checksum covers the ConfigMaps and Secrets among the objects
+// that the pods use as volumes or environment variables, so that any change of their content rolls the pods. Configs
+// that are not among the objects, e.g. those applied by other tasks, are not covered.
+func injectConfigChecksums(objs []runtime.Object) error {
+	var workloads []runtime.Object
+	for _, obj := range objs {
+		if m, err := meta.Accessor(obj); err == nil && m.GetAnnotations()[kudo.RollOnConfigChangeAnnotation] == "true" {
+			workloads = append(workloads, obj)
+		}
+	}
+	if len(workloads) == 0 {
+		return nil
+	}
+
+	configs, err := configContents(objs)
+	if err != nil {
+		return err
+	}
+	for _, obj := range workloads {
+		if err := injectConfigChecksum(obj, configs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configContents returns the serialized content of the ConfigMaps and Secrets among the objects by configKey
+func configContents(objs []runtime.Object) (map[string][]byte, error) {
+	configs := map[string][]byte{}
+	for _, obj := range objs {
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		fields, ok := configFields[kind]
+		if !ok {
+			continue
+		}
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%wfailed to compute the config checksum: %v", ErrFatalExecution, err)
+		}
+		selected := map[string]interface{}{}
+		for _, f := range fields {
+			selected[f] = content[f]
+		}
+		// maps are serialized with sorted keys, so equal content results in equal checksums
+		b, err := json.Marshal(selected)
+		if err != nil {
+			return nil, fmt.Errorf("%wfailed to compute the config checksum: %v", ErrFatalExecution, err)
+		}
+		m, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, fmt.Errorf("%wfailed to compute the config checksum: %v", ErrFatalExecution, err)
+		}
+		configs[configKey(kind, m.GetNamespace(), m.GetName())] = b
+	}
+	return configs, nil
+}
+
+func configKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// injectConfigChecksum sets the checksum of the configs used by the pods of each pod template of the workload
+func injectConfigChecksum(obj runtime.Object, configs map[string][]byte) error {
+	u, isUnstructured := obj.(*unstructured.Unstructured)
+	var content map[string]interface{}
+	if isUnstructured {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return fmt.Errorf("%wfailed to inject the config checksum: %v", ErrFatalExecution, err)
+		}
+	}
+	namespace, _, _ := unstructured.NestedString(content, "metadata", "namespace")
+
+	changed := false
+	for _, path := range podTemplatePaths {
+		spec, ok, _ := unstructured.NestedMap(content, append(append([]string{}, path...), "spec")...)
+		if !ok {
+			continue
+		}
+		h := sha256.New()
+		found := false
+		for _, ref := range configReferences(spec) {
+			if data, ok := configs[configKey(ref.kind, namespace, ref.name)]; ok {
+				fmt.Fprintf(h, "%s/%s\n%s\n", ref.kind, ref.name, data)
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		fields := append(append([]string{}, path...), "metadata", "annotations")
+		annotations, _, err := unstructured.NestedStringMap(content, fields...)
+		if err != nil {
+			return fmt.Errorf("%wfailed to inject the config checksum: %v", ErrFatalExecution, err)
+		}
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[kudo.ConfigChecksumAnnotation] = hex.EncodeToString(h.Sum(nil))
+		if err := unstructured.SetNestedStringMap(content, annotations, fields...); err != nil {
+			return fmt.Errorf("%wfailed to inject the config checksum: %v", ErrFatalExecution, err)
+		}
+		changed = true
+	}
+
+	if isUnstructured || !changed {
+		return nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return fmt.Errorf("%wfailed to inject the config checksum: %v", ErrFatalExecution, err)
+	}
+	return nil
+}
+
+type configReference struct {
+	kind string
+	name string
+}
+
+// configReferences returns the ConfigMaps and Secrets a pod spec uses in volumes, projected volumes and environment
+// variables of its containers, sorted and without duplicates
+func configReferences(spec map[string]interface{}) []configReference {
+	refs := map[configReference]bool{}
+	add := func(kind string, obj map[string]interface{}, fields ...string) {
+		if name, ok, _ := unstructured.NestedString(obj, fields...); ok && name != "" {
+			refs[configReference{kind: kind, name: name}] = true
+		}
+	}
+
+	volumes, _, _ := unstructured.NestedSlice(spec, "volumes")
+	for _, v := range volumes {
+		volume, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		add("ConfigMap", volume, "configMap", "name")
+		add("Secret", volume, "secret", "secretName")
+		sources, _, _ := unstructured.NestedSlice(volume, "projected", "sources")
+		for _, s := range sources {
+			if source, ok := s.(map[string]interface{}); ok {
+				add("ConfigMap", source, "configMap", "name")
+				add("Secret", source, "secret", "name")
+			}
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers"} {
+		containers, _, _ := unstructured.NestedSlice(spec, field)
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			envFrom, _, _ := unstructured.NestedSlice(container, "envFrom")
+			for _, e := range envFrom {
+				if source, ok := e.(map[string]interface{}); ok {
+					add("ConfigMap", source, "configMapRef", "name")
+					add("Secret", source, "secretRef", "name")
+				}
+			}
+			env, _, _ := unstructured.NestedSlice(container, "env")
+			for _, e := range env {
+				if variable, ok := e.(map[string]interface{}); ok {
+					add("ConfigMap", variable, "valueFrom", "configMapKeyRef", "name")
+					add("Secret", variable, "valueFrom", "secretKeyRef", "name")
+				}
+			}
+		}
+	}
+
+	sorted := make([]configReference, 0, len(refs))
+	for ref := range refs {
+		sorted = append(sorted, ref)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].kind != sorted[j].kind {
+			return sorted[i].kind < sorted[j].kind
+		}
+		return sorted[i].name < sorted[j].name
+	})
+	return sorted
+}
@@ -0,0 +1,57 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestInjectConfigChecksums(t *testing.T) {
+	config := func(value string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"},
+			Data:       map[string]string{"server.properties": value},
+		}
+	}
+	deployment := func(annotations map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			TypeMeta:   metav1.TypeMeta{Kind: "Deployment", APIVersion: "apps/v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default", Annotations: annotations},
+			Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "kafka", Image: "kafka"}},
+					Volumes: []corev1.Volume{{Name: "config", VolumeSource: corev1.VolumeSource{
+						ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: "config"}},
+					}}},
+				},
+			}},
+		}
+	}
+	rolling := map[string]string{kudo.RollOnConfigChangeAnnotation: "true"}
+
+	checksum := func(value string) string {
+		d := deployment(rolling)
+		assert.NoError(t, injectConfigChecksums([]runtime.Object{config(value), d}))
+		return d.Spec.Template.Annotations[kudo.ConfigChecksumAnnotation]
+	}
+	first := checksum("retention=1h")
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, checksum("retention=1h"), "equal configs result in equal checksums")
+	assert.NotEqual(t, first, checksum("retention=2h"), "changed configs result in a different checksum")
+
+	unannotated := deployment(nil)
+	assert.NoError(t, injectConfigChecksums([]runtime.Object{config("retention=1h"), unannotated}))
+	assert.Equal(t, deployment(nil), unannotated, "workloads not rolling on config changes are not changed")
+
+	unused := deployment(rolling)
+	unused.Spec.Template.Spec.Volumes = nil
+	assert.NoError(t, injectConfigChecksums([]runtime.Object{config("retention=1h"), unused}))
+	assert.Nil(t, unused.Spec.Template.Annotations, "workloads without configs among the objects are not changed")
+}
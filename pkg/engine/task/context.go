@@ -1,7 +1,13 @@
 package task
 
 import (
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/util/apiwarnings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -23,16 +29,97 @@ type EngineMetadata struct {
 	OperatorName        string
 	OperatorVersionName string
 	OperatorVersion     string
+	// Constants are the constants of the OperatorVersion, templates reference them as .Constants
+	Constants map[string]interface{}
 
 	// the object that will own all the resources created by this execution
 	ResourcesOwner metav1.Object
+
+	// PropagatedLabels and PropagatedAnnotations are added to all resources, see v1alpha1.LabelPropagationPolicy
+	PropagatedLabels      map[string]string
+	PropagatedAnnotations map[string]string
+	// Images redirects the images of all pods, see v1alpha1.ImageSettings
+	Images v1alpha1.ImageSettings
+	// Patches are applied to the rendered templates before the conventions, see v1alpha1.Patch
+	Patches []v1alpha1.Patch
+	// Placement constrains the nodes of all pods, see v1alpha1.Placement
+	Placement *v1alpha1.Placement
+	// PodMetadata are added to all pods, see v1alpha1.PodMetadata
+	PodMetadata *v1alpha1.PodMetadata
+	// TargetCluster is the name of the ClusterTarget the resources are applied to, it is empty if they are applied to
+	// the cluster of the instance. Resources in another cluster are not owned by the instance.
+	TargetCluster string
+	// ServiceAccountName is the ServiceAccount in the namespace of the instance that is impersonated to apply the
+	// resources, it is empty if they are applied with the permissions of the manager
+	ServiceAccountName string
+	// PostRenderers mutate or reject the objects after the conventions were applied, see PostRenderer
+	PostRenderers []PostRenderer
 }
 
 // Context is a engine.task execution context containing k8s client, templates parameters etc.
 type Context struct {
-	Client     client.Client
-	Enhancer   KubernetesObjectEnhancer
-	Meta       ExecutionMetadata
-	Templates  map[string]string // Raw templates
-	Parameters map[string]string // Instance and OperatorVersion parameters merged
+	// Client accesses the cluster of the instance, e.g. to read other instances and the persisted values
+	Client client.Client
+	// TargetClient accesses the cluster the resources of the instance are applied to. It may be nil, the resources are
+	// applied with Client in that case.
+	TargetClient client.Client
+	Enhancer     KubernetesObjectEnhancer
+	Meta         ExecutionMetadata
+	Templates    map[string]string // Raw templates
+	Parameters   map[string]string // Instance and OperatorVersion parameters merged
+	// ParameterDefinitions are the parameters of the OperatorVersion, array parameters are passed to templates as lists
+	ParameterDefinitions []v1alpha1.Parameter
+	// ParsedTemplates caches the parsed Templates across executions. It may be nil, the templates are parsed on every
+	// execution in that case.
+	ParsedTemplates *engine.Templates
+	// RecordResource records the health of an object applied by the task in the status of the step. It may be nil.
+	RecordResource func(status v1alpha1.ResourceStatus)
+	// Executor runs the commands of Exec tasks in pods. It may be nil, Exec tasks fail in that case.
+	Executor PodExecutor
+	// RecordChange records the change to an object found by a dry-run of the task. It may be nil.
+	RecordChange func(change v1alpha1.ResourceChange)
+	// RecordWarning records a warning the API server returned for a request of the task. It may be nil.
+	RecordWarning func(warning string)
+}
+
+// target returns the client of the cluster the resources of the instance are applied to. Warnings the API server
+// returns for its requests are recorded.
+func (c Context) target() client.Client {
+	target := c.TargetClient
+	if target == nil {
+		target = c.Client
+	}
+	return apiwarnings.NewClient(target, c.RecordWarning)
+}
+
+// recordResource records the health of the object and the error that caused it, if resources are recorded
+func (c Context) recordResource(obj runtime.Object, health v1alpha1.ResourceHealth, err error) {
+	if c.RecordResource == nil {
+		return
+	}
+	status := v1alpha1.ResourceStatus{Task: c.Meta.TaskName, Health: health}
+	status.APIVersion, status.Kind = obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	if m, merr := meta.Accessor(obj); merr == nil {
+		status.Namespace, status.Name = m.GetNamespace(), m.GetName()
+	}
+	if err != nil {
+		status.Message = err.Error()
+	}
+	c.RecordResource(status)
+}
+
+// recordChange records how the dry-run of the task would change the object, if changes are recorded
+func (c Context) recordChange(obj runtime.Object, action v1alpha1.ResourceAction, fields []v1alpha1.FieldDrift) {
+	if c.RecordChange == nil {
+		return
+	}
+	change := v1alpha1.ResourceChange{Action: action, Fields: fields, Task: c.Meta.TaskName}
+	change.APIVersion, change.Kind = obj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	if m, err := meta.Accessor(obj); err == nil {
+		change.Namespace, change.Name = m.GetNamespace(), m.GetName()
+	}
+	if c.Meta.PhaseName != "" || c.Meta.StepName != "" {
+		change.Step = c.Meta.PhaseName + "." + c.Meta.StepName
+	}
+	c.RecordChange(change)
 }
@@ -0,0 +1,129 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+)
+
+// ComputeDefaults evaluates the computed defaults of the effective parameters, see
+// v1alpha1.Parameter.HasComputedDefault. Params are the parameters set on the instance, their values are never
+// evaluated. A computed default can reference other parameters with .Params, including other computed defaults which
+// are evaluated first. The effective parameters are returned with the computed values.
+func ComputeDefaults(definitions []v1alpha1.Parameter, params map[string]string, effective map[string]string) (map[string]string, error) {
+	computed := map[string]*v1alpha1.Parameter{}
+	for i := range definitions {
+		p := &definitions[i]
+		if _, set := params[p.Name]; set || !p.HasComputedDefault() {
+			continue
+		}
+		if _, ok := effective[p.Name]; ok {
+			computed[p.Name] = p
+		}
+	}
+	if len(computed) == 0 {
+		return effective, nil
+	}
+
+	order, err := ComputedDefaultsOrder(definitions)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(effective))
+	pending := make(map[string]bool, len(computed))
+	for k, v := range effective {
+		result[k] = v
+		if computed[k] != nil {
+			pending[k] = true
+		}
+	}
+
+	e := engine.New()
+	for _, name := range order {
+		p, ok := computed[name]
+		if !ok {
+			continue
+		}
+		// parameters whose default is not computed yet can not be referenced, the order guarantees that no default
+		// references them
+		available := make(map[string]string, len(result))
+		for k, v := range result {
+			if !pending[k] {
+				available[k] = v
+			}
+		}
+		typed, err := v1alpha1.TemplateParameters(definitions, available)
+		if err != nil {
+			return nil, err
+		}
+		value, err := e.Render(*p.Default, map[string]interface{}{"Params": typed})
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute the default of parameter %s: %v", name, err)
+		}
+		result[name] = value
+		pending[name] = false
+	}
+	return result, nil
+}
+
+// ComputedDefaultsOrder returns the names of the parameters with a computed default in the order they have to be
+// evaluated, i.e. every parameter after the computed defaults it references. It fails if computed defaults reference
+// each other in a cycle or can not be parsed.
+func ComputedDefaultsOrder(definitions []v1alpha1.Parameter) ([]string, error) {
+	e := engine.New()
+	refs := map[string][]string{}
+	for i := range definitions {
+		p := &definitions[i]
+		if !p.HasComputedDefault() {
+			continue
+		}
+		r, err := e.ParameterReferences(*p.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default of parameter %s is invalid: %v", p.Name, err)
+		}
+		refs[p.Name] = r
+	}
+
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("computed defaults of parameters reference each other in a cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		for _, ref := range refs[name] {
+			if _, ok := refs[ref]; !ok {
+				continue
+			}
+			if err := visit(ref, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
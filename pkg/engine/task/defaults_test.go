@@ -0,0 +1,69 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeDefaults(t *testing.T) {
+	definitions := []v1alpha1.Parameter{
+		{Name: "NODE_COUNT", Default: kudo.String("3")},
+		{Name: "MEMORY", Default: kudo.String("{{ mul .Params.NODE_COUNT 2 }}Gi")},
+		{Name: "HEAP", Default: kudo.String("{{ .Params.MEMORY }}")},
+		{Name: "NAME", Default: kudo.String("kafka")},
+	}
+	effective := func(params map[string]string) map[string]string {
+		ov := &v1alpha1.OperatorVersion{Spec: v1alpha1.OperatorVersionSpec{Parameters: definitions}}
+		e, _ := v1alpha1.EffectiveParameters(ov, params)
+		return e
+	}
+
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected map[string]string
+	}{
+		{name: "defaults", params: map[string]string{},
+			expected: map[string]string{"NODE_COUNT": "3", "MEMORY": "6Gi", "HEAP": "6Gi", "NAME": "kafka"}},
+		{name: "referenced parameter set", params: map[string]string{"NODE_COUNT": "5"},
+			expected: map[string]string{"NODE_COUNT": "5", "MEMORY": "10Gi", "HEAP": "10Gi", "NAME": "kafka"}},
+		{name: "computed parameter set", params: map[string]string{"MEMORY": "{{ not evaluated }}"},
+			expected: map[string]string{"NODE_COUNT": "3", "MEMORY": "{{ not evaluated }}", "HEAP": "{{ not evaluated }}", "NAME": "kafka"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			computed, err := ComputeDefaults(definitions, tt.params, effective(tt.params))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, computed)
+		})
+	}
+}
+
+func TestComputeDefaults_Invalid(t *testing.T) {
+	definitions := []v1alpha1.Parameter{
+		{Name: "MEMORY", Default: kudo.String("{{ mul .Params.MISSING 2 }}")},
+	}
+	_, err := ComputeDefaults(definitions, map[string]string{}, map[string]string{"MEMORY": *definitions[0].Default})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to compute the default of parameter MEMORY")
+}
+
+func TestComputedDefaultsOrder(t *testing.T) {
+	definitions := []v1alpha1.Parameter{
+		{Name: "A", Default: kudo.String("{{ .Params.B }}")},
+		{Name: "B", Default: kudo.String("{{ .Params.C }}")},
+		{Name: "C", Default: kudo.String("{{ .Params.D }}")},
+		{Name: "D", Default: kudo.String("1")},
+	}
+	order, err := ComputedDefaultsOrder(definitions)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"C", "B", "A"}, order)
+
+	definitions[2].Default = kudo.String("{{ .Params.A }}")
+	_, err = ComputedDefaultsOrder(definitions)
+	assert.EqualError(t, err, "computed defaults of parameters reference each other in a cycle: A -> B -> C -> A")
+}
@@ -0,0 +1,255 @@
+package task
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// redactedValue replaces the expected and actual values of drifted fields that may hold secrets
+const redactedValue = "<redacted>"
+
+// ignoredMetadata are the metadata fields that are maintained by the API server and change without being drift
+var ignoredMetadata = []string{"resourceVersion", "generation", "managedFields", "creationTimestamp", "uid", "selfLink"}
+
+// Drift renders the resources of the task like Run and compares them with the live objects. The patch the task would
+// apply is dry-run on the server, every field the patch would change was modified out-of-band. Fields that were added
+// to a live object but are not part of its template are not reported, as applying the template keeps them. Fields of
+// the last applied configuration that are not part of the template anymore are reported, as applying the template
+// removes them. If correct is set, drifted objects are applied again. Values of persisted template functions are only
+// stored if drift is corrected. The values of fields that may hold secrets are redacted, see redactFields.
+func (at ApplyTask) Drift(ctx Context, correct bool, now metav1.Time) ([]v1alpha1.ResourceDrift, error) {
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	var values engine.ValueStore = newDryRunValues(ctx.Client, ctx.Meta)
+	if correct {
+		values = newPersistedValues(ctx.Client, ctx.Meta)
+	}
+	rendered, err := render(at.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render task resources: %v", err)
+	}
+	kustomized, err := kustomize(rendered, ctx.Meta, ctx.Enhancer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to kustomize task resources: %v", err)
+	}
+	sortByKind(kustomized, at.KindOrder)
+
+	var drifts []v1alpha1.ResourceDrift
+	for _, r := range kustomized {
+		drift, err := resourceDrift(r, ctx.target())
+		if err != nil {
+			return nil, err
+		}
+		if drift == nil {
+			continue
+		}
+		drift.Fields = redactFields(r, drift.Fields, ctx)
+		if correct {
+			if _, err := apply([]runtime.Object{r}, ctx.target(), ctx.recordResource); err != nil {
+				return nil, fmt.Errorf("failed to correct drift of object %s: %v", prettyPrint(objectKey(drift)), err)
+			}
+			correctedAt := now
+			drift.CorrectedAt = &correctedAt
+		}
+		drifts = append(drifts, *drift)
+	}
+	return drifts, nil
+}
+
+// resourceDrift compares the live object with the result of a dry-run of the patch of the new object. It returns nil
+// if the live object did not drift.
+func resourceDrift(newObj runtime.Object, c client.Client) (*v1alpha1.ResourceDrift, error) {
+	drift := &v1alpha1.ResourceDrift{}
+	drift.APIVersion, drift.Kind = newObj.GetObjectKind().GroupVersionKind().ToAPIVersionAndKind()
+	if m, err := meta.Accessor(newObj); err == nil {
+		drift.Namespace, drift.Name = m.GetNamespace(), m.GetName()
+	}
+
+	key, _ := client.ObjectKeyFromObject(newObj)
+	existing := emptyCopy(newObj)
+	err := c.Get(context.TODO(), key, existing)
+	switch {
+	case apierrors.IsNotFound(err):
+		drift.Missing = true
+		return drift, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to get object %s: %v", prettyPrint(key), err)
+	}
+
+	// the patched object is returned in the object the patch is sent for, which depends on the patch type
+	newCopy, dryRun := newObj.DeepCopyObject(), existing.DeepCopyObject()
+	if err := patch(newCopy, dryRun, c, client.DryRunAll); err != nil {
+		return nil, err
+	}
+	if usesMergePatch(newObj) {
+		dryRun = newCopy
+	}
+
+	expected, err := driftValue(dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare object %s: %v", prettyPrint(key), err)
+	}
+	actual, err := driftValue(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare object %s: %v", prettyPrint(key), err)
+	}
+	drift.Fields = driftFields("", expected, actual)
+	if len(drift.Fields) == 0 {
+		return nil, nil
+	}
+	return drift, nil
+}
+
+// driftValue converts an object to its generic JSON representation without its type, its status and the metadata
+// maintained by the API server
+func driftValue(obj runtime.Object) (map[string]interface{}, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	value = without(value, "apiVersion", "kind", "status")
+	if m, ok := value["metadata"].(map[string]interface{}); ok {
+		value["metadata"] = without(m, ignoredMetadata...)
+	}
+	return value, nil
+}
+
+// without returns a copy of the map without the given keys, the builtin delete is shadowed in this package
+func without(m map[string]interface{}, keys ...string) map[string]interface{} {
+	ignored := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		ignored[k] = true
+	}
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if !ignored[k] {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// driftFields compares two generic JSON values recursively and returns the paths of all differences
+func driftFields(path string, expected, actual interface{}) []v1alpha1.FieldDrift {
+	em, eIsMap := expected.(map[string]interface{})
+	am, aIsMap := actual.(map[string]interface{})
+	if eIsMap && aIsMap {
+		keys := make([]string, 0, len(em)+len(am))
+		for k := range em {
+			keys = append(keys, k)
+		}
+		for k := range am {
+			if _, ok := em[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		var fields []v1alpha1.FieldDrift
+		for _, k := range keys {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			fields = append(fields, driftFields(p, em[k], am[k])...)
+		}
+		return fields
+	}
+
+	el, eIsList := expected.([]interface{})
+	al, aIsList := actual.([]interface{})
+	if eIsList && aIsList && len(el) == len(al) {
+		var fields []v1alpha1.FieldDrift
+		for i := range el {
+			fields = append(fields, driftFields(fmt.Sprintf("%s[%d]", path, i), el[i], al[i])...)
+		}
+		return fields
+	}
+
+	e, a := compactJSON(expected), compactJSON(actual)
+	if e == a {
+		return nil
+	}
+	return []v1alpha1.FieldDrift{{Path: path, Expected: e, Actual: a}}
+}
+
+// redactFields returns a copy of the drifted fields of the object in which the values that may hold secrets are
+// replaced with redactedValue, so that they are not stored in the status of the instance. These are the data of
+// Secrets and all values containing the value of a sensitive parameter.
+func redactFields(obj runtime.Object, fields []v1alpha1.FieldDrift, ctx Context) []v1alpha1.FieldDrift {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	secret := gvk.Group == "" && gvk.Kind == "Secret"
+
+	var sensitive []string
+	for _, p := range ctx.ParameterDefinitions {
+		if v := ctx.Parameters[p.Name]; p.Sensitive && v != "" {
+			sensitive = append(sensitive, v, base64.StdEncoding.EncodeToString([]byte(v)))
+		}
+	}
+
+	var redacted []v1alpha1.FieldDrift
+	for _, f := range fields {
+		if (secret && isSecretData(f.Path)) || containsAny(f.Expected, sensitive) || containsAny(f.Actual, sensitive) {
+			f.Expected, f.Actual = redact(f.Expected), redact(f.Actual)
+		}
+		redacted = append(redacted, f)
+	}
+	return redacted
+}
+
+func isSecretData(path string) bool {
+	for _, field := range []string{"data", "stringData"} {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, values []string) bool {
+	for _, v := range values {
+		if strings.Contains(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact keeps empty values, so that added and removed fields can still be told apart
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+func compactJSON(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func objectKey(d *v1alpha1.ResourceDrift) client.ObjectKey {
+	return client.ObjectKey{Namespace: d.Namespace, Name: d.Name}
+}
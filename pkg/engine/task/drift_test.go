@@ -0,0 +1,179 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// dryRunClient computes the result of dry-run strategic merge patches locally and does not persist dry-run deletes, as
+// the fake client ignores the dry-run option for them
+type dryRunClient struct {
+	client.Client
+}
+
+func (c dryRunClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	o := &client.PatchOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) == 0 || patch.Type() != types.StrategicMergePatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+
+	key, _ := client.ObjectKeyFromObject(obj)
+	live := emptyCopy(obj)
+	if err := c.Get(ctx, key, live); err != nil {
+		return err
+	}
+	original, err := json.Marshal(live)
+	if err != nil {
+		return err
+	}
+	data, err := patch.Data(obj)
+	if err != nil {
+		return err
+	}
+	patched, err := strategicpatch.StrategicMergePatch(original, data, live)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(obj).Elem()
+	v.Set(reflect.Zero(v.Type()))
+	return json.Unmarshal(patched, obj)
+}
+
+func (c dryRunClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	o := &client.DeleteOptions{}
+	o.ApplyOptions(opts)
+	if len(o.DryRun) == 0 {
+		return c.Client.Delete(ctx, obj, opts...)
+	}
+
+	key, _ := client.ObjectKeyFromObject(obj)
+	return c.Get(ctx, key, emptyCopy(obj))
+}
+
+func nginxPod(name, image string) *corev1.Pod {
+	p := pod(name, "default")
+	p.Spec.Containers = []corev1.Container{{Name: "nginx", Image: image}}
+	return p
+}
+
+func TestApplyTask_Drift(t *testing.T) {
+	drifted := nginxPod("drifted", "nginx:1.1")
+	drifted.Labels = map[string]string{"added": "out-of-band"}
+	c := dryRunClient{fake.NewFakeClientWithScheme(scheme.Scheme, drifted, nginxPod("unchanged", "nginx:1.0"))}
+
+	task := ApplyTask{Name: "deploy", Resources: []string{"drifted", "unchanged", "deleted"}}
+	ctx := Context{
+		Client:   c,
+		Enhancer: &testKubernetesObjectEnhancer{},
+		Meta:     ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceNamespace: "default"}},
+		Templates: map[string]string{
+			"drifted":   resourceAsString(nginxPod("drifted", "nginx:1.0")),
+			"unchanged": resourceAsString(nginxPod("unchanged", "nginx:1.0")),
+			"deleted":   resourceAsString(nginxPod("deleted", "nginx:1.0")),
+		},
+	}
+	now := metav1.NewTime(time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC))
+
+	drifts, err := task.Drift(ctx, false, now)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := map[string]v1alpha1.ResourceDrift{
+		"drifted": {
+			APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "drifted",
+			Fields: []v1alpha1.FieldDrift{{Path: "spec.containers[0].image", Expected: `"nginx:1.0"`, Actual: `"nginx:1.1"`}},
+		},
+		"deleted": {APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "deleted", Missing: true},
+	}
+	if len(drifts) != len(expected) {
+		t.Fatalf("expected drift of %d objects but got %+v", len(expected), drifts)
+	}
+	for _, d := range drifts {
+		if !reflect.DeepEqual(d, expected[d.Name]) {
+			t.Errorf("expected drift %+v but got %+v", expected[d.Name], d)
+		}
+	}
+
+	drifts, err = task.Drift(ctx, true, now)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	for _, d := range drifts {
+		if d.CorrectedAt == nil || !d.CorrectedAt.Equal(&now) {
+			t.Errorf("expected drift of %s to be corrected at %v but got %v", d.Name, now, d.CorrectedAt)
+		}
+	}
+
+	drifts, err = task.Drift(ctx, false, now)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift after the correction but got %+v", drifts)
+	}
+	corrected := &corev1.Pod{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "drifted"}, corrected); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if corrected.Labels["added"] != "out-of-band" {
+		t.Errorf("expected fields added out-of-band to be kept but got labels %v", corrected.Labels)
+	}
+}
+
+func TestApplyTask_DriftRedactsSecrets(t *testing.T) {
+	secret := func(password, user string) *corev1.Secret {
+		return &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default", Labels: map[string]string{"user": user}},
+			Data:       map[string][]byte{"password": []byte(password)},
+		}
+	}
+	c := dryRunClient{fake.NewFakeClientWithScheme(scheme.Scheme, secret("changed-out-of-band", "admin"))}
+
+	task := ApplyTask{Name: "deploy", Resources: []string{"credentials"}}
+	ctx := Context{
+		Client:               c,
+		Enhancer:             &testKubernetesObjectEnhancer{},
+		Meta:                 ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceNamespace: "default"}},
+		Templates:            map[string]string{"credentials": resourceAsString(secret("s3cr3t", "s3cr3t-user"))},
+		Parameters:           map[string]string{"USER": "s3cr3t-user"},
+		ParameterDefinitions: []v1alpha1.Parameter{{Name: "USER", Sensitive: true}},
+	}
+
+	drifts, err := task.Drift(ctx, false, metav1.Now())
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := []v1alpha1.FieldDrift{
+		{Path: "data.password", Expected: redactedValue, Actual: redactedValue},
+		{Path: "metadata.labels.user", Expected: redactedValue, Actual: redactedValue},
+	}
+	if len(drifts) != 1 || !reflect.DeepEqual(drifts[0].Fields, expected) {
+		t.Fatalf("expected redacted drift %+v but got %+v", expected, drifts)
+	}
+
+	status, err := json.Marshal(drifts)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	for _, value := range []string{"s3cr3t", "changed-out-of-band", "Y2hhbmdlZC1vdXQtb2YtYmFuZA", "czNjcjN0"} {
+		if strings.Contains(string(status), value) {
+			t.Errorf("expected drift to not contain secret value %s but got %s", value, status)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package task
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DryRunner is implemented by the tasks that can be executed in dry-run mode. A dry-run renders the resources of the
+// task like Run and sends them to the API server in dry-run mode, so that they are validated and defaulted without
+// being persisted. Values of persisted template functions are not stored either. The changes the task would make are recorded with Context.RecordChange. Health checks are skipped.
+type DryRunner interface {
+	DryRun(ctx Context) error
+}
+
+// DryRun records the objects the task would create or update
+func (at ApplyTask) DryRun(ctx Context) error {
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	rendered, err := render(at.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newDryRunValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return renderError(fmt.Errorf("failed to render task resources: %v", err), resolver)
+	}
+	kustomized, err := kustomize(rendered, ctx.Meta, ctx.Enhancer)
+	if err != nil {
+		return fmt.Errorf("%wfailed to kustomize task resources: %v", ErrFatalExecution, err)
+	}
+	sortByKind(kustomized, at.KindOrder)
+	return dryRunApply(kustomized, ctx)
+}
+
+// DryRun records the objects the task would delete
+func (dt DeleteTask) DryRun(ctx Context) error {
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	rendered, err := render(dt.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newDryRunValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return renderError(fmt.Errorf("failed to render task resources: %v", err), resolver)
+	}
+	kustomized, err := kustomize(rendered, ctx.Meta, ctx.Enhancer)
+	if err != nil {
+		return fmt.Errorf("%wfailed to kustomize task resources: %v", ErrFatalExecution, err)
+	}
+
+	for _, r := range kustomized {
+		err := ctx.target().Delete(context.TODO(), r.DeepCopyObject(), client.DryRunAll)
+		switch {
+		case apierrors.IsNotFound(err):
+			continue
+		case err != nil:
+			key, _ := client.ObjectKeyFromObject(r)
+			return fmt.Errorf("failed to delete object %s: %w", prettyPrint(key), err)
+		}
+		ctx.recordChange(r, v1alpha1.ResourceDeleted, nil)
+	}
+	return nil
+}
+
+// dryRunApply sends the objects to the API server in dry-run mode and records the objects that would be created and
+// the fields of existing objects that would be updated. The values of fields that may hold secrets are redacted like in
+// the drift of the instance. Objects that can not be adopted fail the dry-run like they
+// would fail the execution.
+func dryRunApply(ro []runtime.Object, ctx Context) error {
+	for _, r := range ro {
+		key, _ := client.ObjectKeyFromObject(r)
+		existing := emptyCopy(r)
+		err := ctx.target().Get(context.TODO(), key, existing)
+
+		switch {
+		case apierrors.IsNotFound(err):
+			if err := ctx.target().Create(context.TODO(), r.DeepCopyObject(), client.DryRunAll); err != nil {
+				return fmt.Errorf("failed to create object %s: %w", prettyPrint(key), err)
+			}
+			ctx.recordChange(r, v1alpha1.ResourceCreated, nil)
+		case err != nil:
+			return err
+		default:
+			if err := adopt(r.DeepCopyObject(), existing, ctx.target()); err != nil {
+				return err
+			}
+			drift, err := resourceDrift(r, ctx.target())
+			if err != nil {
+				return err
+			}
+			if drift != nil {
+				ctx.recordChange(r, v1alpha1.ResourceUpdated, redactFields(r, drift.Fields, ctx))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDryRun(t *testing.T) {
+	meta := ExecutionMetadata{
+		EngineMetadata: EngineMetadata{InstanceName: "test", InstanceNamespace: "default"},
+		PlanName:       "upgrade",
+		PhaseName:      "main",
+		StepName:       "app",
+		TaskName:       "task",
+	}
+	templates := map[string]string{
+		"existing": resourceAsString(nginxPod("existing", "nginx:1.17")),
+		"new":      resourceAsString(nginxPod("new", "nginx:1.17")),
+		"gone":     resourceAsString(pod("gone", "default")),
+	}
+	c := dryRunClient{fake.NewFakeClientWithScheme(scheme.Scheme, nginxPod("existing", "nginx:1.16"))}
+
+	var changes []v1alpha1.ResourceChange
+	ctx := Context{
+		Client:       c,
+		Enhancer:     &testKubernetesObjectEnhancer{},
+		Meta:         meta,
+		Templates:    templates,
+		RecordChange: func(change v1alpha1.ResourceChange) { changes = append(changes, change) },
+	}
+
+	assert.NoError(t, ApplyTask{Resources: []string{"existing"}}.DryRun(ctx))
+	assert.NoError(t, ApplyTask{Resources: []string{"new"}}.DryRun(ctx))
+	assert.NoError(t, DeleteTask{Resources: []string{"existing"}}.DryRun(ctx))
+	assert.NoError(t, DeleteTask{Resources: []string{"gone"}}.DryRun(ctx))
+
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, v1alpha1.ResourceUpdated, changes[0].Action)
+		assert.Equal(t, "existing", changes[0].Name)
+		assert.Equal(t, "main.app", changes[0].Step)
+		assert.Equal(t, "task", changes[0].Task)
+		assert.NotEmpty(t, changes[0].Fields)
+		assert.Equal(t, v1alpha1.ResourceCreated, changes[1].Action)
+		assert.Equal(t, "new", changes[1].Name)
+		assert.Equal(t, v1alpha1.ResourceDeleted, changes[2].Action)
+		assert.Equal(t, "existing", changes[2].Name)
+	}
+
+	// nothing was persisted
+	existing := &corev1.Pod{}
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "existing", Namespace: "default"}, existing))
+	assert.Equal(t, "nginx:1.16", existing.Spec.Containers[0].Image)
+	err := c.Get(context.TODO(), client.ObjectKey{Name: "new", Namespace: "default"}, &corev1.Pod{})
+	assert.True(t, apierrors.IsNotFound(err))
+}
+
+func TestDryRun_RedactsSecrets(t *testing.T) {
+	secret := func(password string) *corev1.Secret {
+		return &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+			StringData: map[string]string{"password": password},
+		}
+	}
+	c := dryRunClient{fake.NewFakeClientWithScheme(scheme.Scheme, secret("old-password"))}
+
+	var changes []v1alpha1.ResourceChange
+	ctx := Context{
+		Client:       c,
+		Enhancer:     &testKubernetesObjectEnhancer{},
+		Meta:         ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceName: "test", InstanceNamespace: "default"}},
+		Templates:    map[string]string{"credentials": resourceAsString(secret("new-password"))},
+		RecordChange: func(change v1alpha1.ResourceChange) { changes = append(changes, change) },
+	}
+
+	assert.NoError(t, ApplyTask{Resources: []string{"credentials"}}.DryRun(ctx))
+
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, []v1alpha1.FieldDrift{
+			{Path: "stringData.password", Expected: redactedValue, Actual: redactedValue},
+		}, changes[0].Fields)
+	}
+}
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RenderEndpoints renders the access endpoints of the OperatorVersion for an instance with the given parameters. The
+// endpoints reference the same values and partials as the templates and may reference other instances read with c,
+// but they can not generate persisted values. An endpoint that fails to render is returned with the error as message,
+// so that the other endpoints are still reported.
+func RenderEndpoints(c client.Client, ov *v1alpha1.OperatorVersion, params map[string]string, meta EngineMetadata) []v1alpha1.EndpointStatus {
+	if len(ov.Spec.Endpoints) == 0 {
+		return nil
+	}
+
+	configs, valuesErr := templateValues(params, ov.Spec.Parameters, ExecutionMetadata{EngineMetadata: meta})
+	e := engine.New()
+	e.Partials = partials(ov.Spec.Templates)
+	e.Files = ov.Spec.Templates
+	if c != nil {
+		e.Instances = newInstanceResolver(c, meta.InstanceNamespace)
+	}
+
+	endpoints := make([]v1alpha1.EndpointStatus, 0, len(ov.Spec.Endpoints))
+	for _, ep := range ov.Spec.Endpoints {
+		status := v1alpha1.EndpointStatus{Name: ep.Name, Description: ep.Description}
+		if valuesErr != nil {
+			status.Message = valuesErr.Error()
+			endpoints = append(endpoints, status)
+			continue
+		}
+		value, err := e.Render(ep.Value, configs)
+		if err != nil {
+			status.Message = fmt.Sprintf("failed to render endpoint: %v", err)
+		} else {
+			status.Value = value
+		}
+		endpoints = append(endpoints, status)
+	}
+	return endpoints
+}
@@ -0,0 +1,42 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderEndpoints(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		Spec: v1alpha1.OperatorVersionSpec{
+			Templates: map[string]string{
+				"_helpers.tpl": `{{ define "service" }}{{ .Name }}-svc.{{ .Namespace }}.svc{{ end }}`,
+			},
+			Parameters: []v1alpha1.Parameter{
+				{Name: "BROKER_PORT", Default: kudo.String("9092")},
+			},
+			Endpoints: []v1alpha1.AccessEndpoint{
+				{Name: "bootstrap-servers", Description: "Bootstrap servers of clients", Value: `{{ include "service" . }}:{{ .Params.BROKER_PORT }}`},
+				{Name: "metrics", Value: `http://{{ .Name }}-svc.{{ .Namespace }}:{{ .Constants.metricsPort }}/metrics`},
+				{Name: "zookeeper", Value: `{{ instanceEndpoints "zk" "client" }}`},
+			},
+		},
+	}
+	meta := EngineMetadata{
+		InstanceName:      "kafka",
+		InstanceNamespace: "team-a",
+		Constants:         map[string]interface{}{"metricsPort": 9404},
+	}
+
+	got := RenderEndpoints(nil, ov, map[string]string{"BROKER_PORT": "9093"}, meta)
+	assert.Equal(t, 3, len(got))
+	assert.Equal(t, v1alpha1.EndpointStatus{Name: "bootstrap-servers", Description: "Bootstrap servers of clients", Value: "kafka-svc.team-a.svc:9093"}, got[0])
+	assert.Equal(t, v1alpha1.EndpointStatus{Name: "metrics", Value: "http://kafka-svc.team-a:9404/metrics"}, got[1])
+	assert.Empty(t, got[2].Value)
+	assert.Contains(t, got[2].Message, "other instances can not be referenced here", "a failed endpoint does not hide the others")
+
+	assert.Nil(t, RenderEndpoints(nil, &v1alpha1.OperatorVersion{}, nil, meta))
+}
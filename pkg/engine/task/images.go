@@ -0,0 +1,92 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// containerFields are the fields of a pod spec that list containers
+var containerFields = []string{"initContainers", "containers"}
+
+// redirectImages pulls the container images of a pod or of the pod templates of a workload from the registry of the
+// settings and adds the pull secrets to the pod specs, see v1alpha1.ImageSettings. The registry is recorded in the
+// ImageRegistryAnnotation of the pods, so that kudoctl can verify that it is honored.
+func redirectImages(obj runtime.Object, images v1alpha1.ImageSettings) error {
+	if images.Registry == "" && len(images.PullSecrets) == 0 {
+		return nil
+	}
+
+	return mutatePodSpecs(obj, "redirect images", func(content map[string]interface{}, path []string) error {
+		if err := redirectPodSpec(content, path, images); err != nil {
+			return err
+		}
+		if images.Registry == "" {
+			return nil
+		}
+		annotations := map[string]string{v1alpha1.ImageRegistryAnnotation: images.Registry}
+		return propagateMetadata(content, path[:len(path)-1], nil, annotations, false)
+	})
+}
+
+// redirectPodSpec changes the images and pull secrets of the pod spec at the path
+func redirectPodSpec(content map[string]interface{}, path []string, images v1alpha1.ImageSettings) error {
+	if err := rewriteImages(content, path, images.Image); err != nil {
+		return err
+	}
+
+	if len(images.PullSecrets) == 0 {
+		return nil
+	}
+	fields := append(append([]string{}, path...), "imagePullSecrets")
+	secrets, _, err := unstructured.NestedSlice(content, fields...)
+	if err != nil {
+		return fmt.Errorf("%wfailed to add image pull secrets: %v", ErrFatalExecution, err)
+	}
+	existing := map[string]bool{}
+	for _, s := range secrets {
+		if secret, ok := s.(map[string]interface{}); ok {
+			if name, ok := secret["name"].(string); ok {
+				existing[name] = true
+			}
+		}
+	}
+	for _, name := range images.PullSecrets {
+		if !existing[name] {
+			secrets = append(secrets, map[string]interface{}{"name": name})
+		}
+	}
+	if err := unstructured.SetNestedSlice(content, secrets, fields...); err != nil {
+		return fmt.Errorf("%wfailed to add image pull secrets: %v", ErrFatalExecution, err)
+	}
+	return nil
+}
+
+// rewriteImages replaces the images of the containers and init containers of the pod spec at the path with the result
+// of rewrite
+func rewriteImages(content map[string]interface{}, path []string, rewrite func(image string) string) error {
+	for _, field := range containerFields {
+		fields := append(append([]string{}, path...), field)
+		containers, ok, err := unstructured.NestedSlice(content, fields...)
+		if err != nil {
+			return fmt.Errorf("%wfailed to rewrite images of %s: %v", ErrFatalExecution, field, err)
+		}
+		if !ok {
+			continue
+		}
+		for _, c := range containers {
+			if container, ok := c.(map[string]interface{}); ok {
+				if image, ok := container["image"].(string); ok {
+					container["image"] = rewrite(image)
+				}
+			}
+		}
+		if err := unstructured.SetNestedSlice(content, containers, fields...); err != nil {
+			return fmt.Errorf("%wfailed to rewrite images of %s: %v", ErrFatalExecution, field, err)
+		}
+	}
+	return nil
+}
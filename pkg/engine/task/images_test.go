@@ -0,0 +1,51 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1beta1 "k8s.io/api/batch/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRedirectImages(t *testing.T) {
+	images := v1alpha1.ImageSettings{Registry: "mirror.local", PullSecrets: []string{"mirror"}}
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			InitContainers:   []corev1.Container{{Name: "init", Image: "busybox"}},
+			Containers:       []corev1.Container{{Name: "app", Image: "quay.io/team/app:1.0"}, {Name: "sidecar", Image: "mirror.local/proxy"}},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "existing"}, {Name: "mirror"}},
+		}}},
+	}
+	assert.NoError(t, redirectImages(d, images))
+	assert.Equal(t, "mirror.local/busybox", d.Spec.Template.Spec.InitContainers[0].Image)
+	assert.Equal(t, "mirror.local/team/app:1.0", d.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "mirror.local/proxy", d.Spec.Template.Spec.Containers[1].Image, "images of the registry are kept")
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "existing"}, {Name: "mirror"}}, d.Spec.Template.Spec.ImagePullSecrets)
+	assert.Equal(t, map[string]string{v1alpha1.ImageRegistryAnnotation: "mirror.local"}, d.Spec.Template.Annotations)
+	assert.Nil(t, d.Annotations)
+
+	cj := &batchv1beta1.CronJob{
+		Spec: batchv1beta1.CronJobSpec{JobTemplate: batchv1beta1.JobTemplateSpec{}},
+	}
+	cj.Spec.JobTemplate.Spec.Template.Spec.Containers = []corev1.Container{{Name: "backup", Image: "backup:1"}}
+	assert.NoError(t, redirectImages(cj, images))
+	assert.Equal(t, "mirror.local/backup:1", cj.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "mirror"}}, cj.Spec.JobTemplate.Spec.Template.Spec.ImagePullSecrets)
+
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app"}}}}
+	assert.NoError(t, redirectImages(pod, v1alpha1.ImageSettings{Registry: "mirror.local"}))
+	assert.Equal(t, "mirror.local/app", pod.Spec.Containers[0].Image)
+	assert.Nil(t, pod.Spec.ImagePullSecrets)
+	assert.Equal(t, map[string]string{v1alpha1.ImageRegistryAnnotation: "mirror.local"}, pod.Annotations)
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc"}, Spec: corev1.ServiceSpec{ClusterIP: "None"}}
+	assert.NoError(t, redirectImages(svc, images))
+	assert.Equal(t, "None", svc.Spec.ClusterIP, "objects without pods are not changed")
+}
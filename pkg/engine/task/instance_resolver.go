@@ -0,0 +1,107 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// instanceResolver resolves the references of templates to other instances. Lookups are limited to Instances, their
+// OperatorVersions and Services in the namespace of the rendered instance, so templates can not use the permissions of
+// the manager to read anything else of the cluster, e.g. Secrets or objects of other namespaces.
+type instanceResolver struct {
+	client    client.Client
+	namespace string
+	// missing is set if a referenced instance or its services do not exist (yet). As the referenced instance might
+	// still be deployed, rendering is retried instead of failing the plan.
+	missing error
+}
+
+func newInstanceResolver(c client.Client, namespace string) *instanceResolver {
+	return &instanceResolver{client: c, namespace: namespace}
+}
+
+// Parameter returns the value of the parameter of the instance, or the default of its OperatorVersion if not set
+func (r *instanceResolver) Parameter(name, parameter string) (string, error) {
+	instance := &v1alpha1.Instance{}
+	if err := r.client.Get(context.TODO(), client.ObjectKey{Name: name, Namespace: r.namespace}, instance); err != nil {
+		return "", r.getError(fmt.Errorf("failed to get instance %s: %v", name, err), err)
+	}
+	ov := &v1alpha1.OperatorVersion{}
+	key := client.ObjectKey{Name: instance.Spec.OperatorVersion.Name, Namespace: instance.OperatorVersionNamespace()}
+	if key.Namespace != r.namespace {
+		return "", fmt.Errorf("operatorversion %s/%s of instance %s is not in namespace %s", key.Namespace, key.Name, name, r.namespace)
+	}
+	if err := r.client.Get(context.TODO(), key, ov); err != nil {
+		return "", r.getError(fmt.Errorf("failed to get operatorversion %s of instance %s: %v", key.Name, name, err), err)
+	}
+
+	for _, v := range v1alpha1.ParameterValues(ov, instance.Spec.Parameters) {
+		if v.Name == parameter && v.Definition != nil {
+			if v.Definition.Sensitive {
+				// the value might be encrypted, sensitive values are not shared with other instances
+				return "", fmt.Errorf("parameter %s of instance %s is sensitive and can not be referenced", parameter, name)
+			}
+			if v.Source == v1alpha1.ParameterSourceDefault && v.Definition.HasComputedDefault() {
+				return computedParameter(ov, instance, parameter)
+			}
+			return v.Value, nil
+		}
+	}
+	return "", fmt.Errorf("parameter %s is not defined in operatorversion %s of instance %s", parameter, ov.Name, name)
+}
+
+// computedParameter returns the computed default of the parameter of the instance
+func computedParameter(ov *v1alpha1.OperatorVersion, instance *v1alpha1.Instance, parameter string) (string, error) {
+	effective, _ := v1alpha1.EffectiveParameters(ov, instance.Spec.Parameters)
+	computed, err := ComputeDefaults(ov.Spec.Parameters, instance.Spec.Parameters, effective)
+	if err != nil {
+		return "", fmt.Errorf("failed to compute parameter %s of instance %s: %v", parameter, instance.Name, err)
+	}
+	return computed[parameter], nil
+}
+
+// Endpoints returns the sorted host:port endpoints of the services of the instance exposing a port with the given name
+func (r *instanceResolver) Endpoints(name, port string) ([]string, error) {
+	services := &corev1.ServiceList{}
+	if err := r.client.List(context.TODO(), services, client.InNamespace(r.namespace), client.MatchingLabels{kudo.InstanceLabel: name}); err != nil {
+		return nil, fmt.Errorf("failed to list services of instance %s: %v", name, err)
+	}
+
+	var endpoints []string
+	for _, s := range services.Items {
+		for _, p := range s.Spec.Ports {
+			if p.Name == port {
+				endpoints = append(endpoints, fmt.Sprintf("%s.%s.svc:%d", s.Name, s.Namespace, p.Port))
+			}
+		}
+	}
+	if len(endpoints) == 0 {
+		return nil, r.notFound(fmt.Errorf("no service of instance %s exposes port %s", name, port))
+	}
+	sort.Strings(endpoints)
+	return endpoints, nil
+}
+
+// getError records err as missing reference if the object could not be read because it does not exist, cause is the
+// error returned by the client. Other errors, e.g. missing permissions, are returned as they are.
+func (r *instanceResolver) getError(err, cause error) error {
+	if apierrors.IsNotFound(cause) {
+		return r.notFound(err)
+	}
+	return err
+}
+
+func (r *instanceResolver) notFound(err error) error {
+	if r.missing == nil {
+		r.missing = err
+	}
+	return err
+}
@@ -0,0 +1,96 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func service(name, namespace, instance string, ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{kudo.InstanceLabel: instance}},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+func TestInstanceResolver(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(s))
+	assert.NoError(t, v1alpha1.AddToScheme(s))
+
+	objs := []runtime.Object{
+		&v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "zk", Namespace: "default"},
+			Spec: v1alpha1.InstanceSpec{
+				OperatorVersion: corev1.ObjectReference{Name: "zookeeper-0.1.0"},
+				Parameters:      map[string]string{"NODE_COUNT": "5"},
+			},
+		},
+		&v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "zookeeper-0.1.0", Namespace: "default"},
+			Spec: v1alpha1.OperatorVersionSpec{Parameters: []v1alpha1.Parameter{
+				{Name: "NODE_COUNT", Default: kudo.String("3")},
+				{Name: "CLIENT_PORT", Default: kudo.String("2181")},
+				{Name: "SUPER_PASSWORD", Sensitive: true},
+			}},
+		},
+		service("zk-cs", "default", "zk", corev1.ServicePort{Name: "client", Port: 2181}, corev1.ServicePort{Name: "server", Port: 2888}),
+		service("zk-hs", "default", "zk", corev1.ServicePort{Name: "client", Port: 2181}),
+		service("zk-cs", "other", "zk", corev1.ServicePort{Name: "client", Port: 2181}),
+	}
+	r := newInstanceResolver(fake.NewFakeClientWithScheme(s, objs...), "default")
+
+	v, err := r.Parameter("zk", "NODE_COUNT")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", v)
+
+	v, err = r.Parameter("zk", "CLIENT_PORT")
+	assert.NoError(t, err)
+	assert.Equal(t, "2181", v)
+
+	_, err = r.Parameter("zk", "UNKNOWN")
+	assert.EqualError(t, err, "parameter UNKNOWN is not defined in operatorversion zookeeper-0.1.0 of instance zk")
+	assert.Nil(t, r.missing, "an undefined parameter is not a missing instance")
+
+	_, err = r.Parameter("zk", "SUPER_PASSWORD")
+	assert.EqualError(t, err, "parameter SUPER_PASSWORD of instance zk is sensitive and can not be referenced")
+
+	endpoints, err := r.Endpoints("zk", "client")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zk-cs.default.svc:2181", "zk-hs.default.svc:2181"}, endpoints)
+
+	_, err = r.Endpoints("kafka", "client")
+	assert.Error(t, err)
+	assert.NotNil(t, r.missing, "a missing instance is retried")
+
+	r = newInstanceResolver(fake.NewFakeClientWithScheme(s, objs...), "other")
+	_, err = r.Parameter("zk", "NODE_COUNT")
+	assert.Error(t, err, "instances of other namespaces can not be referenced")
+	assert.NotNil(t, r.missing, "a missing instance is retried")
+
+	r = newInstanceResolver(forbiddenClient{fake.NewFakeClientWithScheme(s, objs...)}, "default")
+	_, err = r.Parameter("zk", "NODE_COUNT")
+	assert.Error(t, err)
+	assert.Nil(t, r.missing, "an instance that can not be read is not missing")
+}
+
+// forbiddenClient fails to get any object because of missing permissions
+type forbiddenClient struct {
+	client.Client
+}
+
+func (c forbiddenClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return apierrors.NewForbidden(schema.GroupResource{Group: "kudo.dev", Resource: "instances"}, key.Name, errors.New("access denied"))
+}
@@ -0,0 +1,119 @@
+package task
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// setLastApplied records the configuration of the object in its last applied configuration annotation. The
+// annotation itself is not part of the recorded configuration.
+func setLastApplied(obj runtime.Object) error {
+	config := obj.DeepCopyObject()
+	configMeta, err := meta.Accessor(config)
+	if err != nil {
+		return fmt.Errorf("%wfailed to access object metadata: %v", ErrFatalExecution, err)
+	}
+	if _, ok := configMeta.GetAnnotations()[kudo.LastAppliedConfigAnnotation]; ok {
+		// the builtin delete is shadowed in this package
+		annotations := make(map[string]string, len(configMeta.GetAnnotations()))
+		for k, v := range configMeta.GetAnnotations() {
+			if k != kudo.LastAppliedConfigAnnotation {
+				annotations[k] = v
+			}
+		}
+		configMeta.SetAnnotations(annotations)
+	}
+
+	b, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("%wfailed to marshal object: %v", ErrFatalExecution, err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	objMeta, _ := meta.Accessor(obj)
+	annotations := objMeta.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[kudo.LastAppliedConfigAnnotation] = base64.StdEncoding.EncodeToString(buf.Bytes())
+	objMeta.SetAnnotations(annotations)
+	return nil
+}
+
+// lastApplied returns the configuration the object was last applied with or nil if it was not recorded, e.g. for
+// objects applied by an older KUDO version or created out-of-band
+func lastApplied(obj runtime.Object) []byte {
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return nil
+	}
+	encoded, ok := m.GetAnnotations()[kudo.LastAppliedConfigAnnotation]
+	if !ok {
+		return nil
+	}
+
+	config, err := decodeLastApplied(encoded)
+	if err != nil {
+		key, _ := client.ObjectKeyFromObject(obj)
+		log.Printf("TaskExecution: ignoring invalid last applied configuration of object %s: %v", prettyPrint(key), err)
+		return nil
+	}
+	return config
+}
+
+func decodeLastApplied(encoded string) ([]byte, error) {
+	compressed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return ioutil.ReadAll(zr)
+}
+
+// threeWayPatch computes the patch that applies the new object to the existing object. Fields that were set by the
+// last applied configuration of the existing object but were removed from the new object are deleted, fields that
+// were added to the existing object out-of-band are kept. Without a last applied configuration no fields are deleted.
+func threeWayPatch(newObj, existingObj runtime.Object) ([]byte, error) {
+	modified, err := json.Marshal(newObj)
+	if err != nil {
+		return nil, err
+	}
+	current, err := json.Marshal(existingObj)
+	if err != nil {
+		return nil, err
+	}
+	original := lastApplied(existingObj)
+
+	if usesMergePatch(newObj) {
+		return jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+	}
+	patchMeta, err := strategicpatch.NewPatchMetaFromStruct(newObj)
+	if err != nil {
+		return nil, err
+	}
+	return strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+}
@@ -0,0 +1,84 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSetLastApplied(t *testing.T) {
+	p := pod("pod1", "default")
+	p.Labels = map[string]string{"app": "nginx"}
+	assert.NoError(t, setLastApplied(p))
+	first := p.Annotations[kudo.LastAppliedConfigAnnotation]
+
+	// the recorded configuration does not contain the annotation itself
+	assert.NoError(t, setLastApplied(p))
+	assert.Equal(t, first, p.Annotations[kudo.LastAppliedConfigAnnotation])
+
+	config, err := decodeLastApplied(first)
+	assert.NoError(t, err)
+	assert.Contains(t, string(config), `"labels":{"app":"nginx"}`)
+}
+
+func TestThreeWayPatch(t *testing.T) {
+	labeledPod := func(labels map[string]string) *corev1.Pod {
+		p := pod("pod1", "default")
+		p.Labels = labels
+		return p
+	}
+
+	existing := labeledPod(map[string]string{"app": "nginx", "removed": "later"})
+	assert.NoError(t, setLastApplied(existing))
+	existing.Labels["added"] = "out-of-band"
+
+	patch, err := threeWayPatch(labeledPod(map[string]string{"app": "nginx"}), existing)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"metadata":{"labels":{"removed":null}}}`, string(patch))
+
+	// without a last applied configuration no fields are removed
+	existing.Annotations = nil
+	patch, err = threeWayPatch(labeledPod(map[string]string{"app": "nginx"}), existing)
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(patch))
+}
+
+func TestApply_ThreeWayMerge(t *testing.T) {
+	configMap := func(labels map[string]string) *unstructured.Unstructured {
+		cm := &unstructured.Unstructured{}
+		cm.SetAPIVersion("v1")
+		cm.SetKind("ConfigMap")
+		cm.SetName("cm")
+		cm.SetNamespace("default")
+		cm.SetLabels(labels)
+		return cm
+	}
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	record := Context{}.recordResource
+
+	_, err := apply([]runtime.Object{configMap(map[string]string{"app": "nginx", "removed": "later"})}, c, record)
+	assert.NoError(t, err)
+
+	// a label added out-of-band is not part of the last applied configuration
+	live := configMap(nil)
+	key := client.ObjectKey{Name: "cm", Namespace: "default"}
+	assert.NoError(t, c.Get(context.TODO(), key, live))
+	live.SetLabels(map[string]string{"app": "nginx", "removed": "later", "added": "out-of-band"})
+	assert.NoError(t, c.Update(context.TODO(), live))
+
+	_, err = apply([]runtime.Object{configMap(map[string]string{"app": "nginx"})}, c, record)
+	assert.NoError(t, err)
+
+	actual := configMap(nil)
+	assert.NoError(t, c.Get(context.TODO(), key, actual))
+	assert.Equal(t, map[string]string{"app": "nginx", "added": "out-of-band"}, actual.GetLabels())
+}
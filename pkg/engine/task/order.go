@@ -0,0 +1,70 @@
+package task
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DefaultKindOrder is the order in which the resources of a task are applied by their kind, similar to the install
+// order of Helm. Objects that other objects depend on, e.g. namespaces, CRDs, service accounts and configuration,
+// are applied before the workloads using them. Kinds that are not listed are applied last, ordered by kind.
+var DefaultKindOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+}
+
+// sortByKind sorts the objects by the position of their kind in the order override of the task followed by the
+// DefaultKindOrder. Objects of the same kind keep their order.
+func sortByKind(objs []runtime.Object, override []string) {
+	rank := make(map[string]int, len(override)+len(DefaultKindOrder))
+	for _, kinds := range [][]string{override, DefaultKindOrder} {
+		for _, kind := range kinds {
+			if _, ok := rank[kind]; !ok {
+				rank[kind] = len(rank)
+			}
+		}
+	}
+
+	kind := func(i int) string { return objs[i].GetObjectKind().GroupVersionKind().Kind }
+	sort.SliceStable(objs, func(i, j int) bool {
+		ki, kj := kind(i), kind(j)
+		ri, okI := rank[ki]
+		rj, okJ := rank[kj]
+		switch {
+		case okI && okJ:
+			return ri < rj
+		case okI != okJ:
+			return okI
+		default:
+			return ki < kj
+		}
+	})
+}
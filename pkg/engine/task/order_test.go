@@ -0,0 +1,42 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestSortByKind(t *testing.T) {
+	object := func(kind, name string) runtime.Object {
+		u := &unstructured.Unstructured{}
+		u.SetKind(kind)
+		u.SetName(name)
+		return u
+	}
+	names := func(objs []runtime.Object) []string {
+		var result []string
+		for _, o := range objs {
+			result = append(result, o.(*unstructured.Unstructured).GetName())
+		}
+		return result
+	}
+
+	objs := []runtime.Object{
+		object("StatefulSet", "cassandra"),
+		object("KafkaTopic", "events"),
+		object("ConfigMap", "cassandra-config"),
+		object("Service", "cassandra-svc"),
+		object("ConfigMap", "cassandra-jvm"),
+		object("CassandraBackup", "nightly"),
+		object("Namespace", "data"),
+		object("CustomResourceDefinition", "backups"),
+	}
+
+	sortByKind(objs, nil)
+	assert.Equal(t, []string{"data", "cassandra-config", "cassandra-jvm", "backups", "cassandra-svc", "cassandra", "nightly", "events"}, names(objs))
+
+	sortByKind(objs, []string{"KafkaTopic", "Service"})
+	assert.Equal(t, []string{"events", "cassandra-svc", "data", "cassandra-config", "cassandra-jvm", "backups", "cassandra", "nightly"}, names(objs))
+}
@@ -0,0 +1,77 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/template"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/kustomize/pkg/fs"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	apipatch "sigs.k8s.io/kustomize/pkg/patch"
+)
+
+// objectID identifies an object of the rendered templates by the name it has in the templates
+type objectID struct {
+	gvk  schema.GroupVersionKind
+	name string
+}
+
+// kustomizePatches writes the patches that select an object of the templates to the file system of kustomize and
+// returns them for the kustomization. Patches of objects that are not part of the templates, e.g. because they are
+// applied by another task, are skipped as kustomize fails on patches without a target.
+func kustomizePatches(fsys fs.FileSystem, templates map[string]string, patches []v1alpha1.Patch) ([]apipatch.StrategicMerge, []apipatch.Json6902, error) {
+	if len(patches) == 0 {
+		return []apipatch.StrategicMerge{}, nil, nil
+	}
+
+	objects := map[objectID]bool{}
+	for name, t := range templates {
+		objs, err := template.ParseKubernetesObjects(t)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing template %s before applying patches", name)
+		}
+		for _, o := range objs {
+			accessor, err := meta.Accessor(o)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "error reading the name of an object of template %s", name)
+			}
+			objects[objectID{gvk: o.GetObjectKind().GroupVersionKind(), name: accessor.GetName()}] = true
+		}
+	}
+
+	strategicMerge := []apipatch.StrategicMerge{}
+	var json6902 []apipatch.Json6902
+	for i, p := range patches {
+		selected, name, err := p.Selector()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%wpatch %d is invalid: %v", ErrFatalExecution, i+1, err)
+		}
+		if !objects[objectID{gvk: selected, name: name}] {
+			continue
+		}
+
+		path := fmt.Sprintf("kudo-patches/patch-%d.yaml", i)
+		if p.StrategicMerge != "" {
+			if err := fsys.WriteFile(fmt.Sprintf("%s/%s", basePath, path), []byte(p.StrategicMerge)); err != nil {
+				return nil, nil, errors.Wrapf(err, "error when writing patches to filesystem before applying kustomize")
+			}
+			strategicMerge = append(strategicMerge, apipatch.StrategicMerge(path))
+			continue
+		}
+		if err := fsys.WriteFile(fmt.Sprintf("%s/%s", basePath, path), []byte(p.JSON6902)); err != nil {
+			return nil, nil, errors.Wrapf(err, "error when writing patches to filesystem before applying kustomize")
+		}
+		json6902 = append(json6902, apipatch.Json6902{
+			Target: &apipatch.Target{
+				Gvk:  gvk.Gvk{Group: selected.Group, Version: selected.Version, Kind: selected.Kind},
+				Name: name,
+			},
+			Path: path,
+		})
+	}
+	return strategicMerge, json6902, nil
+}
@@ -0,0 +1,70 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+)
+
+func TestApplyConventionsToTemplates_Patches(t *testing.T) {
+	templates := map[string]string{
+		"deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:1.0
+`,
+		"service.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: 80
+`,
+	}
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{
+		InstanceName:      "instance",
+		InstanceNamespace: "default",
+		ResourcesOwner:    &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default", UID: "uid"}},
+		Patches: []v1alpha1.Patch{
+			{StrategicMerge: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\nspec:\n  replicas: 5\n"},
+			{
+				JSON6902: "- op: add\n  path: /metadata/annotations/backup\n  value: \"true\"\n",
+				Target:   &v1alpha1.PatchTarget{Version: "v1", Kind: "Service", Name: "app"},
+			},
+			// the config map is applied by another task
+			{StrategicMerge: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  key: value\n"},
+		},
+	}}
+
+	enhancer := &KustomizeEnhancer{Scheme: scheme.Scheme}
+	objs, err := enhancer.ApplyConventionsToTemplates(templates, meta)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(objs))
+	for _, o := range objs {
+		switch obj := o.(type) {
+		case *appsv1.Deployment:
+			assert.Equal(t, "instance-app", obj.Name)
+			assert.Equal(t, int32(5), *obj.Spec.Replicas)
+			assert.Equal(t, "app:1.0", obj.Spec.Template.Spec.Containers[0].Image, "strategic merge keeps the containers")
+		case *corev1.Service:
+			assert.Equal(t, "instance-app", obj.Name)
+			assert.Equal(t, "true", obj.Annotations["backup"])
+		default:
+			t.Errorf("unexpected object %v", o)
+		}
+	}
+}
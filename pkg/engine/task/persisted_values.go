@@ -0,0 +1,126 @@
+package task
+
+import (
+	"context"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// persistedValues stores the values generated by the persisted template functions of an instance in a Secret. The
+// Secret is owned by the instance, so the values survive executions of plans and are deleted with the instance.
+type persistedValues struct {
+	client client.Client
+	meta   ExecutionMetadata
+	// secret is loaded on first use, it is nil until then
+	secret *corev1.Secret
+	// exists is true if the secret was found in or created in the cluster
+	exists bool
+}
+
+func newPersistedValues(c client.Client, meta ExecutionMetadata) *persistedValues {
+	return &persistedValues{client: c, meta: meta}
+}
+
+// dryRunValues reads the persisted values of an instance but keeps values generated by a rendering that is never
+// applied in memory, so that dry-runs and drift detections do not change the persisted values
+type dryRunValues struct {
+	persisted *persistedValues
+	generated memoryValues
+}
+
+func newDryRunValues(c client.Client, meta ExecutionMetadata) *dryRunValues {
+	return &dryRunValues{persisted: newPersistedValues(c, meta), generated: memoryValues{}}
+}
+
+// Value returns the value generated by the rendering or the persisted value stored under the key
+func (d *dryRunValues) Value(key string) (string, bool, error) {
+	if value, ok, _ := d.generated.Value(key); ok {
+		return value, true, nil
+	}
+	return d.persisted.Value(key)
+}
+
+// SetValue keeps the value in memory
+func (d *dryRunValues) SetValue(key, value string) error {
+	return d.generated.SetValue(key, value)
+}
+
+// PersistedValuesSecretName returns the name of the Secret holding the persisted values of the instance
+func PersistedValuesSecretName(instanceName string) string {
+	return instanceName + "-persisted-values"
+}
+
+// Value returns the value stored under the key and whether one is stored
+func (p *persistedValues) Value(key string) (string, bool, error) {
+	if err := p.load(); err != nil {
+		return "", false, err
+	}
+	value, ok := p.secret.Data[key]
+	return string(value), ok, nil
+}
+
+// SetValue stores the value under the key. The Secret is created with the first value. Concurrent changes of the
+// Secret fail with a conflict, the task is retried then and loads the values of the other change.
+func (p *persistedValues) SetValue(key, value string) error {
+	if err := p.load(); err != nil {
+		return err
+	}
+	if p.secret.Data == nil {
+		p.secret.Data = map[string][]byte{}
+	}
+	p.secret.Data[key] = []byte(value)
+
+	if p.exists {
+		return p.client.Update(context.TODO(), p.secret)
+	}
+	if err := p.client.Create(context.TODO(), p.secret); err != nil {
+		return err
+	}
+	p.exists = true
+	return nil
+}
+
+func (p *persistedValues) load() error {
+	if p.secret != nil {
+		return nil
+	}
+	secret := &corev1.Secret{}
+	key := client.ObjectKey{Name: PersistedValuesSecretName(p.meta.InstanceName), Namespace: p.meta.InstanceNamespace}
+	err := p.client.Get(context.TODO(), key, secret)
+	switch {
+	case apierrors.IsNotFound(err):
+		p.secret = p.newSecret()
+		return nil
+	case err != nil:
+		return err
+	}
+	p.secret, p.exists = secret, true
+	return nil
+}
+
+func (p *persistedValues) newSecret() *corev1.Secret {
+	secret := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{Kind: "Secret", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      PersistedValuesSecretName(p.meta.InstanceName),
+			Namespace: p.meta.InstanceNamespace,
+			Labels: map[string]string{
+				kudo.HeritageLabel: "kudo",
+				kudo.OperatorLabel: p.meta.OperatorName,
+				kudo.InstanceLabel: p.meta.InstanceName,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+	if p.meta.ResourcesOwner != nil {
+		ref := metav1.NewControllerRef(p.meta.ResourcesOwner, v1alpha1.SchemeGroupVersion.WithKind("Instance"))
+		secret.OwnerReferences = []metav1.OwnerReference{*ref}
+	}
+	return secret
+}
@@ -0,0 +1,78 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPersistedValues(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	owner := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "default", UID: "uid"}}
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{
+		InstanceName:      "mysql",
+		InstanceNamespace: "default",
+		OperatorName:      "mysql",
+		ResourcesOwner:    owner,
+	}}
+	templates := map[string]string{
+		"secret.yaml": `apiVersion: v1
+kind: Secret
+metadata:
+  name: {{ .Name }}-credentials
+stringData:
+  password: {{ randAlphaNumPersisted "password" 12 }}
+`,
+	}
+
+	// every rendering, e.g. by another execution of the plan, uses its own store
+	first, err := render([]string{"secret.yaml"}, templates, nil, nil, nil, meta, newInstanceResolver(c, "default"), newPersistedValues(c, meta))
+	assert.NoError(t, err)
+	second, err := render([]string{"secret.yaml"}, templates, nil, nil, nil, meta, newInstanceResolver(c, "default"), newPersistedValues(c, meta))
+	assert.NoError(t, err)
+	assert.Equal(t, first, second, "expected the generated password to be persisted")
+
+	secret := &corev1.Secret{}
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "mysql-persisted-values", Namespace: "default"}, secret))
+	assert.Len(t, secret.Data["password"], 12)
+	assert.Equal(t, "mysql", secret.Labels[kudo.InstanceLabel])
+	assert.Len(t, secret.OwnerReferences, 1)
+	assert.Equal(t, "Instance", secret.OwnerReferences[0].Kind)
+
+	// further values are added to the existing secret
+	values := newPersistedValues(c, meta)
+	assert.NoError(t, values.SetValue("token", "abc"))
+	v, ok, err := newPersistedValues(c, meta).Value("token")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "abc", v)
+}
+
+func TestDryRunValues(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceName: "mysql", InstanceNamespace: "default", OperatorName: "mysql"}}
+	assert.NoError(t, newPersistedValues(c, meta).SetValue("password", "persisted"))
+
+	values := newDryRunValues(c, meta)
+	v, ok, err := values.Value("password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "persisted", v, "expected the persisted value to be used")
+
+	// generated values are not persisted
+	assert.NoError(t, values.SetValue("token", "abc"))
+	v, _, _ = values.Value("token")
+	assert.Equal(t, "abc", v)
+	_, ok, err = newPersistedValues(c, meta).Value("token")
+	assert.NoError(t, err)
+	assert.False(t, ok, "expected the generated value not to be persisted")
+}
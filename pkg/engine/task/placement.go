@@ -0,0 +1,140 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// affinityFields are the fields of an affinity that are replaced by the affinity of a placement
+var affinityFields = []string{"nodeAffinity", "podAffinity", "podAntiAffinity"}
+
+// podSpecPaths returns the paths of the pod specs of a pod or of the pod templates of a workload
+func podSpecPaths(content map[string]interface{}) [][]string {
+	candidates := [][]string{{"spec"}}
+	for _, path := range podTemplatePaths {
+		candidates = append(candidates, append(append([]string{}, path...), "spec"))
+	}
+	var paths [][]string
+	for _, path := range candidates {
+		if _, ok, _ := unstructured.NestedSlice(content, append(append([]string{}, path...), "containers")...); ok {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// mutatePodSpecs calls mutate with the path of every pod spec of a pod or of the pod templates of a workload. Typed
+// objects are converted to unstructured content and back, failures are reported as failures to do the action.
+func mutatePodSpecs(obj runtime.Object, action string, mutate func(content map[string]interface{}, path []string) error) error {
+	u, isUnstructured := obj.(*unstructured.Unstructured)
+	var content map[string]interface{}
+	if isUnstructured {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return fmt.Errorf("%wfailed to %s: %v", ErrFatalExecution, action, err)
+		}
+	}
+
+	paths := podSpecPaths(content)
+	for _, path := range paths {
+		if err := mutate(content, path); err != nil {
+			return err
+		}
+	}
+
+	if isUnstructured || len(paths) == 0 {
+		return nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return fmt.Errorf("%wfailed to %s: %v", ErrFatalExecution, action, err)
+	}
+	return nil
+}
+
+// place injects the placement of the instance into the pod specs of a pod or of the pod templates of a workload, see
+// v1alpha1.Placement
+func place(obj runtime.Object, placement *v1alpha1.Placement) error {
+	if placement.IsEmpty() {
+		return nil
+	}
+
+	return mutatePodSpecs(obj, "place pods", func(content map[string]interface{}, path []string) error {
+		return placePodSpec(content, path, placement)
+	})
+}
+
+// placePodSpec adds the node selector and the tolerations of the placement to the pod spec at the path and replaces
+// the parts of its affinity that the placement sets
+func placePodSpec(content map[string]interface{}, path []string, placement *v1alpha1.Placement) error {
+	if len(placement.NodeSelector) > 0 {
+		fields := append(append([]string{}, path...), "nodeSelector")
+		selector, _, err := unstructured.NestedStringMap(content, fields...)
+		if err != nil {
+			return fmt.Errorf("%wfailed to set node selector: %v", ErrFatalExecution, err)
+		}
+		if selector == nil {
+			selector = map[string]string{}
+		}
+		for k, v := range placement.NodeSelector {
+			selector[k] = v
+		}
+		if err := unstructured.SetNestedStringMap(content, selector, fields...); err != nil {
+			return fmt.Errorf("%wfailed to set node selector: %v", ErrFatalExecution, err)
+		}
+	}
+
+	if len(placement.Tolerations) > 0 {
+		fields := append(append([]string{}, path...), "tolerations")
+		tolerations, _, err := unstructured.NestedSlice(content, fields...)
+		if err != nil {
+			return fmt.Errorf("%wfailed to add tolerations: %v", ErrFatalExecution, err)
+		}
+		for i := range placement.Tolerations {
+			toleration, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&placement.Tolerations[i])
+			if err != nil {
+				return fmt.Errorf("%wfailed to add tolerations: %v", ErrFatalExecution, err)
+			}
+			if !containsValue(tolerations, toleration) {
+				tolerations = append(tolerations, toleration)
+			}
+		}
+		if err := unstructured.SetNestedSlice(content, tolerations, fields...); err != nil {
+			return fmt.Errorf("%wfailed to add tolerations: %v", ErrFatalExecution, err)
+		}
+	}
+
+	if placement.Affinity != nil {
+		affinity, err := runtime.DefaultUnstructuredConverter.ToUnstructured(placement.Affinity)
+		if err != nil {
+			return fmt.Errorf("%wfailed to set affinity: %v", ErrFatalExecution, err)
+		}
+		for _, field := range affinityFields {
+			value, ok := affinity[field]
+			if !ok {
+				continue
+			}
+			fields := append(append([]string{}, path...), "affinity", field)
+			if err := unstructured.SetNestedField(content, value, fields...); err != nil {
+				return fmt.Errorf("%wfailed to set affinity: %v", ErrFatalExecution, err)
+			}
+		}
+	}
+	return nil
+}
+
+// containsValue returns true if the list contains a value that is semantically equal to the value
+func containsValue(list []interface{}, value interface{}) bool {
+	for _, v := range list {
+		if equality.Semantic.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPlace(t *testing.T) {
+	dedicated := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule}
+	nodeAffinity := &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"eu-west-1a"}},
+		}}},
+	}}
+	placement := &v1alpha1.Placement{
+		NodeSelector: map[string]string{"pool": "databases"},
+		Tolerations:  []corev1.Toleration{dedicated},
+		Affinity:     &corev1.Affinity{NodeAffinity: nodeAffinity},
+	}
+
+	antiAffinity := &corev1.PodAntiAffinity{PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+		{Weight: 100, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: "kubernetes.io/hostname"}},
+	}}
+	sts := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "cassandra"},
+		Spec: appsv1.StatefulSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers:   []corev1.Container{{Name: "cassandra", Image: "cassandra"}},
+			NodeSelector: map[string]string{"pool": "default", "disk": "ssd"},
+			Tolerations:  []corev1.Toleration{dedicated},
+			Affinity:     &corev1.Affinity{PodAntiAffinity: antiAffinity},
+		}}},
+	}
+	assert.NoError(t, place(sts, placement))
+	spec := sts.Spec.Template.Spec
+	assert.Equal(t, map[string]string{"pool": "databases", "disk": "ssd"}, spec.NodeSelector)
+	assert.Equal(t, []corev1.Toleration{dedicated}, spec.Tolerations, "tolerations of the template are not duplicated")
+	assert.Equal(t, nodeAffinity, spec.Affinity.NodeAffinity)
+	assert.Equal(t, antiAffinity, spec.Affinity.PodAntiAffinity, "affinities the placement does not set are kept")
+
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"spec":       map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "app"}}},
+	}}
+	assert.NoError(t, place(pod, &v1alpha1.Placement{NodeSelector: map[string]string{"pool": "databases"}}))
+	selector, _, _ := unstructured.NestedStringMap(pod.Object, "spec", "nodeSelector")
+	assert.Equal(t, map[string]string{"pool": "databases"}, selector)
+
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc"}, Spec: corev1.ServiceSpec{ClusterIP: "None"}}
+	assert.NoError(t, place(svc, placement))
+	assert.Equal(t, "None", svc.Spec.ClusterIP, "objects without pods are not changed")
+
+	empty := sts.DeepCopy()
+	assert.NoError(t, place(empty, &v1alpha1.Placement{}))
+	assert.Equal(t, sts, empty)
+}
@@ -0,0 +1,20 @@
+package task
+
+import (
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// injectPodMetadata adds the labels and annotations of the instance to a pod or to the pod templates of a workload,
+// see v1alpha1.PodMetadata. Unlike propagated labels, they replace the values the templates set for the same keys.
+func injectPodMetadata(obj runtime.Object, podMetadata *v1alpha1.PodMetadata) error {
+	if podMetadata.IsEmpty() {
+		return nil
+	}
+
+	return mutatePodSpecs(obj, "inject pod metadata", func(content map[string]interface{}, path []string) error {
+		// the metadata of a pod or pod template is next to its spec
+		return propagateMetadata(content, path[:len(path)-1], podMetadata.Labels, podMetadata.Annotations, true)
+	})
+}
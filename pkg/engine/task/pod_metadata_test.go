@@ -0,0 +1,55 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestInjectPodMetadata(t *testing.T) {
+	podMetadata := &v1alpha1.PodMetadata{
+		Labels:      map[string]string{"team": "data"},
+		Annotations: map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "9100"},
+	}
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Labels: map[string]string{"app": "kafka"}},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels:      map[string]string{"app": "kafka"},
+				Annotations: map[string]string{"prometheus.io/scrape": "false"},
+			},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "kafka", Image: "kafka"}}},
+		}},
+	}
+	assert.NoError(t, injectPodMetadata(deployment, podMetadata))
+	template := deployment.Spec.Template
+	assert.Equal(t, map[string]string{"app": "kafka", "team": "data"}, template.Labels)
+	assert.Equal(t, map[string]string{"prometheus.io/scrape": "true", "prometheus.io/port": "9100"}, template.Annotations,
+		"annotations of the template with the same key are replaced")
+	assert.Equal(t, map[string]string{"app": "kafka"}, deployment.Labels, "the metadata of the workload is not changed")
+
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec":       map[string]interface{}{"containers": []interface{}{map[string]interface{}{"name": "app"}}},
+	}}
+	assert.NoError(t, injectPodMetadata(pod, &v1alpha1.PodMetadata{Labels: map[string]string{"team": "data"}}))
+	assert.Equal(t, map[string]string{"team": "data"}, pod.GetLabels())
+	assert.Nil(t, pod.GetAnnotations())
+
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config"}}
+	assert.NoError(t, injectPodMetadata(cm, podMetadata))
+	assert.Nil(t, cm.Labels, "objects without pods are not changed")
+
+	unchanged := deployment.DeepCopy()
+	assert.NoError(t, injectPodMetadata(unchanged, nil))
+	assert.Equal(t, deployment, unchanged)
+}
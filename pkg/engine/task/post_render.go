@@ -0,0 +1,281 @@
+package task
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+const (
+	// defaultPostRenderTimeout is the timeout of post-render webhooks that do not set one
+	defaultPostRenderTimeout = 10 * time.Second
+	// postRenderIdleConnTimeout is how long idle connections to post-render webhooks are kept open
+	postRenderIdleConnTimeout = 90 * time.Second
+)
+
+// webhookClients caches a client per post-render webhook, so that the connections to a webhook are reused across
+// executions. The client of a webhook is replaced when its configuration changes.
+var webhookClients = struct {
+	sync.Mutex
+	clients map[string]webhookClient
+}{clients: map[string]webhookClient{}}
+
+type webhookClient struct {
+	config string
+	client *http.Client
+}
+
+// PostRenderer mutates the objects of a task after its templates were rendered and the KUDO conventions were applied,
+// and before they are applied. It returns the objects to apply, which are passed to the next PostRenderer.
+type PostRenderer interface {
+	PostRender(objs []runtime.Object, meta ExecutionMetadata) ([]runtime.Object, error)
+}
+
+// PostRenderers returns the post-render stage of the settings: the built-in label stamping and image rewriting
+// followed by the webhooks
+func PostRenderers(settings v1alpha1.PostRenderSettings) []PostRenderer {
+	var renderers []PostRenderer
+	if len(settings.Labels) > 0 {
+		renderers = append(renderers, LabelStamper(settings.Labels))
+	}
+	if len(settings.ImageRewrites) > 0 {
+		renderers = append(renderers, ImageRewriter(settings.ImageRewrites))
+	}
+	for _, w := range settings.Webhooks {
+		renderers = append(renderers, &WebhookPostRenderer{Webhook: w})
+	}
+	return renderers
+}
+
+// postRender runs the objects through the post-renderers in order
+func postRender(objs []runtime.Object, meta ExecutionMetadata, renderers []PostRenderer) ([]runtime.Object, error) {
+	var err error
+	for _, r := range renderers {
+		if objs, err = r.PostRender(objs, meta); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// LabelStamper adds the labels to all objects and to the pod templates of workloads, existing labels are kept
+type LabelStamper map[string]string
+
+// PostRender implements PostRenderer
+func (s LabelStamper) PostRender(objs []runtime.Object, _ ExecutionMetadata) ([]runtime.Object, error) {
+	for _, obj := range objs {
+		if err := propagate(obj, s, nil); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+// ImageRewriter replaces the prefix of the container images of all pods with the first matching rewrite
+type ImageRewriter []v1alpha1.ImageRewrite
+
+// PostRender implements PostRenderer
+func (r ImageRewriter) PostRender(objs []runtime.Object, _ ExecutionMetadata) ([]runtime.Object, error) {
+	for _, obj := range objs {
+		if err := r.rewrite(obj); err != nil {
+			return nil, err
+		}
+	}
+	return objs, nil
+}
+
+func (r ImageRewriter) rewrite(obj runtime.Object) error {
+	return mutatePodSpecs(obj, "rewrite images", func(content map[string]interface{}, path []string) error {
+		return rewriteImages(content, path, r.image)
+	})
+}
+
+func (r ImageRewriter) image(image string) string {
+	for _, rewrite := range r {
+		if rewritten, ok := rewrite.Rewrite(image); ok {
+			return rewritten
+		}
+	}
+	return image
+}
+
+// PostRenderReview is posted to post-render webhooks with the Request set, they answer with the Response set
+type PostRenderReview struct {
+	Request  *PostRenderRequest  `json:"request,omitempty"`
+	Response *PostRenderResponse `json:"response,omitempty"`
+}
+
+// PostRenderRequest holds the objects of a task and the execution they belong to
+type PostRenderRequest struct {
+	Instance        string            `json:"instance"`
+	Namespace       string            `json:"namespace"`
+	Operator        string            `json:"operator"`
+	OperatorVersion string            `json:"operatorVersion"`
+	Plan            string            `json:"plan,omitempty"`
+	Phase           string            `json:"phase,omitempty"`
+	Step            string            `json:"step,omitempty"`
+	Task            string            `json:"task,omitempty"`
+	Objects         []json.RawMessage `json:"objects"`
+}
+
+// PostRenderResponse allows or rejects the objects. The Objects of an allowed response replace the objects of the
+// request, they are kept if it has none.
+type PostRenderResponse struct {
+	Allowed bool              `json:"allowed"`
+	Message string            `json:"message,omitempty"`
+	Objects []json.RawMessage `json:"objects,omitempty"`
+}
+
+// WebhookPostRenderer posts the objects to a post-render webhook, see v1alpha1.PostRenderWebhook
+type WebhookPostRenderer struct {
+	Webhook v1alpha1.PostRenderWebhook
+	// Client calls the webhook, a client trusting the CA bundle of the webhook is used if it is nil
+	Client *http.Client
+}
+
+// PostRender implements PostRenderer. Rejected objects fail the task, errors calling the webhook are handled
+// according to its failure policy.
+func (w *WebhookPostRenderer) PostRender(objs []runtime.Object, meta ExecutionMetadata) ([]runtime.Object, error) {
+	response, err := w.review(objs, meta)
+	if err != nil {
+		if w.Webhook.FailurePolicy == v1alpha1.PostRenderIgnore {
+			log.Printf("PostRender: Ignoring failed post-render webhook %s: %v", w.Webhook.Name, err)
+			return objs, nil
+		}
+		return nil, fmt.Errorf("post-render webhook %s failed: %v", w.Webhook.Name, err)
+	}
+	if !response.Allowed {
+		return nil, fmt.Errorf("%wobjects of task %s were rejected by post-render webhook %s: %s", ErrFatalExecution, meta.TaskName, w.Webhook.Name, response.Message)
+	}
+	if len(response.Objects) == 0 {
+		return objs, nil
+	}
+
+	mutated := make([]runtime.Object, 0, len(response.Objects))
+	for _, raw := range response.Objects {
+		obj, err := decodeObject(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%wpost-render webhook %s returned an invalid object: %v", ErrFatalExecution, w.Webhook.Name, err)
+		}
+		mutated = append(mutated, obj)
+	}
+	return mutated, nil
+}
+
+// review posts the objects to the webhook and returns its response
+func (w *WebhookPostRenderer) review(objs []runtime.Object, meta ExecutionMetadata) (*PostRenderResponse, error) {
+	request := &PostRenderRequest{
+		Instance:        meta.InstanceName,
+		Namespace:       meta.InstanceNamespace,
+		Operator:        meta.OperatorName,
+		OperatorVersion: meta.OperatorVersionName,
+		Plan:            meta.PlanName,
+		Phase:           meta.PhaseName,
+		Step:            meta.StepName,
+		Task:            meta.TaskName,
+		Objects:         make([]json.RawMessage, 0, len(objs)),
+	}
+	for _, obj := range objs {
+		raw, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode object: %v", err)
+		}
+		request.Objects = append(request.Objects, raw)
+	}
+	body, err := json.Marshal(&PostRenderReview{Request: request})
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := w.client()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(w.Webhook.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+	}
+
+	review := &PostRenderReview{}
+	if err := json.Unmarshal(data, review); err != nil {
+		return nil, fmt.Errorf("failed to decode the review: %v", err)
+	}
+	if review.Response == nil {
+		return nil, fmt.Errorf("the review has no response")
+	}
+	return review.Response, nil
+}
+
+// client returns the client of the webhook, clients are cached by the name and configuration of the webhook
+func (w *WebhookPostRenderer) client() (*http.Client, error) {
+	if w.Client != nil {
+		return w.Client, nil
+	}
+	timeout := defaultPostRenderTimeout
+	if w.Webhook.TimeoutSeconds > 0 {
+		timeout = time.Duration(w.Webhook.TimeoutSeconds) * time.Second
+	}
+	config := fmt.Sprintf("%s %s %x", w.Webhook.URL, timeout, sha256.Sum256(w.Webhook.CABundle))
+
+	webhookClients.Lock()
+	defer webhookClients.Unlock()
+	cached, ok := webhookClients.clients[w.Webhook.Name]
+	if ok && cached.config == config {
+		return cached.client, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(w.Webhook.CABundle) > 0 {
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(w.Webhook.CABundle) {
+			return nil, fmt.Errorf("the CA bundle contains no valid certificate")
+		}
+		tlsConfig.RootCAs = roots
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: tlsConfig,
+			IdleConnTimeout: postRenderIdleConnTimeout,
+		},
+	}
+	if ok {
+		cached.client.CloseIdleConnections()
+	}
+	webhookClients.clients[w.Webhook.Name] = webhookClient{config: config, client: client}
+	return client, nil
+}
+
+// decodeObject decodes an object of a known type, or an unstructured object if its type is unknown
+func decodeObject(raw []byte) (runtime.Object, error) {
+	if obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(raw, nil, nil); err == nil {
+		return obj, nil
+	}
+	u := &unstructured.Unstructured{}
+	if err := u.UnmarshalJSON(raw); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
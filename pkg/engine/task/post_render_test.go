@@ -0,0 +1,158 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPostRenderers_BuiltIn(t *testing.T) {
+	renderers := PostRenderers(v1alpha1.PostRenderSettings{
+		Labels: map[string]string{"cost-center": "platform", "app": "stamped"},
+		ImageRewrites: []v1alpha1.ImageRewrite{
+			{From: "docker.io/", To: "mirror.local/hub/"},
+			{From: "docker.io/library/", To: "never.local/"},
+		},
+	})
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Labels: map[string]string{"app": "kafka"}},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init", Image: "docker.io/library/busybox"}},
+			Containers:     []corev1.Container{{Name: "app", Image: "quay.io/team/app:1.0"}},
+		}}},
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config"}}
+
+	objs, err := postRender([]runtime.Object{d, cm}, ExecutionMetadata{}, renderers)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 2)
+	assert.Equal(t, map[string]string{"app": "kafka", "cost-center": "platform"}, d.Labels, "existing labels are kept")
+	assert.Equal(t, map[string]string{"app": "stamped", "cost-center": "platform"}, d.Spec.Template.Labels)
+	assert.Equal(t, map[string]string{"app": "stamped", "cost-center": "platform"}, cm.Labels)
+	assert.Equal(t, "mirror.local/hub/library/busybox", d.Spec.Template.Spec.InitContainers[0].Image, "the first matching rewrite is applied")
+	assert.Equal(t, "quay.io/team/app:1.0", d.Spec.Template.Spec.Containers[0].Image)
+
+	assert.Empty(t, PostRenderers(v1alpha1.PostRenderSettings{}))
+}
+
+func TestWebhookPostRenderer(t *testing.T) {
+	var request *PostRenderRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		review := &PostRenderReview{}
+		if err := json.NewDecoder(r.Body).Decode(review); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		request = review.Request
+		switch r.URL.Path {
+		case "/mutate":
+			// the policy adds a label to every object
+			response := &PostRenderResponse{Allowed: true}
+			for _, raw := range review.Request.Objects {
+				obj := map[string]interface{}{}
+				_ = json.Unmarshal(raw, &obj)
+				obj["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"policy": "applied"}
+				mutated, _ := json.Marshal(obj)
+				response.Objects = append(response.Objects, mutated)
+			}
+			review.Response = response
+		case "/allow":
+			review.Response = &PostRenderResponse{Allowed: true}
+		case "/deny":
+			review.Response = &PostRenderResponse{Allowed: false, Message: "privileged pods are not allowed"}
+		default:
+			http.Error(w, "policy engine unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(review)
+	}))
+	defer server.Close()
+
+	meta := ExecutionMetadata{
+		EngineMetadata: EngineMetadata{InstanceName: "kafka", InstanceNamespace: "default", OperatorName: "kafka", OperatorVersionName: "kafka-1.0"},
+		PlanName:       "deploy",
+		PhaseName:      "main",
+		StepName:       "app",
+		TaskName:       "app",
+	}
+	objs := func() []runtime.Object {
+		return []runtime.Object{&corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka-config", Namespace: "default"},
+			Data:       map[string]string{"key": "value"},
+		}}
+	}
+	webhook := func(path string, policy v1alpha1.PostRenderFailurePolicy) *WebhookPostRenderer {
+		return &WebhookPostRenderer{Webhook: v1alpha1.PostRenderWebhook{Name: "policies", URL: server.URL + path, FailurePolicy: policy}}
+	}
+
+	// mutated objects replace the rendered objects
+	mutated, err := webhook("/mutate", "").PostRender(objs(), meta)
+	assert.NoError(t, err)
+	if assert.Len(t, mutated, 1) {
+		cm, ok := mutated[0].(*corev1.ConfigMap)
+		if assert.True(t, ok, "expected a typed object but got %T", mutated[0]) {
+			assert.Equal(t, map[string]string{"policy": "applied"}, cm.Labels)
+			assert.Equal(t, map[string]string{"key": "value"}, cm.Data)
+		}
+	}
+	if assert.NotNil(t, request) {
+		assert.Equal(t, "kafka", request.Instance)
+		assert.Equal(t, "deploy", request.Plan)
+		assert.Equal(t, "app", request.Task)
+		assert.Len(t, request.Objects, 1)
+	}
+
+	// objects are kept if an allowed response has none
+	kept, err := webhook("/allow", "").PostRender(objs(), meta)
+	assert.NoError(t, err)
+	assert.Equal(t, objs(), kept)
+
+	// rejected objects fail the task
+	_, err = webhook("/deny", v1alpha1.PostRenderIgnore).PostRender(objs(), meta)
+	assert.True(t, errors.Is(err, ErrFatalExecution), "expected a fatal error but got %v", err)
+	assert.Contains(t, err.Error(), "privileged pods are not allowed")
+
+	// failed calls are retried unless they are ignored
+	_, err = webhook("/unavailable", "").PostRender(objs(), meta)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrFatalExecution))
+	ignored, err := webhook("/unavailable", v1alpha1.PostRenderIgnore).PostRender(objs(), meta)
+	assert.NoError(t, err)
+	assert.Equal(t, objs(), ignored)
+
+	// the CA bundle has to contain a certificate
+	invalidCA := webhook("/allow", "")
+	invalidCA.Webhook.CABundle = []byte("invalid")
+	_, err = invalidCA.PostRender(objs(), meta)
+	assert.Error(t, err)
+}
+
+func TestWebhookPostRenderer_Client(t *testing.T) {
+	webhook := v1alpha1.PostRenderWebhook{Name: "client-cache", URL: "https://policies.example.com/mutate"}
+
+	first, err := (&WebhookPostRenderer{Webhook: webhook}).client()
+	assert.NoError(t, err)
+	second, err := (&WebhookPostRenderer{Webhook: webhook}).client()
+	assert.NoError(t, err)
+	assert.True(t, first == second, "expected the client of the webhook to be reused")
+	assert.Equal(t, postRenderIdleConnTimeout, first.Transport.(*http.Transport).IdleConnTimeout)
+
+	webhook.TimeoutSeconds = 30
+	changed, err := (&WebhookPostRenderer{Webhook: webhook}).client()
+	assert.NoError(t, err)
+	assert.False(t, first == changed, "expected a new client after the webhook changed")
+	assert.Equal(t, 30*time.Second, changed.Timeout)
+}
@@ -0,0 +1,80 @@
+package task
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// podTemplatePaths are the paths of the pod templates of the workload types, the propagated labels and annotations
+// are added to the pods they create as well
+var podTemplatePaths = [][]string{
+	{"spec", "template"},
+	{"spec", "jobTemplate", "spec", "template"},
+}
+
+// propagate adds the labels and annotations to the metadata of the object and of its pod template. Existing labels
+// and annotations are kept, selectors are not modified.
+func propagate(obj runtime.Object, labels, annotations map[string]string) error {
+	if len(labels) == 0 && len(annotations) == 0 {
+		return nil
+	}
+
+	u, isUnstructured := obj.(*unstructured.Unstructured)
+	var content map[string]interface{}
+	if isUnstructured {
+		content = u.Object
+	} else {
+		var err error
+		if content, err = runtime.DefaultUnstructuredConverter.ToUnstructured(obj); err != nil {
+			return fmt.Errorf("%wfailed to propagate labels: %v", ErrFatalExecution, err)
+		}
+	}
+
+	if err := propagateMetadata(content, nil, labels, annotations, false); err != nil {
+		return err
+	}
+	for _, path := range podTemplatePaths {
+		if _, ok, _ := unstructured.NestedMap(content, path...); ok {
+			if err := propagateMetadata(content, path, labels, annotations, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if isUnstructured {
+		return nil
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj); err != nil {
+		return fmt.Errorf("%wfailed to propagate labels: %v", ErrFatalExecution, err)
+	}
+	return nil
+}
+
+// propagateMetadata adds the labels and annotations to the metadata at the path. Existing values are kept unless
+// overwrite is set.
+func propagateMetadata(content map[string]interface{}, path []string, labels, annotations map[string]string, overwrite bool) error {
+	for field, values := range map[string]map[string]string{"labels": labels, "annotations": annotations} {
+		fields := append(append(append([]string{}, path...), "metadata"), field)
+		existing, _, err := unstructured.NestedStringMap(content, fields...)
+		if err != nil {
+			return fmt.Errorf("%wfailed to propagate %s: %v", ErrFatalExecution, field, err)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if existing == nil {
+			existing = make(map[string]string, len(values))
+		}
+		for k, v := range values {
+			if _, ok := existing[k]; overwrite || !ok {
+				existing[k] = v
+			}
+		}
+		if err := unstructured.SetNestedStringMap(content, existing, fields...); err != nil {
+			return fmt.Errorf("%wfailed to propagate %s: %v", ErrFatalExecution, field, err)
+		}
+	}
+	return nil
+}
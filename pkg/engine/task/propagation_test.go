@@ -0,0 +1,38 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestPropagate(t *testing.T) {
+	labels := map[string]string{"team": "data", "app": "instance"}
+	annotations := map[string]string{"cost-center": "42"}
+
+	d := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Labels: map[string]string{"app": "template"}},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "template"}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "template"}}},
+		},
+	}
+	assert.NoError(t, propagate(d, labels, annotations))
+	assert.Equal(t, map[string]string{"app": "template", "team": "data"}, d.Labels)
+	assert.Equal(t, map[string]string{"cost-center": "42"}, d.Annotations)
+	assert.Equal(t, map[string]string{"app": "template", "team": "data"}, d.Spec.Template.Labels)
+	assert.Equal(t, map[string]string{"cost-center": "42"}, d.Spec.Template.Annotations)
+	assert.Equal(t, map[string]string{"app": "template"}, d.Spec.Selector.MatchLabels, "selectors are not modified")
+
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion("example.com/v1")
+	u.SetKind("Topic")
+	u.SetName("topic")
+	assert.NoError(t, propagate(u, labels, nil))
+	assert.Equal(t, labels, u.GetLabels())
+	assert.Nil(t, u.GetAnnotations())
+}
@@ -3,22 +3,28 @@ package task
 import (
 	"fmt"
 
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/engine"
 )
 
 // render method takes resource names and Instance parameters and then renders passed templates using kudo engine.
-func render(resourceNames []string, templates map[string]string, params map[string]string, meta ExecutionMetadata) (map[string]string, error) {
-	configs := make(map[string]interface{})
-	configs["OperatorName"] = meta.OperatorName
-	configs["Name"] = meta.InstanceName
-	configs["Namespace"] = meta.InstanceNamespace
-	configs["Params"] = params
-	configs["PlanName"] = meta.PlanName
-	configs["PhaseName"] = meta.PhaseName
-	configs["StepName"] = meta.StepName
+// References to other instances are resolved with the given resolver, they fail to render without one. Persisted
+// values are stored in the given store. The parsed templates are cached in parsed, if given.
+func render(resourceNames []string, templates map[string]string, parsed *engine.Templates, params map[string]string, definitions []v1alpha1.Parameter, meta ExecutionMetadata, resolver *instanceResolver, values engine.ValueStore) (map[string]string, error) {
+	configs, err := templateValues(params, definitions, meta)
+	if err != nil {
+		return nil, err
+	}
 
 	resources := map[string]string{}
 	engine := engine.New()
+	engine.Partials = partials(templates)
+	engine.Files = templates
+	if resolver != nil {
+		engine.Instances = resolver
+	}
+	engine.Values = values
+	engine.Templates = parsed
 
 	for _, rn := range resourceNames {
 		resource, ok := templates[rn]
@@ -27,7 +33,7 @@ func render(resourceNames []string, templates map[string]string, params map[stri
 			return nil, fmt.Errorf("error finding resource named %v for operator version %v", rn, meta.OperatorVersionName)
 		}
 
-		rendered, err := engine.Render(resource, configs)
+		rendered, err := engine.RenderTemplate(rn, resource, configs)
 		if err != nil {
 			return nil, fmt.Errorf("error expanding template: %w", err)
 		}
@@ -36,3 +42,107 @@ func render(resourceNames []string, templates map[string]string, params map[stri
 	}
 	return resources, nil
 }
+
+// renderError returns the error of a failed rendering. It is fatal unless a referenced instance was missing, which
+// might still be deployed.
+func renderError(err error, resolver *instanceResolver) error {
+	if resolver.missing != nil {
+		return fmt.Errorf("waiting for referenced instance: %v", err)
+	}
+	return fmt.Errorf("%w%v", ErrFatalExecution, err)
+}
+
+// templateValues returns the values available in templates for the given Instance parameters and execution metadata.
+// Array parameters are passed as lists and fail if they do not conform to their definition.
+func templateValues(params map[string]string, definitions []v1alpha1.Parameter, meta ExecutionMetadata) (map[string]interface{}, error) {
+	typed, err := v1alpha1.TemplateParameters(definitions, params)
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make(map[string]interface{})
+	configs["OperatorName"] = meta.OperatorName
+	configs["Name"] = meta.InstanceName
+	configs["Namespace"] = meta.InstanceNamespace
+	configs["Params"] = typed
+	configs["Constants"] = meta.Constants
+	configs["PlanName"] = meta.PlanName
+	configs["PhaseName"] = meta.PhaseName
+	configs["StepName"] = meta.StepName
+	configs["ImageRegistry"] = meta.Images.Registry
+	configs["ImagePullSecrets"] = meta.Images.PullSecrets
+	return configs, nil
+}
+
+// partials returns all templates that only define named templates for other templates to use
+func partials(templates map[string]string) map[string]string {
+	partials := map[string]string{}
+	for name, tpl := range templates {
+		if engine.IsPartial(name) {
+			partials[name] = tpl
+		}
+	}
+	return partials
+}
+
+// RenderTemplates renders the templates of the apply and delete tasks of the OperatorVersion for an instance outside
+// of a cluster, e.g. to verify a package before it is installed. Parameters that are not given use their defaults.
+// References to other instances can not be resolved, persisted values are generated but not stored. Templates that
+// fail to render are returned with their errors instead.
+func RenderTemplates(ov *v1alpha1.OperatorVersion, instanceName, namespace string, params map[string]string) (map[string]string, map[string]error) {
+	effective, _ := v1alpha1.EffectiveParameters(ov, params)
+	effective, computeErr := ComputeDefaults(ov.Spec.Parameters, params, effective)
+	constants, err := ov.TemplateConstants()
+	if err != nil {
+		computeErr = err
+	}
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{
+		InstanceName:        instanceName,
+		InstanceNamespace:   namespace,
+		OperatorName:        ov.Spec.Operator.Name,
+		OperatorVersionName: ov.Name,
+		OperatorVersion:     ov.Spec.Version,
+		Constants:           constants,
+	}}
+	values := memoryValues{}
+
+	rendered := map[string]string{}
+	failed := map[string]error{}
+	for _, t := range ov.Spec.Tasks {
+		if t.Kind != ApplyTaskKind && t.Kind != DeleteTaskKind {
+			continue
+		}
+		for _, name := range t.Spec.ResourceTaskSpec.Resources {
+			if _, ok := rendered[name]; ok {
+				continue
+			}
+			if _, ok := failed[name]; ok {
+				continue
+			}
+			if computeErr != nil {
+				failed[name] = computeErr
+				continue
+			}
+			resources, err := render([]string{name}, ov.Spec.Templates, nil, effective, ov.Spec.Parameters, meta, nil, values)
+			if err != nil {
+				failed[name] = err
+				continue
+			}
+			rendered[name] = resources[name]
+		}
+	}
+	return rendered, failed
+}
+
+// memoryValues keeps persisted values in memory for renderings that are never applied
+type memoryValues map[string]string
+
+func (m memoryValues) Value(key string) (string, bool, error) {
+	v, ok := m[key]
+	return v, ok, nil
+}
+
+func (m memoryValues) SetValue(key, value string) error {
+	m[key] = value
+	return nil
+}
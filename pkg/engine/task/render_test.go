@@ -0,0 +1,127 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/template"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestRender_ArrayParameters(t *testing.T) {
+	templates := map[string]string{
+		"services.yaml": `{{ range .Params.LISTENERS }}---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{ $.Name }}-{{ .name }}
+spec:
+  ports:
+    - port: {{ .port }}
+{{ end }}`,
+	}
+	definitions := []v1alpha1.Parameter{
+		{Name: "LISTENERS", Type: v1alpha1.ArrayParameterType, Items: &v1alpha1.ParameterItems{Type: v1alpha1.ObjectParameterType, Required: []string{"name", "port"}}},
+	}
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceName: "kafka"}}
+	resolver := newInstanceResolver(nil, "default")
+
+	params := map[string]string{"LISTENERS": "- name: client\n  port: 9092\n- name: internal\n  port: 9093"}
+	rendered, err := render([]string{"services.yaml"}, templates, nil, params, definitions, meta, resolver, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `---
+apiVersion: v1
+kind: Service
+metadata:
+  name: kafka-client
+spec:
+  ports:
+    - port: 9092
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: kafka-internal
+spec:
+  ports:
+    - port: 9093
+`, rendered["services.yaml"])
+
+	objs, err := template.ParseKubernetesObjects(rendered["services.yaml"])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(objs))
+
+	params = map[string]string{"LISTENERS": "- name: client"}
+	_, err = render([]string{"services.yaml"}, templates, nil, params, definitions, meta, resolver, nil)
+	assert.EqualError(t, err, "parameter LISTENERS is invalid: item 0 is missing required keys: port")
+}
+
+func TestRender_ImageSettings(t *testing.T) {
+	templates := map[string]string{
+		"values.yaml": `registry: {{ .ImageRegistry }}
+secrets: {{ join "," .ImagePullSecrets }}`,
+	}
+	meta := ExecutionMetadata{EngineMetadata: EngineMetadata{
+		InstanceName: "kafka",
+		Images:       v1alpha1.ImageSettings{Registry: "mirror.local", PullSecrets: []string{"mirror", "backup"}},
+	}}
+
+	rendered, err := render([]string{"values.yaml"}, templates, nil, nil, nil, meta, newInstanceResolver(nil, "default"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "registry: mirror.local\nsecrets: mirror,backup", rendered["values.yaml"])
+}
+
+func TestRenderTemplates(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		Spec: v1alpha1.OperatorVersionSpec{
+			Operator: v1.ObjectReference{Name: "kafka"},
+			Templates: map[string]string{
+				"_helpers.tpl":  `{{ define "name" }}{{ .Name }}-broker{{ end }}`,
+				"service.yaml":  `name: {{ template "name" . }}-{{ .Params.PORT }}`,
+				"secret.yaml":   `password: {{ randAlphaNumPersisted "password" 8 | len }}`,
+				"monitor.yaml":  `endpoints: {{ instanceEndpoints "zookeeper" "client" }}`,
+				"unused.yaml":   `{{ .Missing }}`,
+				"obsolete.yaml": `name: {{ .Name }}-old`,
+			},
+			Tasks: []v1alpha1.Task{
+				{Name: "app", Kind: ApplyTaskKind, Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"service.yaml", "secret.yaml", "monitor.yaml"}}}},
+				{Name: "cleanup", Kind: DeleteTaskKind, Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"obsolete.yaml", "service.yaml"}}}},
+			},
+			Parameters: []v1alpha1.Parameter{{Name: "PORT", Default: kudo.String("9092")}},
+		},
+	}
+
+	rendered, failed := RenderTemplates(ov, "kafka", "default", map[string]string{})
+	assert.Equal(t, map[string]string{
+		"service.yaml":  "name: kafka-broker-9092",
+		"secret.yaml":   "password: 8",
+		"obsolete.yaml": "name: kafka-old",
+	}, rendered)
+	assert.Len(t, failed, 1)
+	assert.Contains(t, failed["monitor.yaml"].Error(), "other instances can not be referenced here")
+
+	rendered, _ = RenderTemplates(ov, "kafka", "default", map[string]string{"PORT": "9093"})
+	assert.Equal(t, "name: kafka-broker-9093", rendered["service.yaml"])
+}
+
+func TestRenderTemplates_Constants(t *testing.T) {
+	constants, err := v1alpha1.ParseConstants([]byte("image:\n  repository: kafka\n  tag: 2.3.0\nheapSize: 1000000\n"))
+	assert.NoError(t, err)
+	ov := &v1alpha1.OperatorVersion{
+		Spec: v1alpha1.OperatorVersionSpec{
+			Templates: map[string]string{
+				"pod.yaml": `image: {{ .Constants.image.repository }}:{{ .Constants.image.tag }} heap: {{ .Constants.heapSize }}`,
+			},
+			Tasks: []v1alpha1.Task{
+				{Name: "app", Kind: ApplyTaskKind, Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"pod.yaml"}}}},
+			},
+			Constants: constants,
+		},
+	}
+
+	rendered, failed := RenderTemplates(ov, "kafka", "default", map[string]string{})
+	assert.Empty(t, failed)
+	assert.Equal(t, "image: kafka:2.3.0 heap: 1000000", rendered["pod.yaml"])
+}
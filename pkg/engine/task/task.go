@@ -21,9 +21,12 @@ type Tasker interface {
 
 // Available tasks kinds
 const (
-	ApplyTaskKind  = "Apply"
-	DeleteTaskKind = "Delete"
-	DummyTaskKind  = "Dummy"
+	ApplyTaskKind    = "Apply"
+	DeleteTaskKind   = "Delete"
+	DummyTaskKind    = "Dummy"
+	InstanceTaskKind = "Instance"
+	ExecTaskKind     = "Exec"
+	RolloutTaskKind  = "Rollout"
 )
 
 var (
@@ -40,6 +43,12 @@ func Build(task *v1alpha1.Task) (Tasker, error) {
 		return newDelete(task), nil
 	case DummyTaskKind:
 		return newDummy(task), nil
+	case InstanceTaskKind:
+		return newInstance(task), nil
+	case ExecTaskKind:
+		return newExec(task), nil
+	case RolloutTaskKind:
+		return newRollout(task), nil
 	default:
 		return nil, fmt.Errorf("%wunknown task kind %s", ErrFatalExecution, task.Kind)
 	}
@@ -49,6 +58,7 @@ func newApply(task *v1alpha1.Task) ApplyTask {
 	return ApplyTask{
 		Name:      task.Name,
 		Resources: task.Spec.ResourceTaskSpec.Resources,
+		KindOrder: task.Spec.ResourceTaskSpec.KindOrder,
 	}
 }
 
@@ -56,6 +66,7 @@ func newDelete(task *v1alpha1.Task) DeleteTask {
 	return DeleteTask{
 		Name:      task.Name,
 		Resources: task.Spec.ResourceTaskSpec.Resources,
+		KindOrder: task.Spec.ResourceTaskSpec.KindOrder,
 	}
 }
 
@@ -67,3 +78,43 @@ func newDummy(task *v1alpha1.Task) DummyTask {
 		Done:    task.Spec.DummyTaskSpec.Done,
 	}
 }
+
+func newInstance(task *v1alpha1.Task) InstanceTask {
+	name := task.Spec.InstanceTaskSpec.InstanceName
+	if name == "" {
+		name = task.Name
+	}
+	return InstanceTask{
+		Name:            task.Name,
+		InstanceName:    name,
+		OperatorVersion: task.Spec.InstanceTaskSpec.OperatorVersion,
+		Parameters:      task.Spec.InstanceTaskSpec.Parameters,
+	}
+}
+
+func newExec(task *v1alpha1.Task) ExecTask {
+	return ExecTask{
+		Name:          task.Name,
+		PodSelector:   task.Spec.ExecTaskSpec.PodSelector,
+		Container:     task.Spec.ExecTaskSpec.Container,
+		Command:       task.Spec.ExecTaskSpec.Command,
+		Order:         task.Spec.ExecTaskSpec.Order,
+		FailurePolicy: task.Spec.ExecTaskSpec.FailurePolicy,
+	}
+}
+
+func newRollout(task *v1alpha1.Task) RolloutTask {
+	rt := RolloutTask{
+		Name:      task.Name,
+		Resources: task.Spec.ResourceTaskSpec.Resources,
+		KindOrder: task.Spec.ResourceTaskSpec.KindOrder,
+		BatchSize: 1,
+	}
+	if task.Spec.RolloutTaskSpec.BatchSize != nil {
+		rt.BatchSize = *task.Spec.RolloutTaskSpec.BatchSize
+	}
+	if task.Spec.RolloutTaskSpec.Pause != nil {
+		rt.Pause = task.Spec.RolloutTaskSpec.Pause.Duration
+	}
+	return rt
+}
@@ -5,14 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/util/health"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
 	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	apijson "k8s.io/apimachinery/pkg/util/json"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -20,16 +25,19 @@ import (
 type ApplyTask struct {
 	Name      string
 	Resources []string
+	// KindOrder lists kinds that are applied before the DefaultKindOrder
+	KindOrder []string
 }
 
 // Run method for the ApplyTask. Given the task context, it renders the templates using context parameters
-// creates runtime objects and kustomizes them, and applies them using the controller client. Finally,
+// creates runtime objects and kustomizes them, and applies them ordered by kind using the controller client. Finally,
 // resources are checked for health.
 func (at ApplyTask) Run(ctx Context) (bool, error) {
 	// 1. - Render task templates -
-	rendered, err := render(at.Resources, ctx.Templates, ctx.Parameters, ctx.Meta)
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	rendered, err := render(at.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newPersistedValues(ctx.Client, ctx.Meta))
 	if err != nil {
-		return false, fmt.Errorf("%wfailed to render task resources: %v", ErrFatalExecution, err)
+		return false, renderError(fmt.Errorf("failed to render task resources: %v", err), resolver)
 	}
 
 	// 2. - Kustomize them with metadata -
@@ -37,15 +45,16 @@ func (at ApplyTask) Run(ctx Context) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("%wfailed to kustomize task resources: %v", ErrFatalExecution, err)
 	}
+	sortByKind(kustomized, at.KindOrder)
 
 	// 3. - Apply them using the client -
-	applied, err := apply(kustomized, ctx.Client)
+	applied, err := apply(kustomized, ctx.target(), ctx.recordResource)
 	if err != nil {
 		return false, err
 	}
 
 	// 4. - Check health for all resources -
-	err = isHealthy(applied, ctx.Client)
+	err = isHealthy(kustomized, applied, ctx.target(), ctx.recordResource)
 	if err != nil {
 		// so far we do not distinguish between unhealthy resources and other errors that might occur during a health check
 		// an error during a health check is not treated task execution error
@@ -56,13 +65,19 @@ func (at ApplyTask) Run(ctx Context) (bool, error) {
 }
 
 // apply method takes a slice of k8s object and applies them using passed client. If an object
-// doesn't exist it will be created. An already existing object will be patched.
-func apply(ro []runtime.Object, c client.Client) ([]runtime.Object, error) {
-	applied := make([]runtime.Object, len(ro))
+// doesn't exist it will be created. An already existing object will be patched. The configuration every object is
+// applied with is recorded in the object for the three-way merge of the next apply. Objects that can not be applied
+// are recorded as failed.
+func apply(ro []runtime.Object, c client.Client, record resourceRecorder) ([]runtime.Object, error) {
+	applied := make([]runtime.Object, 0, len(ro))
 
 	for _, r := range ro {
 		key, _ := client.ObjectKeyFromObject(r)
-		existing := r.DeepCopyObject()
+		if err := setLastApplied(r); err != nil {
+			record(r, v1alpha1.ResourceFailed, err)
+			return nil, err
+		}
+		existing := emptyCopy(r)
 
 		err := c.Get(context.TODO(), key, existing)
 
@@ -70,13 +85,22 @@ func apply(ro []runtime.Object, c client.Client) ([]runtime.Object, error) {
 		case apierrors.IsNotFound(err): // create resource if it doesn't exist
 			err = c.Create(context.TODO(), r)
 			if err != nil {
+				record(r, v1alpha1.ResourceFailed, err)
 				return nil, err
 			}
+			existing = r
 		case err != nil: // raise any error other than StatusReasonNotFound
+			record(r, v1alpha1.ResourceFailed, err)
 			return nil, err
 		default: // update existing resource
-			err := patch(r, existing, c)
+			err := adopt(r, existing, c)
+			if err != nil {
+				record(r, v1alpha1.ResourceFailed, err)
+				return nil, err
+			}
+			err = patch(r, existing, c)
 			if err != nil {
+				record(r, v1alpha1.ResourceFailed, err)
 				return nil, err
 			}
 		}
@@ -86,26 +110,42 @@ func apply(ro []runtime.Object, c client.Client) ([]runtime.Object, error) {
 	return applied, nil
 }
 
-// patch calls update method on kubernetes client to make sure the current resource reflects what is on server
+// resourceRecorder records the health of an applied object, see Context.recordResource
+type resourceRecorder func(obj runtime.Object, health v1alpha1.ResourceHealth, err error)
+
+// emptyCopy returns an empty object of the same type. Getting an object into a copy of the new object would keep all
+// fields of the new object that are not set on the server.
+func emptyCopy(obj runtime.Object) runtime.Object {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		empty := &unstructured.Unstructured{}
+		empty.SetGroupVersionKind(u.GroupVersionKind())
+		return empty
+	}
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(runtime.Object)
+}
+
+// patch calls update method on kubernetes client to make sure the current resource reflects what is on server. The
+// patch is a three-way merge of the last applied configuration of the existing object, the new object and the
+// existing object, so that fields removed from a template are removed from the object as well.
 //
 // an obvious optimization here would be to not patch when objects are the same, however that is not easy
 // kubernetes native objects might be a problem because we cannot just compare the spec as the spec might have extra fields
 // and those extra fields are set by some kubernetes component
 // because of that for now we just try to apply the patch every time
-func patch(newObj runtime.Object, existingObj runtime.Object, c client.Client) error {
-	newObjJSON, _ := apijson.Marshal(newObj)
+func patch(newObj runtime.Object, existingObj runtime.Object, c client.Client, opts ...client.PatchOption) error {
 	key, _ := client.ObjectKeyFromObject(newObj)
-	_, isUnstructured := newObj.(runtime.Unstructured)
-	_, isCRD := newObj.(*apiextv1beta1.CustomResourceDefinition)
+	newObjJSON, err := threeWayPatch(newObj, existingObj)
+	if err != nil {
+		return fmt.Errorf("failed to compute patch of object %s: %w", prettyPrint(key), err)
+	}
 
-	if isUnstructured || isCRD || isKudoType(newObj) {
-		// strategic merge patch is not supported for these types, falling back to merge patch
-		err := c.Patch(context.TODO(), newObj, client.ConstantPatch(types.MergePatchType, newObjJSON))
+	if usesMergePatch(newObj) {
+		err := c.Patch(context.TODO(), newObj, client.ConstantPatch(types.MergePatchType, newObjJSON), opts...)
 		if err != nil {
 			return fmt.Errorf("failed to apply merge patch to object %s: %w", prettyPrint(key), err)
 		}
 	} else {
-		err := c.Patch(context.TODO(), existingObj, client.ConstantPatch(types.StrategicMergePatchType, newObjJSON))
+		err := c.Patch(context.TODO(), existingObj, client.ConstantPatch(types.StrategicMergePatchType, newObjJSON), opts...)
 		if err != nil {
 			return fmt.Errorf("failed to apply StrategicMergePatch to object %s: %w", prettyPrint(key), err)
 		}
@@ -113,6 +153,64 @@ func patch(newObj runtime.Object, existingObj runtime.Object, c client.Client) e
 	return nil
 }
 
+// adopt makes sure that an existing object can be patched with the new object. Objects that are already controlled by
+// the owner of the new object (or were created by KUDO for the same instance) are managed and can always be patched.
+// Other objects are only taken over when the new object has the adopt annotation and a server-side dry-run of the patch
+// succeeds. The time of the adoption is recorded in an annotation of the new object.
+func adopt(newObj runtime.Object, existingObj runtime.Object, c client.Client) error {
+	newMeta, err := meta.Accessor(newObj)
+	if err != nil {
+		return fmt.Errorf("%wfailed to access object metadata: %v", ErrFatalExecution, err)
+	}
+	existingMeta, err := meta.Accessor(existingObj)
+	if err != nil {
+		return fmt.Errorf("%wfailed to access object metadata: %v", ErrFatalExecution, err)
+	}
+	key, _ := client.ObjectKeyFromObject(newObj)
+
+	owner := metav1.GetControllerOf(newMeta)
+	if owner == nil {
+		// no ownership is enforced for the new object
+		return nil
+	}
+	existingOwner := metav1.GetControllerOf(existingMeta)
+	switch {
+	case existingOwner != nil && existingOwner.UID == owner.UID:
+		return nil
+	case existingOwner != nil:
+		return fmt.Errorf("%wobject %s already exists and is controlled by %s %s", ErrFatalExecution, prettyPrint(key), existingOwner.Kind, existingOwner.Name)
+	case existingMeta.GetLabels()[kudo.HeritageLabel] == "kudo" &&
+		existingMeta.GetLabels()[kudo.InstanceLabel] == newMeta.GetLabels()[kudo.InstanceLabel]:
+		// created by KUDO for this instance before controller references were set
+		return nil
+	}
+
+	if newMeta.GetAnnotations()[kudo.AdoptAnnotation] != "true" {
+		return fmt.Errorf("%wobject %s already exists and is not managed by KUDO, set the annotation %s: \"true\" in its template to adopt it",
+			ErrFatalExecution, prettyPrint(key), kudo.AdoptAnnotation)
+	}
+
+	// check that the existing object is compatible with the template without modifying it
+	err = patch(newObj.DeepCopyObject(), existingObj.DeepCopyObject(), c, client.DryRunAll)
+	if err != nil {
+		return fmt.Errorf("%wobject %s can not be adopted: %v", ErrFatalExecution, prettyPrint(key), err)
+	}
+
+	annotations := newMeta.GetAnnotations()
+	annotations[kudo.AdoptedAtAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	newMeta.SetAnnotations(annotations)
+	log.Printf("TaskExecution: adopting existing object %s", prettyPrint(key))
+	return nil
+}
+
+// usesMergePatch returns true if the object is patched with a merge patch as strategic merge patch is not supported
+// for its type. The patched object is returned in the new object in that case, otherwise in the existing object.
+func usesMergePatch(obj runtime.Object) bool {
+	_, isUnstructured := obj.(runtime.Unstructured)
+	_, isCRD := obj.(*apiextv1beta1.CustomResourceDefinition)
+	return isUnstructured || isCRD || isKudoType(obj)
+}
+
 func isKudoType(object runtime.Object) bool {
 	_, isOperator := object.(*v1alpha1.OperatorVersion)
 	_, isOperatorVersion := object.(*v1alpha1.Operator)
@@ -120,15 +218,23 @@ func isKudoType(object runtime.Object) bool {
 	return isOperator || isOperatorVersion || isInstance
 }
 
-func isHealthy(ro []runtime.Object, c client.Client) error {
-	for _, r := range ro {
+// isHealthy checks the health of all applied objects and records it for the objects they were applied from. The
+// first unhealthy object is returned as error.
+func isHealthy(ro []runtime.Object, applied []runtime.Object, c client.Client, record resourceRecorder) error {
+	var unhealthy error
+	for i, r := range applied {
 		err := health.IsHealthy(c, r)
 		if err != nil {
-			key, _ := client.ObjectKeyFromObject(r)
-			return fmt.Errorf("object %s is NOT healthy: %w", prettyPrint(key), err)
+			record(ro[i], v1alpha1.ResourceProgressing, err)
+			if unhealthy == nil {
+				key, _ := client.ObjectKeyFromObject(r)
+				unhealthy = fmt.Errorf("object %s is NOT healthy: %w", prettyPrint(key), err)
+			}
+			continue
 		}
+		record(ro[i], v1alpha1.ResourceReady, nil)
 	}
-	return nil
+	return unhealthy
 }
 
 func prettyPrint(key client.ObjectKey) string {
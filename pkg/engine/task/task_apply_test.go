@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/yaml"
 )
@@ -184,3 +186,97 @@ type errKubernetesObjectEnhancer struct{}
 func (k *errKubernetesObjectEnhancer) ApplyConventionsToTemplates(templates map[string]string, metadata ExecutionMetadata) ([]runtime.Object, error) {
 	return nil, errors.New("always error")
 }
+
+func TestApply_Adoption(t *testing.T) {
+	owner := metav1.OwnerReference{APIVersion: "kudo.dev/v1alpha1", Kind: "Instance", Name: "test", UID: "1", Controller: &[]bool{true}[0]}
+	otherOwner := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "other", UID: "2", Controller: &[]bool{true}[0]}
+
+	withOwner := func(p *corev1.Pod, ref metav1.OwnerReference) *corev1.Pod {
+		p.OwnerReferences = []metav1.OwnerReference{ref}
+		return p
+	}
+	withAnnotations := func(p *corev1.Pod, annotations map[string]string) *corev1.Pod {
+		p.Annotations = annotations
+		return p
+	}
+	withLabels := func(p *corev1.Pod, labels map[string]string) *corev1.Pod {
+		p.Labels = labels
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		existing *corev1.Pod
+		new      *corev1.Pod
+		wantErr  bool
+		adopted  bool
+	}{
+		{
+			name:     "patches object controlled by the same owner",
+			existing: withOwner(pod("pod1", "default"), owner),
+			new:      withOwner(pod("pod1", "default"), owner),
+		},
+		{
+			name:     "patches object created by KUDO for the same instance",
+			existing: withLabels(pod("pod1", "default"), map[string]string{"heritage": "kudo", "kudo.dev/instance": "test"}),
+			new:      withLabels(withOwner(pod("pod1", "default"), owner), map[string]string{"heritage": "kudo", "kudo.dev/instance": "test"}),
+		},
+		{
+			name:     "fails for object controlled by another owner",
+			existing: withOwner(pod("pod1", "default"), otherOwner),
+			new:      withAnnotations(withOwner(pod("pod1", "default"), owner), map[string]string{"kudo.dev/adopt": "true"}),
+			wantErr:  true,
+		},
+		{
+			name:     "fails for unmanaged object without adopt annotation",
+			existing: pod("pod1", "default"),
+			new:      withOwner(pod("pod1", "default"), owner),
+			wantErr:  true,
+		},
+		{
+			name:     "adopts unmanaged object with adopt annotation",
+			existing: pod("pod1", "default"),
+			new:      withAnnotations(withOwner(pod("pod1", "default"), owner), map[string]string{"kudo.dev/adopt": "true"}),
+			adopted:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		c := fake.NewFakeClientWithScheme(scheme.Scheme, tt.existing)
+
+		_, err := apply([]runtime.Object{tt.new}, c, Context{}.recordResource)
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			assert.True(t, errors.Is(err, ErrFatalExecution), tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+
+		actual := &corev1.Pod{}
+		assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "pod1", Namespace: "default"}, actual), tt.name)
+		assert.Equal(t, "1", string(metav1.GetControllerOf(actual).UID), tt.name)
+		_, adopted := actual.Annotations["kudo.dev/adopted-at"]
+		assert.Equal(t, tt.adopted, adopted, tt.name)
+	}
+}
+
+func TestApplyTask_TargetClient(t *testing.T) {
+	local := fake.NewFakeClientWithScheme(scheme.Scheme)
+	target := fake.NewFakeClientWithScheme(scheme.Scheme)
+	task := ApplyTask{Name: "task", Resources: []string{"pod"}}
+	ctx := Context{
+		Client:       local,
+		TargetClient: target,
+		Enhancer:     &testKubernetesObjectEnhancer{},
+		Meta:         ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceNamespace: "default", TargetCluster: "remote"}},
+		Templates:    map[string]string{"pod": resourceAsString(pod("pod1", "default"))},
+	}
+
+	done, err := task.Run(ctx)
+	assert.NoError(t, err)
+	assert.True(t, done)
+
+	key := client.ObjectKey{Name: "pod1", Namespace: "default"}
+	assert.NoError(t, target.Get(context.TODO(), key, &corev1.Pod{}), "the pod is applied to the target cluster")
+	assert.Error(t, local.Get(context.TODO(), key, &corev1.Pod{}), "the pod is not applied to the cluster of the instance")
+}
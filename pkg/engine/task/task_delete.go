@@ -14,15 +14,19 @@ import (
 type DeleteTask struct {
 	Name      string
 	Resources []string
+	// KindOrder lists kinds that are applied before the DefaultKindOrder, they are deleted in the reverse order
+	KindOrder []string
 }
 
 // Run method for the DeleteTask. Given the task context, it renders the templates using context parameters
-// creates runtime objects and kustomizes them, and finally removes them using the controller client.
+// creates runtime objects and kustomizes them, and finally removes them using the controller client. Objects are
+// deleted in the reverse order they are applied in, e.g. workloads before their ConfigMaps.
 func (dt DeleteTask) Run(ctx Context) (bool, error) {
 	// 1. - Render task templates -
-	rendered, err := render(dt.Resources, ctx.Templates, ctx.Parameters, ctx.Meta)
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	rendered, err := render(dt.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newPersistedValues(ctx.Client, ctx.Meta))
 	if err != nil {
-		return false, fmt.Errorf("%wfailed to render task resources: %v", ErrFatalExecution, err)
+		return false, renderError(fmt.Errorf("failed to render task resources: %v", err), resolver)
 	}
 
 	// 2. - Kustomize them with metadata -
@@ -30,9 +34,13 @@ func (dt DeleteTask) Run(ctx Context) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("%wfailed to kustomize task resources: %v", ErrFatalExecution, err)
 	}
+	sortByKind(kustomized, dt.KindOrder)
+	for i, j := 0, len(kustomized)-1; i < j; i, j = i+1, j-1 {
+		kustomized[i], kustomized[j] = kustomized[j], kustomized[i]
+	}
 
 	// 3. - Delete them using the client -
-	err = delete(kustomized, ctx.Client)
+	err = delete(kustomized, ctx.target())
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,195 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxOutputLength is the number of trailing characters of the command output recorded in the step status
+const maxOutputLength = 512
+
+// PodExecutor runs commands in containers of pods and returns their stdout and stderr
+type PodExecutor interface {
+	Exec(namespace, pod, container string, command []string) (string, string, error)
+}
+
+// ExecTask runs a command in the pods of the instance selected by labels. See Run method for more details.
+type ExecTask struct {
+	Name          string
+	PodSelector   map[string]string
+	Container     string
+	Command       []string
+	Order         v1alpha1.ExecOrder
+	FailurePolicy v1alpha1.ExecFailurePolicy
+}
+
+// execResult is the outcome of the command in a single pod
+type execResult struct {
+	pod    *corev1.Pod
+	output string
+	err    error
+}
+
+// Run method for the ExecTask. Given the task context, it renders the command and the pod selector with the context
+// parameters and runs the command in all selected pods of the instance, one after the other or in parallel. The task
+// waits until all selected pods are running. The output of the command in each pod is recorded in the step status. A
+// failing command fails the plan, retries the task or is ignored, depending on the failure policy. As retries run the
+// command in all pods again, commands should be idempotent. Once the command succeeded, the task is not run again
+// while the other tasks of the step are in progress.
+func (et ExecTask) Run(ctx Context) (bool, error) {
+	if ctx.Executor == nil {
+		return false, fmt.Errorf("%wexec task %s can not be run, executing commands in pods is not available", ErrFatalExecution, et.Name)
+	}
+	if len(et.Command) == 0 {
+		return false, fmt.Errorf("%wexec task %s has no command", ErrFatalExecution, et.Name)
+	}
+
+	// 1. - Render the command and the pod selector -
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	command, selector, err := et.render(ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newPersistedValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return false, renderError(fmt.Errorf("failed to render exec task %s: %v", et.Name, err), resolver)
+	}
+
+	// 2. - Find the selected pods and wait for them to run -
+	pods := &corev1.PodList{}
+	if err := ctx.target().List(context.TODO(), pods, client.InNamespace(ctx.Meta.InstanceNamespace), client.MatchingLabels(selector)); err != nil {
+		return false, fmt.Errorf("failed to list pods of exec task %s: %v", et.Name, err)
+	}
+	if len(pods.Items) == 0 {
+		return false, fmt.Errorf("no pods of exec task %s match the selector %v", et.Name, selector)
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for i := range pods.Items {
+		pods.Items[i].TypeMeta = metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"}
+		if pods.Items[i].Status.Phase != corev1.PodRunning {
+			log.Printf("TaskExecution: exec task %s is waiting for pod %s/%s to run", et.Name, pods.Items[i].Namespace, pods.Items[i].Name)
+			return false, nil
+		}
+	}
+
+	// 3. - Run the command -
+	results := et.exec(ctx.Executor, pods.Items, command)
+
+	// 4. - Record the results and apply the failure policy -
+	var failures []string
+	for _, r := range results {
+		if r.err != nil {
+			ctx.recordResource(r.pod, v1alpha1.ResourceFailed, outputError(r.err, r.output))
+			failures = append(failures, fmt.Sprintf("%s: %v", r.pod.Name, r.err))
+			continue
+		}
+		ctx.recordResource(r.pod, v1alpha1.ResourceReady, outputError(nil, r.output))
+	}
+	if len(failures) == 0 {
+		return true, nil
+	}
+
+	err = fmt.Errorf("exec task %s failed in pods %s", et.Name, strings.Join(failures, ", "))
+	switch et.FailurePolicy {
+	case v1alpha1.ExecIgnore:
+		log.Printf("TaskExecution: ignoring failure: %v", err)
+		return true, nil
+	case v1alpha1.ExecRetry:
+		return false, err
+	default:
+		return false, fmt.Errorf("%w%v", ErrFatalExecution, err)
+	}
+}
+
+// render renders the arguments of the command and the values of the pod selector
+func (et ExecTask) render(params map[string]string, definitions []v1alpha1.Parameter, meta ExecutionMetadata, resolver *instanceResolver, values engine.ValueStore) ([]string, map[string]string, error) {
+	engine := engine.New()
+	engine.Instances = resolver
+	engine.Values = values
+	configs, err := templateValues(params, definitions, meta)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	command := make([]string, len(et.Command))
+	for i, arg := range et.Command {
+		if command[i], err = engine.Render(arg, configs); err != nil {
+			return nil, nil, fmt.Errorf("error expanding command argument %d: %w", i, err)
+		}
+	}
+	selector := make(map[string]string, len(et.PodSelector)+1)
+	for k, v := range et.PodSelector {
+		if selector[k], err = engine.Render(v, configs); err != nil {
+			return nil, nil, fmt.Errorf("error expanding pod selector %s: %w", k, err)
+		}
+	}
+	// only pods of the instance are selected, even if the labels of the selector are used by other instances as well
+	selector[kudo.InstanceLabel] = meta.InstanceName
+	return command, selector, nil
+}
+
+// exec runs the command in the pods in the configured order. Sequential execution stops at the first failure.
+func (et ExecTask) exec(executor PodExecutor, pods []corev1.Pod, command []string) []execResult {
+	run := func(pod *corev1.Pod) execResult {
+		container := et.Container
+		if container == "" && len(pod.Spec.Containers) > 0 {
+			container = pod.Spec.Containers[0].Name
+		}
+		log.Printf("TaskExecution: exec task %s runs %v in pod %s/%s", et.Name, command, pod.Namespace, pod.Name)
+		stdout, stderr, err := executor.Exec(pod.Namespace, pod.Name, container, command)
+		if err != nil {
+			return execResult{pod: pod, output: stderr, err: err}
+		}
+		return execResult{pod: pod, output: stdout}
+	}
+
+	results := make([]execResult, len(pods))
+	if et.Order == v1alpha1.ExecParallel {
+		var wg sync.WaitGroup
+		for i := range pods {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = run(&pods[i])
+			}(i)
+		}
+		wg.Wait()
+		return results
+	}
+
+	for i := range pods {
+		results[i] = run(&pods[i])
+		if results[i].err != nil {
+			return results[:i+1]
+		}
+	}
+	return results
+}
+
+// outputError returns the error and the trailing output of a command as error, so they are recorded as message of
+// the pod status
+func outputError(err error, output string) error {
+	output = strings.TrimSpace(output)
+	if len(output) > maxOutputLength {
+		output = "..." + output[len(output)-maxOutputLength:]
+	}
+	switch {
+	case err != nil && output != "":
+		return fmt.Errorf("%v: %s", err, output)
+	case err != nil:
+		return err
+	case output != "":
+		return errors.New(output)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeExecutor records the commands it runs and fails in the given pods
+type fakeExecutor struct {
+	mu       sync.Mutex
+	commands []string
+	failing  map[string]bool
+}
+
+func (e *fakeExecutor) Exec(namespace, pod, container string, command []string) (string, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.commands = append(e.commands, fmt.Sprintf("%s/%s/%s: %v", namespace, pod, container, command))
+	if e.failing[pod] {
+		return "", "drain failed", errors.New("command exited with code 1")
+	}
+	return "drained " + pod, "", nil
+}
+
+func execPod(name string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "cassandra", kudo.InstanceLabel: "cassandra"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "cassandra"}, {Name: "sidecar"}}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestExecTask_Run(t *testing.T) {
+	meta := ExecutionMetadata{
+		EngineMetadata: EngineMetadata{
+			InstanceName:      "cassandra",
+			InstanceNamespace: "default",
+		},
+	}
+	task := ExecTask{
+		Name:        "drain",
+		PodSelector: map[string]string{"app": "{{ .Name }}"},
+		Command:     []string{"nodetool", "drain", "--port={{ .Params.PORT }}"},
+	}
+	otherInstancePod := execPod("cassandra-backup-0", corev1.PodRunning)
+	otherInstancePod.Labels[kudo.InstanceLabel] = "cassandra-backup"
+	withPolicy := func(policy v1alpha1.ExecFailurePolicy) ExecTask {
+		t := task
+		t.FailurePolicy = policy
+		return t
+	}
+
+	tests := []struct {
+		name      string
+		task      ExecTask
+		objects   []runtime.Object
+		failing   map[string]bool
+		noExec    bool
+		done      bool
+		wantErr   bool
+		fatal     bool
+		commands  []string
+		resources []v1alpha1.ResourceStatus
+	}{
+		{
+			name:    "fails without executor",
+			task:    task,
+			noExec:  true,
+			wantErr: true,
+			fatal:   true,
+		},
+		{
+			name:    "fails when no pods are selected",
+			task:    task,
+			wantErr: true,
+		},
+		{
+			name:    "does not select pods of other instances",
+			task:    task,
+			objects: []runtime.Object{otherInstancePod},
+			wantErr: true,
+		},
+		{
+			name:    "waits for selected pods to run",
+			task:    task,
+			objects: []runtime.Object{execPod("cassandra-0", corev1.PodRunning), execPod("cassandra-1", corev1.PodPending)},
+		},
+		{
+			name:     "runs the command in all pods ordered by name",
+			task:     task,
+			objects:  []runtime.Object{execPod("cassandra-1", corev1.PodRunning), execPod("cassandra-0", corev1.PodRunning)},
+			done:     true,
+			commands: []string{"default/cassandra-0/cassandra: [nodetool drain --port=7199]", "default/cassandra-1/cassandra: [nodetool drain --port=7199]"},
+			resources: []v1alpha1.ResourceStatus{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "cassandra-0", Health: v1alpha1.ResourceReady, Message: "drained cassandra-0"},
+				{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "cassandra-1", Health: v1alpha1.ResourceReady, Message: "drained cassandra-1"},
+			},
+		},
+		{
+			name:     "stops at the first failing pod and fails the plan",
+			task:     task,
+			objects:  []runtime.Object{execPod("cassandra-0", corev1.PodRunning), execPod("cassandra-1", corev1.PodRunning)},
+			failing:  map[string]bool{"cassandra-0": true},
+			wantErr:  true,
+			fatal:    true,
+			commands: []string{"default/cassandra-0/cassandra: [nodetool drain --port=7199]"},
+			resources: []v1alpha1.ResourceStatus{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "cassandra-0", Health: v1alpha1.ResourceFailed, Message: "command exited with code 1: drain failed"},
+			},
+		},
+		{
+			name:     "retries failures with retry policy",
+			task:     withPolicy(v1alpha1.ExecRetry),
+			objects:  []runtime.Object{execPod("cassandra-0", corev1.PodRunning)},
+			failing:  map[string]bool{"cassandra-0": true},
+			wantErr:  true,
+			commands: []string{"default/cassandra-0/cassandra: [nodetool drain --port=7199]"},
+			resources: []v1alpha1.ResourceStatus{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "cassandra-0", Health: v1alpha1.ResourceFailed, Message: "command exited with code 1: drain failed"},
+			},
+		},
+		{
+			name:     "ignores failures with ignore policy",
+			task:     withPolicy(v1alpha1.ExecIgnore),
+			objects:  []runtime.Object{execPod("cassandra-0", corev1.PodRunning)},
+			failing:  map[string]bool{"cassandra-0": true},
+			done:     true,
+			commands: []string{"default/cassandra-0/cassandra: [nodetool drain --port=7199]"},
+			resources: []v1alpha1.ResourceStatus{
+				{APIVersion: "v1", Kind: "Pod", Namespace: "default", Name: "cassandra-0", Health: v1alpha1.ResourceFailed, Message: "command exited with code 1: drain failed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		executor := &fakeExecutor{failing: tt.failing}
+		var resources []v1alpha1.ResourceStatus
+		ctx := Context{
+			Client:         fake.NewFakeClientWithScheme(scheme.Scheme, tt.objects...),
+			Meta:           meta,
+			Parameters:     map[string]string{"PORT": "7199"},
+			Executor:       executor,
+			RecordResource: func(s v1alpha1.ResourceStatus) { resources = append(resources, s) },
+		}
+		if tt.noExec {
+			ctx.Executor = nil
+		}
+
+		done, err := tt.task.Run(ctx)
+		assert.Equal(t, tt.done, done, tt.name)
+		assert.Equal(t, tt.wantErr, err != nil, "%s: unexpected error %v", tt.name, err)
+		assert.Equal(t, tt.fatal, errors.Is(err, ErrFatalExecution), "%s: unexpected error %v", tt.name, err)
+		assert.Equal(t, tt.commands, executor.commands, tt.name)
+		assert.Equal(t, tt.resources, resources, tt.name)
+	}
+}
+
+func TestExecTask_Parallel(t *testing.T) {
+	executor := &fakeExecutor{failing: map[string]bool{"cassandra-0": true}}
+	ctx := Context{
+		Client:   fake.NewFakeClientWithScheme(scheme.Scheme, execPod("cassandra-0", corev1.PodRunning), execPod("cassandra-1", corev1.PodRunning)),
+		Meta:     ExecutionMetadata{EngineMetadata: EngineMetadata{InstanceName: "cassandra", InstanceNamespace: "default"}},
+		Executor: executor,
+	}
+	task := ExecTask{
+		Name:          "drain",
+		PodSelector:   map[string]string{"app": "cassandra"},
+		Container:     "sidecar",
+		Command:       []string{"drain"},
+		Order:         v1alpha1.ExecParallel,
+		FailurePolicy: v1alpha1.ExecRetry,
+	}
+
+	done, err := task.Run(ctx)
+	assert.False(t, done)
+	assert.EqualError(t, err, "exec task drain failed in pods cassandra-0: command exited with code 1")
+	assert.ElementsMatch(t, []string{"default/cassandra-0/sidecar: [drain]", "default/cassandra-1/sidecar: [drain]"}, executor.commands)
+}
@@ -0,0 +1,185 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// InstanceTask creates an Instance of another operator that is owned by the current instance, so it is deleted
+// together with it. See Run method for more details.
+type InstanceTask struct {
+	Name string
+	// InstanceName is the name of the created instance before kustomize prefixes it with the name of the owning
+	// instance like all other objects, e.g. "zk" becomes "platform-zk"
+	InstanceName    string
+	OperatorVersion string
+	Parameters      map[string]string
+}
+
+// Run method for the InstanceTask. Given the task context, it renders the parameters of the new instance using context
+// parameters, kustomizes the instance with metadata and applies it using the controller client. The task is done
+// once the created instance has finished executing its active plan.
+func (it InstanceTask) Run(ctx Context) (bool, error) {
+	// 1. - Render and kustomize the instance -
+	kustomized, err := it.objects(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	// 2. - Apply it using the client -
+	if _, err := apply(kustomized, ctx.Client, ctx.recordResource); err != nil {
+		return false, err
+	}
+
+	// 3. - Check that the created instance finished its plan -
+	for _, o := range kustomized {
+		key, _ := client.ObjectKeyFromObject(o)
+		done, err := isInstanceDone(key, ctx.Client)
+		switch {
+		case err != nil:
+			ctx.recordResource(o, v1alpha1.ResourceFailed, err)
+			return false, err
+		case !done:
+			ctx.recordResource(o, v1alpha1.ResourceProgressing, nil)
+			return false, nil
+		}
+		ctx.recordResource(o, v1alpha1.ResourceReady, nil)
+	}
+	return true, nil
+}
+
+// DryRun records whether the instance would be created or updated. The plans of the instance are not dry-run.
+func (it InstanceTask) DryRun(ctx Context) error {
+	kustomized, err := it.objects(ctx)
+	if err != nil {
+		return err
+	}
+	// the instance is created in the cluster of the owning instance, not in the cluster its resources are applied to
+	local := ctx
+	local.TargetClient = nil
+	return dryRunApply(kustomized, local)
+}
+
+// objects renders the instance created by the task and kustomizes it with metadata
+func (it InstanceTask) objects(ctx Context) ([]runtime.Object, error) {
+	// 1. - Find the operator of the instantiated OperatorVersion -
+	ov := &v1alpha1.OperatorVersion{}
+	err := ctx.Client.Get(context.TODO(), client.ObjectKey{Name: it.OperatorVersion, Namespace: ctx.Meta.InstanceNamespace}, ov)
+	if err != nil {
+		// the OperatorVersion might still be installed, so this is not treated as a fatal error
+		return nil, fmt.Errorf("failed to get operatorversion %s for instance task %s: %w", it.OperatorVersion, it.Name, err)
+	}
+
+	// 2. - Render the instance parameters -
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	instance, err := it.instance(ov, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, newPersistedValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return nil, renderError(fmt.Errorf("failed to render instance %s: %v", it.InstanceName, err), resolver)
+	}
+
+	// 3. - Kustomize it with metadata, the instance is created next to the owning instance, which owns it. This also
+	// prefixes its name with the name of the owning instance, so that the instances of two owners do not collide -
+	instanceMeta := ctx.Meta
+	instanceMeta.TargetCluster = ""
+	kustomized, err := kustomize(map[string]string{it.InstanceName: instance}, instanceMeta, ctx.Enhancer)
+	if err != nil {
+		return nil, fmt.Errorf("%wfailed to kustomize instance %s: %v", ErrFatalExecution, it.InstanceName, err)
+	}
+	for _, o := range kustomized {
+		// conventions label the object with the operator of the owning instance, the created instance belongs to its own operator
+		m, err := meta.Accessor(o)
+		if err != nil {
+			return nil, fmt.Errorf("%wfailed to access metadata of instance %s: %v", ErrFatalExecution, it.InstanceName, err)
+		}
+		labels := m.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[kudo.OperatorLabel] = ov.Spec.Operator.Name
+		m.SetLabels(labels)
+	}
+	return kustomized, nil
+}
+
+// instance renders the parameters and returns the created instance as a yaml template
+func (it InstanceTask) instance(ov *v1alpha1.OperatorVersion, params map[string]string, definitions []v1alpha1.Parameter, meta ExecutionMetadata, resolver *instanceResolver, values engine.ValueStore) (string, error) {
+	engine := engine.New()
+	engine.Instances = resolver
+	engine.Values = values
+	configs, err := templateValues(params, definitions, meta)
+	if err != nil {
+		return "", err
+	}
+
+	instanceParams := make(map[string]string, len(it.Parameters))
+	for k, v := range it.Parameters {
+		rendered, err := engine.Render(v, configs)
+		if err != nil {
+			return "", fmt.Errorf("error expanding parameter %s: %w", k, err)
+		}
+		instanceParams[k] = rendered
+	}
+
+	instance := v1alpha1.Instance{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Instance",
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: it.InstanceName,
+		},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: corev1.ObjectReference{
+				Name: ov.Name,
+			},
+			Parameters: instanceParams,
+			// the resources of the created instance are applied to the same cluster as the ones of the owning instance,
+			// with the same permissions
+			ClusterTarget:      meta.TargetCluster,
+			ServiceAccountName: meta.ServiceAccountName,
+		},
+	}
+
+	b, err := yaml.Marshal(instance)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// isInstanceDone returns true when the instance has observed its latest spec and completed its active plan.
+// A fatal error of the instance is propagated as fatal error of the task.
+func isInstanceDone(key client.ObjectKey, c client.Client) (bool, error) {
+	instance := &v1alpha1.Instance{}
+	err := c.Get(context.TODO(), key, instance)
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	status := instance.Status.AggregatedStatus.Status
+	log.Printf("TaskExecution: Instance %s is in state %v", prettyPrint(key), status)
+
+	if !instance.IsSpecObserved() {
+		return false, nil
+	}
+	if status == v1alpha1.ExecutionFatalError {
+		return false, fmt.Errorf("%winstance %s failed to execute its plan", ErrFatalExecution, prettyPrint(key))
+	}
+	return status.IsFinished(), nil
+}
@@ -0,0 +1,162 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInstanceTask_Run(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(s))
+	assert.NoError(t, v1alpha1.AddToScheme(s))
+
+	meta := ExecutionMetadata{
+		EngineMetadata: EngineMetadata{
+			InstanceName:      "platform",
+			InstanceNamespace: "default",
+			OperatorName:      "data-platform",
+		},
+	}
+	task := InstanceTask{
+		Name:            "zookeeper",
+		InstanceName:    "zk",
+		OperatorVersion: "zookeeper-0.1.0",
+		Parameters:      map[string]string{"NODE_COUNT": "{{ .Params.ZK_NODES }}"},
+	}
+
+	tests := []struct {
+		name     string
+		task     InstanceTask
+		objects  []runtime.Object
+		params   map[string]string
+		done     bool
+		wantErr  bool
+		fatal    bool
+		expected map[string]string
+	}{
+		{
+			name:    "fails when the operatorversion is not installed",
+			task:    task,
+			params:  map[string]string{"ZK_NODES": "3"},
+			wantErr: true,
+		},
+		{
+			name:    "fails when a parameter can not be rendered",
+			task:    task,
+			objects: []runtime.Object{zkOperatorVersion()},
+			params:  map[string]string{},
+			wantErr: true,
+			fatal:   true,
+		},
+		{
+			name:     "is not done when the instance was just created",
+			task:     task,
+			objects:  []runtime.Object{zkOperatorVersion()},
+			params:   map[string]string{"ZK_NODES": "3"},
+			done:     false,
+			expected: map[string]string{"NODE_COUNT": "3"},
+		},
+		{
+			name:     "is done when the instance completed its plan",
+			task:     task,
+			objects:  []runtime.Object{zkOperatorVersion(), zkInstance(v1alpha1.ExecutionComplete)},
+			params:   map[string]string{"ZK_NODES": "5"},
+			done:     true,
+			expected: map[string]string{"NODE_COUNT": "5"},
+		},
+		{
+			name:    "fails when the instance failed to execute its plan",
+			task:    task,
+			objects: []runtime.Object{zkOperatorVersion(), zkInstance(v1alpha1.ExecutionFatalError)},
+			params:  map[string]string{"ZK_NODES": "3"},
+			wantErr: true,
+			fatal:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		c := fake.NewFakeClientWithScheme(s, tt.objects...)
+		ctx := Context{
+			Client:     c,
+			Enhancer:   &testKubernetesObjectEnhancer{},
+			Meta:       meta,
+			Parameters: tt.params,
+		}
+
+		got, err := tt.task.Run(ctx)
+		assert.True(t, tt.done == got, fmt.Sprintf("%s failed: want = %t, wantErr = %v", tt.name, got, err))
+		if tt.wantErr {
+			assert.Error(t, err, tt.name)
+			assert.True(t, errors.Is(err, ErrFatalExecution) == tt.fatal, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+
+		instance := &v1alpha1.Instance{}
+		assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Name: "zk"}, instance), tt.name)
+		assert.Equal(t, tt.expected, instance.Spec.Parameters, tt.name)
+		assert.Equal(t, "zookeeper-0.1.0", instance.Spec.OperatorVersion.Name, tt.name)
+		assert.Equal(t, "zookeeper", instance.Labels[kudo.OperatorLabel], tt.name)
+	}
+}
+
+func TestInstanceTask_InstanceName(t *testing.T) {
+	s := runtime.NewScheme()
+	assert.NoError(t, scheme.AddToScheme(s))
+	assert.NoError(t, v1alpha1.AddToScheme(s))
+
+	owner := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "platform", Namespace: "default", UID: "1"}}
+	ctx := Context{
+		Client:   fake.NewFakeClientWithScheme(s, zkOperatorVersion()),
+		Enhancer: &KustomizeEnhancer{Scheme: s},
+		Meta: ExecutionMetadata{
+			EngineMetadata: EngineMetadata{
+				InstanceName:      "platform",
+				InstanceNamespace: "default",
+				OperatorName:      "data-platform",
+				ResourcesOwner:    owner,
+			},
+		},
+	}
+	task := InstanceTask{Name: "zookeeper", InstanceName: "zk", OperatorVersion: "zookeeper-0.1.0"}
+
+	objs, err := task.objects(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(objs))
+	m, err := meta.Accessor(objs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "platform-zk", m.GetName(), "the created instance is prefixed with the name of its owner")
+	assert.Equal(t, "default", m.GetNamespace())
+	assert.Equal(t, "zookeeper", m.GetLabels()[kudo.OperatorLabel])
+	assert.Equal(t, "platform", m.GetOwnerReferences()[0].Name)
+}
+
+func zkOperatorVersion() *v1alpha1.OperatorVersion {
+	return &v1alpha1.OperatorVersion{
+		TypeMeta:   metav1.TypeMeta{Kind: "OperatorVersion", APIVersion: "kudo.dev/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "zookeeper-0.1.0", Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Operator: corev1.ObjectReference{Name: "zookeeper"}},
+	}
+}
+
+func zkInstance(status v1alpha1.ExecutionStatus) *v1alpha1.Instance {
+	return &v1alpha1.Instance{
+		TypeMeta:   metav1.TypeMeta{Kind: "Instance", APIVersion: "kudo.dev/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "zk"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: corev1.ObjectReference{Name: "zookeeper-0.1.0"}},
+		Status:     v1alpha1.InstanceStatus{AggregatedStatus: v1alpha1.AggregatedStatus{Status: status}},
+	}
+}
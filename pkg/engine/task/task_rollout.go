@@ -0,0 +1,247 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/util/health"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RolloutTask applies a set of given resources like the ApplyTask, but updates the pods of StatefulSets in batches.
+// See Run method for more details.
+type RolloutTask struct {
+	Name      string
+	Resources []string
+	KindOrder []string
+	BatchSize int32
+	Pause     time.Duration
+}
+
+// Run method for the RolloutTask. Given the task context, it renders and kustomizes the resources like an ApplyTask.
+// StatefulSets are applied with a partition that keeps their pods at the current revision, so that a change of the
+// pod template does not update any pod yet. Once the updated pods of a StatefulSet are ready and the pause passed,
+// the partition is lowered by the batch size, until all pods are updated. The progress of the rollout is recorded
+// in the step status. The task is done when all StatefulSets are rolled out and all other resources are healthy.
+func (rt RolloutTask) Run(ctx Context) (bool, error) {
+	// 1. - Render and kustomize task templates -
+	kustomized, err := rt.objects(ctx, newPersistedValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return false, err
+	}
+
+	// 2. - Apply them using the client -
+	applied, err := apply(kustomized, ctx.target(), ctx.recordResource)
+	if err != nil {
+		return false, err
+	}
+
+	// 3. - Advance the rollouts and check health for all other resources -
+	done := true
+	now := time.Now()
+	for i, r := range applied {
+		sts, ok := r.(*appsv1.StatefulSet)
+		if !ok {
+			if err := health.IsHealthy(ctx.target(), r); err != nil {
+				ctx.recordResource(kustomized[i], v1alpha1.ResourceProgressing, err)
+				done = false
+				continue
+			}
+			ctx.recordResource(kustomized[i], v1alpha1.ResourceReady, nil)
+			continue
+		}
+
+		rolledOut, progress, err := rt.advance(sts, ctx.target(), now)
+		if err != nil {
+			ctx.recordResource(kustomized[i], v1alpha1.ResourceFailed, err)
+			return false, err
+		}
+		if !rolledOut {
+			log.Printf("TaskExecution: rollout task %s: statefulset %s/%s: %s", rt.Name, sts.Namespace, sts.Name, progress)
+			ctx.recordResource(kustomized[i], v1alpha1.ResourceProgressing, errors.New(progress))
+			done = false
+			continue
+		}
+		ctx.recordResource(kustomized[i], v1alpha1.ResourceReady, nil)
+	}
+	return done, nil
+}
+
+// DryRun records the objects the task would create or update. StatefulSets are recorded with the partition they
+// would be applied with.
+func (rt RolloutTask) DryRun(ctx Context) error {
+	kustomized, err := rt.objects(ctx, newDryRunValues(ctx.Client, ctx.Meta))
+	if err != nil {
+		return err
+	}
+	return dryRunApply(kustomized, ctx)
+}
+
+// objects renders and kustomizes the resources of the task with the values of persisted template functions and sets
+// the partition of all StatefulSets
+func (rt RolloutTask) objects(ctx Context, values engine.ValueStore) ([]runtime.Object, error) {
+	if rt.BatchSize < 1 {
+		return nil, fmt.Errorf("%wrollout task %s has an invalid batch size %d", ErrFatalExecution, rt.Name, rt.BatchSize)
+	}
+
+	resolver := newInstanceResolver(ctx.Client, ctx.Meta.InstanceNamespace)
+	rendered, err := render(rt.Resources, ctx.Templates, ctx.ParsedTemplates, ctx.Parameters, ctx.ParameterDefinitions, ctx.Meta, resolver, values)
+	if err != nil {
+		return nil, renderError(fmt.Errorf("failed to render task resources: %v", err), resolver)
+	}
+	kustomized, err := kustomize(rendered, ctx.Meta, ctx.Enhancer)
+	if err != nil {
+		return nil, fmt.Errorf("%wfailed to kustomize task resources: %v", ErrFatalExecution, err)
+	}
+	sortByKind(kustomized, rt.KindOrder)
+
+	for _, r := range kustomized {
+		if sts, ok := r.(*appsv1.StatefulSet); ok {
+			if err := setPartition(sts, ctx.target()); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return kustomized, nil
+}
+
+// setPartition sets the partition of a StatefulSet before it is applied. A StatefulSet that does not exist yet is
+// created with all its pods at once. A running rollout keeps its partition. Otherwise the partition keeps all pods at
+// their current revision, so that a change of the pod template starts a new rollout.
+func setPartition(sts *appsv1.StatefulSet, c client.Client) error {
+	switch sts.Spec.UpdateStrategy.Type {
+	case "", appsv1.RollingUpdateStatefulSetStrategyType:
+	default:
+		return fmt.Errorf("%wstatefulset %s uses the %s update strategy, a rollout requires the %s update strategy",
+			ErrFatalExecution, sts.Name, sts.Spec.UpdateStrategy.Type, appsv1.RollingUpdateStatefulSetStrategyType)
+	}
+
+	existing := &appsv1.StatefulSet{}
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: sts.Namespace, Name: sts.Name}, existing)
+	var partition int32
+	switch {
+	case apierrors.IsNotFound(err):
+		partition = 0
+	case err != nil:
+		return fmt.Errorf("failed to get statefulset %s/%s: %w", sts.Namespace, sts.Name, err)
+	case existing.Status.CurrentRevision != existing.Status.UpdateRevision:
+		partition = partitionOf(existing)
+	default:
+		partition = replicasOf(sts)
+	}
+
+	sts.Spec.UpdateStrategy.Type = appsv1.RollingUpdateStatefulSetStrategyType
+	sts.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition}
+	return nil
+}
+
+// advance lowers the partition of an applied StatefulSet once the pods of the current batch are updated and ready and
+// the pause passed. It returns true when all pods are updated and ready, otherwise a message describing the progress
+// of the rollout.
+func (rt RolloutTask) advance(sts *appsv1.StatefulSet, c client.Client, now time.Time) (bool, string, error) {
+	if sts.Status.ObservedGeneration < sts.Generation {
+		return false, "waiting for the statefulset controller to observe the update", nil
+	}
+
+	replicas := replicasOf(sts)
+	partition := partitionOf(sts)
+	ready := sts.Status.ReadyReplicas == replicas
+
+	if sts.Status.UpdateRevision == sts.Status.CurrentRevision {
+		// all pods are at the revision of the pod template, there is nothing to roll out
+		if partition > 0 {
+			if err := patchPartition(sts, 0, nil, c); err != nil {
+				return false, "", err
+			}
+		}
+		if !ready {
+			return false, fmt.Sprintf("%d of %d pods are ready", sts.Status.ReadyReplicas, replicas), nil
+		}
+		return true, "", nil
+	}
+
+	progress := fmt.Sprintf("%d of %d pods updated, partition %d", sts.Status.UpdatedReplicas, replicas, partition)
+	if sts.Status.UpdatedReplicas < replicas-partition || !ready {
+		return false, fmt.Sprintf("%s, waiting for the updated pods to be ready", progress), nil
+	}
+	if partition == 0 {
+		return true, "", nil
+	}
+
+	if rt.Pause > 0 {
+		readyAt, err := time.Parse(time.RFC3339, sts.Annotations[kudo.RolloutBatchReadyAnnotation])
+		if err != nil {
+			// the batch just became ready, the pause starts now
+			readyAt = now
+			if err := patchPartition(sts, partition, &readyAt, c); err != nil {
+				return false, "", err
+			}
+		}
+		if until := readyAt.Add(rt.Pause); now.Before(until) {
+			return false, fmt.Sprintf("%s, pausing until %s", progress, until.UTC().Format(time.RFC3339)), nil
+		}
+	}
+
+	next := partition - rt.BatchSize
+	if next < 0 {
+		next = 0
+	}
+	if err := patchPartition(sts, next, nil, c); err != nil {
+		return false, "", err
+	}
+	return false, fmt.Sprintf("%s, updating pods down to ordinal %d", progress, next), nil
+}
+
+// patchPartition sets the partition of the StatefulSet and records the time the current batch was ready. A nil time
+// removes the annotation, so that the pause of the next batch starts when it is ready.
+func patchPartition(sts *appsv1.StatefulSet, partition int32, readyAt *time.Time, c client.Client) error {
+	var annotation interface{}
+	if readyAt != nil {
+		annotation = readyAt.UTC().Format(time.RFC3339)
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{kudo.RolloutBatchReadyAnnotation: annotation},
+		},
+		"spec": map[string]interface{}{
+			"updateStrategy": map[string]interface{}{
+				"rollingUpdate": map[string]interface{}{"partition": partition},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if err := c.Patch(context.TODO(), sts, client.ConstantPatch(types.MergePatchType, patch)); err != nil {
+		return fmt.Errorf("failed to set partition of statefulset %s/%s to %d: %w", sts.Namespace, sts.Name, partition, err)
+	}
+	return nil
+}
+
+// replicasOf returns the desired replicas of the StatefulSet, which default to 1
+func replicasOf(sts *appsv1.StatefulSet) int32 {
+	if sts.Spec.Replicas == nil {
+		return 1
+	}
+	return *sts.Spec.Replicas
+}
+
+// partitionOf returns the partition of the rolling update of the StatefulSet, which defaults to 0
+func partitionOf(sts *appsv1.StatefulSet) int32 {
+	if sts.Spec.UpdateStrategy.RollingUpdate == nil || sts.Spec.UpdateStrategy.RollingUpdate.Partition == nil {
+		return 0
+	}
+	return *sts.Spec.UpdateStrategy.RollingUpdate.Partition
+}
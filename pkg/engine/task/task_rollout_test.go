@@ -0,0 +1,188 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func statefulSet(replicas int32) *appsv1.StatefulSet {
+	return &appsv1.StatefulSet{
+		TypeMeta:   metav1.TypeMeta{Kind: "StatefulSet", APIVersion: "apps/v1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "cassandra", Namespace: "default"},
+		Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+	}
+}
+
+// rollingOut returns a StatefulSet whose pod template changed and whose pods below the partition are not updated yet
+func rollingOut(partition, updated int32, annotations map[string]string) *appsv1.StatefulSet {
+	sts := statefulSet(3)
+	sts.Annotations = annotations
+	sts.Spec.UpdateStrategy = appsv1.StatefulSetUpdateStrategy{
+		Type:          appsv1.RollingUpdateStatefulSetStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateStatefulSetStrategy{Partition: &partition},
+	}
+	sts.Status = appsv1.StatefulSetStatus{
+		Replicas:        3,
+		ReadyReplicas:   3,
+		UpdatedReplicas: updated,
+		CurrentRevision: "cassandra-1",
+		UpdateRevision:  "cassandra-2",
+	}
+	return sts
+}
+
+func TestRolloutTask_Run(t *testing.T) {
+	meta := ExecutionMetadata{
+		EngineMetadata: EngineMetadata{InstanceName: "test", InstanceNamespace: "default"},
+		PlanName:       "upgrade",
+		PhaseName:      "upgrade",
+		StepName:       "rollout",
+		TaskName:       "rollout",
+	}
+	readyAt := time.Now().UTC().Add(-time.Minute).Format(time.RFC3339)
+
+	tests := []struct {
+		name      string
+		task      RolloutTask
+		existing  *appsv1.StatefulSet
+		done      bool
+		fatal     bool
+		partition int32
+		message   string
+	}{
+		{
+			name:      "creates a new statefulset with all pods at once",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1},
+			done:      false,
+			partition: 0,
+			message:   "0 of 3 pods are ready",
+		},
+		{
+			name:      "completes when the pod template did not change",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1},
+			existing:  func() *appsv1.StatefulSet { s := statefulSet(3); s.Status.ReadyReplicas = 3; return s }(),
+			done:      true,
+			partition: 0,
+		},
+		{
+			name:      "updates the first batch",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 2},
+			existing:  rollingOut(3, 0, nil),
+			done:      false,
+			partition: 1,
+			message:   "0 of 3 pods updated, partition 3, updating pods down to ordinal 1",
+		},
+		{
+			name:      "waits for the updated pods to be ready",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1},
+			existing:  rollingOut(2, 0, nil),
+			done:      false,
+			partition: 2,
+			message:   "0 of 3 pods updated, partition 2, waiting for the updated pods to be ready",
+		},
+		{
+			name:      "pauses after a batch is ready",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1, Pause: time.Hour},
+			existing:  rollingOut(2, 1, nil),
+			done:      false,
+			partition: 2,
+		},
+		{
+			name:      "updates the next batch after the pause",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1, Pause: time.Minute},
+			existing:  rollingOut(2, 1, map[string]string{kudo.RolloutBatchReadyAnnotation: readyAt}),
+			done:      false,
+			partition: 1,
+			message:   "1 of 3 pods updated, partition 2, updating pods down to ordinal 1",
+		},
+		{
+			name:      "completes when all pods are updated",
+			task:      RolloutTask{Name: "rollout", Resources: []string{"sts"}, BatchSize: 1},
+			existing:  rollingOut(0, 3, nil),
+			done:      true,
+			partition: 0,
+		},
+		{
+			name:  "fails for an invalid batch size",
+			task:  RolloutTask{Name: "rollout", Resources: []string{"sts"}},
+			fatal: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewFakeClientWithScheme(scheme.Scheme)
+			if tt.existing != nil {
+				assert.NoError(t, c.Create(context.TODO(), tt.existing))
+			}
+			var resources []v1alpha1.ResourceStatus
+			ctx := Context{
+				Client:         c,
+				Enhancer:       &testKubernetesObjectEnhancer{},
+				Meta:           meta,
+				Templates:      map[string]string{"sts": resourceAsString(statefulSet(3))},
+				RecordResource: func(status v1alpha1.ResourceStatus) { resources = append(resources, status) },
+			}
+
+			done, err := tt.task.Run(ctx)
+			if tt.fatal {
+				assert.True(t, errors.Is(err, ErrFatalExecution), "expected a fatal error but got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.done, done)
+
+			sts := &appsv1.StatefulSet{}
+			assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "cassandra"}, sts))
+			assert.Equal(t, tt.partition, partitionOf(sts))
+
+			if assert.Len(t, resources, 1) {
+				if tt.done {
+					assert.Equal(t, v1alpha1.ResourceReady, resources[0].Health)
+				} else {
+					assert.Equal(t, v1alpha1.ResourceProgressing, resources[0].Health)
+				}
+				if tt.message != "" {
+					assert.Equal(t, tt.message, resources[0].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutTask_Pause(t *testing.T) {
+	c := fake.NewFakeClientWithScheme(scheme.Scheme)
+	rt := RolloutTask{Name: "rollout", BatchSize: 1, Pause: time.Minute}
+	sts := rollingOut(2, 1, nil)
+	assert.NoError(t, c.Create(context.TODO(), sts))
+
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	done, progress, err := rt.advance(sts, c, now)
+	assert.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, "1 of 3 pods updated, partition 2, pausing until 2020-01-01T12:01:00Z", progress)
+	assert.Equal(t, "2020-01-01T12:00:00Z", sts.Annotations[kudo.RolloutBatchReadyAnnotation])
+
+	// the pause is kept when the task is run again before it passed
+	_, progress, err = rt.advance(sts, c, now.Add(30*time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, "1 of 3 pods updated, partition 2, pausing until 2020-01-01T12:01:00Z", progress)
+	assert.Equal(t, int32(2), partitionOf(sts))
+
+	_, _, err = rt.advance(sts, c, now.Add(time.Minute))
+	assert.NoError(t, err)
+	stored := &appsv1.StatefulSet{}
+	assert.NoError(t, c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "cassandra"}, stored))
+	assert.Equal(t, int32(1), partitionOf(stored))
+}
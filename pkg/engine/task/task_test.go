@@ -3,6 +3,7 @@ package task
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/stretchr/testify/assert"
@@ -31,6 +32,24 @@ spec:
 			},
 			wantErr: false,
 		},
+		{
+			name: "apply task with kind order",
+			taskYaml: `
+name: apply-task
+kind: Apply
+spec:
+    resources:
+      - topic.yaml
+      - kafka.yaml
+    kindOrder:
+      - KafkaTopic`,
+			want: ApplyTask{
+				Name:      "apply-task",
+				Resources: []string{"topic.yaml", "kafka.yaml"},
+				KindOrder: []string{"KafkaTopic"},
+			},
+			wantErr: false,
+		},
 		{
 			name: "delete task",
 			taskYaml: `
@@ -59,6 +78,78 @@ spec:
 			},
 			wantErr: false,
 		},
+		{
+			name: "instance task",
+			taskYaml: `
+name: zookeeper
+kind: Instance
+spec:
+    operatorVersion: zookeeper-0.1.0
+    parameters:
+      NODE_COUNT: "{{ .Params.ZK_NODES }}"`,
+			want: InstanceTask{
+				Name:            "zookeeper",
+				InstanceName:    "zookeeper",
+				OperatorVersion: "zookeeper-0.1.0",
+				Parameters:      map[string]string{"NODE_COUNT": "{{ .Params.ZK_NODES }}"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "exec task",
+			taskYaml: `
+name: drain
+kind: Exec
+spec:
+    podSelector:
+      app: cassandra
+    container: cassandra
+    command: ["nodetool", "drain"]
+    order: Sequential
+    failurePolicy: Retry`,
+			want: ExecTask{
+				Name:          "drain",
+				PodSelector:   map[string]string{"app": "cassandra"},
+				Container:     "cassandra",
+				Command:       []string{"nodetool", "drain"},
+				Order:         v1alpha1.ExecSequential,
+				FailurePolicy: v1alpha1.ExecRetry,
+			},
+			wantErr: false,
+		},
+		{
+			name: "rollout task",
+			taskYaml: `
+name: upgrade
+kind: Rollout
+spec:
+    resources:
+      - statefulset.yaml
+    batchSize: 2
+    pause: 1m`,
+			want: RolloutTask{
+				Name:      "upgrade",
+				Resources: []string{"statefulset.yaml"},
+				BatchSize: 2,
+				Pause:     time.Minute,
+			},
+			wantErr: false,
+		},
+		{
+			name: "rollout task with defaults",
+			taskYaml: `
+name: upgrade
+kind: Rollout
+spec:
+    resources:
+      - statefulset.yaml`,
+			want: RolloutTask{
+				Name:      "upgrade",
+				Resources: []string{"statefulset.yaml"},
+				BatchSize: 1,
+			},
+			wantErr: false,
+		},
 		{
 			name: "unknown task",
 			taskYaml: `
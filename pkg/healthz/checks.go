@@ -0,0 +1,103 @@
+package healthz
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cacheSyncTimeout is how long the informer cache check waits for the caches to sync. It is longer than the interval
+// at which client-go polls the sync state of the informers.
+const cacheSyncTimeout = time.Second
+
+// KUDOCRDs are the names of the CRDs the manager requires
+var KUDOCRDs = []string{"operators.kudo.dev", "operatorversions.kudo.dev", "instances.kudo.dev"}
+
+// CacheSyncer waits until the informer caches are synced, it is satisfied by the cache of the manager
+type CacheSyncer interface {
+	WaitForCacheSync(stop <-chan struct{}) bool
+}
+
+// Ping always passes, it shows that the manager is serving requests
+func Ping() Check {
+	return Check{Name: "ping", Check: func() error { return nil }}
+}
+
+// InformerCache checks that the informer caches of the manager are synced. Controllers reconcile from the caches, so
+// the manager does not work correctly before they are synced.
+func InformerCache(c CacheSyncer) Check {
+	return Check{Name: "informer-cache", Check: func() error {
+		stop := make(chan struct{})
+		timer := time.AfterFunc(cacheSyncTimeout, func() { close(stop) })
+		defer timer.Stop()
+		if !c.WaitForCacheSync(stop) {
+			return errors.New("informer caches are not synced")
+		}
+		return nil
+	}}
+}
+
+// CRDs checks that the given CRDs exist and are established. The reader should read from the API server, as the CRDs
+// are not cached.
+func CRDs(reader client.Reader, names ...string) Check {
+	return Check{Name: "crds", Check: func() error {
+		for _, name := range names {
+			crd := &apiextv1beta1.CustomResourceDefinition{}
+			if err := reader.Get(context.TODO(), types.NamespacedName{Name: name}, crd); err != nil {
+				return fmt.Errorf("failed to get crd %s: %v", name, err)
+			}
+			if !crdEstablished(crd) {
+				return fmt.Errorf("crd %s is not established", name)
+			}
+		}
+		return nil
+	}}
+}
+
+func crdEstablished(crd *apiextv1beta1.CustomResourceDefinition) bool {
+	for _, c := range crd.Status.Conditions {
+		if c.Type == apiextv1beta1.Established {
+			return c.Status == apiextv1beta1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// WebhookCertificate checks that the serving certificate tls.crt in the certificate directory of the webhook server
+// is valid at the current time. An expired certificate makes the API server reject all requests to the webhooks.
+func WebhookCertificate(certDir string) Check {
+	return Check{Name: "webhook-certificate", Check: func() error {
+		return validCertificate(filepath.Join(certDir, "tls.crt"), time.Now())
+	}}
+}
+
+func validCertificate(path string, now time.Time) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read certificate: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return fmt.Errorf("no PEM encoded certificate found in %s", path)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate: %v", err)
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("certificate is not valid before %s", cert.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate expired at %s", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package healthz
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// LivenessPath is the path the liveness checks are served at
+	LivenessPath = "/healthz"
+	// ReadinessPath is the path the readiness checks are served at
+	ReadinessPath = "/readyz"
+	// DefaultPort is the port the manager serves the health endpoints at by default
+	DefaultPort = 8081
+)
+
+// Check is a named health check, it returns an error describing the problem if the check fails
+type Check struct {
+	Name  string
+	Check func() error
+}
+
+// Server serves the liveness and readiness checks of the manager. Every check is run on each request. A request
+// succeeds with 200 if all checks pass and fails with 500 otherwise, the body lists the result of each check.
+// It implements manager.Runnable and runs on all replicas, not only on the leader.
+type Server struct {
+	// Addr is the TCP address the server listens on, e.g. ":8081"
+	Addr string
+	// Liveness are the checks served at LivenessPath, a failure makes the kubelet restart the manager
+	Liveness []Check
+	// Readiness are the checks served at ReadinessPath, a failure removes the manager from the webhook service
+	Readiness []Check
+}
+
+// Handler returns the handler serving the liveness and readiness checks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(LivenessPath, checkHandler(s.Liveness))
+	mux.Handle(ReadinessPath, checkHandler(s.Readiness))
+	return mux
+}
+
+// Start serves the health endpoints until the stop channel is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s for health checks: %v", s.Addr, err)
+	}
+	server := &http.Server{Handler: s.Handler()}
+
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			log.Printf("HealthServer: Error shutting down: %v", err)
+		}
+	}()
+
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// NeedLeaderElection returns false, all replicas have to report their health
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+func checkHandler(checks []Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body string
+		failed := false
+		for _, c := range checks {
+			if err := c.Check(); err != nil {
+				failed = true
+				body += fmt.Sprintf("[-]%s failed: %v\n", c.Name, err)
+				continue
+			}
+			body += fmt.Sprintf("[+]%s ok\n", c.Name)
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		if failed {
+			log.Printf("HealthServer: %s check failed:\n%s", r.URL.Path, body)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s%s check failed\n", body, r.URL.Path)
+			return
+		}
+		fmt.Fprintf(w, "%s%s check passed\n", body, r.URL.Path)
+	})
+}
@@ -0,0 +1,152 @@
+package healthz
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+type fakeCache bool
+
+func (c fakeCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	if !c {
+		<-stop
+	}
+	return bool(c)
+}
+
+func TestServer_Handler(t *testing.T) {
+	failing := Check{Name: "failing", Check: func() error { return errors.New("broken") }}
+	s := &Server{
+		Liveness:  []Check{Ping()},
+		Readiness: []Check{Ping(), failing},
+	}
+	handler := s.Handler()
+
+	tests := []struct {
+		path   string
+		status int
+		body   []string
+	}{
+		{LivenessPath, http.StatusOK, []string{"[+]ping ok", "/healthz check passed"}},
+		{ReadinessPath, http.StatusInternalServerError, []string{"[+]ping ok", "[-]failing failed: broken", "/readyz check failed"}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected status %d but got %d", tt.path, tt.status, rec.Code)
+		}
+		for _, b := range tt.body {
+			if !strings.Contains(rec.Body.String(), b) {
+				t.Errorf("%s: expected body to contain %q but got %q", tt.path, b, rec.Body.String())
+			}
+		}
+	}
+}
+
+func TestInformerCache(t *testing.T) {
+	if err := InformerCache(fakeCache(true)).Check(); err != nil {
+		t.Errorf("expected synced cache to pass but got %v", err)
+	}
+	if err := InformerCache(fakeCache(false)).Check(); err == nil {
+		t.Errorf("expected unsynced cache to fail")
+	}
+}
+
+func TestCRDs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := apiextv1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	crd := func(name string, established apiextv1beta1.ConditionStatus) *apiextv1beta1.CustomResourceDefinition {
+		return &apiextv1beta1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: apiextv1beta1.CustomResourceDefinitionStatus{Conditions: []apiextv1beta1.CustomResourceDefinitionCondition{
+				{Type: apiextv1beta1.Established, Status: established},
+			}},
+		}
+	}
+	c := fake.NewFakeClientWithScheme(scheme,
+		crd("operators.kudo.dev", apiextv1beta1.ConditionTrue),
+		crd("operatorversions.kudo.dev", apiextv1beta1.ConditionTrue),
+		crd("instances.kudo.dev", apiextv1beta1.ConditionFalse),
+	)
+
+	if err := CRDs(c, "operators.kudo.dev", "operatorversions.kudo.dev").Check(); err != nil {
+		t.Errorf("expected established crds to pass but got %v", err)
+	}
+	if err := CRDs(c, KUDOCRDs...).Check(); err == nil || err.Error() != "crd instances.kudo.dev is not established" {
+		t.Errorf("expected crd that is not established to fail but got %v", err)
+	}
+	if err := CRDs(c, "missing.kudo.dev").Check(); err == nil {
+		t.Errorf("expected missing crd to fail")
+	}
+}
+
+func TestValidCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	notBefore := time.Date(2019, 12, 1, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(dir, "tls.crt")
+	writeCertificate(t, path, notBefore, notBefore.AddDate(1, 0, 0))
+
+	tests := []struct {
+		now   time.Time
+		valid bool
+	}{
+		{notBefore.AddDate(0, 6, 0), true},
+		{notBefore.AddDate(0, 0, -1), false},
+		{notBefore.AddDate(1, 0, 1), false},
+	}
+	for _, tt := range tests {
+		if err := validCertificate(path, tt.now); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid %v but got %v", tt.now, tt.valid, err)
+		}
+	}
+
+	if err := WebhookCertificate(filepath.Join(dir, "missing")).Check(); err == nil {
+		t.Errorf("expected missing certificate to fail")
+	}
+}
+
+func writeCertificate(t *testing.T, path string, notBefore, notAfter time.Time) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kudo-controller-manager-service.kudo-system.svc"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
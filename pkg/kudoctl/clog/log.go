@@ -66,6 +66,7 @@ func (l *Level) Type() string {
 
 type loggingT struct {
 	verbosity Level // V logging level, the value of the -v flag/
+	quiet     bool  // only print results, the value of the --quiet flag
 	out       io.Writer
 }
 
@@ -94,7 +95,7 @@ func V(level Level) Verbose {
 	// The fast path is two atomic loads and compares.
 
 	// Here is a cheap but safe test to see if V logging is enabled globally.
-	if logging.verbosity.get() >= level {
+	if !logging.quiet && logging.verbosity.get() >= level {
 		return Verbose(true)
 	}
 	return Verbose(false)
@@ -113,6 +114,7 @@ func Init(f *pflag.FlagSet, out io.Writer) {
 	// allows for initialization of writer in testing without CLI flags
 	if f != nil {
 		f.VarP(&logging.verbosity, "v", "v", "log level for V logs")
+		f.BoolVarP(&logging.quiet, "quiet", "q", false, "Only print essential identifiers, e.g. the names of created objects.")
 	}
 	logging.out = out
 }
@@ -129,6 +131,27 @@ func Printf(format string, args ...interface{}) {
 	V(0).Printf(format, args...)
 }
 
+// Quiet reports whether output is limited to the results of commands
+func Quiet() bool {
+	return logging.quiet
+}
+
+// Resultf prints the result of a command. In quiet mode only the given id is printed so the output
+// can be consumed by scripts, otherwise the formatted message is printed.
+func Resultf(id string, format string, args ...interface{}) {
+	Fresultf(logging.out, id, format, args...)
+}
+
+// Fresultf is like Resultf but writes to the given writer
+func Fresultf(w io.Writer, id string, format string, args ...interface{}) {
+	if logging.quiet {
+		fmt.Fprintln(w, id)
+		return
+	}
+	fmt.Fprintf(w, format, args...)
+	fmt.Fprintf(w, "\n")
+}
+
 // Errorf formats and returns error and logs at level 2
 func Errorf(format string, a ...interface{}) error {
 	err := fmt.Errorf(format, a...)
@@ -69,3 +69,24 @@ func TestErrorf(t *testing.T) {
 	Errorf("error msg")
 	assert.Equal(t, "error msg\n", buf.String())
 }
+
+func TestQuiet(t *testing.T) {
+	var buf bytes.Buffer
+
+	logging.quiet = true
+	defer func() { logging.quiet = false }()
+	logging.out = &buf
+
+	// decorative output is suppressed at all levels
+	Printf("level 0")
+	assert.Equal(t, "", buf.String())
+
+	// only the id of a result is printed
+	Resultf("flink", "instance %s created", "flink")
+	assert.Equal(t, "flink\n", buf.String())
+	buf.Reset()
+
+	logging.quiet = false
+	Resultf("flink", "instance %s created", "flink")
+	assert.Equal(t, "instance flink created\n", buf.String())
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+const configDesc = `
+This command consists of multiple sub-commands to manage the client configuration in $KUDO_HOME/config.
+
+The client configuration holds defaults of flags, so that they do not have to be repeated on every invocation.
+Flags always take precedence over the configuration. The keys are:
+
+  namespace        default of --namespace, it takes precedence over the namespace of the kubeconfig context
+  output           default of --output
+  repo             default of --repo, it takes precedence over the repository context
+  request-timeout  default of --request-timeout, e.g. 10s
+`
+
+const configExample = `  # Use the namespace team-a by default
+  kubectl kudo config set namespace team-a
+
+  # Show the default repository
+  kubectl kudo config get repo
+
+  # Show the whole client configuration
+  kubectl kudo config view
+
+  # Remove the default request timeout
+  kubectl kudo config unset request-timeout
+`
+
+// newConfigCmd creates the commands managing the client configuration
+func newConfigCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config set|get|unset|view",
+		Short:   "Manage the defaults of the client configuration.",
+		Long:    configDesc,
+		Example: configExample,
+	}
+	keys := fmt.Sprintf("one of %s", strings.Join(env.ConfigKeys, ", "))
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set KEY VALUE",
+		Short: fmt.Sprintf("Set a default of the client configuration, KEY is %s.", keys),
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setConfig(fs, Settings.Home, args[0], args[1])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "unset KEY",
+		Short: fmt.Sprintf("Remove a default of the client configuration, KEY is %s.", keys),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setConfig(fs, Settings.Home, args[0], "")
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get KEY",
+		Short: fmt.Sprintf("Print a default of the client configuration, KEY is %s.", keys),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return getConfig(fs, out, Settings.Home, args[0])
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "view",
+		Short: "Print the client configuration.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return viewConfig(fs, out, Settings.Home)
+		},
+	})
+
+	return cmd
+}
+
+// setConfig sets the key of the client configuration, an empty value unsets it. A default repository has to be
+// configured in the repositories file.
+func setConfig(fs afero.Fs, home kudohome.Home, key, value string) error {
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	if err := config.Set(key, value); err != nil {
+		return err
+	}
+	if key == env.RepoKey && value != "" {
+		repos, err := repo.LoadRepositories(fs, home.RepositoryFile())
+		if err != nil {
+			return err
+		}
+		if repos.GetConfiguration(value) == nil {
+			return fmt.Errorf("no repo named %q found, 'kubectl kudo repo list' lists the repositories", value)
+		}
+	}
+	return config.WriteFile(fs, home.ConfigFile())
+}
+
+func getConfig(fs afero.Fs, out io.Writer, home kudohome.Home, key string) error {
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	value, err := config.Get(key)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, value)
+	return nil
+}
+
+func viewConfig(fs afero.Fs, out io.Writer, home kudohome.Home) error {
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, string(b))
+	return nil
+}
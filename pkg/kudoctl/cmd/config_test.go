@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	out := &bytes.Buffer{}
+	home := kudohome.Home("kudo_home")
+
+	// the repositories file is written by init
+	i := &initCmd{fs: fs, out: out, home: home}
+	assert.NoError(t, i.initialize())
+
+	assert.NoError(t, setConfig(fs, home, "namespace", "team-a"))
+	assert.NoError(t, setConfig(fs, home, "repo", "community"))
+	assert.NoError(t, setConfig(fs, home, "request-timeout", "10s"))
+	assert.NoError(t, setConfig(fs, home, "output", "yaml"))
+
+	out.Reset()
+	assert.NoError(t, getConfig(fs, out, home, "namespace"))
+	assert.Equal(t, "team-a\n", out.String())
+
+	assert.NoError(t, setConfig(fs, home, "output", ""))
+	out.Reset()
+	assert.NoError(t, viewConfig(fs, out, home))
+	assert.Equal(t, "namespace: team-a\nrepo: community\nrequestTimeout: 10s\n", out.String())
+
+	assert.EqualError(t, setConfig(fs, home, "repo", "foo"), `no repo named "foo" found, 'kubectl kudo repo list' lists the repositories`)
+	err := setConfig(fs, home, "request-timeout", "soon")
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "invalid request timeout soon")
+	}
+	assert.EqualError(t, setConfig(fs, home, "output", "table"), "unsupported output format table, expecting one of yaml")
+	assert.EqualError(t, setConfig(fs, home, "context", "kind"), "unknown configuration key context, expecting one of namespace, output, repo, request-timeout")
+	assert.EqualError(t, getConfig(fs, out, home, "context"), "unknown configuration key context, expecting one of namespace, output, repo, request-timeout")
+
+	out.Reset()
+	assert.NoError(t, viewConfig(fs, out, home))
+	assert.Equal(t, "namespace: team-a\nrepo: community\nrequestTimeout: 10s\n", out.String(), "invalid values are not written")
+}
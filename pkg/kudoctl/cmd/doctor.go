@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/doctor"
+
+	"github.com/spf13/cobra"
+)
+
+const doctorExample = `  # Diagnose the KUDO installation and the instances in the current namespace
+  kubectl kudo doctor
+
+  # Report plans that are in progress for more than 30 minutes as stuck
+  kubectl kudo doctor --stuck-after 30m
+`
+
+// newDoctorCmd creates a command that diagnoses common misconfigurations of a KUDO installation
+func newDoctorCmd() *cobra.Command {
+	options := doctor.DefaultOptions
+	options.ManagerNamespace = cmdInit.DefaultNamespace
+	doctorCmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnose common misconfigurations of KUDO.",
+		Long: `Diagnose the KUDO installation and report the problems found with their severity and a hint how to resolve them.
+The connectivity to the cluster, the installed CRDs, the webhook certificate and the health of the manager pods are
+checked, as well as plans stuck in progress and orphaned or unused operatorversions in the namespace.
+The command fails if any problem prevents KUDO from working.`,
+		Example: doctorExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.Namespace = Settings.Namespace
+			return doctor.Run(cmd.OutOrStdout(), options, &Settings)
+		},
+	}
+
+	doctorCmd.Flags().StringVar(&options.ManagerNamespace, "manager-namespace", options.ManagerNamespace, "The namespace the KUDO manager is running in.")
+	doctorCmd.Flags().DurationVar(&options.StuckAfter, "stuck-after", options.StuckAfter, "Report plans that are in progress for longer than this duration.")
+	doctorCmd.Flags().DurationVar(&options.CertificateWarning, "certificate-warning", options.CertificateWarning, "Report a webhook certificate that expires within this duration.")
+	return doctorCmd
+}
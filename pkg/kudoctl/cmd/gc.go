@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	gcExample = `  # List operatorversions and operators that are not used anymore
+  kubectl kudo gc
+
+  # Delete operatorversions and operators that are not used anymore
+  kubectl kudo gc --confirm
+`
+)
+
+type gcOptions struct {
+	Confirm bool
+}
+
+type gcCmd struct {
+	out io.Writer
+}
+
+func (cmd *gcCmd) run(options gcOptions, settings *env.Settings) error {
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	clog.V(3).Printf("acquiring kudo client")
+	if err != nil {
+		clog.V(3).Printf("failed to acquire kudo client: %v", err)
+		return fmt.Errorf("failed to acquire kudo client: %w", err)
+	}
+
+	return cmd.gc(kc, options.Confirm, settings.Namespace)
+}
+
+// gc finds operatorversions that are not referenced by any instance and operators that have no operatorversions
+// (once the unused ones are removed) and deletes them if confirm is set. Otherwise they are only printed.
+func (cmd *gcCmd) gc(kc *kudo.Client, confirm bool, namespace string) error {
+	ovs, err := kc.UnusedOperatorVersions(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to find unused operatorversions: %w", err)
+	}
+	operators, err := kc.UnusedOperators(namespace, ovs)
+	if err != nil {
+		return fmt.Errorf("failed to find unused operators: %w", err)
+	}
+
+	if len(ovs) == 0 && len(operators) == 0 {
+		if !clog.Quiet() {
+			fmt.Fprintf(cmd.out, "No unused operatorversions or operators found in namespace %s\n", namespace)
+		}
+		return nil
+	}
+
+	action := "would be deleted"
+	if confirm {
+		action = "deleted"
+	}
+
+	// operatorversions go first so that an operator is never removed while its versions still exist
+	for _, ov := range ovs {
+		if confirm {
+			if err := kc.DeleteOperatorVersion(ov.Name, namespace); err != nil {
+				return fmt.Errorf("failed to delete operatorversion %s: %w", ov.Name, err)
+			}
+		}
+		id := "operatorversion.kudo.dev/" + ov.Name
+		clog.Fresultf(cmd.out, id, "%s %s", id, action)
+	}
+	for _, o := range operators {
+		if confirm {
+			if err := kc.DeleteOperator(o.Name, namespace); err != nil {
+				return fmt.Errorf("failed to delete operator %s: %w", o.Name, err)
+			}
+		}
+		id := "operator.kudo.dev/" + o.Name
+		clog.Fresultf(cmd.out, id, "%s %s", id, action)
+	}
+
+	if !confirm && !clog.Quiet() {
+		fmt.Fprintf(cmd.out, "Run again with --confirm to delete the listed objects\n")
+	}
+	return nil
+}
+
+// newGCCmd creates a command that removes operatorversions and operators which are not used by any instance
+func newGCCmd(out io.Writer) *cobra.Command {
+	options := gcOptions{}
+	gc := &gcCmd{out: out}
+
+	gcCmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Remove unused operatorversions and operators.",
+		Long: `Find operatorversions that are not referenced by any instance and operators without any operatorversion.
+By default the objects are only listed, use --confirm to delete them.`,
+		Example: gcExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return gc.run(options, &Settings)
+		},
+	}
+
+	gcCmd.Flags().BoolVar(&options.Confirm, "confirm", false, "Delete the unused objects instead of only listing them.")
+
+	return gcCmd
+}
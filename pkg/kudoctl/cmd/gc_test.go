@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGC(t *testing.T) {
+	operators := []string{"kafka", "zookeeper", "flink"}
+	ovs := map[string]string{"kafka-1.0": "kafka", "kafka-2.0": "kafka", "zookeeper-1.0": "zookeeper"}
+	instances := map[string]string{"kafka": "kafka-2.0"}
+
+	kc := newTestClient()
+	for _, name := range operators {
+		o := &v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if _, err := kc.InstallOperatorObjToCluster(o, "default"); err != nil {
+			t.Fatalf("failed to install operator: %v", err)
+		}
+	}
+	for name, operator := range ovs {
+		ov := &v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.OperatorVersionSpec{Operator: v1.ObjectReference{Name: operator}},
+		}
+		if _, err := kc.InstallOperatorVersionObjToCluster(ov, "default"); err != nil {
+			t.Fatalf("failed to install operatorversion: %v", err)
+		}
+	}
+	for name, ov := range instances {
+		i := &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: ov}},
+		}
+		if _, err := kc.InstallInstanceObjToCluster(i, "default"); err != nil {
+			t.Fatalf("failed to install instance: %v", err)
+		}
+	}
+
+	expected := []string{
+		"operatorversion.kudo.dev/kafka-1.0",
+		"operatorversion.kudo.dev/zookeeper-1.0",
+		"operator.kudo.dev/zookeeper",
+		"operator.kudo.dev/flink",
+	}
+
+	// without confirm nothing is deleted
+	var out bytes.Buffer
+	cmd := gcCmd{out: &out}
+	if err := cmd.gc(kc, false, "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e+" would be deleted") {
+			t.Errorf("expected output to contain %s but got %s", e, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "kafka-2.0") || strings.Contains(out.String(), "operator.kudo.dev/kafka ") {
+		t.Errorf("expected used objects to be kept but got %s", out.String())
+	}
+	if ov, _ := kc.GetOperatorVersion("kafka-1.0", "default"); ov == nil {
+		t.Errorf("expected operatorversion kafka-1.0 to still exist")
+	}
+
+	out.Reset()
+	if err := cmd.gc(kc, true, "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e+" deleted") {
+			t.Errorf("expected output to contain %s but got %s", e, out.String())
+		}
+	}
+	for ov := range ovs {
+		existing, err := kc.GetOperatorVersion(ov, "default")
+		if err != nil {
+			t.Fatalf("failed to get operatorversion: %v", err)
+		}
+		if (existing != nil) != (ov == "kafka-2.0") {
+			t.Errorf("unexpected state of operatorversion %s after gc: %v", ov, existing)
+		}
+	}
+	for _, o := range operators {
+		exists := kc.OperatorExistsInCluster(o, "default")
+		if exists != (o == "kafka") {
+			t.Errorf("unexpected state of operator %s after gc, exists: %v", o, exists)
+		}
+	}
+
+	out.Reset()
+	if err := cmd.gc(kc, true, "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if !strings.Contains(out.String(), "No unused operatorversions or operators found") {
+		t.Errorf("expected nothing to be collected but got %s", out.String())
+	}
+}
@@ -5,20 +5,33 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const getExample = `  # Get all available instances
-  kubectl kudo get instances 
+const getExample = `  # Get all available instances with the status of their last plan
+  kubectl kudo get instances
+
+  # Get all failed instances, whose active plan is retrying after an error or failed fatally
+  kubectl kudo get instances --status failed
+
+  # Get the instance kafka with the endpoints to connect to it
+  kubectl kudo get instance kafka -o wide
+
+  # Get all installed operatorversions with the number of their plans, parameters and instances
+  kubectl kudo get operatorversions
 `
 
-// newGetCmd creates a command that lists the instances in the cluster
+// newGetCmd creates a command that lists the instances or operatorversions in the cluster
 func newGetCmd() *cobra.Command {
+	options := get.DefaultOptions
 	getCmd := &cobra.Command{
-		Use:     "get instances",
-		Short:   "Gets all available instances.",
+		Use:     "get instances [NAME]|operatorversions",
+		Short:   "Gets all available instances or operatorversions.",
 		Example: getExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return get.Run(args, &Settings)
+			return get.Run(cmd.OutOrStdout(), args, options, &Settings)
 		},
 	}
 
+	getCmd.Flags().StringVar(&options.Status, "status", "", "Only list the instances with the given status: failed, in-progress or healthy")
+	getCmd.Flags().StringVarP(&options.Output, "output", "o", "", "Output format, wide adds the endpoints of the instances")
+
 	return getCmd
 }
@@ -2,61 +2,297 @@ package get
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
 
+	"github.com/Masterminds/semver"
+	"github.com/gosuri/uitable"
 	"github.com/pkg/errors"
-	"github.com/xlab/treeprint"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
 )
 
+const (
+	instancesResource        = "instances"
+	instanceResource         = "instance"
+	operatorVersionsResource = "operatorversions"
+)
+
+// OutputWide adds the endpoints of the instances to the printed table, see Options.Output
+const OutputWide = "wide"
+
+// Health filters of the instances, see Options.Status
+const (
+	StatusFailed     = "failed"
+	StatusInProgress = "in-progress"
+	StatusHealthy    = "healthy"
+)
+
+// Options are the options of the get command
+type Options struct {
+	// Status filters the instances by the health derived from their aggregated status, one of StatusFailed,
+	// StatusInProgress or StatusHealthy. All instances are listed if it is empty.
+	Status string
+	// Output is the output format, OutputWide adds the endpoints of the instances. The default table is printed if it
+	// is empty.
+	Output string
+}
+
+// DefaultOptions provides the default options for get
+var DefaultOptions = &Options{}
+
 // Run returns the errors associated with cmd env
-func Run(args []string, settings *env.Settings) error {
+func Run(out io.Writer, args []string, options *Options, settings *env.Settings) error {
 
 	err := validate(args)
 	if err != nil {
 		return err
 	}
+	if isInstances(args[0]) {
+		if err := validateStatus(options.Status); err != nil {
+			return err
+		}
+		if options.Output != "" && options.Output != OutputWide {
+			return fmt.Errorf("expecting --output to be %s and not \"%s\"", OutputWide, options.Output)
+		}
+	} else if options.Status != "" {
+		return fmt.Errorf("--status can only be used to filter instances")
+	} else if options.Output != "" {
+		return fmt.Errorf("--output can only be used to print instances")
+	}
 
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	if err != nil {
 		return errors.Wrap(err, "creating kudo client")
 	}
 
-	p, err := getInstances(kc, settings)
-	if err != nil {
-		log.Printf("Error: %v", err)
+	if args[0] == operatorVersionsResource {
+		return getOperatorVersions(out, kc, settings)
 	}
-	tree := treeprint.New()
-
-	for _, plan := range p {
-		tree.AddBranch(plan)
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
 	}
-	fmt.Printf("List of current installed instances in namespace \"%s\":\n", settings.Namespace)
-	fmt.Println(tree.String())
-	return err
+	return printInstances(out, kc, name, options, settings)
 }
 
 func validate(args []string) error {
-	if len(args) != 1 {
-		return fmt.Errorf("expecting exactly one argument - \"instances\"")
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance")
 	}
 
-	if args[0] != "instances" {
-		return fmt.Errorf("expecting \"instances\" and not \"%s\"", args[0])
+	if !isInstances(args[0]) && args[0] != operatorVersionsResource {
+		return fmt.Errorf("expecting \"instances\" or \"operatorversions\" and not \"%s\"", args[0])
+	}
+	if len(args) == 2 && !isInstances(args[0]) {
+		return fmt.Errorf("only instances can be selected by name")
 	}
 
 	return nil
 
 }
 
-func getInstances(kc *kudo.Client, settings *env.Settings) ([]string, error) {
+// isInstances returns whether the resource argument selects instances, a single instance may be named in singular
+func isInstances(resource string) bool {
+	return resource == instancesResource || resource == instanceResource
+}
+
+func validateStatus(status string) error {
+	switch status {
+	case "", StatusFailed, StatusInProgress, StatusHealthy:
+		return nil
+	}
+	return fmt.Errorf("expecting --status to be one of %s, %s or %s and not \"%s\"", StatusFailed, StatusInProgress, StatusHealthy, status)
+}
+
+// getInstances returns the instances of the namespace with the given health, or all instances if status is empty.
+// Only the instance with the given name is returned if name is not empty.
+func getInstances(kc *kudo.Client, name, status string, settings *env.Settings) ([]v1alpha1.Instance, error) {
 
-	instanceList, err := kc.ListInstances(settings.Namespace)
+	instanceList, err := kc.ListInstancesBySelector(settings.Namespace, "")
 	if err != nil {
 		return nil, errors.Wrap(err, "getting instances")
 	}
+	if status == "" && name == "" {
+		return instanceList, nil
+	}
+
+	filtered := []v1alpha1.Instance{}
+	for _, instance := range instanceList {
+		if name != "" && instance.Name != name {
+			continue
+		}
+		if status == "" || instanceHealth(&instance) == status {
+			filtered = append(filtered, instance)
+		}
+	}
+	return filtered, nil
+}
 
-	return instanceList, nil
+// instanceHealth returns the health of the instance derived from its aggregated status, or an empty string if no plan
+// was executed yet. An instance whose active plan is retrying after an error counts as failed.
+func instanceHealth(instance *v1alpha1.Instance) string {
+	switch instance.Status.AggregatedStatus.Status {
+	case v1alpha1.ErrorStatus, v1alpha1.ExecutionFatalError:
+		return StatusFailed
+	case v1alpha1.ExecutionInProgress, v1alpha1.ExecutionPending:
+		return StatusInProgress
+	case v1alpha1.ExecutionComplete:
+		return StatusHealthy
+	}
+	return ""
+}
+
+// printInstances prints the instances of the namespace with their operator and version, the status of their last
+// plan and their age. The wide output adds their endpoints. Only the instance with the given name is printed if name
+// is not empty.
+func printInstances(out io.Writer, kc *kudo.Client, name string, options *Options, settings *env.Settings) error {
+	instances, err := getInstances(kc, name, options.Status, settings)
+	if err != nil {
+		return err
+	}
+	if name != "" && len(instances) == 0 && options.Status == "" {
+		return fmt.Errorf("instance \"%s\" not found in namespace \"%s\"", name, settings.Namespace)
+	}
+	sort.SliceStable(instances, func(i, j int) bool { return instances[i].Name < instances[j].Name })
+
+	if clog.Quiet() {
+		for _, instance := range instances {
+			fmt.Fprintln(out, instance.Name)
+		}
+		return nil
+	}
+	if len(instances) == 0 {
+		if options.Status != "" {
+			fmt.Fprintf(out, "No %s instances in namespace \"%s\".\n", options.Status, settings.Namespace)
+			return nil
+		}
+		fmt.Fprintf(out, "No instances installed in namespace \"%s\".\n", settings.Namespace)
+		return nil
+	}
+
+	ovs := map[types.NamespacedName]*v1alpha1.OperatorVersion{}
+	table := uitable.New()
+	header := []interface{}{"NAME", "OPERATOR", "VERSION", "LAST PLAN", "PLAN STATUS", "AGE"}
+	if options.Output == OutputWide {
+		header = append(header, "ENDPOINTS")
+	}
+	table.AddRow(header...)
+	for i := range instances {
+		instance := &instances[i]
+		key := types.NamespacedName{Name: instance.Spec.OperatorVersion.Name, Namespace: instance.OperatorVersionNamespace()}
+		ov, ok := ovs[key]
+		if !ok {
+			if ov, err = kc.GetOperatorVersion(key.Name, key.Namespace); err != nil {
+				return errors.Wrapf(err, "getting operatorversion %s", key)
+			}
+			ovs[key] = ov
+		}
+
+		operator, version := instance.Labels[util.OperatorLabel], ""
+		if ov != nil {
+			operator, version = ov.Spec.Operator.Name, ov.Spec.Version
+		}
+		plan, planStatus := "", ""
+		if last := instance.GetLastExecutedPlanStatus(); last != nil {
+			plan, planStatus = last.Name, string(last.Status)
+		}
+		row := []interface{}{instance.Name, valueOrDash(operator), valueOrDash(version), valueOrDash(plan), valueOrDash(planStatus),
+			age(instance.CreationTimestamp)}
+		if options.Output == OutputWide {
+			row = append(row, valueOrDash(endpoints(instance.Status.Endpoints)))
+		}
+		table.AddRow(row...)
+	}
+	fmt.Fprintln(out, table)
+	return nil
+}
+
+// endpoints returns the endpoints as comma separated name=value pairs, endpoints that failed to render are marked as
+// such, their message is found in the status of the instance
+func endpoints(endpoints []v1alpha1.EndpointStatus) string {
+	pairs := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		value := ep.Value
+		if value == "" {
+			value = "<failed>"
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", ep.Name, value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// age returns the time since the timestamp like kubectl does
+func age(timestamp metav1.Time) string {
+	if timestamp.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(timestamp.Time))
+}
+
+// getOperatorVersions prints the operatorversions of the namespace with the number of their plans and parameters and
+// the number of instances of all namespaces using them
+func getOperatorVersions(out io.Writer, kc *kudo.Client, settings *env.Settings) error {
+	ovs, err := kc.ListOperatorVersions(settings.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "getting operatorversions")
+	}
+	usage, err := kc.OperatorVersionUsage()
+	if err != nil {
+		return errors.Wrap(err, "getting instances")
+	}
+	sortOperatorVersions(ovs)
+
+	if clog.Quiet() {
+		for _, ov := range ovs {
+			fmt.Fprintln(out, ov.Name)
+		}
+		return nil
+	}
+	if len(ovs) == 0 {
+		fmt.Fprintf(out, "No operatorversions installed in namespace \"%s\".\n", settings.Namespace)
+		return nil
+	}
+
+	table := uitable.New()
+	table.AddRow("NAME", "OPERATOR", "VERSION", "APP VERSION", "PLANS", "PARAMS", "INSTANCES")
+	for _, ov := range ovs {
+		instances := usage[types.NamespacedName{Name: ov.Name, Namespace: ov.Namespace}]
+		table.AddRow(ov.Name, ov.Spec.Operator.Name, ov.Spec.Version, valueOrDash(ov.Spec.AppVersion),
+			len(ov.Spec.Plans), len(ov.Spec.Parameters), instances)
+	}
+	fmt.Fprintln(out, table)
+	return nil
+}
+
+// sortOperatorVersions sorts by operator and then by version, versions that are not semantic versions are compared as
+// strings
+func sortOperatorVersions(ovs []v1alpha1.OperatorVersion) {
+	sort.SliceStable(ovs, func(i, j int) bool {
+		if ovs[i].Spec.Operator.Name != ovs[j].Spec.Operator.Name {
+			return ovs[i].Spec.Operator.Name < ovs[j].Spec.Operator.Name
+		}
+		vi, erri := semver.NewVersion(ovs[i].Spec.Version)
+		vj, errj := semver.NewVersion(ovs[j].Spec.Version)
+		if erri != nil || errj != nil {
+			return ovs[i].Spec.Version < ovs[j].Spec.Version
+		}
+		return vi.LessThan(vj)
+	})
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
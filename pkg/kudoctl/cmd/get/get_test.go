@@ -1,6 +1,9 @@
 package get
 
 import (
+	"bytes"
+	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
@@ -17,10 +20,11 @@ func TestValidate(t *testing.T) {
 		arg []string
 		err string
 	}{
-		{nil, "expecting exactly one argument - \"instances\""},                          // 1
-		{[]string{"arg", "arg2"}, "expecting exactly one argument - \"instances\""},      // 2
-		{[]string{}, "expecting exactly one argument - \"instances\""},                   // 3
-		{[]string{"somethingelse"}, "expecting \"instances\" and not \"somethingelse\""}, // 4
+		{nil, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance"},                             // 1
+		{[]string{"arg", "arg2", "arg3"}, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance"}, // 2
+		{[]string{}, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance"},                      // 3
+		{[]string{"somethingelse"}, "expecting \"instances\" or \"operatorversions\" and not \"somethingelse\""},                             // 4
+		{[]string{"operatorversions", "kafka"}, "only instances can be selected by name"},                                                    // 5
 	}
 
 	for _, tt := range tests {
@@ -61,22 +65,26 @@ func TestGetInstances(t *testing.T) {
 		err       string
 		instances []string
 	}{
-		{nil, "expecting exactly one argument - \"instances\"", nil},                                   // 1
-		{[]string{"arg", "arg2"}, "expecting exactly one argument - \"instances\"", nil},               // 2
-		{[]string{}, "expecting exactly one argument - \"instances\"", nil},                            // 3
-		{[]string{"somethingelse"}, "expecting \"instances\" and not \"somethingelse\"", nil},          // 4
-		{[]string{"instances"}, "expecting \"instances\" and not \"somethingelse\"", []string{"test"}}, // 5
+		{nil, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance", nil},                             // 1
+		{[]string{"arg", "arg2", "arg3"}, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance", nil}, // 2
+		{[]string{}, "expecting \"instances\" or \"operatorversions\", optionally followed by the name of an instance", nil},                      // 3
+		{[]string{"somethingelse"}, "expecting \"instances\" or \"operatorversions\" and not \"somethingelse\"", nil},                             // 4
+		{[]string{"instances"}, "expecting \"instances\" or \"operatorversions\" and not \"somethingelse\"", []string{"test"}},                    // 5
 	}
 
 	for i, tt := range tests {
 		kc := newTestClient()
 		kc.InstallInstanceObjToCluster(testInstance, "default")
-		instanceList, err := getInstances(kc, env.DefaultSettings)
+		instances, err := getInstances(kc, "", "", env.DefaultSettings)
 		if err != nil {
 			if err.Error() != tt.err {
 				t.Errorf("%d: Expecting error message '%s' but got '%s'", i+1, tt.err, err)
 			}
 		}
+		instanceList := []string{}
+		for _, instance := range instances {
+			instanceList = append(instanceList, instance.Name)
+		}
 		missing := compareSlice(tt.instances, instanceList)
 		for _, m := range missing {
 			t.Errorf("%d: Missed expected instance \"%v\"", i+1, m)
@@ -84,6 +92,187 @@ func TestGetInstances(t *testing.T) {
 	}
 }
 
+func TestGetOperatorVersions(t *testing.T) {
+	ov := func(operator, version, appVersion string) *v1alpha1.OperatorVersion {
+		return &v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: operator + "-" + version, Namespace: "default"},
+			Spec: v1alpha1.OperatorVersionSpec{
+				Operator:   v1.ObjectReference{Name: operator, Kind: "Operator"},
+				Version:    version,
+				AppVersion: appVersion,
+				Plans:      map[string]v1alpha1.Plan{"deploy": {}, "update": {}},
+				Parameters: []v1alpha1.Parameter{{Name: "BROKERS"}},
+			},
+		}
+	}
+	instance := func(name, namespace, ovName, ovNamespace string) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: ovName, Namespace: ovNamespace}},
+		}
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(
+		ov("kafka", "1.10.0", "2.4.0"), ov("kafka", "1.2.0", ""), ov("cassandra", "0.1.0", "3.11"),
+		instance("kafka", "default", "kafka-1.10.0", ""),
+		instance("kafka", "team-a", "kafka-1.10.0", "default"),
+		instance("cassandra", "team-a", "cassandra-0.1.0", ""),
+	))
+
+	var out bytes.Buffer
+	if err := getOperatorVersions(&out, kc, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := `NAME           	OPERATOR 	VERSION	APP VERSION	PLANS	PARAMS	INSTANCES
+cassandra-0.1.0	cassandra	0.1.0  	3.11       	2    	1     	0        
+kafka-1.2.0    	kafka    	1.2.0  	-          	2    	1     	0        
+kafka-1.10.0   	kafka    	1.10.0 	2.4.0      	2    	1     	2        
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	settings := *env.DefaultSettings
+	settings.Namespace = "empty"
+	if err := getOperatorVersions(&out, kc, &settings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if out.String() != "No operatorversions installed in namespace \"empty\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+}
+
+func TestPrintInstances(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.2.0", Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Operator: v1.ObjectReference{Name: "kafka"}, Version: "1.2.0"},
+	}
+	instance := func(name string, status v1alpha1.ExecutionStatus) *v1alpha1.Instance {
+		i := &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"kudo.dev/operator": "kafka"}},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.2.0"}},
+		}
+		if status != "" {
+			i.Status.AggregatedStatus.Status = status
+			i.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: status}}
+		}
+		return i
+	}
+	missingOV := instance("orphan", v1alpha1.ExecutionComplete)
+	missingOV.Spec.OperatorVersion.Name = "kafka-0.1.0"
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(ov,
+		instance("healthy", v1alpha1.ExecutionComplete),
+		instance("broken", v1alpha1.ExecutionFatalError),
+		instance("retrying", v1alpha1.ErrorStatus),
+		instance("deploying", v1alpha1.ExecutionInProgress),
+		instance("new", ""),
+		missingOV,
+	))
+
+	var out bytes.Buffer
+	if err := printInstances(&out, kc, "", &Options{}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := `NAME     	OPERATOR	VERSION	LAST PLAN	PLAN STATUS	AGE      
+broken   	kafka   	1.2.0  	deploy   	FATAL_ERROR	<unknown>
+deploying	kafka   	1.2.0  	deploy   	IN_PROGRESS	<unknown>
+healthy  	kafka   	1.2.0  	deploy   	COMPLETE   	<unknown>
+new      	kafka   	1.2.0  	-        	-          	<unknown>
+orphan   	kafka   	-      	deploy   	COMPLETE   	<unknown>
+retrying 	kafka   	1.2.0  	deploy   	ERROR      	<unknown>
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	tests := []struct {
+		status    string
+		instances []string
+	}{
+		{StatusFailed, []string{"broken", "retrying"}},
+		{StatusInProgress, []string{"deploying"}},
+		{StatusHealthy, []string{"healthy", "orphan"}},
+	}
+	for _, tt := range tests {
+		instances, err := getInstances(kc, "", tt.status, env.DefaultSettings)
+		if err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.status, err)
+		}
+		names := []string{}
+		for _, instance := range instances {
+			names = append(names, instance.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, tt.instances) {
+			t.Errorf("%s: expected instances %v but got %v", tt.status, tt.instances, names)
+		}
+	}
+
+	out.Reset()
+	settings := *env.DefaultSettings
+	settings.Namespace = "empty"
+	if err := printInstances(&out, kc, "", &Options{Status: StatusFailed}, &settings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if out.String() != "No failed instances in namespace \"empty\".\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := validateStatus("broken"); err == nil {
+		t.Errorf("expected an error for an unknown status")
+	}
+}
+
+func TestPrintInstances_Wide(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.2.0", Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Operator: v1.ObjectReference{Name: "kafka"}, Version: "1.2.0"},
+	}
+	instance := func(name string, endpoints ...v1alpha1.EndpointStatus) *v1alpha1.Instance {
+		i := &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.2.0"}},
+		}
+		i.Status.AggregatedStatus.Status = v1alpha1.ExecutionComplete
+		i.Status.PlanStatus = map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete}}
+		i.Status.Endpoints = endpoints
+		return i
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(ov,
+		instance("kafka",
+			v1alpha1.EndpointStatus{Name: "bootstrap", Value: "kafka-svc.default:9092"},
+			v1alpha1.EndpointStatus{Name: "metrics", Message: "failed to render endpoint"}),
+		instance("other"),
+	))
+
+	var out bytes.Buffer
+	if err := printInstances(&out, kc, "", &Options{Output: OutputWide}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := `NAME 	OPERATOR	VERSION	LAST PLAN	PLAN STATUS	AGE      	ENDPOINTS                                        
+kafka	kafka   	1.2.0  	deploy   	COMPLETE   	<unknown>	bootstrap=kafka-svc.default:9092,metrics=<failed>
+other	kafka   	1.2.0  	deploy   	COMPLETE   	<unknown>	-                                                
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	if err := printInstances(&out, kc, "other", &Options{}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected = `NAME 	OPERATOR	VERSION	LAST PLAN	PLAN STATUS	AGE      
+other	kafka   	1.2.0  	deploy   	COMPLETE   	<unknown>
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, out.String())
+	}
+
+	if err := printInstances(&out, kc, "missing", &Options{}, env.DefaultSettings); err == nil {
+		t.Errorf("expected an error for a missing instance")
+	}
+}
+
 func compareSlice(real, mock []string) []string {
 	lm := len(mock)
 
@@ -1,16 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/verify"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -35,6 +41,22 @@ To dump a manifest containing the KUDO deployment YAML, combine the '--dry-run'
 
 Running 'kudo init' on server-side is idempotent - it skips manifests alredy applied to the cluster in previous runs
 and finishes with success if KUDO is already installed.
+
+Use '--ha' to install a highly available KUDO manager. It runs two replicas with leader election, spread over the nodes
+of the cluster, and a PodDisruptionBudget makes sure one of them keeps executing plans while a node is drained.
+
+Use '--webhook' to install the admission webhook that defaults Instances server-side. Instances created directly with
+kubectl, e.g. by GitOps tools, are validated against their parameters and get the operator label and a history
+revision like the ones created with kudoctl. The webhook is served with a self-signed certificate created by 'kudo init'.
+
+Use '--verify' to check an existing KUDO installation instead of installing it. It verifies that the CRDs are installed,
+the manager is healthy, the webhook is reachable, the current user is allowed to manage KUDO objects and the pods of
+the instances pull their images from the registry of the KudoConfig.
+
+Use '--upgrade' to upgrade an existing installation. The CRDs, the manager and the validating webhooks are updated
+instead of being kept. Before anything is changed, all Operators, OperatorVersions and Instances are exported to a
+timestamped backup file in '--backup-dir' (default current directory). Should the upgrade lose or break any of these objects, they can be
+re-applied with 'kubectl kudo restore <backup-file>'.
 `
 	initExample = `  # yaml output
   kubectl kudo init --dry-run --output yaml
@@ -44,10 +66,18 @@ and finishes with success if KUDO is already installed.
   kubectl kudo init --client-only
   # set up KUDO in your local environment only (non default $KUDO_HOME)
   kubectl kudo init --client-only --home /opt/home2
+  # install a highly available KUDO manager
+  kubectl kudo init --ha
+  # install KUDO with the admission webhook defaulting instances created with kubectl
+  kubectl kudo init --webhook
   # install kudo crds only
   kubectl kudo init --crd-only
   # delete crds
   kubectl kudo init --crd-only --dry-run --output yaml | kubectl delete -f -
+  # verify the KUDO installation in the cluster
+  kubectl kudo init --verify
+  # upgrade KUDO to a new version, backing up all KUDO objects to /tmp first
+  kubectl kudo init --upgrade --version 0.10.0 --backup-dir /tmp
 `
 )
 
@@ -63,8 +93,14 @@ type initCmd struct {
 	timeout    int64
 	clientOnly bool
 	crdOnly    bool
+	verify     bool
+	ha         bool
+	webhook    bool
+	upgrade    bool
+	backupDir  string
 	home       kudohome.Home
 	client     *kube.Client
+	kudoClient *kudo.Client
 }
 
 func newInitCmd(fs afero.Fs, out io.Writer) *cobra.Command {
@@ -84,6 +120,9 @@ func newInitCmd(fs afero.Fs, out io.Writer) *cobra.Command {
 			}
 			i.home = Settings.Home
 			i.ns = Settings.Namespace
+			if i.dryRun && i.output == "" && Settings.Config != nil {
+				i.output = Settings.Config.Output
+			}
 			clog.V(8).Printf("init cmd %v", i)
 			return i.run()
 		},
@@ -98,6 +137,11 @@ func newInitCmd(fs afero.Fs, out io.Writer) *cobra.Command {
 	f.BoolVar(&i.crdOnly, "crd-only", false, "Add only KUDO CRDs to your cluster")
 	f.BoolVarP(&i.wait, "wait", "w", false, "Block until KUDO manager is running and ready to receive requests")
 	f.Int64Var(&i.timeout, "wait-timeout", 300, "Wait timeout to be used")
+	f.BoolVar(&i.verify, "verify", false, "Verify the KUDO installation in the cluster instead of installing it")
+	f.BoolVar(&i.ha, "ha", false, "Install a highly available KUDO manager with two replicas using leader election")
+	f.BoolVar(&i.webhook, "webhook", false, "Install the admission webhook defaulting instances created without kudoctl")
+	f.BoolVar(&i.upgrade, "upgrade", false, "Upgrade the CRDs and the manager of an existing installation after backing up all KUDO objects")
+	f.StringVar(&i.backupDir, "backup-dir", "", "Directory of the backup file written before an upgrade (default current directory)")
 
 	return cmd
 }
@@ -115,6 +159,21 @@ func (initCmd *initCmd) validate(flags *flag.FlagSet) error {
 	if initCmd.crdOnly && initCmd.wait {
 		return errors.New("wait is not allowed with crd-only")
 	}
+	if initCmd.ha && (initCmd.clientOnly || initCmd.crdOnly || initCmd.verify) {
+		return errors.New("you cannot use client-only, crd-only and verify flags with ha option")
+	}
+	if initCmd.webhook && (initCmd.clientOnly || initCmd.crdOnly || initCmd.verify) {
+		return errors.New("you cannot use client-only, crd-only and verify flags with webhook option")
+	}
+	if initCmd.verify && (initCmd.clientOnly || initCmd.crdOnly || initCmd.dryRun || initCmd.output != "" || initCmd.wait) {
+		return errors.New("you cannot use client-only, crd-only, dry-run, output and wait flags with verify option")
+	}
+	if initCmd.upgrade && (initCmd.clientOnly || initCmd.dryRun || initCmd.verify) {
+		return errors.New("you cannot use client-only, dry-run and verify flags with upgrade option")
+	}
+	if flags.Changed("backup-dir") && !initCmd.upgrade {
+		return errors.New("backup-dir is only useful when using the flag '--upgrade'")
+	}
 	if flags.Changed("wait-timeout") && !initCmd.wait {
 		return errors.New("wait-timeout is only useful when using the flag '--wait'")
 	}
@@ -129,6 +188,21 @@ func (initCmd *initCmd) run() error {
 	if initCmd.image != "" {
 		opts.Image = initCmd.image
 	}
+	if initCmd.ha {
+		opts.Replicas = cmdInit.HAReplicas
+	}
+	if initCmd.webhook {
+		cert, err := cmdInit.NewWebhookCertificate(opts.Namespace)
+		if err != nil {
+			return err
+		}
+		opts.WebhookCertificate = cert
+	}
+
+	if initCmd.verify {
+		return initCmd.verifyServer(opts)
+	}
+	opts.Upgrade = initCmd.upgrade
 
 	//TODO: implement output=yaml|json (define a type for output to constrain)
 	//define an Encoder to replace YAMLWriter
@@ -136,7 +210,7 @@ func (initCmd *initCmd) run() error {
 
 		var mans []string
 
-		crd, err := cmdInit.CRDManifests()
+		crd, err := kudoinit.CRDManifests()
 		if err != nil {
 			return err
 		}
@@ -173,12 +247,14 @@ func (initCmd *initCmd) run() error {
 	// initialize server
 	if !initCmd.clientOnly {
 		clog.V(4).Printf("initializing server")
-		if initCmd.client == nil {
-			client, err := kube.GetKubeClient(Settings.KubeConfig)
-			if err != nil {
-				return clog.Errorf("could not get Kubernetes client: %s", err)
+		if err := initCmd.ensureClient(); err != nil {
+			return err
+		}
+
+		if initCmd.upgrade {
+			if err := initCmd.backup(time.Now()); err != nil {
+				return clog.Errorf("error backing up KUDO objects, nothing was upgraded: %s", err)
 			}
-			initCmd.client = client
 		}
 
 		if err := cmdInit.Install(initCmd.client, opts, initCmd.crdOnly); err != nil {
@@ -197,6 +273,72 @@ func (initCmd *initCmd) run() error {
 	return nil
 }
 
+// verifyServer runs all checks of the KUDO installation and prints their results
+func (initCmd *initCmd) verifyServer(opts cmdInit.Options) error {
+	if err := initCmd.ensureClient(); err != nil {
+		return err
+	}
+	verifyOpts := verify.Options{ManagerNamespace: opts.Namespace, Namespace: Settings.Namespace}
+	if err := initCmd.ensureKudoClient(); err != nil {
+		return err
+	}
+	// the images of instances are checked against the registry of the KudoConfig, other checks do not depend on it
+	if config, err := initCmd.kudoClient.GetKudoConfig(); err != nil {
+		clog.Printf("could not read the KudoConfig, images are not checked against its registry: %s", err)
+	} else {
+		verifyOpts.ImageRegistry = config.Images.Registry
+	}
+	results := verify.Run(initCmd.client, verifyOpts, verify.All()...)
+	results.Print(initCmd.out)
+	return results.Err()
+}
+
+// ensureKudoClient creates the KUDO client unless it is set already
+func (initCmd *initCmd) ensureKudoClient() error {
+	if initCmd.kudoClient == nil {
+		kc, err := kudo.NewClient(Settings.KubeConfig, Settings.Context)
+		if err != nil {
+			return fmt.Errorf("could not get KUDO client: %s", err)
+		}
+		initCmd.kudoClient = kc
+	}
+	return nil
+}
+
+// backup exports all KUDO objects to a timestamped file in the backup directory before they are affected by an upgrade
+func (initCmd *initCmd) backup(now time.Time) error {
+	if err := initCmd.ensureKudoClient(); err != nil {
+		return err
+	}
+
+	b, err := initCmd.kudoClient.Backup()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		return err
+	}
+	path := filepath.Join(initCmd.backupDir, kudo.BackupFileName(now))
+	if err := afero.WriteFile(initCmd.fs, path, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	clog.Printf("✅ backed up %d operators, %d operatorversions and %d instances to %s", len(b.Operators), len(b.OperatorVersions), len(b.Instances), path)
+	return nil
+}
+
+func (initCmd *initCmd) ensureClient() error {
+	if initCmd.client != nil {
+		return nil
+	}
+	client, err := kube.GetKubeClient(Settings.KubeConfig, Settings.Context)
+	if err != nil {
+		return clog.Errorf("could not get Kubernetes client: %s", err)
+	}
+	initCmd.client = client
+	return nil
+}
+
 // YAMLWriter writes yaml to writer.   Looked into using https://godoc.org/gopkg.in/yaml.v2#NewEncoder which
 // looks like a better way, however the omitted JSON elements are encoded which results in a very verbose output.
 //TODO: Write a Encoder util which uses the "sigs.k8s.io/yaml" library for marshalling
@@ -2,32 +2,49 @@ package init
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
 	"github.com/kudobuilder/kudo/pkg/version"
 
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/kubernetes"
 	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	policyv1beta1client "k8s.io/client-go/kubernetes/typed/policy/v1beta1"
 	"sigs.k8s.io/yaml"
 )
 
 //Defines the deployment of the KUDO manager and it's service definition.
 
 const (
-	group              = "kudo.dev"
-	crdVersion         = "v1alpha1"
-	defaultns          = "kudo-system"
 	defaultGracePeriod = 10
+
+	// DefaultNamespace is the namespace the KUDO manager is installed in by default
+	DefaultNamespace = "kudo-system"
+
+	// HAReplicas is the number of manager replicas of a highly available installation
+	HAReplicas = 2
+
+	// healthPort is the port the manager serves its liveness and readiness endpoints at
+	healthPort = 8081
+	// metricsPort is the port the manager serves its Prometheus metrics at
+	metricsPort = 8080
+
+	// managerName is the name of the statefulset and the pod disruption budget of the KUDO manager
+	managerName = "kudo-controller-manager"
+
+	// VersionLabel is the label of the manager statefulset recording the KUDO version the manager runs
+	VersionLabel = "kudo.dev/version"
 )
 
 // Options is the configurable options to init
@@ -40,6 +57,20 @@ type Options struct {
 	TerminationGracePeriodSeconds int64
 	// Image defines the image to be used
 	Image string
+	// Replicas is the number of manager replicas. With more than one replica leader election is enabled and a
+	// PodDisruptionBudget keeps at least one replica running during voluntary disruptions like node drains.
+	Replicas int32
+	// WebhookCertificate is the serving certificate of the admission webhooks of the manager. The webhooks are only
+	// enabled if it is set.
+	WebhookCertificate *Certificate
+	// Upgrade updates the CRDs, the manager statefulset and the validating webhook configurations of an existing
+	// installation instead of keeping them
+	Upgrade bool
+}
+
+// HighlyAvailable returns true if more than one manager replica is installed
+func (o Options) HighlyAvailable() bool {
+	return o.Replicas > 1
 }
 
 // NewOptions provides an option struct with defaults
@@ -49,7 +80,7 @@ func NewOptions(v string, ns string) Options {
 		v = version.Get().GitVersion
 	}
 	if ns == "" {
-		ns = defaultns
+		ns = DefaultNamespace
 	}
 
 	return Options{
@@ -57,6 +88,7 @@ func NewOptions(v string, ns string) Options {
 		Namespace:                     ns,
 		TerminationGracePeriodSeconds: defaultGracePeriod,
 		Image:                         fmt.Sprintf("kudobuilder/controller:v%v", v),
+		Replicas:                      1,
 	}
 }
 
@@ -64,7 +96,7 @@ func NewOptions(v string, ns string) Options {
 func Install(client *kube.Client, opts Options, crdOnly bool) error {
 
 	clog.Printf("✅ installing crds")
-	if err := installCrds(client.ExtClient); err != nil {
+	if err := installCrds(client.ExtClient, opts.Upgrade); err != nil {
 		return err
 	}
 	if crdOnly {
@@ -79,6 +111,13 @@ func Install(client *kube.Client, opts Options, crdOnly bool) error {
 	if err := installManager(client.KubeClient, opts); err != nil {
 		return err
 	}
+
+	if opts.WebhookCertificate != nil {
+		clog.Printf("✅ installing admission webhooks")
+		if err := installWebhook(client.KubeClient, opts); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -91,16 +130,32 @@ func installManager(client kubernetes.Interface, opts Options) error {
 	if err := installService(client.CoreV1(), opts); err != nil {
 		return err
 	}
+
+	if opts.HighlyAvailable() {
+		if err := installPodDisruptionBudget(client.PolicyV1beta1(), opts); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func installStatefulSet(client appsv1client.StatefulSetsGetter, opts Options) error {
 	ss := generateDeployment(opts)
 	_, err := client.StatefulSets(opts.Namespace).Create(ss)
-	if kerrors.IsAlreadyExists(err) {
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	if !opts.Upgrade {
 		clog.V(4).Printf("statefulset %v already exists", ss.Name)
 		return nil
 	}
+	existing, err := client.StatefulSets(opts.Namespace).Get(ss.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	ss.ResourceVersion = existing.ResourceVersion
+	clog.V(4).Printf("updating statefulset %v", ss.Name)
+	_, err = client.StatefulSets(opts.Namespace).Update(ss)
 	return err
 }
 
@@ -115,12 +170,29 @@ func installService(client corev1.ServicesGetter, opts Options) error {
 	return err
 }
 
-// ManagerManifests provides a slice of strings for the deployment and service manifest
+func installPodDisruptionBudget(client policyv1beta1client.PodDisruptionBudgetsGetter, opts Options) error {
+	pdb := generatePodDisruptionBudget(opts)
+	_, err := client.PodDisruptionBudgets(opts.Namespace).Create(pdb)
+	if kerrors.IsAlreadyExists(err) {
+		clog.V(4).Printf("poddisruptionbudget %v already exists", pdb.Name)
+		return nil
+	}
+	return err
+}
+
+// ManagerManifests provides a slice of strings for the deployment and service manifest, the pod disruption
+// budget of a highly available installation and the webhook configurations if webhooks are enabled
 func ManagerManifests(opts Options) ([]string, error) {
 	s := managerService(opts)
 	d := managerDeployment(opts)
 
 	objs := []runtime.Object{s, d}
+	if opts.HighlyAvailable() {
+		objs = append(objs, managerPodDisruptionBudget(opts))
+	}
+	if opts.WebhookCertificate != nil {
+		objs = append(objs, webhookConfigurations(opts)...)
+	}
 
 	manifests := make([]string, len(objs))
 	for i, obj := range objs {
@@ -155,24 +227,43 @@ func managerService(opts Options) *v1.Service {
 	return svc
 }
 
+// managerPodDisruptionBudget provides the KUDO manager pod disruption budget manifest for printing
+func managerPodDisruptionBudget(opts Options) *policyv1beta1.PodDisruptionBudget {
+	pdb := generatePodDisruptionBudget(opts)
+	pdb.TypeMeta = metav1.TypeMeta{
+		Kind:       "PodDisruptionBudget",
+		APIVersion: "policy/v1beta1",
+	}
+	return pdb
+}
+
 func generateDeployment(opts Options) *appsv1.StatefulSet {
 
-	labels := managerLabels()
+	labels := kudoinit.ManagerLabels()
 
 	secretDefaultMode := int32(420)
 	image := opts.Image
+	// the version is not part of the selector, which can not be changed when the manager is upgraded
+	statefulSetLabels := kudoinit.ManagerLabels()
+	statefulSetLabels[VersionLabel] = opts.Version
 	d := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: opts.Namespace,
-			Name:      "kudo-controller-manager",
-			Labels:    labels,
+			Name:      managerName,
+			Labels:    statefulSetLabels,
 		},
 		Spec: appsv1.StatefulSetSpec{
+			Replicas:    &opts.Replicas,
 			Selector:    &metav1.LabelSelector{MatchLabels: labels},
-			ServiceName: "kudo-controller-manager-service",
+			ServiceName: kudoinit.ServiceName,
 			Template: v1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
+					// the conventional annotations of the Prometheus Kubernetes service discovery
+					Annotations: map[string]string{
+						"prometheus.io/scrape": "true",
+						"prometheus.io/port":   strconv.Itoa(metricsPort),
+					},
 				},
 				Spec: v1.PodSpec{
 					ServiceAccountName: "kudo-manager",
@@ -181,7 +272,7 @@ func generateDeployment(opts Options) *appsv1.StatefulSet {
 							Command: []string{"/root/manager"},
 							Env: []v1.EnvVar{
 								{Name: "POD_NAMESPACE", ValueFrom: &v1.EnvVarSource{FieldRef: &v1.ObjectFieldSelector{FieldPath: "metadata.namespace"}}},
-								{Name: "SECRET_NAME", Value: "kudo-webhook-server-secret"},
+								{Name: "SECRET_NAME", Value: kudoinit.WebhookSecretName},
 							},
 							Image:           image,
 							ImagePullPolicy: "Always",
@@ -189,7 +280,11 @@ func generateDeployment(opts Options) *appsv1.StatefulSet {
 							Ports: []v1.ContainerPort{
 								// name matters for service
 								{ContainerPort: 9876, Name: "webhook-server", Protocol: "TCP"},
+								{ContainerPort: healthPort, Name: "health", Protocol: "TCP"},
+								{ContainerPort: metricsPort, Name: "metrics", Protocol: "TCP"},
 							},
+							LivenessProbe:  healthProbe("/healthz"),
+							ReadinessProbe: healthProbe("/readyz"),
 							Resources: v1.ResourceRequirements{
 								Requests: v1.ResourceList{
 									"cpu":    resource.MustParse("100m"),
@@ -202,19 +297,64 @@ func generateDeployment(opts Options) *appsv1.StatefulSet {
 					},
 					TerminationGracePeriodSeconds: &opts.TerminationGracePeriodSeconds,
 					Volumes: []v1.Volume{
-						{Name: "cert", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "kudo-webhook-server-secret", DefaultMode: &secretDefaultMode}}},
+						{Name: "cert", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: kudoinit.WebhookSecretName, DefaultMode: &secretDefaultMode}}},
 					},
 				},
 			},
 		},
 	}
 
+	if opts.WebhookCertificate != nil {
+		d.Spec.Template.Spec.Containers[0].Args = append(d.Spec.Template.Spec.Containers[0].Args, "--enable-webhooks")
+	}
+
+	if opts.HighlyAvailable() {
+		// only the elected leader executes plans, the other replicas take over when it goes away
+		d.Spec.PodManagementPolicy = appsv1.ParallelPodManagement
+		d.Spec.Template.Spec.Containers[0].Args = append(d.Spec.Template.Spec.Containers[0].Args, "--enable-leader-election")
+		// spread the replicas over the nodes so that draining a node does not stop all of them
+		d.Spec.Template.Spec.Affinity = &v1.Affinity{
+			PodAntiAffinity: &v1.PodAntiAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{{
+					Weight: 100,
+					PodAffinityTerm: v1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: labels},
+						TopologyKey:   "kubernetes.io/hostname",
+					},
+				}},
+			},
+		}
+	}
+
 	return d
 }
 
-func managerLabels() labels.Set {
-	labels := generateLabels(map[string]string{"control-plane": "controller-manager", "controller-tools.k8s.io": "1.0"})
-	return labels
+// healthProbe probes the health endpoint of the manager at the given path
+func healthProbe(path string) *v1.Probe {
+	return &v1.Probe{
+		Handler: v1.Handler{
+			HTTPGet: &v1.HTTPGetAction{Path: path, Port: intstr.FromString("health")},
+		},
+		InitialDelaySeconds: 10,
+		PeriodSeconds:       10,
+		FailureThreshold:    3,
+	}
+}
+
+func generatePodDisruptionBudget(opts Options) *policyv1beta1.PodDisruptionBudget {
+	labels := kudoinit.ManagerLabels()
+	minAvailable := intstr.FromInt(1)
+	return &policyv1beta1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: opts.Namespace,
+			Name:      managerName,
+			Labels:    labels,
+		},
+		Spec: policyv1beta1.PodDisruptionBudgetSpec{
+			MinAvailable: &minAvailable,
+			Selector:     &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
 }
 
 func generateService(opts Options) *v1.Service {
@@ -222,7 +362,7 @@ func generateService(opts Options) *v1.Service {
 	s := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: opts.Namespace,
-			Name:      "kudo-controller-manager-service",
+			Name:      kudoinit.ServiceName,
 			Labels:    labels,
 		},
 		Spec: v1.ServiceSpec{
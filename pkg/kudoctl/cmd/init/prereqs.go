@@ -2,6 +2,7 @@ package init
 
 import (
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
 
 	v1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -125,10 +126,13 @@ func generateWebHookSecret(opts Options) *v1.Secret {
 	secret := &v1.Secret{
 		Data: make(map[string][]byte),
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "kudo-webhook-server-secret",
+			Name:      kudoinit.WebhookSecretName,
 			Namespace: opts.Namespace,
 		},
 	}
+	if opts.WebhookCertificate != nil {
+		secret.Data = certificateData(opts.WebhookCertificate)
+	}
 
 	return secret
 }
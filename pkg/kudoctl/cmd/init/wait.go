@@ -2,15 +2,14 @@ package init
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
-	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/version"
 
-	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
-	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
 // WatchKUDOUntilReady waits for the KUDO pod to become available.
@@ -25,7 +24,7 @@ func WatchKUDOUntilReady(client kubernetes.Interface, opts Options, timeout int6
 
 	go func() {
 		for range checkPodTicker.C {
-			image, err := GetKUDOPodImage(client.CoreV1(), opts.Namespace)
+			image, err := kudoinit.GetKUDOPodImage(client.CoreV1(), opts.Namespace)
 			if err == nil && image == opts.Image {
 				doneChan <- true
 				break
@@ -43,34 +42,24 @@ func WatchKUDOUntilReady(client kubernetes.Interface, opts Options, timeout int6
 	}
 }
 
-// GetKUDOPodImage fetches the image of KUDO pod running in the given namespace.
-func GetKUDOPodImage(client corev1.PodsGetter, namespace string) (string, error) {
-	selector := managerLabels().AsSelector()
-	pod, err := getFirstRunningPod(client, namespace, selector)
+// GetKUDOVersion returns the KUDO version of the manager running in the given namespace. It is read from the version
+// label of the manager statefulset, installations without the label fall back to the tag of the manager image.
+func GetKUDOVersion(client kubernetes.Interface, namespace string) (string, error) {
+	ss, err := client.AppsV1().StatefulSets(namespace).Get(managerName, metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
-	for _, c := range pod.Spec.Containers {
-		if c.Name == "manager" {
-			return c.Image, nil
-		}
+	if v, ok := ss.Labels[VersionLabel]; ok {
+		return v, nil
 	}
-	return "", fmt.Errorf("could not find a KUDO pod")
-}
 
-func getFirstRunningPod(client corev1.PodsGetter, namespace string, selector labels.Selector) (*v1.Pod, error) {
-	options := metav1.ListOptions{LabelSelector: selector.String()}
-	pods, err := client.Pods(namespace).List(options)
+	image, err := kudoinit.GetKUDOPodImage(client.CoreV1(), namespace)
 	if err != nil {
-		return nil, err
-	}
-	if len(pods.Items) < 1 {
-		return nil, fmt.Errorf("could not find KUDO manager")
+		return "", err
 	}
-	for _, p := range pods.Items {
-		if kube.IsPodReady(&p) {
-			return &p, nil
-		}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return "", fmt.Errorf("KUDO manager image %s has no tag", image)
 	}
-	return nil, fmt.Errorf("could not find a ready KUDO pod")
+	return version.Clean(image[i+1:]), nil
 }
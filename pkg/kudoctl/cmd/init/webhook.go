@@ -0,0 +1,255 @@
+package init
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/webhook"
+
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1beta1"
+)
+
+//Defines the admission webhooks of the KUDO manager and the self-signed certificate they are served with.
+
+const (
+	webhookConfigurationName   = "kudo-manager-instance-defaulter"
+	validatorConfigurationName = "kudo-manager-instance-validator"
+	certificateValidity        = 10 * 365 * 24 * time.Hour
+)
+
+// Certificate is a PEM encoded certificate and its private key
+type Certificate struct {
+	Cert []byte
+	Key  []byte
+}
+
+// NewWebhookCertificate creates a self-signed certificate for the webhook service of the manager in the namespace
+func NewWebhookCertificate(namespace string) (*Certificate, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate webhook key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("could not generate webhook certificate serial number: %w", err)
+	}
+
+	host := fmt.Sprintf("%s.%s.svc", kudoinit.ServiceName, namespace)
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: host},
+		DNSNames:              []string{kudoinit.ServiceName, fmt.Sprintf("%s.%s", kudoinit.ServiceName, namespace), host, host + ".cluster.local"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certificateValidity),
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create webhook certificate: %w", err)
+	}
+
+	return &Certificate{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}, nil
+}
+
+// installWebhook registers the webhooks of the manager. If the webhook secret was created by an earlier init without
+// webhooks, the certificate is added to it, otherwise the certificate of the secret is trusted.
+func installWebhook(client kubernetes.Interface, opts Options) error {
+	secret, err := client.CoreV1().Secrets(opts.Namespace).Get(kudoinit.WebhookSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if len(secret.Data["tls.crt"]) == 0 {
+		secret.Data = certificateData(opts.WebhookCertificate)
+		if secret, err = client.CoreV1().Secrets(opts.Namespace).Update(secret); err != nil {
+			return err
+		}
+	}
+
+	wh := generateWebhookConfiguration(opts, secret.Data["tls.crt"])
+	_, err = client.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Create(wh)
+	if kerrors.IsAlreadyExists(err) {
+		clog.V(4).Printf("mutating webhook configuration %v already exists", wh.Name)
+	} else if err != nil {
+		return err
+	}
+
+	if err := installValidatingWebhook(client.AdmissionregistrationV1beta1(), generateProtectorConfiguration(opts, secret.Data["tls.crt"]), opts.Upgrade); err != nil {
+		return err
+	}
+	return installValidatingWebhook(client.AdmissionregistrationV1beta1(), generateValidatorConfiguration(opts, secret.Data["tls.crt"]), opts.Upgrade)
+}
+
+// installValidatingWebhook creates the validating webhook configuration. An existing configuration is kept, unless
+// upgrade is set.
+func installValidatingWebhook(client admissionclient.ValidatingWebhookConfigurationsGetter, vwh *admissionv1beta1.ValidatingWebhookConfiguration, upgrade bool) error {
+	_, err := client.ValidatingWebhookConfigurations().Create(vwh)
+	if !kerrors.IsAlreadyExists(err) {
+		return err
+	}
+	if !upgrade {
+		clog.V(4).Printf("validating webhook configuration %v already exists", vwh.Name)
+		return nil
+	}
+	existing, err := client.ValidatingWebhookConfigurations().Get(vwh.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	vwh.ResourceVersion = existing.ResourceVersion
+	clog.V(4).Printf("updating validating webhook configuration %v", vwh.Name)
+	_, err = client.ValidatingWebhookConfigurations().Update(vwh)
+	return err
+}
+
+func certificateData(c *Certificate) map[string][]byte {
+	return map[string][]byte{
+		"tls.crt": c.Cert,
+		"tls.key": c.Key,
+	}
+}
+
+// generateWebhookConfiguration builds the mutating webhook configuration of the instance defaulter. Requests are
+// admitted unchanged while the manager is unavailable.
+func generateWebhookConfiguration(opts Options, caBundle []byte) *admissionv1beta1.MutatingWebhookConfiguration {
+	failurePolicy := admissionv1beta1.Ignore
+	path := webhook.InstanceDefaulterPath
+	return &admissionv1beta1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   webhookConfigurationName,
+			Labels: kudoinit.ManagerLabels(),
+		},
+		Webhooks: []admissionv1beta1.Webhook{
+			{
+				Name: "instance-defaulter.kudo.dev",
+				Rules: []admissionv1beta1.RuleWithOperations{{
+					Operations: []admissionv1beta1.OperationType{admissionv1beta1.Create, admissionv1beta1.Update},
+					Rule: admissionv1beta1.Rule{
+						APIGroups:   []string{kudoinit.Group},
+						APIVersions: []string{kudoinit.CRDVersion},
+						Resources:   []string{"instances"},
+					},
+				}},
+				ClientConfig: admissionv1beta1.WebhookClientConfig{
+					Service: &admissionv1beta1.ServiceReference{
+						Namespace: opts.Namespace,
+						Name:      kudoinit.ServiceName,
+						Path:      &path,
+					},
+					CABundle: caBundle,
+				},
+				FailurePolicy: &failurePolicy,
+			},
+		},
+	}
+}
+
+// generateProtectorConfiguration builds the validating webhook configuration of the instance protector. Deletions of
+// instances are rejected while the manager is unavailable, so that protected instances can not be deleted by stopping
+// it. Only deletions are sent to the webhook, other changes of instances are not blocked by an unavailable manager.
+func generateProtectorConfiguration(opts Options, caBundle []byte) *admissionv1beta1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionv1beta1.Fail
+	path := webhook.InstanceProtectorPath
+	return &admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   kudoinit.ProtectorConfigurationName,
+			Labels: kudoinit.ManagerLabels(),
+		},
+		Webhooks: []admissionv1beta1.Webhook{
+			{
+				Name: "instance-protector.kudo.dev",
+				Rules: []admissionv1beta1.RuleWithOperations{{
+					Operations: []admissionv1beta1.OperationType{admissionv1beta1.Delete},
+					Rule: admissionv1beta1.Rule{
+						APIGroups:   []string{kudoinit.Group},
+						APIVersions: []string{kudoinit.CRDVersion},
+						Resources:   []string{"instances"},
+					},
+				}},
+				ClientConfig: admissionv1beta1.WebhookClientConfig{
+					Service: &admissionv1beta1.ServiceReference{
+						Namespace: opts.Namespace,
+						Name:      kudoinit.ServiceName,
+						Path:      &path,
+					},
+					CABundle: caBundle,
+				},
+				FailurePolicy: &failurePolicy,
+			},
+		},
+	}
+}
+
+// generateValidatorConfiguration builds the validating webhook configuration of the instance validator. Creations and
+// updates of instances are rejected while the manager is unavailable, so that invalid instances are not admitted and
+// upgrades can not skip the pre-upgrade checks.
+func generateValidatorConfiguration(opts Options, caBundle []byte) *admissionv1beta1.ValidatingWebhookConfiguration {
+	failurePolicy := admissionv1beta1.Fail
+	path := webhook.InstanceValidatorPath
+	return &admissionv1beta1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   validatorConfigurationName,
+			Labels: kudoinit.ManagerLabels(),
+		},
+		Webhooks: []admissionv1beta1.Webhook{
+			{
+				Name: "instance-validator.kudo.dev",
+				Rules: []admissionv1beta1.RuleWithOperations{{
+					Operations: []admissionv1beta1.OperationType{admissionv1beta1.Create, admissionv1beta1.Update},
+					Rule: admissionv1beta1.Rule{
+						APIGroups:   []string{kudoinit.Group},
+						APIVersions: []string{kudoinit.CRDVersion},
+						Resources:   []string{"instances"},
+					},
+				}},
+				ClientConfig: admissionv1beta1.WebhookClientConfig{
+					Service: &admissionv1beta1.ServiceReference{
+						Namespace: opts.Namespace,
+						Name:      kudoinit.ServiceName,
+						Path:      &path,
+					},
+					CABundle: caBundle,
+				},
+				FailurePolicy: &failurePolicy,
+			},
+		},
+	}
+}
+
+// webhookConfigurations provides the mutating and validating webhook configuration manifests for printing
+func webhookConfigurations(opts Options) []runtime.Object {
+	wh := generateWebhookConfiguration(opts, opts.WebhookCertificate.Cert)
+	wh.TypeMeta = metav1.TypeMeta{
+		Kind:       "MutatingWebhookConfiguration",
+		APIVersion: "admissionregistration.k8s.io/v1beta1",
+	}
+	vwh := generateProtectorConfiguration(opts, opts.WebhookCertificate.Cert)
+	vwh.TypeMeta = metav1.TypeMeta{
+		Kind:       "ValidatingWebhookConfiguration",
+		APIVersion: "admissionregistration.k8s.io/v1beta1",
+	}
+	uwh := generateValidatorConfiguration(opts, opts.WebhookCertificate.Cert)
+	uwh.TypeMeta = metav1.TypeMeta{
+		Kind:       "ValidatingWebhookConfiguration",
+		APIVersion: "admissionregistration.k8s.io/v1beta1",
+	}
+	return []runtime.Object{wh, vwh, uwh}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	cmdinit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
 
 	testutils "github.com/kudobuilder/kudo/pkg/test/utils"
 	"github.com/spf13/afero"
@@ -54,7 +55,7 @@ func TestIntegInitForCRDs(t *testing.T) {
 	assert.IsType(t, &meta.NoKindMatchError{}, testClient.Create(context.TODO(), instance))
 
 	// Install all of the CRDs.
-	crds := cmdinit.CRDs()
+	crds := kudoinit.CRDs()
 	defer deleteInitObjects(testClient)
 
 	var buf bytes.Buffer
@@ -93,7 +94,7 @@ func TestIntegInitWithNameSpace(t *testing.T) {
 	assert.IsType(t, &meta.NoKindMatchError{}, testClient.Create(context.TODO(), instance))
 
 	// Install all of the CRDs.
-	crds := cmdinit.CRDs()
+	crds := kudoinit.CRDs()
 	defer deleteInitObjects(testClient)
 
 	var buf bytes.Buffer
@@ -142,7 +143,7 @@ func TestNoErrorOnReInit(t *testing.T) {
 	assert.IsType(t, &meta.NoKindMatchError{}, testClient.Create(context.TODO(), instance))
 
 	// Install all of the CRDs.
-	crds := cmdinit.CRDs()
+	crds := kudoinit.CRDs()
 	defer deleteInitObjects(testClient)
 
 	var buf bytes.Buffer
@@ -175,7 +176,7 @@ func TestNoErrorOnReInit(t *testing.T) {
 }
 
 func deleteInitObjects(client *testutils.RetryClient) {
-	crds := cmdinit.CRDs()
+	crds := kudoinit.CRDs()
 	prereqs := cmdinit.Prereq(cmdinit.NewOptions("", ""))
 	deleteCRDs(crds, client)
 	deletePrereq(prereqs, client)
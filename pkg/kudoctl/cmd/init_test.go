@@ -9,13 +9,19 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	kudofake "github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	apiextfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
@@ -79,6 +85,175 @@ func TestInitCmd_exists(t *testing.T) {
 	}
 }
 
+func TestInitCmd_HA(t *testing.T) {
+	var buf bytes.Buffer
+	fc := fake.NewSimpleClientset()
+	cmd := &initCmd{
+		out:    &buf,
+		fs:     afero.NewMemMapFs(),
+		client: &kube.Client{KubeClient: fc, ExtClient: apiextfake.NewSimpleClientset()},
+		ha:     true,
+	}
+	clog.Init(nil, &buf)
+	Settings.Home = "/opt"
+
+	if err := cmd.run(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	ss, err := fc.AppsV1().StatefulSets("kudo-system").Get("kudo-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the manager to be installed but got %v", err)
+	}
+	if *ss.Spec.Replicas != cmdInit.HAReplicas {
+		t.Errorf("expected %d replicas but got %d", cmdInit.HAReplicas, *ss.Spec.Replicas)
+	}
+	if args := ss.Spec.Template.Spec.Containers[0].Args; len(args) != 1 || args[0] != "--enable-leader-election" {
+		t.Errorf("expected leader election to be enabled but got args %v", args)
+	}
+	if ss.Spec.Template.Spec.Affinity == nil || ss.Spec.Template.Spec.Affinity.PodAntiAffinity == nil {
+		t.Errorf("expected the replicas to be spread over the nodes")
+	}
+
+	pdb, err := fc.PolicyV1beta1().PodDisruptionBudgets("kudo-system").Get("kudo-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected a pod disruption budget to be installed but got %v", err)
+	}
+	if pdb.Spec.MinAvailable.IntValue() != 1 {
+		t.Errorf("expected a pod disruption budget with minAvailable 1 but got %v", pdb.Spec.MinAvailable)
+	}
+}
+
+func TestInitCmd_Upgrade(t *testing.T) {
+	var buf bytes.Buffer
+	fc := fake.NewSimpleClientset(&appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kudo-system", Name: "kudo-controller-manager"},
+		Spec: appsv1.StatefulSetSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "manager", Image: "kudobuilder/controller:v0.8.0"}},
+		}}},
+	})
+	kc := kudofake.NewSimpleClientset(&v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kafka"}})
+	fs := afero.NewMemMapFs()
+	cmd := &initCmd{
+		out:        &buf,
+		fs:         fs,
+		version:    "0.9.0",
+		upgrade:    true,
+		backupDir:  "/backup",
+		client:     &kube.Client{KubeClient: fc, ExtClient: apiextfake.NewSimpleClientset()},
+		kudoClient: kudo.NewClientFromK8s(kc),
+	}
+	clog.Init(nil, &buf)
+	Settings.Home = "/opt"
+
+	if err := cmd.run(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	files, err := afero.ReadDir(fs, "/backup")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected a backup file to be written but got %v, %v", files, err)
+	}
+	backup, err := afero.ReadFile(fs, filepath.Join("/backup", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(backup), "name: kafka") {
+		t.Errorf("expected the instance to be backed up but got %s", backup)
+	}
+
+	ss, err := fc.AppsV1().StatefulSets("kudo-system").Get("kudo-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := ss.Spec.Template.Spec.Containers[0].Image; image != "kudobuilder/controller:v0.9.0" {
+		t.Errorf("expected the manager to be upgraded but got image %s", image)
+	}
+	if v, err := cmdInit.GetKUDOVersion(fc, "kudo-system"); err != nil || v != "0.9.0" {
+		t.Errorf("expected the manager to be labeled with version 0.9.0 but got %s, %v", v, err)
+	}
+}
+
+func TestGetKUDOVersion_ImageTag(t *testing.T) {
+	// managers installed before the version label was added are identified by the tag of their image
+	labels := map[string]string{"app": "kudo-manager", "control-plane": "controller-manager", "controller-tools.k8s.io": "1.0"}
+	fc := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "kudo-system", Name: "kudo-controller-manager", Labels: labels}},
+		&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "kudo-system", Name: "kudo-controller-manager-0", Labels: labels},
+			Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "manager", Image: "kudobuilder/controller:v0.8.0"}}},
+			Status:     v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}},
+		},
+	)
+
+	v, err := cmdInit.GetKUDOVersion(fc, "kudo-system")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.8.0", v)
+}
+
+func TestInitCmd_Webhook(t *testing.T) {
+	var buf bytes.Buffer
+	fc := fake.NewSimpleClientset()
+	cmd := &initCmd{
+		out:     &buf,
+		fs:      afero.NewMemMapFs(),
+		client:  &kube.Client{KubeClient: fc, ExtClient: apiextfake.NewSimpleClientset()},
+		webhook: true,
+	}
+	clog.Init(nil, &buf)
+	Settings.Home = "/opt"
+
+	if err := cmd.run(); err != nil {
+		t.Fatalf("did not expect error: %v", err)
+	}
+
+	ss, err := fc.AppsV1().StatefulSets("kudo-system").Get("kudo-controller-manager", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the manager to be installed but got %v", err)
+	}
+	if args := ss.Spec.Template.Spec.Containers[0].Args; len(args) != 1 || args[0] != "--enable-webhooks" {
+		t.Errorf("expected webhooks to be enabled but got args %v", args)
+	}
+
+	secret, err := fc.CoreV1().Secrets("kudo-system").Get("kudo-webhook-server-secret", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the webhook secret to be installed but got %v", err)
+	}
+	if len(secret.Data["tls.crt"]) == 0 || len(secret.Data["tls.key"]) == 0 {
+		t.Errorf("expected the webhook secret to contain a certificate")
+	}
+
+	wh, err := fc.AdmissionregistrationV1beta1().MutatingWebhookConfigurations().Get("kudo-manager-instance-defaulter", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the webhook configuration to be installed but got %v", err)
+	}
+	if !bytes.Equal(wh.Webhooks[0].ClientConfig.CABundle, secret.Data["tls.crt"]) {
+		t.Errorf("expected the webhook to trust the certificate of the secret")
+	}
+
+	vwh, err := fc.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("kudo-manager-instance-protector", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the validating webhook configuration to be installed but got %v", err)
+	}
+	if !bytes.Equal(vwh.Webhooks[0].ClientConfig.CABundle, secret.Data["tls.crt"]) {
+		t.Errorf("expected the validating webhook to trust the certificate of the secret")
+	}
+	if p := vwh.Webhooks[0].FailurePolicy; p == nil || *p != admissionv1beta1.Fail {
+		t.Errorf("expected deletions to be rejected while the instance protector is unavailable")
+	}
+
+	uwh, err := fc.AdmissionregistrationV1beta1().ValidatingWebhookConfigurations().Get("kudo-manager-instance-validator", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the instance validating webhook configuration to be installed but got %v", err)
+	}
+	if p := uwh.Webhooks[0].FailurePolicy; p == nil || *p != admissionv1beta1.Fail {
+		t.Errorf("expected instances to be rejected while the instance validator is unavailable")
+	}
+	if ops := uwh.Webhooks[0].Rules[0].Operations; len(ops) != 2 || ops[0] != admissionv1beta1.Create || ops[1] != admissionv1beta1.Update {
+		t.Errorf("expected creations and updates of instances to be validated but got %v", ops)
+	}
+}
+
 // TestInitCmd_output tests that init -o can be decoded
 func TestInitCmd_output(t *testing.T) {
 
@@ -158,6 +333,10 @@ func TestNewInitCmd(t *testing.T) {
 		{name: "arguments invalid", parameters: []string{"foo"}, errorMessage: "this command does not accept arguments"},
 		{name: "name and version together invalid", flags: map[string]string{"kudo-image": "foo", "version": "bar"}, errorMessage: "specify either 'kudo-image' or 'version', not both"},
 		{name: "crd-only and wait together invalid", flags: map[string]string{"crd-only": "true", "wait": "true"}, errorMessage: "wait is not allowed with crd-only"},
+		{name: "verify and client-only together invalid", flags: map[string]string{"verify": "true", "client-only": "true"}, errorMessage: "you cannot use client-only, crd-only, dry-run, output and wait flags with verify option"},
+		{name: "ha and crd-only together invalid", flags: map[string]string{"ha": "true", "crd-only": "true"}, errorMessage: "you cannot use client-only, crd-only and verify flags with ha option"},
+		{name: "upgrade and dry-run together invalid", flags: map[string]string{"upgrade": "true", "dry-run": "true"}, errorMessage: "you cannot use client-only, dry-run and verify flags with upgrade option"},
+		{name: "backup-dir invalid without upgrade", flags: map[string]string{"backup-dir": "/tmp"}, errorMessage: "backup-dir is only useful when using the flag '--upgrade'"},
 		{name: "wait-timeout invalid without wait", flags: map[string]string{"wait-timeout": "400"}, errorMessage: "wait-timeout is only useful when using the flag '--wait'"},
 	}
 
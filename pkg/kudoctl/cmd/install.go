@@ -10,7 +10,8 @@ import (
 
 var (
 	installExample = `  The install argument must be a name of the package in the repository, a path to package in *.tgz format,
-  or a path to an unpacked package directory.
+  a path to an unpacked package directory, a directory in a Git repository prefixed with 'git+' or '-' to read a package
+  in *.tgz format from stdin.
 
   # Install the most recent Flink package to your cluster.
   kubectl kudo install flink
@@ -25,23 +26,138 @@ var (
   # Install operator from tarball at URL
   kubectl kudo install http://kudo.dev/zk.tgz
 
+  # Install operator from the kafka directory of a Git repository at a tag, the commit it resolves to is recorded in the
+  # kudo.dev/package-source annotation. Private repositories authenticate with the ssh agent or the KUDO_GIT_TOKEN,
+  # which is only sent to the hosts listed in KUDO_GIT_TOKEN_HOSTS.
+  kubectl kudo install git+https://github.com/org/operators//kafka?ref=v1.2.0
+
+  # Install operator from a tarball read from stdin
+  curl -sL http://kudo.dev/zk.tgz | kubectl kudo install -
+
   # Specify a package version of Kafka to install to your cluster
-  kubectl kudo install kafka --version=1.1.1`
+  kubectl kudo install kafka --version=1.1.1
+
+  # Install the most recent Kafka package of the 1.x versions starting with 1.2
+  kubectl kudo install kafka --version='>=1.2 <2'
+
+  # Install with parameters from a base file overlaid by an environment specific file
+  kubectl kudo install kafka --parameter-file base.yaml --parameter-file prod.yaml
+
+  # Install an instance into the team namespace sharing the OperatorVersion of the kudo-catalog namespace
+  kubectl kudo install kafka --namespace team-a --catalog-namespace kudo-catalog
+
+  # Publish the Operator and OperatorVersion of Kafka to a catalog namespace without creating an instance
+  kubectl kudo install kafka --version=1.1.1 --namespace kudo-catalog --skip-instance
+
+  # Create an instance of the most recent Kafka version published to the catalog namespace
+  kubectl kudo install kafka --only-instance --namespace team-a --catalog-namespace kudo-catalog
+
+  # Pull the images of the instance from a mirror registry in an air-gapped cluster
+  kubectl kudo install kafka --image-registry registry.example.com:5000 --image-pull-secret mirror-credentials
+
+  # Install with the production overlay of the package and additional patches of the rendered templates
+  kubectl kudo install kafka --overlay production --patch-file team-annotations.yaml
+
+  # Schedule all pods of the instance on the dedicated database nodes
+  kubectl kudo install cassandra --set-node-selector pool=databases --set-tolerations dedicated=databases:NoSchedule
+
+  # Let Prometheus scrape all pods of the instance
+  kubectl kudo install kafka --set-pod-annotation prometheus.io/scrape=true --set-pod-label team=data
+
+  # Encrypt the values of sensitive parameters before they are stored in the instance
+  kubectl kudo install kafka -p SUPER_PASSWORD=secret --encryption-config encryption.yaml
+
+  # Check that the KUDO CRDs are installed and the RBAC permissions suffice before installing
+  kubectl kudo install kafka --verify`
 )
 
 // newInstallCmd creates the install command for the CLI
 func newInstallCmd(fs afero.Fs) *cobra.Command {
 	options := install.DefaultOptions
 	var parameters []string
+	var parameterFiles []string
+	var patchFiles []string
+	var encryptionConfig string
+	var nodeSelector []string
+	var tolerations []string
+	var affinity string
+	var podLabels []string
+	var podAnnotations []string
 	installCmd := &cobra.Command{
-		Use:     "install <name>",
-		Short:   "Install an official KUDO package.",
-		Long:    `Install a KUDO package from local filesystem or the official repo.`,
+		Use:   "install <name>",
+		Short: "Install an official KUDO package.",
+		Long: `Install a KUDO package from local filesystem or the official repo.
+
+Parameters can be given with -p or read from YAML files with --parameter-file. Parameter files are merged in the
+given order: scalar values and lists of a later file replace earlier values, maps are merged recursively and a null
+value removes a parameter. Parameters given with -p always take precedence over parameter files.
+
+The rendered templates can be changed with the patches of an overlay of the package, selected with --overlay, and with
+patch files given with --patch-file. A patch file is a YAML list of strategic merge patches and JSON patches:
+
+  - strategicMerge: |
+      apiVersion: apps/v1
+      kind: StatefulSet
+      metadata:
+        name: kafka
+      spec:
+        replicas: 5
+  - target: {group: apps, version: v1, kind: StatefulSet, name: kafka}
+    json6902: |
+      - op: add
+        path: /metadata/annotations/backup
+        value: "true"
+
+Objects are selected by the names they have in the templates. Patch files are applied after the overlay.
+
+The nodes all pods of the instance are scheduled on can be constrained with --set-node-selector, --set-tolerations and
+--set-affinity, independent of the parameters of the package. Node selector entries and tolerations are added to every
+pod, tolerations have the format key[=value][:effect] of taints. The affinity is given in YAML or JSON and replaces the
+node affinity, pod affinity and pod anti-affinity of every pod as far as it sets them:
+
+  --set-affinity "$(cat affinity.yaml)"
+
+Labels and annotations given with --set-pod-label and --set-pod-annotation are added to all pods of the instance, e.g.
+the scrape annotations of Prometheus or the sidecar injection flags of a service mesh. They replace the labels and
+annotations the templates set for the same keys.
+
+The manager applies the resources of the instance impersonating the ServiceAccount given with --service-account, so
+that the instance can only create what the RBAC permissions of the ServiceAccount in its namespace allow. The
+ServiceAccount has to exist in the namespace of the instance.
+
+The values of parameters marked as sensitive by the operator are encrypted before the instance is created if an
+encryption configuration is given with --encryption-config. The manager needs a configuration with the same providers
+to decrypt them. Values are encrypted with the first provider, either an AES key or the commands of a KMS plugin:
+
+  providers:
+  - name: kms
+    exec:
+      encrypt: ["kudo-kms-plugin", "encrypt", "--key-id", "alias/kudo"]
+      decrypt: ["kudo-kms-plugin", "decrypt"]
+  - name: team-key
+    aesgcm:
+      keyFile: /etc/kudo/encryption/team.key`,
 		Example: installExample,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Prior to command execution we parse and validate passed arguments
 			var err error
-			options.Parameters, err = install.GetParameterMap(parameters)
+			options.Parameters, err = install.GetParameters(fs, parameterFiles, parameters)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.Patches, err = install.GetPatches(fs, patchFiles)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.Placement, err = install.GetPlacement(nodeSelector, tolerations, affinity)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.PodMetadata, err = install.GetPodMetadata(podLabels, podAnnotations)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.Keyring, err = install.GetKeyring(encryptionConfig)
 			if err != nil {
 				return errors.WithMessage(err, "could not parse arguments")
 			}
@@ -52,8 +168,23 @@ func newInstallCmd(fs afero.Fs) *cobra.Command {
 
 	installCmd.Flags().StringVar(&options.InstanceName, "instance", "", "The instance name. (defaults to operator name plus some random string)")
 	installCmd.Flags().StringArrayVarP(&parameters, "parameter", "p", nil, "The parameter name and value separated by '='")
-	installCmd.Flags().StringVar(&options.RepoName, "repo", "", "Name of repository configuration to use. (default defined by context)")
-	installCmd.Flags().StringVar(&options.PackageVersion, "version", "", "A specific package version on the official GitHub repo. (default to the most recent)")
+	installCmd.Flags().StringArrayVar(&parameterFiles, "parameter-file", nil, "A YAML file with parameters, can be repeated. Later files are merged on top of earlier ones and -p takes precedence")
+	installCmd.Flags().StringVar(&options.RepoName, "repo", "", "Name of repository configuration to use. (default defined by the client configuration or context)")
+	installCmd.Flags().StringVar(&options.PackageVersion, "version", "", "A specific package version or a version constraint, e.g. '>=1.2 <2', on the official repository. (default to the most recent)")
+	installCmd.Flags().StringVar(&options.CatalogNamespace, "catalog-namespace", "", "Namespace to install the Operator and OperatorVersion into, so they can be shared by instances of other namespaces. (default to the namespace of the instance)")
 	installCmd.Flags().BoolVar(&options.SkipInstance, "skip-instance", false, "If set, install will install the Operator and OperatorVersion, but not an instance. (default \"false\")")
+	installCmd.Flags().StringVar(&options.ImageRegistry, "image-registry", "", "Registry to pull all images of the instance from, overrides the registry of the KudoConfig. (default defined by the KudoConfig)")
+	installCmd.Flags().StringArrayVar(&options.ImagePullSecrets, "image-pull-secret", nil, "Name of a secret used to pull the images of the instance, can be repeated. Overrides the pull secrets of the KudoConfig")
+	installCmd.Flags().StringVar(&options.Overlay, "overlay", "", "Name of the overlay of the package whose patches are applied to the rendered templates, e.g. production")
+	installCmd.Flags().StringArrayVar(&patchFiles, "patch-file", nil, "A YAML file with a list of patches applied to the rendered templates after the overlay, can be repeated")
+	installCmd.Flags().StringArrayVar(&nodeSelector, "set-node-selector", nil, "A node selector entry 'key=value' added to all pods of the instance, can be repeated")
+	installCmd.Flags().StringArrayVar(&tolerations, "set-tolerations", nil, "A toleration 'key[=value][:effect]' added to all pods of the instance, can be repeated")
+	installCmd.Flags().StringVar(&affinity, "set-affinity", "", "Affinity in YAML or JSON that replaces the affinity of all pods of the instance")
+	installCmd.Flags().StringArrayVar(&podLabels, "set-pod-label", nil, "A label 'key=value' added to all pods of the instance, can be repeated")
+	installCmd.Flags().StringArrayVar(&podAnnotations, "set-pod-annotation", nil, "An annotation 'key=value' added to all pods of the instance, can be repeated")
+	installCmd.Flags().StringVar(&options.ServiceAccountName, "service-account", "", "Name of the ServiceAccount in the namespace of the instance that the manager impersonates to apply its resources")
+	installCmd.Flags().StringVar(&encryptionConfig, "encryption-config", "", "Encryption configuration file whose first provider encrypts the values of sensitive parameters")
+	installCmd.Flags().BoolVar(&options.Verify, "verify", false, "If set, install checks that the KUDO CRDs are installed and the RBAC permissions allow to manage KUDO objects before installing. (default \"false\")")
+	installCmd.Flags().BoolVar(&options.OnlyInstance, "only-instance", false, "If set, install will only create an instance of an OperatorVersion that is already installed in the catalog namespace, the argument is the operator name. (default \"false\")")
 	return installCmd
 }
@@ -0,0 +1,43 @@
+package install
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+)
+
+// GetKeyring loads the keyring of the encryption configuration file, it returns nil if no file is given
+func GetKeyring(configFile string) (*encryption.Keyring, error) {
+	if configFile == "" {
+		return nil, nil
+	}
+	return encryption.LoadConfig(configFile)
+}
+
+// EncryptSensitiveParameters returns a copy of the parameters in which the values of the parameters the
+// OperatorVersion marks as sensitive are encrypted with the keyring. Values that are already encrypted are kept.
+// Plaintext values are encrypted with a new nonce every time, so passing the current value of a parameter again in
+// plaintext changes the stored value and triggers the plan of the parameter. Without keyring the parameters are
+// returned unchanged.
+func EncryptSensitiveParameters(ov *v1alpha1.OperatorVersion, params map[string]string, keyring *encryption.Keyring) (map[string]string, error) {
+	if keyring == nil || len(params) == 0 {
+		return params, nil
+	}
+	encrypted := make(map[string]string, len(params))
+	for k, v := range params {
+		encrypted[k] = v
+	}
+	for _, p := range ov.Spec.Parameters {
+		v, ok := params[p.Name]
+		if !ok || !p.Sensitive {
+			continue
+		}
+		e, err := keyring.Encrypt(p.Name, v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt parameter %s: %w", p.Name, err)
+		}
+		encrypted[p.Name] = e
+	}
+	return encrypted, nil
+}
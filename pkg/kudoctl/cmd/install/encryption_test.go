@@ -0,0 +1,35 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptSensitiveParameters(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{Spec: v1alpha1.OperatorVersionSpec{Parameters: []v1alpha1.Parameter{
+		{Name: "PASSWORD", Sensitive: true},
+		{Name: "TOKEN", Sensitive: true},
+		{Name: "USER"},
+	}}}
+	params := map[string]string{"PASSWORD": "hunter2", "TOKEN": "ENC[kms:dG9rZW4=]", "USER": "admin"}
+
+	unchanged, err := EncryptSensitiveParameters(ov, params, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, params, unchanged, "parameters are stored in plaintext without keyring")
+
+	keyring := encryption.NewKeyring()
+	assert.NoError(t, keyring.Add("plain", &encryption.ExecProvider{EncryptCommand: []string{"cat"}}))
+	encrypted, err := EncryptSensitiveParameters(ov, params, keyring)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"PASSWORD": "ENC[plain:aHVudGVyMg==]", "TOKEN": "ENC[kms:dG9rZW4=]", "USER": "admin"}, encrypted)
+	assert.Equal(t, "hunter2", params["PASSWORD"], "the given parameters are not changed")
+
+	failing := encryption.NewKeyring()
+	assert.NoError(t, failing.Add("kms", &encryption.ExecProvider{DecryptCommand: []string{"cat"}}))
+	_, err = EncryptSensitiveParameters(ov, params, failing)
+	assert.EqualError(t, err, "failed to encrypt parameter PASSWORD: failed to encrypt with provider kms: no encrypt command is configured")
+}
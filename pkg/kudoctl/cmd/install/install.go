@@ -1,20 +1,27 @@
 package install
 
 import (
-	"os"
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
-	"github.com/kudobuilder/kudo/pkg/kudoctl/http"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
-	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/finder"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/resolver"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/verify"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
 
+	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 // RepositoryOptions defines the options necessary for any cmd working with repository
@@ -29,6 +36,30 @@ type Options struct {
 	Parameters     map[string]string
 	PackageVersion string
 	SkipInstance   bool
+	// OnlyInstance creates only the Instance, the OperatorVersion it uses has to be installed already
+	OnlyInstance bool
+	// CatalogNamespace is the namespace the Operator and OperatorVersion are installed into, so they can be shared by
+	// instances of other namespaces. Defaults to the namespace of the instance.
+	CatalogNamespace string
+	// ImageRegistry and ImagePullSecrets override the image settings of the KudoConfig for the instance, see
+	// v1alpha1.ImageSettings
+	ImageRegistry    string
+	ImagePullSecrets []string
+	// Overlay selects an overlay of the OperatorVersion and Patches are applied after its patches, see v1alpha1.Patch
+	Overlay string
+	Patches []v1alpha1.Patch
+	// Placement constrains the nodes all pods of the instance are scheduled on, see v1alpha1.Placement
+	Placement *v1alpha1.Placement
+	// PodMetadata are labels and annotations added to all pods of the instance, see v1alpha1.PodMetadata
+	PodMetadata *v1alpha1.PodMetadata
+	// ServiceAccountName is the ServiceAccount the manager impersonates to apply the resources of the instance
+	ServiceAccountName string
+	// Keyring encrypts the values of sensitive parameters before the instance is created, parameters are stored in
+	// plaintext without it
+	Keyring *encryption.Keyring
+	// Verify checks that the KUDO CRDs are installed and the user is allowed to manage KUDO objects before anything
+	// is installed. The checks need several requests to the API server, so they are only run on demand.
+	Verify bool
 }
 
 // DefaultOptions initializes the install command options to its defaults
@@ -50,75 +81,73 @@ func validate(args []string, options *Options) error {
 	if len(args) != 1 {
 		return clog.Errorf("expecting exactly one argument - name of the package or path to install")
 	}
-
-	return nil
-}
-
-// GetPackageCRDs tries to look for package files resolving the operator name to:
-// - a local tgz file
-// - a local directory
-// - a url to a tgz
-// - an operator name in the remote repository
-// in that order. Should there exist a local folder e.g. `cassandra` it will take precedence
-// over the remote repository package with the same name.
-func GetPackageCRDs(name string, version string, repository repo.Repository) (*packages.PackageCRDs, error) {
-
-	// Local files/folder have priority
-	if _, err := os.Stat(name); err == nil {
-		clog.V(2).Printf("local operator discovered: %v", name)
-		f := finder.NewLocal()
-		b, err := f.GetPackage(name, version)
-		if err != nil {
-			return nil, err
-		}
-		return b.GetCRDs()
+	if options.SkipInstance && options.OnlyInstance {
+		return clog.Errorf("--skip-instance and --only-instance can not be used together")
 	}
 
-	clog.V(3).Printf("no local operator discovered, looking for http")
-	if http.IsValidURL(name) {
-		clog.V(3).Printf("operator using http protocol for %v", name)
-		f := finder.NewURL()
-		b, err := f.GetPackage(name, version)
-		if err != nil {
-			return nil, err
-		}
-		return b.GetCRDs()
-	}
-
-	clog.V(3).Printf("no http discovered, looking for repository")
-	b, err := repository.GetPackage(name, version)
-	if err != nil {
-		return nil, err
-	}
-	return b.GetCRDs()
+	return nil
 }
 
 // installOperator is installing single operator into cluster and returns error in case of error
 func installOperator(operatorArgument string, options *Options, fs afero.Fs, settings *env.Settings) error {
 
-	repository, err := repo.ClientFromSettings(fs, settings.Home, options.RepoName)
+	repository, err := repo.ClientFromSettings(fs, settings.Home, settings.RepoName(options.RepoName))
 	if err != nil {
 		return errors.WithMessage(err, "could not build operator repository")
 	}
 	clog.V(4).Printf("repository used %s", repository)
 
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	client, err := kube.GetKubeClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return errors.Wrap(err, "creating kubernetes client")
+	}
+	if options.Verify {
+		if err := verifyCluster(client, settings); err != nil {
+			return err
+		}
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	clog.V(3).Printf("acquiring kudo client")
 	if err != nil {
 		clog.V(3).Printf("failed to acquire client")
 		return errors.Wrap(err, "creating kudo client")
 	}
 
+	if options.OnlyInstance {
+		return installInstanceOnly(operatorArgument, kc, options, settings)
+	}
+
 	clog.V(3).Printf("getting package crds")
-	crds, err := GetPackageCRDs(operatorArgument, options.PackageVersion, repository)
+	crds, err := resolver.GetCRDs(resolver.New(fs, repository), operatorArgument, options.PackageVersion)
 	if err != nil {
 		return errors.Wrapf(err, "failed to resolve package CRDs for operator: %s", operatorArgument)
 	}
 
-	return installCrds(crds, kc, options, settings)
+	return installCrds(crds, kc, ManagerVersion(client.KubeClient), options, settings)
+}
+
+// verifyCluster makes sure that the KUDO CRDs are installed and the user is allowed to create KUDO objects
+func verifyCluster(client *kube.Client, settings *env.Settings) error {
+	results := verify.Run(client, verify.Options{Namespace: settings.Namespace}, verify.CRDsInstalled, verify.RBACSufficient)
+	for _, r := range results {
+		clog.V(3).Printf("check %q passed: %v", r.Check, r.Passed())
+	}
+	return results.Err()
 }
 
-func installCrds(crds *packages.PackageCRDs, kc *kudo.Client, options *Options, settings *env.Settings) error {
+// ManagerVersion returns the version of the KUDO manager installed in the default namespace, or an empty string if it
+// is unknown
+func ManagerVersion(client kubernetes.Interface) string {
+	v, err := cmdInit.GetKUDOVersion(client, cmdInit.DefaultNamespace)
+	if err != nil {
+		clog.V(2).Printf("unable to determine the version of the KUDO manager: %v", err)
+		return ""
+	}
+	return v
+}
+
+func installCrds(crds *packages.PackageCRDs, kc *kudo.Client, kudoVersion string, options *Options, settings *env.Settings) error {
 	// PRE-INSTALLATION SETUP
 	operatorName := crds.Operator.ObjectMeta.Name
 	clog.V(3).Printf("operator name: %v", operatorName)
@@ -132,28 +161,32 @@ func installCrds(crds *packages.PackageCRDs, kc *kudo.Client, options *Options,
 		return err
 	}
 
-	if err := kc.ValidateServerForOperator(crds.Operator); err != nil {
+	if err := kc.ValidateServerForOperator(crds.Operator, kudoVersion); err != nil {
 		return err
 	}
 
-	// Operator part
-	// Check if Operator exists
-	if !kc.OperatorExistsInCluster(crds.Operator.ObjectMeta.Name, settings.Namespace) {
-		if err := installSingleOperatorToCluster(operatorName, settings.Namespace, crds.Operator, kc); err != nil {
-			return errors.Wrap(err, "installing single Operator")
-		}
+	catalogNamespace := options.catalogNamespace(settings)
+	if catalogNamespace != settings.Namespace {
+		crds.Instance.Spec.OperatorVersion.Namespace = catalogNamespace
+		clog.V(3).Printf("catalog namespace: %v", catalogNamespace)
 	}
 
+	// The Operator, OperatorVersion and Instance are installed as one batch, objects created by the batch are deleted
+	// again if a later one fails, e.g. because the Instance is rejected
+	batch := &kudo.Batch{}
+
+	// Operator part
+	// The Operator is created or updated with the metadata of the package, e.g. changed maintainers
+	batch.ApplyOperator(crds.Operator, catalogNamespace)
+
 	// OperatorVersion part
-	versionsInstalled, err := kc.OperatorVersionsInstalled(operatorName, settings.Namespace)
+	versionsInstalled, err := kc.OperatorVersionsInstalled(operatorName, catalogNamespace)
 	if err != nil {
 		return errors.Wrap(err, "retrieving existing operator versions")
 	}
 	if !VersionExists(versionsInstalled, operatorVersion) {
 		// this version does not exist in the cluster
-		if err := installSingleOperatorVersionToCluster(operatorName, settings.Namespace, kc, crds.OperatorVersion); err != nil {
-			return errors.Wrapf(err, "installing OperatorVersion CRD for operator: %s", operatorName)
-		}
+		batch.CreateOperatorVersion(crds.OperatorVersion, catalogNamespace)
 	}
 
 	// Instances part
@@ -161,10 +194,105 @@ func installCrds(crds *packages.PackageCRDs, kc *kudo.Client, options *Options,
 	// OperatorVersion objects are created to ensure Instances can be created.
 
 	// The user opted not to install the instance.
-	if options.SkipInstance {
-		return nil
+	if !options.SkipInstance {
+		if err := prepareInstance(operatorName, crds, kc, options, settings); err != nil {
+			return err
+		}
+		batch.CreateInstance(crds.Instance, settings.Namespace)
+	}
+	return executeInstall(batch, kc)
+}
+
+// installInstanceOnly creates an instance of an OperatorVersion that is already installed in the catalog namespace.
+// The package is not resolved and the Operator and OperatorVersion are left untouched, so that application teams can
+// consume operators published by a platform team.
+func installInstanceOnly(operatorName string, kc *kudo.Client, options *Options, settings *env.Settings) error {
+	catalogNamespace := options.catalogNamespace(settings)
+	ov, err := installedOperatorVersion(operatorName, options.PackageVersion, catalogNamespace, kc)
+	if err != nil {
+		return err
+	}
+
+	crds := &packages.PackageCRDs{OperatorVersion: ov, Instance: packages.NewInstance(operatorName, ov.Spec.Version)}
+	applyInstanceOverrides(crds.Instance, options)
+	if err := validateCrds(crds, false); err != nil {
+		return err
+	}
+	if catalogNamespace != settings.Namespace {
+		crds.Instance.Spec.OperatorVersion.Namespace = catalogNamespace
+	}
+	return installInstance(operatorName, crds, kc, options, settings)
+}
+
+// installedOperatorVersion returns the OperatorVersion of the operator installed in the namespace matching the
+// version or version constraint. The most recent installed version is returned if no version is given.
+func installedOperatorVersion(operatorName, version, namespace string, kc *kudo.Client) (*v1alpha1.OperatorVersion, error) {
+	installed, err := kc.OperatorVersionsInstalled(operatorName, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving existing operator versions")
 	}
 
+	var constraint *semver.Constraints
+	if version != "" && !VersionExists(installed, version) {
+		if constraint, err = repo.ParseConstraint(version); err != nil {
+			return nil, fmt.Errorf("invalid version or version constraint %s: %v", version, err)
+		}
+	}
+	var versions semver.Collection
+	for _, v := range installed {
+		sv, err := semver.NewVersion(v)
+		if err != nil {
+			clog.V(2).Printf("ignoring operatorversion %s of operator %s: %v", v, operatorName, err)
+			continue
+		}
+		versions = append(versions, sv)
+	}
+	sort.Sort(sort.Reverse(versions))
+
+	selected := ""
+	switch {
+	case version != "" && constraint == nil:
+		selected = version
+	case constraint != nil:
+		for _, v := range versions {
+			if constraint.Check(v) {
+				selected = v.Original()
+				break
+			}
+		}
+	case len(versions) > 0:
+		selected = versions[0].Original()
+	}
+	if selected == "" {
+		if version == "" {
+			return nil, fmt.Errorf("no operatorversion of operator %s is installed in namespace %s, install it first without --only-instance", operatorName, namespace)
+		}
+		return nil, fmt.Errorf("no operatorversion of operator %s matching %s is installed in namespace %s", operatorName, version, namespace)
+	}
+
+	ov, err := kc.GetOperatorVersion(fmt.Sprintf("%s-%s", operatorName, selected), namespace)
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting operatorversion %s-%s", operatorName, selected)
+	}
+	if ov == nil {
+		return nil, fmt.Errorf("operatorversion %s-%s is not installed in namespace %s", operatorName, selected, namespace)
+	}
+	return ov, nil
+}
+
+// installInstance creates the instance of the package unless an instance of the same name exists
+func installInstance(operatorName string, crds *packages.PackageCRDs, kc *kudo.Client, options *Options, settings *env.Settings) error {
+	if err := prepareInstance(operatorName, crds, kc, options, settings); err != nil {
+		return err
+	}
+	batch := &kudo.Batch{}
+	batch.CreateInstance(crds.Instance, settings.Namespace)
+	return executeInstall(batch, kc)
+}
+
+// prepareInstance verifies that no instance of the same name exists and encrypts the sensitive parameters of the
+// instance of the package
+func prepareInstance(operatorName string, crds *packages.PackageCRDs, kc *kudo.Client, options *Options, settings *env.Settings) error {
 	// Check if Instance exists in cluster
 	// It won't create the Instance if any in combination with given Operator Name, OperatorVersion and Instance OperatorName exists
 	instanceName := crds.Instance.ObjectMeta.Name
@@ -172,17 +300,17 @@ func installCrds(crds *packages.PackageCRDs, kc *kudo.Client, options *Options,
 	if err != nil {
 		return errors.Wrapf(err, "verifying the instance does not already exist")
 	}
-
-	if !instanceExists {
-		if err := installSingleInstanceToCluster(operatorName, crds.Instance, kc, options, settings); err != nil {
-			return errors.Wrap(err, "installing single instance")
-
-		}
-
-	} else {
+	if instanceExists {
 		return clog.Errorf("can not install instance '%s' of operator '%s-%s' because instance of that name already exists in namespace %s",
 			instanceName, operatorName, crds.OperatorVersion.Spec.Version, settings.Namespace)
 	}
+
+	// the parameters are encrypted after their validation, the manager decrypts them before rendering templates
+	parameters, err := EncryptSensitiveParameters(crds.OperatorVersion, crds.Instance.Spec.Parameters, options.Keyring)
+	if err != nil {
+		return err
+	}
+	crds.Instance.Spec.Parameters = parameters
 	return nil
 }
 
@@ -192,20 +320,27 @@ func validateCrds(crds *packages.PackageCRDs, skipInstance bool) error {
 		clog.V(3).Printf("skipping instance...")
 		return nil
 	}
-	parameters := crds.OperatorVersion.Spec.Parameters
-	missingParameters := []string{}
-	for _, p := range parameters {
-		if p.Required && p.Default == nil {
-			_, ok := crds.Instance.Spec.Parameters[p.Name]
-			if !ok {
-				missingParameters = append(missingParameters, p.Name)
-			}
-		}
+	// the values of deprecated parameters are moved to the parameters replacing them
+	parameters, warnings, err := v1alpha1.TranslateDeprecatedParameters(crds.OperatorVersion, crds.Instance.Spec.Parameters)
+	for _, w := range warnings {
+		clog.Printf("WARNING: %s", w)
 	}
-
+	if err != nil {
+		return clog.Errorf("invalid parameters during installation: %v", err)
+	}
+	if len(warnings) > 0 {
+		crds.Instance.Spec.Parameters = parameters
+	}
+	_, missingParameters := v1alpha1.EffectiveParameters(crds.OperatorVersion, crds.Instance.Spec.Parameters)
 	if len(missingParameters) > 0 {
 		return clog.Errorf("missing required parameters during installation: %s", strings.Join(missingParameters, ","))
 	}
+	if err := v1alpha1.ValidateParameters(crds.OperatorVersion, crds.Instance.Spec.Parameters); err != nil {
+		return clog.Errorf("invalid parameters during installation: %v", err)
+	}
+	if _, err := crds.Instance.EffectivePatches(crds.OperatorVersion); err != nil {
+		return clog.Errorf("invalid overlay during installation: %v", err)
+	}
 	return nil
 }
 
@@ -219,34 +354,29 @@ func VersionExists(versions []string, currentVersion string) bool {
 	return false
 }
 
-// installSingleOperatorToCluster installs a given Operator to the cluster
-// TODO: needs testing
-func installSingleOperatorToCluster(name, namespace string, o *v1alpha1.Operator, kc *kudo.Client) error {
-	if _, err := kc.InstallOperatorObjToCluster(o, namespace); err != nil {
-		return errors.Wrapf(err, "installing %s-operator.yaml", name)
+// executeInstall executes the batch of an installation and prints the objects it created or changed. Objects created
+// before a failing one are deleted again and reported in the error.
+func executeInstall(batch *kudo.Batch, kc *kudo.Client) error {
+	results, err := kc.Execute(batch)
+	if err != nil {
+		return errors.Wrap(err, "installing the operator")
 	}
-	clog.Printf("operator.%s/%s created", o.APIVersion, o.Name)
-	return nil
-}
-
-// installSingleOperatorVersionToCluster installs a given OperatorVersion to the cluster
-// TODO: needs testing
-func installSingleOperatorVersionToCluster(name, namespace string, kc *kudo.Client, ov *v1alpha1.OperatorVersion) error {
-	if _, err := kc.InstallOperatorVersionObjToCluster(ov, namespace); err != nil {
-		return errors.Wrapf(err, "installing %s-operatorversion.yaml", name)
+	for _, r := range results {
+		if r.Kind == "Instance" {
+			clog.Resultf(r.Name, "instance.%s/%s %s", v1alpha1.SchemeGroupVersion, r.Name, r.Result)
+			continue
+		}
+		clog.Printf("%s.%s/%s %s", strings.ToLower(r.Kind), v1alpha1.SchemeGroupVersion, r.Name, r.Result)
 	}
-	clog.Printf("operatorversion.%s/%s created", ov.APIVersion, ov.Name)
 	return nil
 }
 
-// installSingleInstanceToCluster installs a given Instance to the cluster
-// TODO: needs more testing
-func installSingleInstanceToCluster(name string, instance *v1alpha1.Instance, kc *kudo.Client, options *Options, settings *env.Settings) error {
-	if _, err := kc.InstallInstanceObjToCluster(instance, settings.Namespace); err != nil {
-		return errors.Wrapf(err, "installing instance %s", name)
+// catalogNamespace returns the namespace the Operator and OperatorVersion are installed into
+func (o *Options) catalogNamespace(settings *env.Settings) string {
+	if o.CatalogNamespace != "" {
+		return o.CatalogNamespace
 	}
-	clog.Printf("instance.%s/%s created", instance.APIVersion, instance.Name)
-	return nil
+	return settings.Namespace
 }
 
 func applyInstanceOverrides(instance *v1alpha1.Instance, options *Options) {
@@ -258,4 +388,26 @@ func applyInstanceOverrides(instance *v1alpha1.Instance, options *Options) {
 		instance.Spec.Parameters = options.Parameters
 		clog.V(3).Printf("parameters in use: %v", options.Parameters)
 	}
+	if options.ImageRegistry != "" {
+		metav1.SetMetaDataAnnotation(&instance.ObjectMeta, v1alpha1.ImageRegistryAnnotation, options.ImageRegistry)
+	}
+	if len(options.ImagePullSecrets) > 0 {
+		metav1.SetMetaDataAnnotation(&instance.ObjectMeta, v1alpha1.ImagePullSecretsAnnotation, strings.Join(options.ImagePullSecrets, ","))
+	}
+	if options.Overlay != "" {
+		instance.Spec.Overlay = options.Overlay
+		clog.V(3).Printf("overlay in use: %v", options.Overlay)
+	}
+	if len(options.Patches) > 0 {
+		instance.Spec.Patches = options.Patches
+	}
+	if options.Placement != nil {
+		instance.Spec.Placement = options.Placement
+	}
+	if options.PodMetadata != nil {
+		instance.Spec.PodMetadata = options.PodMetadata
+	}
+	if options.ServiceAccountName != "" {
+		instance.Spec.ServiceAccountName = options.ServiceAccountName
+	}
 }
@@ -1,6 +1,7 @@
 package install
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -11,10 +12,13 @@ import (
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/version"
 	fakediscovery "k8s.io/client-go/discovery/fake"
+	testcore "k8s.io/client-go/testing"
 )
 
 func TestValidate(t *testing.T) {
@@ -38,6 +42,11 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidate_InstanceModes(t *testing.T) {
+	err := validate([]string{"test"}, &Options{SkipInstance: true, OnlyInstance: true})
+	assert.EqualError(t, err, "--skip-instance and --only-instance can not be used together")
+}
+
 func TestParameterValidation_InstallCrds(t *testing.T) {
 	crds := packages.PackageCRDs{
 		Operator: &v1alpha1.Operator{
@@ -122,9 +131,144 @@ func TestParameterValidation_InstallCrds(t *testing.T) {
 		options.Parameters = tt.installParameters
 		options.SkipInstance = tt.skipInstance
 
-		err := installCrds(&testCrds, kc, options, env.DefaultSettings)
+		err := installCrds(&testCrds, kc, "", options, env.DefaultSettings)
 		if err != nil && err.Error() != tt.err {
 			t.Errorf("%s: Expected error '%s', got '%s'", tt.name, tt.err, err.Error())
 		}
 	}
 }
+
+func TestInstallCrds_CatalogNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	kc := kudo.NewClientFromK8s(client)
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.16.0"}
+
+	crds := &packages.PackageCRDs{
+		Operator: &v1alpha1.Operator{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       v1alpha1.OperatorSpec{KubernetesVersion: "1.15"},
+		},
+		OperatorVersion: &v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"},
+			Spec:       v1alpha1.OperatorVersionSpec{Version: "1.0"},
+		},
+		Instance: &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "test-1.0"}},
+		},
+	}
+	settings := *env.DefaultSettings
+	settings.Namespace = "team-a"
+
+	err := installCrds(crds, kc, "", &Options{CatalogNamespace: "catalog"}, &settings)
+	assert.NoError(t, err)
+
+	assert.True(t, kc.OperatorExistsInCluster("test", "catalog"))
+	ov, err := kc.GetOperatorVersion("test-1.0", "catalog")
+	assert.NoError(t, err)
+	assert.NotNil(t, ov)
+
+	instance, err := kc.GetInstance("test", "team-a")
+	assert.NoError(t, err)
+	assert.NotNil(t, instance)
+	assert.Equal(t, "catalog", instance.OperatorVersionNamespace())
+}
+
+func TestInstallCrds_Rollback(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.16.0"}
+	client.PrependReactor("create", "instances", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("denied by webhook")
+	})
+	kc := kudo.NewClientFromK8s(client)
+
+	crds := &packages.PackageCRDs{
+		Operator:        &v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "test"}, Spec: v1alpha1.OperatorSpec{KubernetesVersion: "1.15"}},
+		OperatorVersion: &v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"}, Spec: v1alpha1.OperatorVersionSpec{Version: "1.0"}},
+		Instance: &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "test-1.0"}},
+		},
+	}
+
+	err := installCrds(crds, kc, "", &Options{}, env.DefaultSettings)
+	assert.EqualError(t, err, "installing the operator: instance default/test failed: installing Instance: denied by webhook, rolled back operatorversion default/test-1.0, operator default/test")
+	assert.False(t, kc.OperatorExistsInCluster("test", "default"))
+	ov, err := kc.GetOperatorVersion("test-1.0", "default")
+	assert.NoError(t, err)
+	assert.Nil(t, ov)
+}
+
+func TestInstallInstanceOnly(t *testing.T) {
+	operatorVersion := func(version string) *v1alpha1.OperatorVersion {
+		return &v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-" + version, Namespace: "catalog"},
+			Spec: v1alpha1.OperatorVersionSpec{
+				Version:    version,
+				Parameters: []v1alpha1.Parameter{{Name: "PASSWORD", Required: true}},
+			},
+		}
+	}
+	settings := *env.DefaultSettings
+	settings.Namespace = "team-a"
+
+	tests := []struct {
+		name            string
+		version         string
+		parameters      map[string]string
+		operatorVersion string
+		err             string
+	}{
+		{name: "most recent version", parameters: map[string]string{"PASSWORD": "secret"}, operatorVersion: "test-2.0.0"},
+		{name: "exact version", version: "1.0.0", parameters: map[string]string{"PASSWORD": "secret"}, operatorVersion: "test-1.0.0"},
+		{name: "version constraint", version: "<2", parameters: map[string]string{"PASSWORD": "secret"}, operatorVersion: "test-1.1.0"},
+		{name: "version not installed", version: "3.0.0", err: "no operatorversion of operator test matching 3.0.0 is installed in namespace catalog"},
+		{name: "no matching version", version: ">2", err: "no operatorversion of operator test matching >2 is installed in namespace catalog"},
+		{name: "missing parameter", err: "missing required parameters during installation: PASSWORD"},
+	}
+
+	for _, tt := range tests {
+		kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(operatorVersion("1.0.0"), operatorVersion("1.1.0"), operatorVersion("2.0.0")))
+		options := &Options{InstanceName: "test", PackageVersion: tt.version, Parameters: tt.parameters, CatalogNamespace: "catalog", OnlyInstance: true}
+
+		err := installInstanceOnly("test", kc, options, &settings)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.name)
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		instance, err := kc.GetInstance("test", "team-a")
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.operatorVersion, instance.Spec.OperatorVersion.Name, tt.name)
+		assert.Equal(t, "catalog", instance.OperatorVersionNamespace(), tt.name)
+		assert.False(t, kc.OperatorExistsInCluster("test", "catalog"), tt.name)
+	}
+}
+
+func TestApplyInstanceOverrides_Images(t *testing.T) {
+	instance := packages.NewInstance("kafka", "1.0.0")
+	applyInstanceOverrides(instance, &Options{ImageRegistry: "registry.example.com", ImagePullSecrets: []string{"mirror", "backup"}})
+	assert.Equal(t, "registry.example.com", instance.Annotations[v1alpha1.ImageRegistryAnnotation])
+	assert.Equal(t, "mirror,backup", instance.Annotations[v1alpha1.ImagePullSecretsAnnotation])
+
+	instance = packages.NewInstance("kafka", "1.0.0")
+	applyInstanceOverrides(instance, &Options{})
+	assert.Empty(t, instance.Annotations, "the KudoConfig applies without flags")
+}
+
+func TestValidateCrds_Overlay(t *testing.T) {
+	patch := v1alpha1.Patch{StrategicMerge: "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: kafka\nspec:\n  replicas: 5\n"}
+	crds := &packages.PackageCRDs{
+		OperatorVersion: &v1alpha1.OperatorVersion{Spec: v1alpha1.OperatorVersionSpec{Overlays: map[string][]v1alpha1.Patch{"production": {patch}}}},
+		Instance:        packages.NewInstance("kafka", "1.0.0"),
+	}
+	crds.OperatorVersion.Name = "kafka-1.0.0"
+
+	applyInstanceOverrides(crds.Instance, &Options{Overlay: "production", Patches: []v1alpha1.Patch{patch}})
+	assert.Equal(t, "production", crds.Instance.Spec.Overlay)
+	assert.Equal(t, []v1alpha1.Patch{patch}, crds.Instance.Spec.Patches)
+	assert.NoError(t, validateCrds(crds, false))
+
+	crds.Instance.Spec.Overlay = "staging"
+	assert.EqualError(t, validateCrds(crds, false), "invalid overlay during installation: overlay staging is not defined by operatorversion kafka-1.0.0")
+}
@@ -1,9 +1,15 @@
 package install
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
+
+	"github.com/spf13/afero"
+	yamlv2 "gopkg.in/yaml.v2"
+	"sigs.k8s.io/yaml"
 )
 
 // GetParameterMap takes a slice of parameter strings, parses parameters into a map of keys and values
@@ -46,3 +52,134 @@ func parseParameter(raw string) (key string, param string, err *string) {
 	}
 	return s[0], s[1], nil
 }
+
+// GetParameters merges the parameter files in the given order and applies the raw `key=value` parameters on top.
+// Parameters given on the command line always take precedence over parameter files.
+func GetParameters(fs afero.Fs, files []string, raw []string) (map[string]string, error) {
+	parameters, err := GetParameterMapFromFiles(fs, files)
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := GetParameterMap(raw)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range overrides {
+		parameters[k] = v
+	}
+	return parameters, nil
+}
+
+// GetParameterMapFromFiles reads YAML parameter files and merges them into a single map of parameters.
+//
+// Files are merged in the given order, so a later file overlays the earlier ones:
+//   - scalar values and lists of a later file replace the values of earlier files
+//   - maps are merged recursively, keys that are only defined in earlier files are kept
+//   - a null value removes the parameter from the merged result
+//
+// As parameters are plain strings, scalar values are passed with the text they have in the file and structured
+// values (maps and lists) are passed as YAML.
+func GetParameterMapFromFiles(fs afero.Fs, files []string) (map[string]string, error) {
+	merged := map[string]interface{}{}
+	for _, f := range files {
+		b, err := afero.ReadFile(fs, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read parameter file %s: %w", f, err)
+		}
+		values, err := UnmarshalParameterValues(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse parameter file %s: %w", f, err)
+		}
+		merged = mergeValues(merged, values)
+	}
+
+	var errs []string
+	parameters := make(map[string]string, len(merged))
+	for k, v := range merged {
+		switch value := v.(type) {
+		case string:
+			parameters[k] = value
+		case map[string]interface{}, []interface{}:
+			b, err := yaml.Marshal(value)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("parameter %s can not be converted to YAML: %v", k, err))
+				continue
+			}
+			parameters[k] = strings.TrimSuffix(string(b), "\n")
+		default:
+			parameters[k] = fmt.Sprint(value)
+		}
+	}
+	if errs != nil {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+	return parameters, nil
+}
+
+// UnmarshalParameterValues parses a YAML map of parameter values. Scalar values are returned as strings with the text
+// they have in the YAML, so that e.g. 1000000 is not turned into 1e+06. Structured values keep their structure and
+// null values are returned as nil.
+func UnmarshalParameterValues(b []byte) (map[string]interface{}, error) {
+	var values map[string]interface{}
+	useNumber := func(d *json.Decoder) *json.Decoder {
+		d.UseNumber()
+		return d
+	}
+	if err := yaml.Unmarshal(b, &values, useNumber); err != nil {
+		return nil, err
+	}
+	var scalars map[string]scalarText
+	if err := yamlv2.Unmarshal(b, &scalars); err != nil {
+		return nil, err
+	}
+	for k, s := range scalars {
+		if s.isScalar {
+			values[k] = s.text
+		}
+	}
+	return values, nil
+}
+
+// scalarText holds the text of a scalar YAML value as it is written, it is empty for null and structured values
+type scalarText struct {
+	text     string
+	isScalar bool
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler
+func (s *scalarText) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	switch value.(type) {
+	case nil, map[interface{}]interface{}, []interface{}:
+		return nil
+	}
+	s.isScalar = true
+	return unmarshal(&s.text)
+}
+
+// mergeValues deep merges overlay into base and returns the result. Maps are merged recursively, all other values
+// of overlay replace the values of base and a nil value removes the key.
+func mergeValues(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base)+len(overlay))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		baseMap, baseIsMap := merged[k].(map[string]interface{})
+		overlayMap, overlayIsMap := v.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			merged[k] = mergeValues(baseMap, overlayMap)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
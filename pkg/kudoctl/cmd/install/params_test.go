@@ -3,6 +3,7 @@ package install
 import (
 	"testing"
 
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,3 +29,93 @@ func TestTableParameterParsing(t *testing.T) {
 		}
 	}
 }
+
+func TestGetParameters(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"base.yaml": `
+BROKER_COUNT: 3
+LOG_LEVEL: INFO
+TLS: false
+RETENTION_BYTES: 1000000
+HEAP_RATIO: 0.50
+ZOOKEEPER:
+  url: zk:2181
+  timeout: 10
+  maxBytes: 1000000
+`,
+		"prod.yaml": `
+BROKER_COUNT: 5
+TLS: true
+LOG_LEVEL: null
+ZOOKEEPER:
+  timeout: 30
+`,
+		"invalid.yaml": `- foo`,
+	}
+	for name, content := range files {
+		assert.NoError(t, afero.WriteFile(fs, name, []byte(content), 0644))
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		raw      []string
+		expected map[string]string
+		err      string
+	}{
+		{name: "no files", raw: []string{"foo=bar"}, expected: map[string]string{"foo": "bar"}},
+		{
+			name:  "single file",
+			files: []string{"base.yaml"},
+			expected: map[string]string{
+				"BROKER_COUNT":    "3",
+				"LOG_LEVEL":       "INFO",
+				"TLS":             "false",
+				"RETENTION_BYTES": "1000000",
+				"HEAP_RATIO":      "0.50",
+				"ZOOKEEPER":       "maxBytes: 1000000\ntimeout: 10\nurl: zk:2181",
+			},
+		},
+		{
+			name:  "later files overlay earlier ones",
+			files: []string{"base.yaml", "prod.yaml"},
+			expected: map[string]string{
+				"BROKER_COUNT":    "5",
+				"TLS":             "true",
+				"RETENTION_BYTES": "1000000",
+				"HEAP_RATIO":      "0.50",
+				"ZOOKEEPER":       "maxBytes: 1000000\ntimeout: 30\nurl: zk:2181",
+			},
+		},
+		{
+			name:  "parameters take precedence over files",
+			files: []string{"base.yaml", "prod.yaml"},
+			raw:   []string{"BROKER_COUNT=7", "LOG_LEVEL=DEBUG"},
+			expected: map[string]string{
+				"BROKER_COUNT":    "7",
+				"LOG_LEVEL":       "DEBUG",
+				"TLS":             "true",
+				"RETENTION_BYTES": "1000000",
+				"HEAP_RATIO":      "0.50",
+				"ZOOKEEPER":       "maxBytes: 1000000\ntimeout: 30\nurl: zk:2181",
+			},
+		},
+		{name: "missing file", files: []string{"missing.yaml"}, err: "failed to read parameter file missing.yaml"},
+		{name: "invalid file", files: []string{"invalid.yaml"}, err: "failed to parse parameter file invalid.yaml"},
+		{name: "invalid parameter", files: []string{"base.yaml"}, raw: []string{"foo"}, err: "parameter not set: foo"},
+	}
+
+	for _, tt := range tests {
+		params, err := GetParameters(fs, tt.files, tt.raw)
+		if tt.err != "" {
+			assert.Error(t, err, tt.name)
+			if err != nil {
+				assert.Contains(t, err.Error(), tt.err, tt.name)
+			}
+			continue
+		}
+		assert.NoError(t, err, tt.name)
+		assert.Equal(t, tt.expected, params, tt.name)
+	}
+}
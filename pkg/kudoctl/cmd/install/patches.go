@@ -0,0 +1,26 @@
+package install
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/spf13/afero"
+)
+
+// GetPatches reads the YAML lists of patches of the files and returns them in the given order, see v1alpha1.Patch
+func GetPatches(fs afero.Fs, files []string) ([]v1alpha1.Patch, error) {
+	var patches []v1alpha1.Patch
+	for _, f := range files {
+		b, err := afero.ReadFile(fs, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read patch file %s: %w", f, err)
+		}
+		filePatches, err := v1alpha1.ParsePatches(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse patch file %s: %w", f, err)
+		}
+		patches = append(patches, filePatches...)
+	}
+	return patches, nil
+}
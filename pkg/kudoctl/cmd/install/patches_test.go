@@ -0,0 +1,27 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPatches(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "annotations.yaml", []byte(`- target: {version: v1, kind: Service, name: kafka}
+  json6902: |
+    - op: add
+      path: /metadata/annotations/team
+      value: data
+`), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "invalid.yaml", []byte("- strategicMerge: |\n    kind: Service\n"), 0644))
+
+	patches, err := GetPatches(fs, []string{"annotations.yaml"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(patches))
+	assert.Equal(t, "Service", patches[0].Target.Kind)
+
+	_, err = GetPatches(fs, []string{"annotations.yaml", "invalid.yaml"})
+	assert.EqualError(t, err, "failed to parse patch file invalid.yaml: patch 1: strategic merge patch has to define the apiVersion, kind and metadata.name of the object")
+}
@@ -0,0 +1,72 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// GetPlacement parses the `key=value` node selector entries, the `key[=value][:effect]` tolerations and the YAML
+// or JSON affinity of the command line into the placement of an instance, see v1alpha1.Placement. It returns nil if
+// the placement is not constrained.
+func GetPlacement(nodeSelector []string, tolerations []string, affinity string) (*v1alpha1.Placement, error) {
+	placement := &v1alpha1.Placement{}
+
+	if len(nodeSelector) > 0 {
+		selector, err := GetParameterMap(nodeSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node selector: %v", err)
+		}
+		placement.NodeSelector = selector
+	}
+
+	for _, raw := range tolerations {
+		t, err := parseToleration(raw)
+		if err != nil {
+			return nil, err
+		}
+		placement.Tolerations = append(placement.Tolerations, t)
+	}
+
+	if affinity != "" {
+		placement.Affinity = &corev1.Affinity{}
+		if err := yaml.UnmarshalStrict([]byte(affinity), placement.Affinity); err != nil {
+			return nil, fmt.Errorf("invalid affinity: %v", err)
+		}
+	}
+
+	if placement.IsEmpty() {
+		return nil, nil
+	}
+	return placement, nil
+}
+
+// parseToleration parses a toleration in the format of a taint of `kubectl taint`, i.e. `key[=value][:effect]`. A
+// toleration without value tolerates all values of the key, one without effect tolerates all effects.
+func parseToleration(raw string) (corev1.Toleration, error) {
+	t := corev1.Toleration{Operator: corev1.TolerationOpExists}
+	spec := raw
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		t.Effect = corev1.TaintEffect(spec[i+1:])
+		spec = spec[:i]
+		switch t.Effect {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+		default:
+			return t, fmt.Errorf("invalid toleration %s: effect has to be %s, %s or %s", raw,
+				corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute)
+		}
+	}
+	if parts := strings.SplitN(spec, "=", 2); len(parts) == 2 {
+		t.Key, t.Value, t.Operator = parts[0], parts[1], corev1.TolerationOpEqual
+	} else {
+		t.Key = spec
+	}
+	if t.Key == "" {
+		return t, fmt.Errorf("invalid toleration %s: key can not be empty", raw)
+	}
+	return t, nil
+}
@@ -0,0 +1,45 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetPlacement(t *testing.T) {
+	placement, err := GetPlacement(nil, nil, "")
+	assert.NoError(t, err)
+	assert.Nil(t, placement)
+
+	placement, err = GetPlacement(
+		[]string{"pool=databases", "disk=ssd"},
+		[]string{"dedicated=databases:NoSchedule", "spot", "maintenance:NoExecute"},
+		`{"nodeAffinity": {"requiredDuringSchedulingIgnoredDuringExecution": {"nodeSelectorTerms": [{"matchExpressions": [{"key": "zone", "operator": "In", "values": ["a"]}]}]}}}`,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, &v1alpha1.Placement{
+		NodeSelector: map[string]string{"pool": "databases", "disk": "ssd"},
+		Tolerations: []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "databases", Effect: corev1.TaintEffectNoSchedule},
+			{Key: "spot", Operator: corev1.TolerationOpExists},
+			{Key: "maintenance", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+		},
+		Affinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: []corev1.NodeSelectorRequirement{
+				{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+			}}},
+		}}},
+	}, placement)
+
+	_, err = GetPlacement([]string{"pool"}, nil, "")
+	assert.EqualError(t, err, "invalid node selector: parameter not set: pool")
+	_, err = GetPlacement(nil, []string{"dedicated=databases:NoRun"}, "")
+	assert.EqualError(t, err, "invalid toleration dedicated=databases:NoRun: effect has to be NoSchedule, PreferNoSchedule or NoExecute")
+	_, err = GetPlacement(nil, []string{"=databases"}, "")
+	assert.EqualError(t, err, "invalid toleration =databases: key can not be empty")
+	_, err = GetPlacement(nil, nil, "nodeAfinity: {}")
+	assert.Error(t, err)
+}
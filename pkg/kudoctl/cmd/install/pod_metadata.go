@@ -0,0 +1,48 @@
+package install
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GetPodMetadata parses the `key=value` labels and annotations of the command line into the pod metadata of an
+// instance, see v1alpha1.PodMetadata. It returns nil if no labels or annotations are given.
+func GetPodMetadata(labels []string, annotations []string) (*v1alpha1.PodMetadata, error) {
+	podMetadata := &v1alpha1.PodMetadata{}
+
+	if len(labels) > 0 {
+		parsed, err := GetParameterMap(labels)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod label: %v", err)
+		}
+		for k, v := range parsed {
+			errs := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(v)...)
+			if len(errs) > 0 {
+				return nil, fmt.Errorf("invalid pod label %s=%s: %s", k, v, strings.Join(errs, ", "))
+			}
+		}
+		podMetadata.Labels = parsed
+	}
+
+	if len(annotations) > 0 {
+		parsed, err := GetParameterMap(annotations)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pod annotation: %v", err)
+		}
+		for k := range parsed {
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid pod annotation %s: %s", k, strings.Join(errs, ", "))
+			}
+		}
+		podMetadata.Annotations = parsed
+	}
+
+	if podMetadata.IsEmpty() {
+		return nil, nil
+	}
+	return podMetadata, nil
+}
@@ -0,0 +1,29 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPodMetadata(t *testing.T) {
+	podMetadata, err := GetPodMetadata(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, podMetadata)
+
+	podMetadata, err = GetPodMetadata([]string{"team=data"}, []string{"prometheus.io/scrape=true", "sidecar.istio.io/inject=false"})
+	assert.NoError(t, err)
+	assert.Equal(t, &v1alpha1.PodMetadata{
+		Labels:      map[string]string{"team": "data"},
+		Annotations: map[string]string{"prometheus.io/scrape": "true", "sidecar.istio.io/inject": "false"},
+	}, podMetadata)
+
+	_, err = GetPodMetadata([]string{"team"}, nil)
+	assert.EqualError(t, err, "invalid pod label: parameter not set: team")
+	_, err = GetPodMetadata([]string{"team=data science"}, nil)
+	assert.Error(t, err)
+	_, err = GetPodMetadata(nil, []string{"-scrape=true"})
+	assert.Error(t, err)
+}
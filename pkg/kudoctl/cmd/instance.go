@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/instance"
+
+	"github.com/spf13/cobra"
+)
+
+const instanceHistoryExample = `  # Show who changed the instance dev-flink, when and how the triggered plans ended
+  kubectl kudo instance history dev-flink
+`
+
+const instanceEventsExample = `  # Show the events of the instance dev-flink, its operatorversion and its objects since its last plan started
+  kubectl kudo instance events dev-flink
+
+  # Show the events of all plan executions that are still kept by the cluster
+  kubectl kudo instance events dev-flink --all
+`
+
+const instanceDriftExample = `  # Show the fields of the objects of the instance dev-flink that were changed out-of-band
+  kubectl kudo instance drift dev-flink
+`
+
+// newInstanceCmd creates a new command that allows to inspect instances
+func newInstanceCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "instance",
+		Short: "View instance details.",
+		Long:  `The instance command has subcommands to view details of an instance.`,
+	}
+
+	newCmd.AddCommand(NewInstanceHistoryCmd())
+	newCmd.AddCommand(NewInstanceDriftCmd())
+	newCmd.AddCommand(NewInstanceEventsCmd())
+
+	return newCmd
+}
+
+// NewInstanceHistoryCmd creates a command that shows the recorded changes of an instance
+func NewInstanceHistoryCmd() *cobra.Command {
+	historyCmd := &cobra.Command{
+		Use:   "history <instance>",
+		Short: "Show the change history of an instance.",
+		Long: `Show the changes made to an instance with kudoctl: when and by which kubeconfig user a change was made, the
+kudoctl version, the changed parameters and the outcome of the plan the change triggered. Only the last 20 changes
+are kept, changes made without kudoctl (e.g. kubectl edit) are not recorded.`,
+		Example: instanceHistoryExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return instance.RunHistory(cmd.OutOrStdout(), args, &Settings)
+		},
+	}
+
+	return historyCmd
+}
+
+// NewInstanceDriftCmd creates a command that shows the drift of the objects of an instance
+func NewInstanceDriftCmd() *cobra.Command {
+	driftCmd := &cobra.Command{
+		Use:   "drift <instance>",
+		Short: "Show the objects of an instance that were changed out-of-band.",
+		Long: `Show the result of the last drift detection of an instance: the objects applied by its last plan that were deleted
+or whose fields were changed without KUDO, e.g. with kubectl edit, with the applied and the live value of each changed
+field. Drift is detected periodically by the manager if it runs with the --drift-detection-interval flag, with the
+--drift-auto-correct flag drifted objects are applied again.`,
+		Example: instanceDriftExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return instance.RunDrift(cmd.OutOrStdout(), args, &Settings)
+		},
+	}
+
+	return driftCmd
+}
+
+// NewInstanceEventsCmd creates a command that shows the events of an instance and its objects
+func NewInstanceEventsCmd() *cobra.Command {
+	options := instance.DefaultEventsOptions
+	eventsCmd := &cobra.Command{
+		Use:   "events <instance>",
+		Short: "Show the events of an instance, its operatorversion and its objects.",
+		Long: `Show the Kubernetes events of an instance, its operatorversion and the pods, services, volume claims, deployments,
+replica sets, stateful sets, daemon sets and jobs labeled with the instance, ordered by time. Only events since the
+last plan execution started are shown, unless --all is set. The cluster keeps events for a limited time only, one hour
+by default.`,
+		Example: instanceEventsExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return instance.RunEvents(cmd.OutOrStdout(), args, options, &Settings)
+		},
+	}
+
+	eventsCmd.Flags().BoolVar(&options.All, "all", false, "Show the events of all plan executions instead of only the last one.")
+	return eventsCmd
+}
@@ -0,0 +1,67 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// RunDrift runs the instance drift command
+func RunDrift(out io.Writer, args []string, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return pkgerrors.Wrap(err, "creating kudo client")
+	}
+
+	return drift(out, kc, args[0], settings.Namespace)
+}
+
+func drift(out io.Writer, kc *kudo.Client, instanceName, namespace string) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	d := instance.Status.Drift
+	if d == nil {
+		fmt.Fprintf(out, "No drift detection recorded for instance %s, it is enabled with the --drift-detection-interval flag of the manager.\n", instanceName)
+		return nil
+	}
+	fmt.Fprintf(out, "Objects of plan %s checked at %s.\n", d.Plan, d.CheckedAt.Format(timeLayout))
+	if !d.Drifted() {
+		fmt.Fprintln(out, "No drift detected.")
+		return nil
+	}
+
+	for _, r := range d.Resources {
+		fmt.Fprintf(out, "%s %s/%s %s\n", r.Kind, r.Namespace, r.Name, resourceDriftSummary(r))
+		for _, f := range r.Fields {
+			fmt.Fprintf(out, "    %s: %s -> %s\n", f.Path, valueOrDash(f.Expected), valueOrDash(f.Actual))
+		}
+	}
+	return nil
+}
+
+func resourceDriftSummary(r v1alpha1.ResourceDrift) string {
+	s := "changed (applied -> live)"
+	if r.Missing {
+		s = "deleted"
+	}
+	if r.CorrectedAt != nil {
+		s = fmt.Sprintf("%s, corrected at %s", s, r.CorrectedAt.Format(timeLayout))
+	}
+	return s
+}
@@ -0,0 +1,64 @@
+package instance
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrift(t *testing.T) {
+	checked := metav1.NewTime(time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC))
+	corrected := metav1.NewTime(time.Date(2019, 12, 1, 10, 0, 1, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		drift    *v1alpha1.DriftStatus
+		expected string
+	}{
+		{
+			name:     "drift detection disabled",
+			expected: "No drift detection recorded for instance test, it is enabled with the --drift-detection-interval flag of the manager.\n",
+		},
+		{
+			name:     "no drift",
+			drift:    &v1alpha1.DriftStatus{CheckedAt: checked, Plan: "deploy"},
+			expected: "Objects of plan deploy checked at 2019-12-01T10:00:00.\nNo drift detected.\n",
+		},
+		{
+			name: "drift",
+			drift: &v1alpha1.DriftStatus{CheckedAt: checked, Plan: "deploy", Resources: []v1alpha1.ResourceDrift{
+				{Kind: "Deployment", Namespace: "default", Name: "test-nginx", Fields: []v1alpha1.FieldDrift{
+					{Path: "spec.replicas", Expected: "3", Actual: "5"},
+					{Path: "spec.paused", Expected: "true"},
+				}},
+				{Kind: "Service", Namespace: "default", Name: "test-svc", Missing: true, CorrectedAt: &corrected},
+			}},
+			expected: `Objects of plan deploy checked at 2019-12-01T10:00:00.
+Deployment default/test-nginx changed (applied -> live)
+    spec.replicas: 3 -> 5
+    spec.paused: true -> -
+Service default/test-svc deleted, corrected at 2019-12-01T10:00:01
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+		instance.Status.Drift = tt.drift
+		kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance))
+
+		var out bytes.Buffer
+		if err := drift(&out, kc, "test", "default"); err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.name, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected output\n%s\nbut got\n%s", tt.name, tt.expected, out.String())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	kudoutil "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/gosuri/uitable"
+	pkgerrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// EventsOptions are the options of the instance events command
+type EventsOptions struct {
+	// All shows the events of all plan executions instead of only the last one
+	All bool
+}
+
+// DefaultEventsOptions provides the default options for instance events
+var DefaultEventsOptions = &EventsOptions{}
+
+// RunEvents runs the instance events command
+func RunEvents(out io.Writer, args []string, options *EventsOptions, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return pkgerrors.Wrap(err, "creating kudo client")
+	}
+	client, err := kube.GetKubeClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return pkgerrors.Wrap(err, "creating kubernetes client")
+	}
+
+	return events(out, kc, client.KubeClient, args[0], settings.Namespace, options)
+}
+
+func events(out io.Writer, kc *kudo.Client, client kubernetes.Interface, instanceName, namespace string, options *EventsOptions) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	// the events are matched with the objects by their UID, so events of deleted objects with the same name are not shown
+	objects := map[types.UID]bool{instance.UID: true}
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return pkgerrors.Wrapf(err, "getting operatorversion of instance %s", instanceName)
+	}
+	if ov != nil {
+		objects[ov.UID] = true
+	}
+	if err := addInstanceObjects(objects, client, instance); err != nil {
+		return pkgerrors.Wrapf(err, "listing objects of instance %s", instanceName)
+	}
+
+	namespaces := []string{namespace}
+	if ov != nil && ov.Namespace != namespace {
+		namespaces = append(namespaces, ov.Namespace)
+	}
+	var matched []corev1.Event
+	for _, ns := range namespaces {
+		list, err := client.CoreV1().Events(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return pkgerrors.Wrapf(err, "listing events in namespace %s", ns)
+		}
+		for _, e := range list.Items {
+			if objects[e.InvolvedObject.UID] {
+				matched = append(matched, e)
+			}
+		}
+	}
+
+	var since time.Time
+	plan := instance.GetLastExecutedPlanStatus()
+	if !options.All && plan != nil {
+		since = plan.StartedAt.Time
+		matched = eventsSince(matched, since)
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return eventTime(matched[i]).Before(eventTime(matched[j])) })
+
+	if len(matched) == 0 {
+		if since.IsZero() {
+			fmt.Fprintf(out, "No events found for instance %s.\n", instanceName)
+		} else {
+			fmt.Fprintf(out, "No events found for instance %s since plan %s started at %s.\n", instanceName, plan.Name, since.Format(timeLayout))
+		}
+		return nil
+	}
+
+	table := uitable.New()
+	table.Wrap = true
+	table.MaxColWidth = 80
+	table.AddRow("TIME", "TYPE", "REASON", "OBJECT", "COUNT", "MESSAGE")
+	for _, e := range matched {
+		count := e.Count
+		if count == 0 {
+			count = 1
+		}
+		table.AddRow(eventTime(e).Format(timeLayout), e.Type, e.Reason,
+			fmt.Sprintf("%s/%s", e.InvolvedObject.Kind, e.InvolvedObject.Name), count, e.Message)
+	}
+	fmt.Fprintln(out, table)
+	return nil
+}
+
+// addInstanceObjects adds the UIDs of the objects labeled with the instance. Only the kinds of objects that commonly
+// report events are looked up.
+func addInstanceObjects(objects map[types.UID]bool, client kubernetes.Interface, instance *v1alpha1.Instance) error {
+	ns := instance.Namespace
+	opts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", kudoutil.InstanceLabel, instance.Name)}
+	lists := []func() (runtime.Object, error){
+		func() (runtime.Object, error) { return client.CoreV1().Pods(ns).List(opts) },
+		func() (runtime.Object, error) { return client.CoreV1().Services(ns).List(opts) },
+		func() (runtime.Object, error) { return client.CoreV1().PersistentVolumeClaims(ns).List(opts) },
+		func() (runtime.Object, error) { return client.AppsV1().Deployments(ns).List(opts) },
+		func() (runtime.Object, error) { return client.AppsV1().ReplicaSets(ns).List(opts) },
+		func() (runtime.Object, error) { return client.AppsV1().StatefulSets(ns).List(opts) },
+		func() (runtime.Object, error) { return client.AppsV1().DaemonSets(ns).List(opts) },
+		func() (runtime.Object, error) { return client.BatchV1().Jobs(ns).List(opts) },
+	}
+
+	for _, list := range lists {
+		l, err := list()
+		if err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(l)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			o, err := meta.Accessor(item)
+			if err != nil {
+				return err
+			}
+			objects[o.GetUID()] = true
+		}
+	}
+	return nil
+}
+
+func eventsSince(events []corev1.Event, since time.Time) []corev1.Event {
+	var filtered []corev1.Event
+	for _, e := range events {
+		if !eventTime(e).Before(since) {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered
+}
+
+// eventTime returns the time the event last occurred
+func eventTime(e corev1.Event) time.Time {
+	switch {
+	case !e.LastTimestamp.IsZero():
+		return e.LastTimestamp.Time
+	case !e.EventTime.IsZero():
+		return e.EventTime.Time
+	default:
+		return e.FirstTimestamp.Time
+	}
+}
@@ -0,0 +1,88 @@
+package instance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	kudoutil "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEvents(t *testing.T) {
+	started := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default", UID: "instance-uid"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: corev1.ObjectReference{Name: "test-1.0"}},
+		Status: v1alpha1.InstanceStatus{PlanStatus: map[string]v1alpha1.PlanStatus{
+			"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete, StartedAt: metav1.NewTime(started), LastFinishedRun: metav1.NewTime(started.Add(time.Minute))},
+		}},
+	}
+	ov := &v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.0", Namespace: "default", UID: "ov-uid"}}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance, ov))
+
+	event := func(name, kind, object string, uid types.UID, at time.Time, reason string) *corev1.Event {
+		return &corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: name, Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: kind, Name: object, UID: uid},
+			Type:           "Normal",
+			Reason:         reason,
+			Message:        reason + " " + object,
+			LastTimestamp:  metav1.NewTime(at),
+		}
+	}
+	client := kubefake.NewSimpleClientset(
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "test-zk", Namespace: "default", UID: "sts-uid", Labels: map[string]string{kudoutil.InstanceLabel: "test"},
+		}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{
+			Name: "other-zk", Namespace: "default", UID: "other-uid", Labels: map[string]string{kudoutil.InstanceLabel: "other"},
+		}},
+		event("e1", "Instance", "test", "instance-uid", started.Add(-time.Hour), "PlanStarted"),
+		event("e2", "StatefulSet", "test-zk", "sts-uid", started.Add(30*time.Second), "SuccessfulCreate"),
+		event("e3", "Instance", "test", "instance-uid", started, "PlanStarted"),
+		event("e4", "StatefulSet", "other-zk", "other-uid", started.Add(10*time.Second), "SuccessfulCreate"),
+		event("e5", "OperatorVersion", "test-1.0", "ov-uid", started.Add(-2*time.Hour), "Created"),
+	)
+
+	tests := []struct {
+		name    string
+		all     bool
+		reasons []string
+	}{
+		{"last plan execution", false, []string{"PlanStarted test", "SuccessfulCreate test-zk"}},
+		{"all", true, []string{"Created test-1.0", "PlanStarted test", "PlanStarted test", "SuccessfulCreate test-zk"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := events(&out, kc, client, "test", "default", &EventsOptions{All: tt.all}); err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.name, err)
+		}
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != len(tt.reasons)+1 {
+			t.Fatalf("%s: expected %d events but got\n%s", tt.name, len(tt.reasons), out.String())
+		}
+		for i, reason := range tt.reasons {
+			if !strings.HasSuffix(strings.TrimSpace(lines[i+1]), reason) {
+				t.Errorf("%s: expected event %d to be %q but got %q", tt.name, i, reason, lines[i+1])
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	if err := events(&out, kc, kubefake.NewSimpleClientset(), "test", "default", DefaultEventsOptions); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "No events found for instance test since plan deploy started at 2019-12-01T10:00:00.\n"; out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
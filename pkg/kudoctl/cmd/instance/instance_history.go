@@ -0,0 +1,108 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/gosuri/uitable"
+	pkgerrors "github.com/pkg/errors"
+)
+
+const timeLayout = "2006-01-02T15:04:05"
+
+// RunHistory runs the instance history command
+func RunHistory(out io.Writer, args []string, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return pkgerrors.Wrap(err, "creating kudo client")
+	}
+
+	return history(out, kc, args[0], settings.Namespace)
+}
+
+func history(out io.Writer, kc *kudo.Client, instanceName, namespace string) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	revisions, err := instance.History()
+	if err != nil {
+		return pkgerrors.Wrapf(err, "reading history of instance %s", instanceName)
+	}
+	if len(revisions) == 0 {
+		fmt.Fprintf(out, "No history recorded for instance %s.\n", instanceName)
+		return nil
+	}
+
+	table := uitable.New()
+	table.Wrap = true
+	table.MaxColWidth = 60
+	table.AddRow("REVISION", "TIME", "USER", "CLIENT", "ACTION", "OPERATORVERSION", "PARAMETERS", "PLAN", "OUTCOME")
+	for _, r := range revisions {
+		table.AddRow(r.Revision, r.Time.Format(timeLayout), valueOrDash(r.User), valueOrDash(r.ClientVersion), action(r),
+			r.OperatorVersion, valueOrDash(parameterChanges(r.Parameters)), valueOrDash(r.Plan), valueOrDash(outcome(r)))
+	}
+	fmt.Fprintln(out, table)
+	return nil
+}
+
+func action(r v1alpha1.InstanceRevision) string {
+	if r.RolledBackTo != 0 {
+		return fmt.Sprintf("%s to %d", r.Action, r.RolledBackTo)
+	}
+	return r.Action
+}
+
+// parameterChanges formats the changed parameters of a revision
+func parameterChanges(changes []v1alpha1.ParameterChange) string {
+	formatted := make([]string, 0, len(changes))
+	for _, c := range changes {
+		formatted = append(formatted, parameterChange(c))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func parameterChange(c v1alpha1.ParameterChange) string {
+	switch {
+	case c.Old == nil:
+		return fmt.Sprintf("%s=%s", c.Name, strconv.Quote(*c.New))
+	case c.New == nil:
+		return fmt.Sprintf("%s removed", c.Name)
+	default:
+		return fmt.Sprintf("%s: %s -> %s", c.Name, strconv.Quote(*c.Old), strconv.Quote(*c.New))
+	}
+}
+
+// outcome formats the status of the plan triggered by a revision
+func outcome(r v1alpha1.InstanceRevision) string {
+	o := string(r.PlanStatus)
+	if r.PlanFinishedAt != nil {
+		o = fmt.Sprintf("%s at %s", o, r.PlanFinishedAt.Format(timeLayout))
+	}
+	if r.PlanMessage != "" {
+		o = fmt.Sprintf("%s: %s", o, r.PlanMessage)
+	}
+	return o
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
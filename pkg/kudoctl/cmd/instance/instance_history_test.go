@@ -0,0 +1,77 @@
+package instance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHistory(t *testing.T) {
+	changed := metav1.NewTime(time.Date(2019, 11, 5, 10, 0, 0, 0, time.UTC))
+	finished := metav1.NewTime(time.Date(2019, 11, 5, 10, 2, 0, 0, time.UTC))
+	instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	revisions := []v1alpha1.InstanceRevision{
+		{
+			Time: changed, User: "admin", ClientVersion: "v0.9.0", Action: "install", OperatorVersion: "test-1.0",
+			Parameters: []v1alpha1.ParameterChange{{Name: "REPLICAS", New: util.String("3")}},
+			Plan:       "deploy", PlanStatus: v1alpha1.ExecutionComplete, PlanFinishedAt: &finished,
+		},
+		{
+			Time: changed, Action: "update", OperatorVersion: "test-1.0",
+			Parameters: []v1alpha1.ParameterChange{{Name: "REPLICAS", Old: util.String("3"), New: util.String("5")}, {Name: "DEBUG", Old: util.String("true")}},
+			Plan:       "deploy", PlanStatus: v1alpha1.ExecutionFatalError, PlanMessage: "plan deploy timed out after 1m0s",
+		},
+	}
+	for _, r := range revisions {
+		if err := instance.AddRevision(r); err != nil {
+			t.Fatalf("expected no error but got %v", err)
+		}
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance))
+
+	var out bytes.Buffer
+	if err := history(&out, kc, "test", "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 revisions but got %q", out.String())
+	}
+	expected := [][]string{
+		{"2019-11-05T10:00:00", "admin", "v0.9.0", "install", `REPLICAS="3"`, "COMPLETE at 2019-11-05T10:02:00"},
+		{"2019-11-05T10:00:00", "-", "update", `REPLICAS: "3" -> "5", DEBUG removed`, "FATAL_ERROR: plan deploy timed out after 1m0s"},
+	}
+	for i, e := range expected {
+		for _, s := range e {
+			if !strings.Contains(lines[i+1], s) {
+				t.Errorf("expected revision %d to contain %q but got %q", i, s, lines[i+1])
+			}
+		}
+	}
+}
+
+func TestHistory_NoHistory(t *testing.T) {
+	instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance))
+
+	var out bytes.Buffer
+	if err := history(&out, kc, "test", "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if out.String() != "No history recorded for instance test.\n" {
+		t.Errorf("unexpected output %q", out.String())
+	}
+
+	if err := history(&out, kc, "other", "default"); err == nil {
+		t.Errorf("expected an error for a missing instance")
+	}
+}
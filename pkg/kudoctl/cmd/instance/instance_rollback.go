@@ -0,0 +1,125 @@
+package instance
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/pkg/errors"
+)
+
+// RollbackOptions are the configurable options for rollback
+type RollbackOptions struct {
+	// ToRevision is the revision to roll back to, 0 means the revision before the latest one
+	ToRevision int
+	// Yes skips the confirmation of the previewed changes
+	Yes bool
+}
+
+// DefaultRollbackOptions provides the default options for rollback
+var DefaultRollbackOptions = &RollbackOptions{}
+
+// RunRollback runs the rollback command
+func RunRollback(in io.Reader, out io.Writer, args []string, options *RollbackOptions, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+	if options.ToRevision < 0 {
+		return errors.New("--to-revision has to be a positive revision number")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return errors.Wrap(err, "creating kudo client")
+	}
+
+	return rollback(in, out, kc, args[0], settings.Namespace, options)
+}
+
+func rollback(in io.Reader, out io.Writer, kc *kudo.Client, instanceName, namespace string, options *RollbackOptions) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	toRevision := options.ToRevision
+	if toRevision == 0 {
+		revisions, err := instance.History()
+		if err != nil {
+			return errors.Wrapf(err, "reading history of instance %s", instanceName)
+		}
+		if len(revisions) < 2 {
+			return fmt.Errorf("instance %s has no previous revision to roll back to", instanceName)
+		}
+		toRevision = revisions[len(revisions)-2].Revision
+	}
+
+	spec, err := instance.SpecAtRevision(toRevision)
+	if err != nil {
+		return err
+	}
+	ov, err := kc.GetOperatorVersion(spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return errors.Wrapf(err, "getting operatorversion %s", spec.OperatorVersion.Name)
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s of revision %d does not exist in namespace %s anymore", spec.OperatorVersion.Name, toRevision, instance.OperatorVersionNamespace())
+	}
+
+	changes := v1alpha1.ParameterChanges(instance.Spec.Parameters, spec.Parameters)
+	if spec.OperatorVersion.Name == instance.Spec.OperatorVersion.Name && len(changes) == 0 {
+		fmt.Fprintf(out, "Instance %s already matches revision %d, nothing to roll back.\n", instanceName, toRevision)
+		return nil
+	}
+
+	// the preview is needed for the confirmation, it is only omitted in quiet mode without confirmation
+	if !clog.Quiet() || !options.Yes {
+		printPreview(out, instance, spec, toRevision, changes)
+	}
+
+	if !options.Yes && !confirm(in, out) {
+		fmt.Fprintf(out, "Rollback cancelled, no changes made.\n")
+		return nil
+	}
+
+	if err := kc.RollbackInstance(instance, toRevision); err != nil {
+		return errors.Wrapf(err, "rolling back instance %s", instanceName)
+	}
+	clog.Fresultf(out, instanceName, "Instance %s was rolled back to revision %d.", instanceName, toRevision)
+	return nil
+}
+
+// printPreview prints the changes a rollback of the instance to the given spec applies
+func printPreview(out io.Writer, instance *v1alpha1.Instance, spec *v1alpha1.InstanceSpec, toRevision int, changes []v1alpha1.ParameterChange) {
+	fmt.Fprintf(out, "Rolling back instance %s to revision %d:\n", instance.Name, toRevision)
+	if spec.OperatorVersion.Name != instance.Spec.OperatorVersion.Name {
+		fmt.Fprintf(out, "  operatorversion: %s -> %s\n", instance.Spec.OperatorVersion.Name, spec.OperatorVersion.Name)
+	}
+	if len(changes) > 0 {
+		fmt.Fprintf(out, "  parameters:\n")
+		for _, c := range changes {
+			fmt.Fprintf(out, "    %s\n", parameterChange(c))
+		}
+	}
+}
+
+// confirm asks the user to confirm the previewed changes
+func confirm(in io.Reader, out io.Writer) bool {
+	fmt.Fprintf(out, "Do you want to apply these changes? [y/N] ")
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Fprintln(out)
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,110 @@
+package instance
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func rollbackTestClient(t *testing.T) *kudo.Client {
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(
+		&v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.0", Namespace: "default"}},
+		&v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.1", Namespace: "default"}},
+	))
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"REPLICAS": "3"},
+		},
+	}
+	if _, err := kc.InstallInstanceObjToCluster(instance, "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if err := kc.UpdateInstance("test", "default", util.String("test-1.1"), map[string]string{"REPLICAS": "5"}, nil, ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	return kc
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  RollbackOptions
+		input    string
+		output   string
+		ov       string
+		replicas string
+	}{
+		{
+			name:     "confirmed",
+			input:    "y\n",
+			output:   "Rolling back instance test to revision 1:\n  operatorversion: test-1.1 -> test-1.0\n  parameters:\n    REPLICAS: \"5\" -> \"3\"\nDo you want to apply these changes? [y/N] Instance test was rolled back to revision 1.\n",
+			ov:       "test-1.0",
+			replicas: "3",
+		},
+		{
+			name:     "cancelled",
+			input:    "\n",
+			output:   "Do you want to apply these changes? [y/N] Rollback cancelled, no changes made.\n",
+			ov:       "test-1.1",
+			replicas: "5",
+		},
+		{
+			name:     "without confirmation",
+			options:  RollbackOptions{ToRevision: 1, Yes: true},
+			output:   "REPLICAS: \"5\" -> \"3\"\nInstance test was rolled back to revision 1.\n",
+			ov:       "test-1.0",
+			replicas: "3",
+		},
+		{
+			name:     "nothing to roll back",
+			options:  RollbackOptions{ToRevision: 2},
+			output:   "Instance test already matches revision 2, nothing to roll back.\n",
+			ov:       "test-1.1",
+			replicas: "5",
+		},
+	}
+
+	for _, tt := range tests {
+		kc := rollbackTestClient(t)
+		var out bytes.Buffer
+		if err := rollback(strings.NewReader(tt.input), &out, kc, "test", "default", &tt.options); err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.name, err)
+		}
+		if !strings.HasSuffix(out.String(), tt.output) {
+			t.Errorf("%s: expected output to end with %q but got %q", tt.name, tt.output, out.String())
+		}
+		instance, _ := kc.GetInstance("test", "default")
+		if instance.Spec.OperatorVersion.Name != tt.ov || instance.Spec.Parameters["REPLICAS"] != tt.replicas {
+			t.Errorf("%s: expected %s with REPLICAS=%s but got %s with %v", tt.name, tt.ov, tt.replicas, instance.Spec.OperatorVersion.Name, instance.Spec.Parameters)
+		}
+	}
+}
+
+func TestRollback_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string
+		options  RollbackOptions
+		err      string
+	}{
+		{name: "missing instance", instance: "other", err: "instance other in namespace default does not exist in the cluster"},
+		{name: "unknown revision", instance: "test", options: RollbackOptions{ToRevision: 5}, err: "revision 5 not found in history of instance default/test, available revisions are 1 to 2"},
+	}
+
+	for _, tt := range tests {
+		err := rollback(strings.NewReader(""), &bytes.Buffer{}, rollbackTestClient(t), tt.instance, "default", &tt.options)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
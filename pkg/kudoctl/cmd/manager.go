@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/manager"
+
+	"github.com/spf13/cobra"
+)
+
+const managerLogsExample = `  # Show the logs of the KUDO manager
+  kubectl kudo manager logs
+
+  # Stream the logs of the last 10 minutes and all new logs mentioning the deploy plan of the instance dev-flink
+  kubectl kudo manager logs -f --since=10m --instance=dev-flink --plan=deploy
+`
+
+// newManagerCmd creates a new command that allows to inspect the KUDO manager
+func newManagerCmd() *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "manager",
+		Short: "Inspect the KUDO manager.",
+		Long:  `The manager command has subcommands to inspect the KUDO manager running in the cluster.`,
+	}
+
+	newCmd.AddCommand(NewManagerLogsCmd())
+
+	return newCmd
+}
+
+// NewManagerLogsCmd creates a command that shows the logs of the KUDO manager
+func NewManagerLogsCmd() *cobra.Command {
+	options := manager.DefaultLogsOptions
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Show the logs of the KUDO manager.",
+		Long: `Show the logs of the KUDO manager pods, prefixed with the name of the pod if more than one manager is running.
+The logs can be filtered by instance and plan: structured log lines are matched by their instance and plan fields,
+plain log lines have to mention the instance and plan names.`,
+		Example: managerLogsExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return manager.RunLogs(cmd.OutOrStdout(), args, options, &Settings)
+		},
+	}
+
+	logsCmd.Flags().BoolVarP(&options.Follow, "follow", "f", false, "Stream the logs until the command is interrupted.")
+	logsCmd.Flags().DurationVar(&options.Since, "since", 0, "Only show logs newer than a relative duration like 5s, 2m or 3h. Defaults to all logs.")
+	logsCmd.Flags().StringVar(&options.Instance, "instance", "", "Only show log lines of the instance.")
+	logsCmd.Flags().StringVar(&options.Plan, "plan", "", "Only show log lines of the plan.")
+	logsCmd.Flags().StringVar(&options.Namespace, "manager-namespace", options.Namespace, "The namespace the KUDO manager is running in.")
+	return logsCmd
+}
@@ -0,0 +1,172 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+
+	pkgerrors "github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// managerContainer is the name of the container of the manager pods running the KUDO manager
+const managerContainer = "manager"
+
+// LogsOptions are the options of the manager logs command
+type LogsOptions struct {
+	// Namespace is the namespace the KUDO manager is running in
+	Namespace string
+	// Follow streams the logs until the command is interrupted
+	Follow bool
+	// Since only shows the logs newer than the duration, all logs are shown if it is zero
+	Since time.Duration
+	// Instance only shows the log lines mentioning the instance
+	Instance string
+	// Plan only shows the log lines mentioning the plan
+	Plan string
+}
+
+// DefaultLogsOptions provides the default options for manager logs
+var DefaultLogsOptions = &LogsOptions{Namespace: cmdInit.DefaultNamespace}
+
+// logStreamer opens the log stream of the manager container of a pod
+type logStreamer func(pod string, options *corev1.PodLogOptions) (io.ReadCloser, error)
+
+// RunLogs runs the manager logs command
+func RunLogs(out io.Writer, args []string, options *LogsOptions, settings *env.Settings) error {
+	if len(args) != 0 {
+		return errors.New("expecting no arguments")
+	}
+
+	client, err := kube.GetKubeClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return pkgerrors.Wrap(err, "creating kubernetes client")
+	}
+	pods, err := kudoinit.GetKUDOPods(client.KubeClient.CoreV1(), options.Namespace)
+	if err != nil {
+		return err
+	}
+
+	stream := func(pod string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		return client.KubeClient.CoreV1().Pods(options.Namespace).GetLogs(pod, opts).Stream()
+	}
+	return logs(out, pods, stream, options)
+}
+
+// logs prints the filtered logs of the manager pods. Lines are prefixed with the name of their pod if there is more
+// than one, as with a highly available installation. Followed logs of several pods are interleaved as they arrive.
+func logs(out io.Writer, pods []corev1.Pod, stream logStreamer, options *LogsOptions) error {
+	logOptions := &corev1.PodLogOptions{Container: managerContainer, Follow: options.Follow}
+	if options.Since > 0 {
+		seconds := int64(options.Since.Seconds())
+		logOptions.SinceSeconds = &seconds
+	}
+
+	var mu sync.Mutex
+	printPod := func(pod string) error {
+		r, err := stream(pod, logOptions)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "getting logs of pod %s", pod)
+		}
+		defer r.Close()
+
+		prefix := ""
+		if len(pods) > 1 {
+			prefix = fmt.Sprintf("[%s] ", pod)
+		}
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !matches(line, options) {
+				continue
+			}
+			mu.Lock()
+			fmt.Fprintf(out, "%s%s\n", prefix, line)
+			mu.Unlock()
+		}
+		return pkgerrors.Wrapf(scanner.Err(), "reading logs of pod %s", pod)
+	}
+
+	if !options.Follow {
+		for _, p := range pods {
+			if err := printPod(p.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make(chan error, len(pods))
+	var wg sync.WaitGroup
+	for _, p := range pods {
+		wg.Add(1)
+		go func(pod string) {
+			defer wg.Done()
+			errs <- printPod(pod)
+		}(p.Name)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches returns true if the log line mentions the instance and plan of the options. The fields of structured log
+// lines are compared with the filters, plain log lines have to contain them as words.
+func matches(line string, options *LogsOptions) bool {
+	if options.Instance == "" && options.Plan == "" {
+		return true
+	}
+
+	var fields map[string]interface{}
+	if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &fields) == nil {
+		return matchesField(fields, "instance", options.Instance) && matchesField(fields, "plan", options.Plan)
+	}
+	return containsWord(line, options.Instance) && containsWord(line, options.Plan)
+}
+
+// matchesField returns true if the field has the value, instances may also be logged with their namespace
+func matchesField(fields map[string]interface{}, name, value string) bool {
+	if value == "" {
+		return true
+	}
+	v, ok := fields[name].(string)
+	return ok && (v == value || strings.HasSuffix(v, "/"+value))
+}
+
+// containsWord returns true if the line contains the word not directly surrounded by other characters of a name
+func containsWord(line, word string) bool {
+	if word == "" {
+		return true
+	}
+	for i := strings.Index(line, word); i >= 0; {
+		end := i + len(word)
+		if (i == 0 || !isNameChar(line[i-1])) && (end == len(line) || !isNameChar(line[end])) {
+			return true
+		}
+		next := strings.Index(line[i+1:], word)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return false
+}
+
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.'
+}
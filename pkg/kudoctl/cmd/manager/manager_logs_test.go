@@ -0,0 +1,66 @@
+package manager
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		line     string
+		instance string
+		plan     string
+		matches  bool
+	}{
+		{`InstanceController: Going to proceed in execution of active plan deploy on instance default/flink`, "flink", "deploy", true},
+		{`InstanceController: Going to proceed in execution of active plan deploy on instance default/flink-2`, "flink", "", false},
+		{`InstanceController: Received Reconcile request for instance "flink"`, "flink", "", true},
+		{`InstanceController: Received Reconcile request for instance "flink"`, "flink", "deploy", false},
+		{`{"msg":"plan started","instance":"default/flink","plan":"deploy"}`, "flink", "deploy", true},
+		{`{"msg":"plan started","instance":"default/flink","plan":"update"}`, "flink", "deploy", false},
+		{`anything`, "", "", true},
+	}
+
+	for _, tt := range tests {
+		got := matches(tt.line, &LogsOptions{Instance: tt.instance, Plan: tt.plan})
+		assert.Equal(t, tt.matches, got, "%s with instance %q and plan %q", tt.line, tt.instance, tt.plan)
+	}
+}
+
+func TestLogs(t *testing.T) {
+	logsByPod := map[string]string{
+		"kudo-controller-manager-0": "starting manager\nreconciling instance default/flink\n",
+		"kudo-controller-manager-1": "waiting for leader election\n",
+	}
+	var requested []*corev1.PodLogOptions
+	stream := func(pod string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+		requested = append(requested, options)
+		return ioutil.NopCloser(strings.NewReader(logsByPod[pod])), nil
+	}
+	pods := []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "kudo-controller-manager-0"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "kudo-controller-manager-1"}},
+	}
+
+	var out bytes.Buffer
+	assert.NoError(t, logs(&out, pods, stream, &LogsOptions{Since: 10 * time.Minute}))
+	expected := `[kudo-controller-manager-0] starting manager
+[kudo-controller-manager-0] reconciling instance default/flink
+[kudo-controller-manager-1] waiting for leader election
+`
+	assert.Equal(t, expected, out.String())
+	assert.Equal(t, "manager", requested[0].Container)
+	assert.Equal(t, int64(600), *requested[0].SinceSeconds)
+
+	out.Reset()
+	assert.NoError(t, logs(&out, pods[:1], stream, &LogsOptions{Instance: "flink", Follow: true}))
+	assert.Equal(t, "reconciling instance default/flink\n", out.String())
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
@@ -49,6 +50,11 @@ func newPackageCmd(fs afero.Fs, out io.Writer) *cobra.Command {
 		},
 	}
 
+	cmd.AddCommand(newPackageDiffCmd(fs, out))
+	cmd.AddCommand(newPackageImportHelmCmd(fs, out))
+	cmd.AddCommand(newPackageTestCmd(fs, out))
+	cmd.AddCommand(newPackageVerifyCmd(fs, out))
+
 	f := cmd.Flags()
 	f.StringVarP(&pkg.destination, "destination", "d", ".", "Location to write the package.")
 	f.BoolVarP(&pkg.overwrite, "overwrite", "w", false, "Overwrite existing package.")
@@ -66,7 +72,7 @@ func validate(args []string) error {
 func (pkg *packageCmd) run() error {
 	tarfile, err := packages.CreateTarball(pkg.fs, pkg.path, pkg.destination, pkg.overwrite)
 	if err == nil {
-		fmt.Fprintf(pkg.out, "Package created: %v\n", tarfile)
+		clog.Fresultf(pkg.out, tarfile, "Package created: %v", tarfile)
 	}
 	return err
 }
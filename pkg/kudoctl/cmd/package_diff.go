@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pkgDiffDesc = `Compare two versions of a KUDO operator package.
+Lists the parameters, templates, tasks and plans that were added, removed or changed. Elements are compared semantically,
+changes of formatting or key order in YAML are not reported. Both arguments can be a package tarball or an operator folder.
+`
+	pkgDiffExample = `  # Compare two released packages
+  kubectl kudo package diff kafka-0.1.0.tgz kafka-0.2.0.tgz
+
+  # Compare a released package with the operator in development
+  kubectl kudo package diff kafka-0.1.0.tgz ./operators/kafka/operator`
+)
+
+type packageDiffCmd struct {
+	out io.Writer
+	fs  afero.Fs
+}
+
+// newPackageDiffCmd creates a command that compares two operator packages. fs is the file system, out is stdout for CLI
+func newPackageDiffCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	diff := &packageDiffCmd{out: out, fs: fs}
+	cmd := &cobra.Command{
+		Use:     "diff <old> <new>",
+		Short:   "Compare two versions of an operator package.",
+		Long:    pkgDiffDesc,
+		Example: pkgDiffExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("expecting exactly two arguments - the old and the new package")
+			}
+			return diff.run(args[0], args[1])
+		},
+	}
+	return cmd
+}
+
+func (d *packageDiffCmd) run(oldPath, newPath string) error {
+	old, err := d.read(oldPath)
+	if err != nil {
+		return err
+	}
+	new, err := d.read(newPath)
+	if err != nil {
+		return err
+	}
+
+	changes, err := packages.Diff(old, new)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(d.out, "No changes")
+		return nil
+	}
+	for _, c := range changes {
+		fmt.Fprintf(d.out, "%s %s %s\n", c.Kind, c.Name, c.Type)
+		for _, detail := range c.Details {
+			fmt.Fprintf(d.out, "    %s\n", detail)
+		}
+	}
+	return nil
+}
+
+func (d *packageDiffCmd) read(path string) (*packages.PackageFiles, error) {
+	pkg, err := packages.ReadPackage(d.fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", path, err)
+	}
+	files, err := pkg.GetPkgFiles()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package %s: %w", path, err)
+	}
+	return files, nil
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pkgTestDesc = `Run the template tests of a KUDO operator in development.
+Every folder tests/<name> of the operator is a test case. The templates of the apply and delete tasks are rendered with
+the parameter values of tests/<name>/params.yaml, the defaults are used for all other parameters, and compared with the
+expected output in tests/<name>/rendered/<template>. The instance is named after the operator and lives in the
+"default" namespace. No cluster is needed, templates referencing other instances can not be rendered.
+
+With --update the expected output is replaced by the rendered templates, review the changes before committing them.
+The tests folder is not included when the operator is packaged.
+`
+	pkgTestExample = `  # Run the template tests of the operator in development
+  kubectl kudo package test ./operators/kafka/operator
+
+  # Record the expected output after changing a template
+  kubectl kudo package test ./operators/kafka/operator --update`
+)
+
+type packageTestCmd struct {
+	update bool
+	out    io.Writer
+	fs     afero.Fs
+}
+
+// newPackageTestCmd creates a command that runs the template tests of an operator. fs is the file system, out is stdout for CLI
+func newPackageTestCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	pt := &packageTestCmd{out: out, fs: fs}
+	cmd := &cobra.Command{
+		Use:     "test <operator_dir>",
+		Short:   "Render the templates of an operator with the parameters of its tests and compare them with the expected output.",
+		Long:    pkgTestDesc,
+		Example: pkgTestExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("expecting exactly one argument - directory of the operator to test")
+			}
+			return pt.run(args[0])
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&pt.update, "update", false, "Replace the expected output of the tests with the rendered templates.")
+	return cmd
+}
+
+func (pt *packageTestCmd) run(path string) error {
+	pkg, err := packages.ReadPackage(pt.fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to read operator %s: %w", path, err)
+	}
+	crds, err := pkg.GetCRDs()
+	if err != nil {
+		return fmt.Errorf("operator %s is invalid: %w", path, err)
+	}
+	fixtures, err := packages.Fixtures(pt.fs, path)
+	if err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		fmt.Fprintf(pt.out, "No tests found in %s\n", path)
+		return nil
+	}
+
+	failed := 0
+	for _, f := range fixtures {
+		problems, err := pt.runFixture(crds.OperatorVersion, f)
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(problems) > 0:
+			failed++
+			fmt.Fprintf(pt.out, "❌ %s\n", f.Name)
+			for _, p := range problems {
+				fmt.Fprintf(pt.out, "    %s\n", p)
+			}
+		case pt.update:
+			fmt.Fprintf(pt.out, "✅ %s updated\n", f.Name)
+		default:
+			fmt.Fprintf(pt.out, "✅ %s\n", f.Name)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(fixtures))
+	}
+	return nil
+}
+
+// runFixture renders the templates with the parameters of the fixture and compares them with its expected output or
+// updates it. Templates that can not be rendered are always a problem, they would fail the deployment of the operator.
+func (pt *packageTestCmd) runFixture(ov *v1alpha1.OperatorVersion, f packages.Fixture) ([]string, error) {
+	params := map[string]string{}
+	if f.ParamsFile != "" {
+		var err error
+		if params, err = install.GetParameterMapFromFiles(pt.fs, []string{f.ParamsFile}); err != nil {
+			return nil, err
+		}
+	}
+
+	rendered, failed := task.RenderTemplates(ov, ov.Spec.Operator.Name, "default", params)
+	var problems []string
+	for name, err := range failed {
+		problems = append(problems, fmt.Sprintf("template %s can not be rendered: %v", name, err))
+	}
+	sort.Strings(problems)
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	if pt.update {
+		return nil, f.Update(pt.fs, rendered)
+	}
+	return f.Compare(pt.fs, rendered)
+}
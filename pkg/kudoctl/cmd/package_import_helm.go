@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pkgImportHelmDesc = `Import a Helm chart as KUDO operator package.
+The values of values.yaml become parameters, nested values are named after their path, e.g. image.pullPolicy becomes
+IMAGE_PULL_POLICY. The templates are rewritten to use these parameters and a deploy plan applying all templates is
+generated. Constructs without a KUDO equivalent, e.g. hooks, subcharts or functions like toYaml, are kept as they are
+and listed as warnings, they have to be migrated manually. The chart argument must be an unpacked chart directory.
+`
+	pkgImportHelmExample = `  # Import the chart in the folder nginx into the folder operator
+  kubectl kudo package import-helm nginx
+
+  # Specify a destination folder and replace an earlier import
+  kubectl kudo package import-helm nginx --destination=../operators/nginx/operator --overwrite`
+)
+
+type packageImportHelmCmd struct {
+	destination string
+	overwrite   bool
+	out         io.Writer
+	fs          afero.Fs
+}
+
+// newPackageImportHelmCmd creates a command that converts a Helm chart into an operator package. fs is the file system, out is stdout for CLI
+func newPackageImportHelmCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	imp := &packageImportHelmCmd{out: out, fs: fs}
+	cmd := &cobra.Command{
+		Use:     "import-helm <chart_dir>",
+		Short:   "Import a Helm chart as operator package.",
+		Long:    pkgImportHelmDesc,
+		Example: pkgImportHelmExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("expecting exactly one argument - directory of the chart to import")
+			}
+			return imp.run(args[0])
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVarP(&imp.destination, "destination", "d", "operator", "Location to write the operator package.")
+	f.BoolVarP(&imp.overwrite, "overwrite", "w", false, "Overwrite an existing operator package.")
+	return cmd
+}
+
+func (i *packageImportHelmCmd) run(chart string) error {
+	imported, err := packages.ImportHelmChart(i.fs, chart)
+	if err != nil {
+		return fmt.Errorf("failed to import chart %s: %v", chart, err)
+	}
+
+	empty, err := afero.IsEmpty(i.fs, i.destination)
+	if err == nil && !empty && !i.overwrite {
+		return fmt.Errorf("destination %s is not empty, use --overwrite to replace the files", i.destination)
+	}
+
+	names := make([]string, 0, len(imported.Files))
+	for name := range imported.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(i.destination, filepath.FromSlash(name))
+		if err := i.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := afero.WriteFile(i.fs, path, []byte(imported.Files[name]), 0644); err != nil {
+			return err
+		}
+	}
+
+	if len(imported.Warnings) > 0 {
+		fmt.Fprintln(i.out, "The following parts of the chart have to be migrated manually:")
+		for _, w := range imported.Warnings {
+			fmt.Fprintf(i.out, "    %s\n", w)
+		}
+	}
+	clog.Fresultf(i.out, i.destination, "Operator package created: %v", i.destination)
+	return nil
+}
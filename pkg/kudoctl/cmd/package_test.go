@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 )
 
 var packageCmdArgs = []struct {
@@ -34,3 +41,155 @@ func TestTableNewPackageCmd(t *testing.T) {
 		}
 	}
 }
+
+func TestPackageDiffCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files.CopyOperatorToFs(fs, "../packages/testdata/zk", "/old")
+	files.CopyOperatorToFs(fs, "../packages/testdata/zk", "/new")
+	params, _ := afero.ReadFile(fs, "/new/zk/params.yaml")
+	assert.NoError(t, afero.WriteFile(fs, "/new/zk/params.yaml", append(params, []byte("\nlogLevel:\n  default: INFO\n")...), 0644))
+
+	var out bytes.Buffer
+	cmd := newPackageDiffCmd(fs, &out)
+	assert.NoError(t, cmd.RunE(cmd, []string{"/old/zk", "/old/zk"}))
+	assert.Equal(t, "No changes\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, cmd.RunE(cmd, []string{"/old/zk", "/new/zk"}))
+	assert.Equal(t, "parameter logLevel added\n", out.String())
+
+	assert.EqualError(t, cmd.RunE(cmd, []string{"/old/zk"}), "expecting exactly two arguments - the old and the new package")
+}
+
+func TestPackageImportHelmCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/nginx/Chart.yaml", []byte("name: nginx\nversion: 1.0.0\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "/nginx/values.yaml", []byte("replicaCount: 1\n"), 0644))
+	assert.NoError(t, afero.WriteFile(fs, "/nginx/templates/deployment.yaml", []byte("replicas: {{ .Values.replicaCount }}\n"), 0644))
+
+	var out bytes.Buffer
+	cmd := newPackageImportHelmCmd(fs, &out)
+	assert.NoError(t, cmd.Flags().Set("destination", "/operator"))
+	assert.NoError(t, cmd.RunE(cmd, []string{"/nginx"}))
+	assert.Equal(t, "Operator package created: /operator\n", out.String())
+
+	template, _ := afero.ReadFile(fs, "/operator/templates/deployment.yaml")
+	assert.Equal(t, "replicas: {{ .Params.REPLICA_COUNT }}\n", string(template))
+	_, err := packages.ReadPackage(fs, "/operator")
+	assert.NoError(t, err)
+
+	assert.EqualError(t, cmd.RunE(cmd, []string{"/nginx"}), "destination /operator is not empty, use --overwrite to replace the files")
+	assert.NoError(t, cmd.Flags().Set("overwrite", "true"))
+	assert.NoError(t, cmd.RunE(cmd, []string{"/nginx"}))
+}
+
+func TestPackageVerifyCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files.CopyOperatorToFs(fs, "../packages/testdata/zk", "/opt")
+	sts, _ := afero.ReadFile(fs, "/opt/zk/templates/statefulset.yaml")
+	sts = bytes.Replace(sts, []byte(`accessModes: ["ReadWriteOnce"]`), []byte("storageClassName: fast\n        accessModes: [\"ReadWriteOnce\"]"), 1)
+	assert.NoError(t, afero.WriteFile(fs, "/opt/zk/templates/statefulset.yaml", sts, 0644))
+
+	var out bytes.Buffer
+	cmd := newPackageVerifyCmd(fs, &out)
+	assert.NoError(t, cmd.RunE(cmd, []string{"/opt/zk"}))
+	assert.Equal(t, "Package zookeeper-0.1.0 is valid\n", out.String())
+
+	kc := kubefake.NewSimpleClientset()
+	services := &metav1.APIResourceList{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "services", Kind: "Service"}}}
+	statefulSets := &metav1.APIResourceList{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "statefulsets", Kind: "StatefulSet"}, {Name: "statefulsets/scale", Kind: "Scale"}}}
+	kc.Fake.Resources = []*metav1.APIResourceList{services, statefulSets,
+		{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{{Name: "cronjobs", Kind: "CronJob"}}},
+	}
+	out.Reset()
+	v := &packageVerifyCmd{out: &out, fs: fs, againstCluster: true, instanceName: "zk", client: &kube.Client{KubeClient: kc}}
+	assert.EqualError(t, v.run("/opt/zk"), "package zookeeper-0.1.0 can not be installed into the cluster, 3 problems found")
+	assert.Equal(t, `❌ template pdb.yaml: PodDisruptionBudget zk-pdb uses apiVersion policy/v1beta1 which could not be discovered: GroupVersion "policy/v1beta1" not found
+❌ template statefulset.yaml: StatefulSet zk uses storage class fast which does not exist
+❌ template validation.yaml: Job zookeeper-validation has kind Job which is not served by the cluster in apiVersion batch/v1
+`, out.String())
+
+	kc.Fake.Resources = []*metav1.APIResourceList{services, statefulSets,
+		{GroupVersion: "batch/v1", APIResources: []metav1.APIResource{{Name: "jobs", Kind: "Job"}}},
+		{GroupVersion: "policy/v1beta1", APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets", Kind: "PodDisruptionBudget"}}},
+	}
+	_, err := kc.StorageV1().StorageClasses().Create(&storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}})
+	assert.NoError(t, err)
+	out.Reset()
+	assert.NoError(t, v.run("/opt/zk"))
+	assert.Equal(t, "Package zookeeper-0.1.0 is valid and can be installed into the cluster\n", out.String())
+}
+
+func TestPackageVerifyCmd_Stdin(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	tgz, err := afero.ReadFile(afero.NewOsFs(), "../packages/testdata/zk.tgz")
+	assert.NoError(t, err)
+
+	var out, errOut bytes.Buffer
+	cmd := newPackageVerifyCmd(fs, &out)
+	cmd.SetIn(bytes.NewReader(tgz))
+	cmd.SetErr(&errOut)
+	assert.NoError(t, cmd.Flags().Set("render-out", "-"))
+	assert.NoError(t, cmd.Flags().Set("instance", "zk"))
+	assert.NoError(t, cmd.RunE(cmd, []string{"-"}))
+
+	assert.Equal(t, "Package zookeeper-0.1.0 is valid\n", errOut.String())
+	assert.True(t, strings.HasPrefix(out.String(), "---\n# Source: "), out.String())
+	assert.Contains(t, out.String(), "# Source: statefulset.yaml\n")
+	assert.Contains(t, out.String(), "name: zk\n")
+}
+
+func TestPackageVerifyCmd_RenderOutFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files.CopyOperatorToFs(fs, "../packages/testdata/zk", "/opt")
+
+	var out bytes.Buffer
+	v := &packageVerifyCmd{out: &out, fs: fs, renderOut: "/tmp/rendered.yaml"}
+	assert.NoError(t, v.run("/opt/zk"))
+	assert.Equal(t, "Package zookeeper-0.1.0 is valid\n", out.String())
+
+	rendered, err := afero.ReadFile(fs, "/tmp/rendered.yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, string(rendered), "# Source: statefulset.yaml\n")
+}
+
+func TestPackageTestCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	files.CopyOperatorToFs(fs, "../packages/testdata/zk", "/opt")
+	assert.NoError(t, fs.MkdirAll("/opt/zk/tests/defaults", 0755))
+	assert.NoError(t, afero.WriteFile(fs, "/opt/zk/tests/small/params.yaml", []byte("memory: 256Mi\n"), 0644))
+
+	var out bytes.Buffer
+	cmd := newPackageTestCmd(fs, &out)
+	assert.NoError(t, cmd.Flags().Set("update", "true"))
+	assert.NoError(t, cmd.RunE(cmd, []string{"/opt/zk"}))
+	assert.Equal(t, "✅ defaults updated\n✅ small updated\n", out.String())
+
+	statefulSet, err := afero.ReadFile(fs, "/opt/zk/tests/small/rendered/statefulset.yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, string(statefulSet), `memory: "256Mi"`)
+
+	// the tests are not part of the operator
+	assert.NoError(t, fs.Mkdir("/out", 0755))
+	tarball, err := packages.CreateTarball(fs, "/opt/zk", "/out", false)
+	assert.NoError(t, err)
+	pkg, err := packages.ReadPackage(fs, tarball)
+	assert.NoError(t, err)
+	pkgFiles, err := pkg.GetPkgFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(pkgFiles.Templates))
+
+	out.Reset()
+	cmd = newPackageTestCmd(fs, &out)
+	assert.NoError(t, cmd.RunE(cmd, []string{"/opt/zk"}))
+	assert.Equal(t, "✅ defaults\n✅ small\n", out.String())
+
+	assert.NoError(t, afero.WriteFile(fs, "/opt/zk/tests/small/params.yaml", []byte("memory: 512Mi\n"), 0644))
+	assert.NoError(t, fs.Remove("/opt/zk/tests/small/rendered/services.yaml"))
+	out.Reset()
+	assert.EqualError(t, cmd.RunE(cmd, []string{"/opt/zk"}), "1 of 2 tests failed")
+	assert.Contains(t, out.String(), "❌ small\n")
+	assert.Contains(t, out.String(), "    template services.yaml has no expected output\n")
+	assert.Contains(t, out.String(), `    template statefulset.yaml line `)
+	assert.Contains(t, out.String(), `removed "              memory: \"256Mi\""`)
+}
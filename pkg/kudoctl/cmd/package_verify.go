@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/resolver"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/verify"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	pkgVerifyDesc = `Verify a KUDO operator package.
+The package is read and validated like it is on install. With --against-cluster the templates of the apply and delete
+tasks are rendered with the defaults of the parameters, overridden by -p and --parameter-file, and checked against the
+current cluster: the apiVersion and kind of every object have to be served by the cluster or be defined by a CRD bundled
+with the package, and the storage classes and priority classes the objects use have to exist. Templates referencing
+other instances can not be rendered without the instances and are reported as well.
+
+The package is read from stdin if it is given as '-'. With --render-out the rendered templates are written to a file,
+or to stdout if it is '-', all other output is written to stderr then.
+`
+	pkgVerifyExample = `  # Verify the operator in development
+  kubectl kudo package verify ./operators/kafka/operator
+
+  # Verify that a released package can be installed into the current cluster with the production parameters
+  kubectl kudo package verify kafka-1.2.0.tgz --against-cluster --parameter-file prod.yaml
+
+  # Verify a package built in a pipe and pass the rendered templates on to another tool
+  curl -sL https://example.com/kafka-1.2.0.tgz | kubectl kudo package verify - --render-out - | kubeval`
+)
+
+type packageVerifyCmd struct {
+	againstCluster bool
+	instanceName   string
+	parameters     []string
+	parameterFiles []string
+	renderOut      string
+	in             io.Reader
+	out            io.Writer
+	errOut         io.Writer
+	fs             afero.Fs
+	client         *kube.Client
+}
+
+// newPackageVerifyCmd creates a command that verifies an operator package. fs is the file system, out is stdout for CLI
+func newPackageVerifyCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	v := &packageVerifyCmd{out: out, fs: fs}
+	cmd := &cobra.Command{
+		Use:     "verify <package>",
+		Short:   "Verify an operator package, optionally against the current cluster.",
+		Long:    pkgVerifyDesc,
+		Example: pkgVerifyExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("expecting exactly one argument - the package to verify")
+			}
+			v.in, v.errOut = cmd.InOrStdin(), cmd.ErrOrStderr()
+			return v.run(args[0])
+		},
+	}
+
+	f := cmd.Flags()
+	f.BoolVar(&v.againstCluster, "against-cluster", false, "Render the templates and check that the current cluster serves their kinds and provides the classes they use.")
+	f.StringVar(&v.instanceName, "instance", "", "The instance name the templates are rendered for. (defaults to the operator name)")
+	f.StringArrayVarP(&v.parameters, "parameter", "p", nil, "The parameter name and value separated by '=' used to render the templates")
+	f.StringArrayVar(&v.parameterFiles, "parameter-file", nil, "A YAML file with parameters used to render the templates, can be repeated")
+	f.StringVar(&v.renderOut, "render-out", "", "A file the rendered templates are written to, '-' writes them to stdout")
+	return cmd
+}
+
+func (v *packageVerifyCmd) run(path string) error {
+	pkg, err := v.readPackage(path)
+	if err != nil {
+		return fmt.Errorf("failed to read package %s: %w", path, err)
+	}
+	crds, err := pkg.GetCRDs()
+	if err != nil {
+		return fmt.Errorf("package %s is invalid: %w", path, err)
+	}
+	ov := crds.OperatorVersion
+	out := v.statusOut()
+	if !v.againstCluster && v.renderOut == "" {
+		fmt.Fprintf(out, "Package %s is valid\n", ov.Name)
+		return nil
+	}
+
+	params, err := install.GetParameters(v.fs, v.parameterFiles, v.parameters)
+	if err != nil {
+		return fmt.Errorf("could not parse parameters: %w", err)
+	}
+	instanceName := v.instanceName
+	if instanceName == "" {
+		instanceName = ov.Spec.Operator.Name
+	}
+
+	rendered, failed := task.RenderTemplates(ov, instanceName, Settings.Namespace, params)
+	if v.renderOut != "" {
+		if err := v.writeRendered(rendered); err != nil {
+			return err
+		}
+	}
+
+	var problems []string
+	if v.againstCluster {
+		if v.client == nil {
+			if v.client, err = kube.GetKubeClient(Settings.KubeConfig, Settings.Context); err != nil {
+				return err
+			}
+		}
+		if problems, err = verify.CheckPackage(v.client, rendered, ov.Spec.CRDs); err != nil {
+			return err
+		}
+	}
+	for name, err := range failed {
+		problems = append(problems, fmt.Sprintf("template %s can not be rendered: %v", name, err))
+	}
+	sort.Strings(problems)
+
+	if len(problems) == 0 {
+		if v.againstCluster {
+			fmt.Fprintf(out, "Package %s is valid and can be installed into the cluster\n", ov.Name)
+		} else {
+			fmt.Fprintf(out, "Package %s is valid\n", ov.Name)
+		}
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Fprintf(out, "❌ %s\n", p)
+	}
+	if !v.againstCluster {
+		return fmt.Errorf("package %s is invalid, %d problems found", ov.Name, len(problems))
+	}
+	return fmt.Errorf("package %s can not be installed into the cluster, %d problems found", ov.Name, len(problems))
+}
+
+// readPackage reads the package from stdin if the path is '-', otherwise from the file system
+func (v *packageVerifyCmd) readPackage(path string) (packages.Package, error) {
+	if path == resolver.StdinName {
+		return resolver.NewStdin(v.in).GetPackage(path, "")
+	}
+	return packages.ReadPackage(v.fs, path)
+}
+
+// statusOut returns the writer for messages, which is stderr if the rendered templates are written to stdout
+func (v *packageVerifyCmd) statusOut() io.Writer {
+	if v.renderOut == "-" && v.errOut != nil {
+		return v.errOut
+	}
+	return v.out
+}
+
+// writeRendered writes the rendered templates as one YAML stream ordered by their names, every template starts with a
+// comment naming it
+func (v *packageVerifyCmd) writeRendered(rendered map[string]string) error {
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "---\n# Source: %s\n%s", name, strings.TrimPrefix(rendered[name], "---\n"))
+		if !strings.HasSuffix(rendered[name], "\n") {
+			b.WriteString("\n")
+		}
+	}
+
+	if v.renderOut == "-" {
+		_, err := io.WriteString(v.out, b.String())
+		return err
+	}
+	if err := afero.WriteFile(v.fs, v.renderOut, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write the rendered templates: %w", err)
+	}
+	return nil
+}
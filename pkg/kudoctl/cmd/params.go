@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/params"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	paramsEditExample = `  # Edit the parameters of instance dev-flink in your $EDITOR
+  kubectl kudo params edit --instance dev-flink
+
+  # Use an alternative editor
+  KUBE_EDITOR="nano" kubectl kudo params edit --instance dev-flink
+`
+	paramsListExample = `  # Show the effective parameters of instance dev-flink
+  kubectl kudo params list --instance dev-flink
+`
+	paramsRenderExample = `  # Show the parameters the kafka package would be installed with
+  kubectl kudo params render kafka
+
+  # Show the parameters of a base file overlaid by an environment specific file and a single override
+  kubectl kudo params render kafka --parameter-file base.yaml --parameter-file prod.yaml -p BROKER_COUNT=5
+`
+)
+
+// newParamsCmd creates a new command that allows to inspect and modify the parameters of an instance
+func newParamsCmd(fs afero.Fs) *cobra.Command {
+	newCmd := &cobra.Command{
+		Use:   "params",
+		Short: "View and modify instance parameters.",
+		Long:  `The params command has subcommands to view and modify the parameters of an instance.`,
+	}
+
+	newCmd.AddCommand(NewParamsEditCmd())
+	newCmd.AddCommand(NewParamsListCmd())
+	newCmd.AddCommand(NewParamsRenderCmd(fs))
+
+	return newCmd
+}
+
+// NewParamsEditCmd creates a command that opens the parameters of an instance in an editor
+func NewParamsEditCmd() *cobra.Command {
+	options := params.DefaultEditOptions
+	var encryptionConfig string
+	editCmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Edit the parameters of an instance.",
+		Long: `Edit the parameters of an instance using the editor defined by the KUBE_EDITOR or EDITOR environment
+variables. Saved parameters are validated against the OperatorVersion and only changed parameters are applied.
+
+With --encryption-config the changed values of sensitive parameters are encrypted before they are stored in the
+instance, see the install command.`,
+		Example: paramsEditExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			options.Keyring, err = install.GetKeyring(encryptionConfig)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			return params.RunEdit(cmd.OutOrStdout(), options, &Settings)
+		},
+	}
+
+	editCmd.Flags().StringVar(&options.Instance, "instance", "", "The instance name.")
+	editCmd.Flags().StringVar(&encryptionConfig, "encryption-config", "", "Encryption configuration file whose first provider encrypts the values of sensitive parameters")
+	if err := editCmd.MarkFlagRequired("instance"); err != nil {
+		panic(err)
+	}
+
+	return editCmd
+}
+
+// NewParamsListCmd creates a command that shows the effective parameters of an instance
+func NewParamsListCmd() *cobra.Command {
+	options := params.DefaultListOptions
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the parameters of an instance.",
+		Long: `List the effective value of every parameter of an instance, whether it is set on the instance or inherited from
+the default of the OperatorVersion, and whether it is required. Required parameters without a value are shown as missing.`,
+		Example: paramsListExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return params.RunList(cmd.OutOrStdout(), options, &Settings)
+		},
+	}
+
+	listCmd.Flags().StringVar(&options.Instance, "instance", "", "The instance name.")
+	if err := listCmd.MarkFlagRequired("instance"); err != nil {
+		panic(err)
+	}
+
+	return listCmd
+}
+
+// NewParamsRenderCmd creates a command that shows the effective parameters of a package before it is installed
+func NewParamsRenderCmd(fs afero.Fs) *cobra.Command {
+	options := params.DefaultRenderOptions
+	renderCmd := &cobra.Command{
+		Use:   "render <name>",
+		Short: "Show the effective parameters of a package.",
+		Long: `Show the parameters an instance of the package would be installed with. Defaults of the package are overlaid
+by the parameter files in the given order and by the parameters given with -p. See 'kubectl kudo install --help'.`,
+		Example: paramsRenderExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return params.RunRender(cmd.OutOrStdout(), args, options, fs, &Settings)
+		},
+	}
+
+	renderCmd.Flags().StringArrayVarP(&options.Parameters, "parameter", "p", nil, "The parameter name and value separated by '='")
+	renderCmd.Flags().StringArrayVar(&options.ParameterFiles, "parameter-file", nil, "A YAML file with parameters, can be repeated. Later files are merged on top of earlier ones and -p takes precedence")
+	renderCmd.Flags().StringVar(&options.RepoName, "repo", "", "Name of repository configuration to use. (default defined by the client configuration or context)")
+	renderCmd.Flags().StringVar(&options.PackageVersion, "version", "", "A specific package version or a version constraint, e.g. '>=1.2 <2', on the official repository. (default to the most recent)")
+
+	return renderCmd
+}
@@ -0,0 +1,312 @@
+package params
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+
+	"github.com/google/shlex"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Options are the configurable options for params commands
+type Options struct {
+	Instance string
+	// Keyring encrypts the values of sensitive parameters
+	Keyring *encryption.Keyring
+}
+
+var (
+	// DefaultEditOptions provides the default options for params edit
+	DefaultEditOptions = &Options{}
+)
+
+const (
+	defaultEditor = "vi"
+	editHeader    = `# Please edit the parameters below. Lines beginning with a '#' will be ignored,
+# and an empty file will abort the edit. Only parameters defined by the
+# OperatorVersion %s are accepted.
+#
+`
+)
+
+// Editor opens the file at the given path for editing and blocks until the user is done
+type Editor interface {
+	Launch(path string) error
+}
+
+// cmdEditor launches an external editor process attached to the terminal
+type cmdEditor struct {
+	args []string
+}
+
+// Launch runs the editor command with the path of the file as the last argument
+func (e cmdEditor) Launch(path string) error {
+	args := append(append([]string{}, e.args...), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	clog.V(4).Printf("launching editor: %v", args)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q exited with error: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// newEditor returns the editor defined by KUBE_EDITOR or EDITOR, falling back to vi
+func newEditor() (Editor, error) {
+	editor := defaultEditor
+	for _, e := range []string{"KUBE_EDITOR", "EDITOR"} {
+		if v := os.Getenv(e); v != "" {
+			editor = v
+			break
+		}
+	}
+	args, err := shlex.Split(editor)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse editor command %q: %w", editor, err)
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("editor command is empty")
+	}
+	return cmdEditor{args: args}, nil
+}
+
+// RunEdit runs the params edit command
+func RunEdit(out io.Writer, options *Options, settings *env.Settings) error {
+	editor, err := newEditor()
+	if err != nil {
+		return err
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return errors.Wrap(err, "creating kudo client")
+	}
+
+	return edit(out, kc, editor, options.Instance, settings.Namespace, options.Keyring)
+}
+
+func edit(out io.Writer, kc *kudo.Client, editor Editor, instanceName, namespace string, keyring *encryption.Keyring) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return errors.Wrapf(err, "getting operatorversion %s", instance.Spec.OperatorVersion.Name)
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s for instance %s does not exist in namespace %s", instance.Spec.OperatorVersion.Name, instanceName, instance.OperatorVersionNamespace())
+	}
+
+	edited, err := editParameters(editor, ov.Name, instance.Spec.Parameters)
+	if err != nil {
+		return err
+	}
+	if edited == nil {
+		if !clog.Quiet() {
+			fmt.Fprintf(out, "Edit cancelled, no changes made.\n")
+		}
+		return nil
+	}
+
+	if err := validateParameters(edited, instance.Spec.Parameters, ov); err != nil {
+		return err
+	}
+
+	changed, err := encryptChangedParameters(changedParameters(instance.Spec.Parameters, edited), instance.Spec.Parameters, ov, keyring)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		if !clog.Quiet() {
+			fmt.Fprintf(out, "Edit cancelled, no changes made.\n")
+		}
+		return nil
+	}
+	clog.V(2).Printf("changed parameters: %v", keys(changed))
+
+	if err := kc.UpdateInstance(instanceName, namespace, nil, changed, nil, ""); err != nil {
+		return errors.Wrapf(err, "updating instance %s", instanceName)
+	}
+	clog.Fresultf(out, instanceName, "instance.%s/%s edited", instance.APIVersion, instanceName)
+	return nil
+}
+
+// editParameters writes the parameters to a temporary file, launches the editor and parses the result.
+// A nil map is returned when the user emptied the file.
+func editParameters(editor Editor, ovName string, params map[string]string) (map[string]string, error) {
+	f, err := ioutil.TempFile("", "kudo-params-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, editHeader, ovName)
+	if len(params) > 0 {
+		b, err := yaml.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	if err := editor.Launch(f.Name()); err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(f.Name())
+	if err != nil {
+		return nil, errors.Wrap(err, "reading edited parameters")
+	}
+	return parseParameters(b)
+}
+
+// parseParameters parses a YAML map of parameters. Scalar values are kept with the text they have in the YAML, nested
+// structures and empty values are rejected.
+func parseParameters(b []byte) (map[string]string, error) {
+	raw, err := install.UnmarshalParameterValues(b)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing edited parameters")
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	params := make(map[string]string, len(raw))
+	var errs []string
+	for k, v := range raw {
+		switch v.(type) {
+		case nil:
+			errs = append(errs, fmt.Sprintf("parameter value can not be empty: %s", k))
+		case map[string]interface{}, []interface{}:
+			errs = append(errs, fmt.Sprintf("parameter value has to be a scalar: %s", k))
+		default:
+			params[k] = fmt.Sprint(v)
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, ", "))
+	}
+	return params, nil
+}
+
+// validateParameters makes sure that the edited parameters are defined in the OperatorVersion, that no
+// parameter was removed, that all required parameters without a default are still set and that the values conform to
+// the type and schema of their parameter
+func validateParameters(edited, current map[string]string, ov *v1alpha1.OperatorVersion) error {
+	defined := make(map[string]v1alpha1.Parameter, len(ov.Spec.Parameters))
+	for _, p := range ov.Spec.Parameters {
+		defined[p.Name] = p
+	}
+
+	var unknown, removed []string
+	for k := range edited {
+		if _, ok := defined[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	for k := range current {
+		if _, ok := edited[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	var errs []string
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		errs = append(errs, fmt.Sprintf("parameters not defined in operatorversion %s: %s", ov.Name, strings.Join(unknown, ",")))
+	}
+	if len(removed) > 0 {
+		sort.Strings(removed)
+		errs = append(errs, fmt.Sprintf("removing parameters is not supported: %s", strings.Join(removed, ",")))
+	}
+	if err := v1alpha1.ValidateParameters(ov, edited); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// changedParameters returns all parameters that were added or changed in new compared to old
+func changedParameters(old, new map[string]string) map[string]string {
+	changed := make(map[string]string)
+	for k, v := range new {
+		if oldValue, ok := old[k]; !ok || oldValue != v {
+			changed[k] = v
+		}
+	}
+	return changed
+}
+
+// encryptChangedParameters encrypts the values of the changed parameters that the OperatorVersion marks as sensitive.
+// The stored values of sensitive parameters are encrypted, so a changed parameter whose value equals the decrypted
+// stored value is dropped. Without keyring the changed values are kept in plaintext.
+func encryptChangedParameters(changed, current map[string]string, ov *v1alpha1.OperatorVersion, keyring *encryption.Keyring) (map[string]string, error) {
+	for _, p := range ov.Spec.Parameters {
+		value, ok := changed[p.Name]
+		if !ok || !p.Sensitive {
+			continue
+		}
+		if keyring == nil {
+			clog.Printf("WARNING: the value of sensitive parameter %s is stored in plaintext, use --encryption-config to encrypt it", p.Name)
+			continue
+		}
+		old, ok := current[p.Name]
+		if !ok {
+			continue
+		}
+		oldValue, err := keyring.Decrypt(p.Name, old)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypting parameter %s", p.Name)
+		}
+		newValue, err := keyring.Decrypt(p.Name, value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decrypting parameter %s", p.Name)
+		}
+		if oldValue == newValue {
+			delete(changed, p.Name)
+		}
+	}
+	return install.EncryptSensitiveParameters(ov, changed, keyring)
+}
+
+// keys returns the sorted names of the parameters, so that their values are not logged
+func keys(params map[string]string) []string {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,201 @@
+package params
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeEditor replaces the content of the edited file with the given content
+type fakeEditor struct {
+	content string
+}
+
+func (e fakeEditor) Launch(path string) error {
+	return ioutil.WriteFile(path, []byte(e.content), 0600)
+}
+
+func newTestClient() *kudo.Client {
+	return kudo.NewClientFromK8s(fake.NewSimpleClientset())
+}
+
+func TestEdit(t *testing.T) {
+	testOv := &v1alpha1.OperatorVersion{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kudo.dev/v1alpha1",
+			Kind:       "OperatorVersion",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-1.0",
+		},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Version: "1.0",
+			Parameters: []v1alpha1.Parameter{
+				{Name: "REPLICAS", Default: util.String("1")},
+				{Name: "PASSWORD", Required: true},
+				{Name: "MEMORY", Default: util.String("1Gi")},
+			},
+		},
+	}
+	testInstance := &v1alpha1.Instance{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "kudo.dev/v1alpha1",
+			Kind:       "Instance",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{
+				Name: "test-1.0",
+			},
+			Parameters: map[string]string{"PASSWORD": "secret", "REPLICAS": "3"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected map[string]string
+		out      string
+		err      string
+	}{
+		{"no changes", "PASSWORD: secret\nREPLICAS: \"3\"\n", map[string]string{"PASSWORD": "secret", "REPLICAS": "3"}, "no changes made", ""},
+		{"empty file aborts", "# only a comment\n", map[string]string{"PASSWORD": "secret", "REPLICAS": "3"}, "no changes made", ""},
+		{"changed and added parameters", "PASSWORD: secret\nREPLICAS: 5\nMEMORY: 2Gi\n", map[string]string{"PASSWORD": "secret", "REPLICAS": "5", "MEMORY": "2Gi"}, "instance.kudo.dev/v1alpha1/test edited", ""},
+		{"large numbers keep their text", "PASSWORD: secret\nREPLICAS: 1000000\n", map[string]string{"PASSWORD": "secret", "REPLICAS": "1000000"}, "instance.kudo.dev/v1alpha1/test edited", ""},
+		{"unknown parameter", "PASSWORD: secret\nREPLICAS: \"3\"\nFOO: bar\n", nil, "", "parameters not defined in operatorversion test-1.0: FOO"},
+		{"removed parameter", "PASSWORD: secret\n", nil, "", "removing parameters is not supported: REPLICAS"},
+		{"empty value", "PASSWORD:\nREPLICAS: \"3\"\n", nil, "", "parameter value can not be empty: PASSWORD"},
+		{"non scalar value", "PASSWORD: secret\nREPLICAS: [1, 2]\n", nil, "", "parameter value has to be a scalar: REPLICAS"},
+		{"invalid yaml", "PASSWORD: secret\n  REPLICAS: 3\n", nil, "", "parsing edited parameters"},
+	}
+
+	for _, tt := range tests {
+		kc := newTestClient()
+		if _, err := kc.InstallOperatorVersionObjToCluster(testOv, "default"); err != nil {
+			t.Fatalf("%s: failed to install operatorversion: %v", tt.name, err)
+		}
+		if _, err := kc.InstallInstanceObjToCluster(testInstance.DeepCopy(), "default"); err != nil {
+			t.Fatalf("%s: failed to install instance: %v", tt.name, err)
+		}
+
+		var out bytes.Buffer
+		err := edit(&out, kc, fakeEditor{tt.content}, "test", "default", nil)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error '%s' but got '%v'", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+			continue
+		}
+		if !strings.Contains(out.String(), tt.out) {
+			t.Errorf("%s: expected output to contain '%s' but got '%s'", tt.name, tt.out, out.String())
+		}
+
+		instance, err := kc.GetInstance("test", "default")
+		if err != nil {
+			t.Fatalf("%s: error when getting instance to verify the test: %v", tt.name, err)
+		}
+		if len(instance.Spec.Parameters) != len(tt.expected) {
+			t.Errorf("%s: expected parameters %v but got %v", tt.name, tt.expected, instance.Spec.Parameters)
+		}
+		for k, v := range tt.expected {
+			if instance.Spec.Parameters[k] != v {
+				t.Errorf("%s: expected parameter %s to be %s but params are %v", tt.name, k, v, instance.Spec.Parameters)
+			}
+		}
+	}
+}
+
+func TestEdit_InstanceDoesNotExist(t *testing.T) {
+	err := edit(&bytes.Buffer{}, newTestClient(), fakeEditor{}, "test", "default", nil)
+	if err == nil || !strings.Contains(err.Error(), "instance test in namespace default does not exist in the cluster") {
+		t.Errorf("expected instance not found error but got %v", err)
+	}
+}
+
+func TestEdit_SensitiveParameters(t *testing.T) {
+	provider, err := encryption.NewAESGCMProvider([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := encryption.NewKeyring()
+	if err := keyring.Add("test", provider); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := keyring.Encrypt("PASSWORD", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Parameters: []v1alpha1.Parameter{{Name: "PASSWORD", Sensitive: true}, {Name: "REPLICAS"}},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"PASSWORD": stored, "REPLICAS": "3"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		password string
+		replicas string
+	}{
+		{"unchanged plaintext value", "PASSWORD: secret\nREPLICAS: \"3\"\n", "secret", "3"},
+		{"changed plaintext value", "PASSWORD: other\nREPLICAS: \"3\"\n", "other", "3"},
+		{"other parameter changed", "PASSWORD: secret\nREPLICAS: \"5\"\n", "secret", "5"},
+	}
+	for _, tt := range tests {
+		kc := newTestClient()
+		if _, err := kc.InstallOperatorVersionObjToCluster(ov, "default"); err != nil {
+			t.Fatalf("%s: failed to install operatorversion: %v", tt.name, err)
+		}
+		if _, err := kc.InstallInstanceObjToCluster(instance.DeepCopy(), "default"); err != nil {
+			t.Fatalf("%s: failed to install instance: %v", tt.name, err)
+		}
+
+		if err := edit(&bytes.Buffer{}, kc, fakeEditor{tt.content}, "test", "default", keyring); err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+			continue
+		}
+
+		edited, err := kc.GetInstance("test", "default")
+		if err != nil {
+			t.Fatalf("%s: error when getting instance to verify the test: %v", tt.name, err)
+		}
+		password := edited.Spec.Parameters["PASSWORD"]
+		if !encryption.IsEncrypted(password) {
+			t.Errorf("%s: expected the password to be stored encrypted but got %s", tt.name, password)
+		}
+		if decrypted, _ := keyring.Decrypt("PASSWORD", password); decrypted != tt.password {
+			t.Errorf("%s: expected the password %s but got %s", tt.name, tt.password, decrypted)
+		}
+		if tt.password == "secret" && password != stored {
+			t.Errorf("%s: expected the unchanged password not to be encrypted again", tt.name)
+		}
+		if edited.Spec.Parameters["REPLICAS"] != tt.replicas {
+			t.Errorf("%s: expected replicas %s but got %s", tt.name, tt.replicas, edited.Spec.Parameters["REPLICAS"])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package params
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/gosuri/uitable"
+	"github.com/pkg/errors"
+)
+
+// DefaultListOptions provides the default options for params list
+var DefaultListOptions = &Options{}
+
+// RunList runs the params list command
+func RunList(out io.Writer, options *Options, settings *env.Settings) error {
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return errors.Wrap(err, "creating kudo client")
+	}
+
+	return list(out, kc, options.Instance, settings.Namespace)
+}
+
+func list(out io.Writer, kc *kudo.Client, instanceName, namespace string) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return errors.Wrapf(err, "getting instance %s", instanceName)
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+	}
+
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return errors.Wrapf(err, "getting operatorversion %s", instance.Spec.OperatorVersion.Name)
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s for instance %s does not exist in namespace %s", instance.Spec.OperatorVersion.Name, instanceName, instance.OperatorVersionNamespace())
+	}
+
+	table := uitable.New()
+	table.AddRow("NAME", "VALUE", "SOURCE", "REQUIRED", "DESCRIPTION")
+	for _, v := range v1alpha1.ParameterValues(ov, instance.Spec.Parameters) {
+		required, description := "-", "not defined in operatorversion "+ov.Name
+		if v.Definition != nil {
+			required, description = strconv.FormatBool(v.Definition.Required), v.Definition.Description
+		}
+		table.AddRow(v.Name, strconv.Quote(v.Value), v.Source, required, description)
+	}
+	fmt.Fprintln(out, table)
+	return nil
+}
@@ -0,0 +1,62 @@
+package params
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestList(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Parameters: []v1alpha1.Parameter{
+				{Name: "REPLICAS", Default: util.String("1"), Description: "Number of replicas"},
+				{Name: "PASSWORD", Required: true},
+				{Name: "MEMORY", Default: util.String("1Gi")},
+			},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"REPLICAS": "3", "LEGACY": "true"},
+		},
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(ov, instance))
+
+	var out bytes.Buffer
+	if err := list(&out, kc, "test", "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	expected := [][]string{
+		{"NAME", "VALUE", "SOURCE", "REQUIRED", "DESCRIPTION"},
+		{"LEGACY", `"true"`, "instance", "-", "not defined in operatorversion test-1.0"},
+		{"MEMORY", `"1Gi"`, "default", "false"},
+		{"PASSWORD", `""`, "missing", "true"},
+		{"REPLICAS", `"3"`, "instance", "false", "Number of replicas"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines but got %q", len(expected), out.String())
+	}
+	for i, e := range expected {
+		if fields := strings.Fields(lines[i]); !strings.HasPrefix(strings.Join(fields, " "), strings.Join(e, " ")) {
+			t.Errorf("expected line %d to start with %v but got %q", i, e, lines[i])
+		}
+	}
+
+	if err := list(&out, kc, "other", "default"); err == nil {
+		t.Errorf("expected an error for a missing instance")
+	}
+}
@@ -0,0 +1,94 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/resolver"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderOptions are the configurable options for params render
+type RenderOptions struct {
+	install.RepositoryOptions
+	PackageVersion string
+	ParameterFiles []string
+	Parameters     []string
+}
+
+// DefaultRenderOptions provides the default options for params render
+var DefaultRenderOptions = &RenderOptions{}
+
+// RunRender runs the params render command
+func RunRender(out io.Writer, args []string, options *RenderOptions, fs afero.Fs, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the package or path to render the parameters for")
+	}
+
+	params, err := install.GetParameters(fs, options.ParameterFiles, options.Parameters)
+	if err != nil {
+		return fmt.Errorf("could not parse parameters: %w", err)
+	}
+
+	repository, err := repo.ClientFromSettings(fs, settings.Home, settings.RepoName(options.RepoName))
+	if err != nil {
+		return fmt.Errorf("could not build operator repository: %w", err)
+	}
+	crds, err := resolver.GetCRDs(resolver.New(fs, repository), args[0], options.PackageVersion)
+	if err != nil {
+		return fmt.Errorf("failed to resolve package CRDs for operator %s: %w", args[0], err)
+	}
+
+	return render(out, crds.OperatorVersion, params)
+}
+
+// render prints the effective parameters an instance of the OperatorVersion would be installed with
+func render(out io.Writer, ov *v1alpha1.OperatorVersion, params map[string]string) (err error) {
+	span := tracing.StartSpan("params.Render")
+	defer func() { tracing.EndSpan(span, err) }()
+
+	effective, err := effectiveParameters(ov, params)
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(effective)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(b)
+	return err
+}
+
+// effectiveParameters overlays the given parameters on the defaults of the OperatorVersion. Parameters that are not
+// defined in the OperatorVersion, missing required parameters and invalid array and object parameters are reported as
+// error.
+func effectiveParameters(ov *v1alpha1.OperatorVersion, params map[string]string) (map[string]string, error) {
+	var unknown []string
+	for _, v := range v1alpha1.ParameterValues(ov, params) {
+		if v.Definition == nil {
+			unknown = append(unknown, v.Name)
+		}
+	}
+	effective, _ := v1alpha1.EffectiveParameters(ov, params)
+
+	var errs []string
+	if len(unknown) > 0 {
+		errs = append(errs, fmt.Sprintf("parameters not defined in operatorversion %s: %s", ov.Name, strings.Join(unknown, ",")))
+	}
+	if err := v1alpha1.ValidateParameters(ov, params); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return effective, nil
+}
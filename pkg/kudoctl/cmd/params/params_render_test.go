@@ -0,0 +1,71 @@
+package params
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRender(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.0"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Parameters: []v1alpha1.Parameter{
+				{Name: "BROKER_COUNT", Default: util.String("3")},
+				{Name: "LOG_LEVEL", Default: util.String("INFO")},
+				{Name: "PASSWORD", Required: true},
+				{Name: "OPTIONAL"},
+				{Name: "LISTENERS", Type: v1alpha1.ArrayParameterType, Items: &v1alpha1.ParameterItems{Type: v1alpha1.ObjectParameterType, Required: []string{"port"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		params   map[string]string
+		expected string
+		err      string
+	}{
+		{
+			name:     "defaults are overlaid",
+			params:   map[string]string{"BROKER_COUNT": "5", "PASSWORD": "secret"},
+			expected: "BROKER_COUNT: \"5\"\nLISTENERS: \"\"\nLOG_LEVEL: INFO\nOPTIONAL: \"\"\nPASSWORD: secret\n",
+		},
+		{
+			name:   "invalid array parameter",
+			params: map[string]string{"PASSWORD": "secret", "LISTENERS": "- name: client"},
+			err:    "parameter LISTENERS is invalid: item 0 is missing required keys: port",
+		},
+		{
+			name:   "missing required parameter",
+			params: map[string]string{"BROKER_COUNT": "5"},
+			err:    "missing required parameters: PASSWORD",
+		},
+		{
+			name:   "unknown parameter",
+			params: map[string]string{"PASSWORD": "secret", "FOO": "bar"},
+			err:    "parameters not defined in operatorversion kafka-1.0: FOO",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		err := render(&out, ov, tt.params)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("%s: expected %q but got %q", tt.name, tt.expected, out.String())
+		}
+	}
+}
@@ -1,7 +1,11 @@
 package cmd
 
 import (
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/plan"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
@@ -11,23 +15,85 @@ const (
 `
 	planStatuExample = `  # View plan status
   kubectl kudo plan status --instance=<instanceName>
+
+  # View plan status including the health of the resources applied by each step
+  kubectl kudo plan status --instance=<instanceName> --verbose
+
+  # Watch the progress of the active plan until it is done
+  kubectl kudo plan status --instance=<instanceName> --watch
+
+  # Print the status as JSON, one document per refresh when watching
+  kubectl kudo plan status --instance=<instanceName> -o json
+
+  # Print the JSON schema of the JSON output
+  kubectl kudo plan status --instance=<instanceName> -o json-schema
+`
+	planListExample = `  # List the plans of an instance with their phases, steps and the parameters triggering them
+  kubectl kudo plan list --instance=<instanceName>
+
+  # Show which plan an update of parameters would trigger
+  kubectl kudo plan list --instance=<instanceName> -p MEMORY=2Gi
+`
+	planDryRunExample = `  # Show the changes the upgrade plan would make to the objects of an instance
+  kubectl kudo plan dry-run <instanceName> --name=upgrade
+`
+	planApproveExample = `  # Approve the manual step switch-traffic of the upgrade plan of an instance
+  kubectl kudo plan approve <instanceName> --name=upgrade --step=switch-traffic
+
+  # Approve the manual phase cutover of the upgrade plan of an instance
+  kubectl kudo plan approve <instanceName> --name=upgrade --phase=cutover
 `
 )
 
 // newPlanCmd creates a new command that shows the plans available for an instance
-func newPlanCmd() *cobra.Command {
+func newPlanCmd(fs afero.Fs) *cobra.Command {
 	newCmd := &cobra.Command{
 		Use:   "plan",
 		Short: "View all available plans.",
 		Long:  `The plan command has subcommands to view all available plans.`,
 	}
 
+	newCmd.AddCommand(NewPlanListCmd(fs))
 	newCmd.AddCommand(NewPlanHistoryCmd())
 	newCmd.AddCommand(NewPlanStatusCmd())
+	newCmd.AddCommand(NewPlanDryRunCmd())
+	newCmd.AddCommand(NewPlanApproveCmd())
 
 	return newCmd
 }
 
+// NewPlanListCmd creates a command that lists the plans of an instance and predicts the plan an update triggers
+func NewPlanListCmd(fs afero.Fs) *cobra.Command {
+	options := plan.DefaultListOptions
+	var parameters []string
+	var parameterFiles []string
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the plans of an instance and the plan an update of parameters would trigger.",
+		Long: `Lists the plans of the operatorversion of an instance with their phases, steps and tasks. Plans are annotated
+with the parameters triggering them, parameters without trigger trigger the update plan, or the deploy plan if there
+is no update plan.
+
+Given parameters with -p or --parameter-file, the plan an update of the instance with these parameters would trigger is
+shown, without updating the instance. Use it to avoid triggering disruptive plans by accident.`,
+		Example: planListExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			options.Parameters, err = install.GetParameters(fs, parameterFiles, parameters)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			return plan.RunList(cmd.OutOrStdout(), options, &Settings)
+		},
+	}
+
+	listCmd.Flags().StringVar(&options.Instance, "instance", "", "The instance name.")
+	listCmd.Flags().StringArrayVarP(&parameters, "parameter", "p", nil, "The parameter name and value of an update separated by '='")
+	listCmd.Flags().StringArrayVar(&parameterFiles, "parameter-file", nil, "A YAML file with parameters of an update, can be repeated. Later files are merged on top of earlier ones and -p takes precedence")
+
+	return listCmd
+}
+
 // NewPlanHistoryCmd creates a command that shows the plan history of an instance.
 func NewPlanHistoryCmd() *cobra.Command {
 	options := plan.DefaultHistoryOptions
@@ -58,6 +124,53 @@ func NewPlanStatusCmd() *cobra.Command {
 	}
 
 	statusCmd.Flags().StringVar(&options.Instance, "instance", "", "The instance name available from 'kubectl get instances'")
+	statusCmd.Flags().BoolVar(&options.Verbose, "verbose", false, "Show the health of the resources applied by each step")
+	statusCmd.Flags().BoolVarP(&options.Watch, "watch", "w", false, "Refresh the status until the active plan is not running anymore")
+	statusCmd.Flags().StringVarP(&options.Output, "output", "o", "", "Output format, json prints the status in a stable schema and json-schema prints that schema")
 
 	return statusCmd
 }
+
+// NewPlanDryRunCmd creates a command that executes a plan of an instance in dry-run mode and shows the changes it would make
+func NewPlanDryRunCmd() *cobra.Command {
+	options := plan.DefaultDryRunOptions
+	dryRunCmd := &cobra.Command{
+		Use:   "dry-run <instance>",
+		Short: "Shows the changes a plan would make to the objects of an instance.",
+		Long: `Executes a plan of an instance in dry-run mode. The manager renders the templates of the plan with the current
+parameters of the instance and sends the objects to the API server in dry-run mode, health checks are skipped and
+nothing is changed. The objects that would be created, updated or deleted are reported in the status of the instance.`,
+		Example: planDryRunExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return plan.RunDryRun(cmd.OutOrStdout(), args, options, &Settings)
+		},
+	}
+
+	dryRunCmd.Flags().StringVar(&options.Plan, "name", "", "The name of the plan to execute in dry-run mode")
+	dryRunCmd.Flags().DurationVar(&options.Timeout, "timeout", options.Timeout, "The time to wait for the report of the dry-run")
+
+	return dryRunCmd
+}
+
+// NewPlanApproveCmd creates a command that approves a manual phase or step of the active plan of an instance
+func NewPlanApproveCmd() *cobra.Command {
+	options := plan.DefaultApproveOptions
+	approveCmd := &cobra.Command{
+		Use:   "approve <instance>",
+		Short: "Approves a manual phase or step of the active plan of an instance.",
+		Long: `Approves the gate of a manual phase or step of the plan an instance is executing. Manual phases and steps are
+not started before they are approved, e.g. to let a change-management process sign off risky operations. The plan
+waits at the gate with the status PENDING. Approvals only apply to the current execution of the plan, every execution
+has to be approved again.`,
+		Example: planApproveExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return plan.RunApprove(cmd.OutOrStdout(), args, options, &Settings)
+		},
+	}
+
+	approveCmd.Flags().StringVar(&options.Plan, "name", "", "The name of the active plan")
+	approveCmd.Flags().StringVar(&options.Phase, "phase", "", "The name of the manual phase, or of the phase of the step")
+	approveCmd.Flags().StringVar(&options.Step, "step", "", "The name of the manual step")
+
+	return approveCmd
+}
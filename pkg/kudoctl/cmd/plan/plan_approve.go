@@ -0,0 +1,133 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+)
+
+// ApproveOptions are the options of the plan approve command
+type ApproveOptions struct {
+	// Plan is the name of the active plan whose gate is approved
+	Plan string
+	// Phase is the name of the manual phase, or of the phase of the step
+	Phase string
+	// Step is the name of the manual step
+	Step string
+}
+
+// DefaultApproveOptions provides the default options for plan approve
+var DefaultApproveOptions = &ApproveOptions{}
+
+// RunApprove runs the plan approve command
+func RunApprove(out io.Writer, args []string, options *ApproveOptions, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+	if options.Plan == "" {
+		return errors.New("flag Error: Please set the plan to approve, e.g. \"--name=upgrade\"")
+	}
+	if options.Phase == "" && options.Step == "" {
+		return errors.New("flag Error: Please set the phase or step to approve, e.g. \"--step=switch-traffic\"")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return fmt.Errorf("unable to create kudo client to talk to kubernetes API server: %w", err)
+	}
+	return approve(out, kc, args[0], settings.Namespace, options)
+}
+
+// approve approves the manual gate of a phase or step of the active plan of the instance
+func approve(out io.Writer, kc *kudo.Client, instanceName, namespace string, options *ApproveOptions) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return err
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s/%s does not exist", namespace, instanceName)
+	}
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return err
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s/%s of instance %s does not exist", instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name, instance.Name)
+	}
+	if _, ok := ov.Spec.Plans[options.Plan]; !ok {
+		return fmt.Errorf("plan %s does not exist in operatorversion %s", options.Plan, ov.Name)
+	}
+	// the gates of referenced plans are approved with the names of the flattened steps
+	plan, err := kudov1alpha1.ResolvePlan(ov.Spec.Plans, options.Plan)
+	if err != nil {
+		return err
+	}
+
+	phase, step, err := findGate(plan, options)
+	if err != nil {
+		return err
+	}
+	if active := instance.GetPlanInProgress(); active == nil || active.Name != options.Plan {
+		return fmt.Errorf("plan %s is not in progress on instance %s, only gates of the active plan can be approved", options.Plan, instanceName)
+	}
+
+	gate := fmt.Sprintf("phase %s", phase)
+	if step != "" {
+		gate = fmt.Sprintf("step %s of phase %s", step, phase)
+	}
+	approved, err := kc.ApproveGate(instance, options.Plan, phase, step)
+	if err != nil {
+		return fmt.Errorf("approving %s of plan %s: %w", gate, options.Plan, err)
+	}
+	if !approved {
+		fmt.Fprintf(out, "The %s of plan %s of instance %s is already approved\n", gate, options.Plan, instanceName)
+		return nil
+	}
+	fmt.Fprintf(out, "Approved %s of plan %s of instance %s\n", gate, options.Plan, instanceName)
+	return nil
+}
+
+// findGate returns the names of the manual phase or step of the plan selected by the options. A step may be given
+// without its phase if its name is unique in the plan.
+func findGate(plan kudov1alpha1.Plan, options *ApproveOptions) (phase, step string, err error) {
+	if options.Step == "" {
+		for _, ph := range plan.Phases {
+			if ph.Name != options.Phase {
+				continue
+			}
+			if !ph.Manual {
+				return "", "", fmt.Errorf("phase %s of plan %s is not manual and needs no approval", ph.Name, options.Plan)
+			}
+			return ph.Name, "", nil
+		}
+		return "", "", fmt.Errorf("phase %s does not exist in plan %s", options.Phase, options.Plan)
+	}
+
+	var phases []string
+	var found *kudov1alpha1.Step
+	for _, ph := range plan.Phases {
+		if options.Phase != "" && ph.Name != options.Phase {
+			continue
+		}
+		for i := range ph.Steps {
+			if ph.Steps[i].Name == options.Step {
+				phases = append(phases, ph.Name)
+				found = &ph.Steps[i]
+			}
+		}
+	}
+	switch {
+	case len(phases) == 0:
+		return "", "", fmt.Errorf("step %s does not exist in plan %s", options.Step, options.Plan)
+	case len(phases) > 1:
+		return "", "", fmt.Errorf("step %s exists in phases %s of plan %s, select the phase with --phase", options.Step, strings.Join(phases, ", "), options.Plan)
+	case !found.Manual:
+		return "", "", fmt.Errorf("step %s of phase %s of plan %s is not manual and needs no approval", options.Step, phases[0], options.Plan)
+	}
+	return phases[0], options.Step, nil
+}
@@ -0,0 +1,79 @@
+package plan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApprove(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{Plans: map[string]v1alpha1.Plan{
+			"deploy": {},
+			"upgrade": {Phases: []v1alpha1.Phase{
+				{Name: "main", Steps: []v1alpha1.Step{{Name: "prepare"}, {Name: "switch-traffic", Manual: true}, {Name: "verify", Manual: true}}},
+				{Name: "cleanup", Manual: true, Steps: []v1alpha1.Step{{Name: "verify", Manual: true}}},
+			}},
+		}},
+	}
+	instance := func(activePlan string) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"}},
+			Status:     v1alpha1.InstanceStatus{PlanStatus: map[string]v1alpha1.PlanStatus{activePlan: {Name: activePlan, Status: v1alpha1.ExecutionInProgress}}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		plan     string
+		options  ApproveOptions
+		approved string
+		out      string
+		err      string
+	}{
+		{name: "step", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Step: "switch-traffic"}, approved: "upgrade/main/switch-traffic",
+			out: "Approved step switch-traffic of phase main of plan upgrade of instance kafka\n"},
+		{name: "step of phase", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Phase: "cleanup", Step: "verify"}, approved: "upgrade/cleanup/verify",
+			out: "Approved step verify of phase cleanup of plan upgrade of instance kafka\n"},
+		{name: "phase", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Phase: "cleanup"}, approved: "upgrade/cleanup",
+			out: "Approved phase cleanup of plan upgrade of instance kafka\n"},
+		{name: "ambiguous step", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Step: "verify"},
+			err: "step verify exists in phases main, cleanup of plan upgrade, select the phase with --phase"},
+		{name: "step without gate", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Step: "prepare"},
+			err: "step prepare of phase main of plan upgrade is not manual and needs no approval"},
+		{name: "unknown step", plan: "upgrade", options: ApproveOptions{Plan: "upgrade", Step: "rollback"}, err: "step rollback does not exist in plan upgrade"},
+		{name: "unknown plan", plan: "upgrade", options: ApproveOptions{Plan: "backup", Step: "switch-traffic"}, err: "plan backup does not exist in operatorversion kafka-1.0"},
+		{name: "plan not active", plan: "deploy", options: ApproveOptions{Plan: "upgrade", Step: "switch-traffic"},
+			err: "plan upgrade is not in progress on instance kafka, only gates of the active plan can be approved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance(tt.plan), ov))
+			var out bytes.Buffer
+			err := approve(&out, kc, "kafka", "default", &tt.options)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, out.String())
+
+			i, err := kc.GetInstance("kafka", "default")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.approved, i.Annotations[v1alpha1.ApprovalsAnnotation])
+
+			out.Reset()
+			assert.NoError(t, approve(&out, kc, "kafka", "default", &tt.options))
+			assert.Contains(t, out.String(), "is already approved")
+		})
+	}
+}
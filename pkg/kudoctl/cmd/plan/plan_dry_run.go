@@ -0,0 +1,117 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+)
+
+// dryRunInterval is the interval the instance is polled with while waiting for the report of a dry-run
+const dryRunInterval = time.Second
+
+// DryRunOptions are the options of the plan dry-run command
+type DryRunOptions struct {
+	// Plan is the name of the plan to execute in dry-run mode
+	Plan string
+	// Timeout is the time to wait for the manager to execute the dry-run
+	Timeout time.Duration
+}
+
+// DefaultDryRunOptions provides the default options for plan dry-run
+var DefaultDryRunOptions = &DryRunOptions{Timeout: 30 * time.Second}
+
+// RunDryRun runs the plan dry-run command
+func RunDryRun(out io.Writer, args []string, options *DryRunOptions, settings *env.Settings) error {
+	if len(args) != 1 {
+		return errors.New("expecting exactly one argument - name of the instance")
+	}
+	if options.Plan == "" {
+		return errors.New("flag Error: Please set the plan to execute, e.g. \"--name=upgrade\"")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return fmt.Errorf("unable to create kudo client to talk to kubernetes API server: %w", err)
+	}
+	return dryRun(out, kc, args[0], settings.Namespace, options)
+}
+
+// dryRun requests the dry-run of the plan from the manager and prints the report once it is available
+func dryRun(out io.Writer, kc *kudo.Client, instanceName, namespace string, options *DryRunOptions) error {
+	instance, err := kc.GetInstance(instanceName, namespace)
+	if err != nil {
+		return err
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s/%s does not exist", namespace, instanceName)
+	}
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return err
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s/%s of instance %s does not exist", instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name, instance.Name)
+	}
+	if _, ok := ov.Spec.Plans[options.Plan]; !ok {
+		plans := make([]string, 0, len(ov.Spec.Plans))
+		for name := range ov.Spec.Plans {
+			plans = append(plans, name)
+		}
+		sort.Strings(plans)
+		return fmt.Errorf("plan %s does not exist in operatorversion %s, available plans: %s", options.Plan, ov.Name, strings.Join(plans, ", "))
+	}
+
+	request, err := kc.RequestDryRun(instanceName, namespace, options.Plan, nil)
+	if err != nil {
+		return fmt.Errorf("requesting dry-run of plan %s: %w", options.Plan, err)
+	}
+	report, err := kc.WaitForDryRun(instanceName, namespace, request, dryRunInterval, options.Timeout)
+	if err != nil {
+		return err
+	}
+
+	PrintDryRun(out, instanceName, report)
+	if report.Message != "" {
+		return fmt.Errorf("dry-run of plan %s failed: %s", report.Plan, report.Message)
+	}
+	return nil
+}
+
+// PrintDryRun prints the changes of the report grouped by the step and task making them
+func PrintDryRun(out io.Writer, instanceName string, report *kudov1alpha1.DryRunStatus) {
+	if len(report.Changes) == 0 {
+		fmt.Fprintf(out, "Plan %s of instance %s would not change any objects.\n", report.Plan, instanceName)
+	} else {
+		fmt.Fprintf(out, "Plan %s of instance %s would make the following changes:\n", report.Plan, instanceName)
+	}
+
+	var step, task string
+	for _, c := range report.Changes {
+		if c.Step != step || c.Task != task {
+			step, task = c.Step, c.Task
+			fmt.Fprintf(out, "  Step %s, task %s:\n", step, task)
+		}
+		fmt.Fprintf(out, "    %s %s %s\n", c.Action, c.Kind, c.Name)
+		for _, f := range c.Fields {
+			fmt.Fprintf(out, "      %s: %s -> %s\n", f.Path, orNone(f.Actual), orNone(f.Expected))
+		}
+	}
+
+	if len(report.SkippedTasks) > 0 {
+		fmt.Fprintf(out, "Tasks that can not be executed in dry-run mode were skipped: %s\n", strings.Join(report.SkippedTasks, ", "))
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,59 @@
+package plan
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPrintDryRun(t *testing.T) {
+	report := &v1alpha1.DryRunStatus{
+		Plan: "upgrade",
+		Changes: []v1alpha1.ResourceChange{
+			{Kind: "StatefulSet", Name: "kafka-broker", Step: "main.app", Task: "app", Action: v1alpha1.ResourceUpdated,
+				Fields: []v1alpha1.FieldDrift{{Path: "spec.replicas", Expected: "5", Actual: "3"}, {Path: "metadata.labels.new", Expected: `"yes"`}}},
+			{Kind: "Service", Name: "kafka-svc", Step: "main.app", Task: "app", Action: v1alpha1.ResourceCreated},
+			{Kind: "ConfigMap", Name: "kafka-old", Step: "cleanup.old", Task: "remove", Action: v1alpha1.ResourceDeleted},
+		},
+		SkippedTasks: []string{"backup"},
+	}
+
+	var out bytes.Buffer
+	PrintDryRun(&out, "kafka", report)
+	expected := `Plan upgrade of instance kafka would make the following changes:
+  Step main.app, task app:
+    Update StatefulSet kafka-broker
+      spec.replicas: 3 -> 5
+      metadata.labels.new: <none> -> "yes"
+    Create Service kafka-svc
+  Step cleanup.old, task remove:
+    Delete ConfigMap kafka-old
+Tasks that can not be executed in dry-run mode were skipped: backup
+`
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	PrintDryRun(&out, "kafka", &v1alpha1.DryRunStatus{Plan: "deploy"})
+	assert.Equal(t, "Plan deploy of instance kafka would not change any objects.\n", out.String())
+}
+
+func TestDryRun_UnknownPlan(t *testing.T) {
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"}},
+	}
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.0", Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Plans: map[string]v1alpha1.Plan{"deploy": {}, "update": {}}},
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance, ov))
+
+	err := dryRun(&bytes.Buffer{}, kc, "kafka", "default", &DryRunOptions{Plan: "upgrade"})
+	assert.EqualError(t, err, "plan upgrade does not exist in operatorversion kafka-1.0, available plans: deploy, update")
+}
@@ -12,6 +12,12 @@ import (
 // Options are the configurable options for plans
 type Options struct {
 	Instance string
+	// Verbose shows the health of the resources applied by each step in plan status
+	Verbose bool
+	// Watch refreshes plan status until the plan is not running anymore
+	Watch bool
+	// Output is the format of plan status, OutputJSON or OutputJSONSchema, the tree is printed if it is empty
+	Output string
 }
 
 var (
@@ -36,10 +42,9 @@ func RunHistory(cmd *cobra.Command, options *Options, settings *env.Settings) er
 func planHistory(options *Options, settings *env.Settings) error {
 	namespace := settings.Namespace
 
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	if err != nil {
-		fmt.Printf("Unable to create kudo client to talk to kubernetes API server %w", err)
-		return err
+		return fmt.Errorf("unable to create kudo client to talk to kubernetes API server: %w", err)
 	}
 	instance, err := kc.GetInstance(options.Instance, namespace)
 	if err != nil {
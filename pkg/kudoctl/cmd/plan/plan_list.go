@@ -0,0 +1,159 @@
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/xlab/treeprint"
+)
+
+// ListOptions are the options of the plan list command
+type ListOptions struct {
+	Instance string
+	// Parameters are the parameters of an update to predict the triggered plan for
+	Parameters map[string]string
+}
+
+// DefaultListOptions provides the default options for plan list
+var DefaultListOptions = &ListOptions{}
+
+// RunList runs the plan list command
+func RunList(out io.Writer, options *ListOptions, settings *env.Settings) error {
+	if options.Instance == "" {
+		return errors.New("flag Error: Please set instance flag, e.g. \"--instance=<instanceName>\"")
+	}
+
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return fmt.Errorf("unable to create kudo client to talk to kubernetes API server: %w", err)
+	}
+	return planList(out, kc, settings.Namespace, options)
+}
+
+// planList prints the plans of the operatorversion of the instance and the plan an update of the parameters would
+// trigger
+func planList(out io.Writer, kc *kudo.Client, namespace string, options *ListOptions) error {
+	instance, err := kc.GetInstance(options.Instance, namespace)
+	if err != nil {
+		return err
+	}
+	if instance == nil {
+		return fmt.Errorf("instance %s/%s does not exist", namespace, options.Instance)
+	}
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return err
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s/%s of instance %s does not exist", instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name, instance.Name)
+	}
+
+	fmt.Fprintf(out, "Plans of instance %s (operatorversion %s):\n", instance.Name, ov.Name)
+	fmt.Fprintln(out, listTree(ov))
+	if len(options.Parameters) > 0 {
+		fmt.Fprintln(out, predictPlan(ov, instance.Spec.Parameters, options.Parameters))
+	}
+	return nil
+}
+
+// listTree renders the plans of the operatorversion with their phases, steps and tasks. Plans are annotated with the
+// parameters triggering them.
+func listTree(ov *kudov1alpha1.OperatorVersion) string {
+	triggers := map[string][]string{}
+	for _, p := range ov.Spec.Parameters {
+		if p.Trigger != "" {
+			triggers[p.Trigger] = append(triggers[p.Trigger], p.Name)
+		}
+	}
+	defaultPlan := kudov1alpha1.TriggeredPlan(ov, nil, nil)
+
+	names := make([]string, 0, len(ov.Spec.Plans))
+	for name := range ov.Spec.Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tree := treeprint.New()
+	for _, name := range names {
+		plan := ov.Spec.Plans[name]
+		planDisplay := fmt.Sprintf("Plan %s (%s strategy)", name, plan.Strategy)
+		var triggeredBy []string
+		if params, ok := triggers[name]; ok {
+			triggeredBy = append(triggeredBy, strings.Join(params, ", "))
+		}
+		if defaultPlan != nil && *defaultPlan == name {
+			triggeredBy = append(triggeredBy, "parameters without trigger")
+		}
+		if len(triggeredBy) > 0 {
+			planDisplay += fmt.Sprintf(" triggered by %s", strings.Join(triggeredBy, " and "))
+		}
+		planBranch := tree.AddBranch(planDisplay)
+		for _, phase := range plan.Phases {
+			phaseDisplay := fmt.Sprintf("Phase %s (%s strategy)", phase.Name, phase.Strategy)
+			if phase.Manual {
+				phaseDisplay += " (manual)"
+			}
+			phaseBranch := planBranch.AddBranch(phaseDisplay)
+			for _, step := range phase.Steps {
+				stepDisplay := fmt.Sprintf("Step %s: %s", step.Name, strings.Join(step.Tasks, ", "))
+				if step.Plan != "" {
+					stepDisplay = fmt.Sprintf("Step %s: plan %s", step.Name, step.Plan)
+				}
+				if step.Delete {
+					stepDisplay += " (delete)"
+				}
+				if step.Manual {
+					stepDisplay += " (manual)"
+				}
+				phaseBranch.AddNode(stepDisplay)
+			}
+		}
+	}
+	return tree.String()
+}
+
+// predictPlan describes the plan that is triggered when the parameters are set on an instance with the current
+// parameters
+func predictPlan(ov *kudov1alpha1.OperatorVersion, current, parameters map[string]string) string {
+	updated := make(map[string]string, len(current)+len(parameters))
+	for k, v := range current {
+		updated[k] = v
+	}
+	for k, v := range parameters {
+		updated[k] = v
+	}
+	if reflect.DeepEqual(current, updated) {
+		return "The parameters are already set on the instance, the update would not trigger a plan."
+	}
+
+	plan := kudov1alpha1.TriggeredPlan(ov, current, updated)
+	if plan == nil {
+		return "The update would fail: the operatorversion has neither a deploy nor an update plan."
+	}
+	message := fmt.Sprintf("The update would trigger plan %s.", *plan)
+
+	// parameters whose trigger loses against the trigger of another parameter
+	var ignored []string
+	for _, p := range ov.Spec.Parameters {
+		if v, ok := updated[p.Name]; !ok || current[p.Name] == v {
+			continue
+		}
+		if p.Trigger != "" && p.Trigger != *plan {
+			if _, ok := ov.Spec.Plans[p.Trigger]; ok {
+				ignored = append(ignored, fmt.Sprintf("%s (plan %s)", p.Name, p.Trigger))
+			}
+		}
+	}
+	if len(ignored) > 0 {
+		message += fmt.Sprintf("\nWARNING: the plans triggered by %s are not executed, update these parameters separately.", strings.Join(ignored, ", "))
+	}
+	return message
+}
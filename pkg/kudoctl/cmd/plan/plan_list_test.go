@@ -0,0 +1,79 @@
+package plan
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlanList(t *testing.T) {
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"},
+			Parameters:      map[string]string{"BROKERS": "3", "MEMORY": "1Gi"},
+		},
+	}
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Parameters: []v1alpha1.Parameter{
+				{Name: "BROKERS"},
+				{Name: "MEMORY", Trigger: "restart"},
+				{Name: "LOG_LEVEL", Trigger: "reload"},
+			},
+			Plans: map[string]v1alpha1.Plan{
+				"deploy":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "app", Tasks: []string{"config", "app"}}, {Name: "restart", Plan: "restart"}}}}},
+				"restart": {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "pods", Tasks: []string{"pods"}, Delete: true}}}}},
+				"reload":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "parallel", Steps: []v1alpha1.Step{{Name: "config", Tasks: []string{"config"}}}}}},
+			},
+		},
+	}
+	kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(instance, ov))
+
+	var out bytes.Buffer
+	assert.NoError(t, planList(&out, kc, "default", &ListOptions{Instance: "kafka"}))
+	expected := `Plans of instance kafka (operatorversion kafka-1.0):
+.
+├── Plan deploy (serial strategy) triggered by parameters without trigger
+│   └── Phase main (serial strategy)
+│       ├── Step app: config, app
+│       └── Step restart: plan restart
+├── Plan reload (serial strategy) triggered by LOG_LEVEL
+│   └── Phase main (parallel strategy)
+│       └── Step config: config
+└── Plan restart (serial strategy) triggered by MEMORY
+    └── Phase main (serial strategy)
+        └── Step pods: pods (delete)
+
+`
+	// treeprint indents with non-breaking spaces
+	assert.Equal(t, expected, strings.ReplaceAll(out.String(), "\u00a0", " "))
+
+	tests := []struct {
+		params   map[string]string
+		expected string
+	}{
+		{map[string]string{"BROKERS": "3"}, "The parameters are already set on the instance, the update would not trigger a plan."},
+		{map[string]string{"BROKERS": "5"}, "The update would trigger plan deploy."},
+		{map[string]string{"BROKERS": "5", "MEMORY": "2Gi"}, "The update would trigger plan restart."},
+		{
+			map[string]string{"MEMORY": "2Gi", "LOG_LEVEL": "debug"},
+			"The update would trigger plan restart.\nWARNING: the plans triggered by LOG_LEVEL (plan reload) are not executed, update these parameters separately.",
+		},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, predictPlan(ov, instance.Spec.Parameters, tt.params))
+	}
+
+	err := planList(&out, kc, "default", &ListOptions{Instance: "zookeeper"})
+	assert.EqualError(t, err, "instance default/zookeeper does not exist")
+}
@@ -1,18 +1,23 @@
 package plan
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"sort"
+	"time"
 
 	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	"github.com/spf13/cobra"
 	"github.com/xlab/treeprint"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// watchInterval is the interval the plan status is refreshed with when watching
+	watchInterval = 2 * time.Second
+	// clearScreen moves the cursor of the terminal to the top left corner and clears the screen
+	clearScreen = "\033[H\033[2J"
 )
 
 // DefaultStatusOptions provides the default options for plan status
@@ -25,121 +30,193 @@ func RunStatus(cmd *cobra.Command, options *Options, settings *env.Settings) err
 	if err != nil || instanceFlag == "" {
 		return fmt.Errorf("flag Error: Please set instance flag, e.g. \"--instance=<instanceName>\"")
 	}
+	switch options.Output {
+	case "", OutputJSON:
+	case OutputJSONSchema:
+		fmt.Fprint(cmd.OutOrStdout(), StatusJSONSchema)
+		return nil
+	default:
+		return fmt.Errorf("flag Error: unsupported output format %q, use %s or %s", options.Output, OutputJSON, OutputJSONSchema)
+	}
 
-	err = planStatus(options, settings)
+	err = planStatus(cmd.OutOrStdout(), options, settings)
 	if err != nil {
 		return fmt.Errorf("client Error: %v", err)
 	}
 	return nil
 }
 
-func planStatus(options *Options, settings *env.Settings) error {
+// planStatus prints the status of the plans of the instance. When watching, the status is refreshed until the last
+// executed plan is not running anymore. The JSON output prints one document per refresh instead of clearing the screen.
+func planStatus(out io.Writer, options *Options, settings *env.Settings) error {
 	namespace := settings.Namespace
 
-	tree := treeprint.New()
-
-	config, err := clientcmd.BuildConfigFromFlags("", settings.KubeConfig)
-	if err != nil {
-		return err
-	}
-
-	//  Create a Dynamic Client to interface with CRDs.
-	dynamicClient, err := dynamic.NewForConfig(config)
-	if err != nil {
-		return err
-	}
-
-	instancesGVR := schema.GroupVersionResource{
-		Group:    "kudo.dev",
-		Version:  "v1alpha1",
-		Resource: "instances",
-	}
-
-	instObj, err := dynamicClient.Resource(instancesGVR).Namespace(namespace).Get(options.Instance, metav1.GetOptions{})
-	if err != nil {
-		return err
-	}
-
-	mInstObj, err := instObj.MarshalJSON()
-	if err != nil {
-		return err
-	}
-
-	instance := kudov1alpha1.Instance{}
-
-	err = json.Unmarshal(mInstObj, &instance)
-	if err != nil {
-		return err
-	}
-
-	operatorVersionNameOfInstance := instance.Spec.OperatorVersion.Name
-
-	operatorGVR := schema.GroupVersionResource{
-		Group:    "kudo.dev",
-		Version:  "v1alpha1",
-		Resource: "operatorversions",
-	}
-
-	//  List all of the Virtual Services.
-	operatorObj, err := dynamicClient.Resource(operatorGVR).Namespace(namespace).Get(operatorVersionNameOfInstance, metav1.GetOptions{})
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to create kudo client to talk to kubernetes API server: %w", err)
 	}
 
-	mOperatorObj, err := operatorObj.MarshalJSON()
-	if err != nil {
-		return err
-	}
+	for {
+		instance, err := kc.GetInstance(options.Instance, namespace)
+		if err != nil {
+			return err
+		}
+		if instance == nil {
+			return fmt.Errorf("instance %s/%s does not exist", namespace, options.Instance)
+		}
+		ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+		if err != nil {
+			return err
+		}
+		if ov == nil {
+			return fmt.Errorf("operatorversion %s/%s of instance %s does not exist", instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name, instance.Name)
+		}
 
-	operator := kudov1alpha1.OperatorVersion{}
+		lastPlanStatus := instance.GetLastExecutedPlanStatus()
+		if options.Watch && options.Output != OutputJSON {
+			fmt.Fprint(out, clearScreen)
+		}
+		switch {
+		case options.Output == OutputJSON:
+			if err := printStatusJSON(out, instance, ov); err != nil {
+				return err
+			}
+		case lastPlanStatus == nil:
+			fmt.Fprintf(out, "No plan ever run for instance - nothing to show for instance %s\n", instance.Name)
+		default:
+			fmt.Fprintf(out, "Plan(s) for \"%s\" in namespace \"%s\":\n", instance.Name, namespace)
+			fmt.Fprintln(out, statusTree(instance, ov, lastPlanStatus, options.Verbose, time.Now()))
+		}
 
-	err = json.Unmarshal(mOperatorObj, &operator)
-	if err != nil {
-		return err
+		if !options.Watch || (lastPlanStatus != nil && !lastPlanStatus.Status.IsRunning()) {
+			return nil
+		}
+		time.Sleep(watchInterval)
 	}
+}
 
-	lastPlanStatus := instance.GetLastExecutedPlanStatus()
-
-	if lastPlanStatus == nil {
-		log.Printf("No plan ever run for instance - nothing to show for instance %s\n", instance.Name)
-		return nil
-	}
+// statusTree renders the plans of the operator version as a tree of phases and steps. The phases and steps of the
+// last executed plan show their status, the step that is currently executed is highlighted.
+func statusTree(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion, lastPlanStatus *kudov1alpha1.PlanStatus, verbose bool, now time.Time) string {
+	tree := treeprint.New()
 
 	rootDisplay := fmt.Sprintf("%s (Operator-Version: \"%s\" Active-Plan: \"%s\")", instance.Name, instance.Spec.OperatorVersion.Name, lastPlanStatus.Name)
 	rootBranchName := tree.AddBranch(rootDisplay)
 
-	for name, plan := range operator.Spec.Plans {
+	names := make([]string, 0, len(ov.Spec.Plans))
+	for name := range ov.Spec.Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		plan := resolvedPlan(ov, name)
 		if name == lastPlanStatus.Name {
-			planDisplay := fmt.Sprintf("Plan %s (%s strategy) [%s]", name, plan.Strategy, lastPlanStatus.Status)
+			planDisplay := fmt.Sprintf("%s Plan %s (%s strategy) [%s]%s%s", glyph(lastPlanStatus.Status), name, plan.Strategy, lastPlanStatus.Status,
+				planDuration(lastPlanStatus, now), statusMessage(lastPlanStatus.Message))
 			planBranchName := rootBranchName.AddBranch(planDisplay)
 			for _, phase := range lastPlanStatus.Phases {
-				phaseDisplay := fmt.Sprintf("Phase %s [%s]", phase.Name, phase.Status)
+				phaseDisplay := fmt.Sprintf("%s Phase %s [%s]%s%s", glyph(phase.Status), phase.Name, phase.Status,
+					runningFor(phase.Status, phase.StartedAt.Time, now), statusMessage(phase.Message))
 				phaseBranchName := planBranchName.AddBranch(phaseDisplay)
-				for _, steps := range phase.Steps {
-					stepsDisplay := fmt.Sprintf("Step %s (%s)", steps.Name, steps.Status)
-					phaseBranchName.AddBranch(stepsDisplay)
+				for _, step := range phase.Steps {
+					stepDisplay := fmt.Sprintf("%s Step %s [%s]%s%s", glyph(step.Status), step.Name, step.Status,
+						runningFor(step.Status, step.StartedAt.Time, now), statusMessage(step.Message))
+					if step.Status == kudov1alpha1.ExecutionInProgress {
+						stepDisplay += " <- executing"
+					}
+					stepBranchName := phaseBranchName.AddBranch(stepDisplay)
+					if verbose {
+						addResources(stepBranchName, step.Resources)
+					}
+					// warnings are shown regardless of verbosity, e.g. deprecated APIs break the operator once removed
+					for _, w := range step.Warnings {
+						stepBranchName.AddNode("Warning: " + w)
+					}
 				}
 			}
 		} else {
-			planDisplay := fmt.Sprintf("Plan %s (%s strategy) [NOT ACTIVE]", name, plan.Strategy)
+			planDisplay := fmt.Sprintf("%s Plan %s (%s strategy) [NOT ACTIVE]", glyph(kudov1alpha1.ExecutionNeverRun), name, plan.Strategy)
 			planBranchName := rootBranchName.AddBranch(planDisplay)
 			for _, phase := range plan.Phases {
-				phaseDisplay := fmt.Sprintf("Phase %s (%s strategy) [NOT ACTIVE]", phase.Name, phase.Strategy)
+				phaseDisplay := fmt.Sprintf("%s Phase %s (%s strategy) [NOT ACTIVE]", glyph(kudov1alpha1.ExecutionNeverRun), phase.Name, phase.Strategy)
 				phaseBranchName := planBranchName.AddBranch(phaseDisplay)
-				for _, steps := range plan.Phases {
-					stepsDisplay := fmt.Sprintf("Step %s (%s strategy) [NOT ACTIVE]", steps.Name, steps.Strategy)
-					stepBranchName := phaseBranchName.AddBranch(stepsDisplay)
-					for _, step := range steps.Steps {
-						stepDisplay := fmt.Sprintf("%s [NOT ACTIVE]", step.Name)
-						stepBranchName.AddBranch(stepDisplay)
-					}
+				for _, step := range phase.Steps {
+					stepDisplay := fmt.Sprintf("%s Step %s [NOT ACTIVE]", glyph(kudov1alpha1.ExecutionNeverRun), step.Name)
+					phaseBranchName.AddBranch(stepDisplay)
 				}
 			}
 		}
 	}
 
-	fmt.Printf("Plan(s) for \"%s\" in namespace \"%s\":\n", instance.Name, namespace)
-	fmt.Println(tree.String())
+	return tree.String()
+}
 
-	return nil
+// resolvedPlan returns the plan with the steps of the plans it references, so that it is shown like it is executed. A
+// plan with invalid references is returned as declared.
+func resolvedPlan(ov *kudov1alpha1.OperatorVersion, name string) kudov1alpha1.Plan {
+	plan, err := kudov1alpha1.ResolvePlan(ov.Spec.Plans, name)
+	if err != nil {
+		return ov.Spec.Plans[name]
+	}
+	return plan
+}
+
+// glyph returns the symbol the status is shown with
+func glyph(status kudov1alpha1.ExecutionStatus) string {
+	switch status {
+	case kudov1alpha1.ExecutionComplete:
+		return "✓"
+	case kudov1alpha1.ExecutionInProgress:
+		return "▶"
+	case kudov1alpha1.ExecutionPending:
+		return "○"
+	case kudov1alpha1.ErrorStatus:
+		return "!"
+	case kudov1alpha1.ExecutionFatalError:
+		return "✗"
+	default:
+		return "-"
+	}
+}
+
+// planDuration formats the duration of the last run of the plan, or how long it has been running
+func planDuration(status *kudov1alpha1.PlanStatus, now time.Time) string {
+	if status.StartedAt.IsZero() {
+		return ""
+	}
+	if status.Status.IsRunning() {
+		return runningFor(status.Status, status.StartedAt.Time, now)
+	}
+	if status.LastFinishedRun.Before(&status.StartedAt) {
+		return ""
+	}
+	return fmt.Sprintf(" took %s", status.LastFinishedRun.Sub(status.StartedAt.Time).Round(time.Second))
+}
+
+// runningFor formats how long a plan, phase or step has been running. It is empty for statuses that are not running.
+func runningFor(status kudov1alpha1.ExecutionStatus, startedAt time.Time, now time.Time) string {
+	if !status.IsRunning() || status == kudov1alpha1.ExecutionPending || startedAt.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" running for %s", now.Sub(startedAt).Round(time.Second))
+}
+
+// addResources adds the resources applied by a step with their health to the branch of the step
+func addResources(branch treeprint.Tree, resources []kudov1alpha1.ResourceStatus) {
+	for _, r := range resources {
+		name := r.Name
+		if r.Namespace != "" {
+			name = fmt.Sprintf("%s/%s", r.Namespace, r.Name)
+		}
+		branch.AddNode(fmt.Sprintf("%s %s [%s]%s", r.Kind, name, r.Health, statusMessage(r.Message)))
+	}
+}
+
+// statusMessage formats the message of a plan, phase or step status to be appended to its status
+func statusMessage(message string) string {
+	if message == "" {
+		return ""
+	}
+	return ": " + message
 }
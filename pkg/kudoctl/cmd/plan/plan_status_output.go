@@ -0,0 +1,251 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	kudov1alpha1 "github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+)
+
+// Output formats of plan status besides the tree
+const (
+	// OutputJSON prints the status in the JSON schema StatusSchemaVersion
+	OutputJSON = "json"
+	// OutputJSONSchema prints the JSON schema of OutputJSON
+	OutputJSONSchema = "json-schema"
+)
+
+// StatusSchemaVersion identifies the schema of the JSON output of plan status. Tooling can depend on the schema: fields
+// are only ever added to a schema version, removing or changing a field requires a new version. The schema is
+// published in StatusJSONSchema and config/schemas/plan-status.v1.json.
+const StatusSchemaVersion = "kudo.dev/plan-status/v1"
+
+// StatusOutput is the JSON output of plan status. Its fields must not change, see StatusSchemaVersion.
+type StatusOutput struct {
+	SchemaVersion   string `json:"schemaVersion"`
+	Instance        string `json:"instance"`
+	Namespace       string `json:"namespace"`
+	OperatorVersion string `json:"operatorVersion"`
+	// LastPlan is the plan that was executed last, it is empty if no plan ever ran
+	LastPlan string `json:"lastPlan,omitempty"`
+	// Plans are all plans of the operatorversion sorted by name
+	Plans []PlanOutput `json:"plans"`
+}
+
+// PlanOutput is the status of the last run of a plan, plans that never ran have the status NEVER_RUN
+type PlanOutput struct {
+	Name     string `json:"name"`
+	Strategy string `json:"strategy"`
+	Status   string `json:"status"`
+	Message  string `json:"message,omitempty"`
+	// StartedAt is the start of the last run in RFC 3339 format
+	StartedAt string `json:"startedAt,omitempty"`
+	// FinishedAt is the end of the last run in RFC 3339 format, it is not set while the plan is running
+	FinishedAt string        `json:"finishedAt,omitempty"`
+	Phases     []PhaseOutput `json:"phases"`
+}
+
+// PhaseOutput is the status of a phase in the last run of its plan
+type PhaseOutput struct {
+	Name      string       `json:"name"`
+	Strategy  string       `json:"strategy"`
+	Status    string       `json:"status"`
+	Message   string       `json:"message,omitempty"`
+	StartedAt string       `json:"startedAt,omitempty"`
+	Steps     []StepOutput `json:"steps"`
+}
+
+// StepOutput is the status of a step in the last run of its plan
+type StepOutput struct {
+	Name      string           `json:"name"`
+	Status    string           `json:"status"`
+	Message   string           `json:"message,omitempty"`
+	StartedAt string           `json:"startedAt,omitempty"`
+	Resources []ResourceOutput `json:"resources,omitempty"`
+	Warnings  []string         `json:"warnings,omitempty"`
+}
+
+// ResourceOutput is the health of an object applied by a step
+type ResourceOutput struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Health     string `json:"health,omitempty"`
+	Message    string `json:"message,omitempty"`
+}
+
+// StatusJSONSchema is the JSON schema of StatusOutput
+const StatusJSONSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "kudo.dev/plan-status/v1",
+  "title": "KUDO plan status",
+  "description": "The status of the plans of an instance as printed by 'kubectl kudo plan status -o json'. Fields are only added to this version of the schema. Statuses are IN_PROGRESS, PENDING, COMPLETE, ERROR, FATAL_ERROR and NEVER_RUN, new statuses may be added.",
+  "type": "object",
+  "required": ["schemaVersion", "instance", "namespace", "operatorVersion", "plans"],
+  "properties": {
+    "schemaVersion": {"type": "string", "const": "kudo.dev/plan-status/v1"},
+    "instance": {"type": "string"},
+    "namespace": {"type": "string"},
+    "operatorVersion": {"type": "string"},
+    "lastPlan": {"type": "string", "description": "The plan that was executed last, missing if no plan ever ran."},
+    "plans": {"type": "array", "items": {"$ref": "#/definitions/plan"}}
+  },
+  "definitions": {
+    "plan": {
+      "type": "object",
+      "required": ["name", "strategy", "status", "phases"],
+      "properties": {
+        "name": {"type": "string"},
+        "strategy": {"type": "string"},
+        "status": {"type": "string"},
+        "message": {"type": "string"},
+        "startedAt": {"type": "string", "format": "date-time"},
+        "finishedAt": {"type": "string", "format": "date-time", "description": "Missing while the plan is running."},
+        "phases": {"type": "array", "items": {"$ref": "#/definitions/phase"}}
+      }
+    },
+    "phase": {
+      "type": "object",
+      "required": ["name", "strategy", "status", "steps"],
+      "properties": {
+        "name": {"type": "string"},
+        "strategy": {"type": "string"},
+        "status": {"type": "string"},
+        "message": {"type": "string"},
+        "startedAt": {"type": "string", "format": "date-time"},
+        "steps": {"type": "array", "items": {"$ref": "#/definitions/step"}}
+      }
+    },
+    "step": {
+      "type": "object",
+      "required": ["name", "status"],
+      "properties": {
+        "name": {"type": "string"},
+        "status": {"type": "string"},
+        "message": {"type": "string"},
+        "startedAt": {"type": "string", "format": "date-time"},
+        "resources": {"type": "array", "items": {"$ref": "#/definitions/resource"}},
+        "warnings": {"type": "array", "items": {"type": "string"}}
+      }
+    },
+    "resource": {
+      "type": "object",
+      "required": ["kind", "name"],
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "namespace": {"type": "string"},
+        "name": {"type": "string"},
+        "health": {"type": "string"},
+        "message": {"type": "string"}
+      }
+    }
+  }
+}
+`
+
+// statusOutput returns the status of all plans of the operatorversion on the instance
+func statusOutput(instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion) *StatusOutput {
+	out := &StatusOutput{
+		SchemaVersion:   StatusSchemaVersion,
+		Instance:        instance.Name,
+		Namespace:       instance.Namespace,
+		OperatorVersion: instance.Spec.OperatorVersion.Name,
+		Plans:           []PlanOutput{},
+	}
+	if last := instance.GetLastExecutedPlanStatus(); last != nil {
+		out.LastPlan = last.Name
+	}
+
+	names := make([]string, 0, len(ov.Spec.Plans))
+	for name := range ov.Spec.Plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.Plans = append(out.Plans, planOutput(name, resolvedPlan(ov, name), instance.Status.PlanStatus[name]))
+	}
+	return out
+}
+
+// planOutput returns the status of the last run of the plan, the phases and steps of the plan are reported as never
+// run if the plan never ran
+func planOutput(name string, plan kudov1alpha1.Plan, status kudov1alpha1.PlanStatus) PlanOutput {
+	out := PlanOutput{Name: name, Strategy: string(plan.Strategy), Status: string(kudov1alpha1.ExecutionNeverRun), Phases: []PhaseOutput{}}
+	if status.Status == "" || status.Status == kudov1alpha1.ExecutionNeverRun {
+		for _, ph := range plan.Phases {
+			phase := PhaseOutput{Name: ph.Name, Strategy: string(ph.Strategy), Status: string(kudov1alpha1.ExecutionNeverRun), Steps: []StepOutput{}}
+			for _, st := range ph.Steps {
+				phase.Steps = append(phase.Steps, StepOutput{Name: st.Name, Status: string(kudov1alpha1.ExecutionNeverRun)})
+			}
+			out.Phases = append(out.Phases, phase)
+		}
+		return out
+	}
+
+	out.Status = string(status.Status)
+	out.Message = status.Message
+	out.StartedAt = timestamp(status.StartedAt.Time)
+	if !status.Status.IsRunning() && !status.StartedAt.IsZero() && !status.LastFinishedRun.Before(&status.StartedAt) {
+		out.FinishedAt = timestamp(status.LastFinishedRun.Time)
+	}
+	strategies := map[string]kudov1alpha1.Ordering{}
+	for _, ph := range plan.Phases {
+		strategies[ph.Name] = ph.Strategy
+	}
+	for _, ph := range status.Phases {
+		phase := PhaseOutput{
+			Name:      ph.Name,
+			Strategy:  string(strategies[ph.Name]),
+			Status:    string(ph.Status),
+			Message:   ph.Message,
+			StartedAt: timestamp(ph.StartedAt.Time),
+			Steps:     []StepOutput{},
+		}
+		for _, st := range ph.Steps {
+			step := StepOutput{
+				Name:      st.Name,
+				Status:    string(st.Status),
+				Message:   st.Message,
+				StartedAt: timestamp(st.StartedAt.Time),
+				Warnings:  st.Warnings,
+			}
+			for _, r := range st.Resources {
+				step.Resources = append(step.Resources, ResourceOutput{
+					APIVersion: r.APIVersion,
+					Kind:       r.Kind,
+					Namespace:  r.Namespace,
+					Name:       r.Name,
+					Health:     string(r.Health),
+					Message:    r.Message,
+				})
+			}
+			phase.Steps = append(phase.Steps, step)
+		}
+		out.Phases = append(out.Phases, phase)
+	}
+	return out
+}
+
+// timestamp formats the time in RFC 3339 format in UTC, it is empty for the zero time
+func timestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// printStatusJSON prints the status of the plans of the instance as a single line of JSON, so that the refreshed
+// statuses of --watch can be consumed line by line
+func printStatusJSON(out io.Writer, instance *kudov1alpha1.Instance, ov *kudov1alpha1.OperatorVersion) error {
+	b, err := json.Marshal(statusOutput(instance, ov))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(b))
+	return nil
+}
@@ -0,0 +1,151 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var updateGolden = flag.Bool("update", false, "update .golden files")
+
+func TestPrintStatusJSON(t *testing.T) {
+	at := func(min int) metav1.Time {
+		return metav1.NewTime(time.Date(2019, 10, 25, 12, min, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	}
+
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"}},
+		Status: v1alpha1.InstanceStatus{
+			PlanStatus: map[string]v1alpha1.PlanStatus{
+				"deploy": {
+					Name:            "deploy",
+					Status:          v1alpha1.ExecutionComplete,
+					StartedAt:       at(0),
+					LastFinishedRun: at(5),
+					Phases: []v1alpha1.PhaseStatus{{
+						Name: "main", Status: v1alpha1.ExecutionComplete, StartedAt: at(0),
+						Steps: []v1alpha1.StepStatus{{Name: "app", Status: v1alpha1.ExecutionComplete, StartedAt: at(0)}},
+					}},
+				},
+				"update": {
+					Name:            "update",
+					Status:          v1alpha1.ExecutionInProgress,
+					Message:         "waiting for the statefulset",
+					StartedAt:       at(10),
+					LastFinishedRun: at(5),
+					Phases: []v1alpha1.PhaseStatus{{
+						Name: "main", Status: v1alpha1.ExecutionInProgress, StartedAt: at(10),
+						Steps: []v1alpha1.StepStatus{{
+							Name: "app", Status: v1alpha1.ExecutionInProgress, StartedAt: at(10),
+							Resources: []v1alpha1.ResourceStatus{
+								{APIVersion: "apps/v1", Kind: "StatefulSet", Namespace: "default", Name: "kafka", Health: v1alpha1.ResourceProgressing, Message: "1 of 3 replicas ready"},
+							},
+							Warnings: []string{"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	ov := &v1alpha1.OperatorVersion{
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{
+				"deploy":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "app"}}}}},
+				"update":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "parallel", Steps: []v1alpha1.Step{{Name: "app"}}}}},
+				"upgrade": {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "migrate", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "schema"}}}}},
+			},
+		},
+	}
+	var out bytes.Buffer
+	assert.NoError(t, printStatusJSON(&out, instance, ov))
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"), "the status is a single line")
+
+	// the golden file is indented to be readable, the output is compared as JSON
+	var indented bytes.Buffer
+	assert.NoError(t, json.Indent(&indented, out.Bytes(), "", "  "))
+	gp := filepath.Join("testdata", "plan-status.json.golden")
+	if *updateGolden {
+		t.Logf("updating golden file %s", gp)
+		if err := ioutil.WriteFile(gp, indented.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %s", err)
+		}
+	}
+	golden, err := ioutil.ReadFile(gp)
+	if err != nil {
+		t.Fatalf("failed reading .golden: %s", err)
+	}
+	assert.JSONEq(t, string(golden), out.String(), "the JSON output changed, any change must be compatible with %s", StatusSchemaVersion)
+}
+
+// schemaObject is the part of a JSON schema object definition the compatibility is checked with
+type schemaObject struct {
+	Required   []string               `json:"required"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// TestStatusJSONSchema fails when the JSON fields of the output types and the published schema diverge. Changing a
+// field of the schema requires a new StatusSchemaVersion, fields may only be added.
+func TestStatusJSONSchema(t *testing.T) {
+	var schema struct {
+		schemaObject
+		Definitions map[string]schemaObject `json:"definitions"`
+	}
+	if err := json.Unmarshal([]byte(StatusJSONSchema), &schema); err != nil {
+		t.Fatalf("the schema is not valid JSON: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		typ  interface{}
+		def  schemaObject
+	}{
+		{"status", StatusOutput{}, schema.schemaObject},
+		{"plan", PlanOutput{}, schema.Definitions["plan"]},
+		{"phase", PhaseOutput{}, schema.Definitions["phase"]},
+		{"step", StepOutput{}, schema.Definitions["step"]},
+		{"resource", ResourceOutput{}, schema.Definitions["resource"]},
+	}
+	for _, tt := range tests {
+		var properties, required []string
+		rt := reflect.TypeOf(tt.typ)
+		for i := 0; i < rt.NumField(); i++ {
+			tag := strings.Split(rt.Field(i).Tag.Get("json"), ",")
+			properties = append(properties, tag[0])
+			if len(tag) == 1 {
+				required = append(required, tag[0])
+			}
+		}
+
+		var schemaProperties []string
+		for p := range tt.def.Properties {
+			schemaProperties = append(schemaProperties, p)
+		}
+		sort.Strings(properties)
+		sort.Strings(required)
+		sort.Strings(schemaProperties)
+		sort.Strings(tt.def.Required)
+		assert.Equal(t, schemaProperties, properties, "properties of %s", tt.name)
+		assert.Equal(t, tt.def.Required, required, "required properties of %s", tt.name)
+	}
+}
+
+func TestStatusJSONSchema_Published(t *testing.T) {
+	published, err := ioutil.ReadFile(filepath.Join("..", "..", "..", "..", "config", "schemas", "plan-status.v1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, StatusJSONSchema, string(published), "the published schema differs from StatusJSONSchema")
+}
@@ -0,0 +1,67 @@
+package plan
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStatusTree(t *testing.T) {
+	now := time.Date(2019, 10, 25, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) metav1.Time { return metav1.NewTime(now.Add(-d)) }
+
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"}},
+	}
+	ov := &v1alpha1.OperatorVersion{
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{
+				"deploy":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "app"}, {Name: "config"}}}}},
+				"update":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "parallel", Steps: []v1alpha1.Step{{Name: "app"}}}}},
+				"restart": {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "rolling", Plan: "update"}}}}},
+			},
+		},
+	}
+	status := &v1alpha1.PlanStatus{
+		Name:      "deploy",
+		Status:    v1alpha1.ExecutionInProgress,
+		StartedAt: ago(90 * time.Second),
+		Phases: []v1alpha1.PhaseStatus{{
+			Name:      "main",
+			Status:    v1alpha1.ExecutionInProgress,
+			StartedAt: ago(90 * time.Second),
+			Steps: []v1alpha1.StepStatus{
+				{Name: "app", Status: v1alpha1.ExecutionComplete, StartedAt: ago(90 * time.Second),
+					Warnings: []string{"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"}},
+				{Name: "config", Status: v1alpha1.ExecutionInProgress, StartedAt: ago(30 * time.Second), Message: "waiting"},
+			},
+		}},
+	}
+
+	expected := `.
+└── kafka (Operator-Version: "kafka-1.0" Active-Plan: "deploy")
+    ├── ▶ Plan deploy (serial strategy) [IN_PROGRESS] running for 1m30s
+    │   └── ▶ Phase main [IN_PROGRESS] running for 1m30s
+    │       ├── ✓ Step app [COMPLETE]
+    │       │   └── Warning: extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+
+    │       └── ▶ Step config [IN_PROGRESS] running for 30s: waiting <- executing
+    ├── - Plan restart (serial strategy) [NOT ACTIVE]
+    │   └── - Phase main (serial strategy) [NOT ACTIVE]
+    │       └── - Step rolling.main.app [NOT ACTIVE]
+    └── - Plan update (serial strategy) [NOT ACTIVE]
+        └── - Phase main (parallel strategy) [NOT ACTIVE]
+            └── - Step app [NOT ACTIVE]
+`
+	// treeprint indents with non-breaking spaces
+	assert.Equal(t, expected, strings.ReplaceAll(statusTree(instance, ov, status, false, now), "\u00a0", " "))
+
+	status.Status = v1alpha1.ExecutionComplete
+	status.LastFinishedRun = ago(0)
+	assert.Equal(t, " took 1m30s", planDuration(status, now))
+}
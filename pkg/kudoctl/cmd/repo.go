@@ -11,20 +11,22 @@ import (
 const repoDesc = `
 This command consists of multiple sub-commands to interact with KUDO repositories.
 
-It can be used to add, remove, list, and index kudo repositories.
+It can be used to add, remove, list, index, mirror, and publish to kudo repositories.
 `
 
 const examples = `  kubectl kudo repo add [NAME] [REPO_URL]
   kubectl kudo repo remove
   kubectl kudo repo list
   kubectl kudo repo context [NAME]
+  kubectl kudo repo mirror [SOURCE] [DIR]
+  kubectl kudo repo publish [PACKAGE] --to [DESTINATION] --url [REPO_URL]
 `
 
 // newRepoCmd for repo commands such as building a repo index
 func newRepoCmd(fs afero.Fs, out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "repo [FLAGS] add|remove|list|index [ARGS]",
-		Short:   "Add, list, remove, and index kudo repositories.",
+		Use:     "repo [FLAGS] add|remove|list|index|mirror|publish [ARGS]",
+		Short:   "Add, list, remove, index, mirror, and publish to kudo repositories.",
 		Long:    repoDesc,
 		Example: examples,
 	}
@@ -34,6 +36,8 @@ func newRepoCmd(fs afero.Fs, out io.Writer) *cobra.Command {
 	cmd.AddCommand(newRepoAddCmd(fs, out))
 	cmd.AddCommand(newRepoRemoveCmd(fs, out))
 	cmd.AddCommand(newRepoContextCmd(fs))
+	cmd.AddCommand(newRepoMirrorCmd(fs, out, &t))
+	cmd.AddCommand(newRepoPublishCmd(fs, out, &t))
 
 	return cmd
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 
@@ -33,7 +34,7 @@ func (addCmd repoAddCmd) run() error {
 	if err := addRepository(addCmd.fs, addCmd.name, addCmd.url, addCmd.home, addCmd.skipCheck); err != nil {
 		return err
 	}
-	fmt.Fprintf(addCmd.out, "%q has been added to your repositories\n", addCmd.name)
+	clog.Fresultf(addCmd.out, addCmd.name, "%q has been added to your repositories", addCmd.name)
 	return nil
 
 }
@@ -6,6 +6,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 
@@ -146,7 +147,7 @@ func (ri *repoIndexCmd) run() error {
 	if err := index.WriteFile(ri.fs, target); err != nil {
 		return err
 	}
-	fmt.Fprintf(ri.out, "index %v created.\n", target)
+	clog.Fresultf(ri.out, target, "index %v created.", target)
 	return nil
 }
 
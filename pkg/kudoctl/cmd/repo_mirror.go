@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	repoMirrorDesc = `
+Download the operator packages of a repository into a directory and generate the index file of the mirror.
+
+The source is the name of a configured repository or the URL of a repository. Every package is verified against the
+digest of the source index and the generated 'index.yaml' references the packages at the URL given with '--url',
+so the directory can be served as a self-contained repository, e.g. in an air-gapped environment. To store the
+mirror in a bucket, upload the directory to the bucket and use the URL of the bucket.
+
+Use '--operator' to mirror only some operators. A version constraint can be appended to the name of the
+operator with '@'.`
+
+	repoMirrorExample = `  # mirror the community repository
+  kubectl kudo repo mirror community /opt/mirror --url https://kudo.example.com
+
+  # mirror the 1.x versions of kafka and all versions of zookeeper
+  kubectl kudo repo mirror https://kudo-repository.storage.googleapis.com /opt/mirror --operator "kafka@>=1.0.0, <2.0.0" --operator zookeeper
+`
+)
+
+type repoMirrorCmd struct {
+	source    string
+	path      string
+	url       string
+	operators []string
+	overwrite bool
+	out       io.Writer
+	time      *time.Time
+	fs        afero.Fs
+}
+
+// newRepoMirrorCmd for mirroring the packages of a repository into a directory
+func newRepoMirrorCmd(fs afero.Fs, out io.Writer, time *time.Time) *cobra.Command {
+	mirror := &repoMirrorCmd{out: out, fs: fs, time: time}
+	cmd := &cobra.Command{
+		Use:     "mirror [flags] <SOURCE> <DIR>",
+		Short:   "Download the packages of a repository into a directory containing a self-contained mirror",
+		Long:    repoMirrorDesc,
+		Example: repoMirrorExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("expecting exactly two arguments - the source repository and the mirror directory")
+			}
+			mirror.source = args[0]
+			mirror.path = args[1]
+			return mirror.run()
+		},
+		SilenceUsage: true,
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&mirror.url, "url", "", "URL of the mirror to reference the operators with in the index file")
+	f.StringArrayVar(&mirror.operators, "operator", nil, "Operator to mirror with an optional version constraint, e.g. kafka@>=1.0.0 (can be repeated)")
+	f.BoolVarP(&mirror.overwrite, "overwrite", "w", false, "Overwrite existing packages and index file")
+
+	return cmd
+}
+
+func (rm *repoMirrorCmd) run() error {
+	filters := make([]repo.MirrorFilter, 0, len(rm.operators))
+	for _, o := range rm.operators {
+		f, err := repo.ParseMirrorFilter(o)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, f)
+	}
+
+	target, err := files.FullPathToTarget(rm.fs, rm.path, "index.yaml", rm.overwrite)
+	if err != nil {
+		return err
+	}
+
+	config, err := rm.sourceConfig()
+	if err != nil {
+		return err
+	}
+	client, err := repo.NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	index, err := client.Mirror(rm.fs, rm.path, rm.url, filters, rm.overwrite, rm.time)
+	if err != nil {
+		return err
+	}
+	if err := index.WriteFile(rm.fs, target); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, pvs := range index.Entries {
+		count += len(pvs)
+	}
+	clog.Fresultf(rm.out, target, "mirrored %d packages of %d operators, index %v created.", count, len(index.Entries), target)
+	return nil
+}
+
+// sourceConfig returns the configuration of the named repository or of the repository at the source URL
+func (rm *repoMirrorCmd) sourceConfig() (*repo.Configuration, error) {
+	repos, err := repo.LoadRepositories(rm.fs, Settings.Home.RepositoryFile())
+	if err == nil {
+		if config := repos.GetConfiguration(rm.source); config != nil {
+			return config, nil
+		}
+	}
+	return &repo.Configuration{
+		URL:  rm.source,
+		Name: "temp-mirror-source",
+	}, nil
+}
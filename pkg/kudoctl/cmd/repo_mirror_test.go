@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoMirrorCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	fs.MkdirAll("/opt/mirror", 0755)
+	afero.WriteFile(fs, "/opt/mirror/index.yaml", []byte{}, 0644)
+
+	var tests = []struct {
+		name         string
+		flags        map[string]string
+		arguments    []string
+		errorMessage string
+	}{
+		{name: "no arguments", errorMessage: "expecting exactly two arguments - the source repository and the mirror directory"},
+		{name: "one argument", arguments: []string{"community"}, errorMessage: "expecting exactly two arguments - the source repository and the mirror directory"},
+		{name: "invalid operator filter", arguments: []string{"community", "/opt/mirror"}, flags: map[string]string{"operator": "@1.0.0"}, errorMessage: `operator filter "@1.0.0" has no operator name`},
+		{name: "existing index", arguments: []string{"community", "/opt/mirror"}, errorMessage: `target file "/opt/mirror/index.yaml" already exists`},
+		{name: "missing directory", arguments: []string{"community", "/opt/missing"}, errorMessage: `destination "/opt/missing" is not a proper directory`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			now := time.Now()
+			cmd := newRepoMirrorCmd(fs, out, &now)
+			for key, value := range tt.flags {
+				cmd.Flags().Set(key, value)
+			}
+			err := cmd.RunE(cmd, tt.arguments)
+			assert.EqualError(t, err, tt.errorMessage)
+		})
+	}
+}
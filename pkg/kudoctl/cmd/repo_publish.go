@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	repoPublishDesc = `
+Publish an operator package to a repository by uploading the package and the updated index file.
+
+The package is added to the index file of the repository and referenced at the repository URL given with '--url'.
+The package is uploaded first, followed by the index file, to the destination given with '--to':
+
+  s3://<bucket>[/<prefix>]        an AWS S3 bucket. The credentials are read from AWS_ACCESS_KEY_ID,
+                                  AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN or the shared credentials file of the
+                                  AWS CLI, the region from AWS_REGION. AWS_ENDPOINT_URL sets the endpoint of an S3
+                                  compatible storage.
+  gs://<bucket>[/<prefix>]        a Google Cloud Storage bucket, using the application default credentials
+  http(s)://host/path             a web server accepting PUT requests, using basic authentication with
+                                  KUDO_PUBLISH_USERNAME and KUDO_PUBLISH_PASSWORD if set
+  sftp://[user@]host[:port]/path  a directory on an SFTP server, using the sftp command in batch mode
+  <DIR>                           a local directory, e.g. a worktree of the gh-pages branch of a repository
+                                  served by GitHub Pages, that is committed and pushed afterwards
+
+Credentials are not accepted in the destination URL. The index file of s3, gs and http(s) destinations is read from
+the destination and only replaced if it was not changed by a concurrent publication in the meantime. The index file of
+sftp and local destinations is downloaded from the repository URL and replaced unconditionally, so concurrent
+publications to them can overwrite each other's changes of the index file.
+
+A version that is already in the index file of the repository can't be published again.`
+
+	repoPublishExample = `  # publish a package to a repository in an S3 bucket
+  kubectl kudo repo publish kafka-1.2.0.tgz --to s3://kudo-operators --url https://kudo-operators.s3.amazonaws.com
+
+  # publish a package to a repository served by GitHub Pages
+  kubectl kudo repo publish kafka-1.2.0.tgz --to ./gh-pages --url https://example.github.io/operators
+`
+)
+
+type repoPublishCmd struct {
+	path        string
+	url         string
+	destination string
+	out         io.Writer
+	time        *time.Time
+	fs          afero.Fs
+}
+
+// newRepoPublishCmd for publishing a package to a repository
+func newRepoPublishCmd(fs afero.Fs, out io.Writer, time *time.Time) *cobra.Command {
+	publish := &repoPublishCmd{out: out, fs: fs, time: time}
+	cmd := &cobra.Command{
+		Use:     "publish [flags] <PACKAGE>",
+		Short:   "Upload an operator package and the updated index file to a repository",
+		Long:    repoPublishDesc,
+		Example: repoPublishExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := publish.validate(args); err != nil {
+				return err
+			}
+			publish.path = args[0]
+			return publish.run()
+		},
+		SilenceUsage: true,
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&publish.url, "url", "", "URL of the repository to download the index file from and to reference the package with")
+	f.StringVar(&publish.destination, "to", "", "Destination to upload the package and the index file to")
+
+	return cmd
+}
+
+func (rp *repoPublishCmd) validate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("expecting exactly one argument - the package to publish")
+	}
+	if rp.url == "" {
+		return errors.New("the URL of the repository is required, use '--url'")
+	}
+	if rp.destination == "" {
+		return errors.New("the destination to upload to is required, use '--to'")
+	}
+	return nil
+}
+
+func (rp *repoPublishCmd) run() error {
+	uploader, err := repo.NewUploader(rp.fs, rp.destination)
+	if err != nil {
+		return err
+	}
+	client, err := repo.NewClient(&repo.Configuration{
+		URL:  rp.url,
+		Name: "temp-publish-target",
+	})
+	if err != nil {
+		return err
+	}
+
+	pv, err := client.Publish(rp.fs, rp.path, uploader, rp.time)
+	if err != nil {
+		return err
+	}
+	clog.Fresultf(rp.out, pv.URLs[0], "operator %s version %s published to %s.", pv.Name, pv.Version, uploader)
+	return nil
+}
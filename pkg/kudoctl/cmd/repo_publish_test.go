@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoPublishCmd(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	var tests = []struct {
+		name         string
+		flags        map[string]string
+		arguments    []string
+		errorMessage string
+	}{
+		{name: "no arguments", errorMessage: "expecting exactly one argument - the package to publish"},
+		{name: "missing url", arguments: []string{"kafka-1.0.0.tgz"}, flags: map[string]string{"to": "/opt/repo"}, errorMessage: "the URL of the repository is required, use '--url'"},
+		{name: "missing destination", arguments: []string{"kafka-1.0.0.tgz"}, flags: map[string]string{"url": "https://repo.example.com"}, errorMessage: "the destination to upload to is required, use '--to'"},
+		{name: "unsupported destination", arguments: []string{"kafka-1.0.0.tgz"}, flags: map[string]string{"url": "https://repo.example.com", "to": "ftp://repo.example.com"},
+			errorMessage: "unsupported destination ftp://repo.example.com, expected a local directory or a s3, gs, http(s) or sftp URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			now := time.Now()
+			cmd := newRepoPublishCmd(fs, out, &now)
+			for key, value := range tt.flags {
+				cmd.Flags().Set(key, value)
+			}
+			err := cmd.RunE(cmd, tt.arguments)
+			assert.EqualError(t, err, tt.errorMessage)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 
@@ -68,7 +69,7 @@ func removeRepoLine(fs afero.Fs, out io.Writer, name string, home kudohome.Home)
 		return err
 	}
 
-	fmt.Fprintf(out, "%q has been removed from your repositories\n", name)
+	clog.Fresultf(out, name, "%q has been removed from your repositories", name)
 
 	return nil
 }
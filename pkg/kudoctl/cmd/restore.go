@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+const (
+	restoreExample = `  # Re-apply the KUDO objects backed up by 'kubectl kudo init --upgrade'
+  kubectl kudo restore kudo-backup-20191201-100000.yaml
+`
+)
+
+type restoreCmd struct {
+	out io.Writer
+	fs  afero.Fs
+}
+
+func (cmd *restoreCmd) run(path string, settings *env.Settings) error {
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	clog.V(3).Printf("acquiring kudo client")
+	if err != nil {
+		clog.V(3).Printf("failed to acquire kudo client: %v", err)
+		return fmt.Errorf("failed to acquire kudo client: %w", err)
+	}
+
+	return cmd.restore(kc, path)
+}
+
+// restore reads the backup file and re-applies its objects to the cluster
+func (cmd *restoreCmd) restore(kc *kudo.Client, path string) error {
+	f, err := cmd.fs.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	b, err := kudo.ReadBackup(f)
+	if err != nil {
+		return err
+	}
+	if err := kc.Restore(b); err != nil {
+		return err
+	}
+
+	if !clog.Quiet() {
+		fmt.Fprintf(cmd.out, "Restored %d operators, %d operatorversions and %d instances from %s\n", len(b.Operators), len(b.OperatorVersions), len(b.Instances), path)
+	}
+	return nil
+}
+
+// newRestoreCmd creates a command that re-applies the KUDO objects of a backup file
+func newRestoreCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	restore := &restoreCmd{out: out, fs: fs}
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore <backup-file>",
+		Short: "Restore KUDO objects from a backup.",
+		Long: `Re-apply the operators, operatorversions and instances of a backup file written by 'kubectl kudo init --upgrade'.
+Missing objects are created, instances with their status so that their plans are not executed again. Existing objects
+are updated with the backed up spec.`,
+		Example: restoreExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return restore.run(args[0], &Settings)
+		},
+	}
+
+	return restoreCmd
+}
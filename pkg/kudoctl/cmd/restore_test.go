@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+const testBackup = `---
+apiVersion: kudo.dev/v1alpha1
+kind: Operator
+metadata:
+  name: kafka
+  namespace: default
+---
+apiVersion: kudo.dev/v1alpha1
+kind: Instance
+metadata:
+  name: kafka
+  namespace: default
+spec:
+  operatorVersion:
+    name: kafka-1.0
+`
+
+func TestRestore(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "backup.yaml", []byte(testBackup), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	kc := newTestClient()
+	var out bytes.Buffer
+	cmd := restoreCmd{out: &out, fs: fs}
+	if err := cmd.restore(kc, "backup.yaml"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if !strings.Contains(out.String(), "Restored 1 operators, 0 operatorversions and 1 instances from backup.yaml") {
+		t.Errorf("unexpected output %s", out.String())
+	}
+	if i, err := kc.GetInstance("kafka", "default"); err != nil || i == nil {
+		t.Errorf("expected instance to be restored but got %v, %v", i, err)
+	}
+
+	if err := cmd.restore(kc, "missing.yaml"); err == nil {
+		t.Errorf("expected error for a missing backup file")
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/instance"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	rollbackDesc = `Roll back an instance to the operatorversion and parameters of a previous revision. The revisions of an instance
+are shown by 'kubectl kudo instance history'. The changes are previewed and have to be confirmed before they are
+applied, the controller then runs the plan triggered by the changes. The rollback is recorded as a new revision.`
+	rollbackExample = `  # Roll back instance dev-flink to the revision before the latest change
+  kubectl kudo rollback dev-flink
+
+  # Roll back instance dev-flink to revision 3 without confirmation
+  kubectl kudo rollback dev-flink --to-revision 3 --yes
+`
+)
+
+// newRollbackCmd creates the rollback command for the CLI
+func newRollbackCmd() *cobra.Command {
+	options := instance.DefaultRollbackOptions
+	rollbackCmd := &cobra.Command{
+		Use:     "rollback <instance>",
+		Short:   "Roll back an instance to a previous revision.",
+		Long:    rollbackDesc,
+		Example: rollbackExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return instance.RunRollback(cmd.InOrStdin(), cmd.OutOrStdout(), args, options, &Settings)
+		},
+	}
+
+	rollbackCmd.Flags().IntVar(&options.ToRevision, "to-revision", 0, "The revision to roll back to. (default the revision before the latest one)")
+	rollbackCmd.Flags().BoolVarP(&options.Yes, "yes", "y", false, "Apply the changes without confirmation.")
+
+	return rollbackCmd
+}
@@ -2,14 +2,15 @@ package cmd
 
 import (
 	"io"
-	"os"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
 	"github.com/kudobuilder/kudo/pkg/version"
 
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
@@ -57,12 +58,21 @@ and serves as an API aggregation layer.
 	cmd.AddCommand(newInstallCmd(fs))
 	cmd.AddCommand(newInitCmd(fs, cmd.OutOrStdout()))
 	cmd.AddCommand(newUpgradeCmd(fs))
-	cmd.AddCommand(newUpdateCmd())
+	cmd.AddCommand(newUpdateCmd(fs))
+	cmd.AddCommand(newRollbackCmd())
 	cmd.AddCommand(newUninstallCmd())
+	cmd.AddCommand(newGCCmd(cmd.OutOrStdout()))
+	cmd.AddCommand(newRestoreCmd(fs, cmd.OutOrStdout()))
 	cmd.AddCommand(newPackageCmd(fs, cmd.OutOrStdout()))
 	cmd.AddCommand(newGetCmd())
-	cmd.AddCommand(newPlanCmd())
+	cmd.AddCommand(newInstanceCmd())
+	cmd.AddCommand(newPlanCmd(fs))
+	cmd.AddCommand(newManagerCmd())
+	cmd.AddCommand(newDoctorCmd())
+	cmd.AddCommand(newParamsCmd(fs))
 	cmd.AddCommand(newRepoCmd(fs, cmd.OutOrStdout()))
+	cmd.AddCommand(newConfigCmd(fs, cmd.OutOrStdout()))
+	cmd.AddCommand(newTelemetryCmd(fs, cmd.OutOrStdout()))
 	cmd.AddCommand(newTestCmd())
 	cmd.AddCommand(newVersionCmd())
 
@@ -71,12 +81,16 @@ and serves as an API aggregation layer.
 	return cmd
 }
 
+// initGlobalFlags adds the global flags. The settings are initialized after all flags of the invoked command are
+// parsed, as the namespace defaults to the one of the selected kubeconfig context.
 func initGlobalFlags(cmd *cobra.Command, out io.Writer) {
 	flags := cmd.PersistentFlags()
 	Settings.AddFlags(flags)
 	clog.Init(flags, out)
-	// FIXME: add error handling
-	cmd.ParseFlags(os.Args[1:])
-	// set ENV if flags are not used.
-	Settings.Init(flags)
+	cmd.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		// set ENV if flags are not used.
+		Settings.Init(cmd.Flags())
+		// the span of the command is ended by the caller of Execute
+		tracing.StartRootSpan(cmd.CommandPath(), attribute.String("kudo.namespace", Settings.Namespace))
+	}
 }
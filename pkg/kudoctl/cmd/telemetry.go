@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/telemetry"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const telemetryDesc = `
+This command consists of multiple sub-commands to manage the opt-in reporting of anonymous usage.
+
+Usage reports help the maintainers to prioritize features. Once enabled, each command reports its name, the names
+of the flags that were set, the category of its error, its runtime and the version and platform of the CLI together
+with a random ID of the installation. Arguments, flag values and error messages are never reported.
+
+Reporting is disabled if the DO_NOT_TRACK environment variable is set, regardless of the opt-in.
+`
+
+const telemetryExample = `  # Show whether usage is reported
+  kubectl kudo telemetry status
+
+  # Opt in to report usage to the default endpoint
+  kubectl kudo telemetry enable
+
+  # Opt in to report usage to a custom endpoint
+  kubectl kudo telemetry enable --endpoint https://telemetry.example.com/v1/usage
+
+  # Opt out, the random ID of the installation is removed
+  kubectl kudo telemetry disable
+`
+
+// newTelemetryCmd creates the commands managing the opt-in of the usage reporting
+func newTelemetryCmd(fs afero.Fs, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "telemetry status|enable|disable",
+		Short:   "Manage the opt-in reporting of anonymous usage.",
+		Long:    telemetryDesc,
+		Example: telemetryExample,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether anonymous usage is reported.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return telemetryStatus(fs, out, Settings.Home)
+		},
+	})
+
+	var endpoint string
+	enableCmd := &cobra.Command{
+		Use:   "enable",
+		Short: "Opt in to report anonymous usage.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return enableTelemetry(fs, out, Settings.Home, endpoint)
+		},
+	}
+	enableCmd.Flags().StringVar(&endpoint, "endpoint", "", "The URL usage is reported to, defaults to the endpoint the CLI was built with.")
+	cmd.AddCommand(enableCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "disable",
+		Short: "Opt out of reporting anonymous usage.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return disableTelemetry(fs, out, Settings.Home)
+		},
+	})
+
+	return cmd
+}
+
+func telemetryStatus(fs afero.Fs, out io.Writer, home kudohome.Home) error {
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	s := telemetry.GetStatus(config)
+	if s.Enabled {
+		fmt.Fprintf(out, "Anonymous usage is reported to %s with ID %s\n", s.Endpoint, s.ID)
+		return nil
+	}
+	fmt.Fprintf(out, "Anonymous usage is not reported: %s\n", s.Reason)
+	return nil
+}
+
+// enableTelemetry opts in to the usage reporting. The random ID of the installation is generated on the first opt-in.
+func enableTelemetry(fs afero.Fs, out io.Writer, home kudohome.Home, endpoint string) error {
+	if !telemetry.Available {
+		return fmt.Errorf("telemetry can not be enabled, the CLI was built without it")
+	}
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	if config.Telemetry == nil {
+		config.Telemetry = &env.TelemetryConfig{}
+	}
+	if endpoint != "" {
+		config.Telemetry.Endpoint = endpoint
+	}
+	if config.Telemetry.Endpoint == "" && telemetry.DefaultEndpoint == "" {
+		return fmt.Errorf("the CLI was built without a telemetry endpoint, set one with --endpoint")
+	}
+	config.Telemetry.Enabled = true
+	if config.Telemetry.ID == "" {
+		id, err := newInstallationID()
+		if err != nil {
+			return err
+		}
+		config.Telemetry.ID = id
+	}
+	if err := config.WriteFile(fs, home.ConfigFile()); err != nil {
+		return err
+	}
+	return telemetryStatus(fs, out, home)
+}
+
+// newInstallationID returns a random version 4 UUID. Unlike a version 1 UUID it does not contain the MAC address of
+// the host or the time of the opt-in, so it does not identify the machine.
+func newInstallationID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate the ID of the installation: %v", err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// disableTelemetry opts out of the usage reporting and removes the ID of the installation
+func disableTelemetry(fs afero.Fs, out io.Writer, home kudohome.Home) error {
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	if err != nil {
+		return err
+	}
+	if config.Telemetry != nil {
+		config.Telemetry.Enabled = false
+		config.Telemetry.ID = ""
+		if err := config.WriteFile(fs, home.ConfigFile()); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(out, "Anonymous usage is not reported anymore")
+	return nil
+}
+
+// ReportUsage reports the anonymous usage of the executed command if the user opted in. The client configuration is
+// read again, so that the telemetry commands report with the opt-in they set.
+func ReportUsage(cmd *cobra.Command, err error, duration time.Duration) {
+	if cmd == nil || Settings.Home == "" {
+		return
+	}
+	config, cerr := env.LoadConfig(fs, Settings.Home.ConfigFile())
+	if cerr != nil {
+		return
+	}
+	var flags []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		flags = append(flags, f.Name)
+	})
+	telemetry.Report(config, telemetry.NewEvent(cmd.CommandPath(), flags, err, duration))
+}
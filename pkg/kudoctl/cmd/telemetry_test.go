@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/telemetry"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelemetry(t *testing.T) {
+	if !telemetry.Available {
+		t.Skip("the CLI is built without telemetry")
+	}
+	fs := afero.NewMemMapFs()
+	out := &bytes.Buffer{}
+	home := kudohome.Home("kudo_home")
+
+	assert.NoError(t, telemetryStatus(fs, out, home))
+	assert.Equal(t, "Anonymous usage is not reported: telemetry was not enabled\n", out.String())
+
+	assert.EqualError(t, enableTelemetry(fs, out, home, ""), "the CLI was built without a telemetry endpoint, set one with --endpoint")
+	assert.NoError(t, enableTelemetry(fs, out, home, "https://telemetry.example.com"))
+	config, err := env.LoadConfig(fs, home.ConfigFile())
+	assert.NoError(t, err)
+	if assert.NotNil(t, config.Telemetry) {
+		assert.True(t, config.Telemetry.Enabled)
+		assert.Regexp(t, "^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$", config.Telemetry.ID, "the ID is a random UUID")
+		assert.Equal(t, "https://telemetry.example.com", config.Telemetry.Endpoint)
+	}
+	id := config.Telemetry.ID
+
+	out.Reset()
+	assert.NoError(t, enableTelemetry(fs, out, home, ""))
+	assert.Equal(t, "Anonymous usage is reported to https://telemetry.example.com with ID "+id+"\n", out.String(), "the ID is kept")
+
+	assert.NoError(t, disableTelemetry(fs, out, home))
+	config, err = env.LoadConfig(fs, home.ConfigFile())
+	assert.NoError(t, err)
+	assert.Equal(t, &env.TelemetryConfig{Endpoint: "https://telemetry.example.com"}, config.Telemetry, "the ID is removed")
+}
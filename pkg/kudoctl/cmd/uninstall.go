@@ -11,27 +11,31 @@ import (
 )
 
 const (
-	uninstallExample = `kubectl kudo uninstall --instance flink`
+	uninstallExample = `  kubectl kudo uninstall --instance flink
+
+  # Uninstall an instance that is protected from deletion
+  kubectl kudo uninstall --instance flink --force-protected`
 )
 
 type uninstallOptions struct {
-	InstanceName string
+	InstanceName   string
+	ForceProtected bool
 }
 
 type uninstallCmd struct{}
 
 func (cmd *uninstallCmd) run(options uninstallOptions, settings *env.Settings) error {
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	clog.V(3).Printf("acquiring kudo client")
 	if err != nil {
 		clog.V(3).Printf("failed to acquire kudo client: %v", err)
 		return fmt.Errorf("failed to acquire kudo client: %w", err)
 	}
 
-	return cmd.uninstall(kc, options.InstanceName, settings)
+	return cmd.uninstall(kc, options.InstanceName, options.ForceProtected, settings)
 }
 
-func (cmd *uninstallCmd) uninstall(kc *kudo.Client, instanceName string, settings *env.Settings) error {
+func (cmd *uninstallCmd) uninstall(kc *kudo.Client, instanceName string, forceProtected bool, settings *env.Settings) error {
 	instance, err := kc.GetInstance(instanceName, settings.Namespace)
 	if err != nil {
 		return fmt.Errorf("failed to verify if instance already exists: %w", err)
@@ -41,12 +45,22 @@ func (cmd *uninstallCmd) uninstall(kc *kudo.Client, instanceName string, setting
 		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, settings.Namespace)
 	}
 
+	if instance.IsProtected() {
+		if !forceProtected {
+			return fmt.Errorf("instance %s in namespace %s is protected from deletion, use --force-protected to uninstall it anyway", instanceName, settings.Namespace)
+		}
+		clog.V(2).Printf("removing the protection of instance %s", instanceName)
+		if err := kc.UnprotectInstance(instanceName, settings.Namespace); err != nil {
+			return fmt.Errorf("failed to remove the protection of instance %s: %w", instanceName, err)
+		}
+	}
+
 	err = kc.DeleteInstance(instanceName, settings.Namespace)
 	if err != nil {
 		return err
 	}
 
-	clog.Printf("instance.%s/%s deleted\n", instance.APIVersion, instanceName)
+	clog.Resultf(instanceName, "instance.%s/%s deleted", instance.APIVersion, instanceName)
 	return nil
 }
 
@@ -66,6 +80,7 @@ func newUninstallCmd() *cobra.Command {
 	}
 
 	uninstallCmd.Flags().StringVar(&options.InstanceName, "instance", "", "The instance name.")
+	uninstallCmd.Flags().BoolVar(&options.ForceProtected, "force-protected", false, "Remove the protection of a protected instance and uninstall it.")
 	if err := uninstallCmd.MarkFlagRequired("instance"); err != nil {
 		panic(err)
 	}
@@ -40,7 +40,7 @@ func TestUninstall(t *testing.T) {
 	}
 
 	cmd := uninstallCmd{}
-	err = cmd.uninstall(kc, "nonexisting-instance", settings)
+	err = cmd.uninstall(kc, "nonexisting-instance", false, settings)
 	if err == nil {
 		t.Errorf("expected an error but got none")
 	}
@@ -50,7 +50,7 @@ func TestUninstall(t *testing.T) {
 		t.Errorf("expected error message '%s' but got '%v'", errMsg, err)
 	}
 
-	err = cmd.uninstall(kc, testInstance.Name, settings)
+	err = cmd.uninstall(kc, testInstance.Name, false, settings)
 	if err != nil {
 		t.Errorf("failed to uninstall instance: %v", err)
 	}
@@ -64,3 +64,37 @@ func TestUninstall(t *testing.T) {
 		t.Errorf("instance %s still found after deletion", testInstance.Name)
 	}
 }
+
+func TestUninstall_Protected(t *testing.T) {
+	testInstance := v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: map[string]string{v1alpha1.ProtectedAnnotation: "true"},
+		},
+	}
+
+	settings := env.DefaultSettings
+
+	kc := newTestClient()
+	if _, err := kc.InstallInstanceObjToCluster(&testInstance, settings.Namespace); err != nil {
+		t.Fatalf("failed to install instance: %v", err)
+	}
+
+	cmd := uninstallCmd{}
+	err := cmd.uninstall(kc, testInstance.Name, false, settings)
+	errMsg := "instance test in namespace default is protected from deletion, use --force-protected to uninstall it anyway"
+	if err == nil || err.Error() != errMsg {
+		t.Errorf("expected error message '%s' but got '%v'", errMsg, err)
+	}
+	if instance, _ := kc.GetInstance(testInstance.Name, settings.Namespace); instance == nil {
+		t.Errorf("expected the protected instance %s to be kept", testInstance.Name)
+	}
+
+	if err := cmd.uninstall(kc, testInstance.Name, true, settings); err != nil {
+		t.Errorf("failed to uninstall protected instance: %v", err)
+	}
+	if instance, _ := kc.GetInstance(testInstance.Name, settings.Namespace); instance != nil {
+		t.Errorf("instance %s still found after forced deletion", testInstance.Name)
+	}
+}
@@ -2,37 +2,92 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"time"
 
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/batch"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 )
 
 var (
 	updateDesc = `Update KUDO operator instance with new arguments. The update does not accept any arguments.
+
+Parameters removed with --remove-param are unset on the instance, so that they fall back to the default of the
+operatorversion. Like changed parameters they trigger the plan the parameter is bound to.
+
+With --plan the given plan is executed for the update instead of the plan the changed parameters trigger, e.g. when a
+cheaper plan suffices for the change. The plan has to exist in the operatorversion of the instance.
+
+Instead of a single instance, all instances matching a label selector can be updated. The update is rolled out to at
+most --max-parallel instances at the same time in the given --order. With --wait an instance only counts as updated
+once the plan triggered by the update completed. No further instances are updated after an update failed, unless
+--continue-on-failure is set.
+
+With --encryption-config the values of sensitive parameters are encrypted before they are stored in the instance, see
+the install command.
+
+With --dry-run the instance is not updated. The parameters that would change and the plan the update would trigger are
+printed instead. With --diff the manager additionally executes the plan in dry-run mode with the updated parameters:
+the objects are rendered and sent to the API server in dry-run mode, and the objects and fields that would change are
+printed, like with 'kubectl kudo plan dry-run'.
 `
 	updateExample = `  # Update dev-flink instance with setting parameter param with value value
   kubectl kudo update --instance dev-flink -p param=value
 
   # Update dev-flink instance in namespace services with setting parameter param with value value
-  kubectl kudo update --instance dev-flink -n services -p param=value`
+  kubectl kudo update --instance dev-flink -n services -p param=value
+
+  # Unset parameter param of dev-flink instance, so that its default value is used again
+  kubectl kudo update --instance dev-flink --remove-param param
+
+  # Update dev-flink instance executing the rolling-restart plan instead of the plan triggered by param
+  kubectl kudo update --instance dev-flink -p param=value --plan rolling-restart
+
+  # Update all kafka instances, two at a time, waiting for the plan of each instance to complete
+  kubectl kudo update --selector kudo.dev/operator=kafka -p param=value --max-parallel 2 --wait
+
+  # Show the objects of dev-flink that would change if param was set to value, without updating the instance
+  kubectl kudo update --instance dev-flink -p param=value --dry-run --diff`
 )
 
 type updateOptions struct {
 	InstanceName string
 	Parameters   map[string]string
+	// RemovedParameters are unset on the instance
+	RemovedParameters []string
+	// Plan is executed for the update instead of the plan triggered by the parameters, if set
+	Plan string
+	// Selector selects the instances to update instead of a single instance
+	Selector    string
+	Batch       batch.Options
+	Wait        bool
+	WaitTimeout time.Duration
+	// Keyring encrypts the values of sensitive parameters
+	Keyring *encryption.Keyring
+	// DryRun prints the changes of the update instead of updating the instances
+	DryRun bool
+	// Diff executes the plan triggered by the update in dry-run mode and prints the objects that would change
+	Diff bool
 }
 
 // defaultOptions initializes the install command options to its defaults
 var defaultUpdateOptions = &updateOptions{}
 
 // newUpdateCmd creates the install command for the CLI
-func newUpdateCmd() *cobra.Command {
+func newUpdateCmd(fs afero.Fs) *cobra.Command {
 	options := defaultUpdateOptions
 	var parameters []string
+	var parameterFiles []string
+	var encryptionConfig string
 	updateCmd := &cobra.Command{
 		Use:     "update",
 		Short:   "Update KUDO operator instance.",
@@ -41,16 +96,32 @@ func newUpdateCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Prior to command execution we parse and validate passed arguments
 			var err error
-			options.Parameters, err = install.GetParameterMap(parameters)
+			options.Parameters, err = install.GetParameters(fs, parameterFiles, parameters)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.Keyring, err = install.GetKeyring(encryptionConfig)
 			if err != nil {
 				return errors.WithMessage(err, "could not parse arguments")
 			}
-			return runUpdate(args, options, &Settings)
+			return runUpdate(cmd.OutOrStdout(), args, options, &Settings)
 		},
 	}
 
 	updateCmd.Flags().StringVar(&options.InstanceName, "instance", "", "The instance name.")
+	updateCmd.Flags().StringVarP(&options.Selector, "selector", "l", "", "Update all instances matching the label selector instead of a single instance, e.g. kudo.dev/operator=kafka")
+	updateCmd.Flags().IntVar(&options.Batch.MaxParallel, "max-parallel", 1, "The number of instances matching the selector that are updated at the same time.")
+	updateCmd.Flags().StringVar((*string)(&options.Batch.Order), "order", string(batch.OrderName), "The order in which instances matching the selector are updated: name or creation (oldest first).")
+	updateCmd.Flags().BoolVar(&options.Batch.ContinueOnFailure, "continue-on-failure", false, "Keep updating instances matching the selector after an update failed.")
+	updateCmd.Flags().BoolVar(&options.Wait, "wait", false, "Wait for the plan triggered by the update to complete.")
+	updateCmd.Flags().DurationVar(&options.WaitTimeout, "wait-timeout", 10*time.Minute, "The time to wait for the plan of an instance to complete.")
 	updateCmd.Flags().StringArrayVarP(&parameters, "parameter", "p", nil, "The parameter name and value separated by '='")
+	updateCmd.Flags().StringArrayVar(&options.RemovedParameters, "remove-param", nil, "The name of a parameter to unset so that it falls back to its default, can be repeated")
+	updateCmd.Flags().StringVar(&options.Plan, "plan", "", "The plan to execute for the update instead of the plan triggered by the changed parameters.")
+	updateCmd.Flags().StringArrayVar(&parameterFiles, "parameter-file", nil, "A YAML file with parameters, can be repeated. Later files are merged on top of earlier ones and -p takes precedence")
+	updateCmd.Flags().StringVar(&encryptionConfig, "encryption-config", "", "Encryption configuration file whose first provider encrypts the values of sensitive parameters")
+	updateCmd.Flags().BoolVar(&options.DryRun, "dry-run", false, "Print the parameters that would change and the plan that would be executed without updating the instance.")
+	updateCmd.Flags().BoolVar(&options.Diff, "diff", false, "With --dry-run, execute the plan in dry-run mode with the updated parameters and print the objects that would change.")
 
 	return updateCmd
 }
@@ -59,28 +130,51 @@ func validateUpdateCmd(args []string, options *updateOptions) error {
 	if len(args) != 0 {
 		return errors.New("expecting no arguments provided for update. Only named flags are accepted")
 	}
-	if options.InstanceName == "" {
+	if options.InstanceName == "" && options.Selector == "" {
 		return errors.New("--instance flag has to be provided to indicate which instance you want to update")
 	}
-	if len(options.Parameters) == 0 {
-		return errors.New("need to specify at least one parameter to override via -p otherwise there is nothing to update")
+	if options.InstanceName != "" && options.Selector != "" {
+		return errors.New("--instance and --selector flags can not be used together")
+	}
+	if len(options.Parameters) == 0 && len(options.RemovedParameters) == 0 {
+		return errors.New("need to specify at least one parameter to override via -p or to remove via --remove-param otherwise there is nothing to update")
+	}
+	for _, name := range options.RemovedParameters {
+		if _, ok := options.Parameters[name]; ok {
+			return fmt.Errorf("parameter %s can not be set and removed at the same time", name)
+		}
+	}
+	if options.Diff && !options.DryRun {
+		return errors.New("--diff can only be used together with --dry-run")
+	}
+	if options.DryRun && options.Wait {
+		return errors.New("--dry-run and --wait flags can not be used together")
+	}
+	if options.Selector != "" {
+		return options.Batch.Validate()
 	}
 
 	return nil
 }
 
-func runUpdate(args []string, options *updateOptions, settings *env.Settings) error {
+func runUpdate(out io.Writer, args []string, options *updateOptions, settings *env.Settings) error {
 	err := validateUpdateCmd(args, options)
 	if err != nil {
 		return err
 	}
 	instanceToUpdate := options.InstanceName
 
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	if err != nil {
 		return errors.Wrap(err, "creating kudo client")
 	}
 
+	if options.DryRun {
+		return previewUpdates(out, kc, options, settings)
+	}
+	if options.Selector != "" {
+		return updateSelected(kc, options, settings)
+	}
 	return update(instanceToUpdate, kc, options, settings)
 }
 
@@ -94,11 +188,100 @@ func update(instanceToUpdate string, kc *kudo.Client, options *updateOptions, se
 		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceToUpdate, settings.Namespace)
 	}
 
+	parameters, err := prepareUpdate(kc, instance, options)
+	if err != nil {
+		return errors.Wrapf(err, "updating instance %s", instanceToUpdate)
+	}
+
 	// Update arguments
-	err = kc.UpdateInstance(instanceToUpdate, settings.Namespace, nil, options.Parameters)
+	err = kc.UpdateInstance(instanceToUpdate, settings.Namespace, nil, parameters, options.RemovedParameters, options.Plan)
 	if err != nil {
 		return errors.Wrapf(err, "updating instance %s", instanceToUpdate)
 	}
-	fmt.Printf("Instance %s was updated.", instanceToUpdate)
+	if options.Wait {
+		if err := kc.WaitForPlan(instanceToUpdate, settings.Namespace, planPollInterval, options.WaitTimeout); err != nil {
+			return err
+		}
+	}
+	clog.Resultf(instanceToUpdate, "Instance %s was updated.", instanceToUpdate)
+	return nil
+}
+
+// prepareUpdate validates the parameters the instance has after the update against its operatorversion, e.g. that no
+// required parameter without default is removed, and that the requested plan exists. It returns the parameters to set with the values of sensitive
+// parameters encrypted. Instances whose operatorversion does not exist are not validated.
+func prepareUpdate(kc *kudo.Client, instance *v1alpha1.Instance, options *updateOptions) (map[string]string, error) {
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting operatorversion of instance %s", instance.Name)
+	}
+	if ov == nil {
+		return options.Parameters, nil
+	}
+	updated := updatedParameters(instance, options.Parameters, options.RemovedParameters)
+	if err := v1alpha1.ValidateParameters(ov, updated); err != nil {
+		return nil, err
+	}
+	if _, ok := ov.Spec.Plans[options.Plan]; options.Plan != "" && !ok {
+		return nil, fmt.Errorf("plan %s does not exist in operatorversion %s", options.Plan, ov.Name)
+	}
+	return install.EncryptSensitiveParameters(ov, options.Parameters, options.Keyring)
+}
+
+// updatedParameters returns the parameters the instance has after the given parameters are set and removed
+func updatedParameters(instance *v1alpha1.Instance, parameters map[string]string, removed []string) map[string]string {
+	updated := make(map[string]string, len(instance.Spec.Parameters)+len(parameters))
+	for k, v := range instance.Spec.Parameters {
+		updated[k] = v
+	}
+	for k, v := range parameters {
+		updated[k] = v
+	}
+	for _, k := range removed {
+		delete(updated, k)
+	}
+	return updated
+}
+
+// planPollInterval is the interval at which the status of an instance is checked while waiting for its plan
+var planPollInterval = 2 * time.Second
+
+// updateSelected updates all instances matching the selector of the options in batches
+func updateSelected(kc *kudo.Client, options *updateOptions, settings *env.Settings) error {
+	instances, err := kc.ListInstancesBySelector(settings.Namespace, options.Selector)
+	if err != nil {
+		return errors.Wrapf(err, "listing instances matching %s", options.Selector)
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances in namespace %s match the selector %s", settings.Namespace, options.Selector)
+	}
+
+	results := batch.Run(instances, options.Batch, func(instance *v1alpha1.Instance) error {
+		parameters, err := prepareUpdate(kc, instance, options)
+		if err != nil {
+			return errors.Wrapf(err, "updating instance %s", instance.Name)
+		}
+		if err := kc.UpdateInstance(instance.Name, instance.Namespace, nil, parameters, options.RemovedParameters, options.Plan); err != nil {
+			return errors.Wrapf(err, "updating instance %s", instance.Name)
+		}
+		if options.Wait {
+			return kc.WaitForPlan(instance.Name, instance.Namespace, planPollInterval, options.WaitTimeout)
+		}
+		return nil
+	})
+
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			clog.Printf("Instance %s was not updated as an earlier update failed.", r.Instance.Name)
+		case r.Err != nil:
+			clog.Printf("Instance %s failed: %v", r.Instance.Name, r.Err)
+		default:
+			clog.Resultf(r.Instance.Name, "Instance %s was updated.", r.Instance.Name)
+		}
+	}
+	if succeeded, failed, skipped := batch.Summary(results); failed > 0 {
+		return fmt.Errorf("update failed for %d of %d instances, %d updated, %d skipped", failed, len(results), succeeded, skipped)
+	}
 	return nil
 }
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/plan"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/pkg/errors"
+)
+
+// The report of the dry-run of the plan triggered by an update is polled with the interval until the timeout passed
+var (
+	updateDryRunInterval = time.Second
+	updateDryRunTimeout  = 30 * time.Second
+)
+
+// previewUpdates previews the update of the instance or of all instances matching the selector
+func previewUpdates(out io.Writer, kc *kudo.Client, options *updateOptions, settings *env.Settings) error {
+	if options.Selector == "" {
+		instance, err := kc.GetInstance(options.InstanceName, settings.Namespace)
+		if err != nil {
+			return errors.Wrapf(err, "getting instance %s", options.InstanceName)
+		}
+		if instance == nil {
+			return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", options.InstanceName, settings.Namespace)
+		}
+		return previewUpdate(out, kc, instance, options)
+	}
+
+	instances, err := kc.ListInstancesBySelector(settings.Namespace, options.Selector)
+	if err != nil {
+		return errors.Wrapf(err, "listing instances matching %s", options.Selector)
+	}
+	if len(instances) == 0 {
+		return fmt.Errorf("no instances in namespace %s match the selector %s", settings.Namespace, options.Selector)
+	}
+	failed := 0
+	for i := range instances {
+		instance := &instances[i]
+		if err := previewUpdate(out, kc, instance, options); err != nil {
+			fmt.Fprintf(out, "Instance %s failed: %v\n", instance.Name, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("dry-run failed for %d of %d instances", failed, len(instances))
+	}
+	return nil
+}
+
+// previewUpdate prints the parameters the update would change on the instance and the plan it would trigger, nothing
+// is updated. With --diff the manager executes the plan in dry-run mode with the updated parameters and the objects
+// the plan would change are printed.
+func previewUpdate(out io.Writer, kc *kudo.Client, instance *v1alpha1.Instance, options *updateOptions) error {
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+	if err != nil {
+		return errors.Wrapf(err, "getting operatorversion of instance %s", instance.Name)
+	}
+	if ov == nil {
+		return fmt.Errorf("operatorversion %s/%s of instance %s does not exist", instance.OperatorVersionNamespace(), instance.Spec.OperatorVersion.Name, instance.Name)
+	}
+	parameters, err := prepareUpdate(kc, instance, options)
+	if err != nil {
+		return err
+	}
+
+	updated := updatedParameters(instance, parameters, options.RemovedParameters)
+	changes := v1alpha1.ParameterChanges(instance.Spec.Parameters, updated)
+	if len(changes) == 0 {
+		fmt.Fprintf(out, "The update would not change instance %s.\n", instance.Name)
+		return nil
+	}
+	printParameterChanges(out, instance.Name, ov, changes)
+
+	planName := options.Plan
+	if planName == "" {
+		triggered := v1alpha1.TriggeredPlan(ov, instance.Spec.Parameters, updated)
+		if triggered == nil {
+			return fmt.Errorf("the update would not trigger a plan, none of the update plans exists in operatorversion %s", ov.Name)
+		}
+		planName = *triggered
+	}
+	fmt.Fprintf(out, "The update would execute plan %s.\n", planName)
+	if !options.Diff {
+		return nil
+	}
+
+	overrides := make(map[string]*string, len(changes))
+	for _, c := range changes {
+		overrides[c.Name] = c.New
+	}
+	request, err := kc.RequestDryRun(instance.Name, instance.Namespace, planName, overrides)
+	if err != nil {
+		return errors.Wrapf(err, "requesting dry-run of plan %s", planName)
+	}
+	report, err := kc.WaitForDryRun(instance.Name, instance.Namespace, request, updateDryRunInterval, updateDryRunTimeout)
+	if err != nil {
+		return err
+	}
+	plan.PrintDryRun(out, instance.Name, report)
+	if report.Message != "" {
+		return fmt.Errorf("dry-run of plan %s failed: %s", report.Plan, report.Message)
+	}
+	return nil
+}
+
+// printParameterChanges prints the changes of the parameters, the values of sensitive parameters are hidden
+func printParameterChanges(out io.Writer, instanceName string, ov *v1alpha1.OperatorVersion, changes []v1alpha1.ParameterChange) {
+	sensitive := map[string]bool{}
+	for _, p := range ov.Spec.Parameters {
+		sensitive[p.Name] = p.Sensitive
+	}
+	value := func(name string, v *string) string {
+		switch {
+		case v == nil:
+			return "<default>"
+		case sensitive[name]:
+			return "<sensitive>"
+		}
+		return *v
+	}
+
+	fmt.Fprintf(out, "The update would change the following parameters of instance %s:\n", instanceName)
+	for _, c := range changes {
+		fmt.Fprintf(out, "  %s: %s -> %s\n", c.Name, value(c.Name, c.Old), value(c.Name, c.New))
+	}
+}
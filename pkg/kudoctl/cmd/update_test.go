@@ -1,13 +1,20 @@
 package cmd
 
 import (
+	"bytes"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/batch"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/spf13/afero"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -19,14 +26,19 @@ func TestUpdateCommand_Validation(t *testing.T) {
 		instanceName string
 		parameters   map[string]string
 		err          string
+		flags        map[string]string
 	}{
-		{"too many arguments", []string{"aaa"}, "instance", map[string]string{"param": "value"}, "expecting no arguments provided"},
-		{"no instance name", []string{}, "", map[string]string{}, "--instance flag has to be provided"},
-		{"no parameter", []string{}, "instance", map[string]string{}, "need to specify at least one parameter to override "},
+		{"too many arguments", []string{"aaa"}, "instance", map[string]string{"param": "value"}, "expecting no arguments provided", nil},
+		{"no instance name", []string{}, "", map[string]string{}, "--instance flag has to be provided", nil},
+		{"no parameter", []string{}, "instance", map[string]string{}, "need to specify at least one parameter to override ", nil},
+		{"parameter set and removed", []string{}, "instance", map[string]string{}, "parameter param can not be set and removed at the same time", map[string]string{"parameter": "param=value", "remove-param": "param"}},
+		{"instance and selector", []string{}, "instance", map[string]string{}, "--instance and --selector flags can not be used together", map[string]string{"selector": "app=kafka"}},
+		{"invalid max parallel", []string{}, "", map[string]string{}, "max parallel has to be at least 1", map[string]string{"selector": "app=kafka", "parameter": "param=value", "max-parallel": "0"}},
+		{"invalid order", []string{}, "", map[string]string{}, "unknown order random", map[string]string{"selector": "app=kafka", "parameter": "param=value", "order": "random"}},
 	}
 
 	for _, tt := range tests {
-		cmd := newUpdateCmd()
+		cmd := newUpdateCmd(afero.NewMemMapFs())
 		cmd.SetArgs(tt.args)
 		for _, v := range tt.parameters {
 			cmd.Flags().Set("p", v)
@@ -34,6 +46,9 @@ func TestUpdateCommand_Validation(t *testing.T) {
 		if tt.instanceName != "" {
 			cmd.Flags().Set("instance", tt.instanceName)
 		}
+		for k, v := range tt.flags {
+			cmd.Flags().Set(k, v)
+		}
 		_, err := cmd.ExecuteC()
 		if !strings.Contains(err.Error(), tt.err) {
 			t.Errorf("%s: expecting error %s got %v", tt.name, tt.err, err)
@@ -100,3 +115,263 @@ func TestUpdate(t *testing.T) {
 		}
 	}
 }
+
+func TestUpdate_RemoveParameters(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"},
+		Spec: v1alpha1.OperatorVersionSpec{Parameters: []v1alpha1.Parameter{
+			{Name: "replicas", Default: util.String("3")},
+			{Name: "password", Required: true},
+		}},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"replicas": "5", "password": "secret"},
+		},
+	}
+
+	c := newTestClient()
+	if _, err := c.InstallOperatorVersionObjToCluster(ov, "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.InstallInstanceObjToCluster(instance, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := update("test", c, &updateOptions{RemovedParameters: []string{"password"}}, env.DefaultSettings)
+	if err == nil || !strings.Contains(err.Error(), "missing required parameters: password") {
+		t.Errorf("expected removing a required parameter to fail but got %v", err)
+	}
+
+	if err := update("test", c, &updateOptions{RemovedParameters: []string{"replicas", "unset"}}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	updated, err := c.GetInstance("test", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := updated.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []v1alpha1.ParameterChange{{Name: "replicas", Old: util.String("5")}}
+	if changes := revisions[len(revisions)-1].Parameters; !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected the removal of replicas to be recorded but got %v", changes)
+	}
+}
+
+func TestUpdate_Plan(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans:      map[string]v1alpha1.Plan{"deploy": {}, "rolling-restart": {}},
+			Parameters: []v1alpha1.Parameter{{Name: "memory"}},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "test-1.0"}},
+	}
+
+	c := newTestClient()
+	if _, err := c.InstallOperatorVersionObjToCluster(ov, "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.InstallInstanceObjToCluster(instance, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := update("test", c, &updateOptions{Parameters: map[string]string{"memory": "2Gi"}, Plan: "restart"}, env.DefaultSettings)
+	if err == nil || !strings.Contains(err.Error(), "plan restart does not exist in operatorversion test-1.0") {
+		t.Errorf("expected a plan that does not exist to fail but got %v", err)
+	}
+
+	if err := update("test", c, &updateOptions{Parameters: map[string]string{"memory": "2Gi"}, Plan: "rolling-restart"}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	updated, err := c.GetInstance("test", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan := updated.Annotations[v1alpha1.UpdatePlanAnnotation]; plan != "rolling-restart" {
+		t.Errorf("expected the plan rolling-restart to be requested but got %q", plan)
+	}
+}
+
+func TestUpdate_Selector(t *testing.T) {
+	newInstance := func(name, operator string, status v1alpha1.ExecutionStatus) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{util.OperatorLabel: operator}},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: operator + "-1.0"}},
+			Status: v1alpha1.InstanceStatus{
+				PlanStatus: map[string]v1alpha1.PlanStatus{"deploy": {Name: "deploy", Status: status, Message: "step failed"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		instances []*v1alpha1.Instance
+		options   updateOptions
+		updated   []string
+		err       string
+	}{
+		{
+			name:      "no matching instances",
+			instances: []*v1alpha1.Instance{newInstance("zookeeper", "zookeeper", v1alpha1.ExecutionComplete)},
+			err:       "no instances in namespace default match the selector kudo.dev/operator=kafka",
+		},
+		{
+			name: "updates matching instances",
+			instances: []*v1alpha1.Instance{
+				newInstance("kafka-b", "kafka", v1alpha1.ExecutionComplete),
+				newInstance("kafka-a", "kafka", v1alpha1.ExecutionComplete),
+				newInstance("zookeeper", "zookeeper", v1alpha1.ExecutionComplete),
+			},
+			options: updateOptions{Wait: true, Batch: batch.Options{MaxParallel: 2}},
+			updated: []string{"kafka-a", "kafka-b"},
+		},
+		{
+			name: "stops after a failed plan",
+			instances: []*v1alpha1.Instance{
+				newInstance("kafka-a", "kafka", v1alpha1.ExecutionFatalError),
+				newInstance("kafka-b", "kafka", v1alpha1.ExecutionComplete),
+			},
+			options: updateOptions{Wait: true, Batch: batch.Options{MaxParallel: 1}},
+			updated: []string{"kafka-a"},
+			err:     "update failed for 1 of 2 instances, 0 updated, 1 skipped",
+		},
+	}
+
+	for _, tt := range tests {
+		c := newTestClient()
+		for _, i := range tt.instances {
+			if _, err := c.InstallInstanceObjToCluster(i, "default"); err != nil {
+				t.Fatalf("%s: failed to create instance: %v", tt.name, err)
+			}
+		}
+
+		options := tt.options
+		options.Selector = util.OperatorLabel + "=kafka"
+		options.Parameters = map[string]string{"param": "value"}
+		options.WaitTimeout = time.Second
+		err := updateSelected(c, &options, env.DefaultSettings)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		}
+
+		for _, i := range tt.instances {
+			instance, err := c.GetInstance(i.Name, "default")
+			if err != nil {
+				t.Fatalf("%s: failed to get instance: %v", tt.name, err)
+			}
+			updated := instance.Spec.Parameters["param"] == "value"
+			if expected := contains(tt.updated, i.Name); updated != expected {
+				t.Errorf("%s: expected instance %s to be updated: %v", tt.name, i.Name, expected)
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUpdate_DryRun(t *testing.T) {
+	ov := &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-1.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Plans: map[string]v1alpha1.Plan{"deploy": {}, "resize": {}},
+			Parameters: []v1alpha1.Parameter{
+				{Name: "memory", Trigger: "resize"},
+				{Name: "password", Sensitive: true},
+			},
+		},
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"memory": "1Gi", "password": "secret"},
+		},
+	}
+	clientset := fake.NewSimpleClientset(instance, ov)
+	c := kudo.NewClientFromK8s(clientset)
+	options := &updateOptions{
+		InstanceName:      "test",
+		Parameters:        map[string]string{"memory": "2Gi"},
+		RemovedParameters: []string{"password"},
+		DryRun:            true,
+	}
+
+	var out bytes.Buffer
+	if err := previewUpdates(&out, c, options, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := `The update would change the following parameters of instance test:
+  memory: 1Gi -> 2Gi
+  password: <sensitive> -> <default>
+The update would execute plan resize.
+`
+	if out.String() != expected {
+		t.Errorf("expected output\n%s\nbut got\n%s", expected, out.String())
+	}
+	unchanged, _ := c.GetInstance("test", "default")
+	if !reflect.DeepEqual(unchanged.Spec.Parameters, instance.Spec.Parameters) {
+		t.Errorf("expected the instance not to be updated but got %v", unchanged.Spec.Parameters)
+	}
+
+	// the manager answers the dry-run with the requested parameters
+	defer func(interval time.Duration) { updateDryRunInterval = interval }(updateDryRunInterval)
+	updateDryRunInterval = time.Millisecond
+	done := make(chan error)
+	go func() {
+		for {
+			requested, err := c.GetInstance("test", "default")
+			if err != nil {
+				done <- err
+				return
+			}
+			plan, request := requested.GetPendingDryRun()
+			if plan == "" {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			parameters, err := requested.GetDryRunParameters()
+			if err != nil {
+				done <- err
+				return
+			}
+			requested.Status.DryRun = &v1alpha1.DryRunStatus{Request: request, Plan: plan, Changes: []v1alpha1.ResourceChange{{
+				Kind: "StatefulSet", Name: "test", Step: "main.app", Task: "app", Action: v1alpha1.ResourceUpdated,
+				Fields: []v1alpha1.FieldDrift{{Path: "spec.template.spec.containers[0].resources.limits.memory", Expected: parameters["memory"], Actual: "1Gi"}},
+			}}}
+			_, err = clientset.KudoV1alpha1().Instances("default").Update(requested)
+			done <- err
+			return
+		}
+	}()
+
+	out.Reset()
+	options.Diff = true
+	if err := previewUpdates(&out, c, options, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Plan resize of instance test would make the following changes:\n  Step main.app, task app:\n    Update StatefulSet test\n      spec.template.spec.containers[0].resources.limits.memory: 1Gi -> 2Gi\n") {
+		t.Errorf("expected the changes of the dry-run but got\n%s", out.String())
+	}
+}
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/cmd/install"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/resolver"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
-	util "github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/encryption"
 
 	"github.com/Masterminds/semver"
 	"github.com/pkg/errors"
@@ -26,8 +30,14 @@ package in the repository, a path to package in *.tgz format, or a path to an un
   # Upgrade flink to the version 1.1.1
   kubectl kudo upgrade flink --instance dev-flink --version 1.1.1
 
+  # Upgrade flink to the most recent 1.x version
+  kubectl kudo upgrade flink --instance dev-flink --version '<2'
+
   # By default arguments are all reused from the previous installation, if you need to modify, use -p
-  kubectl kudo upgrade flink --instance dev-flink -p param=xxx`
+  kubectl kudo upgrade flink --instance dev-flink -p param=xxx
+
+  # Upgrade flink even though the pre-upgrade checks of the new version fail
+  kubectl kudo upgrade flink --instance dev-flink --skip-pre-upgrade-checks`
 )
 
 type options struct {
@@ -35,15 +45,26 @@ type options struct {
 	InstanceName   string
 	PackageVersion string
 	Parameters     map[string]string
+	// SkipPreUpgradeChecks upgrades the instance without executing the pre-upgrade checks of the new version
+	SkipPreUpgradeChecks bool
+	// PreUpgradeCheckTimeout is the time to wait for the manager to execute the pre-upgrade checks
+	PreUpgradeCheckTimeout time.Duration
+	// Keyring encrypts the values of sensitive parameters
+	Keyring *encryption.Keyring
 }
 
 // defaultOptions initializes the install command options to its defaults
-var defaultOptions = &options{}
+var defaultOptions = &options{PreUpgradeCheckTimeout: 30 * time.Second}
+
+// preUpgradeCheckInterval is the interval the instance is polled with while waiting for the pre-upgrade checks
+var preUpgradeCheckInterval = time.Second
 
 // newUpgradeCmd creates the install command for the CLI
 func newUpgradeCmd(fs afero.Fs) *cobra.Command {
 	options := defaultOptions
 	var parameters []string
+	var parameterFiles []string
+	var encryptionConfig string
 	upgradeCmd := &cobra.Command{
 		Use:     "upgrade <name>",
 		Short:   "Upgrade KUDO package.",
@@ -52,7 +73,11 @@ func newUpgradeCmd(fs afero.Fs) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Prior to command execution we parse and validate passed arguments
 			var err error
-			options.Parameters, err = install.GetParameterMap(parameters)
+			options.Parameters, err = install.GetParameters(fs, parameterFiles, parameters)
+			if err != nil {
+				return errors.WithMessage(err, "could not parse arguments")
+			}
+			options.Keyring, err = install.GetKeyring(encryptionConfig)
 			if err != nil {
 				return errors.WithMessage(err, "could not parse arguments")
 			}
@@ -62,8 +87,12 @@ func newUpgradeCmd(fs afero.Fs) *cobra.Command {
 
 	upgradeCmd.Flags().StringVar(&options.InstanceName, "instance", "", "The instance name.")
 	upgradeCmd.Flags().StringArrayVarP(&parameters, "parameter", "p", nil, "The parameter name and value separated by '='")
-	upgradeCmd.Flags().StringVar(&options.RepoName, "repo", "", "Name of repository configuration to use. (default defined by context)")
-	upgradeCmd.Flags().StringVar(&options.PackageVersion, "version", "", "A specific package version on the official repository. When installing from other sources than official repository, version from inside operator.yaml will be used. (default to the most recent)")
+	upgradeCmd.Flags().StringArrayVar(&parameterFiles, "parameter-file", nil, "A YAML file with parameters, can be repeated. Later files are merged on top of earlier ones and -p takes precedence")
+	upgradeCmd.Flags().StringVar(&encryptionConfig, "encryption-config", "", "Encryption configuration file whose first provider encrypts the values of sensitive parameters, including those of the new version")
+	upgradeCmd.Flags().StringVar(&options.RepoName, "repo", "", "Name of repository configuration to use. (default defined by the client configuration or context)")
+	upgradeCmd.Flags().BoolVar(&options.SkipPreUpgradeChecks, "skip-pre-upgrade-checks", false, "Upgrade even if the pre-upgrade checks declared by the new version fail.")
+	upgradeCmd.Flags().DurationVar(&options.PreUpgradeCheckTimeout, "pre-upgrade-check-timeout", options.PreUpgradeCheckTimeout, "The time to wait for the manager to execute the pre-upgrade checks.")
+	upgradeCmd.Flags().StringVar(&options.PackageVersion, "version", "", "A specific package version or a version constraint, e.g. '<2', on the official repository. When installing from other sources than official repository, version from inside operator.yaml will be used. (default to the most recent)")
 
 	return upgradeCmd
 }
@@ -86,21 +115,30 @@ func runUpgrade(args []string, options *options, fs afero.Fs, settings *env.Sett
 	}
 	packageToUpgrade := args[0]
 
-	kc, err := kudo.NewClient(settings.Namespace, settings.KubeConfig)
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
 	if err != nil {
 		return errors.Wrap(err, "creating kudo client")
 	}
 
 	// Resolve the package to upgrade to
-	repository, err := repo.ClientFromSettings(fs, settings.Home, options.RepoName)
+	repository, err := repo.ClientFromSettings(fs, settings.Home, settings.RepoName(options.RepoName))
 	if err != nil {
 		return errors.WithMessage(err, "could not build operator repository")
 	}
-	crds, err := install.GetPackageCRDs(packageToUpgrade, options.PackageVersion, repository)
+	crds, err := resolver.GetCRDs(resolver.New(fs, repository), packageToUpgrade, options.PackageVersion)
 	if err != nil {
 		return errors.Wrapf(err, "failed to resolve package CRDs for operator: %s", packageToUpgrade)
 	}
 
+	// The new version might require a newer KUDO than the one running in the cluster
+	client, err := kube.GetKubeClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return errors.Wrap(err, "creating kubernetes client")
+	}
+	if err := kc.ValidateServerForOperator(crds.Operator, install.ManagerVersion(client.KubeClient)); err != nil {
+		return err
+	}
+
 	return upgrade(crds.OperatorVersion, kc, options, settings)
 }
 
@@ -117,13 +155,15 @@ func upgrade(newOv *v1alpha1.OperatorVersion, kc *kudo.Client, options *options,
 		return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", options.InstanceName, settings.Namespace)
 	}
 
-	// Check OperatorVersion and if upgraded version is higher than current version
-	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, settings.Namespace)
+	// Check OperatorVersion and if upgraded version is higher than current version. The new OperatorVersion is
+	// installed next to the current one, which might be in a catalog namespace shared with other namespaces.
+	catalogNamespace := instance.OperatorVersionNamespace()
+	ov, err := kc.GetOperatorVersion(instance.Spec.OperatorVersion.Name, catalogNamespace)
 	if err != nil {
 		return errors.Wrap(err, "retrieving existing operator version")
 	}
 	if ov == nil {
-		return fmt.Errorf("no operator version for this operator installed yet for %s in namespace %s. Please use install command if you want to install new operator into cluster", operatorName, settings.Namespace)
+		return fmt.Errorf("no operator version for this operator installed yet for %s in namespace %s. Please use install command if you want to install new operator into cluster", operatorName, catalogNamespace)
 	}
 	oldVersion, err := semver.NewVersion(ov.Spec.Version)
 	if err != nil {
@@ -137,23 +177,92 @@ func upgrade(newOv *v1alpha1.OperatorVersion, kc *kudo.Client, options *options,
 		return fmt.Errorf("upgraded version %s is the same or smaller as current version %s -> not upgrading", nextOperatorVersion, ov.Spec.Version)
 	}
 
+	// The values of deprecated parameters are moved to the parameters replacing them in the new version
+	parameters := make(map[string]string, len(instance.Spec.Parameters)+len(options.Parameters))
+	for k, v := range instance.Spec.Parameters {
+		parameters[k] = v
+	}
+	for k, v := range options.Parameters {
+		parameters[k] = v
+	}
+	parameters, warnings, err := v1alpha1.TranslateDeprecatedParameters(newOv, parameters)
+	for _, w := range warnings {
+		clog.Printf("WARNING: %s", w)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "upgrading to version %s", nextOperatorVersion)
+	}
+	if err := v1alpha1.ValidateParameters(newOv, parameters); err != nil {
+		return errors.Wrapf(err, "upgrading to version %s", nextOperatorVersion)
+	}
+	if parameters, err = install.EncryptSensitiveParameters(newOv, parameters, options.Keyring); err != nil {
+		return errors.Wrapf(err, "upgrading to version %s", nextOperatorVersion)
+	}
+
 	// install OV
-	versionsInstalled, err := kc.OperatorVersionsInstalled(operatorName, settings.Namespace)
+	versionsInstalled, err := kc.OperatorVersionsInstalled(operatorName, catalogNamespace)
 	if err != nil {
 		return errors.Wrap(err, "retrieving existing operator versions")
 	}
 	if !install.VersionExists(versionsInstalled, nextOperatorVersion) {
-		if _, err := kc.InstallOperatorVersionObjToCluster(newOv, settings.Namespace); err != nil {
+		if _, err := kc.InstallOperatorVersionObjToCluster(newOv, catalogNamespace); err != nil {
 			return errors.Wrapf(err, "failed installing OperatorVersion %s for operator: %s", nextOperatorVersion, operatorName)
 		}
-		fmt.Printf("operatorversion.%s/%s successfully created\n", newOv.APIVersion, newOv.Name)
+		clog.Printf("operatorversion.%s/%s successfully created", newOv.APIVersion, newOv.Name)
+	}
+
+	switch {
+	case len(newOv.Spec.PreUpgradeChecks) == 0:
+	case options.SkipPreUpgradeChecks:
+		clog.Printf("WARNING: skipping the pre-upgrade checks of version %s", newOv.Spec.Version)
+	default:
+		if err := runPreUpgradeChecks(newOv, instance, kc, options); err != nil {
+			return err
+		}
+		// the instance changed with the check request and its report
+		instance, err = kc.GetInstance(options.InstanceName, settings.Namespace)
+		if err != nil {
+			return errors.Wrapf(err, "getting instance %s", options.InstanceName)
+		}
+		if instance == nil {
+			return fmt.Errorf("instance %s in namespace %s does not exist in the cluster", options.InstanceName, settings.Namespace)
+		}
 	}
 
 	// Change instance to point to the new OV and optionally update arguments
-	err = kc.UpdateInstance(options.InstanceName, settings.Namespace, util.String(newOv.Name), options.Parameters)
+	err = kc.UpgradeInstance(instance, newOv.Name, parameters, options.SkipPreUpgradeChecks)
 	if err != nil {
 		return errors.Wrapf(err, "updating instance to point to new operatorversion %s", newOv.Name)
 	}
-	fmt.Printf("instance.%s/%s successfully updated\n", instance.APIVersion, instance.Name)
+	clog.Resultf(instance.Name, "instance.%s/%s successfully updated", instance.APIVersion, instance.Name)
+	return nil
+}
+
+// runPreUpgradeChecks lets the manager execute the pre-upgrade checks of the new operatorversion and fails unless all
+// of them pass. The instance is not switched to the new operatorversion before the checks passed, the admission
+// webhook rejects the upgrade otherwise.
+func runPreUpgradeChecks(newOv *v1alpha1.OperatorVersion, instance *v1alpha1.Instance, kc *kudo.Client, options *options) error {
+	request, err := kc.RequestPreUpgradeCheck(instance.Name, instance.Namespace, newOv.Name)
+	if err != nil {
+		return errors.Wrapf(err, "requesting the pre-upgrade checks of version %s", newOv.Spec.Version)
+	}
+	report, err := kc.WaitForPreUpgradeCheck(instance.Name, instance.Namespace, request, preUpgradeCheckInterval, options.PreUpgradeCheckTimeout)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range report.Results {
+		result := "passed"
+		if !r.Passed {
+			result = "failed"
+		}
+		clog.Printf("Pre-upgrade check %s %s: %s", r.Name, result, r.Message)
+	}
+	if report.Message != "" {
+		return fmt.Errorf("pre-upgrade checks of version %s could not be executed: %s", newOv.Spec.Version, report.Message)
+	}
+	if !report.Passed() {
+		return fmt.Errorf("pre-upgrade checks of version %s failed, instance %s was not upgraded", newOv.Spec.Version, instance.Name)
+	}
 	return nil
 }
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/spf13/afero"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testcore "k8s.io/client-go/testing"
 )
 
 func TestUpgradeCommand_Validation(t *testing.T) {
@@ -130,3 +133,55 @@ func TestUpgrade(t *testing.T) {
 		}
 	}
 }
+
+func TestUpgrade_DeprecatedParameters(t *testing.T) {
+	ov := func(version string) *v1alpha1.OperatorVersion {
+		return &v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "test-" + version, Namespace: "default"},
+			Spec: v1alpha1.OperatorVersionSpec{
+				Version:  version,
+				Operator: v1.ObjectReference{Name: "test"},
+				Parameters: []v1alpha1.Parameter{
+					{Name: "NODES", Deprecated: &v1alpha1.ParameterDeprecation{ReplacedBy: "REPLICAS", GraceVersion: "3.0.0"}},
+					{Name: "REPLICAS", Default: util.String("3")},
+				},
+			},
+		}
+	}
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0.0"},
+			Parameters:      map[string]string{"NODES": "5", "MEMORY": "1Gi"},
+		},
+	}
+
+	clientset := fake.NewSimpleClientset(instance, ov("1.0.0"))
+	c := kudo.NewClientFromK8s(clientset)
+	if err := upgrade(ov("2.0.0"), c, &options{InstanceName: "test"}, env.DefaultSettings); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	// the fake clientset does not remove fields set to null by merge patches, so the patch is checked instead
+	var patch struct {
+		Spec struct {
+			Parameters map[string]*string `json:"parameters"`
+		} `json:"spec"`
+	}
+	for _, a := range clientset.Actions() {
+		if p, ok := a.(testcore.PatchAction); ok {
+			if err := json.Unmarshal(p.GetPatch(), &patch); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expected := map[string]*string{"NODES": nil, "REPLICAS": util.String("5"), "MEMORY": util.String("1Gi")}
+	if !reflect.DeepEqual(patch.Spec.Parameters, expected) {
+		t.Errorf("expected parameters %v but got %v", expected, patch.Spec.Parameters)
+	}
+
+	c = kudo.NewClientFromK8s(fake.NewSimpleClientset(instance, ov("1.0.0")))
+	err := upgrade(ov("3.0.0"), c, &options{InstanceName: "test"}, env.DefaultSettings)
+	if err == nil || !strings.Contains(err.Error(), "parameter NODES is no longer supported since version 3.0.0") {
+		t.Errorf("expected the deprecated parameter to be rejected but got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package doctor
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	v1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managerRestartWarning is the number of restarts of a manager container from which on it is reported
+const managerRestartWarning = 3
+
+const (
+	initHint    = "install KUDO with 'kubectl kudo init'"
+	upgradeHint = "upgrade KUDO with 'kubectl kudo init --upgrade'"
+)
+
+func checkConnectivity(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	version, err := client.KubeClient.Discovery().ServerVersion()
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("the cluster is not reachable: %v", err),
+			Hint: "check the current context of the kubeconfig with 'kubectl config current-context' and the credentials of its user"}}
+	}
+	clog.V(2).Printf("connected to Kubernetes %s", version.GitVersion)
+	return nil
+}
+
+// checkCRDs compares the installed CRDs with the CRDs of this version of the CLI
+func checkCRDs(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	var findings Findings
+	for _, obj := range kudoinit.CRDs() {
+		expected := obj.(*apiextv1beta1.CustomResourceDefinition)
+		crd, err := client.ExtClient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(expected.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			findings = append(findings, Finding{Severity: Error, Message: fmt.Sprintf("crd %s is not installed", expected.Name), Hint: initHint + " or " + upgradeHint})
+			continue
+		}
+		if err != nil {
+			findings = append(findings, Finding{Severity: Error, Message: fmt.Sprintf("failed to get crd %s: %v", expected.Name, err)})
+			continue
+		}
+		for _, c := range crd.Status.Conditions {
+			if c.Type == apiextv1beta1.Established && c.Status == apiextv1beta1.ConditionFalse {
+				findings = append(findings, Finding{Severity: Error, Message: fmt.Sprintf("crd %s is not established: %s", crd.Name, c.Message)})
+			}
+		}
+		switch {
+		case crd.Spec.Version != expected.Spec.Version:
+			findings = append(findings, Finding{Severity: Error,
+				Message: fmt.Sprintf("crd %s serves version %s instead of %s", crd.Name, crd.Spec.Version, expected.Spec.Version),
+				Hint:    "use the version of the CLI that matches the installed KUDO version or " + upgradeHint})
+		case !sameValidation(crd.Spec.Validation, expected.Spec.Validation):
+			findings = append(findings, Finding{Severity: Warning,
+				Message: fmt.Sprintf("crd %s differs from the one of this version of the CLI, new fields may be dropped", crd.Name),
+				Hint:    "use the version of the CLI that matches the installed KUDO version or " + upgradeHint})
+		}
+	}
+	return findings
+}
+
+// sameValidation compares the validation of an installed CRD with the expected one. Both are normalized like the API
+// server stores them, so that e.g. an empty list and a list the server omitted are equal.
+func sameValidation(installed, expected *apiextv1beta1.CustomResourceValidation) bool {
+	return equality.Semantic.DeepEqual(normalizeValidation(installed), normalizeValidation(expected))
+}
+
+// normalizeValidation returns the validation after a round trip through its JSON representation
+func normalizeValidation(v *apiextv1beta1.CustomResourceValidation) *apiextv1beta1.CustomResourceValidation {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	normalized := &apiextv1beta1.CustomResourceValidation{}
+	if err := json.Unmarshal(b, normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// checkWebhookCertificate reports a webhook certificate that expired or expires soon, the webhooks reject or ignore
+// all requests once it expired
+func checkWebhookCertificate(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	cert, err := kudoinit.WebhookCertificate(client.KubeClient.CoreV1(), opts.ManagerNamespace)
+	if kerrors.IsNotFound(err) {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("secret %s/%s does not exist", opts.ManagerNamespace, kudoinit.WebhookSecretName), Hint: initHint}}
+	}
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("failed to read the webhook certificate: %v", err)}}
+	}
+	if cert == nil {
+		return nil
+	}
+	renewHint := fmt.Sprintf("delete the secret %s/%s and the webhook configurations of KUDO, then %s",
+		opts.ManagerNamespace, kudoinit.WebhookSecretName, upgradeHint)
+	expiry := cert.NotAfter.UTC().Format(time.RFC3339)
+	if now.After(cert.NotAfter) {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("the webhook certificate expired at %s", expiry), Hint: renewHint}}
+	}
+	if cert.NotAfter.Sub(now) < opts.CertificateWarning {
+		return Findings{{Severity: Warning, Message: fmt.Sprintf("the webhook certificate expires at %s", expiry), Hint: renewHint}}
+	}
+	return nil
+}
+
+// checkManager reports manager pods that are not ready or restart repeatedly
+func checkManager(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	pods, err := kudoinit.GetKUDOPods(client.KubeClient.CoreV1(), opts.ManagerNamespace)
+	if err != nil {
+		return Findings{{Severity: Error, Message: err.Error(), Hint: initHint}}
+	}
+	logsHint := fmt.Sprintf("inspect the logs with 'kubectl kudo manager logs --manager-namespace %s'", opts.ManagerNamespace)
+	var findings Findings
+	for _, pod := range pods {
+		if !podReady(pod) {
+			findings = append(findings, Finding{Severity: Error, Message: fmt.Sprintf("manager pod %s is not ready (%s)", pod.Name, pod.Status.Phase), Hint: logsHint})
+		}
+		for _, s := range pod.Status.ContainerStatuses {
+			if s.RestartCount >= managerRestartWarning {
+				findings = append(findings, Finding{Severity: Warning,
+					Message: fmt.Sprintf("container %s of manager pod %s restarted %d times", s.Name, pod.Name, s.RestartCount), Hint: logsHint})
+			}
+		}
+	}
+	return findings
+}
+
+func podReady(pod v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// checkStuckPlans reports instances that execute a plan for longer than the threshold, e.g. because a resource never
+// becomes healthy
+func checkStuckPlans(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	var findings Findings
+	err := kc.ForEachInstance(opts.Namespace, metav1.ListOptions{}, func(instances []v1alpha1.Instance) error {
+		for _, i := range instances {
+			plan := i.GetPlanInProgress()
+			if plan == nil || plan.StartedAt.IsZero() {
+				continue
+			}
+			if running := now.Sub(plan.StartedAt.Time); running > opts.StuckAfter {
+				findings = append(findings, Finding{Severity: Warning,
+					Message: fmt.Sprintf("plan %s of instance %s/%s is in progress since %s", plan.Name, i.Namespace, i.Name, running.Round(time.Second)),
+					Hint:    fmt.Sprintf("inspect the plan with 'kubectl kudo plan status --instance %s -n %s'", i.Name, i.Namespace)})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		findings = append(findings, Finding{Severity: Error, Message: fmt.Sprintf("failed to list instances: %v", err)})
+	}
+	return findings
+}
+
+// checkOperatorVersions reports operatorversions whose operator does not exist and operatorversions that are not
+// used by any instance
+func checkOperatorVersions(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	operators := map[string]bool{}
+	err := kc.ForEachOperator(opts.Namespace, metav1.ListOptions{}, func(ops []v1alpha1.Operator) error {
+		for _, o := range ops {
+			operators[o.Name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("failed to list operators: %v", err)}}
+	}
+	ovs, err := kc.ListOperatorVersions(opts.Namespace)
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("failed to list operatorversions: %v", err)}}
+	}
+
+	var findings Findings
+	for _, ov := range ovs {
+		if !operators[ov.Spec.Operator.Name] {
+			findings = append(findings, Finding{Severity: Warning,
+				Message: fmt.Sprintf("operatorversion %s/%s belongs to operator %s which does not exist", ov.Namespace, ov.Name, ov.Spec.Operator.Name),
+				Hint:    "install the operator again or delete the operatorversion"})
+		}
+	}
+
+	unused, err := kc.UnusedOperatorVersions(opts.Namespace)
+	if err != nil {
+		return append(findings, Finding{Severity: Error, Message: fmt.Sprintf("failed to find unused operatorversions: %v", err)})
+	}
+	for _, ov := range unused {
+		findings = append(findings, Finding{Severity: Info,
+			Message: fmt.Sprintf("operatorversion %s/%s is not used by any instance", ov.Namespace, ov.Name),
+			Hint:    fmt.Sprintf("remove unused operatorversions with 'kubectl kudo gc --confirm -n %s'", ov.Namespace)})
+	}
+	return findings
+}
+
+// checkProtection reports protected instances whose deletion is not rejected, because the webhook protecting them is
+// not installed
+func checkProtection(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	var protected []v1alpha1.Instance
+	err := kc.ForEachInstance(opts.Namespace, metav1.ListOptions{}, func(instances []v1alpha1.Instance) error {
+		for _, i := range instances {
+			if i.IsProtected() {
+				protected = append(protected, i)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("failed to list instances: %v", err)}}
+	}
+	if len(protected) == 0 {
+		return nil
+	}
+
+	installed, err := kudoinit.InstanceProtectionInstalled(client.KubeClient.AdmissionregistrationV1beta1())
+	if err != nil {
+		return Findings{{Severity: Error, Message: fmt.Sprintf("failed to get the webhook protecting instances: %v", err)}}
+	}
+	if installed {
+		return nil
+	}
+	var findings Findings
+	for _, i := range protected {
+		findings = append(findings, Finding{Severity: Warning,
+			Message: fmt.Sprintf("instance %s/%s is protected from deletion, but only kudoctl respects the protection as the webhooks of KUDO are not installed", i.Namespace, i.Name),
+			Hint:    "install the webhooks with 'kubectl kudo init --webhook --upgrade'"})
+	}
+	return findings
+}
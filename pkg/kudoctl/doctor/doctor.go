@@ -0,0 +1,155 @@
+// Package doctor diagnoses common misconfigurations of a KUDO installation and reports them as findings with a
+// severity and a hint how to resolve them.
+package doctor
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+)
+
+// Severity is the severity of a finding
+type Severity int
+
+const (
+	// Info findings do not affect KUDO, e.g. objects that could be cleaned up
+	Info Severity = iota
+	// Warning findings affect KUDO soon or only some instances
+	Warning
+	// Error findings prevent KUDO from working
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "INFO"
+	case Warning:
+		return "WARNING"
+	case Error:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Options defines the namespaces and the thresholds of the diagnosis
+type Options struct {
+	// ManagerNamespace is the namespace the KUDO manager is running in
+	ManagerNamespace string
+	// Namespace is the namespace the instances and operatorversions are diagnosed in
+	Namespace string
+	// StuckAfter is the time after which a plan in progress is considered stuck
+	StuckAfter time.Duration
+	// CertificateWarning is the remaining validity of the webhook certificate below which it is reported
+	CertificateWarning time.Duration
+}
+
+// DefaultOptions provides the default options of the diagnosis
+var DefaultOptions = Options{StuckAfter: time.Hour, CertificateWarning: 30 * 24 * time.Hour}
+
+// Finding is a single problem found by a check
+type Finding struct {
+	Severity Severity
+	// Check is the name of the check that found the problem
+	Check   string
+	Message string
+	// Hint describes how to resolve the problem, it may be empty
+	Hint string
+}
+
+// Findings are the problems found by a diagnosis
+type Findings []Finding
+
+// check finds the problems of one aspect of the installation
+type check struct {
+	name     string
+	diagnose func(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings
+}
+
+// checks are run in order, the first check verifies that the cluster is reachable at all
+var checks = []check{
+	{name: "connectivity", diagnose: checkConnectivity},
+	{name: "crds", diagnose: checkCRDs},
+	{name: "webhook", diagnose: checkWebhookCertificate},
+	{name: "manager", diagnose: checkManager},
+	{name: "plans", diagnose: checkStuckPlans},
+	{name: "protection", diagnose: checkProtection},
+	{name: "operatorversions", diagnose: checkOperatorVersions},
+}
+
+// Diagnose runs all checks and returns their findings. The remaining checks are skipped if the cluster is not
+// reachable, as they would only report the same problem again.
+func Diagnose(client *kube.Client, kc *kudo.Client, opts Options, now time.Time) Findings {
+	var findings Findings
+	for _, c := range checks {
+		found := c.diagnose(client, kc, opts, now)
+		for i := range found {
+			found[i].Check = c.name
+		}
+		findings = append(findings, found...)
+		if c.name == "connectivity" && found.Count(Error) > 0 {
+			break
+		}
+	}
+	return findings
+}
+
+// Run diagnoses the cluster of the settings and prints the findings. An error is returned if any finding is an error,
+// including a kubeconfig that does not allow to connect to the cluster at all.
+func Run(out io.Writer, opts Options, settings *env.Settings) error {
+	findings := diagnoseSettings(opts, settings, time.Now())
+	findings.Print(out)
+	return findings.Err()
+}
+
+func diagnoseSettings(opts Options, settings *env.Settings, now time.Time) Findings {
+	hint := "check the kubeconfig given with --kubeconfig or $KUBECONFIG and the context given with --context"
+	client, err := kube.GetKubeClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return Findings{{Severity: Error, Check: "connectivity", Message: err.Error(), Hint: hint}}
+	}
+	kc, err := kudo.NewClient(settings.KubeConfig, settings.Context)
+	if err != nil {
+		return Findings{{Severity: Error, Check: "connectivity", Message: err.Error(), Hint: hint}}
+	}
+	return Diagnose(client, kc, opts, now)
+}
+
+// Count returns the number of findings with the severity
+func (f Findings) Count(s Severity) int {
+	n := 0
+	for _, finding := range f {
+		if finding.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Err returns an error if any finding is an error
+func (f Findings) Err() error {
+	if n := f.Count(Error); n > 0 {
+		return fmt.Errorf("found %d problem(s) that prevent KUDO from working", n)
+	}
+	return nil
+}
+
+// Print writes one line per finding followed by its hint, and a summary
+func (f Findings) Print(out io.Writer) {
+	for _, finding := range f {
+		fmt.Fprintf(out, "%-7s [%s] %s\n", finding.Severity, finding.Check, finding.Message)
+		if finding.Hint != "" {
+			fmt.Fprintf(out, "        -> %s\n", finding.Hint)
+		}
+	}
+	if len(f) == 0 {
+		fmt.Fprintln(out, "No problems found")
+		return
+	}
+	fmt.Fprintf(out, "%d error(s), %d warning(s), %d info(s)\n", f.Count(Error), f.Count(Warning), f.Count(Info))
+}
@@ -0,0 +1,224 @@
+package doctor
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+	cmdInit "github.com/kudobuilder/kudo/pkg/kudoctl/cmd/init"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admissionregistration/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+var opts = Options{ManagerNamespace: "kudo-system", Namespace: "default", StuckAfter: time.Hour, CertificateWarning: 30 * 24 * time.Hour}
+
+func managerPod(ready v1.ConditionStatus, restarts int32) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kudo-controller-manager-0",
+			Namespace: "kudo-system",
+			Labels:    map[string]string{"app": "kudo-manager", "control-plane": "controller-manager", "controller-tools.k8s.io": "1.0"},
+		},
+		Status: v1.PodStatus{
+			Phase:             v1.PodRunning,
+			Conditions:        []v1.PodCondition{{Type: v1.PodReady, Status: ready}},
+			ContainerStatuses: []v1.ContainerStatus{{Name: "manager", RestartCount: restarts}},
+		},
+	}
+}
+
+func webhookSecret(t *testing.T) *v1.Secret {
+	cert, err := cmdInit.NewWebhookCertificate("kudo-system")
+	assert.NoError(t, err)
+	return &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: kudoinit.WebhookSecretName, Namespace: "kudo-system"},
+		Data:       map[string][]byte{"tls.crt": cert.Cert, "tls.key": cert.Key},
+	}
+}
+
+func instance(name string, plan *v1alpha1.PlanStatus) *v1alpha1.Instance {
+	i := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0"}},
+	}
+	if plan != nil {
+		i.Status.PlanStatus = map[string]v1alpha1.PlanStatus{plan.Name: *plan}
+	}
+	return i
+}
+
+func operatorVersion(name, operator string) *v1alpha1.OperatorVersion {
+	return &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1alpha1.OperatorVersionSpec{Operator: v1.ObjectReference{Name: operator}},
+	}
+}
+
+func protectorConfiguration() *admissionv1beta1.ValidatingWebhookConfiguration {
+	return &admissionv1beta1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: "kudo-manager-instance-protector"}}
+}
+
+// storedCRDs returns the CRDs of the CLI like the API server returns them: empty lists are omitted and lists that were
+// not set are returned empty
+func storedCRDs(t *testing.T) []runtime.Object {
+	var crds []runtime.Object
+	for _, obj := range kudoinit.CRDs() {
+		b, err := json.Marshal(obj)
+		assert.NoError(t, err)
+		crd := &apiextv1beta1.CustomResourceDefinition{}
+		assert.NoError(t, json.Unmarshal(b, crd))
+		if schema := crd.Spec.Validation.OpenAPIV3Schema; schema != nil && schema.Required == nil {
+			schema.Required = []string{}
+		}
+		crds = append(crds, crd)
+	}
+	return crds
+}
+
+func TestDiagnose(t *testing.T) {
+	now := time.Now()
+	started := metav1.NewTime(now.Add(-2 * time.Hour))
+	healthyKudo := []runtime.Object{
+		&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "default"}},
+		operatorVersion("kafka-1.0", "kafka"),
+		instance("kafka", &v1alpha1.PlanStatus{Name: "deploy", Status: v1alpha1.ExecutionComplete, StartedAt: started}),
+	}
+
+	protectedInstance := instance("kafka", nil)
+	protectedInstance.Annotations = map[string]string{v1alpha1.ProtectedAnnotation: "true"}
+
+	tests := []struct {
+		name     string
+		kube     []runtime.Object
+		ext      []runtime.Object
+		kudo     []runtime.Object
+		now      time.Time
+		expected []string
+	}{
+		{
+			name: "healthy installation",
+			kube: []runtime.Object{managerPod(v1.ConditionTrue, 0), webhookSecret(t)},
+			ext:  kudoinit.CRDs(),
+			kudo: healthyKudo,
+			now:  now,
+		},
+		{
+			name: "crds as stored by the api server",
+			kube: []runtime.Object{managerPod(v1.ConditionTrue, 0), webhookSecret(t)},
+			ext:  storedCRDs(t),
+			kudo: healthyKudo,
+			now:  now,
+		},
+		{
+			name: "nothing installed",
+			now:  now,
+			expected: []string{
+				"ERROR [crds] crd operators.kudo.dev is not installed",
+				"ERROR [crds] crd operatorversions.kudo.dev is not installed",
+				"ERROR [crds] crd instances.kudo.dev is not installed",
+				"ERROR [crds] crd kudoconfigs.kudo.dev is not installed",
+				"ERROR [crds] crd clustertargets.kudo.dev is not installed",
+				"ERROR [crds] crd planexecutions.kudo.dev is not installed",
+				"ERROR [webhook] secret kudo-system/kudo-webhook-server-secret does not exist",
+				"ERROR [manager] could not find KUDO manager in namespace kudo-system",
+			},
+		},
+		{
+			name: "expired certificate and unhealthy manager",
+			kube: []runtime.Object{managerPod(v1.ConditionFalse, 5), webhookSecret(t)},
+			ext:  kudoinit.CRDs(),
+			kudo: healthyKudo,
+			now:  now.Add(11 * 365 * 24 * time.Hour),
+			expected: []string{
+				"ERROR [webhook] the webhook certificate expired at",
+				"ERROR [manager] manager pod kudo-controller-manager-0 is not ready (Running)",
+				"WARNING [manager] container manager of manager pod kudo-controller-manager-0 restarted 5 times",
+			},
+		},
+		{
+			name: "stuck plan and orphaned operatorversions",
+			kube: []runtime.Object{managerPod(v1.ConditionTrue, 0), webhookSecret(t)},
+			ext:  kudoinit.CRDs(),
+			kudo: []runtime.Object{
+				operatorVersion("kafka-1.0", "kafka"),
+				operatorVersion("zookeeper-1.0", "zookeeper"),
+				instance("kafka", &v1alpha1.PlanStatus{Name: "deploy", Status: v1alpha1.ExecutionInProgress, StartedAt: started}),
+			},
+			now: now,
+			expected: []string{
+				"WARNING [plans] plan deploy of instance default/kafka is in progress since 2h0m0s",
+				"WARNING [operatorversions] operatorversion default/kafka-1.0 belongs to operator kafka which does not exist",
+				"WARNING [operatorversions] operatorversion default/zookeeper-1.0 belongs to operator zookeeper which does not exist",
+				"INFO [operatorversions] operatorversion default/zookeeper-1.0 is not used by any instance",
+			},
+		},
+		{
+			name: "protected instance with webhooks",
+			kube: []runtime.Object{managerPod(v1.ConditionTrue, 0), webhookSecret(t), protectorConfiguration()},
+			ext:  kudoinit.CRDs(),
+			kudo: []runtime.Object{healthyKudo[0], healthyKudo[1], protectedInstance},
+			now:  now,
+		},
+		{
+			name: "protected instance without webhooks",
+			kube: []runtime.Object{managerPod(v1.ConditionTrue, 0), webhookSecret(t)},
+			ext:  kudoinit.CRDs(),
+			kudo: []runtime.Object{healthyKudo[0], healthyKudo[1], protectedInstance},
+			now:  now,
+			expected: []string{
+				"WARNING [protection] instance default/kafka is protected from deletion, but only kudoctl respects the protection as the webhooks of KUDO are not installed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &kube.Client{KubeClient: kubefake.NewSimpleClientset(tt.kube...), ExtClient: apiextfake.NewSimpleClientset(tt.ext...)}
+			kc := kudo.NewClientFromK8s(fake.NewSimpleClientset(tt.kudo...))
+
+			findings := Diagnose(client, kc, opts, tt.now)
+			actual := make([]string, len(findings))
+			for i, f := range findings {
+				actual[i] = strings.Join([]string{f.Severity.String(), "[" + f.Check + "]", f.Message}, " ")
+			}
+			assert.Equal(t, len(tt.expected), len(actual), "%v", actual)
+			for i := range tt.expected {
+				if i < len(actual) {
+					assert.True(t, strings.HasPrefix(actual[i], tt.expected[i]), "expected %q but got %q", tt.expected[i], actual[i])
+				}
+			}
+			assert.Equal(t, findings.Count(Error) > 0, findings.Err() != nil)
+		})
+	}
+}
+
+func TestFindings_Print(t *testing.T) {
+	var out strings.Builder
+	Findings{}.Print(&out)
+	assert.Equal(t, "No problems found\n", out.String())
+
+	out.Reset()
+	Findings{
+		{Severity: Error, Check: "manager", Message: "manager pod kudo-controller-manager-0 is not ready (Pending)", Hint: "inspect the logs"},
+		{Severity: Info, Check: "operatorversions", Message: "operatorversion default/kafka-1.0 is not used by any instance"},
+	}.Print(&out)
+	expected := `ERROR   [manager] manager pod kudo-controller-manager-0 is not ready (Pending)
+        -> inspect the logs
+INFO    [operatorversions] operatorversion default/kafka-1.0 is not used by any instance
+1 error(s), 0 warning(s), 1 info(s)
+`
+	assert.Equal(t, expected, out.String())
+}
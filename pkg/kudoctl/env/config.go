@@ -0,0 +1,146 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+)
+
+// Keys of the client configuration, they are named like the flags whose defaults they set
+const (
+	NamespaceKey      = "namespace"
+	OutputKey         = "output"
+	RepoKey           = "repo"
+	RequestTimeoutKey = "request-timeout"
+)
+
+// ConfigKeys are the keys of the client configuration
+var ConfigKeys = []string{NamespaceKey, OutputKey, RepoKey, RequestTimeoutKey}
+
+// OutputFormats are the output formats the client configuration accepts
+var OutputFormats = []string{"yaml"}
+
+// Config holds the defaults of the CLI stored in $KUDO_HOME/config, so that they do not have to be repeated on every
+// invocation. Flags take precedence over them.
+type Config struct {
+	// Namespace is the default of --namespace, it takes precedence over the namespace of the kubeconfig context
+	Namespace string `json:"namespace,omitempty"`
+	// Output is the default of --output
+	Output string `json:"output,omitempty"`
+	// Repo is the default of --repo, it takes precedence over the repository context
+	Repo string `json:"repo,omitempty"`
+	// RequestTimeout is the default of --request-timeout
+	RequestTimeout string `json:"requestTimeout,omitempty"`
+	// Telemetry is the opt-in of the anonymous usage reporting, it is managed with the telemetry command
+	Telemetry *TelemetryConfig `json:"telemetry,omitempty"`
+}
+
+// TelemetryConfig holds the opt-in of the anonymous usage reporting
+type TelemetryConfig struct {
+	// Enabled is true once the user opted in
+	Enabled bool `json:"enabled"`
+	// ID is a random identifier of the installation generated on opt-in, it is removed on opt-out
+	ID string `json:"id,omitempty"`
+	// Endpoint is the URL usage is reported to, it takes precedence over the endpoint the CLI was built with
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// LoadConfig reads the client configuration from the file, an empty configuration is returned if it does not exist
+func LoadConfig(fs afero.Fs, path string) (*Config, error) {
+	b, err := afero.ReadFile(fs, path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client configuration %s: %w", path, err)
+	}
+	c := &Config{}
+	if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, fmt.Errorf("failed to parse client configuration %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// WriteFile writes the client configuration to the file, the directory is created if it does not exist
+func (c *Config) WriteFile(fs afero.Fs, path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, path, data, 0644)
+}
+
+// Get returns the value of the key, an empty string if it is not set
+func (c *Config) Get(key string) (string, error) {
+	field, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	return *field, nil
+}
+
+// Set validates and sets the value of the key, an empty value unsets it
+func (c *Config) Set(key, value string) error {
+	field, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	if value != "" {
+		if err := validateConfigValue(key, value); err != nil {
+			return err
+		}
+	}
+	*field = value
+	return nil
+}
+
+// Timeout returns the request timeout, 0 if it is not set
+func (c *Config) Timeout() (time.Duration, error) {
+	if c.RequestTimeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(c.RequestTimeout)
+}
+
+func (c *Config) field(key string) (*string, error) {
+	switch key {
+	case NamespaceKey:
+		return &c.Namespace, nil
+	case OutputKey:
+		return &c.Output, nil
+	case RepoKey:
+		return &c.Repo, nil
+	case RequestTimeoutKey:
+		return &c.RequestTimeout, nil
+	}
+	return nil, fmt.Errorf("unknown configuration key %s, expecting one of %s", key, strings.Join(ConfigKeys, ", "))
+}
+
+func validateConfigValue(key, value string) error {
+	switch key {
+	case OutputKey:
+		for _, f := range OutputFormats {
+			if value == f {
+				return nil
+			}
+		}
+		return fmt.Errorf("unsupported output format %s, expecting one of %s", value, strings.Join(OutputFormats, ", "))
+	case RequestTimeoutKey:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid request timeout %s: %v", value, err)
+		}
+		if d < 0 {
+			return fmt.Errorf("invalid request timeout %s: it must not be negative", value)
+		}
+	}
+	return nil
+}
@@ -3,9 +3,13 @@ package env
 import (
 	"os"
 	"path/filepath"
+	"time"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
 
+	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
 	"k8s.io/client-go/util/homedir"
 )
@@ -17,35 +21,79 @@ var DefaultKudoHome = filepath.Join(homedir.HomeDir(), ".kudo")
 type Settings struct {
 	// KubeConfig is the path to an explicit kubeconfig file. This overwrites the value in $KUBECONFIG
 	KubeConfig string
+	// Context is the kubeconfig context to use, defaults to the current context
+	Context string
 	// Home is the local path to kudo home directory
 	Home kudohome.Home
-	// Namespace used when working with Kubernetes
+	// Namespace used when working with Kubernetes, defaults to the namespace of the client configuration or of the
+	// kubeconfig context
 	Namespace string
+	// RequestTimeout is the timeout of the requests to the API server, 0 keeps the default of each client
+	RequestTimeout time.Duration
+	// Config holds the defaults of the client configuration in the home directory
+	Config *Config
 }
 
 // DefaultSettings initializes the settings to its defaults
 var DefaultSettings = &Settings{
 	Namespace: "default",
+	Config:    &Config{},
 }
 
-// envMap maps flag names to envvars
+// envMap maps flag names to envvars. $KUBECONFIG is not mapped to the kubeconfig flag as it can list multiple files
+// that are merged when the kubeconfig is loaded.
 var envMap = map[string]string{
-	"home":       "KUDO_HOME",
-	"kubeconfig": "KUBECONFIG",
+	"home": "KUDO_HOME",
 }
 
-// AddFlags binds flags to the given flagset.
+// AddFlags binds flags to the given flagset. The Kubernetes flags are named like the ones of kubectl so that they
+// work the same way when kudoctl is invoked as kubectl plugin.
 func (s *Settings) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar((*string)(&s.Home), "home", DefaultKudoHome, "location of your KUDO config.")
-	fs.StringVar(&s.KubeConfig, "kubeconfig", os.Getenv("HOME")+"/.kube/config", "Path to your Kubernetes configuration file.")
-	fs.StringVarP(&s.Namespace, "namespace", "n", "default", "Target namespace for the object.")
+	fs.StringVar(&s.KubeConfig, "kubeconfig", "", "Path to the kubeconfig file to use, defaults to $KUBECONFIG or ~/.kube/config.")
+	fs.StringVar(&s.Context, "context", "", "The name of the kubeconfig context to use.")
+	fs.StringVarP(&s.Namespace, "namespace", "n", "", "Target namespace for the object, defaults to the namespace of the kubeconfig context.")
+	fs.DurationVar(&s.RequestTimeout, "request-timeout", 0, "The timeout of requests to the API server, 0 keeps the default of the command.")
 }
 
-// Init sets values from the environment.
+// Init sets values from the environment and the client configuration in the home directory and defaults the namespace
+// to the one of the kubeconfig context. Flags take precedence over the environment, which takes precedence over the
+// client configuration.
 func (s *Settings) Init(f *pflag.FlagSet) {
 	for name, envar := range envMap {
 		setFlagFromEnv(name, envar, f)
 	}
+
+	config, err := LoadConfig(afero.NewOsFs(), s.Home.ConfigFile())
+	if err != nil {
+		clog.Printf("WARNING: ignoring the client configuration: %v", err)
+		config = &Config{}
+	}
+	s.Config = config
+	if s.Namespace == "" {
+		s.Namespace = config.Namespace
+	}
+	if !f.Changed("request-timeout") {
+		if timeout, err := config.Timeout(); err != nil {
+			clog.Printf("WARNING: ignoring the request timeout of the client configuration: %v", err)
+		} else {
+			s.RequestTimeout = timeout
+		}
+	}
+
+	if s.Namespace == "" {
+		s.Namespace = kube.Namespace(s.KubeConfig, s.Context)
+	}
+	kube.RequestTimeout = s.RequestTimeout
+}
+
+// RepoName returns the name of the repository to use, the given name of the --repo flag or the default of the client
+// configuration. An empty name selects the repository context.
+func (s *Settings) RepoName(name string) string {
+	if name == "" && s.Config != nil {
+		return s.Config.Repo
+	}
+	return name
 }
 
 // setFlagFromEnv looks up and sets a flag if the corresponding environment variable changed.
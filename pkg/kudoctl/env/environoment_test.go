@@ -1,10 +1,14 @@
 package env
 
 import (
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/kudohome"
 
 	"github.com/spf13/pflag"
@@ -25,7 +29,7 @@ func TestEnvSettings(t *testing.T) {
 			name:    "defaults",
 			args:    []string{},
 			home:    DefaultKudoHome,
-			kconfig: os.Getenv("HOME") + "/.kube/config",
+			kconfig: "",
 		},
 		{
 			name:    "with flags set",
@@ -38,7 +42,7 @@ func TestEnvSettings(t *testing.T) {
 			args:    []string{},
 			envars:  map[string]string{"KUDO_HOME": "/bar", "KUBECONFIG": "/foo"},
 			home:    "/bar",
-			kconfig: "/foo",
+			kconfig: "",
 		},
 		{
 			name:    "with flags and ENV set",
@@ -98,3 +102,122 @@ func resetEnv(envars map[string]string) func() {
 		}
 	}
 }
+
+const kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://localhost:6443
+users:
+- name: user
+contexts:
+- name: dev
+  context:
+    cluster: cluster
+    user: user
+    namespace: dev
+- name: prod
+  context:
+    cluster: cluster
+    user: user
+current-context: dev
+`
+
+func TestEnvSettings_Namespace(t *testing.T) {
+	f, err := ioutil.TempFile("", "kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name      string
+		args      []string
+		envars    map[string]string
+		namespace string
+	}{
+		{name: "namespace of current context", args: []string{"--kubeconfig", f.Name()}, namespace: "dev"},
+		{name: "namespace of current context from KUBECONFIG", envars: map[string]string{"KUBECONFIG": f.Name()}, namespace: "dev"},
+		{name: "context without namespace", args: []string{"--kubeconfig", f.Name(), "--context", "prod"}, namespace: "default"},
+		{name: "namespace flag", args: []string{"--kubeconfig", f.Name(), "-n", "test"}, namespace: "test"},
+	}
+
+	resetOrigEnv := resetEnv(map[string]string{"KUBECONFIG": ""})
+	defer resetOrigEnv()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.envars {
+				os.Setenv(k, v)
+			}
+
+			flags := pflag.NewFlagSet("testing", pflag.ContinueOnError)
+
+			settings := &Settings{}
+			settings.AddFlags(flags)
+			flags.Parse(tt.args)
+
+			settings.Init(flags)
+
+			if settings.Namespace != tt.namespace {
+				t.Errorf("expected namespace %q, got %q", tt.namespace, settings.Namespace)
+			}
+
+			resetEnv(tt.envars)
+		})
+	}
+}
+
+func TestEnvSettings_Config(t *testing.T) {
+	home, err := ioutil.TempDir("", "kudo-home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	config := "namespace: team-a\nrepo: local\nrequestTimeout: 10s\n"
+	if err := ioutil.WriteFile(filepath.Join(home, "config"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		args      []string
+		namespace string
+		timeout   time.Duration
+		repo      string
+	}{
+		{name: "defaults of the config", args: []string{"--home", home}, namespace: "team-a", timeout: 10 * time.Second, repo: "local"},
+		{name: "flags", args: []string{"--home", home, "-n", "test", "--request-timeout", "1m"}, namespace: "test", timeout: time.Minute, repo: "local"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := pflag.NewFlagSet("testing", pflag.ContinueOnError)
+
+			settings := &Settings{}
+			settings.AddFlags(flags)
+			flags.Parse(tt.args)
+
+			settings.Init(flags)
+
+			if settings.Namespace != tt.namespace {
+				t.Errorf("expected namespace %q, got %q", tt.namespace, settings.Namespace)
+			}
+			if settings.RequestTimeout != tt.timeout {
+				t.Errorf("expected request timeout %s, got %s", tt.timeout, settings.RequestTimeout)
+			}
+			if repo := settings.RepoName(""); repo != tt.repo {
+				t.Errorf("expected repo %q, got %q", tt.repo, repo)
+			}
+			if repo := settings.RepoName("community"); repo != "community" {
+				t.Errorf("expected the repo flag to take precedence, got %q", repo)
+			}
+		})
+	}
+	kube.RequestTimeout = 0
+}
@@ -0,0 +1,172 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+)
+
+const progressWidth = 30
+
+// Download fetches href into a buffer. Failed downloads are retried and an interrupted download is resumed with a
+// range request if the server supports it. If digest is set, the sha256 checksum of the content is verified.
+func (c *Client) Download(href string, digest string) (*bytes.Buffer, error) {
+	buf := bytes.NewBuffer(nil)
+
+	var err error
+	for attempt := 0; attempt <= c.Retries; attempt++ {
+		if attempt > 0 {
+			clog.V(2).Printf("retrying download of %s after %d bytes (attempt %d of %d): %v", href, buf.Len(), attempt, c.Retries, err)
+			time.Sleep(time.Duration(attempt) * c.RetryDelay)
+		}
+		var retry bool
+		retry, err = c.fetch(href, buf)
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if digest != "" {
+		sum, err := files.Sha256Sum(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			return nil, err
+		}
+		if sum != digest {
+			return nil, fmt.Errorf("checksum mismatch for %s: expected %s but got %s", href, digest, sum)
+		}
+		clog.V(4).Printf("verified checksum %s of %s", sum, href)
+	}
+	return buf, nil
+}
+
+// StatusError is returned for a download that failed with an unexpected HTTP status
+type StatusError struct {
+	URL        string
+	Status     string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("failed to fetch %s : %s", e.URL, e.Status)
+}
+
+// fetch downloads href into buf. If buf is not empty the download is resumed at its current length. The returned
+// bool is true if the error is temporary and the download can be retried.
+func (c *Client) fetch(href string, buf *bytes.Buffer) (bool, error) {
+	req, err := newRequest(href)
+	if err != nil {
+		return false, err
+	}
+	offset := int64(buf.Len())
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			clog.V(4).Printf("error when closing the response body %s", err)
+		}
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		clog.V(4).Printf("resuming download of %s at %d bytes", href, offset)
+	case resp.StatusCode == http.StatusOK:
+		// the server does not support range requests, start over
+		buf.Reset()
+		offset = 0
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return true, &StatusError{URL: href, Status: resp.Status, StatusCode: resp.StatusCode}
+	default:
+		return false, &StatusError{URL: href, Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+
+	var w io.Writer = buf
+	if c.Progress != nil {
+		total := int64(-1)
+		if resp.ContentLength >= 0 {
+			total = offset + resp.ContentLength
+		}
+		p := &progress{out: c.Progress, name: downloadName(href), current: offset, total: total}
+		defer p.done()
+		w = io.MultiWriter(buf, p)
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return true, fmt.Errorf("failed to read %s: %w", href, err)
+	}
+	return false, nil
+}
+
+// downloadName returns the last element of the URL path to be shown in the progress bar
+func downloadName(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || path.Base(u.Path) == "/" || path.Base(u.Path) == "." {
+		return href
+	}
+	return path.Base(u.Path)
+}
+
+// progress is a writer printing a progress bar of the bytes written to it
+type progress struct {
+	out     io.Writer
+	name    string
+	current int64
+	total   int64
+	printed string
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.current += int64(len(b))
+	p.print()
+	return len(b), nil
+}
+
+func (p *progress) print() {
+	var line string
+	if p.total > 0 {
+		percent := int(p.current * 100 / p.total)
+		filled := progressWidth * percent / 100
+		line = fmt.Sprintf("%s [%s%s] %3d%% %s/%s", p.name, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled),
+			percent, byteCount(p.current), byteCount(p.total))
+	} else {
+		line = fmt.Sprintf("%s %s", p.name, byteCount(p.current))
+	}
+	// only redraw the line if it changed
+	if line != p.printed {
+		fmt.Fprintf(p.out, "\r%s", line)
+		p.printed = line
+	}
+}
+
+func (p *progress) done() {
+	fmt.Fprintln(p.out)
+}
+
+// byteCount formats a number of bytes in a human readable way
+func byteCount(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,135 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const content = "kudo package content that is downloaded in more than one piece"
+
+func testClient() *Client {
+	c := NewClient()
+	c.Progress = nil
+	c.RetryDelay = 0
+	return c
+}
+
+func checksum(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))
+}
+
+// interruptingHandler serves only the first half of the content to the first request and supports range requests
+func interruptingHandler(t *testing.T, requests *[]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Header.Get("Range"))
+		if len(*requests) == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(content[:len(content)/2]))
+			// the connection is closed before all promised bytes were sent
+			return
+		}
+		var offset int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset); err != nil {
+			t.Errorf("expected range request but got %q", r.Header.Get("Range"))
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte(content[offset:]))
+	}
+}
+
+func TestDownload_Resume(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(interruptingHandler(t, &requests))
+	defer server.Close()
+
+	buf, err := testClient().Download(server.URL+"/zk-0.1.0.tgz", checksum(content))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if buf.String() != content {
+		t.Errorf("expected %q but got %q", content, buf.String())
+	}
+	expected := []string{"", fmt.Sprintf("bytes=%d-", len(content)/2)}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected requests %v but got %v", expected, requests)
+	}
+}
+
+func TestDownload_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		digest   string
+		requests int
+		err      string
+	}{
+		{name: "retries server errors", statuses: []int{500, 503, 200}, requests: 3},
+		{name: "gives up after retries", statuses: []int{500, 500, 500, 500, 500}, requests: 4, err: "500 Internal Server Error"},
+		{name: "does not retry client errors", statuses: []int{404, 200}, requests: 1, err: "404 Not Found"},
+		{name: "verifies checksum", statuses: []int{200}, digest: checksum("other"), requests: 1, err: "checksum mismatch"},
+	}
+
+	for _, tt := range tests {
+		var requests int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.statuses[requests])
+			requests++
+			_, _ = w.Write([]byte(content))
+		}))
+
+		_, err := testClient().Download(server.URL, tt.digest)
+		server.Close()
+
+		if requests != tt.requests {
+			t.Errorf("%s: expected %d requests but got %d", tt.name, tt.requests, requests)
+		}
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.name, err)
+		}
+		if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: expected error containing %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestDownload_Progress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	c := testClient()
+	c.Progress = &out
+	if _, err := c.Download(server.URL+"/index.yaml", ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := fmt.Sprintf("index.yaml [%s] 100%% %dB/%dB\n", strings.Repeat("=", progressWidth), len(content), len(content))
+	if !strings.HasSuffix(out.String(), expected) {
+		t.Errorf("expected progress to end with %q but got %q", expected, out.String())
+	}
+}
+
+func TestByteCount(t *testing.T) {
+	tests := map[int64]string{
+		0:               "0B",
+		1023:            "1023B",
+		1024:            "1.0KiB",
+		1536:            "1.5KiB",
+		5 * 1024 * 1024: "5.0MiB",
+		3 << 30:         "3.0GiB",
+	}
+	for b, expected := range tests {
+		if got := byteCount(b); got != expected {
+			t.Errorf("byteCount(%d) = %s, want %s", b, got, expected)
+		}
+	}
+}
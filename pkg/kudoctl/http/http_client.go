@@ -6,56 +6,82 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"time"
 
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/version"
 )
 
+const (
+	defaultRetries    = 3
+	defaultRetryDelay = time.Second
+)
+
 // Client is client used to communicate with KUDO repositories
 // it enriches HTTP client with expected headers etc.
 type Client struct {
 	client *http.Client
+	// Retries is the number of times a failed download is retried
+	Retries int
+	// RetryDelay is the delay before the first retry, it grows linearly with every attempt
+	RetryDelay time.Duration
+	// Progress receives a progress bar of running downloads, no progress is shown if nil
+	Progress io.Writer
 }
 
 // Get performs HTTP get on KUDO repository
 func (c *Client) Get(href string) (*bytes.Buffer, error) {
-	buf := bytes.NewBuffer(nil)
+	return c.Download(href, "")
+}
 
-	req, err := http.NewRequest("GET", href, nil)
-	if err != nil {
-		return buf, err
+// NewClient creates HTTP client. Proxies are configured by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment
+// variables. Download progress is shown when stderr is a terminal.
+func NewClient() *Client {
+	var client Client
+	tr := &http.Transport{
+		DisableCompression: true,
+		Proxy:              proxyFromEnvironment,
 	}
-	req.Header.Set("User-Agent", fmt.Sprintf("KUDO/%s", strings.TrimPrefix(version.Get().GitVersion, "v")))
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return buf, err
-	}
-	if resp.StatusCode != 200 {
-		return buf, fmt.Errorf("failed to fetch %s : %s", href, resp.Status)
+	client.client = &http.Client{Transport: tr}
+	client.Retries = defaultRetries
+	client.RetryDelay = defaultRetryDelay
+	if isTerminal(os.Stderr) && !clog.Quiet() {
+		client.Progress = os.Stderr
 	}
+	return &client
+}
 
-	_, err = io.Copy(buf, resp.Body)
+// proxyFromEnvironment returns the proxy configured in the environment for the request and logs it
+func proxyFromEnvironment(req *http.Request) (*url.URL, error) {
+	proxy, err := http.ProxyFromEnvironment(req)
 	if err != nil {
-		fmt.Printf("Error when copying response buffer %s", err)
+		return nil, fmt.Errorf("invalid proxy configuration: %w", err)
 	}
-	err = resp.Body.Close()
-	if err != nil {
-		fmt.Printf("Error when closing the response body %s", err)
+	if proxy != nil {
+		clog.V(4).Printf("using proxy %s for %s", proxy.Host, req.URL.Host)
 	}
-	return buf, err
+	return proxy, nil
 }
 
-// NewClient creates HTTP client
-func NewClient() *Client {
-	var client Client
-	tr := &http.Transport{
-		DisableCompression: true,
-		Proxy:              http.ProxyFromEnvironment,
+// isTerminal returns true if the file is a character device
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
 	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
 
-	client.client = &http.Client{Transport: tr}
-	return &client
+func newRequest(href string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("KUDO/%s", strings.TrimPrefix(version.Get().GitVersion, "v")))
+	return req, nil
 }
 
 // IsValidURL returns true if the url is a Parsable URL
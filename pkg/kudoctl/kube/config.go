@@ -2,6 +2,7 @@ package kube
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 
@@ -11,18 +12,24 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// RequestTimeout is the timeout of the requests of the clients created with the REST config of GetRestConfig, 0 keeps
+// the default timeout of each client. It is set from the settings of the CLI.
+var RequestTimeout time.Duration
+
 // Client provides access different K8S clients
 type Client struct {
 	KubeClient kubernetes.Interface
 	ExtClient  apiextensionsclient.Interface
 }
 
-// GetConfig returns a Kubernetes client config for a given kubeconfig.
-func GetConfig(kubeconfig string) clientcmd.ClientConfig {
+// GetConfig returns a Kubernetes client config loaded the same way kubectl does: an explicit kubeconfig takes
+// precedence over the files listed in $KUBECONFIG, which take precedence over ~/.kube/config. The context defaults
+// to the current context of the kubeconfig.
+func GetConfig(kubeconfig, context string) clientcmd.ClientConfig {
 	rules := clientcmd.NewDefaultClientConfigLoadingRules()
 	rules.DefaultClientConfig = &clientcmd.DefaultClientConfig
 
-	overrides := &clientcmd.ConfigOverrides{ClusterDefaults: clientcmd.ClusterDefaults}
+	overrides := &clientcmd.ConfigOverrides{ClusterDefaults: clientcmd.ClusterDefaults, CurrentContext: context}
 
 	if kubeconfig != "" {
 		rules.ExplicitPath = kubeconfig
@@ -31,18 +38,48 @@ func GetConfig(kubeconfig string) clientcmd.ClientConfig {
 	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
 }
 
-func getRestConfig(kubeconfig string) (*rest.Config, error) {
-	config, err := GetConfig(kubeconfig).ClientConfig()
+// GetRestConfig returns the REST config of the context in the kubeconfig
+func GetRestConfig(kubeconfig, context string) (*rest.Config, error) {
+	config, err := GetConfig(kubeconfig, context).ClientConfig()
 	if err != nil {
 		return nil, fmt.Errorf("could not get Kubernetes config using configuration %q: %s", kubeconfig, err)
 	}
 	clog.V(4).Printf("configuration from %q finds host %v", kubeconfig, config.Host)
+	if RequestTimeout > 0 {
+		config.Timeout = RequestTimeout
+	}
 	return config, nil
 }
 
-// GetKubeClient provides k8s client for kubeconfig
-func GetKubeClient(kubeconfig string) (*Client, error) {
-	config, err := getRestConfig(kubeconfig)
+// Namespace returns the namespace of the context in the kubeconfig, "default" if the context has no namespace
+func Namespace(kubeconfig, context string) string {
+	namespace, _, err := GetConfig(kubeconfig, context).Namespace()
+	if err != nil {
+		clog.V(4).Printf("could not determine the namespace from kubeconfig: %v", err)
+		return "default"
+	}
+	return namespace
+}
+
+// User returns the user of the context in the kubeconfig or an empty string if it is not known
+func User(kubeconfig, context string) string {
+	config, err := GetConfig(kubeconfig, context).RawConfig()
+	if err != nil {
+		clog.V(4).Printf("could not determine the current user from kubeconfig: %v", err)
+		return ""
+	}
+	if context == "" {
+		context = config.CurrentContext
+	}
+	if ctx, ok := config.Contexts[context]; ok {
+		return ctx.AuthInfo
+	}
+	return ""
+}
+
+// GetKubeClient provides k8s client for the context in the kubeconfig
+func GetKubeClient(kubeconfig, context string) (*Client, error) {
+	config, err := GetRestConfig(kubeconfig, context)
 	if err != nil {
 		return nil, err
 	}
@@ -29,3 +29,8 @@ func (h Home) Repository() string {
 func (h Home) RepositoryFile() string {
 	return h.path("repository", "repositories.yaml")
 }
+
+// ConfigFile returns the path to the client configuration file.
+func (h Home) ConfigFile() string {
+	return h.path("config")
+}
@@ -0,0 +1,627 @@
+package kudoinit
+
+import (
+	"strings"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+//Defines the CRDs that the KUDO manager implements and watches.
+
+// ageColumn is the printer column of the age of an object, it is only shown by default if no other columns are defined
+var ageColumn = apiextv1beta1.CustomResourceColumnDefinition{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"}
+
+// operatorCrd provides the Operator CRD manifest for printing
+func operatorCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateOperator()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generateOperator() *apiextv1beta1.CustomResourceDefinition {
+
+	maintainers := map[string]apiextv1beta1.JSONSchemaProps{
+		"name":  apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"email": apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+	links := map[string]apiextv1beta1.JSONSchemaProps{
+		"name": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"url":  apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+	stringArray := apiextv1beta1.JSONSchemaProps{Type: "array",
+		Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "string"}},
+	}
+	apiRequirements := map[string]apiextv1beta1.JSONSchemaProps{
+		"group":    apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"version":  apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"resource": apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+	requirements := map[string]apiextv1beta1.JSONSchemaProps{
+		"apis": apiextv1beta1.JSONSchemaProps{Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: apiRequirements,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"featureGates": stringArray,
+	}
+
+	crd := generateCrd("Operator", "operators")
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"description":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kubernetesVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kudoVersion":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"maintainers": apiextv1beta1.JSONSchemaProps{Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: maintainers,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"url":        apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"icon":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"keywords":   stringArray,
+		"categories": stringArray,
+		"links": apiextv1beta1.JSONSchemaProps{Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: links,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"requirements": apiextv1beta1.JSONSchemaProps{Type: "object", Properties: requirements},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Type: "object"},
+		"status":     apiextv1beta1.JSONSchemaProps{Type: "object"},
+	}
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	return crd
+}
+
+// operatorVersionCrd provides the OperatorVersion CRD manifest for printing
+func operatorVersionCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateOperatorVersion()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generateOperatorVersion() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateCrd("OperatorVersion", "operatorversions")
+	dependProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"referenceName": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name specifies the name of the dependency.  Referenced via this in defaults.config"},
+		"crdVersion":    apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Version captures the requirements for what versions of the above object are allowed Example: ^3.1.4"},
+	}
+	paramProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"default": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Default is a default value if no parameter is provided by the instance"},
+		"deprecated": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Deprecated marks the parameter as deprecated, values set for it are moved to the parameter replacing it", Properties: map[string]apiextv1beta1.JSONSchemaProps{
+			"replacedBy":   apiextv1beta1.JSONSchemaProps{Type: "string", Description: "ReplacedBy is the name of the parameter replacing the deprecated one"},
+			"graceVersion": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "GraceVersion is the first version of the operator that rejects instances setting the deprecated parameter"},
+			"message":      apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Message is shown to users setting the deprecated parameter"},
+		}},
+		"description": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Description captures a longer description of how the variable will be used"},
+		"displayName": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Human friendly crdVersion of the parameter name"},
+		"name":        apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name is the string that should be used in the template file for example, if `name: COUNT` then using the variable `.Params.COUNT`"},
+		"required":    apiextv1beta1.JSONSchemaProps{Type: "boolean", Description: "Required specifies if the parameter is required to be provided by all instances, or whether a default can suffice"},
+		"trigger":     apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Trigger identifies the plan that gets executed when this parameter changes in the Instance object. Default is `update` if present, or `deploy` if not present"},
+		"type":        apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Type is the type of the parameter value, either `string` (the default), `array` or `object`"},
+		"schema":      apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Schema is the OpenAPI v3 schema the value of an array or object parameter must conform to"},
+		"sensitive":   apiextv1beta1.JSONSchemaProps{Type: "boolean", Description: "Sensitive marks parameters whose values are encrypted before they are stored in the Instance if kudoctl is configured with an encryption provider"},
+		"items": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Items is the schema the items of an array parameter must conform to", Properties: map[string]apiextv1beta1.JSONSchemaProps{
+			"type": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Type is the type of the items: string, integer, boolean or object"},
+			"required": apiextv1beta1.JSONSchemaProps{Type: "array", Description: "Required are the keys items of type object must define",
+				Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "string"}}},
+		}},
+	}
+	taskProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"name": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"spec": apiextv1beta1.JSONSchemaProps{Type: "object"},
+	}
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"appVersion":       apiextv1beta1.JSONSchemaProps{Type: "string", Description: "AppVersion is the version of the application the operator manages"},
+		"connectionString": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "ConnectionString defines a mustached string that can be used to connect to an instance of the Operator"},
+		"constants":        apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Constants are values of the operator that templates reference as .Constants, e.g. internal tunables"},
+		"crds":             apiextv1beta1.JSONSchemaProps{Type: "object", Description: "CRDs maps the file names of the CustomResourceDefinitions bundled with the operator to their manifests"},
+		"defaultPlans": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "DefaultPlans declares the plans KUDO executes when an instance is created, updated or upgraded",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"deploy":  apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Deploy is executed when an instance is created, it defaults to deploy"},
+				"update":  apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Update is executed when parameters without a trigger of an instance change, it defaults to update"},
+				"upgrade": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Upgrade is executed when an instance is upgraded to the OperatorVersion, it defaults to upgrade"},
+			},
+		},
+		"dependencies": apiextv1beta1.JSONSchemaProps{
+			Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Required:   []string{"referenceName", "crdVersion"},
+				Properties: dependProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"endpoints": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Endpoints are the ways to connect to an instance of the operator, rendered once a plan of the instance completed",
+			Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object", Required: []string{"name", "value"}}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"operator": apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"overlays": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Overlays maps an environment name to the patches that are applied to the rendered templates of the instances selecting it"},
+		"parameters": apiextv1beta1.JSONSchemaProps{
+			Type: "array",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: paramProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"plans": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Plans specify a map a plans that specify how to"},
+		"preUpgradeChecks": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "PreUpgradeChecks have to pass before an instance is upgraded to this OperatorVersion. The admission webhook rejects upgrades unless the checks passed recently.",
+			Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object", Required: []string{"name"}}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"tasks": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "List of all tasks available in this OperatorVersions",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: taskProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"templates": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "List of go templates YAML files that define the application operator instance"},
+		"upgradableFrom": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "UpgradableFrom lists all OperatorVersions that can upgrade to this OperatorVersion",
+			Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object"}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"crdVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Type: "object"},
+		"status":     apiextv1beta1.JSONSchemaProps{Type: "object"},
+	}
+
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	crd.Spec.AdditionalPrinterColumns = []apiextv1beta1.CustomResourceColumnDefinition{
+		{Name: "Operator", Type: "string", JSONPath: ".spec.operator.name", Description: "Name of the operator"},
+		{Name: "Version", Type: "string", JSONPath: ".spec.version", Description: "Version of the operator package"},
+		{Name: "App Version", Type: "string", JSONPath: ".spec.appVersion", Description: "Version of the application", Priority: 1},
+		ageColumn,
+	}
+	return crd
+}
+
+// InstanceCrd provides the Instance CRD manifest for printing
+func InstanceCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateInstance()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generateInstance() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateCrd("Instance", "instances")
+	dependProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"referenceName": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name specifies the name of the dependency.  Referenced via this in defaults.config"},
+		"crdVersion":    apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Version captures the requirements for what versions of the above object are allowed Example: ^3.1.4"},
+	}
+	scheduleProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"name":     apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"plan":     apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the plan that is triggered"},
+		"schedule": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Schedule in cron format, evaluated in UTC"},
+		"concurrencyPolicy": apiextv1beta1.JSONSchemaProps{
+			Type:        "string",
+			Description: "What happens if another plan is in progress when the schedule is due",
+			Enum:        []apiextv1beta1.JSON{{Raw: []byte(`"Forbid"`)}, {Raw: []byte(`"Replace"`)}},
+		},
+	}
+	patchProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"strategicMerge": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Strategic merge patch in YAML, the object is selected by the apiVersion, kind and name of the patch"},
+		"json6902":       apiextv1beta1.JSONSchemaProps{Type: "string", Description: "JSON patch in JSON or YAML that is applied to the target"},
+		"target":         apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Target selects the object of a JSON patch"},
+	}
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"dependencies": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Dependency references specific",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Required:   []string{"referenceName", "crdVersion"},
+				Properties: dependProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"OperatorVersion": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Operator specifies a reference to a specific Operator object"},
+		"overlay":         apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Overlay selects the overlay of the OperatorVersion whose patches are applied to the rendered templates"},
+		"parameters":      apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"patches": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Patches are applied to the rendered templates after the patches of the overlay",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Properties: patchProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"clusterTarget":      apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the ClusterTarget in the namespace of the instance its resources are applied to"},
+		"serviceAccountName": apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the ServiceAccount in the namespace of the instance that is impersonated to apply its resources"},
+		"placement": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Placement constrains the nodes all pods of the instance are scheduled on",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"affinity":     apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Affinity replaces the node affinity, pod affinity and pod anti-affinity of every pod, as far as they are set"},
+				"nodeSelector": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "NodeSelector entries are added to the node selector of every pod, replacing entries with the same key"},
+				"tolerations": apiextv1beta1.JSONSchemaProps{
+					Type:        "array",
+					Description: "Tolerations are added to the tolerations of every pod",
+					Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object"}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+				},
+			},
+		},
+		"podMetadata": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "PodMetadata are labels and annotations added to all pods of the instance",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"labels":      apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Labels are added to the labels of every pod, replacing labels with the same key"},
+				"annotations": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Annotations are added to the annotations of every pod, replacing annotations with the same key"},
+			},
+		},
+		"schedules": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Schedules trigger plans of the instance periodically",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Required:   []string{"name", "plan", "schedule"},
+				Properties: scheduleProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+	}
+	conditionProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"type":               apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"status":             apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"lastTransitionTime": apiextv1beta1.JSONSchemaProps{Type: "string", Format: "date-time"},
+		"reason":             apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"message":            apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+	statusProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"observedGeneration": apiextv1beta1.JSONSchemaProps{Type: "integer", Format: "int64", Description: "The most recent generation of the Instance spec observed by the controller"},
+		"planStatus":         apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"aggregatedStatus":   apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"conditions": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Latest available observations of the instance state",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Required:   []string{"type", "status"},
+				Properties: conditionProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"drift":           apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Result of the last drift detection of the objects of the instance"},
+		"schedules":       apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Executions of the schedules of the instance by their name"},
+		"dryRun":          apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Report of the last requested dry-run of a plan"},
+		"preUpgradeCheck": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Results of the last requested pre-upgrade checks"},
+		"endpoints": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Endpoints of the OperatorVersion, rendered when the last plan completed",
+			Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object", Required: []string{"name"}}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Type: "object"},
+		"status": apiextv1beta1.JSONSchemaProps{
+			Type:       "object",
+			Properties: statusProps,
+		},
+	}
+
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	// status is maintained by the manager only, updates to the main resource ignore it
+	crd.Spec.Subresources = &apiextv1beta1.CustomResourceSubresources{
+		Status: &apiextv1beta1.CustomResourceSubresourceStatus{},
+	}
+	crd.Spec.AdditionalPrinterColumns = []apiextv1beta1.CustomResourceColumnDefinition{
+		{Name: "Operator", Type: "string", JSONPath: ".metadata.labels.kudo\\.dev/operator", Description: "Name of the operator"},
+		{Name: "Version", Type: "string", JSONPath: ".spec.operatorVersion.name", Description: "Name of the OperatorVersion"},
+		{Name: "Last Plan", Type: "string", JSONPath: ".status.aggregatedStatus.lastPlanName", Description: "Name of the plan that was started last"},
+		{Name: "Status", Type: "string", JSONPath: ".status.aggregatedStatus.status", Description: "Status of the plan that was started last"},
+		ageColumn,
+	}
+	return crd
+}
+
+// kudoConfigCrd provides the KudoConfig CRD manifest for printing
+func kudoConfigCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateKudoConfig()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generateKudoConfig() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateCrd("KudoConfig", "kudoconfigs")
+	// the manager reads a single configuration for the whole cluster
+	crd.Spec.Scope = apiextv1beta1.ClusterScoped
+
+	limit := apiextv1beta1.JSONSchemaProps{Type: "integer", Minimum: float64Ptr(0)}
+	limits := apiextv1beta1.JSONSchemaProps{Type: "object",
+		AdditionalProperties: &apiextv1beta1.JSONSchemaPropsOrBool{Allows: true, Schema: &limit},
+	}
+	concurrencyProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"perNamespace": limit,
+		"perOperator":  limit,
+		"namespaces":   limits,
+		"operators":    limits,
+	}
+	keys := apiextv1beta1.JSONSchemaProps{Type: "array",
+		Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "string"}},
+	}
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"concurrency": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Maximum number of plans in progress per namespace and operator, 0 is unlimited",
+			Properties:  concurrencyProps,
+		},
+		"labelPropagation": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Keys of the instance labels and annotations that are copied to the objects of the instance",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"labels":      keys,
+				"annotations": keys,
+			},
+		},
+		"images": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Registry and pull secrets used for the images of all instances",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"registry":    apiextv1beta1.JSONSchemaProps{Type: "string"},
+				"pullSecrets": keys,
+			},
+		},
+		"postRender": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Mutators and webhooks applied to the rendered objects of all instances before they are applied",
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"labels": apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Labels added to all objects and pod templates"},
+				"imageRewrites": apiextv1beta1.JSONSchemaProps{
+					Type:        "array",
+					Description: "Prefixes of container images that are replaced, the first matching rewrite is applied",
+					Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"from", "to"},
+						Properties: map[string]apiextv1beta1.JSONSchemaProps{
+							"from": apiextv1beta1.JSONSchemaProps{Type: "string"},
+							"to":   apiextv1beta1.JSONSchemaProps{Type: "string"},
+						},
+					}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+				},
+				"webhooks": apiextv1beta1.JSONSchemaProps{
+					Type:        "array",
+					Description: "Webhooks that mutate or reject the objects of every task",
+					Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"name", "url"},
+						Properties: map[string]apiextv1beta1.JSONSchemaProps{
+							"name":           apiextv1beta1.JSONSchemaProps{Type: "string"},
+							"url":            apiextv1beta1.JSONSchemaProps{Type: "string"},
+							"caBundle":       apiextv1beta1.JSONSchemaProps{Type: "string", Format: "byte", Description: "PEM encoded CA bundle verifying the serving certificate of the webhook"},
+							"timeoutSeconds": apiextv1beta1.JSONSchemaProps{Type: "integer", Format: "int32", Minimum: float64Ptr(0)},
+							"failurePolicy":  apiextv1beta1.JSONSchemaProps{Type: "string", Enum: []apiextv1beta1.JSON{{Raw: []byte(`"Fail"`)}, {Raw: []byte(`"Ignore"`)}}},
+						},
+					}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+				},
+			},
+		},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Type: "object"},
+	}
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	return crd
+}
+
+// clusterTargetCrd provides the ClusterTarget CRD manifest for printing
+func clusterTargetCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateClusterTarget()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generateClusterTarget() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateCrd("ClusterTarget", "clustertargets")
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"kubeconfigSecret": apiextv1beta1.JSONSchemaProps{
+			Type:        "object",
+			Description: "Secret in the namespace of the ClusterTarget containing the kubeconfig of the cluster, only inline tokens and certificates are supported",
+			Required:    []string{"name"},
+			Properties: map[string]apiextv1beta1.JSONSchemaProps{
+				"name": apiextv1beta1.JSONSchemaProps{Type: "string"},
+				"key":  apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Key of the kubeconfig in the secret, defaults to kubeconfig"},
+			},
+		},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Required: []string{"kubeconfigSecret"}, Type: "object"},
+	}
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	return crd
+}
+
+// planExecutionCrd provides the PlanExecution CRD manifest for printing
+func planExecutionCrd() *apiextv1beta1.CustomResourceDefinition {
+	crd := generatePlanExecution()
+	crd.TypeMeta = metav1.TypeMeta{
+		Kind:       "CustomResourceDefinition",
+		APIVersion: "apiextensions.k8s.io/v1beta1",
+	}
+	return crd
+}
+
+func generatePlanExecution() *apiextv1beta1.CustomResourceDefinition {
+	crd := generateCrd("PlanExecution", "planexecutions")
+	specProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"instance":           apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the instance in the namespace of the PlanExecution the plan runs on"},
+		"instanceGeneration": apiextv1beta1.JSONSchemaProps{Type: "integer", Format: "int64", Description: "Generation of the instance spec the plan was started for"},
+		"operatorVersion":    apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the operatorversion the plan is defined in"},
+		"plan":               apiextv1beta1.JSONSchemaProps{Type: "string", Description: "Name of the plan"},
+		"parameters":         apiextv1beta1.JSONSchemaProps{Type: "object", Description: "Parameters of the instance when the plan was started"},
+	}
+	logProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"phase":     apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"step":      apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":      apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"namespace": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"name":      apiextv1beta1.JSONSchemaProps{Type: "string"},
+	}
+	statusProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"status":     apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"message":    apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"startedAt":  apiextv1beta1.JSONSchemaProps{Type: "string", Format: "date-time", Description: "Time the plan was started"},
+		"finishedAt": apiextv1beta1.JSONSchemaProps{Type: "string", Format: "date-time", Description: "Time the plan reached a terminal status"},
+		"phases": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Results of the phases and their steps",
+			Items:       &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "object"}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+		"logs": apiextv1beta1.JSONSchemaProps{
+			Type:        "array",
+			Description: "Objects applied by the steps whose logs hold the output of the steps",
+			Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{
+				Type:       "object",
+				Required:   []string{"phase", "step", "kind", "name"},
+				Properties: logProps,
+			}, JSONSchemas: []apiextv1beta1.JSONSchemaProps{}},
+		},
+	}
+
+	validationProps := map[string]apiextv1beta1.JSONSchemaProps{
+		"apiVersion": apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"kind":       apiextv1beta1.JSONSchemaProps{Type: "string"},
+		"meta":       apiextv1beta1.JSONSchemaProps{Type: "object"},
+		"spec":       apiextv1beta1.JSONSchemaProps{Properties: specProps, Required: []string{"instance", "operatorVersion", "plan"}, Type: "object"},
+		"status":     apiextv1beta1.JSONSchemaProps{Properties: statusProps, Type: "object"},
+	}
+	crd.Spec.Validation = &apiextv1beta1.CustomResourceValidation{
+		OpenAPIV3Schema: &apiextv1beta1.JSONSchemaProps{Type: "object",
+			Properties: validationProps,
+		},
+	}
+	return crd
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+// generateCrd provides a generic CRD object to be configured
+func generateCrd(kind string, plural string) *apiextv1beta1.CustomResourceDefinition {
+	plural = strings.ToLower(plural)
+	name := plural + "." + Group
+
+	labels := map[string]string{"app": "kudo-manager", "controller-tools.k8s.io": "1.0"}
+	crd := &apiextv1beta1.CustomResourceDefinition{
+		ObjectMeta: v1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+		Spec: apiextv1beta1.CustomResourceDefinitionSpec{
+			Group:   Group,
+			Version: CRDVersion,
+			Names: apiextv1beta1.CustomResourceDefinitionNames{
+				Plural:     plural,
+				Singular:   strings.ToLower(kind),
+				ShortNames: nil,
+				Kind:       kind,
+			},
+			Scope: "Namespaced",
+		},
+		Status: apiextv1beta1.CustomResourceDefinitionStatus{
+			Conditions:     []apiextv1beta1.CustomResourceDefinitionCondition{},
+			StoredVersions: []string{},
+		},
+	}
+	// below is needed if we support 1.15 CRD in v1beta1, it is deprecated within the 1.15
+	// for 1.16 it is removed and functions as if preserve == false
+	// preserveFields := false
+	// crd.Spec.PreserveUnknownFields = &preserveFields
+	return crd
+}
+
+// CRDManifests provides a slice of strings for each CRD manifest
+func CRDManifests() ([]string, error) {
+	objs := CRDs()
+	manifests := make([]string, len(objs))
+	for i, obj := range objs {
+		o, err := yaml.Marshal(obj)
+		if err != nil {
+			return []string{}, err
+		}
+		manifests[i] = string(o)
+	}
+
+	return manifests, nil
+}
+
+// CRDs returns the slice of crd objects for KUDO
+func CRDs() []runtime.Object {
+	o := operatorCrd()
+	ov := operatorVersionCrd()
+	i := InstanceCrd()
+	c := kudoConfigCrd()
+	ct := clusterTargetCrd()
+	pe := planExecutionCrd()
+
+	return []runtime.Object{o, ov, i, c, ct, pe}
+}
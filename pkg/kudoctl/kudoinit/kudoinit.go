@@ -0,0 +1,78 @@
+// Package kudoinit contains the names, CRDs and lookups of a KUDO manager installation that are shared by 'kudo init',
+// which installs the manager, and the packages checking an existing installation.
+package kudoinit
+
+import (
+	"fmt"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// Group is the API group of the KUDO CRDs
+	Group = "kudo.dev"
+	// CRDVersion is the version of the KUDO CRDs
+	CRDVersion = "v1alpha1"
+
+	// ServiceName is the name of the service exposing the webhook server of the KUDO manager
+	ServiceName = "kudo-controller-manager-service"
+)
+
+// ManagerLabels returns the labels of the KUDO manager statefulset and its pods
+func ManagerLabels() labels.Set {
+	return labels.Set{
+		"app":                     "kudo-manager",
+		"control-plane":           "controller-manager",
+		"controller-tools.k8s.io": "1.0",
+	}
+}
+
+// GetKUDOPodImage fetches the image of KUDO pod running in the given namespace.
+func GetKUDOPodImage(client corev1.PodsGetter, namespace string) (string, error) {
+	selector := ManagerLabels().AsSelector()
+	pod, err := getFirstRunningPod(client, namespace, selector)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range pod.Spec.Containers {
+		if c.Name == "manager" {
+			return c.Image, nil
+		}
+	}
+	return "", fmt.Errorf("could not find a KUDO pod")
+}
+
+// GetKUDOPods lists the pods of the KUDO manager running in the given namespace
+func GetKUDOPods(client corev1.PodsGetter, namespace string) ([]v1.Pod, error) {
+	options := metav1.ListOptions{LabelSelector: ManagerLabels().AsSelector().String()}
+	pods, err := client.Pods(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) < 1 {
+		return nil, fmt.Errorf("could not find KUDO manager in namespace %s", namespace)
+	}
+	return pods.Items, nil
+}
+
+func getFirstRunningPod(client corev1.PodsGetter, namespace string, selector labels.Selector) (*v1.Pod, error) {
+	options := metav1.ListOptions{LabelSelector: selector.String()}
+	pods, err := client.Pods(namespace).List(options)
+	if err != nil {
+		return nil, err
+	}
+	if len(pods.Items) < 1 {
+		return nil, fmt.Errorf("could not find KUDO manager")
+	}
+	for _, p := range pods.Items {
+		if kube.IsPodReady(&p) {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("could not find a ready KUDO pod")
+}
@@ -0,0 +1,46 @@
+package kudoinit
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	admissionclient "k8s.io/client-go/kubernetes/typed/admissionregistration/v1beta1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// WebhookSecretName is the name of the secret in the namespace of the manager with the webhook certificate
+	WebhookSecretName = "kudo-webhook-server-secret"
+	// ProtectorConfigurationName is the name of the validating webhook configuration of the instance protector
+	ProtectorConfigurationName = "kudo-manager-instance-protector"
+)
+
+// WebhookCertificate returns the certificate the webhooks of the manager in the namespace are served with. It is nil
+// if the webhook secret contains no certificate, i.e. the webhooks are not installed.
+func WebhookCertificate(client corev1.SecretsGetter, namespace string) (*x509.Certificate, error) {
+	secret, err := client.Secrets(namespace).Get(WebhookSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if len(secret.Data["tls.crt"]) == 0 {
+		return nil, nil
+	}
+	block, _ := pem.Decode(secret.Data["tls.crt"])
+	if block == nil {
+		return nil, fmt.Errorf("secret %s/%s contains no PEM encoded certificate", namespace, WebhookSecretName)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// InstanceProtectionInstalled returns true if the validating webhook rejecting the deletion of protected instances is
+// registered. Without it, protected instances are only kept from being uninstalled by kudoctl.
+func InstanceProtectionInstalled(client admissionclient.ValidatingWebhookConfigurationsGetter) (bool, error) {
+	_, err := client.ValidatingWebhookConfigurations().Get(ProtectorConfigurationName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,303 @@
+package packages
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ChangeType describes how an element of a package changed between two versions
+type ChangeType string
+
+const (
+	// Added is used for elements that only exist in the new package
+	Added ChangeType = "added"
+	// Removed is used for elements that only exist in the old package
+	Removed ChangeType = "removed"
+	// Changed is used for elements that exist in both packages but differ
+	Changed ChangeType = "changed"
+)
+
+// Change is the difference of a single parameter, template, task or plan between two versions of a package
+type Change struct {
+	// Kind is the kind of the element: parameter, template, task or plan
+	Kind string
+	Name string
+	Type ChangeType
+	// Details lists the changed fields of a changed element as `path: old -> new`
+	Details []string
+}
+
+// Diff compares two packages semantically: parameters, tasks and plans are compared field by field and templates
+// are compared as YAML documents, so changes of formatting or key order are not reported. Templates that are not
+// valid YAML (e.g. because of template actions) are compared line by line. Changes are sorted by kind and name.
+func Diff(old, new *PackageFiles) ([]Change, error) {
+	var changes []Change
+
+	oldParams, newParams := map[string]interface{}{}, map[string]interface{}{}
+	for _, p := range old.Params {
+		oldParams[p.Name] = p
+	}
+	for _, p := range new.Params {
+		newParams[p.Name] = p
+	}
+	c, err := diffElements("parameter", oldParams, newParams)
+	if err != nil {
+		return nil, err
+	}
+	changes = append(changes, c...)
+
+	oldTasks, newTasks := map[string]interface{}{}, map[string]interface{}{}
+	for _, t := range old.Operator.Tasks {
+		oldTasks[t.Name] = t
+	}
+	for _, t := range new.Operator.Tasks {
+		newTasks[t.Name] = t
+	}
+	if c, err = diffElements("task", oldTasks, newTasks); err != nil {
+		return nil, err
+	}
+	changes = append(changes, c...)
+
+	oldPlans, newPlans := map[string]interface{}{}, map[string]interface{}{}
+	for name, p := range old.Operator.Plans {
+		oldPlans[name] = p
+	}
+	for name, p := range new.Operator.Plans {
+		newPlans[name] = p
+	}
+	if c, err = diffElements("plan", oldPlans, newPlans); err != nil {
+		return nil, err
+	}
+	changes = append(changes, c...)
+
+	changes = append(changes, diffTemplates(old.Templates, new.Templates)...)
+
+	order := map[string]int{"parameter": 0, "template": 1, "task": 2, "plan": 3}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return order[changes[i].Kind] < order[changes[j].Kind]
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes, nil
+}
+
+// diffElements compares the named elements of the given kind after converting them to their JSON representation
+func diffElements(kind string, old, new map[string]interface{}) ([]Change, error) {
+	var changes []Change
+	for _, name := range unionKeys(old, new) {
+		o, inOld := old[name]
+		n, inNew := new[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: kind, Name: name, Type: Added})
+		case !inNew:
+			changes = append(changes, Change{Kind: kind, Name: name, Type: Removed})
+		default:
+			ov, err := toValue(o)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s %s: %w", kind, name, err)
+			}
+			nv, err := toValue(n)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s %s: %w", kind, name, err)
+			}
+			if details := diffValues("", ov, nv); len(details) > 0 {
+				changes = append(changes, Change{Kind: kind, Name: name, Type: Changed, Details: details})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// diffTemplates compares templates as YAML documents, falling back to a line diff for templates that do not parse
+func diffTemplates(old, new map[string]string) []Change {
+	oldTpls, newTpls := make(map[string]interface{}, len(old)), make(map[string]interface{}, len(new))
+	for k, v := range old {
+		oldTpls[k] = v
+	}
+	for k, v := range new {
+		newTpls[k] = v
+	}
+
+	var changes []Change
+	for _, name := range unionKeys(oldTpls, newTpls) {
+		o, inOld := old[name]
+		n, inNew := new[name]
+		switch {
+		case !inOld:
+			changes = append(changes, Change{Kind: "template", Name: name, Type: Added})
+		case !inNew:
+			changes = append(changes, Change{Kind: "template", Name: name, Type: Removed})
+		default:
+			var details []string
+			ov, oErr := yamlDocuments(o)
+			nv, nErr := yamlDocuments(n)
+			if oErr == nil && nErr == nil {
+				details = diffValues("", ov, nv)
+			} else {
+				details = diffLines(o, n)
+			}
+			if len(details) > 0 {
+				changes = append(changes, Change{Kind: "template", Name: name, Type: Changed, Details: details})
+			}
+		}
+	}
+	return changes
+}
+
+// yamlDocuments parses the documents of a YAML stream. A single document is returned as is, multiple documents as a
+// list.
+func yamlDocuments(s string) (interface{}, error) {
+	var docs []interface{}
+	for _, d := range strings.Split(s, "\n---") {
+		if strings.TrimSpace(d) == "" {
+			continue
+		}
+		var doc interface{}
+		if err := yaml.Unmarshal([]byte(d), &doc); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+	return docs, nil
+}
+
+// toValue converts an element to its generic JSON representation
+func toValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	err = json.Unmarshal(b, &value)
+	return value, err
+}
+
+// diffValues compares two generic JSON values recursively and describes every difference by its path
+func diffValues(path string, old, new interface{}) []string {
+	om, oIsMap := old.(map[string]interface{})
+	nm, nIsMap := new.(map[string]interface{})
+	if oIsMap && nIsMap {
+		var details []string
+		for _, k := range unionKeys(om, nm) {
+			details = append(details, diffValues(join(path, k), om[k], nm[k])...)
+		}
+		return details
+	}
+
+	ol, oIsList := old.([]interface{})
+	nl, nIsList := new.([]interface{})
+	if oIsList && nIsList {
+		var details []string
+		for i := 0; i < len(ol) || i < len(nl); i++ {
+			var o, n interface{}
+			if i < len(ol) {
+				o = ol[i]
+			}
+			if i < len(nl) {
+				n = nl[i]
+			}
+			details = append(details, diffValues(fmt.Sprintf("%s[%d]", path, i), o, n)...)
+		}
+		return details
+	}
+
+	oj, nj := compact(old), compact(new)
+	if oj == nj {
+		return nil
+	}
+	if path == "" {
+		path = "."
+	}
+	switch {
+	case old == nil:
+		return []string{fmt.Sprintf("%s: added %s", path, nj)}
+	case new == nil:
+		return []string{fmt.Sprintf("%s: removed %s", path, oj)}
+	default:
+		return []string{fmt.Sprintf("%s: %s -> %s", path, oj, nj)}
+	}
+}
+
+// diffLines returns the removed and added lines of a longest common subsequence diff, ignoring trailing whitespace
+func diffLines(old, new string) []string {
+	a := strings.Split(strings.TrimRight(old, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(new, "\n"), "\n")
+	for i := range a {
+		a[i] = strings.TrimRight(a[i], " \t")
+	}
+	for i := range b {
+		b[i] = strings.TrimRight(b[i], " \t")
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var details []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			details = append(details, fmt.Sprintf("line %d: removed %q", i+1, a[i]))
+			i++
+		default:
+			details = append(details, fmt.Sprintf("line %d: added %q", j+1, b[j]))
+			j++
+		}
+	}
+	return details
+}
+
+func compact(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
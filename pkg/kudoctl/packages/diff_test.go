@@ -0,0 +1,84 @@
+package packages
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+)
+
+func TestDiff(t *testing.T) {
+	old := &PackageFiles{
+		Operator: &Operator{
+			Tasks: []v1alpha1.Task{
+				{Name: "app", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"deployment.yaml"}}}},
+				{Name: "cleanup", Kind: "Delete"},
+			},
+			Plans: map[string]v1alpha1.Plan{
+				"deploy": {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "serial", Steps: []v1alpha1.Step{{Name: "app", Tasks: []string{"app"}}}}}},
+			},
+		},
+		Params: []v1alpha1.Parameter{
+			{Name: "REPLICAS", Default: kudo.String("3")},
+			{Name: "MEMORY", Default: kudo.String("1Gi")},
+			{Name: "OLD"},
+		},
+		Templates: map[string]string{
+			"deployment.yaml": "kind: Deployment\nspec:\n  replicas: 3\n  paused: false\n",
+			"service.yaml":    "kind: Service\nspec:\n  port: {{ .Params.PORT }}\n",
+			"removed.yaml":    "kind: ConfigMap\n",
+		},
+	}
+	new := &PackageFiles{
+		Operator: &Operator{
+			Tasks: []v1alpha1.Task{
+				{Name: "app", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"deployment.yaml", "service.yaml"}}}},
+				{Name: "cleanup", Kind: "Delete"},
+			},
+			Plans: map[string]v1alpha1.Plan{
+				"deploy":  {Strategy: "serial", Phases: []v1alpha1.Phase{{Name: "main", Strategy: "parallel", Steps: []v1alpha1.Step{{Name: "app", Tasks: []string{"app"}}}}}},
+				"upgrade": {Strategy: "serial"},
+			},
+		},
+		Params: []v1alpha1.Parameter{
+			{Name: "MEMORY", Default: kudo.String("1Gi")},
+			{Name: "REPLICAS", Default: kudo.String("5"), Required: true},
+			{Name: "NEW"},
+		},
+		Templates: map[string]string{
+			// reformatted with a different key order, but the same content
+			"deployment.yaml": "kind: Deployment\nspec:\n  paused: false\n  replicas:   3\n",
+			"service.yaml":    "kind: Service\nspec:\n  port: {{ .Params.CLIENT_PORT }}\n",
+			"added.yaml":      "kind: Secret\n",
+		},
+	}
+
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	expected := []Change{
+		{Kind: "parameter", Name: "NEW", Type: Added},
+		{Kind: "parameter", Name: "OLD", Type: Removed},
+		{Kind: "parameter", Name: "REPLICAS", Type: Changed, Details: []string{`default: "3" -> "5"`, `required: added true`}},
+		{Kind: "template", Name: "added.yaml", Type: Added},
+		{Kind: "template", Name: "removed.yaml", Type: Removed},
+		{Kind: "template", Name: "service.yaml", Type: Changed, Details: []string{
+			`line 3: removed "  port: {{ .Params.PORT }}"`,
+			`line 3: added "  port: {{ .Params.CLIENT_PORT }}"`,
+		}},
+		{Kind: "task", Name: "app", Type: Changed, Details: []string{`spec.resources[1]: added "service.yaml"`}},
+		{Kind: "plan", Name: "deploy", Type: Changed, Details: []string{`phases[0].strategy: "serial" -> "parallel"`}},
+		{Kind: "plan", Name: "upgrade", Type: Added},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected changes\n%+v\nbut got\n%+v", expected, changes)
+	}
+
+	changes, err = Diff(old, old)
+	if err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes comparing a package to itself but got %v, %v", changes, err)
+	}
+}
@@ -0,0 +1,144 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	fixturesFolder        = "tests"
+	fixtureParamsFileName = "params.yaml"
+	fixtureRenderedFolder = "rendered"
+)
+
+// Fixture is a test case in the tests folder of an operator package. It is a folder tests/<name> with an optional
+// params.yaml with the parameter values to render the templates with, and the expected output of each template in
+// tests/<name>/rendered/<template>. Fixtures are only used during development, they are skipped when a package is read
+// or packaged.
+type Fixture struct {
+	Name string
+	// Path is the folder of the fixture
+	Path string
+	// ParamsFile is the parameter file of the fixture, empty if the templates are rendered with the default values
+	ParamsFile string
+}
+
+// isFixturesFolder returns true if path is the tests folder of the package at packagePath
+func isFixturesFolder(packagePath, path string) bool {
+	return filepath.Clean(path) == filepath.Join(packagePath, fixturesFolder)
+}
+
+// Fixtures returns the test fixtures of the operator package folder sorted by name
+func Fixtures(fs afero.Fs, packagePath string) ([]Fixture, error) {
+	infos, err := afero.ReadDir(fs, filepath.Join(packagePath, fixturesFolder))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the test fixtures of %s: %w", packagePath, err)
+	}
+
+	var fixtures []Fixture
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		f := Fixture{Name: info.Name(), Path: filepath.Join(packagePath, fixturesFolder, info.Name())}
+		paramsFile := filepath.Join(f.Path, fixtureParamsFileName)
+		exists, err := afero.Exists(fs, paramsFile)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			f.ParamsFile = paramsFile
+		}
+		fixtures = append(fixtures, f)
+	}
+	return fixtures, nil
+}
+
+// Compare compares the rendered templates with the expected output of the fixture and returns the differences
+func (f Fixture) Compare(fs afero.Fs, rendered map[string]string) ([]string, error) {
+	expected, err := f.expected(fs)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	for name := range expected {
+		if _, ok := rendered[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diffs []string
+	for _, name := range names {
+		want, hasExpected := expected[name]
+		got, hasRendered := rendered[name]
+		switch {
+		case !hasExpected:
+			diffs = append(diffs, fmt.Sprintf("template %s has no expected output", name))
+		case !hasRendered:
+			diffs = append(diffs, fmt.Sprintf("template %s is expected but was not rendered", name))
+		default:
+			for _, d := range diffLines(want, got) {
+				diffs = append(diffs, fmt.Sprintf("template %s %s", name, d))
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// Update replaces the expected output of the fixture with the rendered templates
+func (f Fixture) Update(fs afero.Fs, rendered map[string]string) error {
+	dir := filepath.Join(f.Path, fixtureRenderedFolder)
+	if err := fs.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove the expected output of fixture %s: %w", f.Name, err)
+	}
+	for name, content := range rendered {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create the folder of %s: %w", path, err)
+		}
+		if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write the expected output of fixture %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// expected returns the expected output of the fixture by template name
+func (f Fixture) expected(fs afero.Fs) (map[string]string, error) {
+	dir := filepath.Join(f.Path, fixtureRenderedFolder)
+	expected := map[string]string{}
+	if exists, err := afero.DirExists(fs, dir); err != nil || !exists {
+		return expected, err
+	}
+	err := afero.Walk(fs, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		b, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		expected[filepath.ToSlash(name)] = string(b)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the expected output of fixture %s: %w", f.Name, err)
+	}
+	return expected, nil
+}
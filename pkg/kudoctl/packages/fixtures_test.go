@@ -0,0 +1,73 @@
+package packages
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestFixtures(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	if err := afero.WriteFile(fs, "/op/tests/tls/params.yaml", []byte("TLS: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := afero.WriteFile(fs, "/op/tests/README.md", []byte("docs"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.MkdirAll("/op/tests/defaults", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := Fixtures(fs, "/op")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Fixture{
+		{Name: "defaults", Path: "/op/tests/defaults"},
+		{Name: "tls", Path: "/op/tests/tls", ParamsFile: "/op/tests/tls/params.yaml"},
+	}
+	if !reflect.DeepEqual(expected, fixtures) {
+		t.Fatalf("expected fixtures %v, got %v", expected, fixtures)
+	}
+
+	tls := fixtures[1]
+	rendered := map[string]string{
+		"service.yaml":       "kind: Service\nspec:\n  port: 443\n",
+		"config/server.yaml": "kind: ConfigMap\n",
+	}
+	if err := tls.Update(fs, rendered); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := tls.Compare(fs, rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no differences after the update, got %v", diffs)
+	}
+
+	diffs, err = tls.Compare(fs, map[string]string{
+		"service.yaml": "kind: Service\nspec:\n  port: 80\n",
+		"new.yaml":     "kind: Secret\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedDiffs := []string{
+		`template config/server.yaml is expected but was not rendered`,
+		`template new.yaml has no expected output`,
+		`template service.yaml line 3: removed "  port: 443"`,
+		`template service.yaml line 3: added "  port: 80"`,
+	}
+	if !reflect.DeepEqual(expectedDiffs, diffs) {
+		t.Errorf("expected differences %v, got %v", expectedDiffs, diffs)
+	}
+}
+
+func TestFixtures_NoTests(t *testing.T) {
+	fixtures, err := Fixtures(afero.NewMemMapFs(), "/op")
+	if err != nil || fixtures != nil {
+		t.Errorf("expected no fixtures and no error, got %v and %v", fixtures, err)
+	}
+}
@@ -0,0 +1,73 @@
+package packages
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Package formats are identified by the apiVersion of operator.yaml. Every supported format has a parser converting
+// it into the Operator model used by KUDO, so packages keep working when the format evolves.
+
+const (
+	// OperatorAPIVersionV1Alpha1 is the first package format. operator.yaml files without an apiVersion are read in
+	// this format.
+	OperatorAPIVersionV1Alpha1 = "kudo.dev/v1alpha1"
+
+	// CurrentOperatorAPIVersion is the package format written by new packages
+	CurrentOperatorAPIVersion = OperatorAPIVersionV1Alpha1
+)
+
+// operatorParser converts an operator.yaml of one package format into the Operator model
+type operatorParser func(data []byte) (*Operator, error)
+
+// operatorParsers are the parsers of the supported package formats by apiVersion
+var operatorParsers = map[string]operatorParser{
+	OperatorAPIVersionV1Alpha1: parseOperatorV1Alpha1,
+}
+
+// SupportedOperatorAPIVersions returns the sorted apiVersions of the package formats that can be read
+func SupportedOperatorAPIVersions() []string {
+	versions := make([]string, 0, len(operatorParsers))
+	for v := range operatorParsers {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// parseOperator reads an operator.yaml with the parser of its apiVersion
+func parseOperator(data []byte) (*Operator, error) {
+	var header struct {
+		APIVersion string `json:"apiVersion,omitempty"`
+	}
+	if err := yaml.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	version := header.APIVersion
+	if version == "" {
+		version = OperatorAPIVersionV1Alpha1
+	}
+
+	parse, ok := operatorParsers[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported apiVersion %s, supported versions are: %s", version, strings.Join(SupportedOperatorAPIVersions(), ", "))
+	}
+	operator, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	operator.APIVersion = version
+	return operator, nil
+}
+
+// parseOperatorV1Alpha1 reads the first package format, which the Operator model still matches
+func parseOperatorV1Alpha1(data []byte) (*Operator, error) {
+	operator := &Operator{}
+	if err := yaml.Unmarshal(data, operator); err != nil {
+		return nil, err
+	}
+	return operator, nil
+}
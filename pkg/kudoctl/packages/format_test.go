@@ -0,0 +1,67 @@
+package packages
+
+import (
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestParseOperator(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		apiVersion string
+		err        string
+	}{
+		{"without apiVersion", "name: zookeeper\nversion: 0.1.0\n", OperatorAPIVersionV1Alpha1, ""},
+		{"v1alpha1", "apiVersion: kudo.dev/v1alpha1\nname: zookeeper\nversion: 0.1.0\n", OperatorAPIVersionV1Alpha1, ""},
+		{"unsupported", "apiVersion: kudo.dev/v2\nname: zookeeper\n", "", "unsupported apiVersion kudo.dev/v2, supported versions are: kudo.dev/v1alpha1"},
+		{"invalid", "name: [zookeeper\n", "", "error converting YAML to JSON"},
+	}
+
+	for _, tt := range tests {
+		operator, err := parseOperator([]byte(tt.data))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: expected no error but got %v", tt.name, err)
+		}
+		if operator.APIVersion != tt.apiVersion || operator.Name != "zookeeper" || operator.Version != "0.1.0" {
+			t.Errorf("%s: unexpected operator %+v", tt.name, operator)
+		}
+	}
+}
+
+func TestParseOperator_Conversion(t *testing.T) {
+	// a format that nests the metadata of the operator is converted by its parser
+	const testVersion = "kudo.dev/vtest"
+	operatorParsers[testVersion] = func(data []byte) (*Operator, error) {
+		var nested struct {
+			Metadata struct {
+				Name    string `json:"name"`
+				Version string `json:"version"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal(data, &nested); err != nil {
+			return nil, err
+		}
+		return &Operator{Name: nested.Metadata.Name, Version: nested.Metadata.Version}, nil
+	}
+	defer delete(operatorParsers, testVersion)
+
+	operator, err := parseOperator([]byte("apiVersion: kudo.dev/vtest\nmetadata:\n  name: zookeeper\n  version: 0.2.0\n"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if operator.APIVersion != testVersion || operator.Name != "zookeeper" || operator.Version != "0.2.0" {
+		t.Errorf("unexpected operator %+v", operator)
+	}
+	if versions := SupportedOperatorAPIVersions(); len(versions) != 2 || versions[1] != testVersion {
+		t.Errorf("expected the test format to be supported but got %v", versions)
+	}
+}
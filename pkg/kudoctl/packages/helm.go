@@ -0,0 +1,416 @@
+package packages
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
+	"github.com/kudobuilder/kudo/pkg/engine/task"
+
+	"github.com/spf13/afero"
+	"sigs.k8s.io/yaml"
+)
+
+// The Helm import converts a chart into an operator package:
+// values.yaml is flattened into parameters, e.g. image.pullPolicy becomes IMAGE_PULL_POLICY, the templates are
+// rewritten to reference these parameters and the KUDO equivalents of the built-in objects of Helm, and a deploy plan
+// applies all templates. Constructs without a KUDO equivalent are kept as they are and reported as warnings, they
+// have to be migrated manually.
+
+const (
+	helmChartFileName  = "Chart.yaml"
+	helmValuesFileName = "values.yaml"
+	helmTemplatesDir   = "templates"
+
+	// helmDeployName is the name of the plan, phase, step and task applying the imported templates
+	helmDeployName = "deploy"
+)
+
+// HelmImport is an operator package converted from a Helm chart
+type HelmImport struct {
+	// Files are the contents of the package files by their path relative to the operator directory
+	Files map[string]string
+	// Warnings are the parts of the chart that could not be converted and have to be migrated manually
+	Warnings []string
+}
+
+// helmChart is the part of Chart.yaml that is imported
+type helmChart struct {
+	Name        string   `json:"name"`
+	Version     string   `json:"version"`
+	AppVersion  string   `json:"appVersion,omitempty"`
+	Description string   `json:"description,omitempty"`
+	KubeVersion string   `json:"kubeVersion,omitempty"`
+	Home        string   `json:"home,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	Maintainers []struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+	} `json:"maintainers,omitempty"`
+	Dependencies []interface{} `json:"dependencies,omitempty"`
+}
+
+// importedOperator is the operator.yaml written for an imported chart. The task only has the fields of Apply tasks,
+// the Task type of the API would write the fields of all task kinds.
+type importedOperator struct {
+	APIVersion        string                   `json:"apiVersion"`
+	Name              string                   `json:"name"`
+	Description       string                   `json:"description,omitempty"`
+	Version           string                   `json:"version"`
+	AppVersion        string                   `json:"appVersion,omitempty"`
+	KubernetesVersion string                   `json:"kubernetesVersion,omitempty"`
+	Maintainers       []*v1alpha1.Maintainer   `json:"maintainers,omitempty"`
+	URL               string                   `json:"url,omitempty"`
+	Icon              string                   `json:"icon,omitempty"`
+	Keywords          []string                 `json:"keywords,omitempty"`
+	Tasks             []importedTask           `json:"tasks"`
+	Plans             map[string]v1alpha1.Plan `json:"plans"`
+}
+
+type importedTask struct {
+	Name string                    `json:"name"`
+	Kind string                    `json:"kind"`
+	Spec v1alpha1.ResourceTaskSpec `json:"spec"`
+}
+
+// helmValue is a leaf of values.yaml imported as parameter
+type helmValue struct {
+	path  string
+	param string
+	def   parameterDefinition
+	// boolean values are compared with "true" in templates, as all parameters are strings
+	boolean bool
+}
+
+var (
+	// helmAction matches the actions of a template
+	helmAction = regexp.MustCompile(`(?s){{.*?}}`)
+	// helmValueRef matches references to values, e.g. .Values.image.tag or $.Values.image.tag, and to .Values itself.
+	// The first group is the character before the reference, it makes sure that fields of values are not matched.
+	helmValueRef = regexp.MustCompile(`(^|[^A-Za-z0-9_)\]])\.Values\b((?:\.[A-Za-z_][A-Za-z0-9_]*)*)`)
+	// helmBuiltinRef matches references to the built-in objects of Helm other than .Values
+	helmBuiltinRef = regexp.MustCompile(`(^|[^A-Za-z0-9_)\]])(\.(?:Release|Chart|Capabilities|Files|Template)\b(?:\.[A-Za-z_][A-Za-z0-9_]*)*)`)
+	// helmBuiltins are the built-in objects of Helm with a KUDO equivalent
+	helmBuiltins = map[string]string{
+		".Release.Name":      ".Name",
+		".Release.Namespace": ".Namespace",
+		".Chart.Name":        ".OperatorName",
+	}
+	// helmFuncs are the template functions Helm adds to Sprig that the KUDO engine does not provide
+	helmFuncs = unsupportedFuncs("toYaml", "fromYaml", "toToml", "toJson", "fromJson", "tpl", "required", "lookup")
+)
+
+// unsupportedFuncs returns patterns matching calls of the given functions unknown to the KUDO engine by function name
+func unsupportedFuncs(names ...string) map[string]*regexp.Regexp {
+	known := engine.New().FuncMap
+	funcs := map[string]*regexp.Regexp{}
+	for _, fn := range names {
+		if _, ok := known[fn]; !ok {
+			funcs[fn] = regexp.MustCompile(`\b` + fn + `\b`)
+		}
+	}
+	return funcs
+}
+
+// ImportHelmChart converts the unpacked Helm chart in the directory into an operator package
+func ImportHelmChart(fs afero.Fs, path string) (*HelmImport, error) {
+	result := &HelmImport{Files: map[string]string{}}
+
+	data, err := afero.ReadFile(fs, filepath.Join(path, helmChartFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, only unpacked charts can be imported: %v", helmChartFileName, err)
+	}
+	chart := helmChart{}
+	if err := yaml.Unmarshal(data, &chart); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", helmChartFileName, err)
+	}
+	if len(chart.Dependencies) > 0 {
+		result.warnf("%s: dependencies are not imported, install them as separate operators", helmChartFileName)
+	}
+	if exists, _ := afero.DirExists(fs, filepath.Join(path, "charts")); exists {
+		result.warnf("charts: subcharts are not imported, install them as separate operators")
+	}
+
+	values := map[string]*helmValue{}
+	if data, err := afero.ReadFile(fs, filepath.Join(path, helmValuesFileName)); err == nil {
+		var raw map[string]interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", helmValuesFileName, err)
+		}
+		result.flattenValues("", raw, values)
+	}
+	params, err := importedParams(values)
+	if err != nil {
+		return nil, err
+	}
+	result.Files[paramsFileName] = params
+
+	resources, err := result.importTemplates(fs, path, values)
+	if err != nil {
+		return nil, err
+	}
+
+	operator, err := result.importedOperator(chart, resources)
+	if err != nil {
+		return nil, err
+	}
+	result.Files[operatorFileName] = operator
+
+	sort.Strings(result.Warnings)
+	return result, nil
+}
+
+func (r *HelmImport) warnf(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// flattenValues adds a parameter for every leaf of the values. Lists become array parameters, empty maps string
+// parameters without a value.
+func (r *HelmImport) flattenValues(prefix string, raw map[string]interface{}, values map[string]*helmValue) {
+	for key, v := range raw {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if m, ok := v.(map[string]interface{}); ok && len(m) > 0 {
+			r.flattenValues(path, m, values)
+			continue
+		}
+
+		value := &helmValue{path: path, param: helmParamName(path)}
+		value.def.Description = fmt.Sprintf("Imported from %s of the Helm chart", path)
+		notRequired := "false"
+		value.def.Required = &notRequired
+		var def string
+		switch v := v.(type) {
+		case nil:
+		case map[string]interface{}:
+			r.warnf("%s: %s is a map, maps can not be parameters, it is imported as an empty string", helmValuesFileName, path)
+		case []interface{}:
+			value.def.Type = v1alpha1.ArrayParameterType
+			b, _ := yaml.Marshal(v)
+			def = strings.TrimSpace(string(b))
+		case bool:
+			value.boolean = true
+			def = strconv.FormatBool(v)
+		case float64:
+			def = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			def = fmt.Sprint(v)
+		}
+		value.def.Default = &def
+
+		if other, ok := values[value.param]; ok {
+			r.warnf("%s: %s and %s are both imported as parameter %s", helmValuesFileName, other.path, path, value.param)
+		}
+		values[value.param] = value
+	}
+}
+
+// helmParamName converts the path of a value into the name of a parameter, e.g. image.pullPolicy into
+// IMAGE_PULL_POLICY
+func helmParamName(path string) string {
+	var b strings.Builder
+	runes := []rune(path)
+	for i, c := range runes {
+		switch {
+		case c == '.' || c == '-':
+			b.WriteRune('_')
+		case unicode.IsUpper(c) && i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])):
+			b.WriteRune('_')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(unicode.ToUpper(c))
+		}
+	}
+	return b.String()
+}
+
+func importedParams(values map[string]*helmValue) (string, error) {
+	defs := make(map[string]parameterDefinition, len(values))
+	for name, v := range values {
+		defs[name] = v.def
+	}
+	b, err := yaml.Marshal(defs)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", paramsFileName, err)
+	}
+	return string(b), nil
+}
+
+// importTemplates converts all templates of the chart and returns the names of the templates to apply
+func (r *HelmImport) importTemplates(fs afero.Fs, chartDir string, values map[string]*helmValue) ([]string, error) {
+	root := filepath.Join(chartDir, helmTemplatesDir)
+	var resources []string
+	err := afero.Walk(fs, root, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && file == root {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if info.IsDir() {
+			if name == "tests" {
+				r.warnf("%s/tests: Helm tests are not imported", helmTemplatesDir)
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := filepath.Ext(name)
+		if ext != ".yaml" && ext != ".yml" && ext != ".tpl" {
+			r.warnf("%s/%s: only .yaml and .tpl templates are imported", helmTemplatesDir, name)
+			return nil
+		}
+		if ext == ".yml" {
+			name = strings.TrimSuffix(name, ext) + ".yaml"
+		}
+		// Helm shares the named templates of all files, KUDO only those of partials
+		if ext == ".tpl" && !engine.IsPartial(name) {
+			name = path.Join(path.Dir(name), "_"+path.Base(name))
+		}
+
+		data, err := afero.ReadFile(fs, file)
+		if err != nil {
+			return err
+		}
+		r.Files[helmTemplatesDir+"/"+name] = r.convertTemplate(helmTemplatesDir+"/"+name, string(data), values)
+		if !engine.IsPartial(name) {
+			resources = append(resources, name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates: %v", err)
+	}
+	sort.Strings(resources)
+	return resources, nil
+}
+
+// convertTemplate rewrites the references to values and built-in objects in the actions of the template and reports
+// everything that can not be converted
+func (r *HelmImport) convertTemplate(name, tpl string, values map[string]*helmValue) string {
+	if strings.Contains(tpl, "helm.sh/hook") {
+		r.warnf("%s: Helm hooks are not supported, the objects are applied with all others", name)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range helmAction.FindAllStringIndex(tpl, -1) {
+		b.WriteString(tpl[last:loc[0]])
+		line := strings.Count(tpl[:loc[0]], "\n") + 1
+		b.WriteString(r.convertAction(fmt.Sprintf("%s:%d", name, line), tpl[loc[0]:loc[1]], values))
+		last = loc[1]
+	}
+	b.WriteString(tpl[last:])
+	return b.String()
+}
+
+func (r *HelmImport) convertAction(pos, action string, values map[string]*helmValue) string {
+	for fn, call := range helmFuncs {
+		if call.MatchString(action) {
+			r.warnf("%s: function %s is not supported", pos, fn)
+		}
+	}
+
+	action = helmBuiltinRef.ReplaceAllStringFunc(action, func(match string) string {
+		groups := helmBuiltinRef.FindStringSubmatch(match)
+		prefix, ref := groups[1], groups[2]
+		if replacement, ok := helmBuiltins[ref]; ok {
+			return prefix + replacement
+		}
+		r.warnf("%s: %s is not supported", pos, ref)
+		return match
+	})
+
+	return helmValueRef.ReplaceAllStringFunc(action, func(match string) string {
+		groups := helmValueRef.FindStringSubmatch(match)
+		prefix, path := groups[1], strings.TrimPrefix(groups[2], ".")
+		if path == "" {
+			r.warnf("%s: .Values can not be used as a whole, reference single values instead", pos)
+			return match
+		}
+		v, ok := values[helmParamName(path)]
+		switch {
+		case ok && v.path == path && v.boolean:
+			return fmt.Sprintf(`%s(eq .Params.%s "true")`, prefix, v.param)
+		case ok && v.path == path:
+			return prefix + ".Params." + v.param
+		case isValuePrefix(path, values):
+			r.warnf("%s: .Values.%s is a map, maps are imported as one parameter per value", pos, path)
+		default:
+			r.warnf("%s: .Values.%s is not defined in %s", pos, path, helmValuesFileName)
+		}
+		return match
+	})
+}
+
+func isValuePrefix(path string, values map[string]*helmValue) bool {
+	for _, v := range values {
+		if strings.HasPrefix(v.path, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *HelmImport) importedOperator(chart helmChart, resources []string) (string, error) {
+	operator := importedOperator{
+		APIVersion:  CurrentOperatorAPIVersion,
+		Name:        chart.Name,
+		Description: chart.Description,
+		Version:     chart.Version,
+		AppVersion:  chart.AppVersion,
+		URL:         chart.Home,
+		Icon:        chart.Icon,
+		Keywords:    chart.Keywords,
+		Tasks: []importedTask{{
+			Name: helmDeployName,
+			Kind: task.ApplyTaskKind,
+			Spec: v1alpha1.ResourceTaskSpec{Resources: resources},
+		}},
+		Plans: map[string]v1alpha1.Plan{
+			helmDeployName: {
+				Strategy: v1alpha1.Serial,
+				Phases: []v1alpha1.Phase{{
+					Name:     helmDeployName,
+					Strategy: v1alpha1.Serial,
+					Steps:    []v1alpha1.Step{{Name: helmDeployName, Tasks: []string{helmDeployName}}},
+				}},
+			},
+		},
+	}
+	for _, m := range chart.Maintainers {
+		operator.Maintainers = append(operator.Maintainers, &v1alpha1.Maintainer{Name: m.Name, Email: m.Email})
+	}
+	// kubeVersion of a chart is a constraint, only plain versions can be imported
+	if kv := strings.TrimPrefix(strings.TrimPrefix(chart.KubeVersion, ">="), "v"); kv != "" {
+		if regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(strings.TrimSpace(kv)) {
+			operator.KubernetesVersion = strings.TrimSpace(kv)
+		} else {
+			r.warnf("%s: kubeVersion %s is not imported, only minimum versions are supported", helmChartFileName, chart.KubeVersion)
+		}
+	}
+	if len(resources) == 0 {
+		r.warnf("%s: the chart has no templates, the deploy plan has to be completed manually", helmTemplatesDir)
+	}
+
+	b, err := yaml.Marshal(operator)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %v", operatorFileName, err)
+	}
+	return string(b), nil
+}
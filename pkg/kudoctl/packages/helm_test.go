@@ -0,0 +1,151 @@
+package packages
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+const helmChartDir = "/charts/nginx"
+
+func helmTestChart(t *testing.T) afero.Fs {
+	fs := afero.NewMemMapFs()
+	files := map[string]string{
+		"Chart.yaml": `apiVersion: v1
+name: nginx
+version: 1.2.0
+appVersion: 1.17.6
+description: A web server
+kubeVersion: ">=1.15.0"
+maintainers:
+- name: alice
+  email: alice@example.com
+`,
+		"values.yaml": `replicaCount: 2
+image:
+  repository: nginx
+  pullPolicy: IfNotPresent
+ingress:
+  enabled: false
+  hosts:
+  - example.com
+resources: {}
+`,
+		"templates/_helpers.tpl": `{{- define "nginx.fullname" -}}
+{{ .Release.Name }}-{{ .Chart.Name }}
+{{- end -}}
+`,
+		"templates/deployment.yaml": `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{ .Release.Name }}
+  namespace: {{ $.Release.Namespace }}
+spec:
+  replicas: {{ .Values.replicaCount }}
+  template:
+    spec:
+      containers:
+      - image: "{{ .Values.image.repository }}:{{ .Chart.AppVersion }}"
+        imagePullPolicy: {{ .Values.image.pullPolicy }}
+        resources:
+{{ toYaml .Values.resources | indent 10 }}
+`,
+		"templates/ingress.yml": `{{- if .Values.ingress.enabled }}
+apiVersion: networking.k8s.io/v1beta1
+kind: Ingress
+metadata:
+  name: {{ .Release.Name }}
+{{- end }}
+`,
+		"templates/NOTES.txt":            "Visit {{ .Release.Name }}",
+		"templates/tests/test-conn.yaml": "kind: Pod",
+	}
+	for name, content := range files {
+		if err := afero.WriteFile(fs, filepath.Join(helmChartDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return fs
+}
+
+func TestImportHelmChart(t *testing.T) {
+	fs := helmTestChart(t)
+
+	imported, err := ImportHelmChart(fs, helmChartDir)
+	if err != nil {
+		t.Fatalf("failed to import chart: %v", err)
+	}
+
+	assert.Equal(t, []string{
+		"templates/NOTES.txt: only .yaml and .tpl templates are imported",
+		"templates/deployment.yaml:11: .Chart.AppVersion is not supported",
+		"templates/deployment.yaml:14: function toYaml is not supported",
+		"templates/tests: Helm tests are not imported",
+		"values.yaml: resources is a map, maps can not be parameters, it is imported as an empty string",
+	}, imported.Warnings)
+
+	deployment := imported.Files["templates/deployment.yaml"]
+	assert.Contains(t, deployment, "name: {{ .Name }}")
+	assert.Contains(t, deployment, "namespace: {{ $.Namespace }}")
+	assert.Contains(t, deployment, "replicas: {{ .Params.REPLICA_COUNT }}")
+	assert.Contains(t, deployment, `image: "{{ .Params.IMAGE_REPOSITORY }}:{{ .Chart.AppVersion }}"`)
+	assert.Contains(t, deployment, "imagePullPolicy: {{ .Params.IMAGE_PULL_POLICY }}")
+	assert.Contains(t, imported.Files["templates/ingress.yaml"], `{{- if (eq .Params.INGRESS_ENABLED "true") }}`)
+	assert.Contains(t, imported.Files["templates/_helpers.tpl"], "{{ .Name }}-{{ .OperatorName }}")
+
+	// the imported files are a valid operator package
+	for name, content := range imported.Files {
+		assert.NoError(t, afero.WriteFile(fs, filepath.Join("/operator", name), []byte(content), 0644))
+	}
+	pkg, err := ReadPackage(fs, "/operator")
+	if err != nil {
+		t.Fatalf("failed to read imported package: %v", err)
+	}
+	files, err := pkg.GetPkgFiles()
+	if err != nil {
+		t.Fatalf("failed to read imported package: %v", err)
+	}
+
+	assert.Equal(t, "nginx", files.Operator.Name)
+	assert.Equal(t, "1.2.0", files.Operator.Version)
+	assert.Equal(t, "1.15.0", files.Operator.KubernetesVersion)
+	assert.Equal(t, []string{"deployment.yaml", "ingress.yaml"}, files.Operator.Tasks[0].Spec.Resources)
+	assert.Equal(t, []string{"deploy"}, files.Operator.Plans["deploy"].Phases[0].Steps[0].Tasks)
+
+	params := map[string]string{}
+	for _, p := range files.Params {
+		params[p.Name] = *p.Default
+	}
+	assert.Equal(t, map[string]string{
+		"REPLICA_COUNT":     "2",
+		"IMAGE_REPOSITORY":  "nginx",
+		"IMAGE_PULL_POLICY": "IfNotPresent",
+		"INGRESS_ENABLED":   "false",
+		"INGRESS_HOSTS":     "- example.com",
+		"RESOURCES":         "",
+	}, params)
+}
+
+func TestImportHelmChart_NotAChart(t *testing.T) {
+	_, err := ImportHelmChart(afero.NewMemMapFs(), "/missing")
+	if err == nil || !strings.Contains(err.Error(), "only unpacked charts can be imported") {
+		t.Errorf("expected missing Chart.yaml to fail but got %v", err)
+	}
+}
+
+func TestHelmParamName(t *testing.T) {
+	tests := map[string]string{
+		"replicaCount":     "REPLICA_COUNT",
+		"image.pullPolicy": "IMAGE_PULL_POLICY",
+		"service-account":  "SERVICE_ACCOUNT",
+		"http2Enabled":     "HTTP2_ENABLED",
+		"TLS.secretName":   "TLS_SECRET_NAME",
+		"persistence.size": "PERSISTENCE_SIZE",
+	}
+	for path, expected := range tests {
+		assert.Equal(t, expected, helmParamName(path), path)
+	}
+}
@@ -7,17 +7,23 @@ import (
 	"io/ioutil"
 	"log"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/engine"
 	"github.com/kudobuilder/kudo/pkg/engine/task"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
 	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/attribute"
 	v1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"sigs.k8s.io/yaml"
@@ -25,8 +31,11 @@ import (
 
 const (
 	operatorFileName      = "operator.yaml"
-	templateFileNameRegex = "templates/.*.yaml"
+	templateFileNameRegex = "templates/.*\\.(yaml|tpl)$"
+	crdFileNameRegex      = "crds/.*\\.yaml$"
+	patchFileNameRegex    = "patches/[^/]+\\.yaml$"
 	paramsFileName        = "params.yaml"
+	constantsFileName     = "constants.yaml"
 )
 
 const apiVersion = "kudo.dev/v1alpha1"
@@ -44,10 +53,18 @@ type PackageFiles struct {
 	Templates map[string]string
 	Operator  *Operator
 	Params    []v1alpha1.Parameter
+	// CRDs are the CustomResourceDefinitions bundled in the crds folder by their file name
+	CRDs map[string]string
+	// Overlays are the patches of the files in the patches folder by the file name without extension
+	Overlays map[string][]v1alpha1.Patch
+	// Constants are the values of constants.yaml that templates reference as .Constants
+	Constants *apiextv1beta1.JSON
 }
 
 // Operator is a representation of the KEP-9 Operator YAML
 type Operator struct {
+	// APIVersion is the package format of operator.yaml, see SupportedOperatorAPIVersions
+	APIVersion        string                   `json:"apiVersion,omitempty"`
 	Name              string                   `json:"name"`
 	Description       string                   `json:"description,omitempty"`
 	Version           string                   `json:"version"`
@@ -56,8 +73,35 @@ type Operator struct {
 	KubernetesVersion string                   `json:"kubernetesVersion,omitempty"`
 	Maintainers       []*v1alpha1.Maintainer   `json:"maintainers,omitempty"`
 	URL               string                   `json:"url,omitempty"`
+	Icon              string                   `json:"icon,omitempty"`
+	Keywords          []string                 `json:"keywords,omitempty"`
+	Categories        []string                 `json:"categories,omitempty"`
+	Links             []*v1alpha1.Link         `json:"links,omitempty"`
 	Tasks             []v1alpha1.Task          `json:"tasks"`
 	Plans             map[string]v1alpha1.Plan `json:"plans"`
+	// PreUpgradeChecks have to pass before an instance is upgraded to this version
+	PreUpgradeChecks []v1alpha1.UpgradeCheck `json:"preUpgradeChecks,omitempty"`
+	// DefaultPlans declares the plans executed when an instance is created, updated or upgraded
+	DefaultPlans *v1alpha1.DefaultPlans `json:"defaultPlans,omitempty"`
+	// Requirements are the capabilities of the cluster the operator needs beyond the Kubernetes version
+	Requirements *v1alpha1.ClusterRequirements `json:"requirements,omitempty"`
+	// Endpoints are the templated connection strings or URLs reported for instances once a plan completed
+	Endpoints []v1alpha1.AccessEndpoint `json:"endpoints,omitempty"`
+}
+
+// parameterDefinition is a parameter of params.yaml. Scalar fields are read as strings, so that e.g. a numeric default
+// is kept as written.
+type parameterDefinition struct {
+	DisplayName string                         `json:"displayName,omitempty"`
+	Description string                         `json:"description,omitempty"`
+	Required    *string                        `json:"required,omitempty"`
+	Default     *string                        `json:"default,omitempty"`
+	Trigger     string                         `json:"trigger,omitempty"`
+	Type        v1alpha1.ParameterType         `json:"type,omitempty"`
+	Items       *v1alpha1.ParameterItems       `json:"items,omitempty"`
+	Schema      *apiextv1beta1.JSONSchemaProps `json:"schema,omitempty"`
+	Deprecated  *v1alpha1.ParameterDeprecation `json:"deprecated,omitempty"`
+	Sensitive   bool                           `json:"sensitive,omitempty"`
 }
 
 // PackageFilesDigest is a tuple of data used to return the package files AND the digest of a tarball
@@ -83,48 +127,96 @@ func parsePackageFile(filePath string, fileBytes []byte, currentPackage *Package
 		return strings.HasSuffix(name, paramsFileName)
 	}
 
+	isConstantsFile := func(name string) bool {
+		return strings.HasSuffix(name, constantsFileName)
+	}
+
+	// CRD files are matched first as they may have any name, e.g. crds/operator.yaml
+	isCRDFile := func(name string) bool {
+		matched, err := regexp.Match(crdFileNameRegex, []byte(name))
+		if err != nil {
+			panic(err)
+		}
+		return matched && !isTemplateFile(name)
+	}
+
+	isPatchFile := func(name string) bool {
+		matched, err := regexp.Match(patchFileNameRegex, []byte(name))
+		if err != nil {
+			panic(err)
+		}
+		return matched && !isTemplateFile(name)
+	}
+
 	switch {
+	case isCRDFile(filePath):
+		pathParts := strings.Split(filePath, "crds/")
+		name := pathParts[len(pathParts)-1]
+		if currentPackage.CRDs == nil {
+			currentPackage.CRDs = make(map[string]string)
+		}
+		currentPackage.CRDs[name] = string(fileBytes)
+	case isPatchFile(filePath):
+		pathParts := strings.Split(filePath, "patches/")
+		name := strings.TrimSuffix(pathParts[len(pathParts)-1], ".yaml")
+		patches, err := v1alpha1.ParsePatches(fileBytes)
+		if err != nil {
+			return errors.Wrapf(err, "failed to parse patches file: %s", filePath)
+		}
+		if currentPackage.Overlays == nil {
+			currentPackage.Overlays = make(map[string][]v1alpha1.Patch)
+		}
+		currentPackage.Overlays[name] = patches
 	case isOperatorFile(filePath):
-		if err := yaml.Unmarshal(fileBytes, &currentPackage.Operator); err != nil {
+		operator, err := parseOperator(fileBytes)
+		if err != nil {
 			return errors.Wrap(err, "failed to unmarshal operator file")
 		}
+		currentPackage.Operator = operator
 	case isTemplateFile(filePath):
 		pathParts := strings.Split(filePath, "templates/")
 		name := pathParts[len(pathParts)-1]
 		currentPackage.Templates[name] = string(fileBytes)
 	case isParametersFile(filePath):
-		var params map[string]map[string]string
+		var params map[string]parameterDefinition
 		if err := yaml.Unmarshal(fileBytes, &params); err != nil {
 			return errors.Wrapf(err, "failed to unmarshal parameters file: %s", filePath)
 		}
 		paramsStruct := make([]v1alpha1.Parameter, 0)
 		for paramName, param := range params {
-			required := true // defaults to true
-			if _, ok := param["required"]; ok {
-				parsed, err := strconv.ParseBool(param["required"])
+			required := param.Deprecated == nil // defaults to true, deprecated parameters are optional
+			if param.Required != nil {
+				parsed, err := strconv.ParseBool(*param.Required)
 				if err != nil {
 					// ideally this should never happen and be already caught by some kind of linter
-					return errors.Wrapf(err, "failed parsing required field from parameter %s. cannot convert %s to bool", paramName, param["required"])
+					return errors.Wrapf(err, "failed parsing required field from parameter %s. cannot convert %s to bool", paramName, *param.Required)
 				}
 
 				required = parsed
 			}
-			var defaultValue *string
-			if val, ok := param["default"]; ok {
-				defaultValue = kudo.String(val)
-			}
 
 			r := v1alpha1.Parameter{
 				Name:        paramName,
-				Description: param["description"],
-				Default:     defaultValue,
-				Trigger:     param["trigger"],
+				Description: param.Description,
+				Default:     param.Default,
+				Trigger:     param.Trigger,
 				Required:    required,
-				DisplayName: param["displayName"],
+				DisplayName: param.DisplayName,
+				Type:        param.Type,
+				Items:       param.Items,
+				Schema:      param.Schema,
+				Deprecated:  param.Deprecated,
+				Sensitive:   param.Sensitive,
 			}
 			paramsStruct = append(paramsStruct, r)
 		}
 		currentPackage.Params = paramsStruct
+	case isConstantsFile(filePath):
+		constants, err := v1alpha1.ParseConstants(fileBytes)
+		if err != nil {
+			return errors.Wrapf(err, "failed to unmarshal constants file: %s", filePath)
+		}
+		currentPackage.Constants = constants
 	default:
 		return fmt.Errorf("unexpected file when reading package from filesystem: %s", filePath)
 	}
@@ -137,6 +229,76 @@ func newPackageFiles() PackageFiles {
 	}
 }
 
+// validateCRDs checks that every file of the crds folder is a single CustomResourceDefinition
+func validateCRDs(crds map[string]string) []string {
+	var errs []string
+	for name, manifest := range crds {
+		var crd apiextv1beta1.CustomResourceDefinition
+		if err := yaml.Unmarshal([]byte(manifest), &crd); err != nil {
+			errs = append(errs, fmt.Sprintf("crd %s is invalid: %v", name, err))
+			continue
+		}
+		if crd.Kind != "CustomResourceDefinition" {
+			errs = append(errs, fmt.Sprintf("crd %s has kind %q but only CustomResourceDefinitions are allowed in the crds folder", name, crd.Kind))
+			continue
+		}
+		if crd.Name == "" {
+			errs = append(errs, fmt.Sprintf("crd %s has no name", name))
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+// validateDeprecations checks that deprecated parameters are replaced by parameters that are declared
+func validateDeprecations(params []v1alpha1.Parameter) []string {
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		declared[p.Name] = true
+	}
+	var errs []string
+	for _, p := range params {
+		if p.Deprecated != nil && p.Deprecated.ReplacedBy != "" && !declared[p.Deprecated.ReplacedBy] {
+			errs = append(errs, fmt.Sprintf("parameter %s is replaced by parameter %s which is not declared in %s", p.Name, p.Deprecated.ReplacedBy, paramsFileName))
+		}
+	}
+	sort.Strings(errs)
+	return errs
+}
+
+// validateEndpoints checks that the endpoints are complete, have unique names and do not reference sensitive
+// parameters, as the rendered endpoints are stored unencrypted in the status of instances
+func validateEndpoints(endpoints []v1alpha1.AccessEndpoint, params []v1alpha1.Parameter) []string {
+	sensitive := make(map[string]bool, len(params))
+	for _, p := range params {
+		sensitive[p.Name] = p.Sensitive
+	}
+	var errs []string
+	names := make(map[string]bool, len(endpoints))
+	e := engine.New()
+	for _, ep := range endpoints {
+		if err := ep.Validate(); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if names[ep.Name] {
+			errs = append(errs, fmt.Sprintf("endpoint %s is declared more than once", ep.Name))
+		}
+		names[ep.Name] = true
+		refs, err := e.ParameterReferences(ep.Value)
+		if err != nil {
+			// invalid values are reported by validateParameterReferences
+			continue
+		}
+		for _, ref := range refs {
+			if sensitive[ref] {
+				errs = append(errs, fmt.Sprintf("endpoint %s references sensitive parameter %s, endpoints are stored unencrypted in the status of instances", ep.Name, ref))
+			}
+		}
+	}
+	return errs
+}
+
 func validateTask(t v1alpha1.Task, templates map[string]string) []string {
 	var resources []string
 	switch t.Kind {
@@ -145,31 +307,332 @@ func validateTask(t v1alpha1.Task, templates map[string]string) []string {
 	case task.DeleteTaskKind:
 		resources = t.Spec.ResourceTaskSpec.Resources
 	case task.DummyTaskKind:
+	case task.InstanceTaskKind:
+		if t.Spec.InstanceTaskSpec.OperatorVersion == "" {
+			return []string{fmt.Sprintf("task %s is missing the operatorVersion to instantiate", t.Name)}
+		}
+	case task.ExecTaskKind:
+		return validateExecTask(t)
+	case task.RolloutTaskKind:
+		resources = t.Spec.ResourceTaskSpec.Resources
+		if errs := validateRolloutTask(t); len(errs) > 0 {
+			return errs
+		}
 	default:
 		log.Printf("no validation for task kind %s implemented", t.Kind)
 	}
 
-	var errs []string
+	errs := validateKindOrder(t)
 	for _, res := range resources {
 		if _, ok := templates[res]; !ok {
 			errs = append(errs, fmt.Sprintf("task %s missing template: %s", t.Name, res))
+			continue
+		}
+		if engine.IsPartial(res) {
+			errs = append(errs, fmt.Sprintf("task %s can not use partial template as a resource: %s", t.Name, res))
 		}
 	}
 
 	return errs
 }
 
-func (p *PackageFiles) getCRDs() (*PackageCRDs, error) {
+// validateKindOrder makes sure that the kind order of a task lists every kind once
+func validateKindOrder(t v1alpha1.Task) []string {
+	var errs []string
+	seen := map[string]bool{}
+	for _, kind := range t.Spec.ResourceTaskSpec.KindOrder {
+		switch {
+		case kind == "":
+			errs = append(errs, fmt.Sprintf("task %s has an empty kind in its kindOrder", t.Name))
+		case seen[kind]:
+			errs = append(errs, fmt.Sprintf("task %s lists kind %s twice in its kindOrder", t.Name, kind))
+		}
+		seen[kind] = true
+	}
+	return errs
+}
+
+// validateExecTask makes sure that an exec task selects pods, has a command and a valid order and failure policy
+func validateExecTask(t v1alpha1.Task) []string {
+	var errs []string
+	spec := t.Spec.ExecTaskSpec
+	if len(spec.PodSelector) == 0 {
+		errs = append(errs, fmt.Sprintf("task %s is missing the podSelector of the pods to run the command in", t.Name))
+	}
+	if len(spec.Command) == 0 {
+		errs = append(errs, fmt.Sprintf("task %s is missing the command to run", t.Name))
+	}
+	switch spec.Order {
+	case "", v1alpha1.ExecSequential, v1alpha1.ExecParallel:
+	default:
+		errs = append(errs, fmt.Sprintf("task %s has an invalid order %s, it has to be %s or %s", t.Name, spec.Order, v1alpha1.ExecSequential, v1alpha1.ExecParallel))
+	}
+	switch spec.FailurePolicy {
+	case "", v1alpha1.ExecFail, v1alpha1.ExecRetry, v1alpha1.ExecIgnore:
+	default:
+		errs = append(errs, fmt.Sprintf("task %s has an invalid failurePolicy %s, it has to be %s, %s or %s", t.Name, spec.FailurePolicy, v1alpha1.ExecFail, v1alpha1.ExecRetry, v1alpha1.ExecIgnore))
+	}
+	return errs
+}
+
+// validateRolloutTask makes sure that a rollout task updates at least one pod per batch and pauses for a positive time
+func validateRolloutTask(t v1alpha1.Task) []string {
+	var errs []string
+	spec := t.Spec.RolloutTaskSpec
+	if spec.BatchSize != nil && *spec.BatchSize < 1 {
+		errs = append(errs, fmt.Sprintf("task %s has an invalid batchSize %d, it has to be at least 1", t.Name, *spec.BatchSize))
+	}
+	if spec.Pause != nil && spec.Pause.Duration <= 0 {
+		errs = append(errs, fmt.Sprintf("task %s has an invalid pause: %s", t.Name, spec.Pause.Duration))
+	}
+	return errs
+}
+
+// validateDefaultPlans makes sure that the declared default plans and the deploy plan exist. A plan with a reserved
+// name, e.g. "upgrade", that is replaced by another declared default plan is not executed automatically anymore, which
+// is reported as a warning.
+func validateDefaultPlans(defaults *v1alpha1.DefaultPlans, plans map[string]v1alpha1.Plan) (errs []string, warnings []string) {
+	roles := []struct {
+		role     string
+		reserved string
+		declared string
+		plan     string
+	}{
+		{"deploy", v1alpha1.DeployPlanName, defaults.DeployPlan(), "executed when an instance is created"},
+		{"update", v1alpha1.UpdatePlanName, defaults.UpdatePlan(), "executed when parameters change"},
+		{"upgrade", v1alpha1.UpgradePlanName, defaults.UpgradePlan(), "executed when an instance is upgraded"},
+	}
+	for _, r := range roles {
+		_, exists := plans[r.declared]
+		switch {
+		case r.declared != r.reserved && !exists:
+			errs = append(errs, fmt.Sprintf("default %s plan %s is not a plan of the operator", r.role, r.declared))
+		case r.role == "deploy" && !exists:
+			errs = append(errs, fmt.Sprintf("operator has no %s plan and declares no other default deploy plan", r.reserved))
+		}
+		if _, ok := plans[r.reserved]; ok && r.declared != r.reserved {
+			warnings = append(warnings, fmt.Sprintf("plan %s is not %s, because %s is declared as the default %s plan", r.reserved, r.plan, r.declared, r.role))
+		}
+	}
+	return errs, warnings
+}
+
+// validateTimeouts makes sure that the timeouts of a plan and its phases and steps are positive
+func validateTimeouts(name string, plan v1alpha1.Plan) []string {
+	var errs []string
+	invalid := func(timeout *metav1.Duration) bool {
+		return timeout != nil && timeout.Duration <= 0
+	}
+	if invalid(plan.Timeout) {
+		errs = append(errs, fmt.Sprintf("plan %s has an invalid timeout: %s", name, plan.Timeout.Duration))
+	}
+	for _, ph := range plan.Phases {
+		if invalid(ph.Timeout) {
+			errs = append(errs, fmt.Sprintf("phase %s.%s has an invalid timeout: %s", name, ph.Name, ph.Timeout.Duration))
+		}
+		for _, st := range ph.Steps {
+			if invalid(st.Timeout) {
+				errs = append(errs, fmt.Sprintf("step %s.%s.%s has an invalid timeout: %s", name, ph.Name, st.Name, st.Timeout.Duration))
+			}
+		}
+	}
+	return errs
+}
+
+// validatePlanReferences makes sure that steps referencing a plan declare nothing else and that all plans can be
+// resolved, i.e. that the referenced plans exist and that the references form no cycle and are not nested too deep
+func validatePlanReferences(plans map[string]v1alpha1.Plan) []string {
+	names := make([]string, 0, len(plans))
+	for name := range plans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []string
+	reported := map[string]bool{}
+	for _, name := range names {
+		for _, ph := range plans[name].Phases {
+			for _, st := range ph.Steps {
+				if st.Plan != "" && (len(st.Tasks) > 0 || st.Delete || st.Timeout != nil) {
+					errs = append(errs, fmt.Sprintf("step %s.%s.%s references plan %s and can not declare tasks, delete or a timeout", name, ph.Name, st.Name, st.Plan))
+				}
+			}
+		}
+		// a broken reference is reported once, even if other plans reference the plan declaring it
+		if _, err := v1alpha1.ResolvePlan(plans, name); err != nil && !reported[err.Error()] {
+			reported[err.Error()] = true
+			errs = append(errs, err.Error())
+		}
+	}
+	return errs
+}
+
+// templateSources returns the templates, the templated fields of instance and exec tasks and the values of the
+// endpoints by a description of their origin
+func templateSources(templates map[string]string, tasks []v1alpha1.Task, endpoints []v1alpha1.AccessEndpoint) map[string]string {
+	sources := make(map[string]string, len(templates))
+	for name, tpl := range templates {
+		sources[fmt.Sprintf("template %s", name)] = tpl
+	}
+	for _, t := range tasks {
+		switch t.Kind {
+		case task.InstanceTaskKind:
+			for name, tpl := range t.Spec.InstanceTaskSpec.Parameters {
+				sources[fmt.Sprintf("task %s parameter %s", t.Name, name)] = tpl
+			}
+		case task.ExecTaskKind:
+			for i, tpl := range t.Spec.ExecTaskSpec.Command {
+				sources[fmt.Sprintf("task %s command argument %d", t.Name, i)] = tpl
+			}
+			for name, tpl := range t.Spec.ExecTaskSpec.PodSelector {
+				sources[fmt.Sprintf("task %s pod selector %s", t.Name, name)] = tpl
+			}
+		}
+	}
+	for _, ep := range endpoints {
+		sources[fmt.Sprintf("endpoint %s", ep.Name)] = ep.Value
+	}
+	return sources
+}
+
+// sortedNames returns the sorted names of the sources
+func sortedNames(sources map[string]string) []string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateParameterReferences parses the templates, the parameters of instance tasks and the endpoints and reports references to
+// parameters that are not declared in params.yaml as errors. Declared parameters that are never referenced are
+// returned as warnings.
+func validateParameterReferences(templates map[string]string, tasks []v1alpha1.Task, endpoints []v1alpha1.AccessEndpoint, params []v1alpha1.Parameter) (errs []string, warnings []string) {
+	sources := templateSources(templates, tasks, endpoints)
+
+	declared := make(map[string]bool, len(params))
+	for _, p := range params {
+		declared[p.Name] = true
+		if p.HasComputedDefault() {
+			sources[fmt.Sprintf("default of parameter %s", p.Name)] = *p.Default
+		}
+	}
+
+	e := engine.New()
+	used := make(map[string]bool, len(params))
+	for _, name := range sortedNames(sources) {
+		refs, err := e.ParameterReferences(sources[name])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s is invalid: %v", name, err))
+			continue
+		}
+		for _, ref := range refs {
+			used[ref] = true
+			if !declared[ref] {
+				errs = append(errs, fmt.Sprintf("%s references parameter %s which is not declared in %s", name, ref, paramsFileName))
+			}
+		}
+	}
+
+	for _, p := range params {
+		replaced := p.Deprecated != nil && p.Deprecated.ReplacedBy != ""
+		if !used[p.Name] && !replaced {
+			warnings = append(warnings, fmt.Sprintf("parameter %s is declared in %s but not used in any template", p.Name, paramsFileName))
+		}
+	}
+	sort.Strings(warnings)
+	return errs, warnings
+}
+
+// validateConstantReferences reports references to constants that are not declared in constants.yaml as errors.
+// Declared constants that are never referenced are returned as warnings. Constants are not available in the computed
+// defaults of parameters.
+func validateConstantReferences(templates map[string]string, tasks []v1alpha1.Task, endpoints []v1alpha1.AccessEndpoint, constants *apiextv1beta1.JSON) (errs []string, warnings []string) {
+	ov := &v1alpha1.OperatorVersion{Spec: v1alpha1.OperatorVersionSpec{Constants: constants}}
+	declared, err := ov.TemplateConstants()
+	if err != nil {
+		return []string{fmt.Sprintf("%s is invalid: %v", constantsFileName, err)}, nil
+	}
+
+	sources := templateSources(templates, tasks, endpoints)
+	e := engine.New()
+	used := make(map[string]bool, len(declared))
+	for _, name := range sortedNames(sources) {
+		refs, err := e.ConstantReferences(sources[name])
+		if err != nil {
+			// invalid templates are reported by validateParameterReferences
+			continue
+		}
+		for _, ref := range refs {
+			used[ref] = true
+			if _, ok := declared[ref]; !ok {
+				errs = append(errs, fmt.Sprintf("%s references constant %s which is not declared in %s", name, ref, constantsFileName))
+			}
+		}
+	}
+
+	for name := range declared {
+		if !used[name] {
+			warnings = append(warnings, fmt.Sprintf("constant %s is declared in %s but not used in any template", name, constantsFileName))
+		}
+	}
+	sort.Strings(warnings)
+	return errs, warnings
+}
+
+func (p *PackageFiles) getCRDs() (crds *PackageCRDs, err error) {
+	span := tracing.StartSpan("package.Render")
+	defer func() { tracing.EndSpan(span, err) }()
+
 	if p.Operator == nil {
 		return nil, errors.New("operator.yaml file is missing")
 	}
 	if p.Params == nil {
 		return nil, errors.New("params.yaml file is missing")
 	}
+	span.SetAttributes(attribute.String("kudo.operator", p.Operator.Name), attribute.String("kudo.version", p.Operator.Version))
+
 	var errs []string
 	for _, tt := range p.Operator.Tasks {
 		errs = append(errs, validateTask(tt, p.Templates)...)
 	}
+	for name, plan := range p.Operator.Plans {
+		errs = append(errs, validateTimeouts(name, plan)...)
+	}
+	errs = append(errs, validatePlanReferences(p.Operator.Plans)...)
+	for _, param := range p.Params {
+		if err := param.ValidateDefinition(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	for _, check := range p.Operator.PreUpgradeChecks {
+		if err := check.Validate(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if p.Operator.Requirements != nil {
+		if err := p.Operator.Requirements.Validate(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	errs = append(errs, validateEndpoints(p.Operator.Endpoints, p.Params)...)
+	errs = append(errs, validateDeprecations(p.Params)...)
+	if _, err := task.ComputedDefaultsOrder(p.Params); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, validateCRDs(p.CRDs)...)
+	planErrs, warnings := validateDefaultPlans(p.Operator.DefaultPlans, p.Operator.Plans)
+	errs = append(errs, planErrs...)
+	refErrs, refWarnings := validateParameterReferences(p.Templates, p.Operator.Tasks, p.Operator.Endpoints, p.Params)
+	errs = append(errs, refErrs...)
+	warnings = append(warnings, refWarnings...)
+	constErrs, constWarnings := validateConstantReferences(p.Templates, p.Operator.Tasks, p.Operator.Endpoints, p.Constants)
+	errs = append(errs, constErrs...)
+	warnings = append(warnings, constWarnings...)
+	for _, w := range warnings {
+		clog.Printf("WARNING: %s", w)
+	}
 
 	if len(errs) != 0 {
 		return nil, errors.New(strings.Join(errs, "\n"))
@@ -190,6 +653,11 @@ func (p *PackageFiles) getCRDs() (*PackageCRDs, error) {
 			KubernetesVersion: p.Operator.KubernetesVersion,
 			Maintainers:       p.Operator.Maintainers,
 			URL:               p.Operator.URL,
+			Icon:              p.Operator.Icon,
+			Keywords:          p.Operator.Keywords,
+			Categories:        p.Operator.Categories,
+			Links:             p.Operator.Links,
+			Requirements:      p.Operator.Requirements,
 		},
 		Status: v1alpha1.OperatorStatus{},
 	}
@@ -208,38 +676,48 @@ func (p *PackageFiles) getCRDs() (*PackageCRDs, error) {
 				Name: p.Operator.Name,
 				Kind: "Operator",
 			},
-			Version:        p.Operator.Version,
-			Templates:      p.Templates,
-			Tasks:          p.Operator.Tasks,
-			Parameters:     p.Params,
-			Plans:          p.Operator.Plans,
-			UpgradableFrom: nil,
+			Version:          p.Operator.Version,
+			AppVersion:       p.Operator.AppVersion,
+			Templates:        p.Templates,
+			Tasks:            p.Operator.Tasks,
+			Parameters:       p.Params,
+			Plans:            p.Operator.Plans,
+			UpgradableFrom:   nil,
+			CRDs:             p.CRDs,
+			PreUpgradeChecks: p.Operator.PreUpgradeChecks,
+			Overlays:         p.Overlays,
+			DefaultPlans:     p.Operator.DefaultPlans,
+			Constants:        p.Constants,
+			Endpoints:        p.Operator.Endpoints,
 		},
 		Status: v1alpha1.OperatorVersionStatus{},
 	}
 
-	instance := &v1alpha1.Instance{
+	return &PackageCRDs{
+		Operator:        operator,
+		OperatorVersion: fv,
+		Instance:        NewInstance(p.Operator.Name, p.Operator.Version),
+	}, nil
+}
+
+// NewInstance returns an instance of the version of the operator with a random name
+func NewInstance(operatorName, version string) *v1alpha1.Instance {
+	return &v1alpha1.Instance{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Instance",
 			APIVersion: apiVersion,
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   fmt.Sprintf("%s-%s", p.Operator.Name, rand.String(6)),
-			Labels: map[string]string{"controller-tools.k8s.io": "1.0", kudo.OperatorLabel: p.Operator.Name},
+			Name:   fmt.Sprintf("%s-%s", operatorName, rand.String(6)),
+			Labels: map[string]string{"controller-tools.k8s.io": "1.0", kudo.OperatorLabel: operatorName},
 		},
 		Spec: v1alpha1.InstanceSpec{
 			OperatorVersion: v1.ObjectReference{
-				Name: fmt.Sprintf("%s-%s", p.Operator.Name, p.Operator.Version),
+				Name: fmt.Sprintf("%s-%s", operatorName, version),
 			},
 		},
 		Status: v1alpha1.InstanceStatus{},
 	}
-
-	return &PackageCRDs{
-		Operator:        operator,
-		OperatorVersion: fv,
-		Instance:        instance,
-	}, nil
 }
 
 // GetFilesDigest maps []string of paths to the [] Operators
@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
@@ -143,6 +144,10 @@ func fromFolder(fs afero.Fs, packagePath string) (*PackageFiles, error) {
 			return err
 		}
 		if file.IsDir() {
+			if isFixturesFolder(packagePath, path) {
+				// test fixtures are not part of the operator
+				return filepath.SkipDir
+			}
 			// skip directories
 			clog.V(6).Printf("folder walking skipping directory %v", file)
 			return nil
@@ -5,14 +5,18 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/rand"
 
 	"github.com/go-test/deep"
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 	"sigs.k8s.io/yaml"
@@ -120,3 +124,524 @@ func loadCRDsFromPath(goldenPath string) (*PackageCRDs, error) {
 	}
 	return result, nil
 }
+
+func TestParsePackageFile_Partials(t *testing.T) {
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/templates/_helpers.tpl", []byte(`{{ define "labels" }}{{ end }}`), &pkg); err != nil {
+		t.Fatalf("expected partial to be accepted but got %v", err)
+	}
+	if _, ok := pkg.Templates["_helpers.tpl"]; !ok {
+		t.Errorf("expected partial to be stored with the templates but got %v", pkg.Templates)
+	}
+	if err := parsePackageFile("operator/templates/notes.txt", []byte("notes"), &pkg); err == nil {
+		t.Errorf("expected error for unexpected file in templates folder")
+	}
+
+	task := v1alpha1.Task{Name: "deploy", Kind: "Apply", Spec: v1alpha1.TaskSpec{ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"_helpers.tpl"}}}}
+	errs := validateTask(task, pkg.Templates)
+	if len(errs) != 1 || !strings.Contains(errs[0], "can not use partial template as a resource: _helpers.tpl") {
+		t.Errorf("expected partial used as a resource to be rejected but got %v", errs)
+	}
+}
+
+func TestParsePackageFile_CRDs(t *testing.T) {
+	crd := `apiVersion: apiextensions.k8s.io/v1beta1
+kind: CustomResourceDefinition
+metadata:
+  name: topics.kafka.example.com
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/crds/operator.yaml", []byte(crd), &pkg); err != nil {
+		t.Fatalf("expected crd to be accepted but got %v", err)
+	}
+	if pkg.Operator != nil {
+		t.Errorf("expected crds/operator.yaml not to be parsed as operator.yaml")
+	}
+	if err := parsePackageFile("operator/templates/crds/topic.yaml", []byte("kind: Topic"), &pkg); err != nil {
+		t.Fatalf("expected template to be accepted but got %v", err)
+	}
+	if _, ok := pkg.CRDs["operator.yaml"]; !ok || len(pkg.CRDs) != 1 {
+		t.Errorf("expected only the crd to be stored with the crds but got %v", pkg.CRDs)
+	}
+
+	pkg.CRDs["topic.yaml"] = "kind: Topic"
+	errs := validateCRDs(pkg.CRDs)
+	if len(errs) != 1 || errs[0] != `crd topic.yaml has kind "Topic" but only CustomResourceDefinitions are allowed in the crds folder` {
+		t.Errorf("expected other kinds to be rejected but got %v", errs)
+	}
+}
+
+func TestParsePackageFile_Patches(t *testing.T) {
+	patches := `- strategicMerge: |
+    apiVersion: apps/v1
+    kind: StatefulSet
+    metadata:
+      name: zk
+    spec:
+      replicas: 5
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/patches/params.yaml", []byte(patches), &pkg); err != nil {
+		t.Fatalf("expected patches to be accepted but got %v", err)
+	}
+	if pkg.Params != nil {
+		t.Errorf("expected patches/params.yaml not to be parsed as params.yaml")
+	}
+	if overlay, ok := pkg.Overlays["params"]; !ok || len(overlay) != 1 {
+		t.Errorf("expected the patches to be stored as overlay params but got %v", pkg.Overlays)
+	}
+	if err := parsePackageFile("operator/templates/patches/job.yaml", []byte("kind: Job"), &pkg); err != nil {
+		t.Fatalf("expected template to be accepted but got %v", err)
+	}
+	if err := parsePackageFile("operator/patches/production.yaml", []byte("- json6902: []\n"), &pkg); err == nil {
+		t.Errorf("expected error for a JSON patch without target")
+	}
+}
+
+func TestParsePackageFile_Timeouts(t *testing.T) {
+	operator := `name: kafka
+version: 0.1.0
+tasks: []
+plans:
+  deploy:
+    strategy: serial
+    timeout: 30m
+    phases:
+      - name: main
+        strategy: serial
+        timeout: 0s
+        steps:
+          - name: everything
+            timeout: 5m
+            tasks:
+              - app
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/operator.yaml", []byte(operator), &pkg); err != nil {
+		t.Fatalf("expected operator to be parsed but got %v", err)
+	}
+	plan := pkg.Operator.Plans["deploy"]
+	if plan.Timeout == nil || plan.Timeout.Duration != 30*time.Minute {
+		t.Errorf("expected plan timeout of 30m but got %v", plan.Timeout)
+	}
+	if step := plan.Phases[0].Steps[0]; step.Timeout == nil || step.Timeout.Duration != 5*time.Minute {
+		t.Errorf("expected step timeout of 5m but got %v", step.Timeout)
+	}
+
+	errs := validateTimeouts("deploy", plan)
+	if len(errs) != 1 || errs[0] != "phase deploy.main has an invalid timeout: 0s" {
+		t.Errorf("expected the zero phase timeout to be rejected but got %v", errs)
+	}
+}
+
+func TestValidateParameterReferences(t *testing.T) {
+	templates := map[string]string{
+		"_helpers.tpl":     `{{ define "labels" }}app: {{ $.Params.APP }}{{ end }}`,
+		"deployment.yaml":  `replicas: {{ .Params.REPLICAS }}{{ if .Params.DEBUG }}{{ index .Params "LOG_LEVEL" }}{{ end }}`,
+		"config.yaml":      `{{ with .Params }}{{ .PORT }}{{ end }}{{ range .Pods }}{{ $.Params.MEMORY }}{{ end }}`,
+		"invalid.yaml":     `{{ .Params.REPLICAS `,
+		"no-params.yaml":   `name: {{ .Name }}`,
+		"unknown-fn.yaml":  `{{ .Params.APP | unknownFunction }}`,
+		"include-fn.yaml":  `{{ include "labels" . | indent 2 }}`,
+		"nested-ref.yaml":  `{{ .Params.CONFIG.nested }}`,
+		"undeclared.yaml":  `{{ default "x" .Params.MISSING }}`,
+		"undeclared2.yaml": `{{ quote $.Params.OTHER }}`,
+	}
+	tasks := []v1alpha1.Task{
+		{Name: "child", Kind: "Instance", Spec: v1alpha1.TaskSpec{InstanceTaskSpec: v1alpha1.InstanceTaskSpec{
+			OperatorVersion: "zookeeper-0.1.0",
+			Parameters:      map[string]string{"NODES": "{{ .Params.ZK_NODES }}"},
+		}}},
+	}
+	var params []v1alpha1.Parameter
+	for _, name := range []string{"APP", "REPLICAS", "DEBUG", "LOG_LEVEL", "MEMORY", "CONFIG", "ZK_NODES", "PORT", "UNUSED"} {
+		params = append(params, v1alpha1.Parameter{Name: name})
+	}
+
+	errs, warnings := validateParameterReferences(templates, tasks, nil, params)
+
+	expectedErrs := []string{
+		`template invalid.yaml is invalid: error parsing template`,
+		`template undeclared.yaml references parameter MISSING which is not declared in params.yaml`,
+		`template undeclared2.yaml references parameter OTHER which is not declared in params.yaml`,
+		`template unknown-fn.yaml is invalid: error parsing template`,
+	}
+	if len(errs) != len(expectedErrs) {
+		t.Fatalf("expected errors:\n%s\nbut got:\n%s", strings.Join(expectedErrs, "\n"), strings.Join(errs, "\n"))
+	}
+	for i := range errs {
+		if !strings.HasPrefix(errs[i], expectedErrs[i]) {
+			t.Errorf("expected error starting with %q but got %q", expectedErrs[i], errs[i])
+		}
+	}
+	// PORT is only referenced relative to the dot set by with, which is not detected
+	expectedWarnings := []string{
+		"parameter PORT is declared in params.yaml but not used in any template",
+		"parameter UNUSED is declared in params.yaml but not used in any template",
+	}
+	if !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("expected warnings %v but got %v", expectedWarnings, warnings)
+	}
+}
+
+func TestParsePackageFile_Constants(t *testing.T) {
+	constants := `image:
+  repository: confluentinc/cp-kafka
+  tag: 5.3.1
+heapSize: 1000000
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/constants.yaml", []byte(constants), &pkg); err != nil {
+		t.Fatalf("expected constants to be accepted but got %v", err)
+	}
+	expected := `{"heapSize":1000000,"image":{"repository":"confluentinc/cp-kafka","tag":"5.3.1"}}`
+	if pkg.Constants == nil || string(pkg.Constants.Raw) != expected {
+		t.Errorf("expected constants %s but got %v", expected, pkg.Constants)
+	}
+	if err := parsePackageFile("operator/templates/constants.yaml", []byte("kind: ConfigMap"), &pkg); err != nil {
+		t.Fatalf("expected template to be accepted but got %v", err)
+	}
+	if err := parsePackageFile("operator/constants.yaml", []byte("- a\n- b\n"), &pkg); err == nil {
+		t.Errorf("expected error for constants that are not an object")
+	}
+}
+
+func TestValidateConstantReferences(t *testing.T) {
+	templates := map[string]string{
+		"deployment.yaml": `image: {{ .Constants.image.repository }}:{{ $.Constants.image.tag }}`,
+		"config.yaml":     `heap: {{ index .Constants "heapSize" }} replicas: {{ .Params.REPLICAS }}`,
+		"undeclared.yaml": `{{ .Constants.MISSING }}`,
+	}
+	tasks := []v1alpha1.Task{
+		{Name: "check", Kind: "Exec", Spec: v1alpha1.TaskSpec{ExecTaskSpec: v1alpha1.ExecTaskSpec{
+			Command: []string{"check", "{{ .Constants.timeout }}"},
+		}}},
+	}
+	constants, err := v1alpha1.ParseConstants([]byte("image: {repository: kafka, tag: '1'}\nheapSize: 1G\ntimeout: 30\nunused: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs, warnings := validateConstantReferences(templates, tasks, nil, constants)
+	expectedErrs := []string{"template undeclared.yaml references constant MISSING which is not declared in constants.yaml"}
+	if !reflect.DeepEqual(errs, expectedErrs) {
+		t.Errorf("expected errors %v but got %v", expectedErrs, errs)
+	}
+	expectedWarnings := []string{"constant unused is declared in constants.yaml but not used in any template"}
+	if !reflect.DeepEqual(warnings, expectedWarnings) {
+		t.Errorf("expected warnings %v but got %v", expectedWarnings, warnings)
+	}
+
+	errs, _ = validateConstantReferences(templates, nil, nil, nil)
+	if len(errs) != 3 {
+		t.Errorf("expected all references to be reported without constants.yaml but got %v", errs)
+	}
+}
+
+func TestParsePackageFile_ArrayParameters(t *testing.T) {
+	params := `REPLICAS:
+  default: 3
+  required: false
+LISTENERS:
+  type: array
+  items:
+    type: object
+    required: [name, port]
+  default: |
+    - name: client
+      port: 9092
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/params.yaml", []byte(params), &pkg); err != nil {
+		t.Fatalf("expected params to be parsed but got %v", err)
+	}
+	byName := map[string]v1alpha1.Parameter{}
+	for _, p := range pkg.Params {
+		byName[p.Name] = p
+	}
+
+	replicas := byName["REPLICAS"]
+	if replicas.Required || replicas.Default == nil || *replicas.Default != "3" || replicas.IsArray() {
+		t.Errorf("expected optional string parameter REPLICAS with default 3 but got %+v", replicas)
+	}
+	listeners := byName["LISTENERS"]
+	if !listeners.IsArray() || listeners.Items == nil || !reflect.DeepEqual(listeners.Items.Required, []string{"name", "port"}) {
+		t.Errorf("expected array parameter LISTENERS with item schema but got %+v", listeners)
+	}
+	if err := listeners.ValidateDefinition(); err != nil {
+		t.Errorf("expected valid definition of LISTENERS but got %v", err)
+	}
+
+	listeners.Default = kudo.String("- name: client")
+	if err := listeners.ValidateDefinition(); err == nil || err.Error() != "default of parameter LISTENERS is invalid: item 0 is missing required keys: port" {
+		t.Errorf("expected invalid default of LISTENERS to be rejected but got %v", err)
+	}
+}
+
+func TestParsePackageFile_ParameterSchema(t *testing.T) {
+	params := `BROKER:
+  type: object
+  schema:
+    type: object
+    required: [name]
+    properties:
+      name:
+        type: string
+        description: Name of the broker
+      port:
+        type: integer
+        minimum: 1024
+  default: |
+    name: kafka
+    port: 9092
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/params.yaml", []byte(params), &pkg); err != nil {
+		t.Fatalf("expected params to be parsed but got %v", err)
+	}
+	broker := pkg.Params[0]
+	if !broker.IsObject() || broker.Schema == nil || broker.Schema.Properties["name"].Description != "Name of the broker" {
+		t.Errorf("expected object parameter BROKER with schema but got %+v", broker)
+	}
+	if err := broker.ValidateDefinition(); err != nil {
+		t.Errorf("expected valid definition of BROKER but got %v", err)
+	}
+
+	broker.Default = kudo.String("{name: kafka, port: 80}")
+	if err := broker.ValidateDefinition(); err == nil || err.Error() != "default of parameter BROKER is invalid: value.port must be greater than or equal to 1024" {
+		t.Errorf("expected invalid default of BROKER to be rejected but got %v", err)
+	}
+}
+
+func TestParsePackageFile_DeprecatedParameters(t *testing.T) {
+	params := `BROKER_COUNT:
+  default: 3
+NODE_COUNT:
+  deprecated:
+    replacedBy: BROKER_COUNT
+    graceVersion: 2.0.0
+ZK_URI:
+  deprecated:
+    replacedBy: ZOOKEEPER_URI
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/params.yaml", []byte(params), &pkg); err != nil {
+		t.Fatalf("expected params to be parsed but got %v", err)
+	}
+	byName := map[string]v1alpha1.Parameter{}
+	for _, p := range pkg.Params {
+		byName[p.Name] = p
+	}
+
+	nodeCount := byName["NODE_COUNT"]
+	if nodeCount.Required || nodeCount.Deprecated == nil || nodeCount.Deprecated.ReplacedBy != "BROKER_COUNT" || nodeCount.Deprecated.GraceVersion != "2.0.0" {
+		t.Errorf("expected optional parameter NODE_COUNT replaced by BROKER_COUNT but got %+v", nodeCount)
+	}
+
+	errs := validateDeprecations(pkg.Params)
+	expected := []string{"parameter ZK_URI is replaced by parameter ZOOKEEPER_URI which is not declared in params.yaml"}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v but got %v", expected, errs)
+	}
+}
+
+func TestParsePackageFile_Endpoints(t *testing.T) {
+	operator := `apiVersion: kudo.dev/v1alpha1
+name: kafka
+version: 0.1.0
+endpoints:
+  - name: bootstrap-servers
+    description: Bootstrap servers of Kafka clients
+    value: "{{ .Name }}-svc.{{ .Namespace }}:{{ .Params.BROKER_PORT }}"
+  - name: admin
+    value: "https://admin:{{ .Params.ADMIN_PASSWORD }}@{{ .Name }}-admin.{{ .Namespace }}"
+  - name: admin
+    value: "{{ .Name }}"
+  - name: empty
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/operator.yaml", []byte(operator), &pkg); err != nil {
+		t.Fatalf("expected operator.yaml to be parsed but got %v", err)
+	}
+	if len(pkg.Operator.Endpoints) != 4 || pkg.Operator.Endpoints[0].Description != "Bootstrap servers of Kafka clients" {
+		t.Fatalf("expected the endpoints of operator.yaml but got %+v", pkg.Operator.Endpoints)
+	}
+
+	params := []v1alpha1.Parameter{{Name: "BROKER_PORT"}, {Name: "ADMIN_PASSWORD", Sensitive: true}}
+	errs := validateEndpoints(pkg.Operator.Endpoints, params)
+	expected := []string{
+		"endpoint admin references sensitive parameter ADMIN_PASSWORD, endpoints are stored unencrypted in the status of instances",
+		"endpoint admin is declared more than once",
+		"endpoint empty has no value",
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v but got %v", expected, errs)
+	}
+
+	refErrs, warnings := validateParameterReferences(nil, nil, pkg.Operator.Endpoints[:2], []v1alpha1.Parameter{{Name: "BROKER_PORT"}})
+	expected = []string{"endpoint admin references parameter ADMIN_PASSWORD which is not declared in params.yaml"}
+	if !reflect.DeepEqual(refErrs, expected) || len(warnings) != 0 {
+		t.Errorf("expected errors %v and no warnings but got %v and %v", expected, refErrs, warnings)
+	}
+}
+
+func TestParsePackageFile_PlanReferences(t *testing.T) {
+	operator := `apiVersion: kudo.dev/v1alpha1
+name: kafka
+version: 0.1.0
+plans:
+  restart:
+    strategy: serial
+    phases:
+      - name: brokers
+        strategy: serial
+        steps:
+          - name: restart
+            tasks:
+              - restart
+  upgrade:
+    strategy: serial
+    phases:
+      - name: upgrade
+        strategy: serial
+        steps:
+          - name: rolling
+            plan: restart
+          - name: broken
+            plan: restart
+            tasks:
+              - cleanup
+  loop:
+    strategy: serial
+    phases:
+      - name: main
+        strategy: serial
+        steps:
+          - name: again
+            plan: loop
+`
+	pkg := newPackageFiles()
+	if err := parsePackageFile("operator/operator.yaml", []byte(operator), &pkg); err != nil {
+		t.Fatalf("expected operator.yaml to be parsed but got %v", err)
+	}
+	if step := pkg.Operator.Plans["upgrade"].Phases[0].Steps[0]; step.Plan != "restart" || len(step.Tasks) != 0 {
+		t.Fatalf("expected step rolling to reference plan restart but got %+v", step)
+	}
+
+	errs := validatePlanReferences(pkg.Operator.Plans)
+	expected := []string{
+		"plan references form a cycle: loop -> loop",
+		"step upgrade.upgrade.broken references plan restart and can not declare tasks, delete or a timeout",
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v but got %v", expected, errs)
+	}
+}
+
+func TestValidateExecTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     v1alpha1.ExecTaskSpec
+		expected []string
+	}{
+		{"valid", v1alpha1.ExecTaskSpec{PodSelector: map[string]string{"app": "cassandra"}, Command: []string{"nodetool", "drain"}, Order: v1alpha1.ExecParallel, FailurePolicy: v1alpha1.ExecIgnore}, nil},
+		{"missing selector and command", v1alpha1.ExecTaskSpec{}, []string{
+			"task drain is missing the podSelector of the pods to run the command in",
+			"task drain is missing the command to run",
+		}},
+		{"invalid order and policy", v1alpha1.ExecTaskSpec{PodSelector: map[string]string{"app": "cassandra"}, Command: []string{"drain"}, Order: "Random", FailurePolicy: "Panic"}, []string{
+			"task drain has an invalid order Random, it has to be Sequential or Parallel",
+			"task drain has an invalid failurePolicy Panic, it has to be Fail, Retry or Ignore",
+		}},
+	}
+
+	for _, tt := range tests {
+		errs := validateTask(v1alpha1.Task{Name: "drain", Kind: "Exec", Spec: v1alpha1.TaskSpec{ExecTaskSpec: tt.spec}}, nil)
+		if !reflect.DeepEqual(tt.expected, errs) {
+			t.Errorf("%s: expected errors %v but got %v", tt.name, tt.expected, errs)
+		}
+	}
+}
+
+func TestValidateKindOrder(t *testing.T) {
+	task := v1alpha1.Task{Name: "deploy", Kind: "Apply", Spec: v1alpha1.TaskSpec{
+		ResourceTaskSpec: v1alpha1.ResourceTaskSpec{KindOrder: []string{"KafkaTopic", "", "Service", "KafkaTopic"}},
+	}}
+	errs := validateTask(task, nil)
+	expected := []string{
+		"task deploy has an empty kind in its kindOrder",
+		"task deploy lists kind KafkaTopic twice in its kindOrder",
+	}
+	if !reflect.DeepEqual(expected, errs) {
+		t.Errorf("expected errors %v but got %v", expected, errs)
+	}
+}
+
+func TestValidateRolloutTask(t *testing.T) {
+	zero := int32(0)
+	templates := map[string]string{"statefulset.yaml": ""}
+	tests := []struct {
+		name     string
+		spec     v1alpha1.TaskSpec
+		expected []string
+	}{
+		{"valid", v1alpha1.TaskSpec{
+			ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"statefulset.yaml"}},
+			RolloutTaskSpec:  v1alpha1.RolloutTaskSpec{Pause: &metav1.Duration{Duration: time.Minute}},
+		}, nil},
+		{"missing template", v1alpha1.TaskSpec{
+			ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"cassandra.yaml"}},
+		}, []string{"task rollout missing template: cassandra.yaml"}},
+		{"invalid batch size and pause", v1alpha1.TaskSpec{
+			ResourceTaskSpec: v1alpha1.ResourceTaskSpec{Resources: []string{"statefulset.yaml"}},
+			RolloutTaskSpec:  v1alpha1.RolloutTaskSpec{BatchSize: &zero, Pause: &metav1.Duration{Duration: -time.Second}},
+		}, []string{
+			"task rollout has an invalid batchSize 0, it has to be at least 1",
+			"task rollout has an invalid pause: -1s",
+		}},
+	}
+
+	for _, tt := range tests {
+		errs := validateTask(v1alpha1.Task{Name: "rollout", Kind: "Rollout", Spec: tt.spec}, templates)
+		if !reflect.DeepEqual(tt.expected, errs) {
+			t.Errorf("%s: expected errors %v but got %v", tt.name, tt.expected, errs)
+		}
+	}
+}
+
+func TestValidateDefaultPlans(t *testing.T) {
+	plans := func(names ...string) map[string]v1alpha1.Plan {
+		m := map[string]v1alpha1.Plan{}
+		for _, n := range names {
+			m[n] = v1alpha1.Plan{}
+		}
+		return m
+	}
+	tests := []struct {
+		name     string
+		defaults *v1alpha1.DefaultPlans
+		plans    map[string]v1alpha1.Plan
+		errs     []string
+		warnings []string
+	}{
+		{"conventional plans", nil, plans("deploy", "update", "upgrade"), nil, nil},
+		{"missing deploy plan", nil, plans("update"), []string{"operator has no deploy plan and declares no other default deploy plan"}, nil},
+		{"declared plans", &v1alpha1.DefaultPlans{Deploy: "install", Update: "reconfigure"}, plans("install", "reconfigure"), nil, nil},
+		{"missing declared plans", &v1alpha1.DefaultPlans{Deploy: "install", Upgrade: "migrate"}, plans("deploy"), []string{
+			"default deploy plan install is not a plan of the operator",
+			"default upgrade plan migrate is not a plan of the operator",
+		}, []string{"plan deploy is not executed when an instance is created, because install is declared as the default deploy plan"}},
+		{"replaced reserved plan", &v1alpha1.DefaultPlans{Update: "reconfigure"}, plans("deploy", "update", "reconfigure"), nil,
+			[]string{"plan update is not executed when parameters change, because reconfigure is declared as the default update plan"}},
+	}
+
+	for _, tt := range tests {
+		errs, warnings := validateDefaultPlans(tt.defaults, tt.plans)
+		if !reflect.DeepEqual(tt.errs, errs) {
+			t.Errorf("%s: expected errors %v but got %v", tt.name, tt.errs, errs)
+		}
+		if !reflect.DeepEqual(tt.warnings, warnings) {
+			t.Errorf("%s: expected warnings %v but got %v", tt.name, tt.warnings, warnings)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// GitPrefix marks package names referring to a directory in a Git repository, e.g.
+	// git+https://github.com/org/operators//kafka?ref=v1.2.0
+	GitPrefix = "git+"
+	// GitTokenEnv is the environment variable holding the token used to authenticate against Git repositories over
+	// https. Repositories over ssh authenticate with the keys of the ssh agent.
+	GitTokenEnv = "KUDO_GIT_TOKEN"
+	// GitTokenHostsEnv is the environment variable holding the comma separated hosts the GitTokenEnv is sent to, e.g.
+	// github.com. The token is not sent to any other host, so that a package name can not leak it.
+	GitTokenHostsEnv = "KUDO_GIT_TOKEN_HOSTS"
+)
+
+// minTokenGitVersion is the first version of git that reads config from GIT_CONFIG_COUNT, older versions ignore it and
+// would fetch without the token
+var minTokenGitVersion = []int{2, 31}
+
+// GitReference is a parsed package name of a Git source
+type GitReference struct {
+	// Repository is the url of the repository passed to git, e.g. https://github.com/org/operators or
+	// git@github.com:org/operators.git
+	Repository string
+	// Dir is the package directory relative to the root of the repository, the root if it is empty
+	Dir string
+	// Ref is the branch, tag or commit to check out, the default branch if it is empty
+	Ref string
+}
+
+// ParseGitReference parses a package name of the form git+<repository>[//<dir>][?ref=<ref>]
+func ParseGitReference(name string) (*GitReference, error) {
+	if !strings.HasPrefix(name, GitPrefix) {
+		return nil, fmt.Errorf("resolver: %v is no git reference", name)
+	}
+	s := strings.TrimPrefix(name, GitPrefix)
+
+	ref := &GitReference{}
+	if i := strings.Index(s, "?"); i >= 0 {
+		query, err := url.ParseQuery(s[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("resolver: invalid query of git reference %v: %v", name, err)
+		}
+		ref.Ref = query.Get("ref")
+		s = s[:i]
+	}
+
+	// the directory is separated by the first double slash after the scheme of the repository
+	start := 0
+	if i := strings.Index(s, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	ref.Repository = s
+	if i := strings.Index(s[start:], "//"); i >= 0 {
+		ref.Repository = s[:start+i]
+		ref.Dir = strings.Trim(s[start+i+len("//"):], "/")
+	}
+	if ref.Repository == "" {
+		return nil, fmt.Errorf("resolver: git reference %v has no repository", name)
+	}
+	// git would parse them as options
+	if strings.HasPrefix(ref.Repository, "-") || strings.HasPrefix(ref.Ref, "-") {
+		return nil, fmt.Errorf("resolver: repository and ref of git reference %v must not start with '-'", name)
+	}
+	if d := path.Clean(ref.Dir); d == ".." || strings.HasPrefix(d, "../") {
+		return nil, fmt.Errorf("resolver: directory %v of git reference %v must not leave the repository", ref.Dir, name)
+	}
+	return ref, nil
+}
+
+// String returns the package name of the reference
+func (r *GitReference) String() string {
+	s := GitPrefix + r.Repository
+	if r.Dir != "" {
+		s += "//" + r.Dir
+	}
+	if r.Ref != "" {
+		s += "?ref=" + url.QueryEscape(r.Ref)
+	}
+	return s
+}
+
+// Git resolves packages from a directory of a Git repository. The ref is fetched into a shallow clone, the version is
+// ignored. The resolved commit is recorded in the PackageSourceAnnotation of the OperatorVersion and the Instance of the
+// package, so that the exact package can be installed again.
+type Git struct{}
+
+// NewGit creates a source for Git repositories
+func NewGit() *Git {
+	return &Git{}
+}
+
+// Handles returns whether name has the GitPrefix
+func (g *Git) Handles(name string) bool {
+	return strings.HasPrefix(name, GitPrefix)
+}
+
+// GetPackage clones the ref of the repository and provides the package in its directory
+func (g *Git) GetPackage(name string, version string) (packages.Package, error) {
+	ref, err := ParseGitReference(name)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "kudo-git-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	commit, err := g.clone(dir, ref)
+	if err != nil {
+		return nil, err
+	}
+	clog.V(2).Printf("resolved %v to commit %s", ref, commit)
+
+	// the clone is removed, the package is kept in memory
+	fs := afero.NewMemMapFs()
+	pkgDir := filepath.Join(dir, filepath.FromSlash(ref.Dir))
+	if err := copyDir(afero.NewOsFs(), pkgDir, fs, "/package"); err != nil {
+		return nil, fmt.Errorf("resolver: failed to read package directory %q of %v: %v", ref.Dir, ref.Repository, err)
+	}
+	p, err := packages.ReadPackage(fs, "/package")
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := *ref
+	resolved.Ref = commit
+	return &sourcePackage{Package: p, source: resolved.String()}, nil
+}
+
+// clone fetches the ref of the repository with a depth of one into dir and returns the commit it resolved to
+func (g *Git) clone(dir string, ref *GitReference) (string, error) {
+	env := []string{"GIT_TERMINAL_PROMPT=0"}
+	tokenEnv, err := tokenConfig(ref.Repository, os.Getenv(GitTokenEnv), os.Getenv(GitTokenHostsEnv))
+	if err != nil {
+		return "", err
+	}
+	env = append(env, tokenEnv...)
+	target := ref.Ref
+	if target == "" {
+		target = "HEAD"
+	}
+
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "--", "origin", ref.Repository},
+		{"fetch", "--quiet", "--depth", "1", "--", "origin", target},
+		{"checkout", "--quiet", "--detach", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		if _, err := runGit(dir, env, args...); err != nil {
+			return "", fmt.Errorf("resolver: failed to clone %v at %s: %v", ref.Repository, target, err)
+		}
+	}
+	commit, err := runGit(dir, env, "rev-parse", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("resolver: failed to resolve commit of %v at %s: %v", ref.Repository, target, err)
+	}
+	return strings.TrimSpace(commit), nil
+}
+
+// tokenConfig returns the environment that makes git send the token to the host of the repository. The token is only
+// sent over http(s) to the hosts listed in hosts, the header is scoped to the host and redirects are not followed, so
+// that the token is never sent to another host.
+func tokenConfig(repository, token, hosts string) ([]string, error) {
+	if token == "" || isSSH(repository) {
+		return nil, nil
+	}
+	u, err := url.Parse(repository)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, nil
+	}
+	allowed := false
+	for _, h := range strings.Split(hosts, ",") {
+		if h = strings.TrimSpace(h); h != "" && strings.EqualFold(h, u.Host) {
+			allowed = true
+		}
+	}
+	if !allowed {
+		clog.Printf("WARNING: %s is not sent to %s, add the host to %s to authenticate against it", GitTokenEnv, u.Host, GitTokenHostsEnv)
+		return nil, nil
+	}
+	if err := checkGitVersion(minTokenGitVersion); err != nil {
+		return nil, err
+	}
+
+	// the header is passed in the environment to keep the token out of the process list
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	scope := fmt.Sprintf("http.%s://%s/", u.Scheme, u.Host)
+	return []string{
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0=" + scope + ".extraHeader", "GIT_CONFIG_VALUE_0=Authorization: Basic " + auth,
+		"GIT_CONFIG_KEY_1=" + scope + ".followRedirects", "GIT_CONFIG_VALUE_1=false",
+	}, nil
+}
+
+// checkGitVersion fails if the installed git is older than min
+func checkGitVersion(min []int) error {
+	out, err := runGit("", nil, "version")
+	if err != nil {
+		return fmt.Errorf("resolver: %v", err)
+	}
+	version, err := parseGitVersion(out)
+	if err != nil {
+		return fmt.Errorf("resolver: %v", err)
+	}
+	for i := range min {
+		if version[i] != min[i] {
+			if version[i] < min[i] {
+				return fmt.Errorf("resolver: authenticating with %s requires git %d.%d or newer, found %d.%d", GitTokenEnv, min[0], min[1], version[0], version[1])
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// parseGitVersion returns the major and minor version of the output of 'git version', e.g. "git version 2.39.2"
+func parseGitVersion(out string) ([]int, error) {
+	fields := strings.Fields(out)
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected output of git version: %q", out)
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(fields[2], "%d.%d", &major, &minor); err != nil {
+		return nil, fmt.Errorf("unexpected output of git version: %q", out)
+	}
+	return []int{major, minor}, nil
+}
+
+func (g *Git) String() string {
+	return "git repository"
+}
+
+// isSSH returns whether git connects to the repository over ssh, either with an ssh url or a scp-like address
+func isSSH(repository string) bool {
+	if strings.HasPrefix(repository, "ssh://") {
+		return true
+	}
+	return !strings.Contains(repository, "://") && strings.Contains(repository, ":")
+}
+
+// runGit executes git in the directory and returns its output
+func runGit(dir string, env []string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// copyDir copies the files of the directory src of srcFs to the directory dst of dstFs
+func copyDir(srcFs afero.Fs, src string, dstFs afero.Fs, dst string) error {
+	return afero.Walk(srcFs, src, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, file)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return dstFs.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		b, err := afero.ReadFile(srcFs, file)
+		if err != nil {
+			return err
+		}
+		return afero.WriteFile(dstFs, target, b, 0644)
+	})
+}
+
+// sourcePackage records the source of the package in the PackageSourceAnnotation of its OperatorVersion and Instance
+type sourcePackage struct {
+	packages.Package
+	source string
+}
+
+// GetCRDs returns the CRDs of the package annotated with its source
+func (p *sourcePackage) GetCRDs() (*packages.PackageCRDs, error) {
+	crds, err := p.Package.GetCRDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range []*map[string]string{&crds.OperatorVersion.Annotations, &crds.Instance.Annotations} {
+		if *meta == nil {
+			*meta = map[string]string{}
+		}
+		(*meta)[kudo.PackageSourceAnnotation] = p.source
+	}
+	return crds, nil
+}
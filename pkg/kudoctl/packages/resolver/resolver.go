@@ -0,0 +1,234 @@
+package resolver
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/http"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
+
+	"github.com/spf13/afero"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Resolver resolves the name of a package into the package. Depending on the implementation the name is a path, a url
+// or the name of an operator. Local and URL sources ignore the version.
+type Resolver interface {
+	GetPackage(name string, version string) (packages.Package, error)
+}
+
+// Source is a Resolver for one kind of package names, e.g. local directories or urls
+type Source interface {
+	Resolver
+	// Handles returns whether the name refers to a package of this source
+	Handles(name string) bool
+	// String describes the source in logs
+	String() string
+}
+
+// Sources resolve a package with the first source that handles its name
+type Sources []Source
+
+// New creates the resolver of the CLI. It resolves the name to
+// - a tgz read from stdin if the name is "-"
+// - a local tgz file
+// - a local directory
+// - a directory in a Git repository if the name starts with GitPrefix
+// - a url to a tgz
+// - an operator name in the repository
+// in that order. Should there exist a local folder e.g. `cassandra` it will take precedence over the repository
+// package with the same name. New sources, e.g. OCI registries, are added here.
+func New(fs afero.Fs, repository repo.Repository) Sources {
+	return Sources{
+		NewStdin(os.Stdin),
+		NewTarball(fs),
+		NewLocalDir(fs),
+		NewGit(),
+		NewURL(),
+		NewRepository(repository),
+	}
+}
+
+// GetPackage resolves the package with the first source handling the name
+func (s Sources) GetPackage(name string, version string) (p packages.Package, err error) {
+	span := tracing.StartSpan("package.Resolve", attribute.String("kudo.package", name), attribute.String("kudo.version", version))
+	defer func() { tracing.EndSpan(span, err) }()
+
+	for _, source := range s {
+		if source.Handles(name) {
+			clog.V(3).Printf("resolving %v from %s", name, source)
+			span.SetAttributes(attribute.String("kudo.source", source.String()))
+			return source.GetPackage(name, version)
+		}
+		clog.V(4).Printf("%v is no %s", name, source)
+	}
+	return nil, fmt.Errorf("resolver: unable to find package for %v", name)
+}
+
+// GetCRDs resolves the package and returns its CRDs
+func GetCRDs(r Resolver, name string, version string) (*packages.PackageCRDs, error) {
+	p, err := r.GetPackage(name, version)
+	if err != nil {
+		return nil, err
+	}
+	return p.GetCRDs()
+}
+
+// StdinName is the package name that refers to a tarball read from stdin, e.g. in pipes of CI systems
+const StdinName = "-"
+
+// Stdin resolves the package named StdinName from a tarball read from a reader, usually stdin. The reader can only be
+// read once.
+type Stdin struct {
+	in io.Reader
+}
+
+// NewStdin creates a source for tarballs read from in
+func NewStdin(in io.Reader) *Stdin {
+	return &Stdin{in: in}
+}
+
+// Handles returns whether name is StdinName
+func (s *Stdin) Handles(name string) bool {
+	return name == StdinName
+}
+
+// GetPackage provides the package of the tarball read from the reader
+func (s *Stdin) GetPackage(name string, version string) (packages.Package, error) {
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(s.in); err != nil {
+		return nil, fmt.Errorf("resolver: failed to read package from stdin: %v", err)
+	}
+	if buf.Len() == 0 {
+		return nil, fmt.Errorf("resolver: no package was given on stdin")
+	}
+	return packages.NewFromBytes(buf), nil
+}
+
+func (s *Stdin) String() string {
+	return "stdin"
+}
+
+// LocalDir resolves packages from operator folders
+type LocalDir struct {
+	fs afero.Fs
+}
+
+// NewLocalDir creates a source for operator folders
+func NewLocalDir(fs afero.Fs) *LocalDir {
+	return &LocalDir{fs: fs}
+}
+
+// Handles returns whether name is an existing folder
+func (l *LocalDir) Handles(name string) bool {
+	isDir, err := afero.IsDir(l.fs, name)
+	return err == nil && isDir
+}
+
+// GetPackage provides the package of the folder
+func (l *LocalDir) GetPackage(name string, version string) (packages.Package, error) {
+	return packages.ReadPackage(l.fs, name)
+}
+
+func (l *LocalDir) String() string {
+	return "local directory"
+}
+
+// Tarball resolves packages from local tarballs
+type Tarball struct {
+	fs afero.Fs
+}
+
+// NewTarball creates a source for local tarballs
+func NewTarball(fs afero.Fs) *Tarball {
+	return &Tarball{fs: fs}
+}
+
+// Handles returns whether name is an existing file
+func (t *Tarball) Handles(name string) bool {
+	fi, err := t.fs.Stat(name)
+	return err == nil && fi.Mode().IsRegular()
+}
+
+// GetPackage provides the package of the tarball
+func (t *Tarball) GetPackage(name string, version string) (packages.Package, error) {
+	return packages.ReadPackage(t.fs, name)
+}
+
+func (t *Tarball) String() string {
+	return "local tarball"
+}
+
+// URL resolves packages from the url of a tarball
+type URL struct {
+	client http.Client
+}
+
+// NewURL creates a source for urls
+func NewURL() *URL {
+	client := http.NewClient()
+
+	return &URL{
+		client: *client,
+	}
+}
+
+// Handles returns whether name is a url
+func (u *URL) Handles(name string) bool {
+	return http.IsValidURL(name)
+}
+
+// GetPackage provides a package for the url provided
+func (u *URL) GetPackage(name string, version string) (packages.Package, error) {
+	// check to see if name is url
+	if !http.IsValidURL(name) {
+		return nil, fmt.Errorf("resolver: url %v invalid", name)
+	}
+	buf, err := u.getPackageByURL(name)
+	if err != nil {
+		return nil, err
+	}
+	return packages.NewFromBytes(buf), nil
+}
+
+func (u *URL) getPackageByURL(url string) (*bytes.Buffer, error) {
+	resp, err := u.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("resolver: unable to get get reader from url %v", url)
+	}
+
+	return resp, nil
+}
+
+func (u *URL) String() string {
+	return "url"
+}
+
+// Repository resolves packages by operator name and version from a repository
+type Repository struct {
+	repository repo.Repository
+}
+
+// NewRepository creates a source for the repository
+func NewRepository(repository repo.Repository) *Repository {
+	return &Repository{repository: repository}
+}
+
+// Handles returns true if there is a repository, every name is looked up in it. It is the last source to try.
+func (r *Repository) Handles(name string) bool {
+	return r.repository != nil
+}
+
+// GetPackage provides the package of the operator in the given version, or the most recent one
+func (r *Repository) GetPackage(name string, version string) (packages.Package, error) {
+	return r.repository.GetPackage(name, version)
+}
+
+func (r *Repository) String() string {
+	return fmt.Sprintf("repository %v", r.repository)
+}
@@ -0,0 +1,91 @@
+package resolver
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRepository map[string]packages.Package
+
+func (r fakeRepository) GetPackage(name string, version string) (packages.Package, error) {
+	if p, ok := r[name+"-"+version]; ok {
+		return p, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func TestSources_GetPackage(t *testing.T) {
+	fs := afero.NewOsFs()
+	repoPackage, err := packages.ReadPackage(fs, "../testdata/zk")
+	assert.NoError(t, err)
+	r := New(fs, fakeRepository{"kafka-1.0.0": repoPackage})
+
+	tests := []struct {
+		name    string
+		version string
+		err     bool
+	}{
+		{"../testdata/zk", "", false},
+		{"../testdata/zk.tgz", "", false},
+		{"kafka", "1.0.0", false},
+		{"kafka", "2.0.0", true},
+	}
+	for _, tt := range tests {
+		p, err := r.GetPackage(tt.name, tt.version)
+		if tt.err {
+			assert.Error(t, err, tt.name)
+			continue
+		}
+		if !assert.NoError(t, err, tt.name) {
+			continue
+		}
+		crds, err := p.GetCRDs()
+		assert.NoError(t, err, tt.name)
+		assert.EqualValues(t, "zookeeper", crds.Operator.Name, tt.name)
+	}
+}
+
+func TestSources_NoSource(t *testing.T) {
+	r := New(afero.NewMemMapFs(), nil)
+	_, err := r.GetPackage("kafka", "")
+	assert.EqualError(t, err, "resolver: unable to find package for kafka")
+}
+
+func TestLocalDir_GetPackage(t *testing.T) {
+	l := NewLocalDir(afero.NewOsFs())
+	assert.True(t, l.Handles("../testdata/zk"))
+	assert.False(t, l.Handles("../testdata/zk.tgz"))
+	assert.False(t, l.Handles("../testdata/zk-bad"))
+
+	crds, err := GetCRDs(l, "../testdata/zk", "")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "zookeeper", crds.Operator.Name)
+}
+
+func TestLocalDir_Failure(t *testing.T) {
+	l := NewLocalDir(afero.NewOsFs())
+	_, err := l.GetPackage("../testdata/zk-bad", "")
+	assert.Errorf(t, err, "should have errored on bad folder name")
+}
+
+func TestStdin_GetPackage(t *testing.T) {
+	tgz, err := afero.ReadFile(afero.NewOsFs(), "../testdata/zk.tgz")
+	assert.NoError(t, err)
+
+	s := NewStdin(bytes.NewReader(tgz))
+	assert.True(t, s.Handles("-"))
+	assert.False(t, s.Handles("../testdata/zk.tgz"))
+
+	crds, err := GetCRDs(s, "-", "")
+	assert.NoError(t, err)
+	assert.EqualValues(t, "zookeeper", crds.Operator.Name)
+
+	_, err = NewStdin(&bytes.Buffer{}).GetPackage("-", "")
+	assert.EqualError(t, err, "resolver: no package was given on stdin")
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/spf13/afero"
 )
 
-// tarballWriter creates a tarball *.tgz file for the file system tree at the provided path.
+// tarballWriter creates a tarball *.tgz file for the file system tree at the provided path, without the test fixtures.
 func tarballWriter(fs afero.Fs, path string, w io.Writer) (err error) {
 	gw := gzip.NewWriter(w)
 	defer gw.Close()
@@ -26,6 +26,11 @@ func tarballWriter(fs afero.Fs, path string, w io.Writer) (err error) {
 			return err
 		}
 
+		// test fixtures are only used during development and are not packaged
+		if fi.IsDir() && isFixturesFolder(path, file) {
+			return filepath.SkipDir
+		}
+
 		// return on non-regular files.  We don't add directories without files and symlinks
 		if !fi.Mode().IsRegular() {
 			return nil
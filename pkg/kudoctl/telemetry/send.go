@@ -0,0 +1,33 @@
+//go:build !notelemetry
+// +build !notelemetry
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Available is false if the CLI was built with the notelemetry tag
+const Available = true
+
+// send posts the event to the endpoint
+func send(endpoint string, event *Event, timeout time.Duration) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return nil
+}
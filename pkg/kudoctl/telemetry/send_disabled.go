@@ -0,0 +1,14 @@
+//go:build notelemetry
+// +build notelemetry
+
+package telemetry
+
+import "time"
+
+// Available is false if the CLI was built with the notelemetry tag
+const Available = false
+
+// send does nothing, the reporting is compiled out
+func send(endpoint string, event *Event, timeout time.Duration) error {
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package telemetry reports anonymous usage of kudoctl to help the maintainers prioritize features. Reporting is opt-in
+// and never includes arguments, flag values or error messages: only the command, the names of the flags that were set
+// and the category of an error are reported. Building with the notelemetry tag compiles out the reporting.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+	"github.com/kudobuilder/kudo/pkg/version"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// DoNotTrackEnv is the environment variable that disables the reporting regardless of the opt-in, see
+// https://consoledonottrack.com
+const DoNotTrackEnv = "DO_NOT_TRACK"
+
+// DefaultEndpoint is the URL usage is reported to unless the client configuration sets one. It is set with -ldflags
+// when the CLI is built, e.g. -X github.com/kudobuilder/kudo/pkg/kudoctl/telemetry.DefaultEndpoint=https://...
+var DefaultEndpoint = ""
+
+// reportTimeout limits the time a command waits for the report of its usage
+const reportTimeout = 2 * time.Second
+
+// Error categories of the reported commands
+const (
+	CategoryNone          = "none"
+	CategoryNotFound      = "not-found"
+	CategoryAlreadyExists = "already-exists"
+	CategoryUnauthorized  = "unauthorized"
+	CategoryInvalid       = "invalid"
+	CategoryConflict      = "conflict"
+	CategoryTimeout       = "timeout"
+	CategoryConnection    = "connection"
+	CategoryOther         = "other"
+)
+
+// Event is the anonymous usage of one command
+type Event struct {
+	// ID is the random identifier of the installation
+	ID string `json:"id"`
+	// Command is the path of the command without arguments, e.g. "kubectl-kudo install"
+	Command string `json:"command"`
+	// Flags are the sorted names of the flags that were set, their values are never reported
+	Flags []string `json:"flags,omitempty"`
+	// ErrorCategory is the category of the error the command failed with, CategoryNone if it succeeded
+	ErrorCategory string `json:"errorCategory"`
+	// DurationMillis is the runtime of the command
+	DurationMillis int64  `json:"durationMillis"`
+	Version        string `json:"version"`
+	Platform       string `json:"platform"`
+}
+
+// NewEvent returns the event of a command that set the flags and returned err, the ID is set when it is reported
+func NewEvent(command string, flags []string, err error, duration time.Duration) *Event {
+	sorted := append([]string{}, flags...)
+	sort.Strings(sorted)
+	return &Event{
+		Command:        command,
+		Flags:          sorted,
+		ErrorCategory:  Categorize(err),
+		DurationMillis: duration.Milliseconds(),
+		Version:        version.Get().GitVersion,
+		Platform:       runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// Categorize returns the category of the error, the error itself is not reported as it may contain names of objects
+func Categorize(err error) string {
+	if err == nil {
+		return CategoryNone
+	}
+	// errors of the API server are usually wrapped by the commands
+	var statusErr *apierrors.StatusError
+	if errors.As(err, &statusErr) {
+		err = statusErr
+	}
+	var netErr net.Error
+	switch {
+	case apierrors.IsNotFound(err):
+		return CategoryNotFound
+	case apierrors.IsAlreadyExists(err):
+		return CategoryAlreadyExists
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return CategoryUnauthorized
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return CategoryInvalid
+	case apierrors.IsConflict(err):
+		return CategoryConflict
+	case apierrors.IsTimeout(err), apierrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return CategoryTimeout
+		}
+		return CategoryConnection
+	}
+	return CategoryOther
+}
+
+// Status describes whether usage is reported
+type Status struct {
+	Enabled bool
+	// Reason explains why usage is not reported
+	Reason   string
+	Endpoint string
+	ID       string
+}
+
+// GetStatus returns whether usage is reported with the client configuration
+func GetStatus(config *env.Config) Status {
+	s := Status{Endpoint: DefaultEndpoint}
+	if config != nil && config.Telemetry != nil {
+		s.ID = config.Telemetry.ID
+		if config.Telemetry.Endpoint != "" {
+			s.Endpoint = config.Telemetry.Endpoint
+		}
+	}
+	switch {
+	case !Available:
+		s.Reason = "the CLI was built without telemetry"
+	case doNotTrack():
+		s.Reason = DoNotTrackEnv + " is set"
+	case config == nil || config.Telemetry == nil || !config.Telemetry.Enabled:
+		s.Reason = "telemetry was not enabled"
+	case s.ID == "":
+		s.Reason = "the configuration has no anonymous ID, enable telemetry again"
+	case s.Endpoint == "":
+		s.Reason = "no endpoint is configured"
+	default:
+		s.Enabled = true
+	}
+	return s
+}
+
+// Report sends the event if usage is reported with the client configuration. Reporting is best effort, it never fails
+// the command and gives up after a short timeout.
+func Report(config *env.Config, event *Event) {
+	s := GetStatus(config)
+	if !s.Enabled {
+		return
+	}
+	event.ID = s.ID
+	if err := send(s.Endpoint, event, reportTimeout); err != nil {
+		clog.V(4).Printf("failed to report usage to %s: %v", s.Endpoint, err)
+	}
+}
+
+// doNotTrack returns whether DoNotTrackEnv is set to a value other than 0 or false
+func doNotTrack() bool {
+	v := strings.TrimSpace(os.Getenv(DoNotTrackEnv))
+	return v != "" && v != "0" && !strings.EqualFold(v, "false")
+}
@@ -0,0 +1,82 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/env"
+
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCategorize(t *testing.T) {
+	gr := schema.GroupResource{Group: "kudo.dev", Resource: "instances"}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, CategoryNone},
+		{apierrors.NewNotFound(gr, "kafka"), CategoryNotFound},
+		{fmt.Errorf("failed to get instance: %w", apierrors.NewNotFound(gr, "kafka")), CategoryNotFound},
+		{apierrors.NewAlreadyExists(gr, "kafka"), CategoryAlreadyExists},
+		{apierrors.NewForbidden(gr, "kafka", errors.New("denied")), CategoryUnauthorized},
+		{apierrors.NewBadRequest("invalid parameters"), CategoryInvalid},
+		{apierrors.NewConflict(gr, "kafka", errors.New("modified")), CategoryConflict},
+		{context.DeadlineExceeded, CategoryTimeout},
+		{&net.OpError{Op: "dial", Err: errors.New("connection refused")}, CategoryConnection},
+		{errors.New("package kafka has no plan deploy"), CategoryOther},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Categorize(tt.err), "%v", tt.err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	if !Available {
+		t.Skip("the CLI is built without telemetry")
+	}
+	events := make(chan *Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		e := &Event{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(e))
+		events <- e
+	}))
+	defer server.Close()
+	defer os.Unsetenv(DoNotTrackEnv)
+
+	config := &env.Config{Telemetry: &env.TelemetryConfig{Enabled: true, ID: "1234", Endpoint: server.URL}}
+	event := NewEvent("kubectl-kudo install", []string{"version", "namespace"}, apierrors.NewBadRequest("invalid"), time.Second)
+
+	os.Setenv(DoNotTrackEnv, "1")
+	assert.Equal(t, Status{Reason: "DO_NOT_TRACK is set", Endpoint: server.URL, ID: "1234"}, GetStatus(config))
+	Report(config, event)
+	assert.Len(t, events, 0, "usage must not be reported with DO_NOT_TRACK")
+
+	os.Setenv(DoNotTrackEnv, "0")
+	Report(config, event)
+	select {
+	case e := <-events:
+		assert.Equal(t, "1234", e.ID)
+		assert.Equal(t, "kubectl-kudo install", e.Command)
+		assert.Equal(t, []string{"namespace", "version"}, e.Flags)
+		assert.Equal(t, CategoryInvalid, e.ErrorCategory)
+		assert.Equal(t, int64(1000), e.DurationMillis)
+	default:
+		t.Error("usage was not reported")
+	}
+
+	assert.Equal(t, "telemetry was not enabled", GetStatus(&env.Config{}).Reason)
+	config.Telemetry.Enabled = false
+	Report(config, event)
+	assert.Len(t, events, 0, "usage must not be reported without opt-in")
+}
@@ -0,0 +1,127 @@
+package batch
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+)
+
+// Order is the order in which an operation is run on the instances
+type Order string
+
+const (
+	// OrderName runs the operation on the instances in alphabetical order of their namespaces and names
+	OrderName Order = "name"
+	// OrderCreation runs the operation on the oldest instances first
+	OrderCreation Order = "creation"
+)
+
+// Options control how an operation is run on many instances
+type Options struct {
+	// MaxParallel is the number of instances the operation runs on at the same time, at least 1
+	MaxParallel int
+	// Order is the order in which the operation is started on the instances, OrderName if empty
+	Order Order
+	// ContinueOnFailure keeps starting the operation on further instances after it failed on an instance. By default
+	// no further operations are started after the first failure, operations that are already running are completed.
+	ContinueOnFailure bool
+}
+
+// Result is the outcome of the operation on an instance
+type Result struct {
+	Instance *v1alpha1.Instance
+	// Err is the error of the operation, nil if it succeeded or was skipped
+	Err error
+	// Skipped is true if the operation was not started because an operation on an earlier instance failed
+	Skipped bool
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	if o.MaxParallel < 1 {
+		return fmt.Errorf("max parallel has to be at least 1 but is %d", o.MaxParallel)
+	}
+	switch o.Order {
+	case "", OrderName, OrderCreation:
+		return nil
+	default:
+		return fmt.Errorf("unknown order %s, has to be %s or %s", o.Order, OrderName, OrderCreation)
+	}
+}
+
+// Sort sorts the instances in the given order
+func Sort(instances []v1alpha1.Instance, order Order) {
+	sort.SliceStable(instances, func(i, j int) bool {
+		a, b := instances[i], instances[j]
+		if order == OrderCreation && !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+}
+
+// Run sorts the instances and runs the operation on them, with at most MaxParallel operations running at the same
+// time. Operations are started in order, a new operation is started as soon as a running one finished. The results
+// are returned in the order of the instances.
+func Run(instances []v1alpha1.Instance, opts Options, op func(instance *v1alpha1.Instance) error) []Result {
+	Sort(instances, opts.Order)
+	parallel := opts.MaxParallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	results := make([]Result, len(instances))
+	slots := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := false
+
+	for i := range instances {
+		results[i].Instance = &instances[i]
+
+		// wait for a free slot before checking for failures, so that failures of running operations stop the batch
+		slots <- struct{}{}
+		mu.Lock()
+		stop := failed && !opts.ContinueOnFailure
+		mu.Unlock()
+		if stop {
+			<-slots
+			results[i].Skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *Result) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := op(r.Instance); err != nil {
+				r.Err = err
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// Summary counts the succeeded, failed and skipped operations
+func Summary(results []Result) (succeeded, failed, skipped int) {
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Err != nil:
+			failed++
+		default:
+			succeeded++
+		}
+	}
+	return succeeded, failed, skipped
+}
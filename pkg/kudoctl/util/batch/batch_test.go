@@ -0,0 +1,114 @@
+package batch
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func instances(names ...string) []v1alpha1.Instance {
+	created := time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC)
+	result := make([]v1alpha1.Instance, 0, len(names))
+	for i, n := range names {
+		result = append(result, v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{
+			Name:              n,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(created.Add(time.Duration(i) * time.Minute)),
+		}})
+	}
+	return result
+}
+
+func names(results []Result) []string {
+	n := make([]string, 0, len(results))
+	for _, r := range results {
+		n = append(n, r.Instance.Name)
+	}
+	return n
+}
+
+func TestSort(t *testing.T) {
+	byName := instances("c", "a", "b")
+	Sort(byName, OrderName)
+	byCreation := instances("c", "a", "b")
+	Sort(byCreation, OrderCreation)
+
+	got := []string{byName[0].Name, byName[1].Name, byName[2].Name, byCreation[0].Name, byCreation[1].Name, byCreation[2].Name}
+	if want := []string{"a", "b", "c", "c", "a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected order %v but got %v", want, got)
+	}
+}
+
+func TestRun_MaxParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	op := func(*v1alpha1.Instance) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	results := Run(instances("e", "d", "c", "b", "a"), Options{MaxParallel: 2}, op)
+	if maxRunning != 2 {
+		t.Errorf("expected 2 operations to run at the same time but got %d", maxRunning)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(names(results), want) {
+		t.Errorf("expected results in order %v but got %v", want, names(results))
+	}
+	if succeeded, failed, skipped := Summary(results); succeeded != 5 || failed != 0 || skipped != 0 {
+		t.Errorf("expected all operations to succeed but got %d succeeded, %d failed, %d skipped", succeeded, failed, skipped)
+	}
+}
+
+func TestRun_StopOnFailure(t *testing.T) {
+	op := func(i *v1alpha1.Instance) error {
+		if i.Name == "b" {
+			return errors.New("plan failed")
+		}
+		return nil
+	}
+
+	results := Run(instances("a", "b", "c", "d"), Options{MaxParallel: 1}, op)
+	if succeeded, failed, skipped := Summary(results); succeeded != 1 || failed != 1 || skipped != 2 {
+		t.Errorf("expected the batch to stop after the failure but got %d succeeded, %d failed, %d skipped", succeeded, failed, skipped)
+	}
+	if !results[2].Skipped || !results[3].Skipped {
+		t.Errorf("expected the instances after the failure to be skipped but got %+v", results)
+	}
+
+	results = Run(instances("a", "b", "c", "d"), Options{MaxParallel: 1, ContinueOnFailure: true}, op)
+	if succeeded, failed, skipped := Summary(results); succeeded != 3 || failed != 1 || skipped != 0 {
+		t.Errorf("expected the batch to continue after the failure but got %d succeeded, %d failed, %d skipped", succeeded, failed, skipped)
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	tests := []struct {
+		opts    Options
+		wantErr bool
+	}{
+		{Options{MaxParallel: 1}, false},
+		{Options{MaxParallel: 3, Order: OrderCreation}, false},
+		{Options{MaxParallel: 0}, true},
+		{Options{MaxParallel: 1, Order: "random"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%+v: expected error %v but got %v", tt.opts, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package kudo
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// Backup contains the KUDO objects of all namespaces of a cluster
+type Backup struct {
+	Operators        []v1alpha1.Operator
+	OperatorVersions []v1alpha1.OperatorVersion
+	Instances        []v1alpha1.Instance
+}
+
+// BackupFileName returns the name of a backup file taken at the given time, e.g. kudo-backup-20191201-100000.yaml
+func BackupFileName(now time.Time) string {
+	return fmt.Sprintf("kudo-backup-%s.yaml", now.UTC().Format("20060102-150405"))
+}
+
+// Backup exports the operators, operatorversions and instances of all namespaces. The metadata maintained by the API
+// server is removed, so that the objects can be created again with Restore or kubectl.
+func (c *Client) Backup() (*Backup, error) {
+	b := &Backup{}
+
+	operators, err := c.listOperators(v1.NamespaceAll)
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing operators")
+	}
+	for _, o := range operators {
+		o.TypeMeta = v1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "Operator"}
+		cleanBackupMetadata(&o.ObjectMeta)
+		b.Operators = append(b.Operators, o)
+	}
+
+	ovs, err := c.listOperatorVersions(v1.NamespaceAll)
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing operatorversions")
+	}
+	for _, ov := range ovs {
+		ov.TypeMeta = v1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "OperatorVersion"}
+		cleanBackupMetadata(&ov.ObjectMeta)
+		b.OperatorVersions = append(b.OperatorVersions, ov)
+	}
+
+	instances, err := c.listInstances(v1.NamespaceAll, v1.ListOptions{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing instances")
+	}
+	for _, i := range instances {
+		i.TypeMeta = v1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: "Instance"}
+		cleanBackupMetadata(&i.ObjectMeta)
+		b.Instances = append(b.Instances, i)
+	}
+	return b, nil
+}
+
+// cleanBackupMetadata removes the metadata that is set by the API server. Owner references are kept, their UIDs are
+// remapped to the restored owners on restore.
+func cleanBackupMetadata(m *v1.ObjectMeta) {
+	m.ResourceVersion = ""
+	m.UID = ""
+	m.SelfLink = ""
+	m.Generation = 0
+	m.CreationTimestamp = v1.Time{}
+	m.ManagedFields = nil
+}
+
+// ownerKey identifies a restored object that can be referenced as owner by other objects in its namespace
+func ownerKey(kind, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", kind, namespace, name)
+}
+
+// remapOwnerReferences replaces the UIDs of the owner references, which belong to the objects of the backed up
+// cluster, with the UIDs of the restored owners. References to owners that were not restored are removed, the garbage
+// collector would delete the restored object otherwise.
+func remapOwnerReferences(m *v1.ObjectMeta, uids map[string]types.UID) {
+	if len(m.OwnerReferences) == 0 {
+		return
+	}
+	refs := make([]v1.OwnerReference, 0, len(m.OwnerReferences))
+	for _, ref := range m.OwnerReferences {
+		uid := uids[ownerKey(ref.Kind, m.Namespace, ref.Name)]
+		if uid == "" {
+			clog.V(2).Printf("removing owner reference of %s/%s to %s %s which was not restored", m.Namespace, m.Name, ref.Kind, ref.Name)
+			continue
+		}
+		ref.UID = uid
+		refs = append(refs, ref)
+	}
+	m.OwnerReferences = refs
+}
+
+// ownersFirst orders the instances so that instances owned by other instances of the backup are restored after their
+// owners and reference their new UIDs
+func ownersFirst(instances []v1alpha1.Instance) []v1alpha1.Instance {
+	pending := map[string]bool{}
+	for _, i := range instances {
+		pending[ownerKey("Instance", i.Namespace, i.Name)] = true
+	}
+	ordered := make([]v1alpha1.Instance, 0, len(instances))
+	for len(ordered) < len(instances) {
+		progress := false
+		for _, i := range instances {
+			key := ownerKey("Instance", i.Namespace, i.Name)
+			if !pending[key] || hasPendingOwner(i, pending) {
+				continue
+			}
+			ordered = append(ordered, i)
+			delete(pending, key)
+			progress = true
+		}
+		if !progress {
+			// owner references form a cycle, the remaining instances are restored in the order of the backup
+			for _, i := range instances {
+				if pending[ownerKey("Instance", i.Namespace, i.Name)] {
+					ordered = append(ordered, i)
+				}
+			}
+		}
+	}
+	return ordered
+}
+
+func hasPendingOwner(i v1alpha1.Instance, pending map[string]bool) bool {
+	for _, ref := range i.OwnerReferences {
+		if pending[ownerKey(ref.Kind, i.Namespace, ref.Name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// Restore creates the objects of the backup in the order operators, operatorversions and instances. Objects that
+// already exist are updated with the backed up spec. The status of created instances is restored too, so that their
+// plans are not executed again, existing instances keep their status. Owner references are remapped to the UIDs of
+// the restored owners.
+func (c *Client) Restore(b *Backup) error {
+	uids := map[string]types.UID{}
+
+	for _, o := range b.Operators {
+		o := o
+		remapOwnerReferences(&o.ObjectMeta, uids)
+		restored, err := c.clientset.KudoV1alpha1().Operators(o.Namespace).Create(&o)
+		if apierrors.IsAlreadyExists(err) {
+			var existing *v1alpha1.Operator
+			existing, err = c.clientset.KudoV1alpha1().Operators(o.Namespace).Get(o.Name, v1.GetOptions{})
+			if err == nil {
+				o.ResourceVersion = existing.ResourceVersion
+				restored, err = c.clientset.KudoV1alpha1().Operators(o.Namespace).Update(&o)
+			}
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "restoring operator %s/%s", o.Namespace, o.Name)
+		}
+		uids[ownerKey("Operator", o.Namespace, o.Name)] = restored.UID
+		clog.V(2).Printf("operator %s/%s restored", o.Namespace, o.Name)
+	}
+
+	for _, ov := range b.OperatorVersions {
+		ov := ov
+		remapOwnerReferences(&ov.ObjectMeta, uids)
+		restored, err := c.clientset.KudoV1alpha1().OperatorVersions(ov.Namespace).Create(&ov)
+		if apierrors.IsAlreadyExists(err) {
+			var existing *v1alpha1.OperatorVersion
+			existing, err = c.clientset.KudoV1alpha1().OperatorVersions(ov.Namespace).Get(ov.Name, v1.GetOptions{})
+			if err == nil {
+				ov.ResourceVersion = existing.ResourceVersion
+				restored, err = c.clientset.KudoV1alpha1().OperatorVersions(ov.Namespace).Update(&ov)
+			}
+		}
+		if err != nil {
+			return errors.WithMessagef(err, "restoring operatorversion %s/%s", ov.Namespace, ov.Name)
+		}
+		uids[ownerKey("OperatorVersion", ov.Namespace, ov.Name)] = restored.UID
+		clog.V(2).Printf("operatorversion %s/%s restored", ov.Namespace, ov.Name)
+	}
+
+	for _, i := range ownersFirst(b.Instances) {
+		i := i
+		remapOwnerReferences(&i.ObjectMeta, uids)
+		created, err := c.clientset.KudoV1alpha1().Instances(i.Namespace).Create(&i)
+		switch {
+		case apierrors.IsAlreadyExists(err):
+			existing, err := c.clientset.KudoV1alpha1().Instances(i.Namespace).Get(i.Name, v1.GetOptions{})
+			if err != nil {
+				return errors.WithMessagef(err, "restoring instance %s/%s", i.Namespace, i.Name)
+			}
+			i.ResourceVersion = existing.ResourceVersion
+			i.Status = existing.Status
+			if _, err := c.clientset.KudoV1alpha1().Instances(i.Namespace).Update(&i); err != nil {
+				return errors.WithMessagef(err, "restoring instance %s/%s", i.Namespace, i.Name)
+			}
+			uids[ownerKey("Instance", i.Namespace, i.Name)] = existing.UID
+		case err != nil:
+			return errors.WithMessagef(err, "restoring instance %s/%s", i.Namespace, i.Name)
+		default:
+			// the status subresource ignores the status on creation
+			created.Status = i.Status
+			if _, err := c.clientset.KudoV1alpha1().Instances(i.Namespace).UpdateStatus(created); err != nil {
+				return errors.WithMessagef(err, "restoring status of instance %s/%s", i.Namespace, i.Name)
+			}
+			uids[ownerKey("Instance", i.Namespace, i.Name)] = created.UID
+		}
+		clog.V(2).Printf("instance %s/%s restored", i.Namespace, i.Name)
+	}
+	return nil
+}
+
+// Write writes the objects of the backup as a multi-document YAML stream that can also be applied with kubectl
+func (b *Backup) Write(w io.Writer) error {
+	var objs []interface{}
+	for i := range b.Operators {
+		objs = append(objs, &b.Operators[i])
+	}
+	for i := range b.OperatorVersions {
+		objs = append(objs, &b.OperatorVersions[i])
+	}
+	for i := range b.Instances {
+		objs = append(objs, &b.Instances[i])
+	}
+
+	for _, obj := range objs {
+		data, err := yaml.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadBackup reads a backup written by Backup.Write
+func ReadBackup(r io.Reader) (*Backup, error) {
+	b := &Backup{}
+	reader := yamlutil.NewYAMLReader(bufio.NewReader(r))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return b, nil
+		}
+		if err != nil {
+			return nil, errors.WithMessage(err, "reading backup")
+		}
+
+		var typeMeta v1.TypeMeta
+		if err := yaml.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, errors.WithMessage(err, "reading backup")
+		}
+		switch typeMeta.Kind {
+		case "":
+			// empty document
+			continue
+		case "Operator":
+			o := v1alpha1.Operator{}
+			err = yaml.Unmarshal(doc, &o)
+			b.Operators = append(b.Operators, o)
+		case "OperatorVersion":
+			ov := v1alpha1.OperatorVersion{}
+			err = yaml.Unmarshal(doc, &ov)
+			b.OperatorVersions = append(b.OperatorVersions, ov)
+		case "Instance":
+			i := v1alpha1.Instance{}
+			err = yaml.Unmarshal(doc, &i)
+			b.Instances = append(b.Instances, i)
+		default:
+			return nil, fmt.Errorf("unexpected object of kind %s in backup", typeMeta.Kind)
+		}
+		if err != nil {
+			return nil, errors.WithMessagef(err, "reading %s from backup", typeMeta.Kind)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package kudo
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestBackupFileName(t *testing.T) {
+	name := BackupFileName(time.Date(2019, 12, 1, 10, 0, 0, 0, time.UTC))
+	if name != "kudo-backup-20191201-100000.yaml" {
+		t.Errorf("unexpected backup file name %s", name)
+	}
+}
+
+func TestClient_BackupRestore(t *testing.T) {
+	source := NewClientFromK8s(fake.NewSimpleClientset(
+		&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kafka", ResourceVersion: "10", UID: "1"}},
+		&v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kafka-1.0", ResourceVersion: "11"},
+			Spec:       v1alpha1.OperatorVersionSpec{Operator: v1.ObjectReference{Name: "kafka"}, Version: "1.0"},
+		},
+		&v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "kafka", ResourceVersion: "12"},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: "kafka-1.0", Namespace: "default"}},
+			Status:     v1alpha1.InstanceStatus{AggregatedStatus: v1alpha1.AggregatedStatus{Status: v1alpha1.ExecutionComplete}},
+		},
+	))
+
+	b, err := source.Backup()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if len(b.Operators) != 1 || len(b.OperatorVersions) != 1 || len(b.Instances) != 1 {
+		t.Fatalf("expected one object of each kind to be backed up but got %+v", b)
+	}
+	if b.Operators[0].ResourceVersion != "" || b.Operators[0].UID != "" {
+		t.Errorf("expected server metadata to be removed but got %+v", b.Operators[0].ObjectMeta)
+	}
+
+	var buf bytes.Buffer
+	if err := b.Write(&buf); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	read, err := ReadBackup(&buf)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// the operator already exists in the target cluster and is updated
+	target := NewClientFromK8s(fake.NewSimpleClientset(
+		&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kafka", ResourceVersion: "3"}},
+	))
+	if err := target.Restore(read); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	ov, err := target.GetOperatorVersion("kafka-1.0", "default")
+	if err != nil || ov == nil || ov.Spec.Version != "1.0" {
+		t.Errorf("expected operatorversion to be restored but got %v, %v", ov, err)
+	}
+	instance, err := target.GetInstance("kafka", "team")
+	if err != nil || instance == nil {
+		t.Fatalf("expected instance to be restored but got %v, %v", instance, err)
+	}
+	if instance.Spec.OperatorVersion.Namespace != "default" || instance.Status.AggregatedStatus.Status != v1alpha1.ExecutionComplete {
+		t.Errorf("expected instance spec and status to be restored but got %+v", instance)
+	}
+}
+
+func TestClient_RestoreOwnerReferences(t *testing.T) {
+	owner := func(kind, name string) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: "kudo.dev/v1alpha1", Kind: kind, Name: name, UID: "backed-up"}}
+	}
+	b := &Backup{Instances: []v1alpha1.Instance{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "zookeeper", OwnerReferences: owner("Instance", "kafka")}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "kafka"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "orphan", OwnerReferences: owner("Instance", "missing")}},
+	}}
+
+	// the API server assigns UIDs on creation
+	clientset := fake.NewSimpleClientset()
+	created := 0
+	clientset.PrependReactor("create", "instances", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		created++
+		obj := action.(clienttesting.CreateAction).GetObject().(*v1alpha1.Instance)
+		obj.UID = types.UID(fmt.Sprintf("uid-%d", created))
+		return false, nil, nil
+	})
+	target := NewClientFromK8s(clientset)
+	if err := target.Restore(b); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	kafka, err := target.GetInstance("kafka", "default")
+	if err != nil || kafka == nil {
+		t.Fatalf("expected instance to be restored but got %v, %v", kafka, err)
+	}
+	zookeeper, err := target.GetInstance("zookeeper", "default")
+	if err != nil || zookeeper == nil {
+		t.Fatalf("expected instance to be restored but got %v, %v", zookeeper, err)
+	}
+	if len(zookeeper.OwnerReferences) != 1 || zookeeper.OwnerReferences[0].UID != kafka.UID {
+		t.Errorf("expected the owner reference to be remapped to %s but got %+v", kafka.UID, zookeeper.OwnerReferences)
+	}
+	orphan, err := target.GetInstance("orphan", "default")
+	if err != nil || orphan == nil {
+		t.Fatalf("expected instance to be restored but got %v, %v", orphan, err)
+	}
+	if len(orphan.OwnerReferences) != 0 {
+		t.Errorf("expected the owner reference to a missing owner to be removed but got %+v", orphan.OwnerReferences)
+	}
+}
+
+func TestReadBackup_UnknownKind(t *testing.T) {
+	_, err := ReadBackup(bytes.NewBufferString("---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: foo\n"))
+	if err == nil || err.Error() != "unexpected object of kind ConfigMap in backup" {
+		t.Errorf("expected error for unknown kind but got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package kudo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Results of the operations of a Batch, named like the results of kubectl apply
+const (
+	ObjectCreated = "created"
+	ObjectUpdated = "configured"
+	ObjectDeleted = "deleted"
+)
+
+// Batch is a set of creates, updates and deletes of KUDO objects that are executed as one logical operation with
+// Client.Execute, e.g. the Operator, OperatorVersion and Instance of an installation. The API server has no
+// transactions, the operations are sent one after another in the order they were added and created objects are
+// deleted again if a later operation fails. The zero value is an empty batch.
+type Batch struct {
+	ops []batchOperation
+}
+
+type batchOperation struct {
+	kind      string
+	name      string
+	namespace string
+	// execute executes the operation and returns its result and a function undoing it, which is nil if the
+	// operation can not be undone
+	execute func(c *Client) (result string, undo func() error, err error)
+}
+
+// BatchResult is the result of an operation of a Batch
+type BatchResult struct {
+	Kind      string
+	Name      string
+	Namespace string
+	// Result is one of ObjectCreated, ObjectUpdated, ObjectDeleted or OperatorUnchanged
+	Result string
+}
+
+func (r BatchResult) String() string {
+	return fmt.Sprintf("%s %s/%s", strings.ToLower(r.Kind), r.Namespace, r.Name)
+}
+
+// BatchError is returned by Client.Execute if an operation of a batch failed. The objects created by the operations
+// before it are deleted again, changes of updates and deletes are kept.
+type BatchError struct {
+	// Failed is the operation that failed
+	Failed BatchResult
+	Err    error
+	// Completed are the operations executed before the failed one that were not rolled back
+	Completed []BatchResult
+	// RolledBack are the objects that were created by the batch and deleted again
+	RolledBack []BatchResult
+	// RollbackErrors are the errors of objects that were created by the batch but could not be deleted again
+	RollbackErrors []error
+}
+
+func (e *BatchError) Error() string {
+	msg := fmt.Sprintf("%s failed: %v", e.Failed, e.Err)
+	if len(e.RolledBack) > 0 {
+		rolledBack := make([]string, 0, len(e.RolledBack))
+		for _, r := range e.RolledBack {
+			rolledBack = append(rolledBack, r.String())
+		}
+		msg += fmt.Sprintf(", rolled back %s", strings.Join(rolledBack, ", "))
+	}
+	if len(e.RollbackErrors) > 0 {
+		errs := make([]string, 0, len(e.RollbackErrors))
+		for _, err := range e.RollbackErrors {
+			errs = append(errs, err.Error())
+		}
+		msg += fmt.Sprintf(", failed to roll back: %s", strings.Join(errs, ", "))
+	}
+	return msg
+}
+
+// ApplyOperator adds an operation that creates the Operator or patches the spec of the existing one, see
+// Client.ApplyOperator. Only a created Operator is rolled back.
+func (b *Batch) ApplyOperator(obj *v1alpha1.Operator, namespace string) {
+	b.add("Operator", obj.Name, namespace, func(c *Client) (string, func() error, error) {
+		_, result, err := c.ApplyOperator(obj, namespace)
+		if err != nil || result != OperatorCreated {
+			return result, nil, err
+		}
+		return ObjectCreated, func() error { return c.DeleteOperator(obj.Name, namespace) }, nil
+	})
+}
+
+// CreateOperatorVersion adds an operation that creates the OperatorVersion
+func (b *Batch) CreateOperatorVersion(obj *v1alpha1.OperatorVersion, namespace string) {
+	b.add("OperatorVersion", obj.Name, namespace, func(c *Client) (string, func() error, error) {
+		if _, err := c.InstallOperatorVersionObjToCluster(obj, namespace); err != nil {
+			return "", nil, err
+		}
+		return ObjectCreated, func() error { return c.DeleteOperatorVersion(obj.Name, namespace) }, nil
+	})
+}
+
+// CreateInstance adds an operation that creates the Instance, see Client.InstallInstanceObjToCluster
+func (b *Batch) CreateInstance(obj *v1alpha1.Instance, namespace string) {
+	b.add("Instance", obj.Name, namespace, func(c *Client) (string, func() error, error) {
+		if _, err := c.InstallInstanceObjToCluster(obj, namespace); err != nil {
+			return "", nil, err
+		}
+		return ObjectCreated, func() error { return c.DeleteInstance(obj.Name, namespace) }, nil
+	})
+}
+
+// UpdateOperatorVersion adds an operation that replaces the existing OperatorVersion with obj
+func (b *Batch) UpdateOperatorVersion(obj *v1alpha1.OperatorVersion, namespace string) {
+	b.add("OperatorVersion", obj.Name, namespace, func(c *Client) (string, func() error, error) {
+		if _, err := c.clientset.KudoV1alpha1().OperatorVersions(namespace).Update(obj); err != nil {
+			return "", nil, err
+		}
+		return ObjectUpdated, nil, nil
+	})
+}
+
+// DeleteInstance adds an operation that deletes the Instance, see Client.DeleteInstance
+func (b *Batch) DeleteInstance(name, namespace string) {
+	b.add("Instance", name, namespace, func(c *Client) (string, func() error, error) {
+		if err := c.DeleteInstance(name, namespace); err != nil {
+			return "", nil, err
+		}
+		return ObjectDeleted, nil, nil
+	})
+}
+
+// DeleteOperatorVersion adds an operation that deletes the OperatorVersion
+func (b *Batch) DeleteOperatorVersion(name, namespace string) {
+	b.add("OperatorVersion", name, namespace, func(c *Client) (string, func() error, error) {
+		if err := c.DeleteOperatorVersion(name, namespace); err != nil {
+			return "", nil, err
+		}
+		return ObjectDeleted, nil, nil
+	})
+}
+
+// DeleteOperator adds an operation that deletes the Operator
+func (b *Batch) DeleteOperator(name, namespace string) {
+	b.add("Operator", name, namespace, func(c *Client) (string, func() error, error) {
+		if err := c.DeleteOperator(name, namespace); err != nil {
+			return "", nil, err
+		}
+		return ObjectDeleted, nil, nil
+	})
+}
+
+// Len returns the number of operations of the batch
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+func (b *Batch) add(kind, name, namespace string, execute func(c *Client) (string, func() error, error)) {
+	b.ops = append(b.ops, batchOperation{kind: kind, name: name, namespace: namespace, execute: execute})
+}
+
+// Execute executes the operations of the batch in order and returns their results. The first failing operation
+// stops the batch: the objects created by the operations before it are deleted in reverse order, so that e.g. a failed
+// installation does not leave an Operator and OperatorVersion without an Instance behind. The failure is returned as
+// *BatchError.
+func (c *Client) Execute(b *Batch) ([]BatchResult, error) {
+	type executed struct {
+		result BatchResult
+		undo   func() error
+	}
+	done := make([]executed, 0, len(b.ops))
+	for _, op := range b.ops {
+		r := BatchResult{Kind: op.kind, Name: op.name, Namespace: op.namespace}
+		result, undo, err := op.execute(c)
+		if err == nil {
+			r.Result = result
+			done = append(done, executed{result: r, undo: undo})
+			continue
+		}
+
+		batchErr := &BatchError{Failed: r, Err: err}
+		rolledBack := make([]bool, len(done))
+		for i := len(done) - 1; i >= 0; i-- {
+			if done[i].undo == nil {
+				continue
+			}
+			clog.V(2).Printf("rolling back %s", done[i].result)
+			if err := done[i].undo(); err != nil && !apierrors.IsNotFound(err) {
+				batchErr.RollbackErrors = append(batchErr.RollbackErrors, fmt.Errorf("%s: %v", done[i].result, err))
+				continue
+			}
+			rolledBack[i] = true
+			batchErr.RolledBack = append(batchErr.RolledBack, done[i].result)
+		}
+		for i, d := range done {
+			if !rolledBack[i] {
+				batchErr.Completed = append(batchErr.Completed, d.result)
+			}
+		}
+		return nil, batchErr
+	}
+
+	results := make([]BatchResult, 0, len(done))
+	for _, d := range done {
+		results = append(results, d.result)
+	}
+	return results, nil
+}
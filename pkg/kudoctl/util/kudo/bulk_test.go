@@ -0,0 +1,68 @@
+package kudo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	testcore "k8s.io/client-go/testing"
+)
+
+func TestClient_Execute(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}})
+	kc := NewClientFromK8s(client)
+
+	batch := &Batch{}
+	batch.ApplyOperator(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}, "default")
+	batch.ApplyOperator(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "test"}}, "default")
+	batch.CreateOperatorVersion(&v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"}}, "default")
+	batch.CreateInstance(&v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test"}}, "default")
+	assert.Equal(t, 4, batch.Len())
+
+	results, err := kc.Execute(batch)
+	assert.NoError(t, err)
+	assert.Equal(t, []BatchResult{
+		{Kind: "Operator", Name: "existing", Namespace: "default", Result: OperatorUnchanged},
+		{Kind: "Operator", Name: "test", Namespace: "default", Result: ObjectCreated},
+		{Kind: "OperatorVersion", Name: "test-1.0", Namespace: "default", Result: ObjectCreated},
+		{Kind: "Instance", Name: "test", Namespace: "default", Result: ObjectCreated},
+	}, results)
+
+	instance, err := kc.GetInstance("test", "default")
+	assert.NoError(t, err)
+	assert.NotNil(t, instance)
+}
+
+func TestClient_Execute_Rollback(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}})
+	client.PrependReactor("create", "instances", func(action testcore.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("denied by webhook")
+	})
+	kc := NewClientFromK8s(client)
+
+	batch := &Batch{}
+	batch.ApplyOperator(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "existing"}}, "default")
+	batch.ApplyOperator(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "test"}}, "default")
+	batch.CreateOperatorVersion(&v1alpha1.OperatorVersion{ObjectMeta: metav1.ObjectMeta{Name: "test-1.0"}}, "default")
+	batch.CreateInstance(&v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test"}}, "default")
+
+	results, err := kc.Execute(batch)
+	assert.Nil(t, results)
+	assert.EqualError(t, err, "instance default/test failed: installing Instance: denied by webhook, rolled back operatorversion default/test-1.0, operator default/test")
+
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, []BatchResult{{Kind: "Operator", Name: "existing", Namespace: "default", Result: OperatorUnchanged}}, batchErr.Completed)
+	assert.Empty(t, batchErr.RollbackErrors)
+
+	assert.True(t, kc.OperatorExistsInCluster("existing", "default"))
+	assert.False(t, kc.OperatorExistsInCluster("test", "default"))
+	ov, err := kc.GetOperatorVersion("test-1.0", "default")
+	assert.NoError(t, err)
+	assert.Nil(t, ov)
+}
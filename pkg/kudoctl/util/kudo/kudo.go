@@ -3,13 +3,16 @@ package kudo
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
 	"github.com/kudobuilder/kudo/pkg/util/kudo"
+	"github.com/kudobuilder/kudo/pkg/util/tracing"
 	"github.com/kudobuilder/kudo/pkg/version"
 
 	"github.com/pkg/errors"
@@ -17,29 +20,37 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/transport"
 
 	// Import Kubernetes authentication providers to support GKE, etc.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
 // Client is a KUDO Client providing access to a clientset
 type Client struct {
 	clientset versioned.Interface
+	// user is recorded in the history of the instances changed by this client
+	user string
 }
 
-// NewClient creates new KUDO Client
-func NewClient(namespace, kubeConfigPath string) (*Client, error) {
+// NewClient creates new KUDO Client for the context in the kubeconfig. It does not verify that KUDO is installed in
+// the cluster, see the verify package for that.
+func NewClient(kubeConfigPath, kubeContext string) (*Client, error) {
 
-	// use the current context in kubeconfig
-	config, err := clientcmd.BuildConfigFromFlags("", kubeConfigPath)
+	config, err := kube.GetRestConfig(kubeConfigPath, kubeContext)
 	if err != nil {
 		return nil, err
 	}
 
-	// set default configs
-	config.Timeout = time.Second * 3
+	// set default configs, unless the timeout was configured
+	if config.Timeout == 0 {
+		config.Timeout = time.Second * 3
+	}
+	// every request to the API server is traced if tracing is enabled
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, tracing.WrapTransport)
 
 	// create the clientset
 	kudoClientset, err := versioned.NewForConfig(config)
@@ -47,24 +58,24 @@ func NewClient(namespace, kubeConfigPath string) (*Client, error) {
 		return nil, err
 	}
 
-	_, err = kudoClientset.KudoV1alpha1().Operators(namespace).List(v1.ListOptions{})
-	if err != nil {
-		return nil, errors.WithMessage(err, "operators")
-	}
-	_, err = kudoClientset.KudoV1alpha1().OperatorVersions(namespace).List(v1.ListOptions{})
-	if err != nil {
-		return nil, errors.WithMessage(err, "operatorversions")
-	}
-	_, err = kudoClientset.KudoV1alpha1().Instances(namespace).List(v1.ListOptions{})
-	if err != nil {
-		return nil, errors.WithMessage(err, "instances")
-	}
-
 	return &Client{
 		clientset: kudoClientset,
+		user:      kube.User(kubeConfigPath, kubeContext),
 	}, nil
 }
 
+// newRevision returns a revision of an instance changed by this client
+func (c *Client) newRevision(action string, operatorVersion string, changes []v1alpha1.ParameterChange) v1alpha1.InstanceRevision {
+	return v1alpha1.InstanceRevision{
+		Time:            v1.Now(),
+		User:            c.user,
+		ClientVersion:   version.Get().GitVersion,
+		Action:          action,
+		OperatorVersion: operatorVersion,
+		Parameters:      changes,
+	}
+}
+
 // NewClientFromK8s creates KUDO client from kubernetes client interface
 func NewClientFromK8s(client versioned.Interface) *Client {
 	result := Client{}
@@ -99,17 +110,17 @@ func (c *Client) OperatorExistsInCluster(name, namespace string) bool {
 //      		kudo.dev/operator: kafka
 // This function also just returns true if the Instance matches a specific OperatorVersion of an Operator
 func (c *Client) InstanceExistsInCluster(operatorName, namespace, version, instanceName string) (bool, error) {
-	instances, err := c.clientset.KudoV1alpha1().Instances(namespace).List(v1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", kudo.OperatorLabel, operatorName)})
+	instances, err := c.listInstances(namespace, v1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", kudo.OperatorLabel, operatorName)})
 	if err != nil {
 		return false, err
 	}
-	if len(instances.Items) < 1 {
+	if len(instances) < 1 {
 		return false, nil
 	}
 
 	// TODO: check function that actual checks for the OperatorVersion named e.g. "test-1.0" to exist
 	var i int
-	for _, v := range instances.Items {
+	for _, v := range instances {
 		if v.Spec.OperatorVersion.Name == operatorName+"-"+version && v.ObjectMeta.Name == instanceName {
 			i++
 		}
@@ -142,52 +153,334 @@ func (c *Client) GetOperatorVersion(name, namespace string) (*v1alpha1.OperatorV
 	return ov, err
 }
 
-// UpdateInstance updates operatorversion on instance
-func (c *Client) UpdateInstance(instanceName, namespace string, operatorVersionName *string, parameters map[string]string) error {
-	instanceSpec := v1alpha1.InstanceSpec{}
+// GetKudoConfig returns the spec of the KudoConfig the manager reads, the defaults are returned if it does not exist.
+// The typed clientset does not include KudoConfigs, so it is read with the REST client of the API group.
+func (c *Client) GetKudoConfig() (*v1alpha1.KudoConfigSpec, error) {
+	config := &v1alpha1.KudoConfig{}
+	err := c.clientset.KudoV1alpha1().RESTClient().Get().Resource("kudoconfigs").Name(v1alpha1.KudoConfigName).Do().Into(config)
+	if apierrors.IsNotFound(err) {
+		return &v1alpha1.KudoConfigSpec{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &config.Spec, nil
+}
+
+// UpdateInstance updates operatorversion on instance. The parameters are set and the removed parameters are unset, so
+// that they fall back to the defaults of the operatorversion. The change is recorded in the history of the instance.
+// If a plan is given, it is executed for the changed parameters instead of the plan they trigger.
+func (c *Client) UpdateInstance(instanceName, namespace string, operatorVersionName *string, parameters map[string]string, removedParameters []string, plan string) error {
+	instance, err := c.clientset.KudoV1alpha1().Instances(namespace).Get(instanceName, v1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	instanceSpec := struct {
+		OperatorVersion *v1core.ObjectReference `json:"operatorVersion,omitempty"`
+		Parameters      map[string]*string      `json:"parameters,omitempty"`
+	}{}
+	action := "update"
 	if operatorVersionName != nil {
-		instanceSpec.OperatorVersion = v1core.ObjectReference{
+		action = "upgrade"
+		instanceSpec.OperatorVersion = &v1core.ObjectReference{
 			Name: kudo.StringValue(operatorVersionName),
 		}
 	}
-	if parameters != nil {
-		instanceSpec.Parameters = parameters
+	newParameters := make(map[string]string, len(instance.Spec.Parameters))
+	for k, v := range instance.Spec.Parameters {
+		newParameters[k] = v
+	}
+	if len(parameters) > 0 || len(removedParameters) > 0 {
+		instanceSpec.Parameters = make(map[string]*string, len(parameters)+len(removedParameters))
+	}
+	for k, v := range parameters {
+		instanceSpec.Parameters[k] = kudo.String(v)
+		newParameters[k] = v
+	}
+	// removed parameters are set to null, parameters that are not set are ignored
+	for _, k := range removedParameters {
+		if _, ok := instance.Spec.Parameters[k]; ok {
+			instanceSpec.Parameters[k] = nil
+			delete(newParameters, k)
+		}
+	}
+
+	revision := c.newRevision(action, kudo.StringValue(operatorVersionName), v1alpha1.ParameterChanges(instance.Spec.Parameters, newParameters))
+	if revision.OperatorVersion == "" {
+		revision.OperatorVersion = instance.Spec.OperatorVersion.Name
+	}
+	// a plan requested earlier but never started is removed, so that it does not apply to this update
+	annotations := map[string]*string{v1alpha1.UpdatePlanAnnotation: nil}
+	if plan != "" {
+		annotations[v1alpha1.UpdatePlanAnnotation] = kudo.String(plan)
+	}
+	return c.patchInstance(instance, revision, annotations, &instanceSpec)
+}
+
+// UpgradeInstance points the instance to the operatorversion and replaces its parameters, parameters that are not
+// included are removed from the instance. The change is recorded in the history of the instance. With skipChecks the
+// admission webhook does not require the pre-upgrade checks of the operatorversion to pass.
+func (c *Client) UpgradeInstance(instance *v1alpha1.Instance, operatorVersionName string, parameters map[string]string, skipChecks bool) error {
+	// parameters that are no longer set are removed by setting them to null
+	patched := make(map[string]*string, len(instance.Spec.Parameters))
+	for k := range instance.Spec.Parameters {
+		patched[k] = nil
+	}
+	for k, v := range parameters {
+		patched[k] = kudo.String(v)
+	}
+
+	var annotations map[string]*string
+	if skipChecks {
+		annotations = map[string]*string{v1alpha1.SkipPreUpgradeChecksAnnotation: kudo.String("true")}
+	}
+
+	revision := c.newRevision("upgrade", operatorVersionName, v1alpha1.ParameterChanges(instance.Spec.Parameters, parameters))
+	return c.patchInstance(instance.DeepCopy(), revision, annotations, struct {
+		OperatorVersion v1core.ObjectReference `json:"operatorVersion"`
+		Parameters      map[string]*string     `json:"parameters"`
+	}{
+		v1core.ObjectReference{Name: operatorVersionName},
+		patched,
+	})
+}
+
+// RollbackInstance restores the operatorversion and parameters the instance had after the given revision. The
+// instance is expected to be unchanged since it was read, otherwise the rollback fails with a conflict. The
+// operatorversion of the revision already ran the instance, so its pre-upgrade checks are skipped.
+func (c *Client) RollbackInstance(instance *v1alpha1.Instance, toRevision int) error {
+	spec, err := instance.SpecAtRevision(toRevision)
+	if err != nil {
+		return err
+	}
+
+	// parameters that did not exist at the revision are removed by setting them to null
+	parameters := make(map[string]*string, len(instance.Spec.Parameters))
+	for k := range instance.Spec.Parameters {
+		parameters[k] = nil
+	}
+	for k, v := range spec.Parameters {
+		parameters[k] = kudo.String(v)
+	}
+
+	revision := c.newRevision("rollback", spec.OperatorVersion.Name, v1alpha1.ParameterChanges(instance.Spec.Parameters, spec.Parameters))
+	revision.RolledBackTo = toRevision
+	annotations := map[string]*string{v1alpha1.SkipPreUpgradeChecksAnnotation: kudo.String("true")}
+	return c.patchInstance(instance.DeepCopy(), revision, annotations, struct {
+		OperatorVersion v1core.ObjectReference `json:"operatorVersion"`
+		Parameters      map[string]*string     `json:"parameters"`
+	}{
+		v1core.ObjectReference{Name: spec.OperatorVersion.Name},
+		parameters,
+	})
+}
+
+// patchInstance records the revision in the history of the instance and applies the spec and the annotations as merge
+// patch, annotations set to nil are removed
+func (c *Client) patchInstance(instance *v1alpha1.Instance, revision v1alpha1.InstanceRevision, annotations map[string]*string, spec interface{}) error {
+	if err := instance.AddRevision(revision); err != nil {
+		return errors.WithMessage(err, "recording instance history")
+	}
+
+	patched := map[string]*string{v1alpha1.HistoryAnnotation: kudo.String(instance.Annotations[v1alpha1.HistoryAnnotation])}
+	for k, v := range annotations {
+		patched[k] = v
 	}
+	// the resource version makes sure that no history recorded in the meantime is overwritten
 	serializedPatch, err := json.Marshal(struct {
-		Spec *v1alpha1.InstanceSpec `json:"spec"`
+		Metadata interface{} `json:"metadata"`
+		Spec     interface{} `json:"spec"`
 	}{
-		&instanceSpec,
+		struct {
+			ResourceVersion string             `json:"resourceVersion,omitempty"`
+			Annotations     map[string]*string `json:"annotations"`
+		}{instance.ResourceVersion, patched},
+		spec,
 	})
 	if err != nil {
 		return err
 	}
-	_, err = c.clientset.KudoV1alpha1().Instances(namespace).Patch(instanceName, types.MergePatchType, serializedPatch)
+	_, err = c.clientset.KudoV1alpha1().Instances(instance.Namespace).Patch(instance.Name, types.MergePatchType, serializedPatch)
 	return err
 }
 
 // ListInstances lists all instances of given operator installed in the cluster in a given ns
 func (c *Client) ListInstances(namespace string) ([]string, error) {
-	instances, err := c.clientset.KudoV1alpha1().Instances(namespace).List(v1.ListOptions{})
+	instances, err := c.listInstances(namespace, v1.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
 	existingInstances := []string{}
 
-	for _, v := range instances.Items {
+	for _, v := range instances {
 		existingInstances = append(existingInstances, v.Name)
 	}
 	return existingInstances, nil
 }
 
+// ListInstancesBySelector lists the instances in the namespace matching the label selector
+func (c *Client) ListInstancesBySelector(namespace, selector string) ([]v1alpha1.Instance, error) {
+	return c.listInstances(namespace, v1.ListOptions{LabelSelector: selector})
+}
+
+// WaitForPlan waits until the instance observed its latest spec and no plan is running. It fails if the last
+// executed plan did not complete or the timeout expired.
+func (c *Client) WaitForPlan(instanceName, namespace string, interval, timeout time.Duration) error {
+	var last *v1alpha1.PlanStatus
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		instance, err := c.GetInstance(instanceName, namespace)
+		if err != nil {
+			return false, err
+		}
+		if instance == nil {
+			return false, fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+		}
+		if !instance.IsSpecObserved() || instance.GetPlanInProgress() != nil {
+			return false, nil
+		}
+		last = instance.GetLastExecutedPlanStatus()
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("timed out after %s waiting for the plan of instance %s to finish", timeout, instanceName)
+	}
+	if err != nil {
+		return err
+	}
+	if last != nil && last.Status != v1alpha1.ExecutionComplete {
+		if last.Message != "" {
+			return fmt.Errorf("plan %s of instance %s finished with status %s: %s", last.Name, instanceName, last.Status, last.Message)
+		}
+		return fmt.Errorf("plan %s of instance %s finished with status %s", last.Name, instanceName, last.Status)
+	}
+	return nil
+}
+
+// RequestDryRun asks the manager to execute the plan of the instance in dry-run mode. The given parameters override
+// the parameters of the instance for the dry-run, parameters set to nil are removed. It returns the request that the
+// report of the dry-run answers, see WaitForDryRun.
+func (c *Client) RequestDryRun(instanceName, namespace, planName string, parameters map[string]*string) (string, error) {
+	request := rand.String(8)
+	// the overrides of an earlier request are removed with a null value
+	var overrides *string
+	if len(parameters) > 0 {
+		b, err := json.Marshal(parameters)
+		if err != nil {
+			return "", err
+		}
+		overrides = kudo.String(string(b))
+	}
+	serializedPatch, err := json.Marshal(struct {
+		Metadata interface{} `json:"metadata"`
+	}{
+		struct {
+			Annotations map[string]*string `json:"annotations"`
+		}{map[string]*string{
+			v1alpha1.DryRunPlanAnnotation:       kudo.String(planName),
+			v1alpha1.DryRunRequestAnnotation:    kudo.String(request),
+			v1alpha1.DryRunParametersAnnotation: overrides,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = c.clientset.KudoV1alpha1().Instances(namespace).Patch(instanceName, types.MergePatchType, serializedPatch)
+	return request, err
+}
+
+// WaitForDryRun waits until the manager reported the dry-run of the request in the status of the instance
+func (c *Client) WaitForDryRun(instanceName, namespace, request string, interval, timeout time.Duration) (*v1alpha1.DryRunStatus, error) {
+	var report *v1alpha1.DryRunStatus
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		instance, err := c.GetInstance(instanceName, namespace)
+		if err != nil {
+			return false, err
+		}
+		if instance == nil {
+			return false, fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+		}
+		report = instance.Status.DryRun
+		return report != nil && report.Request == request, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out after %s waiting for the dry-run of instance %s", timeout, instanceName)
+	}
+	return report, err
+}
+
+// RequestPreUpgradeCheck asks the manager to execute the pre-upgrade checks of the operatorversion for the instance.
+// It returns the request that the report of the checks answers, see WaitForPreUpgradeCheck.
+func (c *Client) RequestPreUpgradeCheck(instanceName, namespace, operatorVersionName string) (string, error) {
+	request := rand.String(8)
+	serializedPatch, err := json.Marshal(struct {
+		Metadata v1.ObjectMeta `json:"metadata"`
+	}{
+		v1.ObjectMeta{Annotations: map[string]string{
+			v1alpha1.PreUpgradeCheckAnnotation:        operatorVersionName,
+			v1alpha1.PreUpgradeCheckRequestAnnotation: request,
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	_, err = c.clientset.KudoV1alpha1().Instances(namespace).Patch(instanceName, types.MergePatchType, serializedPatch)
+	return request, err
+}
+
+// WaitForPreUpgradeCheck waits until the manager reported the pre-upgrade checks of the request in the status of the
+// instance
+func (c *Client) WaitForPreUpgradeCheck(instanceName, namespace, request string, interval, timeout time.Duration) (*v1alpha1.PreUpgradeCheckStatus, error) {
+	var report *v1alpha1.PreUpgradeCheckStatus
+	err := wait.PollImmediate(interval, timeout, func() (bool, error) {
+		instance, err := c.GetInstance(instanceName, namespace)
+		if err != nil {
+			return false, err
+		}
+		if instance == nil {
+			return false, fmt.Errorf("instance %s in namespace %s does not exist in the cluster", instanceName, namespace)
+		}
+		report = instance.Status.PreUpgradeCheck
+		return report != nil && report.Request == request, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return nil, fmt.Errorf("timed out after %s waiting for the pre-upgrade checks of instance %s", timeout, instanceName)
+	}
+	return report, err
+}
+
+// ApproveGate approves the manual gate of a phase, or of a step of the phase if step is not empty, of the plan of the
+// instance. It returns false if the gate was already approved. The instance is patched with its resource version, so
+// that concurrent approvals fail with a conflict instead of overwriting each other.
+func (c *Client) ApproveGate(instance *v1alpha1.Instance, plan, phase, step string) (bool, error) {
+	approved := instance.DeepCopy()
+	if !approved.Approve(plan, phase, step) {
+		return false, nil
+	}
+	serializedPatch, err := json.Marshal(struct {
+		Metadata v1.ObjectMeta `json:"metadata"`
+	}{
+		v1.ObjectMeta{
+			ResourceVersion: instance.ResourceVersion,
+			Annotations:     map[string]string{v1alpha1.ApprovalsAnnotation: approved.Annotations[v1alpha1.ApprovalsAnnotation]},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	_, err = c.clientset.KudoV1alpha1().Instances(instance.Namespace).Patch(instance.Name, types.MergePatchType, serializedPatch)
+	return err == nil, err
+}
+
 // OperatorVersionsInstalled lists all the versions of given operator installed in the cluster in given ns
 func (c *Client) OperatorVersionsInstalled(operatorName, namespace string) ([]string, error) {
-	ov, err := c.clientset.KudoV1alpha1().OperatorVersions(namespace).List(v1.ListOptions{})
+	ovs, err := c.listOperatorVersions(namespace)
 	if err != nil {
 		return nil, err
 	}
 	existingVersions := []string{}
 
-	for _, v := range ov.Items {
+	for _, v := range ovs {
 		if strings.HasPrefix(v.Name, operatorName) {
 			existingVersions = append(existingVersions, v.Spec.Version)
 		}
@@ -204,6 +497,99 @@ func (c *Client) InstallOperatorObjToCluster(obj *v1alpha1.Operator, namespace s
 	return createdObj, nil
 }
 
+// UpdateOperator replaces the spec of the existing Operator with the one of obj. Labels and annotations of obj are
+// added to the existing ones. The Operator is expected to be unchanged since it was read, otherwise the update fails
+// with a conflict.
+func (c *Client) UpdateOperator(obj *v1alpha1.Operator, namespace string) (*v1alpha1.Operator, error) {
+	existing, err := c.clientset.KudoV1alpha1().Operators(namespace).Get(obj.Name, v1.GetOptions{})
+	if err != nil {
+		return nil, errors.WithMessage(err, "updating Operator")
+	}
+	updated := existing.DeepCopy()
+	updated.Spec = *obj.Spec.DeepCopy()
+	updated.Labels = mergeStringMaps(existing.Labels, obj.Labels)
+	updated.Annotations = mergeStringMaps(existing.Annotations, obj.Annotations)
+
+	updatedObj, err := c.clientset.KudoV1alpha1().Operators(namespace).Update(updated)
+	if err != nil {
+		return nil, errors.WithMessage(err, "updating Operator")
+	}
+	return updatedObj, nil
+}
+
+// PatchOperator merges the fields set in spec into the spec of the existing Operator. Fields that are not set keep
+// their value, lists like maintainers are replaced as a whole.
+func (c *Client) PatchOperator(name, namespace string, spec *v1alpha1.OperatorSpec) (*v1alpha1.Operator, error) {
+	serializedPatch, err := json.Marshal(struct {
+		Spec *v1alpha1.OperatorSpec `json:"spec"`
+	}{spec})
+	if err != nil {
+		return nil, err
+	}
+	patchedObj, err := c.clientset.KudoV1alpha1().Operators(namespace).Patch(name, types.MergePatchType, serializedPatch)
+	if err != nil {
+		return nil, errors.WithMessage(err, "patching Operator")
+	}
+	return patchedObj, nil
+}
+
+// Results of ApplyOperator, named like the results of kubectl apply
+const (
+	OperatorCreated    = "created"
+	OperatorConfigured = "configured"
+	OperatorUnchanged  = "unchanged"
+)
+
+// ApplyOperator creates the Operator or, if it already exists, patches its spec with the one of obj, e.g. when the
+// maintainers changed between versions of the package. It returns the Operator in the cluster and whether it was
+// created, configured or unchanged.
+func (c *Client) ApplyOperator(obj *v1alpha1.Operator, namespace string) (*v1alpha1.Operator, string, error) {
+	createdObj, err := c.clientset.KudoV1alpha1().Operators(namespace).Create(obj)
+	if err == nil {
+		return createdObj, OperatorCreated, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, "", errors.WithMessage(err, "installing Operator")
+	}
+
+	existing, err := c.clientset.KudoV1alpha1().Operators(namespace).Get(obj.Name, v1.GetOptions{})
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "installing Operator")
+	}
+	// the spec is merged like the patch would merge it, to find out whether the patch changes anything
+	spec, err := json.Marshal(obj.Spec)
+	if err != nil {
+		return nil, "", err
+	}
+	merged := existing.DeepCopy()
+	if err := json.Unmarshal(spec, &merged.Spec); err != nil {
+		return nil, "", err
+	}
+	if reflect.DeepEqual(existing.Spec, merged.Spec) {
+		return existing, OperatorUnchanged, nil
+	}
+
+	patchedObj, err := c.PatchOperator(obj.Name, namespace, &obj.Spec)
+	if err != nil {
+		return nil, "", err
+	}
+	return patchedObj, OperatorConfigured, nil
+}
+
+func mergeStringMaps(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return base
+	}
+	merged := make(map[string]string, len(base)+len(overrides))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
 // InstallOperatorVersionObjToCluster expects a valid Operator obj to install
 func (c *Client) InstallOperatorVersionObjToCluster(obj *v1alpha1.OperatorVersion, namespace string) (*v1alpha1.OperatorVersion, error) {
 	createdObj, err := c.clientset.KudoV1alpha1().OperatorVersions(namespace).Create(obj)
@@ -213,8 +599,15 @@ func (c *Client) InstallOperatorVersionObjToCluster(obj *v1alpha1.OperatorVersio
 	return createdObj, nil
 }
 
-// InstallInstanceObjToCluster expects a valid Instance obj to install
+// InstallInstanceObjToCluster expects a valid Instance obj to install. The installation is recorded as the first
+// revision in the history of the instance.
 func (c *Client) InstallInstanceObjToCluster(obj *v1alpha1.Instance, namespace string) (*v1alpha1.Instance, error) {
+	if obj != nil {
+		revision := c.newRevision("install", obj.Spec.OperatorVersion.Name, v1alpha1.ParameterChanges(nil, obj.Spec.Parameters))
+		if err := obj.AddRevision(revision); err != nil {
+			return nil, errors.WithMessage(err, "recording instance history")
+		}
+	}
 	createdObj, err := c.clientset.KudoV1alpha1().Instances(namespace).Create(obj)
 	if err != nil {
 		return nil, errors.WithMessage(err, "installing Instance")
@@ -233,18 +626,122 @@ func (c *Client) DeleteInstance(instanceName, namespace string) error {
 	return c.clientset.KudoV1alpha1().Instances(namespace).Delete(instanceName, options)
 }
 
-// ValidateServerForOperator validates that the k8s server version and kudo version are valid for operator
+// UnprotectInstance removes the protection from deletion from an instance.
+func (c *Client) UnprotectInstance(instanceName, namespace string) error {
+	serializedPatch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{v1alpha1.ProtectedAnnotation: nil},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.clientset.KudoV1alpha1().Instances(namespace).Patch(instanceName, types.MergePatchType, serializedPatch)
+	return err
+}
+
+// DeleteOperatorVersion deletes an operatorversion.
+func (c *Client) DeleteOperatorVersion(name, namespace string) error {
+	return c.clientset.KudoV1alpha1().OperatorVersions(namespace).Delete(name, &v1.DeleteOptions{})
+}
+
+// DeleteOperator deletes an operator.
+func (c *Client) DeleteOperator(name, namespace string) error {
+	return c.clientset.KudoV1alpha1().Operators(namespace).Delete(name, &v1.DeleteOptions{})
+}
+
+// ListOperatorVersions lists all operatorversions in the given namespace
+func (c *Client) ListOperatorVersions(namespace string) ([]v1alpha1.OperatorVersion, error) {
+	ovs, err := c.listOperatorVersions(namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing operatorversions")
+	}
+	return ovs, nil
+}
+
+// OperatorVersionUsage counts the instances referencing each operatorversion. Instances of all namespaces are
+// considered as an instance can reference an operatorversion in another namespace.
+func (c *Client) OperatorVersionUsage() (map[types.NamespacedName]int, error) {
+	used := map[types.NamespacedName]int{}
+	err := c.ForEachInstance(v1.NamespaceAll, v1.ListOptions{}, func(instances []v1alpha1.Instance) error {
+		for _, i := range instances {
+			used[types.NamespacedName{Name: i.Spec.OperatorVersion.Name, Namespace: i.OperatorVersionNamespace()}]++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing instances")
+	}
+	return used, nil
+}
+
+// UnusedOperatorVersions lists all operatorversions in the given namespace that are not referenced by any instance,
+// see OperatorVersionUsage.
+func (c *Client) UnusedOperatorVersions(namespace string) ([]v1alpha1.OperatorVersion, error) {
+	used, err := c.OperatorVersionUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	ovs, err := c.ListOperatorVersions(namespace)
+	if err != nil {
+		return nil, err
+	}
+	unused := []v1alpha1.OperatorVersion{}
+	for _, ov := range ovs {
+		if used[types.NamespacedName{Name: ov.Name, Namespace: ov.Namespace}] == 0 {
+			unused = append(unused, ov)
+		}
+	}
+	return unused, nil
+}
+
+// UnusedOperators lists all operators in the given namespace that have no operatorversion left after the
+// given operatorversions are removed. Pass nil to list operators that have no operatorversion at all.
+func (c *Client) UnusedOperators(namespace string, removedVersions []v1alpha1.OperatorVersion) ([]v1alpha1.Operator, error) {
+	removed := map[string]bool{}
+	for _, ov := range removedVersions {
+		removed[ov.Name] = true
+	}
+
+	used := map[string]bool{}
+	err := c.ForEachOperatorVersion(namespace, v1.ListOptions{}, func(ovs []v1alpha1.OperatorVersion) error {
+		for _, ov := range ovs {
+			if !removed[ov.Name] {
+				used[ov.Spec.Operator.Name] = true
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing operatorversions")
+	}
+
+	operators, err := c.listOperators(namespace)
+	if err != nil {
+		return nil, errors.WithMessage(err, "listing operators")
+	}
+	unused := []v1alpha1.Operator{}
+	for _, o := range operators {
+		if !used[o.Name] {
+			unused = append(unused, o)
+		}
+	}
+	return unused, nil
+}
+
+// ValidateServerForOperator validates that the k8s server version, the version of the KUDO manager and the APIs and
+// feature gates of the server are valid for the operator. The KUDO version is not checked if the version of the
+// manager is unknown.
 // error message will provide detail of failure, otherwise nil
-func (c *Client) ValidateServerForOperator(operator *v1alpha1.Operator) error {
+func (c *Client) ValidateServerForOperator(operator *v1alpha1.Operator, kudoVersion string) error {
 	expectedKubver, err := version.New(operator.Spec.KubernetesVersion)
 	if err != nil {
 		return fmt.Errorf("unable to parse operators kubernetes version: %w", err)
 	}
-	//TODO : to be added in when we support kudo server providing server version
-	//expectedKudoVer, err := semver.NewVersion(operator.Spec.KudoVersion)
-	//if err != nil {
-	//	return fmt.Errorf("Unable to parse operators kudo version: %w", err)
-	//}
+	if err := validateKudoVersion(operator, kudoVersion); err != nil {
+		return err
+	}
 	// semvar compares patch, for which we do not want to... compare maj, min only
 	kVer, err := getKubeVersion(c.clientset.Discovery())
 	if err != nil {
@@ -259,6 +756,28 @@ func (c *Client) ValidateServerForOperator(operator *v1alpha1.Operator) error {
 		return fmt.Errorf("expected kubernetes version of %v is not supported with version: %v", expectedKubver, kSemVer)
 	}
 
+	return validateRequirements(c.clientset.Discovery(), operator)
+}
+
+// validateKudoVersion checks that the KUDO manager is at least the KUDO version required by the operator. Prereleases
+// of the required version are accepted.
+func validateKudoVersion(operator *v1alpha1.Operator, kudoVersion string) error {
+	if operator.Spec.KudoVersion == "" || kudoVersion == "" {
+		return nil
+	}
+	expectedKudoVer, err := version.FromGithubVersion(operator.Spec.KudoVersion)
+	if err != nil {
+		return fmt.Errorf("unable to parse operators kudo version: %w", err)
+	}
+	kudoVer, err := version.FromGithubVersion(kudoVersion)
+	if err != nil {
+		clog.V(2).Printf("not checking the KUDO version required by operator %s as the KUDO manager version %s can not be parsed", operator.Name, kudoVersion)
+		return nil
+	}
+	if expectedKudoVer.CompareRelease(kudoVer) > 0 {
+		return fmt.Errorf("operator %s requires KUDO version %s or newer but the KUDO manager has version %s. Upgrade KUDO with 'kubectl kudo init --upgrade' using kubectl-kudo %s or newer",
+			operator.Name, expectedKudoVer, kudoVer, expectedKudoVer)
+	}
 	return nil
 }
 
@@ -3,7 +3,9 @@ package kudo
 import (
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
 	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
@@ -12,6 +14,8 @@ import (
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	testcore "k8s.io/client-go/testing"
 )
 
 func newTestSimpleK2o() *Client {
@@ -22,12 +26,12 @@ func TestNewK2oClient(t *testing.T) {
 	tests := []struct {
 		err string
 	}{
-		{"invalid configuration: no configuration has been provided"}, // non existing test
+		{`could not get Kubernetes config using configuration "/nonexistent/kubeconfig": stat /nonexistent/kubeconfig: no such file or directory`}, // non existing test
 	}
 
 	for _, tt := range tests {
 		// Just interested in errors
-		_, err := NewClient("default", "")
+		_, err := NewClient("/nonexistent/kubeconfig", "")
 		if err.Error() != tt.err {
 			t.Errorf("non existing test:\nexpected: %v\n     got: %v", tt.err, err.Error())
 		}
@@ -303,6 +307,94 @@ func TestKudoClient_InstallOperatorObjToCluster(t *testing.T) {
 	}
 }
 
+func TestKudoClient_ApplyOperator(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	operator := func(url string, maintainers ...string) *v1alpha1.Operator {
+		o := &v1alpha1.Operator{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"app": "test"}},
+			Spec:       v1alpha1.OperatorSpec{URL: url, KubernetesVersion: "1.15.0"},
+		}
+		for _, m := range maintainers {
+			o.Spec.Maintainers = append(o.Spec.Maintainers, &v1alpha1.Maintainer{Name: m})
+		}
+		return o
+	}
+
+	tests := []struct {
+		name        string
+		obj         *v1alpha1.Operator
+		result      string
+		url         string
+		maintainers int
+	}{
+		{"install", operator("https://kudo.dev", "alice"), OperatorCreated, "https://kudo.dev", 1},
+		{"same package", operator("https://kudo.dev", "alice"), OperatorUnchanged, "https://kudo.dev", 1},
+		{"changed maintainers", operator("https://kudo.dev", "alice", "bob"), OperatorConfigured, "https://kudo.dev", 2},
+		// fields not set in the package keep their value
+		{"missing url", operator("", "alice"), OperatorConfigured, "https://kudo.dev", 1},
+	}
+	for _, tt := range tests {
+		o, result, err := k2o.ApplyOperator(tt.obj, "default")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if result != tt.result {
+			t.Errorf("%s: expected result %s but got %s", tt.name, tt.result, result)
+		}
+		if o.Spec.URL != tt.url || len(o.Spec.Maintainers) != tt.maintainers {
+			t.Errorf("%s: expected url %s and %d maintainers but got %s and %d", tt.name, tt.url, tt.maintainers, o.Spec.URL, len(o.Spec.Maintainers))
+		}
+	}
+}
+
+func TestKudoClient_UpdateOperator(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	existing := &v1alpha1.Operator{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"team": "data"}},
+		Spec:       v1alpha1.OperatorSpec{URL: "https://kudo.dev", Description: "old"},
+	}
+	if _, err := k2o.InstallOperatorObjToCluster(existing, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := k2o.UpdateOperator(&v1alpha1.Operator{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"app": "test"}},
+		Spec:       v1alpha1.OperatorSpec{Description: "new"},
+	}, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Spec.Description != "new" || o.Spec.URL != "" {
+		t.Errorf("expected spec to be replaced but got %+v", o.Spec)
+	}
+	if !reflect.DeepEqual(o.Labels, map[string]string{"team": "data", "app": "test"}) {
+		t.Errorf("expected labels to be merged but got %v", o.Labels)
+	}
+
+	if _, err := k2o.UpdateOperator(&v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "missing"}}, "default"); err == nil {
+		t.Errorf("expected update of missing operator to fail")
+	}
+}
+
+func TestKudoClient_PatchOperator(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	existing := &v1alpha1.Operator{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec:       v1alpha1.OperatorSpec{URL: "https://kudo.dev", Description: "old"},
+	}
+	if _, err := k2o.InstallOperatorObjToCluster(existing, "default"); err != nil {
+		t.Fatal(err)
+	}
+
+	o, err := k2o.PatchOperator("test", "default", &v1alpha1.OperatorSpec{Description: "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Spec.Description != "new" || o.Spec.URL != "https://kudo.dev" {
+		t.Errorf("expected spec to be merged but got %+v", o.Spec)
+	}
+}
+
 func TestKudoClient_InstallOperatorVersionObjToCluster(t *testing.T) {
 	obj := v1alpha1.OperatorVersion{
 		TypeMeta: metav1.TypeMeta{
@@ -541,7 +633,7 @@ func TestKudoClient_UpdateOperatorVersion(t *testing.T) {
 			t.Errorf("Error creating operator version in tests setup for %s", tt.name)
 		}
 
-		err = k2o.UpdateInstance(testInstance.Name, installNamespace, tt.patchToVersion, tt.parametersToPatch)
+		err = k2o.UpdateInstance(testInstance.Name, installNamespace, tt.patchToVersion, tt.parametersToPatch, nil, "")
 		instance, _ := k2o.GetInstance(testInstance.Name, installNamespace)
 		if tt.patchToVersion != nil {
 			if err != nil || instance.Spec.OperatorVersion.Name != util.StringValue(tt.patchToVersion) {
@@ -575,6 +667,144 @@ func TestKudoClient_UpdateOperatorVersion(t *testing.T) {
 	}
 }
 
+func TestKudoClient_UpdateInstance_RemoveParameters(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"param": "value", "other": "value"},
+		},
+	}
+	if _, err := k2o.InstallInstanceObjToCluster(instance, "default"); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if err := k2o.UpdateInstance("test", "default", nil, map[string]string{"added": "value"}, []string{"param", "unset"}, ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	// the fake clientset does not remove keys set to null, so the patch itself is verified
+	actions := k2o.clientset.(*fake.Clientset).Actions()
+	patch := string(actions[len(actions)-1].(testcore.PatchAction).GetPatch())
+	if !strings.Contains(patch, `"parameters":{"added":"value","param":null}`) {
+		t.Errorf("expected the patch to set added and remove param but got %s", patch)
+	}
+
+	instance, err := k2o.GetInstance("test", "default")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	revisions, err := instance.History()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := []v1alpha1.ParameterChange{
+		{Name: "added", New: util.String("value")},
+		{Name: "param", Old: util.String("value")},
+	}
+	if changes := revisions[len(revisions)-1].Parameters; !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected the removal to be recorded as %v but got %v", expected, changes)
+	}
+}
+
+func TestKudoClient_InstanceHistory(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	k2o.user = "admin"
+	namespace := "default"
+
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"param": "value"},
+		},
+	}
+	if _, err := k2o.InstallInstanceObjToCluster(instance, namespace); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if err := k2o.UpdateInstance("test", namespace, nil, map[string]string{"param": "value2"}, nil, ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if err := k2o.UpdateInstance("test", namespace, util.String("test-1.1"), nil, nil, ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	instance, err := k2o.GetInstance("test", namespace)
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	revisions, err := instance.History()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	expected := []v1alpha1.InstanceRevision{
+		{Revision: 1, Action: "install", OperatorVersion: "test-1.0", Parameters: []v1alpha1.ParameterChange{{Name: "param", New: util.String("value")}}},
+		{Revision: 2, Action: "update", OperatorVersion: "test-1.0", Parameters: []v1alpha1.ParameterChange{{Name: "param", Old: util.String("value"), New: util.String("value2")}}},
+		{Revision: 3, Action: "upgrade", OperatorVersion: "test-1.1"},
+	}
+	if len(revisions) != len(expected) {
+		t.Fatalf("expected %d revisions but got %d", len(expected), len(revisions))
+	}
+	for i, r := range revisions {
+		if r.User != "admin" || r.ClientVersion == "" || r.Time.IsZero() {
+			t.Errorf("revision %d: expected user, client version and time to be recorded but got %+v", i, r)
+		}
+		r.User, r.ClientVersion, r.Time = "", "", metav1.Time{}
+		if !reflect.DeepEqual(r, expected[i]) {
+			t.Errorf("revision %d:\nexpected: %+v\n     got: %+v", i, expected[i], r)
+		}
+	}
+}
+
+func TestKudoClient_RollbackInstance(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	namespace := "default"
+
+	instance := &v1alpha1.Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: "test-1.0"},
+			Parameters:      map[string]string{"param": "value", "other": "value"},
+		},
+	}
+	if _, err := k2o.InstallInstanceObjToCluster(instance, namespace); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if err := k2o.UpdateInstance("test", namespace, util.String("test-1.1"), map[string]string{"param": "value2", "added": "value"}, nil, ""); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	instance, _ = k2o.GetInstance("test", namespace)
+	if err := k2o.RollbackInstance(instance, 1); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	instance, _ = k2o.GetInstance("test", namespace)
+	if instance.Spec.OperatorVersion.Name != "test-1.0" {
+		t.Errorf("expected operatorversion test-1.0 but got %s", instance.Spec.OperatorVersion.Name)
+	}
+	if instance.Spec.Parameters["param"] != "value" || instance.Spec.Parameters["other"] != "value" {
+		t.Errorf("expected parameters to be restored but got %v", instance.Spec.Parameters)
+	}
+	// the fake clientset does not remove null values of merge patches, so the patch itself is checked
+	actions := k2o.clientset.(*fake.Clientset).Actions()
+	patch := string(actions[len(actions)-2].(testcore.PatchAction).GetPatch())
+	if !strings.Contains(patch, `"added":null`) {
+		t.Errorf("expected parameter added to be removed by patch %s", patch)
+	}
+	revisions, _ := instance.History()
+	if len(revisions) != 3 || revisions[2].Action != "rollback" || revisions[2].RolledBackTo != 1 || revisions[2].Revision != 3 {
+		t.Errorf("expected the rollback to be recorded as revision 3 but got %+v", revisions)
+	}
+
+	if err := k2o.RollbackInstance(instance, 7); err == nil {
+		t.Errorf("expected an error when rolling back to an unknown revision")
+	}
+}
+
 func TestKudoClient_DeleteInstance(t *testing.T) {
 	testInstance := v1alpha1.Instance{
 		TypeMeta: metav1.TypeMeta{
@@ -636,3 +866,119 @@ func TestKudoClient_DeleteInstance(t *testing.T) {
 		}
 	}
 }
+
+func TestKudoClient_DryRun(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	if _, err := k2o.clientset.KudoV1alpha1().Instances("default").Create(instance); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := k2o.RequestDryRun("test", "default", "upgrade", map[string]*string{"REPLICAS": kudo.String("3")})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	requested, _ := k2o.GetInstance("test", "default")
+	if plan, pending := requested.GetPendingDryRun(); plan != "upgrade" || pending != request {
+		t.Fatalf("expected a pending dry-run of plan upgrade but got %q, %q", plan, pending)
+	}
+	if parameters, err := requested.GetDryRunParameters(); err != nil || parameters["REPLICAS"] != "3" {
+		t.Fatalf("expected the dry-run to override parameter REPLICAS but got %v, %v", parameters, err)
+	}
+
+	if _, err := k2o.WaitForDryRun("test", "default", request, time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Errorf("expected a timeout without report")
+	}
+
+	requested.Status.DryRun = &v1alpha1.DryRunStatus{Request: request, Plan: "upgrade"}
+	if _, err := k2o.clientset.KudoV1alpha1().Instances("default").Update(requested); err != nil {
+		t.Fatal(err)
+	}
+	report, err := k2o.WaitForDryRun("test", "default", request, time.Millisecond, 10*time.Millisecond)
+	if err != nil || report.Plan != "upgrade" {
+		t.Errorf("expected the report of the dry-run but got %v, %v", report, err)
+	}
+}
+
+func TestKudoClient_PreUpgradeCheck(t *testing.T) {
+	k2o := newTestSimpleK2o()
+	instance := &v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: "default"}}
+	if _, err := k2o.clientset.KudoV1alpha1().Instances("default").Create(instance); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := k2o.RequestPreUpgradeCheck("test", "default", "test-2.0.0")
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	requested, _ := k2o.GetInstance("test", "default")
+	if ov, pending := requested.GetPendingPreUpgradeCheck(); ov != "test-2.0.0" || pending != request {
+		t.Fatalf("expected pending pre-upgrade checks of test-2.0.0 but got %q, %q", ov, pending)
+	}
+
+	if _, err := k2o.WaitForPreUpgradeCheck("test", "default", request, time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Errorf("expected a timeout without report")
+	}
+
+	requested.Status.PreUpgradeCheck = &v1alpha1.PreUpgradeCheckStatus{Request: request, OperatorVersion: "test-2.0.0"}
+	if _, err := k2o.clientset.KudoV1alpha1().Instances("default").Update(requested); err != nil {
+		t.Fatal(err)
+	}
+	report, err := k2o.WaitForPreUpgradeCheck("test", "default", request, time.Millisecond, 10*time.Millisecond)
+	if err != nil || report.OperatorVersion != "test-2.0.0" {
+		t.Errorf("expected the report of the pre-upgrade checks but got %v, %v", report, err)
+	}
+}
+
+func TestKudoClient_OperatorVersionUsage(t *testing.T) {
+	instance := func(name, namespace, ov, ovNamespace string) *v1alpha1.Instance {
+		return &v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: v1.ObjectReference{Name: ov, Namespace: ovNamespace}},
+		}
+	}
+	k2o := NewClientFromK8s(fake.NewSimpleClientset(
+		instance("a", "default", "kafka-1.0.0", ""),
+		instance("b", "default", "kafka-1.0.0", ""),
+		instance("c", "team-a", "kafka-1.0.0", "default"),
+		instance("d", "team-a", "kafka-1.0.0", ""),
+	))
+
+	usage, err := k2o.OperatorVersionUsage()
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	expected := map[types.NamespacedName]int{
+		{Name: "kafka-1.0.0", Namespace: "default"}: 3,
+		{Name: "kafka-1.0.0", Namespace: "team-a"}:  1,
+	}
+	if !reflect.DeepEqual(expected, usage) {
+		t.Errorf("expected %v but got %v", expected, usage)
+	}
+}
+
+func TestValidateKudoVersion(t *testing.T) {
+	tests := []struct {
+		name        string
+		required    string
+		kudoVersion string
+		err         string
+	}{
+		{"newer manager", "0.9.0", "0.10.0", ""},
+		{"same version", "0.10.0", "v0.10.0", ""},
+		{"prerelease of the required version", "0.10.0", "0.10.0-rc1", ""},
+		{"unknown manager version", "0.10.0", "", ""},
+		{"development manager", "0.10.0", "dev", ""},
+		{"no required version", "", "0.8.0", ""},
+		{"older manager", "0.10.0", "0.9.1", "operator kafka requires KUDO version 0.10.0 or newer but the KUDO manager has version 0.9.1. Upgrade KUDO with 'kubectl kudo init --upgrade' using kubectl-kudo 0.10.0 or newer"},
+		{"invalid required version", "latest", "0.9.1", "unable to parse operators kudo version: Invalid Semantic Version"},
+	}
+
+	for _, tt := range tests {
+		operator := &v1alpha1.Operator{ObjectMeta: metav1.ObjectMeta{Name: "kafka"}, Spec: v1alpha1.OperatorSpec{KudoVersion: tt.required}}
+		err := validateKudoVersion(operator, tt.kudoVersion)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
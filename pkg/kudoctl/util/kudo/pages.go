@@ -0,0 +1,89 @@
+package kudo
+
+import (
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// listPageSize is the number of objects requested per page by the list methods of the Client. Listing namespaces
+// with thousands of instances in a single call times out.
+var listPageSize int64 = 500
+
+// ForEachInstance lists the instances in the namespace matching the options page by page and calls f with every page.
+// Listing stops at the first error returned by f. The Limit and Continue of the options are managed by the Client.
+func (c *Client) ForEachInstance(namespace string, opts v1.ListOptions, f func([]v1alpha1.Instance) error) error {
+	return forEachPage(opts, func(opts v1.ListOptions) (string, error) {
+		list, err := c.clientset.KudoV1alpha1().Instances(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		return list.Continue, f(list.Items)
+	})
+}
+
+// ForEachOperatorVersion lists the operatorversions in the namespace page by page and calls f with every page, see
+// ForEachInstance
+func (c *Client) ForEachOperatorVersion(namespace string, opts v1.ListOptions, f func([]v1alpha1.OperatorVersion) error) error {
+	return forEachPage(opts, func(opts v1.ListOptions) (string, error) {
+		list, err := c.clientset.KudoV1alpha1().OperatorVersions(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		return list.Continue, f(list.Items)
+	})
+}
+
+// ForEachOperator lists the operators in the namespace page by page and calls f with every page, see ForEachInstance
+func (c *Client) ForEachOperator(namespace string, opts v1.ListOptions, f func([]v1alpha1.Operator) error) error {
+	return forEachPage(opts, func(opts v1.ListOptions) (string, error) {
+		list, err := c.clientset.KudoV1alpha1().Operators(namespace).List(opts)
+		if err != nil {
+			return "", err
+		}
+		return list.Continue, f(list.Items)
+	})
+}
+
+// listInstances returns the instances of all pages, see ForEachInstance
+func (c *Client) listInstances(namespace string, opts v1.ListOptions) ([]v1alpha1.Instance, error) {
+	var instances []v1alpha1.Instance
+	err := c.ForEachInstance(namespace, opts, func(page []v1alpha1.Instance) error {
+		instances = append(instances, page...)
+		return nil
+	})
+	return instances, err
+}
+
+// listOperatorVersions returns the operatorversions of all pages, see ForEachOperatorVersion
+func (c *Client) listOperatorVersions(namespace string) ([]v1alpha1.OperatorVersion, error) {
+	var ovs []v1alpha1.OperatorVersion
+	err := c.ForEachOperatorVersion(namespace, v1.ListOptions{}, func(page []v1alpha1.OperatorVersion) error {
+		ovs = append(ovs, page...)
+		return nil
+	})
+	return ovs, err
+}
+
+// listOperators returns the operators of all pages, see ForEachOperator
+func (c *Client) listOperators(namespace string) ([]v1alpha1.Operator, error) {
+	var operators []v1alpha1.Operator
+	err := c.ForEachOperator(namespace, v1.ListOptions{}, func(page []v1alpha1.Operator) error {
+		operators = append(operators, page...)
+		return nil
+	})
+	return operators, err
+}
+
+// forEachPage calls list with the options of each page until the API server returns no continue token
+func forEachPage(opts v1.ListOptions, list func(v1.ListOptions) (string, error)) error {
+	opts.Limit = listPageSize
+	opts.Continue = ""
+	for {
+		next, err := list(opts)
+		if err != nil || next == "" {
+			return err
+		}
+		opts.Continue = next
+	}
+}
@@ -0,0 +1,83 @@
+package kudo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestForEachPage(t *testing.T) {
+	defer func(size int64) { listPageSize = size }(listPageSize)
+	listPageSize = 2
+
+	// the list serves 5 objects in pages of the requested limit, like the API server does
+	var requests []metav1.ListOptions
+	var pages []int
+	list := func(opts metav1.ListOptions) (string, error) {
+		requests = append(requests, opts)
+		start := 0
+		if opts.Continue != "" {
+			start, _ = strconv.Atoi(opts.Continue)
+		}
+		end := start + int(opts.Limit)
+		if end >= 5 {
+			pages = append(pages, 5-start)
+			return "", nil
+		}
+		pages = append(pages, end-start)
+		return strconv.Itoa(end), nil
+	}
+
+	if err := forEachPage(metav1.ListOptions{LabelSelector: "app=kafka", Continue: "stale"}, list); err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if !reflect.DeepEqual([]int{2, 2, 1}, pages) {
+		t.Errorf("expected pages of 2, 2 and 1 objects but got %v", pages)
+	}
+	expected := []metav1.ListOptions{
+		{LabelSelector: "app=kafka", Limit: 2},
+		{LabelSelector: "app=kafka", Limit: 2, Continue: "2"},
+		{LabelSelector: "app=kafka", Limit: 2, Continue: "4"},
+	}
+	if !reflect.DeepEqual(expected, requests) {
+		t.Errorf("expected requests %v but got %v", expected, requests)
+	}
+
+	requests = nil
+	stop := errors.New("stop")
+	err := forEachPage(metav1.ListOptions{}, func(opts metav1.ListOptions) (string, error) {
+		requests = append(requests, opts)
+		return "2", stop
+	})
+	if err != stop || len(requests) != 1 {
+		t.Errorf("expected listing to stop after the first error, got %v after %d requests", err, len(requests))
+	}
+}
+
+func TestKudoClient_ForEachInstance(t *testing.T) {
+	k2o := NewClientFromK8s(fake.NewSimpleClientset(
+		&v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}},
+		&v1alpha1.Instance{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}},
+	))
+
+	var names []string
+	stop := errors.New("stop")
+	err := k2o.ForEachInstance("default", metav1.ListOptions{}, func(instances []v1alpha1.Instance) error {
+		for _, i := range instances {
+			names = append(names, i.Name)
+		}
+		return stop
+	})
+	if err != stop {
+		t.Errorf("expected the error of the callback but got %v", err)
+	}
+	if !reflect.DeepEqual([]string{"a", "b"}, names) {
+		t.Errorf("expected instances a and b but got %v", names)
+	}
+}
@@ -0,0 +1,133 @@
+package kudo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+// featureEnabledMetric matches the samples of the metric the API server reports the state of its feature gates with
+var featureEnabledMetric = regexp.MustCompile(`^kubernetes_feature_enabled\{[^}]*name="([^"]+)"[^}]*\}\s+(\S+)`)
+
+// validateRequirements checks the requirements of the operator against the APIs the server discovers and the feature
+// gates it reports. All unmet requirements are listed in the error. Feature gates are only reported by recent API
+// servers, if they can not be read a warning is printed instead.
+func validateRequirements(d discovery.DiscoveryInterface, operator *v1alpha1.Operator) error {
+	requirements := operator.Spec.Requirements
+	if requirements == nil {
+		return nil
+	}
+
+	var unmet []string
+	if len(requirements.APIs) > 0 {
+		groups, err := d.ServerGroups()
+		if err != nil {
+			return fmt.Errorf("failed to discover the APIs of the server: %w", err)
+		}
+		for _, api := range requirements.APIs {
+			served, err := apiServed(d, groups, api)
+			if err != nil {
+				return err
+			}
+			if !served {
+				unmet = append(unmet, fmt.Sprintf("the %s is not served", api))
+			}
+		}
+	}
+
+	if len(requirements.FeatureGates) > 0 {
+		enabled, err := enabledFeatureGates(d)
+		if err != nil {
+			clog.Printf("WARNING: the feature gates %s required by operator %s can not be verified: %v",
+				strings.Join(requirements.FeatureGates, ", "), operator.Name, err)
+		} else {
+			for _, gate := range requirements.FeatureGates {
+				if !enabled[gate] {
+					unmet = append(unmet, fmt.Sprintf("the feature gate %s is not enabled", gate))
+				}
+			}
+		}
+	}
+
+	if len(unmet) > 0 {
+		return fmt.Errorf("the cluster does not meet the requirements of operator %s:\n  - %s", operator.Name, strings.Join(unmet, "\n  - "))
+	}
+	return nil
+}
+
+// apiServed returns true if the server serves the group, version and resource of the requirement
+func apiServed(d discovery.DiscoveryInterface, groups *v1.APIGroupList, api v1alpha1.APIRequirement) (bool, error) {
+	var versions []string
+	for _, g := range groups.Groups {
+		if g.Name != api.Group {
+			continue
+		}
+		for _, v := range g.Versions {
+			if api.Version == "" || v.Version == api.Version {
+				versions = append(versions, v.GroupVersion)
+			}
+		}
+	}
+	if len(versions) == 0 || api.Resource == "" {
+		return len(versions) > 0, nil
+	}
+
+	for _, gv := range versions {
+		resources, err := d.ServerResourcesForGroupVersion(gv)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to discover the resources of %s: %w", gv, err)
+		}
+		for _, r := range resources.APIResources {
+			if r.Name == api.Resource {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// enabledFeatureGates reads the state of the feature gates from the metrics of the API server
+func enabledFeatureGates(d discovery.DiscoveryInterface) (map[string]bool, error) {
+	// fake discovery clients have no REST client
+	client := d.RESTClient()
+	if c, ok := client.(*rest.RESTClient); client == nil || ok && c == nil {
+		return nil, fmt.Errorf("the metrics of the API server can not be read")
+	}
+	metrics, err := client.Get().AbsPath("/metrics").DoRaw()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the metrics of the API server: %w", err)
+	}
+	return parseFeatureGates(metrics)
+}
+
+// parseFeatureGates returns the state of the feature gates reported by the kubernetes_feature_enabled metric
+func parseFeatureGates(metrics []byte) (map[string]bool, error) {
+	gates := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(metrics))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if m := featureEnabledMetric.FindStringSubmatch(scanner.Text()); m != nil {
+			gates[m[1]] = m[2] == "1"
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse the metrics of the API server: %w", err)
+	}
+	if len(gates) == 0 {
+		return nil, fmt.Errorf("the API server does not report its feature gates")
+	}
+	return gates, nil
+}
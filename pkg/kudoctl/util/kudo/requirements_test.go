@@ -0,0 +1,72 @@
+package kudo
+
+import (
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/fake"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+)
+
+func TestValidateServerForOperator_Requirements(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	discovery := client.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{GitVersion: "v1.16.0"}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+		{GroupVersion: "policy/v1beta1", APIResources: []metav1.APIResource{{Name: "poddisruptionbudgets"}}},
+		{GroupVersion: "snapshot.storage.k8s.io/v1alpha1", APIResources: []metav1.APIResource{{Name: "volumesnapshots"}}},
+	}
+	kc := NewClientFromK8s(client)
+
+	operator := func(requirements *v1alpha1.ClusterRequirements) *v1alpha1.Operator {
+		return &v1alpha1.Operator{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka"},
+			Spec:       v1alpha1.OperatorSpec{KubernetesVersion: "1.15.0", Requirements: requirements},
+		}
+	}
+
+	assert.NoError(t, kc.ValidateServerForOperator(operator(nil), ""))
+	assert.NoError(t, kc.ValidateServerForOperator(operator(&v1alpha1.ClusterRequirements{
+		APIs: []v1alpha1.APIRequirement{
+			{Group: "snapshot.storage.k8s.io"},
+			{Group: "policy", Version: "v1beta1"},
+			{Group: "policy", Resource: "poddisruptionbudgets"},
+			{Version: "v1", Resource: "pods"},
+		},
+		// feature gates can not be read from fake clients, they are not verified
+		FeatureGates: []string{"VolumeSnapshotDataSource"},
+	}), ""))
+
+	err := kc.ValidateServerForOperator(operator(&v1alpha1.ClusterRequirements{
+		APIs: []v1alpha1.APIRequirement{
+			{Group: "snapshot.storage.k8s.io", Version: "v1beta1", Resource: "volumesnapshots"},
+			{Group: "policy", Version: "v1"},
+			{Group: "policy", Resource: "podsecuritypolicies"},
+			{Group: "monitoring.coreos.com"},
+		},
+	}), "")
+	assert.EqualError(t, err, `the cluster does not meet the requirements of operator kafka:
+  - the resource volumesnapshots of the API snapshot.storage.k8s.io/v1beta1 is not served
+  - the API policy/v1 is not served
+  - the resource podsecuritypolicies of the API group policy is not served
+  - the API group monitoring.coreos.com is not served`)
+}
+
+func TestParseFeatureGates(t *testing.T) {
+	gates, err := parseFeatureGates([]byte(`# HELP kubernetes_feature_enabled [ALPHA] This metric records the data about the stage and enablement of a k8s feature.
+# TYPE kubernetes_feature_enabled gauge
+kubernetes_feature_enabled{name="VolumeSnapshotDataSource",stage=""} 1
+kubernetes_feature_enabled{name="InPlacePodVerticalScaling",stage="ALPHA"} 0
+apiserver_request_total{code="200"} 42
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"VolumeSnapshotDataSource": true, "InPlacePodVerticalScaling": false}, gates)
+
+	_, err = parseFeatureGates([]byte("apiserver_request_total{code=\"200\"} 42\n"))
+	assert.EqualError(t, err, "the API server does not report its feature gates")
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
@@ -15,7 +17,23 @@ import (
 	"sigs.k8s.io/yaml"
 )
 
-const defaultURL = "http://localhost/"
+const (
+	defaultURL = "http://localhost/"
+
+	// IndexAPIVersion is the version of the index format written by kudoctl. Version v2 added the KUDO and Kubernetes
+	// version constraints, the release timestamps and the deprecation of package versions, it is compatible with v1.
+	IndexAPIVersion = "v2"
+)
+
+// supportedIndexAPIVersions are the versions of the index format kudoctl can read
+var supportedIndexAPIVersions = []string{"v1", IndexAPIVersion}
+
+var (
+	// constraintSeparator matches the whitespace between two version constraints, e.g. in ">=1.2 <2"
+	constraintSeparator = regexp.MustCompile(`([0-9xX*])\s+([<>=!~^])`)
+	// lessThanMajor matches a less than constraint on a major version, e.g. "<2"
+	lessThanMajor = regexp.MustCompile(`(<\s*[0-9]+)(\s|,|\||$)`)
+)
 
 // IndexFile represents the index file in an operator repository.
 type IndexFile struct {
@@ -34,6 +52,10 @@ type PackageVersion struct {
 	URLs    []string `json:"urls"`
 	Removed bool     `json:"removed,omitempty"`
 	Digest  string   `json:"digest,omitempty"`
+	// Created is the time the package version was added to the repository
+	Created *time.Time `json:"created,omitempty"`
+	// Deprecated package versions can still be installed but kudoctl warns about them
+	Deprecated bool `json:"deprecated,omitempty"`
 }
 
 // Len returns the number of package versions.
@@ -72,7 +94,7 @@ func (i IndexFile) sortPackages() {
 }
 
 // ParseIndexFile loads an index file and sorts the included packages by version.
-// The function will fail if `APIVersion` is not specified.
+// The function will fail if `APIVersion` is not specified or not supported.
 func ParseIndexFile(data []byte) (*IndexFile, error) {
 	i := &IndexFile{}
 	if err := yaml.Unmarshal(data, i); err != nil {
@@ -81,6 +103,10 @@ func ParseIndexFile(data []byte) (*IndexFile, error) {
 	if i.APIVersion == "" {
 		return nil, errors.New("no API version specified")
 	}
+	if !isSupportedIndexAPIVersion(i.APIVersion) {
+		return nil, errors.Errorf("index API version %s is not supported, supported versions are %s, please upgrade kudoctl",
+			i.APIVersion, strings.Join(supportedIndexAPIVersions, ", "))
+	}
 	i.sortPackages()
 	return i, nil
 }
@@ -97,9 +123,19 @@ func (i IndexFile) Write(w io.Writer) error {
 	return err
 }
 
+func isSupportedIndexAPIVersion(version string) bool {
+	for _, v := range supportedIndexAPIVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
 // GetByNameAndVersion returns the operator of given name and version.
 // If no specific version is required, pass an empty string as version and the
-// the latest version will be returned.
+// the latest version will be returned. A version constraint, e.g. ">=1.2 <2", returns the
+// latest version satisfying it.
 func (i IndexFile) GetByNameAndVersion(name, version string) (*PackageVersion, error) {
 	vs, ok := i.Entries[name]
 	if !ok || len(vs) == 0 {
@@ -112,6 +148,21 @@ func (i IndexFile) GetByNameAndVersion(name, version string) (*PackageVersion, e
 		}
 	}
 
+	if _, err := semver.NewVersion(version); err != nil {
+		constraint, err := ParseConstraint(version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version or version constraint %s for %s: %v", version, name, err)
+		}
+		// versions are sorted in descending order
+		for _, ver := range vs {
+			v, err := semver.NewVersion(ver.Version)
+			if err == nil && constraint.Check(v) {
+				return ver, nil
+			}
+		}
+		return nil, fmt.Errorf("no operator version found for %s matching %s", name, version)
+	}
+
 	if version == "" {
 		return nil, fmt.Errorf("no operator version found for %s", name)
 	}
@@ -119,6 +170,15 @@ func (i IndexFile) GetByNameAndVersion(name, version string) (*PackageVersion, e
 	return nil, fmt.Errorf("no operator version found for %s-%v", name, version)
 }
 
+// ParseConstraint parses a version constraint. Constraints that all have to be satisfied are separated by commas or
+// whitespace, e.g. ">=1.2, <2" or ">=1.2 <2".
+func ParseConstraint(constraint string) (*semver.Constraints, error) {
+	c := constraintSeparator.ReplaceAllString(strings.TrimSpace(constraint), "$1, $2")
+	// semver treats "<2" like "<2.x", which includes all 2.x versions
+	c = lessThanMajor.ReplaceAllString(c, "${1}.0${2}")
+	return semver.NewConstraint(c)
+}
+
 // AddPackageVersion adds an entry to the IndexFile (does not allow dups)
 func (i *IndexFile) AddPackageVersion(pv *PackageVersion) error {
 	name := pv.Name
@@ -191,11 +251,17 @@ func ToPackageVersion(pf *packages.PackageFiles, digest string, url string) *Pac
 	url = fmt.Sprintf("%s%s-%v.tgz", url, o.Name, o.Version)
 	pv := PackageVersion{
 		Metadata: &Metadata{
-			Name:        o.Name,
-			Version:     o.Version,
-			Description: o.Description,
-			Maintainers: o.Maintainers,
-			AppVersion:  o.AppVersion,
+			Name:              o.Name,
+			Version:           o.Version,
+			Description:       o.Description,
+			Maintainers:       o.Maintainers,
+			AppVersion:        o.AppVersion,
+			KUDOVersion:       o.KUDOVersion,
+			KubernetesVersion: o.KubernetesVersion,
+			Icon:              o.Icon,
+			Keywords:          o.Keywords,
+			Categories:        o.Categories,
+			Links:             o.Links,
 		},
 		URLs:   []string{url},
 		Digest: digest,
@@ -205,13 +271,13 @@ func ToPackageVersion(pf *packages.PackageFiles, digest string, url string) *Pac
 
 func newIndexFile(t *time.Time) *IndexFile {
 	i := IndexFile{
-		APIVersion: "v1",
+		APIVersion: IndexAPIVersion,
 		Generated:  t,
 	}
 	return &i
 }
 
-// IndexDirectory creates an index file for the operators in the path
+// IndexDirectory creates an index file for the operators in the path, their creation time is set to now
 func IndexDirectory(fs afero.Fs, path string, url string, now *time.Time) (*IndexFile, error) {
 	archives, err := afero.Glob(fs, filepath.Join(path, "*.tgz"))
 	if err != nil {
@@ -224,6 +290,7 @@ func IndexDirectory(fs afero.Fs, path string, url string, now *time.Time) (*Inde
 	ops := packages.GetFilesDigest(fs, archives)
 	pvs := Map(ops, url)
 	for _, pv := range pvs {
+		pv.Created = now
 		err = index.AddPackageVersion(pv)
 		// on error we report and continue
 		if err != nil {
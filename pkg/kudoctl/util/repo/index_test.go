@@ -162,6 +162,10 @@ func TestMapPackageFileToPackageVersion(t *testing.T) {
 		KubernetesVersion: "1.15",
 		Maintainers:       []*v1alpha1.Maintainer{&v1alpha1.Maintainer{Name: "Ken Sipe"}},
 		URL:               "http://kudo.dev/kafka",
+		Icon:              "http://kudo.dev/kafka.svg",
+		Keywords:          []string{"kafka", "streaming"},
+		Categories:        []string{"messaging"},
+		Links:             []*v1alpha1.Link{{Name: "Documentation", URL: "http://kudo.dev/kafka/docs"}},
 	}
 	pf := packages.PackageFiles{
 		Operator: &o,
@@ -173,4 +177,58 @@ func TestMapPackageFileToPackageVersion(t *testing.T) {
 	assert.Equal(t, pv.Version, o.Version)
 	assert.Equal(t, pv.URLs[0], "http://localhost/kafka-1.0.0.tgz")
 	assert.Equal(t, pv.Digest, "1234")
+	assert.Equal(t, pv.Icon, o.Icon)
+	assert.Equal(t, pv.Keywords, o.Keywords)
+	assert.Equal(t, pv.Categories, o.Categories)
+	assert.Equal(t, pv.Links, o.Links)
+	assert.Equal(t, pv.KUDOVersion, o.KUDOVersion)
+	assert.Equal(t, pv.KubernetesVersion, o.KubernetesVersion)
+}
+
+func TestParseIndexFile_UnsupportedVersion(t *testing.T) {
+	_, err := ParseIndexFile([]byte("apiVersion: v3\nentries: {}\n"))
+	if err == nil {
+		t.Fatal("expected an error for an unsupported index version")
+	}
+	assert.Equal(t, err.Error(), "index API version v3 is not supported, supported versions are v1, v2, please upgrade kudoctl")
+}
+
+func TestGetByNameAndVersion(t *testing.T) {
+	index := newIndexFile(nil)
+	for _, v := range []string{"0.9.0", "1.2.0", "1.3.1", "2.0.0"} {
+		pv := getTestPackageVersion("kafka", v)
+		if err := index.AddPackageVersion(&pv); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index.sortPackages()
+
+	tests := []struct {
+		version  string
+		expected string
+		err      string
+	}{
+		{version: "", expected: "2.0.0"},
+		{version: "1.2.0", expected: "1.2.0"},
+		{version: ">=1.2 <2", expected: "1.3.1"},
+		{version: ">= 1.2, < 1.3", expected: "1.2.0"},
+		{version: "~0.9", expected: "0.9.0"},
+		{version: "1.0.0", err: "no operator version found for kafka-1.0.0"},
+		{version: ">3", err: "no operator version found for kafka matching >3"},
+	}
+
+	for _, tt := range tests {
+		pv, err := index.GetByNameAndVersion("kafka", tt.version)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("%s: expected error %s but got %v", tt.version, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.version, err)
+			continue
+		}
+		assert.Equal(t, pv.Version, tt.expected, tt.version)
+	}
 }
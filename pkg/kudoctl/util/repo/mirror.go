@@ -0,0 +1,138 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+
+	"github.com/Masterminds/semver"
+	"github.com/spf13/afero"
+)
+
+// MirrorFilter selects the versions of an operator that are mirrored
+type MirrorFilter struct {
+	Operator string
+	// Constraint limits the mirrored versions, all versions are mirrored if it is nil
+	Constraint *semver.Constraints
+}
+
+// ParseMirrorFilter parses a filter of the form `name` or `name@constraint`, e.g. `kafka@>=1.0.0, <2.0.0`
+func ParseMirrorFilter(s string) (MirrorFilter, error) {
+	parts := strings.SplitN(s, "@", 2)
+	f := MirrorFilter{Operator: strings.TrimSpace(parts[0])}
+	if f.Operator == "" {
+		return f, fmt.Errorf("operator filter %q has no operator name", s)
+	}
+	if len(parts) == 2 {
+		c, err := semver.NewConstraint(parts[1])
+		if err != nil {
+			return f, fmt.Errorf("operator filter %q has an invalid version constraint: %v", s, err)
+		}
+		f.Constraint = c
+	}
+	return f, nil
+}
+
+// matches returns true if the package version is selected by one of the filters or if there are no filters
+func matches(filters []MirrorFilter, pv *PackageVersion) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if f.Operator != pv.Name {
+			continue
+		}
+		if f.Constraint == nil {
+			return true
+		}
+		v, err := semver.NewVersion(pv.Version)
+		if err == nil && f.Constraint.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Mirror downloads the packages of the repository index that are selected by the filters into dir and returns the
+// index of the mirror. Packages with a digest in the index are verified, the index of the mirror references the
+// packages at url and contains the digests of all packages. Removed packages are not mirrored.
+func (c *Client) Mirror(fs afero.Fs, dir, url string, filters []MirrorFilter, overwrite bool, now *time.Time) (*IndexFile, error) {
+	index, err := c.DownloadIndexFile()
+	if err != nil {
+		return nil, fmt.Errorf("could not download repository index file: %w", err)
+	}
+
+	operators := make([]string, 0, len(index.Entries))
+	for name := range index.Entries {
+		operators = append(operators, name)
+	}
+	sort.Strings(operators)
+
+	mirror := newIndexFile(now)
+	for _, operator := range operators {
+		for _, pv := range index.Entries[operator] {
+			if pv.Removed || !matches(filters, pv) {
+				continue
+			}
+			name, err := mirrorPackageName(pv)
+			if err != nil {
+				return nil, err
+			}
+			target, err := files.FullPathToTarget(fs, dir, name, overwrite)
+			if err != nil {
+				return nil, err
+			}
+
+			clog.V(2).Printf("mirroring %s", name)
+			buf, err := c.getPackageReaderByAPackageURL(pv)
+			if err != nil {
+				return nil, fmt.Errorf("failed to download %s: %w", name, err)
+			}
+			digest, err := files.Sha256Sum(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				return nil, err
+			}
+			if err := afero.WriteFile(fs, target, buf.Bytes(), 0644); err != nil {
+				return nil, fmt.Errorf("failed to write %s: %w", target, err)
+			}
+
+			if err := mirror.AddPackageVersion(mirroredPackageVersion(pv, url, name, digest)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(mirror.Entries) == 0 {
+		return nil, fmt.Errorf("no packages of repository %s match the filters", c.Config.Name)
+	}
+	mirror.sortPackages()
+	return mirror, nil
+}
+
+// mirrorPackageName returns the file name of the package in the mirror. The name and version come from the index of
+// the mirrored repository, they are rejected if they would place the package outside of the mirror directory.
+func mirrorPackageName(pv *PackageVersion) (string, error) {
+	name := fmt.Sprintf("%s-%v.tgz", pv.Name, pv.Version)
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") || filepath.Base(name) != name {
+		return "", fmt.Errorf("package %s in version %s has an invalid name", pv.Name, pv.Version)
+	}
+	return name, nil
+}
+
+// mirroredPackageVersion returns the entry of the package in the index of the mirror
+func mirroredPackageVersion(pv *PackageVersion, url, name, digest string) *PackageVersion {
+	if url == "" {
+		url = defaultURL
+	}
+	metadata := *pv.Metadata
+	return &PackageVersion{
+		Metadata: &metadata,
+		URLs:     []string{strings.TrimSuffix(url, "/") + "/" + name},
+		Digest:   digest,
+	}
+}
@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// repoServer serves an index with the given package versions, each package contains its name
+func repoServer(t *testing.T, digests map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/index.yaml" {
+			fmt.Fprint(w, strings.TrimPrefix(r.URL.Path, "/"))
+			return
+		}
+		index := &IndexFile{APIVersion: "v1"}
+		for _, pv := range []struct{ name, version string }{{"kafka", "0.9.0"}, {"kafka", "1.0.0"}, {"kafka", "1.1.0"}, {"zookeeper", "0.3.0"}} {
+			name := fmt.Sprintf("%s-%s.tgz", pv.name, pv.version)
+			assert.NoError(t, index.AddPackageVersion(&PackageVersion{
+				Metadata: &Metadata{Name: pv.name, Version: pv.version},
+				URLs:     []string{server.URL + "/" + name},
+				Digest:   digests[name],
+			}))
+		}
+		assert.NoError(t, index.Write(w))
+	}))
+	return server
+}
+
+func sha256Sum(s string) string {
+	sum, _ := files.Sha256Sum(bytes.NewReader([]byte(s)))
+	return sum
+}
+
+func TestParseMirrorFilter(t *testing.T) {
+	f, err := ParseMirrorFilter("kafka")
+	assert.NoError(t, err)
+	assert.Equal(t, "kafka", f.Operator)
+	assert.Nil(t, f.Constraint)
+
+	f, err = ParseMirrorFilter("kafka@>=1.0.0, <2.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "kafka", f.Operator)
+	assert.NotNil(t, f.Constraint)
+
+	_, err = ParseMirrorFilter("@1.0.0")
+	assert.EqualError(t, err, `operator filter "@1.0.0" has no operator name`)
+
+	_, err = ParseMirrorFilter("kafka@foo")
+	assert.Error(t, err)
+}
+
+func TestMirror(t *testing.T) {
+	server := repoServer(t, map[string]string{"kafka-1.0.0.tgz": sha256Sum("kafka-1.0.0.tgz")})
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, fs.MkdirAll("/mirror", 0755))
+	filter, err := ParseMirrorFilter("kafka@>=1.0.0")
+	assert.NoError(t, err)
+	now := time.Now()
+
+	client, err := NewClient(&Configuration{Name: "test", URL: server.URL})
+	assert.NoError(t, err)
+	index, err := client.Mirror(fs, "/mirror", "https://mirror.example.com/", []MirrorFilter{filter}, false, &now)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"kafka"}, keys(index.Entries))
+	assert.Equal(t, 2, len(index.Entries["kafka"]))
+	for _, pv := range index.Entries["kafka"] {
+		name := fmt.Sprintf("kafka-%s.tgz", pv.Version)
+		assert.Equal(t, []string{"https://mirror.example.com/" + name}, pv.URLs)
+		assert.Equal(t, sha256Sum(name), pv.Digest)
+
+		content, err := afero.ReadFile(fs, "/mirror/"+name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, string(content))
+	}
+	exists, _ := afero.Exists(fs, "/mirror/kafka-0.9.0.tgz")
+	assert.False(t, exists)
+
+	_, err = client.Mirror(fs, "/mirror", "", []MirrorFilter{filter}, false, &now)
+	assert.EqualError(t, err, `target file "/mirror/kafka-1.1.0.tgz" already exists`)
+}
+
+func TestMirror_DigestMismatch(t *testing.T) {
+	server := repoServer(t, map[string]string{"zookeeper-0.3.0.tgz": sha256Sum("something else")})
+	defer server.Close()
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, fs.MkdirAll("/mirror", 0755))
+	now := time.Now()
+
+	client, err := NewClient(&Configuration{Name: "test", URL: server.URL})
+	assert.NoError(t, err)
+	_, err = client.Mirror(fs, "/mirror", "", []MirrorFilter{{Operator: "zookeeper"}}, false, &now)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum mismatch")
+}
+
+func TestMirrorPackageName(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		file    string
+	}{
+		{name: "kafka", version: "1.0.0", file: "kafka-1.0.0.tgz"},
+		{name: "../../etc/cron.d/kafka", version: "1.0.0"},
+		{name: "kafka", version: "1.0.0/../../../evil"},
+		{name: `operators\kafka`, version: "1.0.0"},
+		{name: "kafka", version: ".."},
+	}
+	for _, tt := range tests {
+		file, err := mirrorPackageName(&PackageVersion{Metadata: &Metadata{Name: tt.name, Version: tt.version}})
+		if tt.file == "" {
+			assert.Error(t, err, "%s %s", tt.name, tt.version)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tt.file, file)
+	}
+}
+
+func keys(entries map[string]PackageVersions) []string {
+	var names []string
+	for name := range entries {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+	kudohttp "github.com/kudobuilder/kudo/pkg/kudoctl/http"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages"
+
+	"github.com/spf13/afero"
+)
+
+// maxIndexUpdates is the number of times Publish tries to update an index file that is modified concurrently
+const maxIndexUpdates = 5
+
+// Publish adds the package at the path to the index file of the repository and uploads the package and the updated
+// index file with the uploader. The package is referenced at the URL of the repository, a new index file is created if
+// the repository has none yet. A version that is already published can't be published again.
+// If the uploader is a ConditionalUploader, the index file is read from the destination and only replaced if nobody
+// else changed it in the meantime, otherwise Publish starts over with the changed index file.
+func (c *Client) Publish(fs afero.Fs, path string, uploader Uploader, now *time.Time) (*PackageVersion, error) {
+	b, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := files.Sha256Sum(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	pf, err := packages.NewFromBytes(bytes.NewBuffer(b)).GetPkgFiles()
+	if err != nil {
+		return nil, fmt.Errorf("invalid package %s: %w", path, err)
+	}
+	pv := ToPackageVersion(pf, digest, c.Config.URL)
+	pv.Created = now
+	name := fmt.Sprintf("%s-%v.tgz", pf.Operator.Name, pf.Operator.Version)
+
+	cu, ok := uploader.(ConditionalUploader)
+	if !ok {
+		clog.Printf("WARNING: %s does not support conditional uploads, concurrent publications may overwrite each other's changes of the index file", uploader)
+		index, err := c.publishedIndex(now)
+		if err != nil {
+			return nil, err
+		}
+		indexBytes, err := addToIndex(index, pv, path, now)
+		if err != nil {
+			return nil, err
+		}
+		if err := uploadPackage(uploader, name, b); err != nil {
+			return nil, err
+		}
+		clog.V(2).Printf("uploading index.yaml to %s", uploader)
+		if err := uploader.Upload("index.yaml", indexBytes); err != nil {
+			return nil, fmt.Errorf("failed to upload index.yaml: %w", err)
+		}
+		return pv, nil
+	}
+
+	for i := 1; ; i++ {
+		current, version, err := cu.Read("index.yaml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the index file of %s: %w", uploader, err)
+		}
+		index := newIndexFile(now)
+		if version == "" {
+			clog.Printf("repository %s has no index file yet, creating a new one", c.Config.URL)
+		} else if index, err = ParseIndexFile(current); err != nil {
+			return nil, err
+		}
+		indexBytes, err := addToIndex(index, pv, path, now)
+		if err != nil {
+			return nil, err
+		}
+		if i == 1 {
+			if err := uploadPackage(uploader, name, b); err != nil {
+				return nil, err
+			}
+		}
+		clog.V(2).Printf("uploading index.yaml to %s", uploader)
+		err = cu.UploadIfMatch("index.yaml", indexBytes, version)
+		switch {
+		case err == nil:
+			return pv, nil
+		case !errors.Is(err, errModified):
+			return nil, fmt.Errorf("failed to upload index.yaml: %w", err)
+		case i == maxIndexUpdates:
+			return nil, fmt.Errorf("failed to upload index.yaml, it was modified concurrently %d times", i)
+		}
+		clog.V(2).Printf("index.yaml of %s was modified concurrently, updating it again", uploader)
+	}
+}
+
+// addToIndex adds the package version at the path to the index and returns the updated index file
+func addToIndex(index *IndexFile, pv *PackageVersion, path string, now *time.Time) ([]byte, error) {
+	if err := index.AddPackageVersion(pv); err != nil {
+		return nil, fmt.Errorf("failed to publish %s: %v", path, err)
+	}
+	index.Generated = now
+	index.sortPackages()
+	var buf bytes.Buffer
+	if err := index.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// uploadPackage uploads the package before the index file, so that the index file never references a missing package
+func uploadPackage(uploader Uploader, name string, content []byte) error {
+	clog.V(2).Printf("uploading %s to %s", name, uploader)
+	if err := uploader.Upload(name, content); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	return nil
+}
+
+// publishedIndex downloads the index file of the repository or returns a new one if the repository has none yet
+func (c *Client) publishedIndex(now *time.Time) (*IndexFile, error) {
+	indexURL, err := c.indexURL()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Get(indexURL)
+	var statusErr *kudohttp.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		clog.Printf("repository %s has no index file yet, creating a new one", c.Config.URL)
+		return newIndexFile(now), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the index file of %s: %w", c.Config.URL, err)
+	}
+	indexBytes, err := ioutil.ReadAll(resp)
+	if err != nil {
+		return nil, err
+	}
+	return ParseIndexFile(indexBytes)
+}
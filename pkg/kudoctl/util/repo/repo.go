@@ -183,9 +183,27 @@ type Metadata struct {
 	// AppVersion is the underlying service version (the format is not in our control)
 	AppVersion string `json:"appVersion,omitempty"`
 
+	// KUDOVersion is the minimum version of KUDO the operator requires.
+	KUDOVersion string `json:"kudoVersion,omitempty"`
+
+	// KubernetesVersion is the minimum version of Kubernetes the operator requires.
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+
 	// Description is a one-sentence description of the operator.
 	Description string `json:"description,omitempty"`
 
 	// Maintainers is a list of name and URL/email addresses of the maintainer(s).
 	Maintainers []*v1alpha1.Maintainer `json:"maintainers,omitempty"`
+
+	// Icon is the URL of an icon representing the operator.
+	Icon string `json:"icon,omitempty"`
+
+	// Keywords are used to find the operator.
+	Keywords []string `json:"keywords,omitempty"`
+
+	// Categories the operator is listed under.
+	Categories []string `json:"categories,omitempty"`
+
+	// Links is a list of names and URLs of additional resources like documentation.
+	Links []*v1alpha1.Link `json:"links,omitempty"`
 }
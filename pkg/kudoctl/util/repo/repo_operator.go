@@ -58,14 +58,10 @@ func NewClient(conf *Configuration) (*Client, error) {
 
 // DownloadIndexFile fetches the index file from a repository.
 func (c *Client) DownloadIndexFile() (*IndexFile, error) {
-	var indexURL string
-	parsedURL, err := url.Parse(c.Config.URL)
+	indexURL, err := c.indexURL()
 	if err != nil {
-		return nil, errors.Wrap(err, "parsing config url")
+		return nil, err
 	}
-	parsedURL.Path = fmt.Sprintf("%s/index.yaml", strings.TrimSuffix(parsedURL.Path, "/"))
-
-	indexURL = parsedURL.String()
 
 	resp, err := c.Client.Get(indexURL)
 	if err != nil {
@@ -81,14 +77,25 @@ func (c *Client) DownloadIndexFile() (*IndexFile, error) {
 	return indexFile, err
 }
 
+// indexURL returns the URL of the index file of the repository
+func (c *Client) indexURL() (string, error) {
+	parsedURL, err := url.Parse(c.Config.URL)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing config url")
+	}
+	parsedURL.Path = fmt.Sprintf("%s/index.yaml", strings.TrimSuffix(parsedURL.Path, "/"))
+	return parsedURL.String(), nil
+}
+
 // getPackageReaderByAPackageURL downloads the tgz file from the remote repository and returns a reader
 // The PackageVersion is a package configuration from the index file which has a list of urls where
 // the package can be pulled from.  This will cycle through the list of urls and will return the reader
-// from the first successful url.  If all urls fail, the last error will be returned.
+// from the first successful url.  If all urls fail, the last error will be returned. The downloaded package is verified
+// against the digest of the index file.
 func (c *Client) getPackageReaderByAPackageURL(pkg *PackageVersion) (*bytes.Buffer, error) {
 	var pkgErr error
 	for _, u := range pkg.URLs {
-		r, err := c.getPackageBytesByURL(u)
+		r, err := c.getPackageBytesByURL(u, pkg.Digest)
 		if err == nil {
 			return r, nil
 		}
@@ -99,9 +106,9 @@ func (c *Client) getPackageReaderByAPackageURL(pkg *PackageVersion) (*bytes.Buff
 	return nil, pkgErr
 }
 
-func (c *Client) getPackageBytesByURL(packageURL string) (*bytes.Buffer, error) {
+func (c *Client) getPackageBytesByURL(packageURL string, digest string) (*bytes.Buffer, error) {
 	clog.V(4).Printf("attempt to retrieve package from url: %v", packageURL)
-	resp, err := c.Client.Get(packageURL)
+	resp, err := c.Client.Download(packageURL, digest)
 	if err != nil {
 		return nil, errors.Wrap(err, "getting package url")
 	}
@@ -123,6 +130,9 @@ func (c *Client) GetPackageBytes(name string, version string) (*bytes.Buffer, er
 	if err != nil {
 		return nil, errors.Wrapf(err, "getting %s in index file", name)
 	}
+	if pkgVersion.Deprecated {
+		clog.Printf("WARNING: version %s of operator %s is deprecated", pkgVersion.Version, name)
+	}
 
 	return c.getPackageReaderByAPackageURL(pkgVersion)
 }
@@ -0,0 +1,418 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/clog"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/spf13/afero"
+	"golang.org/x/oauth2/google"
+)
+
+// gcsScope is the OAuth scope required to write objects to a Google Cloud Storage bucket
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// errModified is returned by a conditional upload if the file was changed since it was read
+var errModified = errors.New("the file was modified concurrently")
+
+// Uploader stores the files of a repository in its backend, e.g. a bucket or a web server
+type Uploader interface {
+	fmt.Stringer
+	// Upload stores the content as the file with the name at the root of the repository
+	Upload(name string, content []byte) error
+}
+
+// ConditionalUploader is an Uploader that only replaces a file if it did not change since it was read, so that
+// concurrent publications to a repository don't overwrite each other's changes of the index file
+type ConditionalUploader interface {
+	Uploader
+	// Read returns the content of the file with the name and its version, which is empty if the file does not exist
+	Read(name string) (content []byte, version string, err error)
+	// UploadIfMatch stores the content as the file with the name if the file is still at the version returned by
+	// Read, an empty version only creates a file that does not exist yet. It returns errModified if the file was
+	// changed in the meantime.
+	UploadIfMatch(name string, content []byte, version string) error
+}
+
+// NewUploader returns the uploader for the destination of a repository. The destination is either a local directory,
+// e.g. a worktree of the gh-pages branch of a repository served by GitHub Pages, or a URL. A s3://<bucket>/<prefix> URL
+// uploads to an AWS S3 bucket, see newS3Uploader. A gs://<bucket>/<prefix> URL uploads to a Google Cloud Storage bucket
+// using the application default credentials. An http(s) URL uploads with PUT requests, using basic authentication if
+// the KUDO_PUBLISH_USERNAME or KUDO_PUBLISH_PASSWORD environment variable is set. A sftp://[user@]host[:port]/<path>
+// URL uploads with the sftp command in batch mode. Credentials are never accepted in the URL, as it ends up in logs
+// and error messages.
+func NewUploader(fs afero.Fs, destination string) (Uploader, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		// the error of url.Parse contains the URL, which may contain credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("invalid destination: %w", err)
+	}
+	if _, ok := u.User.Password(); ok || (u.User != nil && u.Scheme != "sftp") {
+		return nil, fmt.Errorf("the destination URL must not contain credentials, " +
+			"use the KUDO_PUBLISH_USERNAME and KUDO_PUBLISH_PASSWORD environment variables for http(s) destinations")
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "", "file":
+		return &dirUploader{fs: fs, path: u.Path}, nil
+	case "s3":
+		return newS3Uploader(u.Host, prefix)
+	case "gs":
+		client, err := google.DefaultClient(context.Background(), gcsScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the credentials for Google Cloud Storage: %w", err)
+		}
+		return &gcsUploader{client: client, endpoint: "https://storage.googleapis.com", bucket: u.Host, prefix: prefix}, nil
+	case "http", "https":
+		return &httpUploader{
+			client:   http.DefaultClient,
+			base:     u,
+			username: os.Getenv("KUDO_PUBLISH_USERNAME"),
+			password: os.Getenv("KUDO_PUBLISH_PASSWORD"),
+		}, nil
+	case "sftp":
+		// sftp would parse them as options
+		if strings.HasPrefix(u.Hostname(), "-") || strings.HasPrefix(u.User.Username(), "-") {
+			return nil, fmt.Errorf("host and user of the sftp destination must not start with '-'")
+		}
+		return &sftpUploader{host: u.Hostname(), port: u.Port(), user: u.User.Username(), path: u.Path}, nil
+	default:
+		return nil, fmt.Errorf("unsupported destination %s, expected a local directory or a s3, gs, http(s) or sftp URL", destination)
+	}
+}
+
+// dirUploader writes the files into a local directory
+type dirUploader struct {
+	fs   afero.Fs
+	path string
+}
+
+func (u *dirUploader) String() string {
+	return u.path
+}
+
+func (u *dirUploader) Upload(name string, content []byte) error {
+	if err := u.fs.MkdirAll(u.path, 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(u.fs, filepath.Join(u.path, name), content, 0644)
+}
+
+// httpUploader uploads the files with PUT requests below a base URL. Conditional uploads use the ETag of the files.
+type httpUploader struct {
+	client   *http.Client
+	base     *url.URL
+	username string
+	password string
+}
+
+func (u *httpUploader) String() string {
+	return u.base.String()
+}
+
+func (u *httpUploader) Upload(name string, content []byte) error {
+	return u.UploadIfMatch(name, content, "*")
+}
+
+func (u *httpUploader) Read(name string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, objectURL(u.base.String(), name), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	u.authenticate(req)
+	return get(u.client, req, "ETag")
+}
+
+func (u *httpUploader) UploadIfMatch(name string, content []byte, version string) error {
+	req, err := newPutRequest(objectURL(u.base.String(), name), name, content)
+	if err != nil {
+		return err
+	}
+	setIfMatch(req, version)
+	u.authenticate(req)
+	return put(u.client, req)
+}
+
+func (u *httpUploader) authenticate(req *http.Request) {
+	if u.username != "" || u.password != "" {
+		req.SetBasicAuth(u.username, u.password)
+	}
+}
+
+// gcsUploader uploads the files to a Google Cloud Storage bucket with the XML API. The client authenticates the
+// requests. Conditional uploads use the generation of the objects.
+type gcsUploader struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+	prefix   string
+}
+
+func (u *gcsUploader) String() string {
+	return fmt.Sprintf("gs://%s/%s", u.bucket, u.prefix)
+}
+
+func (u *gcsUploader) Upload(name string, content []byte) error {
+	req, err := newPutRequest(objectURL(u.endpoint, u.bucket, u.prefix, name), name, content)
+	if err != nil {
+		return err
+	}
+	return put(u.client, req)
+}
+
+func (u *gcsUploader) Read(name string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, objectURL(u.endpoint, u.bucket, u.prefix, name), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return get(u.client, req, "X-Goog-Generation")
+}
+
+func (u *gcsUploader) UploadIfMatch(name string, content []byte, version string) error {
+	req, err := newPutRequest(objectURL(u.endpoint, u.bucket, u.prefix, name), name, content)
+	if err != nil {
+		return err
+	}
+	// generation 0 matches only if the object does not exist
+	if version == "" {
+		version = "0"
+	}
+	req.Header.Set("X-Goog-If-Generation-Match", version)
+	return put(u.client, req)
+}
+
+// s3Uploader uploads the files to an AWS S3 bucket with requests signed with AWS signature version 4. Conditional
+// uploads use the ETag of the objects.
+type s3Uploader struct {
+	client   *http.Client
+	endpoint string
+	bucket   string
+	prefix   string
+	region   string
+	signer   *v4.Signer
+	now      func() time.Time
+}
+
+// newS3Uploader returns an uploader for the bucket. The credentials are read from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and the optional AWS_SESSION_TOKEN environment variables or else from the shared credentials
+// file of the AWS CLI, the region from AWS_REGION or AWS_DEFAULT_REGION. AWS_ENDPOINT_URL sets the endpoint of an S3
+// compatible storage, e.g. MinIO.
+func newS3Uploader(bucket, prefix string) (*s3Uploader, error) {
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvProvider{},
+		&credentials.SharedCredentialsProvider{},
+	})
+	if _, err := creds.Get(); err != nil {
+		return nil, fmt.Errorf("no AWS credentials found in the environment or the shared credentials file to upload to S3")
+	}
+	u := &s3Uploader{
+		client: http.DefaultClient,
+		bucket: bucket,
+		prefix: prefix,
+		region: os.Getenv("AWS_REGION"),
+		signer: v4.NewSigner(creds),
+		now:    time.Now,
+	}
+	if u.region == "" {
+		u.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if u.region == "" {
+		u.region = "us-east-1"
+	}
+	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+		u.endpoint = objectURL(endpoint, bucket)
+	} else {
+		u.endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, u.region)
+	}
+	return u, nil
+}
+
+func (u *s3Uploader) String() string {
+	return fmt.Sprintf("s3://%s/%s", u.bucket, u.prefix)
+}
+
+func (u *s3Uploader) Upload(name string, content []byte) error {
+	return u.UploadIfMatch(name, content, "*")
+}
+
+func (u *s3Uploader) Read(name string) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, objectURL(u.endpoint, u.prefix, name), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := u.signer.Sign(req, nil, "s3", u.region, u.now()); err != nil {
+		return nil, "", fmt.Errorf("failed to sign the request: %w", err)
+	}
+	return get(u.client, req, "ETag")
+}
+
+func (u *s3Uploader) UploadIfMatch(name string, content []byte, version string) error {
+	req, err := newPutRequest(objectURL(u.endpoint, u.prefix, name), name, content)
+	if err != nil {
+		return err
+	}
+	setIfMatch(req, version)
+	if _, err := u.signer.Sign(req, bytes.NewReader(content), "s3", u.region, u.now()); err != nil {
+		return fmt.Errorf("failed to sign the request: %w", err)
+	}
+	return put(u.client, req)
+}
+
+// sftpUploader uploads the files into a directory on an SFTP server with the sftp command in batch mode, which
+// requires an authentication that does not prompt, e.g. a key of the ssh agent. SFTP has no conditional writes, so it
+// is not a ConditionalUploader: the index file is replaced unconditionally and a concurrent publication to the same
+// repository can overwrite the changes of another one.
+type sftpUploader struct {
+	host string
+	port string
+	user string
+	path string
+}
+
+func (u *sftpUploader) String() string {
+	return fmt.Sprintf("sftp://%s%s", u.target(), u.path)
+}
+
+func (u *sftpUploader) target() string {
+	if u.user == "" {
+		return u.host
+	}
+	return u.user + "@" + u.host
+}
+
+func (u *sftpUploader) Upload(name string, content []byte) (err error) {
+	f, err := ioutil.TempFile("", "kudo-publish-")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := os.Remove(f.Name()); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	if _, err := f.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	args := []string{"-b", "-"}
+	if u.port != "" {
+		args = append(args, "-P", u.port)
+	}
+	cmd := exec.Command("sftp", append(args, "--", u.target())...)
+	// a leading '-' ignores the error if the directory exists
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("-mkdir %q\nput %q %q\n", u.path, f.Name(), path.Join(u.path, name)))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sftp failed: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// newPutRequest returns a request to store the named file at the URL. The index file must not be cached, so that
+// published packages are visible immediately.
+func newPutRequest(href, name string, content []byte) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPut, href, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(name, ".tgz") {
+		req.Header.Set("Content-Type", "application/gzip")
+	} else {
+		req.Header.Set("Content-Type", "application/x-yaml")
+		req.Header.Set("Cache-Control", "no-cache")
+	}
+	return req, nil
+}
+
+// setIfMatch makes the request conditional on the version of the file, "*" stores the file unconditionally and an
+// empty version only if the file does not exist
+func setIfMatch(req *http.Request, version string) {
+	switch version {
+	case "*":
+	case "":
+		req.Header.Set("If-None-Match", "*")
+	default:
+		req.Header.Set("If-Match", version)
+	}
+}
+
+// get sends the request and returns the content and the version, read from the header, of the requested file. A file
+// that does not exist has neither content nor version.
+func get(client *http.Client, req *http.Request, versionHeader string) ([]byte, string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			clog.V(4).Printf("error when closing the response body %s", err)
+		}
+	}()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, "", nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, "", fmt.Errorf("GET %s: %s %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+	case resp.Header.Get(versionHeader) == "":
+		return nil, "", fmt.Errorf("GET %s: the response has no %s header to upload conditionally", req.URL, versionHeader)
+	}
+	return body, resp.Header.Get(versionHeader), nil
+}
+
+// put sends the request and returns an error if the server did not accept it, errModified if a precondition of the
+// request failed
+func put(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			clog.V(4).Printf("error when closing the response body %s", err)
+		}
+	}()
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("PUT %s: %w", req.URL, errModified)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("PUT %s: %s %s", req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// objectURL joins the base URL and the non-empty path elements
+func objectURL(base string, elems ...string) string {
+	href := strings.TrimSuffix(base, "/")
+	for _, e := range elems {
+		if e = strings.Trim(e, "/"); e != "" {
+			href += "/" + e
+		}
+	}
+	return href
+}
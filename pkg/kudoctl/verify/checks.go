@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var (
+	kudoResources = []string{"operators", "operatorversions", "instances"}
+	kudoVerbs     = []string{"get", "list", "create", "update", "delete"}
+)
+
+func verifyCRDs(client *kube.Client, opts Options) error {
+	for _, r := range kudoResources {
+		name := fmt.Sprintf("%s.kudo.dev", r)
+		crd, err := client.ExtClient.ApiextensionsV1beta1().CustomResourceDefinitions().Get(name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return fmt.Errorf("crd %s is not installed", name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get crd %s: %w", name, err)
+		}
+		for _, c := range crd.Status.Conditions {
+			if c.Type == apiextv1beta1.Established && c.Status == apiextv1beta1.ConditionFalse {
+				return fmt.Errorf("crd %s is not established: %s", name, c.Message)
+			}
+		}
+	}
+	return nil
+}
+
+func verifyManager(client *kube.Client, opts Options) error {
+	if _, err := kudoinit.GetKUDOPodImage(client.KubeClient.CoreV1(), opts.ManagerNamespace); err != nil {
+		return fmt.Errorf("%v in namespace %s", err, opts.ManagerNamespace)
+	}
+	return nil
+}
+
+func verifyWebhook(client *kube.Client, opts Options) error {
+	endpoints, err := client.KubeClient.CoreV1().Endpoints(opts.ManagerNamespace).Get(kudoinit.ServiceName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return fmt.Errorf("service %s/%s has no endpoints", opts.ManagerNamespace, kudoinit.ServiceName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get endpoints of service %s/%s: %w", opts.ManagerNamespace, kudoinit.ServiceName, err)
+	}
+	for _, s := range endpoints.Subsets {
+		if len(s.Addresses) > 0 && len(s.Ports) > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s/%s has no ready endpoints", opts.ManagerNamespace, kudoinit.ServiceName)
+}
+
+func verifyRBAC(client *kube.Client, opts Options) error {
+	var denied []string
+	for _, r := range kudoResources {
+		for _, verb := range kudoVerbs {
+			review := &authv1.SelfSubjectAccessReview{
+				Spec: authv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authv1.ResourceAttributes{
+						Namespace: opts.Namespace,
+						Verb:      verb,
+						Group:     "kudo.dev",
+						Resource:  r,
+					},
+				},
+			}
+			result, err := client.KubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+			if err != nil {
+				return fmt.Errorf("failed to review access to %s: %w", r, err)
+			}
+			if !result.Status.Allowed {
+				denied = append(denied, fmt.Sprintf("%s %s", verb, r))
+			}
+		}
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("not allowed to %s in namespace %s", strings.Join(denied, ", "), opts.Namespace)
+	}
+	return nil
+}
+
+// verifyImageRegistry checks the images of the pods of all instances in the namespace. A pod has to pull its images
+// from the registry it was redirected to by the manager, pods that were not redirected are checked against the
+// registry of the options.
+func verifyImageRegistry(client *kube.Client, opts Options) error {
+	pods, err := client.KubeClient.CoreV1().Pods(opts.Namespace).List(metav1.ListOptions{LabelSelector: kudo.InstanceLabel})
+	if err != nil {
+		return fmt.Errorf("failed to list pods of instances: %w", err)
+	}
+	var wrong []string
+	for _, pod := range pods.Items {
+		registry := opts.ImageRegistry
+		if r, ok := pod.Annotations[v1alpha1.ImageRegistryAnnotation]; ok {
+			registry = r
+		}
+		settings := v1alpha1.ImageSettings{Registry: registry}
+		containers := append(append([]v1.Container{}, pod.Spec.InitContainers...), pod.Spec.Containers...)
+		for _, c := range containers {
+			if settings.Image(c.Image) != c.Image {
+				wrong = append(wrong, fmt.Sprintf("%s/%s (%s)", pod.Name, c.Name, c.Image))
+			}
+		}
+	}
+	if len(wrong) > 0 {
+		return fmt.Errorf("containers in namespace %s do not pull from the configured registry: %s", opts.Namespace, strings.Join(wrong, ", "))
+	}
+	return nil
+}
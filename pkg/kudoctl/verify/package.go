@@ -0,0 +1,205 @@
+package verify
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// podSpecPaths are the paths of the pod specs of pods and of the pod templates of the workload types
+var podSpecPaths = [][]string{
+	{"spec"},
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// CheckPackage checks that the cluster can run the rendered templates of a package: the kinds of all objects have to
+// be served by the cluster or be defined by the CRDs bundled with the package, and the storage classes and priority
+// classes the objects use have to exist. The problems are returned sorted by template.
+func CheckPackage(client *kube.Client, rendered map[string]string, crds map[string]string) ([]string, error) {
+	bundled, err := bundledKinds(crds)
+	if err != nil {
+		return nil, err
+	}
+	c := &clusterLookup{client: client, kinds: map[string]map[string]bool{}, errs: map[string]error{}}
+
+	names := make([]string, 0, len(rendered))
+	for name := range rendered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	for _, name := range names {
+		objs, err := parseObjects(rendered[name])
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("template %s is not valid YAML: %v", name, err))
+			continue
+		}
+		for _, obj := range objs {
+			for _, p := range c.check(obj, bundled) {
+				problems = append(problems, fmt.Sprintf("template %s: %s", name, p))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// bundledKinds returns the group kinds defined by the CRDs bundled with a package, they are applied before the
+// templates and do not have to be served by the cluster yet
+func bundledKinds(crds map[string]string) (map[schema.GroupKind]bool, error) {
+	kinds := map[schema.GroupKind]bool{}
+	for name, manifest := range crds {
+		var crd apiextv1beta1.CustomResourceDefinition
+		if err := yaml.Unmarshal([]byte(manifest), &crd); err != nil {
+			return nil, fmt.Errorf("crd %s is invalid: %v", name, err)
+		}
+		kinds[schema.GroupKind{Group: crd.Spec.Group, Kind: crd.Spec.Names.Kind}] = true
+	}
+	return kinds, nil
+}
+
+// parseObjects parses all documents of a rendered template, empty documents are skipped
+func parseObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	var objs []*unstructured.Unstructured
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(manifest), 4096)
+	for {
+		content := map[string]interface{}{}
+		if err := decoder.Decode(&content); err == io.EOF {
+			return objs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(content) > 0 {
+			objs = append(objs, &unstructured.Unstructured{Object: content})
+		}
+	}
+}
+
+// clusterLookup looks up kinds and classes in the cluster. The discovered kinds are cached, templates often share
+// them.
+type clusterLookup struct {
+	client *kube.Client
+	// kinds are the kinds served by each group version, errs the errors of the group versions that could not be
+	// discovered
+	kinds map[string]map[string]bool
+	errs  map[string]error
+}
+
+func (c *clusterLookup) check(obj *unstructured.Unstructured, bundled map[schema.GroupKind]bool) []string {
+	var problems []string
+	id := fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	if p := c.checkKind(obj, bundled); p != "" {
+		problems = append(problems, fmt.Sprintf("%s %s", id, p))
+	}
+	for _, class := range storageClasses(obj) {
+		if p := c.checkExists("storage class", class, func() error {
+			_, err := c.client.KubeClient.StorageV1().StorageClasses().Get(class, metav1.GetOptions{})
+			return err
+		}); p != "" {
+			problems = append(problems, fmt.Sprintf("%s uses %s", id, p))
+		}
+	}
+	for _, class := range priorityClasses(obj) {
+		if p := c.checkExists("priority class", class, func() error {
+			_, err := c.client.KubeClient.SchedulingV1().PriorityClasses().Get(class, metav1.GetOptions{})
+			return err
+		}); p != "" {
+			problems = append(problems, fmt.Sprintf("%s uses %s", id, p))
+		}
+	}
+	return problems
+}
+
+// checkKind returns a problem if the kind of the object is neither served by the cluster nor bundled with the package
+func (c *clusterLookup) checkKind(obj *unstructured.Unstructured, bundled map[schema.GroupKind]bool) string {
+	gvk := obj.GroupVersionKind()
+	if gvk.Kind == "" || obj.GetAPIVersion() == "" {
+		return "has no apiVersion or kind"
+	}
+	if bundled[gvk.GroupKind()] {
+		return ""
+	}
+
+	gv := gvk.GroupVersion().String()
+	if _, ok := c.kinds[gv]; !ok && c.errs[gv] == nil {
+		resources, err := c.client.KubeClient.Discovery().ServerResourcesForGroupVersion(gv)
+		if err != nil {
+			c.errs[gv] = err
+		} else {
+			c.kinds[gv] = map[string]bool{}
+			for _, r := range resources.APIResources {
+				// subresources like deployments/scale report the kind of their parent or of the subresource
+				if !strings.Contains(r.Name, "/") {
+					c.kinds[gv][r.Kind] = true
+				}
+			}
+		}
+	}
+	switch err := c.errs[gv]; {
+	case kerrors.IsNotFound(err):
+		return fmt.Sprintf("uses apiVersion %s which is not served by the cluster", gv)
+	case err != nil:
+		return fmt.Sprintf("uses apiVersion %s which could not be discovered: %v", gv, err)
+	case !c.kinds[gv][gvk.Kind]:
+		return fmt.Sprintf("has kind %s which is not served by the cluster in apiVersion %s", gvk.Kind, gv)
+	}
+	return ""
+}
+
+// checkExists returns a problem if the named cluster scoped object does not exist
+func (c *clusterLookup) checkExists(kind, name string, get func() error) string {
+	err := get()
+	switch {
+	case kerrors.IsNotFound(err):
+		return fmt.Sprintf("%s %s which does not exist", kind, name)
+	case err != nil:
+		return fmt.Sprintf("%s %s which could not be read: %v", kind, name, err)
+	}
+	return ""
+}
+
+// storageClasses returns the storage classes of a PersistentVolumeClaim or of the volume claim templates of a
+// StatefulSet. Claims without a class use the default class and are not checked.
+func storageClasses(obj *unstructured.Unstructured) []string {
+	var classes []string
+	switch obj.GetKind() {
+	case "PersistentVolumeClaim":
+		if class, _, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName"); class != "" {
+			classes = append(classes, class)
+		}
+	case "StatefulSet":
+		templates, _, _ := unstructured.NestedSlice(obj.Object, "spec", "volumeClaimTemplates")
+		for _, t := range templates {
+			if claim, ok := t.(map[string]interface{}); ok {
+				if class, _, _ := unstructured.NestedString(claim, "spec", "storageClassName"); class != "" {
+					classes = append(classes, class)
+				}
+			}
+		}
+	}
+	return classes
+}
+
+// priorityClasses returns the priority classes of the pod specs of the object
+func priorityClasses(obj *unstructured.Unstructured) []string {
+	var classes []string
+	for _, path := range podSpecPaths {
+		fields := append(append([]string{}, path...), "priorityClassName")
+		if class, _, _ := unstructured.NestedString(obj.Object, fields...); class != "" {
+			classes = append(classes, class)
+		}
+	}
+	return classes
+}
@@ -0,0 +1,103 @@
+// Package verify provides independent checks that a cluster is ready to be used with KUDO.
+package verify
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+)
+
+// Options defines the namespaces the checks are run against
+type Options struct {
+	// ManagerNamespace is the namespace the KUDO manager is running in
+	ManagerNamespace string
+	// Namespace is the namespace operators are installed into
+	Namespace string
+	// ImageRegistry is the registry of the KudoConfig the images of all instances are pulled from, empty if images
+	// are not redirected
+	ImageRegistry string
+}
+
+// Check is a single named verification of the cluster
+type Check struct {
+	Name   string
+	verify func(client *kube.Client, opts Options) error
+}
+
+// Result is the outcome of a single check. Err is nil if the check passed.
+type Result struct {
+	Check string
+	Err   error
+}
+
+// Passed returns true if the check did not find any problem
+func (r Result) Passed() bool {
+	return r.Err == nil
+}
+
+// Results is the outcome of a list of checks
+type Results []Result
+
+var (
+	// CRDsInstalled verifies that the KUDO CRDs are installed
+	CRDsInstalled = Check{Name: "CRDs installed", verify: verifyCRDs}
+	// ManagerHealthy verifies that a KUDO manager pod is running and ready
+	ManagerHealthy = Check{Name: "manager healthy", verify: verifyManager}
+	// WebhookReachable verifies that the webhook service of the KUDO manager has ready endpoints
+	WebhookReachable = Check{Name: "webhook reachable", verify: verifyWebhook}
+	// RBACSufficient verifies that the current user is allowed to manage KUDO objects
+	RBACSufficient = Check{Name: "RBAC sufficient", verify: verifyRBAC}
+	// ImageRegistryHonored verifies that the pods of the instances pull their images from the configured registry
+	ImageRegistryHonored = Check{Name: "image registry honored", verify: verifyImageRegistry}
+)
+
+// All returns all available checks
+func All() []Check {
+	return []Check{CRDsInstalled, ManagerHealthy, WebhookReachable, RBACSufficient, ImageRegistryHonored}
+}
+
+// Run runs all given checks. A failing check does not prevent the following checks from running.
+func Run(client *kube.Client, opts Options, checks ...Check) Results {
+	results := make(Results, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, Result{Check: c.Name, Err: c.verify(client, opts)})
+	}
+	return results
+}
+
+// Failed returns the results of the failed checks
+func (r Results) Failed() Results {
+	failed := Results{}
+	for _, result := range r {
+		if !result.Passed() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Err returns an error listing all failed checks or nil if all checks passed
+func (r Results) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(failed))
+	for i, result := range failed {
+		msgs[i] = fmt.Sprintf("%s: %v", result.Check, result.Err)
+	}
+	return fmt.Errorf("verification failed: %s", strings.Join(msgs, ", "))
+}
+
+// Print writes one line per result to the writer
+func (r Results) Print(out io.Writer) {
+	for _, result := range r {
+		if result.Passed() {
+			fmt.Fprintf(out, "✅ %s\n", result.Check)
+		} else {
+			fmt.Fprintf(out, "❌ %s: %v\n", result.Check, result.Err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kube"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/kudoinit"
+
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	apiextfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	testcore "k8s.io/client-go/testing"
+)
+
+func crd(name string) *apiextv1beta1.CustomResourceDefinition {
+	return &apiextv1beta1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func managerPod() *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kudo-controller-manager-0",
+			Namespace: "kudo-system",
+			Labels:    map[string]string{"app": "kudo-manager", "control-plane": "controller-manager", "controller-tools.k8s.io": "1.0"},
+		},
+		Spec:   v1.PodSpec{Containers: []v1.Container{{Name: "manager", Image: "kudobuilder/controller:v0.8.0"}}},
+		Status: v1.PodStatus{Conditions: []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}},
+	}
+}
+
+func webhookEndpoints() *v1.Endpoints {
+	return &v1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: kudoinit.ServiceName, Namespace: "kudo-system"},
+		Subsets: []v1.EndpointSubset{{
+			Addresses: []v1.EndpointAddress{{IP: "10.0.0.1"}},
+			Ports:     []v1.EndpointPort{{Name: "kudo", Port: 9876}},
+		}},
+	}
+}
+
+func allowAccess(allowed func(attr *authv1.ResourceAttributes) bool) testcore.ReactionFunc {
+	return func(action testcore.Action) (bool, runtime.Object, error) {
+		review := action.(testcore.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		review.Status.Allowed = allowed(review.Spec.ResourceAttributes)
+		return true, review, nil
+	}
+}
+
+func TestRun(t *testing.T) {
+	opts := Options{ManagerNamespace: "kudo-system", Namespace: "default"}
+
+	tests := []struct {
+		name     string
+		kube     []runtime.Object
+		ext      []runtime.Object
+		allowed  func(attr *authv1.ResourceAttributes) bool
+		expected map[string]string
+	}{
+		{
+			name:    "healthy installation",
+			kube:    []runtime.Object{managerPod(), webhookEndpoints()},
+			ext:     []runtime.Object{crd("operators.kudo.dev"), crd("operatorversions.kudo.dev"), crd("instances.kudo.dev")},
+			allowed: func(*authv1.ResourceAttributes) bool { return true },
+			expected: map[string]string{
+				"CRDs installed":         "",
+				"manager healthy":        "",
+				"webhook reachable":      "",
+				"RBAC sufficient":        "",
+				"image registry honored": "",
+			},
+		},
+		{
+			name: "nothing installed",
+			allowed: func(attr *authv1.ResourceAttributes) bool {
+				return attr.Verb != "delete" || attr.Resource != "instances"
+			},
+			expected: map[string]string{
+				"CRDs installed":         "crd operators.kudo.dev is not installed",
+				"manager healthy":        "could not find KUDO manager in namespace kudo-system",
+				"webhook reachable":      "service kudo-system/kudo-controller-manager-service has no endpoints",
+				"RBAC sufficient":        "not allowed to delete instances in namespace default",
+				"image registry honored": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		kc := fake.NewSimpleClientset(tt.kube...)
+		kc.PrependReactor("create", "selfsubjectaccessreviews", allowAccess(tt.allowed))
+		client := &kube.Client{KubeClient: kc, ExtClient: apiextfake.NewSimpleClientset(tt.ext...)}
+
+		results := Run(client, opts, All()...)
+		if len(results) != len(tt.expected) {
+			t.Fatalf("%s: expected %d results but got %d", tt.name, len(tt.expected), len(results))
+		}
+		for _, r := range results {
+			expected, ok := tt.expected[r.Check]
+			if !ok {
+				t.Errorf("%s: unexpected check %s", tt.name, r.Check)
+				continue
+			}
+			if expected == "" && !r.Passed() {
+				t.Errorf("%s: expected check %s to pass but got %v", tt.name, r.Check, r.Err)
+			}
+			if expected != "" && (r.Passed() || r.Err.Error() != expected) {
+				t.Errorf("%s: expected check %s to fail with %q but got %v", tt.name, r.Check, expected, r.Err)
+			}
+		}
+		if (results.Err() == nil) != (len(results.Failed()) == 0) {
+			t.Errorf("%s: error %v does not match failed checks %v", tt.name, results.Err(), results.Failed())
+		}
+	}
+}
+
+func TestVerifyImageRegistry(t *testing.T) {
+	pod := func(name string, annotations map[string]string, images ...string) *v1.Pod {
+		p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Labels:      map[string]string{"kudo.dev/instance": "kafka"},
+			Annotations: annotations,
+		}}
+		for i, image := range images {
+			p.Spec.Containers = append(p.Spec.Containers, v1.Container{Name: fmt.Sprintf("c%d", i), Image: image})
+		}
+		return p
+	}
+	unmanaged := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "unmanaged", Namespace: "default"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Image: "nginx"}}},
+	}
+
+	tests := []struct {
+		name     string
+		registry string
+		pods     []runtime.Object
+		err      string
+	}{
+		{name: "no registry", pods: []runtime.Object{pod("kafka-0", nil, "kafka:2.3")}},
+		{name: "redirected", registry: "mirror.local", pods: []runtime.Object{pod("kafka-0", nil, "mirror.local/kafka:2.3"), unmanaged}},
+		{
+			name:     "instance override",
+			registry: "mirror.local",
+			pods:     []runtime.Object{pod("kafka-0", map[string]string{"kudo.dev/image-registry": "team.registry"}, "team.registry/kafka:2.3")},
+		},
+		{
+			name:     "not redirected",
+			registry: "mirror.local",
+			pods:     []runtime.Object{pod("kafka-0", nil, "mirror.local/kafka:2.3", "quay.io/exporter:1")},
+			err:      "containers in namespace default do not pull from the configured registry: kafka-0/c1 (quay.io/exporter:1)",
+		},
+	}
+
+	for _, tt := range tests {
+		client := &kube.Client{KubeClient: fake.NewSimpleClientset(tt.pods...)}
+		err := verifyImageRegistry(client, Options{Namespace: "default", ImageRegistry: tt.registry})
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || err.Error() != tt.err) {
+			t.Errorf("%s: expected error %q but got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+func TestResults_Err(t *testing.T) {
+	results := Results{
+		{Check: "CRDs installed"},
+		{Check: "manager healthy", Err: errors.New("could not find KUDO manager")},
+	}
+	err := results.Err()
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	if !strings.Contains(err.Error(), "manager healthy: could not find KUDO manager") || strings.Contains(err.Error(), "CRDs installed") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	var out strings.Builder
+	results.Print(&out)
+	expected := "✅ CRDs installed\n❌ manager healthy: could not find KUDO manager\n"
+	if out.String() != expected {
+		t.Errorf("expected %q but got %q", expected, out.String())
+	}
+}
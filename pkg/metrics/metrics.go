@@ -0,0 +1,153 @@
+// Package metrics exports the state of the KUDO objects as Prometheus metrics, in the style of kube-state-metrics, so
+// that dashboards and alerts of KUDO-managed workloads can be built with existing monitoring stacks.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// DefaultPort is the port the manager serves its metrics at by default
+const DefaultPort = 8080
+
+var (
+	instanceInfo = prometheus.NewDesc("kudo_instance_info",
+		"Information about an instance and the operatorversion it uses.",
+		[]string{"namespace", "instance", "operator", "operatorversion", "operatorversion_namespace"}, nil)
+	instanceStatus = prometheus.NewDesc("kudo_instance_status",
+		"The aggregated status of an instance, 1 for the current status and 0 for all others.",
+		[]string{"namespace", "instance", "status"}, nil)
+	instancePlanStatus = prometheus.NewDesc("kudo_instance_plan_status",
+		"The status of a plan of an instance, 1 for the current status and 0 for all others.",
+		[]string{"namespace", "instance", "plan", "status"}, nil)
+	instancePlanLastFinished = prometheus.NewDesc("kudo_instance_plan_last_finished_timestamp_seconds",
+		"Unix timestamp of the last time a plan of an instance finished.",
+		[]string{"namespace", "instance", "plan"}, nil)
+	operatorVersionInfo = prometheus.NewDesc("kudo_operatorversion_info",
+		"Information about an operatorversion.",
+		[]string{"namespace", "operatorversion", "operator", "version", "app_version"}, nil)
+	operatorInfo = prometheus.NewDesc("kudo_operator_info",
+		"Information about an operator.",
+		[]string{"namespace", "operator", "kudo_version", "kubernetes_version"}, nil)
+)
+
+// statuses are the execution statuses reported for every plan, so that a change of the status is a change of the
+// values and not of the series
+var statuses = []v1alpha1.ExecutionStatus{
+	v1alpha1.ExecutionNeverRun,
+	v1alpha1.ExecutionPending,
+	v1alpha1.ExecutionInProgress,
+	v1alpha1.ExecutionComplete,
+	v1alpha1.ErrorStatus,
+	v1alpha1.ExecutionFatalError,
+}
+
+// Collector lists the Instances, OperatorVersions and Operators on every scrape and reports their state. It is meant
+// to be used with the cached client of the manager, so that a scrape does not reach the API server.
+type Collector struct {
+	client client.Client
+}
+
+// NewCollector creates a collector reading the KUDO objects with the client
+func NewCollector(c client.Client) *Collector {
+	return &Collector{client: c}
+}
+
+// Register registers a collector reading the KUDO objects with the client in the registry of controller-runtime, whose
+// metrics are served by the manager next to the metrics of the controllers
+func Register(c client.Client) error {
+	return ctrlmetrics.Registry.Register(NewCollector(c))
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instanceInfo
+	ch <- instanceStatus
+	ch <- instancePlanStatus
+	ch <- instancePlanLastFinished
+	ch <- operatorVersionInfo
+	ch <- operatorInfo
+}
+
+// Collect implements prometheus.Collector. Objects that can not be listed are reported as invalid metric, which
+// fails the scrape.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	instances := &v1alpha1.InstanceList{}
+	if err := c.client.List(context.TODO(), instances); err != nil {
+		ch <- prometheus.NewInvalidMetric(instanceInfo, fmt.Errorf("failed to list instances: %v", err))
+	} else {
+		for i := range instances.Items {
+			collectInstance(ch, &instances.Items[i])
+		}
+	}
+
+	ovs := &v1alpha1.OperatorVersionList{}
+	if err := c.client.List(context.TODO(), ovs); err != nil {
+		ch <- prometheus.NewInvalidMetric(operatorVersionInfo, fmt.Errorf("failed to list operatorversions: %v", err))
+	} else {
+		for _, ov := range ovs.Items {
+			ch <- prometheus.MustNewConstMetric(operatorVersionInfo, prometheus.GaugeValue, 1,
+				ov.Namespace, ov.Name, ov.Spec.Operator.Name, ov.Spec.Version, ov.Spec.AppVersion)
+		}
+	}
+
+	operators := &v1alpha1.OperatorList{}
+	if err := c.client.List(context.TODO(), operators); err != nil {
+		ch <- prometheus.NewInvalidMetric(operatorInfo, fmt.Errorf("failed to list operators: %v", err))
+	} else {
+		for _, o := range operators.Items {
+			ch <- prometheus.MustNewConstMetric(operatorInfo, prometheus.GaugeValue, 1,
+				o.Namespace, o.Name, o.Spec.KudoVersion, o.Spec.KubernetesVersion)
+		}
+	}
+}
+
+func collectInstance(ch chan<- prometheus.Metric, instance *v1alpha1.Instance) {
+	ch <- prometheus.MustNewConstMetric(instanceInfo, prometheus.GaugeValue, 1,
+		instance.Namespace, instance.Name, instance.Labels[kudo.OperatorLabel], instance.Spec.OperatorVersion.Name, instance.OperatorVersionNamespace())
+
+	current := instance.Status.AggregatedStatus.Status
+	if current == "" {
+		current = v1alpha1.ExecutionNeverRun
+	}
+	for _, s := range statuses {
+		ch <- prometheus.MustNewConstMetric(instanceStatus, prometheus.GaugeValue, boolValue(s == current),
+			instance.Namespace, instance.Name, string(s))
+	}
+
+	plans := make([]string, 0, len(instance.Status.PlanStatus))
+	for name := range instance.Status.PlanStatus {
+		plans = append(plans, name)
+	}
+	sort.Strings(plans)
+	for _, name := range plans {
+		plan := instance.Status.PlanStatus[name]
+		current := plan.Status
+		if current == "" {
+			current = v1alpha1.ExecutionNeverRun
+		}
+		for _, s := range statuses {
+			ch <- prometheus.MustNewConstMetric(instancePlanStatus, prometheus.GaugeValue, boolValue(s == current),
+				instance.Namespace, instance.Name, name, string(s))
+		}
+		if !plan.LastFinishedRun.IsZero() {
+			ch <- prometheus.MustNewConstMetric(instancePlanLastFinished, prometheus.GaugeValue, float64(plan.LastFinishedRun.Unix()),
+				instance.Namespace, instance.Name, name)
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCollector(t *testing.T) {
+	s := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	finished := metav1.NewTime(time.Unix(1577836800, 0))
+	objs := []runtime.Object{
+		&v1alpha1.Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "team-a", Labels: map[string]string{kudo.OperatorLabel: "kafka"}},
+			Spec:       v1alpha1.InstanceSpec{OperatorVersion: corev1.ObjectReference{Name: "kafka-1.2.0", Namespace: "kudo-catalog"}},
+			Status: v1alpha1.InstanceStatus{
+				AggregatedStatus: v1alpha1.AggregatedStatus{Status: v1alpha1.ExecutionInProgress, ActivePlanName: "update"},
+				PlanStatus: map[string]v1alpha1.PlanStatus{
+					"update": {Name: "update", Status: v1alpha1.ExecutionInProgress},
+					"deploy": {Name: "deploy", Status: v1alpha1.ExecutionComplete, LastFinishedRun: finished},
+				},
+			},
+		},
+		&v1alpha1.OperatorVersion{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka-1.2.0", Namespace: "kudo-catalog"},
+			Spec:       v1alpha1.OperatorVersionSpec{Operator: corev1.ObjectReference{Name: "kafka"}, Version: "1.2.0", AppVersion: "2.4.0"},
+		},
+		&v1alpha1.Operator{
+			ObjectMeta: metav1.ObjectMeta{Name: "kafka", Namespace: "kudo-catalog"},
+			Spec:       v1alpha1.OperatorSpec{KudoVersion: "0.10.0", KubernetesVersion: "1.15.0"},
+		},
+	}
+	c := NewCollector(fake.NewFakeClientWithScheme(s, objs...))
+
+	expected := `
+# HELP kudo_instance_info Information about an instance and the operatorversion it uses.
+# TYPE kudo_instance_info gauge
+kudo_instance_info{instance="kafka",namespace="team-a",operator="kafka",operatorversion="kafka-1.2.0",operatorversion_namespace="kudo-catalog"} 1
+# HELP kudo_instance_plan_last_finished_timestamp_seconds Unix timestamp of the last time a plan of an instance finished.
+# TYPE kudo_instance_plan_last_finished_timestamp_seconds gauge
+kudo_instance_plan_last_finished_timestamp_seconds{instance="kafka",namespace="team-a",plan="deploy"} 1.5778368e+09
+# HELP kudo_instance_plan_status The status of a plan of an instance, 1 for the current status and 0 for all others.
+# TYPE kudo_instance_plan_status gauge
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="COMPLETE"} 1
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="ERROR"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="FATAL_ERROR"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="IN_PROGRESS"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="NEVER_RUN"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="deploy",status="PENDING"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="COMPLETE"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="ERROR"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="FATAL_ERROR"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="IN_PROGRESS"} 1
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="NEVER_RUN"} 0
+kudo_instance_plan_status{instance="kafka",namespace="team-a",plan="update",status="PENDING"} 0
+# HELP kudo_instance_status The aggregated status of an instance, 1 for the current status and 0 for all others.
+# TYPE kudo_instance_status gauge
+kudo_instance_status{instance="kafka",namespace="team-a",status="COMPLETE"} 0
+kudo_instance_status{instance="kafka",namespace="team-a",status="ERROR"} 0
+kudo_instance_status{instance="kafka",namespace="team-a",status="FATAL_ERROR"} 0
+kudo_instance_status{instance="kafka",namespace="team-a",status="IN_PROGRESS"} 1
+kudo_instance_status{instance="kafka",namespace="team-a",status="NEVER_RUN"} 0
+kudo_instance_status{instance="kafka",namespace="team-a",status="PENDING"} 0
+# HELP kudo_operator_info Information about an operator.
+# TYPE kudo_operator_info gauge
+kudo_operator_info{kubernetes_version="1.15.0",kudo_version="0.10.0",namespace="kudo-catalog",operator="kafka"} 1
+# HELP kudo_operatorversion_info Information about an operatorversion.
+# TYPE kudo_operatorversion_info gauge
+kudo_operatorversion_info{app_version="2.4.0",namespace="kudo-catalog",operator="kafka",operatorversion="kafka-1.2.0",version="1.2.0"} 1
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/kudobuilder/kudo/pkg/controller/instance"
 	"github.com/kudobuilder/kudo/pkg/controller/operator"
 	"github.com/kudobuilder/kudo/pkg/controller/operatorversion"
+	"github.com/kudobuilder/kudo/pkg/util/exec"
 
 	volumetypes "github.com/docker/docker/api/types/volume"
 	docker "github.com/docker/docker/client"
@@ -282,6 +283,8 @@ func (h *Harness) RunKUDO() error {
 		Client:   mgr.GetClient(),
 		Recorder: mgr.GetEventRecorderFor("instance-controller"),
 		Scheme:   mgr.GetScheme(),
+		Executor: exec.NewExecutor(mgr.GetConfig()),
+		Config:   mgr.GetConfig(),
 	}).SetupWithManager(mgr)
 	if err != nil {
 		h.logger.Log(err, "unable to register instance controller to the manager")
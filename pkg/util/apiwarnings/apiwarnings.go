@@ -0,0 +1,141 @@
+// Package apiwarnings captures the warnings the API server returns in the Warning headers of its responses, e.g. for
+// objects of deprecated APIs, and requests strict field validation for the objects written by a client.
+//
+// The client-go version KUDO builds on neither surfaces warnings nor supports field validation, both are implemented
+// on the transport: the warnings of a response are passed to the handler of the request context and writes made with
+// a handler are sent with the fieldValidation=Strict query parameter. API servers that do not support field validation
+// ignore the parameter.
+package apiwarnings
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Handler handles a warning returned by the API server
+type Handler func(warning string)
+
+type handlerKey struct{}
+
+// NewContext returns a context with the handler for the warnings of the requests made with it
+func NewContext(ctx context.Context, h Handler) context.Context {
+	return context.WithValue(ctx, handlerKey{}, h)
+}
+
+// FromContext returns the handler of the context or nil if it has none
+func FromContext(ctx context.Context) Handler {
+	h, _ := ctx.Value(handlerKey{}).(Handler)
+	return h
+}
+
+// WrapConfig makes the clients created with the config capture warnings and request strict field validation, see
+// WrapTransport. An existing transport wrapper of the config is kept.
+func WrapConfig(config *rest.Config) {
+	wrap := config.WrapTransport
+	config.WrapTransport = func(rt http.RoundTripper) http.RoundTripper {
+		if wrap != nil {
+			rt = wrap(rt)
+		}
+		return WrapTransport(rt)
+	}
+}
+
+// WrapTransport returns a round tripper that passes the warnings of the responses to the handler of the request
+// context. Creates, updates and patches made with a handler are validated strictly, i.e. unknown and duplicate fields
+// are rejected instead of being dropped silently.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	return &transport{next: rt}
+}
+
+type transport struct {
+	next http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := FromContext(req.Context())
+	if h == nil {
+		return t.next.RoundTrip(req)
+	}
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		// a round tripper must not modify the request
+		req = req.Clone(req.Context())
+		query := req.URL.Query()
+		query.Set("fieldValidation", "Strict")
+		req.URL.RawQuery = query.Encode()
+	}
+	resp, err := t.next.RoundTrip(req)
+	if resp != nil {
+		for _, header := range resp.Header["Warning"] {
+			if text, ok := parseWarning(header); ok {
+				h(text)
+			}
+		}
+	}
+	return resp, err
+}
+
+// parseWarning returns the text of a Warning header value of the form `<code> <agent> "<text>" ["<date>"]`. Only
+// warnings with the code 299 (miscellaneous persistent warning) are sent by the API server.
+func parseWarning(header string) (string, bool) {
+	parts := strings.SplitN(strings.TrimSpace(header), " ", 3)
+	if len(parts) != 3 || parts[0] != "299" || !strings.HasPrefix(parts[2], `"`) {
+		return "", false
+	}
+	quoted := parts[2]
+	for i := 1; i < len(quoted); i++ {
+		switch quoted[i] {
+		case '\\':
+			i++
+		case '"':
+			text, err := strconv.Unquote(quoted[:i+1])
+			return text, err == nil
+		}
+	}
+	return "", false
+}
+
+// NewClient returns a client that passes the warnings of all its requests to the handler. The transport of the
+// client has to be wrapped with WrapTransport for warnings to be captured. The client is returned unchanged if the
+// handler is nil.
+func NewClient(c client.Client, h Handler) client.Client {
+	if h == nil {
+		return c
+	}
+	return &warningClient{Client: c, handler: h}
+}
+
+type warningClient struct {
+	client.Client
+	handler Handler
+}
+
+func (c *warningClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return c.Client.Get(NewContext(ctx, c.handler), key, obj)
+}
+
+func (c *warningClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	return c.Client.List(NewContext(ctx, c.handler), list, opts...)
+}
+
+func (c *warningClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	return c.Client.Create(NewContext(ctx, c.handler), obj, opts...)
+}
+
+func (c *warningClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return c.Client.Update(NewContext(ctx, c.handler), obj, opts...)
+}
+
+func (c *warningClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return c.Client.Patch(NewContext(ctx, c.handler), obj, patch, opts...)
+}
+
+func (c *warningClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	return c.Client.Delete(NewContext(ctx, c.handler), obj, opts...)
+}
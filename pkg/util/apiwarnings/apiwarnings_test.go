@@ -0,0 +1,68 @@
+package apiwarnings
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestTransport(t *testing.T) {
+	var queries []string
+	rt := WrapTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		queries = append(queries, req.URL.RawQuery)
+		header := http.Header{}
+		header.Add("Warning", `299 - "extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+"`)
+		header.Add("Warning", `199 - "not from the API server"`)
+		return &http.Response{StatusCode: http.StatusOK, Header: header}, nil
+	}))
+
+	var warnings []string
+	ctx := NewContext(context.Background(), func(w string) { warnings = append(warnings, w) })
+	for _, method := range []string{http.MethodGet, http.MethodPatch} {
+		req, err := http.NewRequest(method, "https://cluster/apis/extensions/v1beta1/namespaces/default/ingresses/web?dryRun=All", nil)
+		assert.NoError(t, err)
+		_, err = rt.RoundTrip(req.WithContext(ctx))
+		assert.NoError(t, err)
+		assert.Equal(t, "dryRun=All", req.URL.RawQuery, "the request is not modified")
+	}
+	assert.Equal(t, []string{"dryRun=All", "dryRun=All&fieldValidation=Strict"}, queries)
+	assert.Equal(t, []string{
+		"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+",
+		"extensions/v1beta1 Ingress is deprecated in v1.14+, unavailable in v1.22+",
+	}, warnings)
+
+	// requests without a handler are passed on unchanged
+	req, err := http.NewRequest(http.MethodPost, "https://cluster/api/v1/namespaces/default/pods", nil)
+	assert.NoError(t, err)
+	_, err = rt.RoundTrip(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "", queries[2])
+}
+
+func TestParseWarning(t *testing.T) {
+	tests := []struct {
+		header string
+		text   string
+		ok     bool
+	}{
+		{header: `299 - "policy/v1beta1 PodSecurityPolicy is deprecated"`, text: "policy/v1beta1 PodSecurityPolicy is deprecated", ok: true},
+		{header: `299 kube-apiserver "quoted \"text\""`, text: `quoted "text"`, ok: true},
+		{header: `299 - "with date" "Sat, 25 Aug 2012 23:34:45 GMT"`, text: "with date", ok: true},
+		{header: `199 - "other code"`},
+		{header: `299 - unquoted`},
+		{header: `299`},
+	}
+	for _, tt := range tests {
+		text, ok := parseWarning(tt.header)
+		assert.Equal(t, tt.ok, ok, tt.header)
+		assert.Equal(t, tt.text, text, tt.header)
+	}
+}
@@ -0,0 +1,199 @@
+// Package cron parses schedules in the standard five field cron format and computes their activation times.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron schedule. Every field is a bit set of the values it matches.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true if the day fields match every day. Only if both are restricted a day matches
+	// if either of them matches, as in the original cron.
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	doms    = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is Sunday as well
+	dows = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a schedule of the form "minute hour day-of-month month day-of-week", e.g. "30 2 * * mon-fri".
+// Fields are lists of values, ranges and steps like "1,15", "1-5" or "*/10". The descriptors @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight and @hourly are supported as well.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		expanded, ok := descriptors[spec]
+		if !ok {
+			return nil, fmt.Errorf("unknown descriptor %s", spec)
+		}
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields but found %d in schedule %q", len(fields), spec)
+	}
+
+	s := &Schedule{
+		domStar: fields[2] == "*" || fields[2] == "?",
+		dowStar: fields[4] == "*" || fields[4] == "?",
+	}
+	var err error
+	for i, f := range []struct {
+		bits   *uint64
+		bounds bounds
+		name   string
+	}{
+		{&s.minute, minutes, "minute"},
+		{&s.hour, hours, "hour"},
+		{&s.dom, doms, "day of month"},
+		{&s.month, months, "month"},
+		{&s.dow, dows, "day of week"},
+	} {
+		if *f.bits, err = parseField(fields[i], f.bounds); err != nil {
+			return nil, fmt.Errorf("invalid %s in schedule %q: %v", f.name, spec, err)
+		}
+	}
+	// Sunday can be written as 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, expr := range strings.Split(field, ",") {
+		rangeExpr, step := expr, uint(1)
+		if i := strings.Index(expr, "/"); i >= 0 {
+			s, err := strconv.ParseUint(expr[i+1:], 10, 8)
+			if err != nil || s == 0 {
+				return 0, fmt.Errorf("invalid step in %q", expr)
+			}
+			rangeExpr, step = expr[:i], uint(s)
+		}
+
+		var start, end uint
+		switch {
+		case rangeExpr == "*" || rangeExpr == "?":
+			start, end = b.min, b.max
+		case strings.Contains(rangeExpr, "-"):
+			parts := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if start, err = parseValue(parts[0], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(parts[1], b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("range %q ends before it starts", rangeExpr)
+			}
+		default:
+			v, err := parseValue(rangeExpr, b)
+			if err != nil {
+				return 0, err
+			}
+			start, end = v, v
+			// a step of a single value applies to the values up to the maximum, e.g. 5/15
+			if step > 1 {
+				end = b.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if uint(v) < b.min || uint(v) > b.max {
+		return 0, fmt.Errorf("value %d is out of range %d-%d", v, b.min, b.max)
+	}
+	return uint(v), nil
+}
+
+// searchYears limits the search for the next activation, schedules like "0 0 30 2 *" never activate
+const searchYears = 5
+
+// Next returns the first activation of the schedule after t, with a precision of a minute, in the location of t.
+// It returns the zero time if the schedule does not activate within the next years.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Year() + searchYears
+
+	// the fields are matched from the month down to the minute, every time a field wraps around the larger fields
+	// have to be matched again
+	for t.Year() <= limit {
+		if !has(s.month, uint(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, uint(t.Hour())) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, uint(t.Minute())) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, uint(t.Day()))
+	dow := has(s.dow, uint(t.Weekday()))
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v uint) bool {
+	return bits&(1<<v) != 0
+}
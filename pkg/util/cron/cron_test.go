@@ -0,0 +1,46 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	from := time.Date(2019, 12, 30, 14, 25, 30, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		schedule string
+		next     time.Time
+	}{
+		{"* * * * *", time.Date(2019, 12, 30, 14, 26, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 12, 30, 14, 30, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2019, 12, 31, 2, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 3 * * sat,sun", time.Date(2020, 1, 4, 3, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 12 29 feb *", time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{"5/20 10-12 * * *", time.Date(2019, 12, 31, 10, 5, 0, 0, time.UTC)},
+		// day of month or day of week if both are restricted
+		{"0 0 15 * fri", time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.schedule)
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", tt.schedule, err)
+			continue
+		}
+		if next := s.Next(from); !next.Equal(tt.next) {
+			t.Errorf("%s: expected next activation at %v but got %v", tt.schedule, tt.next, next)
+		}
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, schedule := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * * funday", "@often"} {
+		if _, err := Parse(schedule); err == nil {
+			t.Errorf("%q: expected an error", schedule)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Config is the encryption configuration file shared by kudoctl and the manager. It lists the providers, each with
+// a name and either an `aesgcm` key file or the `exec` commands of a KMS plugin.
+type Config struct {
+	// Providers are the encryption providers, values are encrypted with the first one
+	Providers []ProviderConfig `json:"providers"`
+}
+
+// ProviderConfig configures a named provider, exactly one of AESGCM and Exec has to be set
+type ProviderConfig struct {
+	Name   string        `json:"name"`
+	AESGCM *AESGCMConfig `json:"aesgcm,omitempty"`
+	Exec   *ExecConfig   `json:"exec,omitempty"`
+}
+
+// AESGCMConfig configures an AESGCMProvider
+type AESGCMConfig struct {
+	// KeyFile contains the base64 encoded key of 16, 24 or 32 bytes
+	KeyFile string `json:"keyFile"`
+}
+
+// ExecConfig configures an ExecProvider. age keys can be used with the age CLI, e.g. an encrypt command
+// ["age", "-r", "<recipient>"] and a decrypt command ["age", "-d", "-i", "<identity file>"].
+type ExecConfig struct {
+	Encrypt []string `json:"encrypt,omitempty"`
+	Decrypt []string `json:"decrypt,omitempty"`
+	// Timeout defaults to DefaultCommandTimeout
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// LoadConfig reads the encryption configuration file and creates a keyring with its providers
+func LoadConfig(path string) (*Keyring, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read encryption config: %v", err)
+	}
+	var config Config
+	if err := yaml.UnmarshalStrict(b, &config); err != nil {
+		return nil, fmt.Errorf("encryption config %s is invalid: %v", path, err)
+	}
+	keyring, err := config.Keyring()
+	if err != nil {
+		return nil, fmt.Errorf("encryption config %s is invalid: %v", path, err)
+	}
+	return keyring, nil
+}
+
+// Keyring creates the providers of the configuration
+func (c Config) Keyring() (*Keyring, error) {
+	if len(c.Providers) == 0 {
+		return nil, errors.New("no providers are configured")
+	}
+	keyring := NewKeyring()
+	for _, pc := range c.Providers {
+		p, err := pc.provider()
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %v", pc.Name, err)
+		}
+		if err := keyring.Add(pc.Name, p); err != nil {
+			return nil, err
+		}
+	}
+	return keyring, nil
+}
+
+func (pc ProviderConfig) provider() (Provider, error) {
+	switch {
+	case pc.AESGCM != nil && pc.Exec != nil:
+		return nil, errors.New("aesgcm and exec can not be configured at the same time")
+	case pc.AESGCM != nil:
+		b, err := ioutil.ReadFile(pc.AESGCM.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %v", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("key file %s is not base64 encoded: %v", pc.AESGCM.KeyFile, err)
+		}
+		return NewAESGCMProvider(key)
+	case pc.Exec != nil:
+		if len(pc.Exec.Encrypt) == 0 && len(pc.Exec.Decrypt) == 0 {
+			return nil, errors.New("exec has to define an encrypt or a decrypt command")
+		}
+		p := &ExecProvider{EncryptCommand: pc.Exec.Encrypt, DecryptCommand: pc.Exec.Decrypt}
+		if pc.Exec.Timeout != nil {
+			p.Timeout = pc.Exec.Timeout.Duration
+		}
+		return p, nil
+	default:
+		return nil, errors.New("either aesgcm or exec has to be configured")
+	}
+}
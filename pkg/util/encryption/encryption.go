@@ -0,0 +1,131 @@
+// Package encryption encrypts the values of sensitive parameters, so that they are not stored in plaintext in the
+// Instance. kudoctl encrypts the values with the first provider of a keyring, the manager decrypts them with the
+// provider named in the encrypted value right before the templates are rendered.
+//
+// Encrypted values have the format ENC[<provider>:<base64 ciphertext>], similar to sops. Values in any other format
+// are plaintext and passed through unchanged, so that encryption can be introduced for existing instances.
+package encryption
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	prefix = "ENC["
+	suffix = "]"
+)
+
+var providerNameRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Provider encrypts and decrypts values, e.g. with a local key or by calling a KMS. The additional data is
+// authenticated but not encrypted, a ciphertext only decrypts with the additional data it was encrypted with. Providers
+// that can not authenticate additional data ignore it.
+type Provider interface {
+	Encrypt(plaintext, additionalData []byte) ([]byte, error)
+	Decrypt(ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// Keyring holds the providers of a configuration by name. Values are encrypted with the first provider, keys are
+// rotated by adding a new provider first and keeping the old one until all values are encrypted with the new one.
+// A nil Keyring decrypts nothing and leaves values to encrypt in plaintext.
+type Keyring struct {
+	names     []string
+	providers map[string]Provider
+}
+
+// NewKeyring creates an empty keyring
+func NewKeyring() *Keyring {
+	return &Keyring{providers: map[string]Provider{}}
+}
+
+// Add adds a provider to the keyring. The name is stored in the values encrypted by the provider, it must be a DNS
+// label and can not be changed later.
+func (k *Keyring) Add(name string, p Provider) error {
+	if !providerNameRegex.MatchString(name) {
+		return fmt.Errorf("encryption provider name %q must consist of lower case alphanumeric characters or '-'", name)
+	}
+	if _, ok := k.providers[name]; ok {
+		return fmt.Errorf("encryption provider %s is defined twice", name)
+	}
+	k.names = append(k.names, name)
+	k.providers[name] = p
+	return nil
+}
+
+// IsEncrypted returns true if the value was encrypted by a Keyring
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// Encrypt encrypts the value of the key, e.g. the name of a parameter, with the first provider of the keyring. The key
+// is passed to the provider as additional data, so that the value can not be decrypted as the value of another key.
+// Values that are already encrypted are returned unchanged, as are all values if the keyring is nil.
+func (k *Keyring) Encrypt(key, value string) (string, error) {
+	if k == nil || IsEncrypted(value) {
+		return value, nil
+	}
+	if len(k.names) == 0 {
+		return "", errors.New("no encryption provider is configured")
+	}
+	name := k.names[0]
+	ciphertext, err := k.providers[name].Encrypt([]byte(value), []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt with provider %s: %v", name, err)
+	}
+	return fmt.Sprintf("%s%s:%s%s", prefix, name, base64.StdEncoding.EncodeToString(ciphertext), suffix), nil
+}
+
+// Decrypt decrypts the value of the key with the provider it was encrypted with. Plaintext values are returned
+// unchanged.
+func (k *Keyring) Decrypt(key, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	envelope := strings.TrimSuffix(strings.TrimPrefix(value, prefix), suffix)
+	parts := strings.SplitN(envelope, ":", 2)
+	if len(parts) != 2 {
+		return "", errors.New("encrypted value does not name its encryption provider")
+	}
+	name := parts[0]
+	var p Provider
+	if k != nil {
+		p = k.providers[name]
+	}
+	if p == nil {
+		return "", fmt.Errorf("value is encrypted with provider %s which is not configured", name)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("encrypted value is not base64 encoded: %v", err)
+	}
+	plaintext, err := p.Decrypt(ciphertext, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt with provider %s: %v", name, err)
+	}
+	return string(plaintext), nil
+}
+
+// DecryptValues returns a copy of the values with all encrypted values decrypted. The errors of all values are
+// reported at once, prefixed with the key of their value.
+func (k *Keyring) DecryptValues(values map[string]string) (map[string]string, error) {
+	decrypted := make(map[string]string, len(values))
+	var errs []string
+	for key, v := range values {
+		d, err := k.Decrypt(key, v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		decrypted[key] = d
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return decrypted, nil
+}
@@ -0,0 +1,191 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	current, err := NewAESGCMProvider(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := NewAESGCMProvider(testKey[:16])
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := NewKeyring()
+	if err := previous.Add("old", old); err != nil {
+		t.Fatal(err)
+	}
+	encryptedWithOld, err := previous.Encrypt("PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the key is rotated by adding the new provider first
+	k := NewKeyring()
+	if err := k.Add("current", current); err != nil {
+		t.Fatal(err)
+	}
+	if err := k.Add("old", old); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := k.Encrypt("PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, "ENC[current:") || strings.Contains(encrypted, "hunter2") {
+		t.Errorf("expected the value to be encrypted with provider current but got %s", encrypted)
+	}
+	if again, _ := k.Encrypt("PASSWORD", encrypted); again != encrypted {
+		t.Errorf("expected an encrypted value to be left unchanged but got %s", again)
+	}
+
+	for _, v := range []string{encrypted, encryptedWithOld, "hunter2"} {
+		decrypted, err := k.Decrypt("PASSWORD", v)
+		if err != nil {
+			t.Errorf("%s: expected no error but got %v", v, err)
+			continue
+		}
+		if decrypted != "hunter2" {
+			t.Errorf("%s: expected hunter2 but got %s", v, decrypted)
+		}
+	}
+	// a value encrypted for one key does not decrypt as the value of another key
+	if _, err := k.Decrypt("USER", encrypted); err == nil {
+		t.Errorf("expected the value of PASSWORD not to decrypt as the value of USER")
+	}
+}
+
+func TestKeyring_Nil(t *testing.T) {
+	var k *Keyring
+	if v, err := k.Encrypt("PASSWORD", "hunter2"); err != nil || v != "hunter2" {
+		t.Errorf("expected a nil keyring to leave the value in plaintext but got %s, %v", v, err)
+	}
+	if v, err := k.Decrypt("PASSWORD", "hunter2"); err != nil || v != "hunter2" {
+		t.Errorf("expected a nil keyring to pass plaintext through but got %s, %v", v, err)
+	}
+	if _, err := k.Decrypt("PASSWORD", "ENC[kms:aGVsbG8=]"); err == nil || err.Error() != "value is encrypted with provider kms which is not configured" {
+		t.Errorf("expected an error for an encrypted value but got %v", err)
+	}
+}
+
+func TestKeyring_DecryptValues(t *testing.T) {
+	k := NewKeyring()
+	if err := k.Add("plain", &ExecProvider{EncryptCommand: []string{"cat"}, DecryptCommand: []string{"cat"}}); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := k.Encrypt("PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encrypted != "ENC[plain:"+base64.StdEncoding.EncodeToString([]byte("hunter2"))+"]" {
+		t.Errorf("unexpected encrypted value %s", encrypted)
+	}
+
+	values, err := k.DecryptValues(map[string]string{"PASSWORD": encrypted, "REPLICAS": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["PASSWORD"] != "hunter2" || values["REPLICAS"] != "3" {
+		t.Errorf("unexpected decrypted values %v", values)
+	}
+
+	_, err = k.DecryptValues(map[string]string{"B": "ENC[kms:aGVsbG8=]", "A": "ENC[plain:%%%]"})
+	expected := "A: encrypted value is not base64 encoded: illegal base64 data at input byte 0; B: value is encrypted with provider kms which is not configured"
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q but got %v", expected, err)
+	}
+}
+
+func TestKeyring_Add(t *testing.T) {
+	k := NewKeyring()
+	if err := k.Add("Team Key", &ExecProvider{}); err == nil {
+		t.Error("expected an error for an invalid provider name")
+	}
+	if err := k.Add("kms", &ExecProvider{}); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if err := k.Add("kms", &ExecProvider{}); err == nil || err.Error() != "encryption provider kms is defined twice" {
+		t.Errorf("expected an error for a duplicate provider but got %v", err)
+	}
+}
+
+func TestExecProvider_Failure(t *testing.T) {
+	p := &ExecProvider{EncryptCommand: []string{"sh", "-c", "echo access denied >&2; exit 1"}}
+	_, err := p.Encrypt([]byte("hunter2"), nil)
+	if err == nil || err.Error() != "command sh failed: exit status 1: access denied" {
+		t.Errorf("expected the command to fail but got %v", err)
+	}
+	if _, err := p.Decrypt([]byte("hunter2"), nil); err == nil || err.Error() != "no decrypt command is configured" {
+		t.Errorf("expected decryption to fail but got %v", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "encryption")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "team.key")
+	if err := ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(testKey)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.yaml")
+	content := `providers:
+- name: team-key
+  aesgcm:
+    keyFile: ` + keyFile + `
+- name: kms
+  exec:
+    decrypt: ["kudo-kms-plugin", "decrypt"]
+    timeout: 5s
+`
+	if err := ioutil.WriteFile(config, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := LoadConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := k.Encrypt("PASSWORD", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encrypted, "ENC[team-key:") {
+		t.Errorf("expected the value to be encrypted with the first provider but got %s", encrypted)
+	}
+	if decrypted, err := k.Decrypt("PASSWORD", encrypted); err != nil || decrypted != "hunter2" {
+		t.Errorf("expected hunter2 but got %s, %v", decrypted, err)
+	}
+
+	invalid := []struct {
+		content string
+		err     string
+	}{
+		{"providers: []\n", "no providers are configured"},
+		{"providers:\n- name: kms\n", "provider kms: either aesgcm or exec has to be configured"},
+		{"providers:\n- name: kms\n  exec: {}\n", "provider kms: exec has to define an encrypt or a decrypt command"},
+		{"providers:\n- name: kms\n  exec:\n    decrypt: [cat]\n- name: kms\n  exec:\n    decrypt: [cat]\n", "encryption provider kms is defined twice"},
+	}
+	for _, tt := range invalid {
+		if err := ioutil.WriteFile(config, []byte(tt.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := LoadConfig(config)
+		expected := "encryption config " + config + " is invalid: " + tt.err
+		if err == nil || err.Error() != expected {
+			t.Errorf("expected error %q but got %v", expected, err)
+		}
+	}
+}
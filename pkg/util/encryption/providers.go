@@ -0,0 +1,100 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultCommandTimeout is the time a command of an ExecProvider may run before it is aborted
+const DefaultCommandTimeout = 30 * time.Second
+
+// AESGCMProvider encrypts values with AES-GCM and a local key. The random nonce is prepended to the ciphertext, the
+// additional data is authenticated by GCM.
+type AESGCMProvider struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMProvider creates a provider for an AES key of 16, 24 or 32 bytes
+func NewAESGCMProvider(key []byte) (*AESGCMProvider, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMProvider{aead: aead}, nil
+}
+
+// Encrypt encrypts the plaintext with a random nonce
+func (p *AESGCMProvider) Encrypt(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, p.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return p.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt decrypts a ciphertext created by Encrypt with the same additional data
+func (p *AESGCMProvider) Decrypt(ciphertext, additionalData []byte) ([]byte, error) {
+	size := p.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return p.aead.Open(nil, ciphertext[:size], ciphertext[size:], additionalData)
+}
+
+// ExecProvider delegates encryption to external commands, e.g. a KMS plugin or the age CLI. The commands read the
+// value from stdin and write the result to stdout. Either command can be empty, e.g. kudoctl only needs to encrypt
+// and the manager only needs to decrypt. The additional data is ignored, the commands only receive the value.
+type ExecProvider struct {
+	EncryptCommand []string
+	DecryptCommand []string
+	Timeout        time.Duration
+}
+
+// Encrypt runs the encrypt command with the plaintext as input
+func (p *ExecProvider) Encrypt(plaintext, _ []byte) ([]byte, error) {
+	if len(p.EncryptCommand) == 0 {
+		return nil, errors.New("no encrypt command is configured")
+	}
+	return p.run(p.EncryptCommand, plaintext)
+}
+
+// Decrypt runs the decrypt command with the ciphertext as input
+func (p *ExecProvider) Decrypt(ciphertext, _ []byte) ([]byte, error) {
+	if len(p.DecryptCommand) == 0 {
+		return nil, errors.New("no decrypt command is configured")
+	}
+	return p.run(p.DecryptCommand, ciphertext)
+}
+
+func (p *ExecProvider) run(command []string, input []byte) ([]byte, error) {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// stdout is not part of the error as it might contain the plaintext
+		return nil, fmt.Errorf("command %s failed: %v: %s", command[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+// Package exec runs commands in containers of pods through the exec subresource of the Kubernetes API.
+//
+// Commands are streamed over a websocket with the v4.channel.k8s.io protocol: every message is prefixed with the
+// channel it belongs to, the error channel carries the exit status of the command.
+package exec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	protocol = "v4.channel.k8s.io"
+
+	stdoutChannel = 1
+	stderrChannel = 2
+	errorChannel  = 3
+
+	// DefaultTimeout is the time a command may run before it is aborted
+	DefaultTimeout = 5 * time.Minute
+)
+
+// Executor runs commands in containers of pods
+type Executor struct {
+	config  *rest.Config
+	Timeout time.Duration
+}
+
+// NewExecutor creates an executor that connects to the API server of the config
+func NewExecutor(config *rest.Config) *Executor {
+	return &Executor{config: config, Timeout: DefaultTimeout}
+}
+
+// Exec runs the command in the container of the pod and returns its output. A command that exits with a non-zero
+// exit code is returned as error.
+func (e *Executor) Exec(namespace, pod, container string, command []string) (string, string, error) {
+	wsConfig, err := e.websocketConfig(namespace, pod, container, command)
+	if err != nil {
+		return "", "", err
+	}
+	ws, err := websocket.DialConfig(wsConfig)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to connect to pod %s/%s: %v", namespace, pod, err)
+	}
+	defer ws.Close()
+	if err := ws.SetDeadline(time.Now().Add(e.Timeout)); err != nil {
+		return "", "", err
+	}
+
+	return read(ws)
+}
+
+// read collects the output of the command until the connection is closed
+func read(ws *websocket.Conn) (string, string, error) {
+	var stdout, stderr, status bytes.Buffer
+	for {
+		var msg []byte
+		err := websocket.Message.Receive(ws, &msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return stdout.String(), stderr.String(), fmt.Errorf("failed to read command output: %v", err)
+		}
+		if len(msg) == 0 {
+			continue
+		}
+		switch msg[0] {
+		case stdoutChannel:
+			stdout.Write(msg[1:])
+		case stderrChannel:
+			stderr.Write(msg[1:])
+		case errorChannel:
+			status.Write(msg[1:])
+		}
+	}
+	return stdout.String(), stderr.String(), statusError(status.Bytes())
+}
+
+// statusError returns the error of the status sent on the error channel, if the command failed
+func statusError(b []byte) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	status := metav1.Status{}
+	if err := json.Unmarshal(b, &status); err != nil {
+		return fmt.Errorf("invalid command status %q: %v", string(b), err)
+	}
+	if status.Status == metav1.StatusSuccess {
+		return nil
+	}
+	if status.Details != nil {
+		for _, c := range status.Details.Causes {
+			if c.Type == "ExitCode" {
+				return fmt.Errorf("command exited with code %s", c.Message)
+			}
+		}
+	}
+	return fmt.Errorf("command failed: %s", status.Message)
+}
+
+// websocketConfig builds the websocket configuration of the exec request, authenticated as the rest config
+func (e *Executor) websocketConfig(namespace, pod, container string, command []string) (*websocket.Config, error) {
+	host, err := url.Parse(e.config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API server host %s: %v", e.config.Host, err)
+	}
+	location := *host
+	switch host.Scheme {
+	case "http":
+		location.Scheme = "ws"
+	default:
+		location.Scheme = "wss"
+	}
+	location.Path = strings.TrimSuffix(host.Path, "/") + fmt.Sprintf("/api/v1/namespaces/%s/pods/%s/exec", namespace, pod)
+	query := url.Values{}
+	query.Set("stdout", "true")
+	query.Set("stderr", "true")
+	if container != "" {
+		query.Set("container", container)
+	}
+	for _, c := range command {
+		query.Add("command", c)
+	}
+	location.RawQuery = query.Encode()
+
+	origin := *host
+	origin.Path = ""
+	wsConfig, err := websocket.NewConfig(location.String(), origin.String())
+	if err != nil {
+		return nil, err
+	}
+	wsConfig.Protocol = []string{protocol}
+	wsConfig.Header = http.Header{}
+	if wsConfig.TlsConfig, err = rest.TLSConfigFor(e.config); err != nil {
+		return nil, err
+	}
+
+	token := e.config.BearerToken
+	if e.config.BearerTokenFile != "" {
+		if b, err := ioutil.ReadFile(e.config.BearerTokenFile); err == nil {
+			token = strings.TrimSpace(string(b))
+		}
+	}
+	switch {
+	case token != "":
+		wsConfig.Header.Set("Authorization", "Bearer "+token)
+	case e.config.Username != "":
+		req := &http.Request{Header: http.Header{}}
+		req.SetBasicAuth(e.config.Username, e.config.Password)
+		wsConfig.Header.Set("Authorization", req.Header.Get("Authorization"))
+	}
+	return wsConfig, nil
+}
@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+	"k8s.io/client-go/rest"
+)
+
+func TestExec(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages [][]byte
+		stdout   string
+		stderr   string
+		err      string
+	}{
+		{
+			name:     "success",
+			messages: [][]byte{{stdoutChannel}, append([]byte{stdoutChannel}, "hello "...), append([]byte{stdoutChannel}, "world"...), append([]byte{errorChannel}, `{"status":"Success"}`...)},
+			stdout:   "hello world",
+		},
+		{
+			name: "non-zero exit code",
+			messages: [][]byte{
+				append([]byte{stderrChannel}, "not found"...),
+				append([]byte{errorChannel}, `{"status":"Failure","reason":"NonZeroExitCode","details":{"causes":[{"reason":"ExitCode","message":"127"}]}}`...),
+			},
+			stderr: "not found",
+			err:    "command exited with code 127",
+		},
+		{
+			name:     "failure",
+			messages: [][]byte{append([]byte{errorChannel}, `{"status":"Failure","message":"container not found"}`...)},
+			err:      "command failed: container not found",
+		},
+	}
+
+	for _, tt := range tests {
+		var query string
+		server := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+			query = ws.Request().URL.String()
+			for _, m := range tt.messages {
+				assert.NoError(t, websocket.Message.Send(ws, m))
+			}
+		}))
+
+		e := NewExecutor(&rest.Config{Host: server.URL, BearerToken: "token"})
+		stdout, stderr, err := e.Exec("default", "pod-0", "app", []string{"sh", "-c", "echo hello"})
+		server.Close()
+
+		assert.Equal(t, tt.stdout, stdout, tt.name)
+		assert.Equal(t, tt.stderr, stderr, tt.name)
+		if tt.err == "" {
+			assert.NoError(t, err, tt.name)
+		} else {
+			assert.EqualError(t, err, tt.err, tt.name)
+		}
+		assert.True(t, strings.HasPrefix(query, "/api/v1/namespaces/default/pods/pod-0/exec?"), tt.name)
+		assert.Contains(t, query, "command=sh&command=-c&command=echo+hello", tt.name)
+		assert.Contains(t, query, "container=app", tt.name)
+	}
+}
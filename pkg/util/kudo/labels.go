@@ -7,6 +7,8 @@ const (
 	OperatorVersionAnnotation = "kudo.dev/operator-version"
 	// InstanceLabel is k8s label key for KUDO instance name
 	InstanceLabel = "kudo.dev/instance"
+	// PlanLabel is k8s label key for the plan a PlanExecution records
+	PlanLabel = "kudo.dev/plan"
 	// HeritageLabel is k8s label key for heritage
 	HeritageLabel = "heritage" // this is not specific to KUDO
 
@@ -16,4 +18,29 @@ const (
 	PhaseAnnotation = "kudo.dev/phase"
 	// StepAnnotation is k8s annotation key for step that created this object
 	StepAnnotation = "kudo.dev/step"
+
+	// AdoptAnnotation is k8s annotation key that allows an instance to take ownership of an already existing object
+	// that is not managed by KUDO. The annotation has to be set to "true" in the template of the object.
+	AdoptAnnotation = "kudo.dev/adopt"
+	// AdoptedAtAnnotation is k8s annotation key recording the time when an existing object was adopted by an instance
+	AdoptedAtAnnotation = "kudo.dev/adopted-at"
+
+	// RolloutBatchReadyAnnotation is k8s annotation key recording the time when the pods of the current batch of a
+	// StatefulSet rollout were ready. The next batch is updated once the pause of the rollout task passed.
+	RolloutBatchReadyAnnotation = "kudo.dev/rollout-batch-ready-at"
+
+	// RollOnConfigChangeAnnotation is k8s annotation key that makes the pods of a workload roll whenever a ConfigMap or
+	// Secret they use changes. The annotation has to be set to "true" in the template of the workload.
+	RollOnConfigChangeAnnotation = "kudo.dev/roll-on-config-change"
+	// ConfigChecksumAnnotation is k8s annotation key of the pod templates of workloads that roll on config changes, it
+	// holds the checksum of the ConfigMaps and Secrets the pods use
+	ConfigChecksumAnnotation = "kudo.dev/config-checksum"
+
+	// PackageSourceAnnotation is k8s annotation key of operatorversions and instances installed from a Git repository,
+	// it holds the package name with the ref resolved to the commit the package was read from
+	PackageSourceAnnotation = "kudo.dev/package-source"
+
+	// LastAppliedConfigAnnotation is k8s annotation key for the gzip compressed and base64 encoded configuration the
+	// object was last applied with. It is the original of the three-way merge when the object is applied again.
+	LastAppliedConfigAnnotation = "kudo.dev/last-applied-configuration"
 )
@@ -0,0 +1,47 @@
+package kudo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+// maxHelperNameLength is the maximum length of a DNS-1123 label, pod names longer than it can not be used as host names
+const maxHelperNameLength = 63
+
+// helperHashLength is the number of hex characters of the hash appended to helper names
+const helperHashLength = 10
+
+var invalidNameCharacters = regexp.MustCompile("[^a-z0-9-]+")
+
+// HelperName returns the name of a helper object that a task of a step creates for an instance, e.g. a pod running
+// a job. Helper objects are created in the namespace of the instance, so tasks and the cleanup of helper objects can
+// derive the same name from the instance, plan, phase, step and task again.
+//
+// The name is a valid DNS-1123 label of at most 63 characters. It starts with the readable, possibly truncated, names
+// joined by dashes and ends with a hash of all names, so that different steps never share a name even if their
+// readable part is the same, e.g. because a long instance name was truncated.
+func HelperName(instance, plan, phase, step, task string) string {
+	names := []string{instance, plan, phase, step, task}
+
+	// the names are hashed with a separator that is not valid in any of them, so that e.g. "a-b" and "c" and "a" and
+	// "b-c" do not result in the same hash
+	sum := sha256.Sum256([]byte(strings.Join(names, "\x00")))
+	hash := hex.EncodeToString(sum[:])[:helperHashLength]
+
+	parts := make([]string, 0, len(names))
+	for _, n := range names {
+		if n = strings.Trim(invalidNameCharacters.ReplaceAllString(strings.ToLower(n), "-"), "-"); n != "" {
+			parts = append(parts, n)
+		}
+	}
+	readable := strings.Join(parts, "-")
+	if max := maxHelperNameLength - helperHashLength - 1; len(readable) > max {
+		readable = strings.TrimRight(readable[:max], "-")
+	}
+	if readable == "" {
+		return hash
+	}
+	return readable + "-" + hash
+}
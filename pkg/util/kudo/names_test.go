@@ -0,0 +1,40 @@
+package kudo
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestHelperName(t *testing.T) {
+	name := HelperName("kafka", "deploy", "main", "backup", "dump")
+	if !strings.HasPrefix(name, "kafka-deploy-main-backup-dump-") {
+		t.Errorf("expected the readable names as prefix of %s", name)
+	}
+	if HelperName("kafka", "deploy", "main", "backup", "dump") != name {
+		t.Errorf("expected the same name for the same step")
+	}
+
+	long := strings.Repeat("a", 100)
+	tests := [][]string{
+		{"kafka", "deploy", "main", "backup", "dump"},
+		{long, "deploy", "main", "backup", "dump"},
+		{long + "b", "deploy", "main", "backup", "dump"},
+		{"kafka-deploy", "main", "backup", "dump", ""},
+		{"kafka", "deploy-main", "backup", "dump", ""},
+		{"Kafka_1", "deploy", "main", "backup", "dump"},
+		{"-", "", "", "", ""},
+	}
+	seen := map[string][]string{}
+	for _, tt := range tests {
+		name := HelperName(tt[0], tt[1], tt[2], tt[3], tt[4])
+		if errs := validation.IsDNS1123Label(name); len(errs) > 0 {
+			t.Errorf("%v: %s is not a valid name: %v", tt, name, errs)
+		}
+		if other, ok := seen[name]; ok {
+			t.Errorf("%v: %s is also the name of %v", tt, name, other)
+		}
+		seen[name] = tt
+	}
+}
@@ -0,0 +1,179 @@
+// Package tracing records spans of kudoctl and the manager and exports them via OTLP with the OpenTelemetry SDK.
+// Tracing is disabled unless an OTLP endpoint is configured with the standard OpenTelemetry environment variables.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// EndpointEnv is the base URL of the OTLP/HTTP receiver, spans are sent to its /v1/traces path
+	EndpointEnv = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	// TracesEndpointEnv is the full URL spans are sent to, it takes precedence over EndpointEnv
+	TracesEndpointEnv = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	// ServiceNameEnv overrides the service name the spans are reported with
+	ServiceNameEnv = "OTEL_SERVICE_NAME"
+
+	// instrumentationName is the name of the tracer all spans of KUDO are started with
+	instrumentationName = "github.com/kudobuilder/kudo"
+	shutdownTimeout     = 10 * time.Second
+)
+
+var (
+	mu       sync.Mutex
+	enabled  bool
+	rootCtx  = context.Background()
+	rootSpan trace.Span
+)
+
+// Setup registers an OTLP exporter for the spans if an endpoint is configured in the environment. It returns a
+// function that exports the remaining spans and stops the exporter, it has to be called before the process exits.
+// Without a configured endpoint tracing stays disabled and the returned function does nothing.
+func Setup(serviceName string) (func(), error) {
+	url := endpoint()
+	if url == "" {
+		return func() {}, nil
+	}
+	u, err := neturl.Parse(url)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return func() {}, fmt.Errorf("invalid OTLP endpoint %q, expected an absolute URL like http://localhost:4318", url)
+	}
+	if name := os.Getenv(ServiceNameEnv); name != "" {
+		serviceName = name
+	}
+
+	// the endpoint is passed explicitly, so that both variables are interpreted the same way by every SDK version
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host), otlptracehttp.WithURLPath(u.Path)}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return func() {}, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	mu.Lock()
+	enabled = true
+	mu.Unlock()
+
+	return func() {
+		// spans that can not be exported are dropped, tracing must never fail the traced work
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = provider.Shutdown(ctx)
+	}, nil
+}
+
+// endpoint returns the URL spans are exported to or an empty string if no endpoint is configured
+func endpoint() string {
+	if url := os.Getenv(TracesEndpointEnv); url != "" {
+		return url
+	}
+	if url := os.Getenv(EndpointEnv); url != "" {
+		return strings.TrimSuffix(url, "/") + "/v1/traces"
+	}
+	return ""
+}
+
+// Enabled returns true if spans are exported
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// Start starts a child span of the span in the context, the caller has to end it
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// StartRootSpan starts the span of the whole process run, e.g. of a kudoctl command. Code that has no context to
+// derive spans from, like the KUDO client and the package resolvers, starts its spans as children of it.
+func StartRootSpan(name string, attributes ...attribute.KeyValue) {
+	mu.Lock()
+	defer mu.Unlock()
+	rootCtx, rootSpan = Start(context.Background(), name, attributes...)
+}
+
+// EndRootSpan ends the span started by StartRootSpan, an error is recorded in its status
+func EndRootSpan(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if rootSpan == nil {
+		return
+	}
+	EndSpan(rootSpan, err)
+	rootCtx, rootSpan = context.Background(), nil
+}
+
+// RootContext returns the context of the root span or the background context if there is none
+func RootContext() context.Context {
+	mu.Lock()
+	defer mu.Unlock()
+	return rootCtx
+}
+
+// StartSpan starts a child span of the root span, the caller has to end it
+func StartSpan(name string, attributes ...attribute.KeyValue) trace.Span {
+	_, span := Start(RootContext(), name, attributes...)
+	return span
+}
+
+// EndSpan ends the span and records the error in its status, a nil error leaves the status unchanged
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// WrapTransport records a client span for every request. Requests without a span in their context become children
+// of the root span. The transport is returned as is if tracing is disabled. It matches the signature of
+// rest.Config.WrapTransport.
+func WrapTransport(rt http.RoundTripper) http.RoundTripper {
+	if !Enabled() {
+		return rt
+	}
+	return &rootTransport{base: otelhttp.NewTransport(rt,
+		otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+			return req.Method + " " + req.URL.Path
+		}),
+	)}
+}
+
+type rootTransport struct {
+	base http.RoundTripper
+}
+
+func (t *rootTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !trace.SpanContextFromContext(req.Context()).IsValid() {
+		if root := trace.SpanFromContext(RootContext()); root.SpanContext().IsValid() {
+			req = req.WithContext(trace.ContextWithSpan(req.Context(), root))
+		}
+	}
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEndpoint(t *testing.T) {
+	defer os.Unsetenv(EndpointEnv)
+	defer os.Unsetenv(TracesEndpointEnv)
+
+	assert.Equal(t, "", endpoint())
+
+	os.Setenv(EndpointEnv, "http://collector:4318/")
+	assert.Equal(t, "http://collector:4318/v1/traces", endpoint())
+
+	os.Setenv(TracesEndpointEnv, "http://collector:4318/custom")
+	assert.Equal(t, "http://collector:4318/custom", endpoint())
+}
+
+func TestSetup_Export(t *testing.T) {
+	var spans []*tracepb.Span
+	var service string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		received := &coltracepb.ExportTraceServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, received))
+		for _, rs := range received.ResourceSpans {
+			for _, a := range rs.Resource.Attributes {
+				if a.Key == "service.name" {
+					service = a.Value.GetStringValue()
+				}
+			}
+			for _, ils := range rs.InstrumentationLibrarySpans {
+				spans = append(spans, ils.Spans...)
+			}
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv(EndpointEnv, server.URL)
+	defer os.Unsetenv(EndpointEnv)
+	shutdown, err := Setup("kudo-test")
+	assert.NoError(t, err)
+	assert.True(t, Enabled())
+
+	StartRootSpan("kubectl-kudo install", attribute.String("kudo.namespace", "default"))
+	child := StartSpan("package.Resolve", attribute.String("kudo.package", "kafka"))
+	EndSpan(child, errors.New("unable to find package"))
+	EndRootSpan(nil)
+	shutdown()
+
+	assert.Equal(t, "kudo-test", service)
+	assert.Len(t, spans, 2)
+	resolve, root := spans[0], spans[1]
+	assert.Equal(t, "package.Resolve", resolve.Name)
+	assert.Equal(t, root.TraceId, resolve.TraceId)
+	assert.Equal(t, root.SpanId, resolve.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_ERROR, resolve.Status.Code)
+	assert.Equal(t, "unable to find package", resolve.Status.Message)
+	assert.Equal(t, "kudo.package", resolve.Attributes[0].Key)
+
+	assert.Equal(t, "kubectl-kudo install", root.Name)
+	assert.Empty(t, root.ParentSpanId)
+	assert.Equal(t, tracepb.Status_STATUS_CODE_UNSET, root.Status.Code)
+}
+
+func TestSetup_InvalidEndpoint(t *testing.T) {
+	os.Setenv(TracesEndpointEnv, "collector:4318")
+	defer os.Unsetenv(TracesEndpointEnv)
+
+	_, err := Setup("kudo-test")
+	assert.EqualError(t, err, `invalid OTLP endpoint "collector:4318", expected an absolute URL like http://localhost:4318`)
+}
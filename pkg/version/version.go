@@ -70,6 +70,16 @@ func (v *Version) CompareMajorMinor(o *Version) int {
 	return 0
 }
 
+// CompareRelease provides Compare results -1, 0, 1 for the major, minor and patch element of the semver, ignoring
+// the prerelease and metadata elements. A prerelease of a version therefore satisfies a minimum of that version,
+// e.g. 0.10.0-rc1 is version 0.10.0 or higher.
+func (v *Version) CompareRelease(o *Version) int {
+	if d := v.CompareMajorMinor(o); d != 0 {
+		return d
+	}
+	return compareSegment(v.Patch(), o.Patch())
+}
+
 // compares v1 against v2 resulting in -1, 0, 1 for less than, equal, greater than
 func compareSegment(v1, v2 int64) int {
 	if v1 < v2 {
@@ -27,6 +27,25 @@ func Test_validVersion(t *testing.T) {
 	}
 }
 
+func TestVersion_CompareRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		actual   *Version
+		expected *Version
+		val      int
+	}{
+		{"expect older patch", MustParse("0.10.1"), MustParse("0.10.0"), -1},
+		{"expect same version", MustParse("0.10.0"), MustParse("0.10.0"), 0},
+		{"prerelease is not a factor", MustParse("0.10.0-rc1"), MustParse("0.10.0"), 0},
+		{"expect newer minor", MustParse("0.9.5"), MustParse("0.10.0"), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected.CompareRelease(tt.actual), tt.val)
+		})
+	}
+}
+
 func TestClean(t *testing.T) {
 	tests := []struct {
 		name     string
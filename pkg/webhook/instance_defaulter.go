@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// InstanceDefaulterPath is the path the instance defaulting webhook is served at
+const InstanceDefaulterPath = "/mutate-kudo-dev-v1alpha1-instance"
+
+// InstanceDefaulter is a mutating admission webhook that defaults Instances server-side the same way kudoctl does on
+// the client, so that Instances created directly with kubectl, e.g. by GitOps tools, behave like the ones created with
+// kudoctl. It
+// - moves the values of deprecated parameters to the parameters replacing them when an Instance is created, upgraded
+//   or its parameters change. Deprecated parameters past their grace version are left to the InstanceValidator
+// - adds the operator label used to find the Instances of an Operator
+// - records a revision in the history of the Instance for changes not made by kudoctl, the controller attaches the
+//   plan triggered by the change to it
+//
+// The defaults of parameters that are not set are deliberately not saved in the Instance, the controller applies the
+// defaults of the current OperatorVersion whenever the Instance is executed, so that changed defaults reach existing
+// Instances on upgrades. No plan annotation is added either, the controller resolves the plan triggered by a change
+// from the parameters like it does for Instances updated by kudoctl. Instances are validated by the InstanceValidator.
+type InstanceDefaulter struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle defaults the Instance of the admission request
+func (d *InstanceDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	instance := &v1alpha1.Instance{}
+	if err := d.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old *v1alpha1.Instance
+	if req.Operation == admissionv1beta1.Update {
+		old = &v1alpha1.Instance{}
+		if err := d.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	ov := &v1alpha1.OperatorVersion{}
+	err := d.client.Get(ctx, types.NamespacedName{Name: instance.Spec.OperatorVersion.Name, Namespace: instance.OperatorVersionNamespace()}, ov)
+	switch {
+	case apierrors.IsNotFound(err):
+		// the operatorversion might be created after the instance, e.g. when all objects are applied at once
+		log.Printf("InstanceDefaulter: operatorversion %s of instance %s/%s not found, parameters are not defaulted",
+			instance.Spec.OperatorVersion.Name, instance.Namespace, instance.Name)
+		ov = nil
+	case err != nil:
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if err := defaultInstance(instance, old, ov, req.UserInfo.Username); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	marshaled, err := json.Marshal(instance)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectClient injects the client used to read the OperatorVersion of the Instance
+func (d *InstanceDefaulter) InjectClient(c client.Client) error {
+	d.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (d *InstanceDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// defaultInstance applies the defaults to the instance. Old is nil when the instance is created and ov is nil if the
+// operatorversion of the instance does not exist (yet).
+func defaultInstance(instance, old *v1alpha1.Instance, ov *v1alpha1.OperatorVersion, user string) error {
+	if ov != nil {
+		translateParameters(instance, old, ov)
+		if _, ok := instance.Labels[kudo.OperatorLabel]; !ok && ov.Spec.Operator.Name != "" {
+			if instance.Labels == nil {
+				instance.Labels = make(map[string]string)
+			}
+			instance.Labels[kudo.OperatorLabel] = ov.Spec.Operator.Name
+		}
+	}
+	return recordRevision(instance, old, user)
+}
+
+// translateParameters moves the values of deprecated parameters to the parameters replacing them. Instances are only
+// translated when they are created, upgraded or their parameters change, like they are validated by the
+// InstanceValidator. Deprecated parameters past their grace version are not translated, the validator rejects them.
+func translateParameters(instance, old *v1alpha1.Instance, ov *v1alpha1.OperatorVersion) {
+	if !parametersChanged(instance, old) {
+		return
+	}
+	translated, warnings, err := v1alpha1.TranslateDeprecatedParameters(ov, instance.Spec.Parameters)
+	if err != nil {
+		return
+	}
+	for _, w := range warnings {
+		log.Printf("InstanceDefaulter: instance %s/%s: %s", instance.Namespace, instance.Name, w)
+	}
+	if len(warnings) > 0 {
+		instance.Spec.Parameters = translated
+	}
+}
+
+// parametersChanged returns true if the instance is created, upgraded or its parameters change
+func parametersChanged(instance, old *v1alpha1.Instance) bool {
+	return old == nil || old.Spec.OperatorVersion.Name != instance.Spec.OperatorVersion.Name ||
+		len(v1alpha1.ParameterChanges(old.Spec.Parameters, instance.Spec.Parameters)) > 0
+}
+
+// recordRevision adds a revision to the history of the instance if its spec was changed by someone else than kudoctl.
+// kudoctl records its own revisions, so changes that come with a changed history are left alone.
+func recordRevision(instance, old *v1alpha1.Instance, user string) error {
+	revision := v1alpha1.InstanceRevision{
+		Time:            metav1.Now(),
+		User:            user,
+		OperatorVersion: instance.Spec.OperatorVersion.Name,
+	}
+	if old == nil {
+		if _, ok := instance.Annotations[v1alpha1.HistoryAnnotation]; ok {
+			return nil
+		}
+		revision.Action = "install"
+		revision.Parameters = v1alpha1.ParameterChanges(nil, instance.Spec.Parameters)
+		return instance.AddRevision(revision)
+	}
+
+	upgrade := instance.Spec.OperatorVersion.Name != old.Spec.OperatorVersion.Name
+	changes := v1alpha1.ParameterChanges(old.Spec.Parameters, instance.Spec.Parameters)
+	if instance.Annotations[v1alpha1.HistoryAnnotation] != old.Annotations[v1alpha1.HistoryAnnotation] || (!upgrade && len(changes) == 0) {
+		return nil
+	}
+	revision.Action = "update"
+	if upgrade {
+		revision.Action = "upgrade"
+	}
+	revision.Parameters = changes
+	return instance.AddRevision(revision)
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/scheme"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func operatorVersion() *v1alpha1.OperatorVersion {
+	return &v1alpha1.OperatorVersion{
+		ObjectMeta: metav1.ObjectMeta{Name: "zk-1.0", Namespace: "default"},
+		Spec: v1alpha1.OperatorVersionSpec{
+			Operator: v1.ObjectReference{Name: "zk"},
+			Parameters: []v1alpha1.Parameter{
+				{Name: "SIZE", Default: kudo.String("3")},
+				{Name: "MEMORY", Default: kudo.String("1Gi")},
+				{Name: "PASSWORD", Required: true},
+				{Name: "OPTIONAL"},
+			},
+		},
+	}
+}
+
+func instance(ov string, parameters map[string]string) *v1alpha1.Instance {
+	return &v1alpha1.Instance{
+		TypeMeta:   metav1.TypeMeta{Kind: "Instance", APIVersion: "kudo.dev/v1alpha1"},
+		ObjectMeta: metav1.ObjectMeta{Name: "zk", Namespace: "default"},
+		Spec: v1alpha1.InstanceSpec{
+			OperatorVersion: v1.ObjectReference{Name: ov},
+			Parameters:      parameters,
+		},
+	}
+}
+
+func raw(t *testing.T, obj runtime.Object) runtime.RawExtension {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime.RawExtension{Raw: b}
+}
+
+func TestDefaultInstance_Create(t *testing.T) {
+	i := instance("zk-1.0", map[string]string{"PASSWORD": "secret", "SIZE": "5"})
+
+	assert.NoError(t, defaultInstance(i, nil, operatorVersion(), "alice"))
+
+	assert.Equal(t, map[string]string{"PASSWORD": "secret", "SIZE": "5"}, i.Spec.Parameters, "defaults are not saved in the instance")
+	assert.Equal(t, "zk", i.Labels[kudo.OperatorLabel])
+	history, err := i.History()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, "install", history[0].Action)
+	assert.Equal(t, "alice", history[0].User)
+	assert.Equal(t, "zk-1.0", history[0].OperatorVersion)
+	assert.Equal(t, 2, len(history[0].Parameters))
+}
+
+func TestDefaultInstance_CreatedByKudoctl(t *testing.T) {
+	i := instance("zk-1.0", map[string]string{"PASSWORD": "secret"})
+	i.Labels = map[string]string{kudo.OperatorLabel: "zookeeper"}
+	assert.NoError(t, i.AddRevision(v1alpha1.InstanceRevision{Action: "install", User: "bob"}))
+
+	assert.NoError(t, defaultInstance(i, nil, operatorVersion(), "alice"))
+
+	assert.Equal(t, "zookeeper", i.Labels[kudo.OperatorLabel])
+	history, _ := i.History()
+	assert.Equal(t, 1, len(history))
+	assert.Equal(t, "bob", history[0].User)
+}
+
+func TestDefaultInstance_Update(t *testing.T) {
+	old := instance("zk-1.0", map[string]string{"PASSWORD": "secret"})
+	assert.NoError(t, old.AddRevision(v1alpha1.InstanceRevision{Action: "install"}))
+
+	tests := []struct {
+		name      string
+		ov        string
+		params    map[string]string
+		revisions int
+		action    string
+	}{
+		{name: "unchanged spec", ov: "zk-1.0", params: map[string]string{"PASSWORD": "secret"}, revisions: 1},
+		{name: "changed parameter", ov: "zk-1.0", params: map[string]string{"PASSWORD": "other"}, revisions: 2, action: "update"},
+		{name: "changed operatorversion", ov: "zk-2.0", params: map[string]string{"PASSWORD": "secret"}, revisions: 2, action: "upgrade"},
+	}
+
+	for _, tt := range tests {
+		i := old.DeepCopy()
+		i.Spec.OperatorVersion.Name = tt.ov
+		i.Spec.Parameters = tt.params
+
+		assert.NoError(t, defaultInstance(i, old, operatorVersion(), "alice"), tt.name)
+
+		history, _ := i.History()
+		assert.Equal(t, tt.revisions, len(history), tt.name)
+		if tt.action != "" {
+			assert.Equal(t, tt.action, history[len(history)-1].Action, tt.name)
+			assert.Equal(t, tt.ov, history[len(history)-1].OperatorVersion, tt.name)
+		}
+		assert.Equal(t, tt.params, i.Spec.Parameters, tt.name)
+	}
+}
+
+func TestDefaultInstance_DeprecatedParameters(t *testing.T) {
+	ov := operatorVersion()
+	ov.Spec.Version = "1.0.0"
+	ov.Spec.Parameters = append(ov.Spec.Parameters,
+		v1alpha1.Parameter{Name: "NODES", Default: kudo.String("3"), Deprecated: &v1alpha1.ParameterDeprecation{ReplacedBy: "SIZE", GraceVersion: "2.0.0"}})
+	old := instance("zk-0.9", map[string]string{"PASSWORD": "secret", "NODES": "5"})
+
+	// the deprecated parameter is renamed when the instance is upgraded
+	i := old.DeepCopy()
+	i.Spec.OperatorVersion.Name = "zk-1.0"
+	assert.NoError(t, defaultInstance(i, old, ov, "alice"))
+	assert.Equal(t, map[string]string{"PASSWORD": "secret", "SIZE": "5"}, i.Spec.Parameters)
+
+	// and left to the validator once the grace version is reached
+	ov.Spec.Version = "2.0.0"
+	i = old.DeepCopy()
+	i.Spec.OperatorVersion.Name = "zk-2.0"
+	assert.NoError(t, defaultInstance(i, old, ov, "alice"))
+	assert.Equal(t, old.Spec.Parameters, i.Spec.Parameters)
+}
+
+func TestInstanceDefaulter_Handle(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		objs    []runtime.Object
+		params  map[string]string
+		allowed bool
+		patched bool
+	}{
+		{name: "defaults instance", objs: []runtime.Object{operatorVersion()}, params: map[string]string{"PASSWORD": "secret"}, allowed: true, patched: true},
+		{name: "leaves validation to the validator", objs: []runtime.Object{operatorVersion()}, allowed: true, patched: true},
+		{name: "allows missing operatorversion", allowed: true, patched: true},
+	}
+
+	for _, tt := range tests {
+		d := &InstanceDefaulter{}
+		assert.NoError(t, d.InjectClient(fake.NewFakeClientWithScheme(scheme.Scheme, tt.objs...)))
+		assert.NoError(t, d.InjectDecoder(decoder))
+
+		resp := d.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+			Operation: admissionv1beta1.Create,
+			Object:    raw(t, instance("zk-1.0", tt.params)),
+			UserInfo:  authenticationv1.UserInfo{Username: "alice"},
+		}})
+
+		assert.Equal(t, tt.allowed, resp.Allowed, tt.name)
+		assert.Equal(t, tt.patched, len(resp.Patches) > 0, tt.name)
+	}
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// InstanceProtectorPath is the path the instance protection webhook is served at
+const InstanceProtectorPath = "/validate-kudo-dev-v1alpha1-instance"
+
+// InstanceProtector is a validating admission webhook that rejects the deletion of Instances annotated with
+// v1alpha1.ProtectedAnnotation. The protection has to be removed before a protected Instance can be deleted, kudoctl
+// does so when the uninstall is forced with --force-protected.
+type InstanceProtector struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle denies the deletion of protected Instances and allows all other requests
+func (p *InstanceProtector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Delete {
+		return admission.Allowed("")
+	}
+
+	instance := &v1alpha1.Instance{}
+	if len(req.OldObject.Raw) > 0 {
+		if err := p.decoder.DecodeRaw(req.OldObject, instance); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	} else {
+		// older API servers do not send the deleted object
+		err := p.client.Get(ctx, types.NamespacedName{Name: req.Name, Namespace: req.Namespace}, instance)
+		switch {
+		case apierrors.IsNotFound(err):
+			return admission.Allowed("")
+		case err != nil:
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+
+	if err := checkProtection(instance); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// InjectClient injects the client used to read the Instance if the request does not contain it
+func (p *InstanceProtector) InjectClient(c client.Client) error {
+	p.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (p *InstanceProtector) InjectDecoder(decoder *admission.Decoder) error {
+	p.decoder = decoder
+	return nil
+}
+
+// checkProtection returns an error if the instance is protected from deletion
+func checkProtection(instance *v1alpha1.Instance) error {
+	if !instance.IsProtected() {
+		return nil
+	}
+	return fmt.Errorf("instance %s/%s is protected from deletion, remove the %s annotation or uninstall it with 'kubectl kudo uninstall --instance %s --force-protected'",
+		instance.Namespace, instance.Name, v1alpha1.ProtectedAnnotation, instance.Name)
+}
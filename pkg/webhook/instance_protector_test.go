@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/scheme"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func protectedInstance() *v1alpha1.Instance {
+	i := instance("zk-1.0", nil)
+	i.Annotations = map[string]string{v1alpha1.ProtectedAnnotation: "true"}
+	return i
+}
+
+func TestInstanceProtector_Handle(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		objs      []runtime.Object
+		operation admissionv1beta1.Operation
+		old       *v1alpha1.Instance
+		allowed   bool
+	}{
+		{name: "denies deleting protected instance", operation: admissionv1beta1.Delete, old: protectedInstance(), allowed: false},
+		{name: "allows deleting unprotected instance", operation: admissionv1beta1.Delete, old: instance("zk-1.0", nil), allowed: true},
+		{name: "reads protected instance without old object", objs: []runtime.Object{protectedInstance()}, operation: admissionv1beta1.Delete, allowed: false},
+		{name: "allows deleting missing instance", operation: admissionv1beta1.Delete, allowed: true},
+		{name: "allows updating protected instance", operation: admissionv1beta1.Update, old: protectedInstance(), allowed: true},
+	}
+
+	for _, tt := range tests {
+		p := &InstanceProtector{}
+		assert.NoError(t, p.InjectClient(fake.NewFakeClientWithScheme(scheme.Scheme, tt.objs...)))
+		assert.NoError(t, p.InjectDecoder(decoder))
+
+		req := admissionv1beta1.AdmissionRequest{Operation: tt.operation, Name: "zk", Namespace: "default"}
+		if tt.old != nil {
+			req.OldObject = raw(t, tt.old)
+		}
+		resp := p.Handle(context.TODO(), admission.Request{AdmissionRequest: req})
+
+		assert.Equal(t, tt.allowed, resp.Allowed, tt.name)
+	}
+}
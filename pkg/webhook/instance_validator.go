@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// InstanceValidatorPath is the path the instance validation webhook is served at
+const InstanceValidatorPath = "/validate-kudo-dev-v1alpha1-instance-spec"
+
+// InstanceValidator is a validating admission webhook that checks Instances against their OperatorVersion. It is
+// registered with the failure policy Fail, so that invalid Instances are not admitted while the manager is
+// unavailable. It
+// - rejects deprecated parameters once the operator reached their grace version when an Instance is created, upgraded
+//   or its parameters change
+// - rejects Instances that miss required parameters or whose parameter values do not conform to the type and schema
+//   of their parameter when an Instance is created, upgraded or its parameters change
+// - rejects a plan requested for the next parameter change that does not exist in the OperatorVersion
+// - rejects upgrades of Instances to an OperatorVersion declaring pre-upgrade checks unless the checks passed recently
+type InstanceValidator struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// Handle denies invalid Instances and allows all other requests
+func (v *InstanceValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1beta1.Create && req.Operation != admissionv1beta1.Update {
+		return admission.Allowed("")
+	}
+
+	instance := &v1alpha1.Instance{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old *v1alpha1.Instance
+	if req.Operation == admissionv1beta1.Update {
+		old = &v1alpha1.Instance{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+
+	ov := &v1alpha1.OperatorVersion{}
+	err := v.client.Get(ctx, types.NamespacedName{Name: instance.Spec.OperatorVersion.Name, Namespace: instance.OperatorVersionNamespace()}, ov)
+	switch {
+	case apierrors.IsNotFound(err) && checksPreUpgrade(instance, old):
+		// the pre-upgrade checks of a missing operatorversion are unknown
+		return admission.Denied(fmt.Sprintf("operatorversion %s of the upgrade does not exist", instance.Spec.OperatorVersion.Name))
+	case apierrors.IsNotFound(err):
+		// the operatorversion might be created after the instance, e.g. when all objects are applied at once
+		return admission.Allowed("")
+	case err != nil:
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	if err := validateInstance(instance, old, ov, time.Now()); err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// InjectClient injects the client used to read the OperatorVersion of the Instance
+func (v *InstanceValidator) InjectClient(c client.Client) error {
+	v.client = c
+	return nil
+}
+
+// InjectDecoder injects the decoder of the admission requests
+func (v *InstanceValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// validateInstance returns an error if the instance is invalid for its operatorversion. Old is nil when the instance is
+// created.
+func validateInstance(instance, old *v1alpha1.Instance, ov *v1alpha1.OperatorVersion, now time.Time) error {
+	if parametersChanged(instance, old) {
+		if err := validateParameters(instance.Spec.Parameters, ov); err != nil {
+			return err
+		}
+	}
+	if err := validateUpdatePlan(instance, old, ov); err != nil {
+		return err
+	}
+	if checksPreUpgrade(instance, old) {
+		return validatePreUpgradeChecks(old, ov, now)
+	}
+	return nil
+}
+
+// validateParameters returns an error if the parameters of an instance that is created, upgraded or whose parameters
+// change set deprecated parameters past their grace version, miss required parameters or do not conform to the type
+// and schema of their parameter. Deprecated parameters are validated with the names replacing them, as the defaulter
+// renaming them might have been skipped.
+func validateParameters(params map[string]string, ov *v1alpha1.OperatorVersion) error {
+	translated, _, err := v1alpha1.TranslateDeprecatedParameters(ov, params)
+	if err != nil {
+		return err
+	}
+	return v1alpha1.ValidateParameters(ov, translated)
+}
+
+// validateUpdatePlan returns an error if the plan requested with v1alpha1.UpdatePlanAnnotation does not exist in the
+// operatorversion. A requested plan is only validated when it changes, so that unrelated updates are not rejected.
+func validateUpdatePlan(instance, old *v1alpha1.Instance, ov *v1alpha1.OperatorVersion) error {
+	plan := instance.Annotations[v1alpha1.UpdatePlanAnnotation]
+	if plan == "" || (old != nil && old.Annotations[v1alpha1.UpdatePlanAnnotation] == plan) {
+		return nil
+	}
+	if _, ok := ov.Spec.Plans[plan]; ok {
+		return nil
+	}
+	plans := make([]string, 0, len(ov.Spec.Plans))
+	for name := range ov.Spec.Plans {
+		plans = append(plans, name)
+	}
+	sort.Strings(plans)
+	return fmt.Errorf("plan %s requested for the update does not exist in operatorversion %s, available plans: %s", plan, ov.Name, strings.Join(plans, ", "))
+}
+
+// checksPreUpgrade returns true if the instance is upgraded and the upgrade does not skip the pre-upgrade checks
+func checksPreUpgrade(instance, old *v1alpha1.Instance) bool {
+	return old != nil && old.Spec.OperatorVersion.Name != instance.Spec.OperatorVersion.Name && !skipsPreUpgradeChecks(instance)
+}
+
+// skipsPreUpgradeChecks returns true if the upgrade of the instance skips the pre-upgrade checks with the
+// v1alpha1.SkipPreUpgradeChecksAnnotation, or the instance is owned by another KUDO instance. Owned instances are
+// upgraded by the plans of their owner and are not checked.
+func skipsPreUpgradeChecks(instance *v1alpha1.Instance) bool {
+	if instance.Annotations[v1alpha1.SkipPreUpgradeChecksAnnotation] == "true" {
+		return true
+	}
+	owner := metav1.GetControllerOf(instance)
+	return owner != nil && owner.APIVersion == v1alpha1.SchemeGroupVersion.String() && owner.Kind == "Instance"
+}
+
+// validatePreUpgradeChecks returns an error unless the checks of the operatorversion the instance is upgraded to passed
+// within v1alpha1.PreUpgradeCheckValidity. Old is the instance before the upgrade.
+func validatePreUpgradeChecks(old *v1alpha1.Instance, ov *v1alpha1.OperatorVersion, now time.Time) error {
+	if len(ov.Spec.PreUpgradeChecks) == 0 {
+		return nil
+	}
+
+	skipHint := fmt.Sprintf("upgrade with kudoctl to execute them or set the annotation %s to \"true\" to skip them", v1alpha1.SkipPreUpgradeChecksAnnotation)
+	report := old.Status.PreUpgradeCheck
+	switch {
+	case report == nil || report.OperatorVersion != ov.Name:
+		return fmt.Errorf("pre-upgrade checks of operatorversion %s were not executed, %s", ov.Name, skipHint)
+	case !report.Passed():
+		return fmt.Errorf("pre-upgrade checks of operatorversion %s failed, %s", ov.Name, skipHint)
+	case now.Sub(report.CompletedAt.Time) > v1alpha1.PreUpgradeCheckValidity:
+		return fmt.Errorf("pre-upgrade checks of operatorversion %s passed more than %v ago, %s", ov.Name, v1alpha1.PreUpgradeCheckValidity, skipHint)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kudobuilder/kudo/pkg/apis/kudo/v1alpha1"
+	"github.com/kudobuilder/kudo/pkg/client/clientset/versioned/scheme"
+	"github.com/kudobuilder/kudo/pkg/util/kudo"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apiextv1beta1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func checkedOperatorVersion() *v1alpha1.OperatorVersion {
+	ov := operatorVersion()
+	ov.Name = "zk-2.0"
+	ov.Spec.PreUpgradeChecks = []v1alpha1.UpgradeCheck{{Name: "version", VersionGap: &v1alpha1.VersionGapCheck{MinVersion: "1.0.0"}}}
+	return ov
+}
+
+func TestValidatePreUpgradeChecks(t *testing.T) {
+	ov := checkedOperatorVersion()
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	report := func(ov string, passed bool, completedAt time.Time) *v1alpha1.PreUpgradeCheckStatus {
+		return &v1alpha1.PreUpgradeCheckStatus{
+			OperatorVersion: ov,
+			CompletedAt:     metav1.NewTime(completedAt),
+			Results:         []v1alpha1.UpgradeCheckResult{{Name: "version", Passed: passed}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		report *v1alpha1.PreUpgradeCheckStatus
+		err    string
+	}{
+		{name: "not executed", err: "pre-upgrade checks of operatorversion zk-2.0 were not executed, upgrade with kudoctl to execute them or set the annotation kudo.dev/skip-pre-upgrade-checks to \"true\" to skip them"},
+		{name: "executed for another version", report: report("zk-3.0", true, now), err: "pre-upgrade checks of operatorversion zk-2.0 were not executed, upgrade with kudoctl to execute them or set the annotation kudo.dev/skip-pre-upgrade-checks to \"true\" to skip them"},
+		{name: "failed", report: report("zk-2.0", false, now), err: "pre-upgrade checks of operatorversion zk-2.0 failed, upgrade with kudoctl to execute them or set the annotation kudo.dev/skip-pre-upgrade-checks to \"true\" to skip them"},
+		{name: "stale", report: report("zk-2.0", true, now.Add(-time.Hour)), err: "pre-upgrade checks of operatorversion zk-2.0 passed more than 10m0s ago, upgrade with kudoctl to execute them or set the annotation kudo.dev/skip-pre-upgrade-checks to \"true\" to skip them"},
+		{name: "passed", report: report("zk-2.0", true, now.Add(-time.Minute))},
+	}
+
+	for _, tt := range tests {
+		old := instance("zk-1.0", nil)
+		old.Status.PreUpgradeCheck = tt.report
+
+		err := validatePreUpgradeChecks(old, ov, now)
+		if tt.err != "" {
+			assert.EqualError(t, err, tt.err, tt.name)
+		} else {
+			assert.NoError(t, err, tt.name)
+		}
+	}
+
+	// operatorversions without checks do not require a report
+	assert.NoError(t, validatePreUpgradeChecks(instance("zk-1.0", nil), operatorVersion(), now))
+}
+
+func TestValidateInstance_MissingRequiredParameter(t *testing.T) {
+	err := validateInstance(instance("zk-1.0", nil), nil, operatorVersion(), time.Now())
+	assert.EqualError(t, err, "missing required parameters: PASSWORD")
+
+	// unrelated updates of an instance missing required parameters are allowed
+	old := instance("zk-1.0", nil)
+	i := old.DeepCopy()
+	i.Labels = map[string]string{"team": "data"}
+	assert.NoError(t, validateInstance(i, old, operatorVersion(), time.Now()))
+}
+
+func TestValidateInstance_InvalidParameter(t *testing.T) {
+	ov := operatorVersion()
+	ov.Spec.Parameters = append(ov.Spec.Parameters, v1alpha1.Parameter{
+		Name:   "SERVERS",
+		Type:   v1alpha1.ArrayParameterType,
+		Schema: &apiextv1beta1.JSONSchemaProps{Items: &apiextv1beta1.JSONSchemaPropsOrArray{Schema: &apiextv1beta1.JSONSchemaProps{Type: "string"}}},
+	})
+
+	err := validateInstance(instance("zk-1.0", map[string]string{"PASSWORD": "secret", "SERVERS": "[a, 1]"}), nil, ov, time.Now())
+	assert.EqualError(t, err, "parameter SERVERS is invalid: value[1] is not of type string")
+
+	// unrelated updates of an instance with invalid parameters are allowed
+	old := instance("zk-1.0", map[string]string{"PASSWORD": "secret", "SERVERS": "[a, 1]"})
+	i := old.DeepCopy()
+	i.Labels = map[string]string{"team": "data"}
+	assert.NoError(t, validateInstance(i, old, ov, time.Now()))
+}
+
+func TestValidateInstance_DeprecatedParameters(t *testing.T) {
+	ov := operatorVersion()
+	ov.Spec.Version = "1.0.0"
+	ov.Spec.Parameters = append(ov.Spec.Parameters,
+		v1alpha1.Parameter{Name: "NODES", Default: kudo.String("3"), Deprecated: &v1alpha1.ParameterDeprecation{ReplacedBy: "SIZE", GraceVersion: "2.0.0"}})
+	old := instance("zk-0.9", map[string]string{"PASSWORD": "secret", "NODES": "5"})
+
+	// deprecated parameters are allowed within the grace version
+	i := old.DeepCopy()
+	i.Spec.OperatorVersion.Name = "zk-1.0"
+	assert.NoError(t, validateInstance(i, old, ov, time.Now()))
+
+	// and rejected once it is reached
+	ov.Spec.Version = "2.0.0"
+	i = old.DeepCopy()
+	i.Spec.OperatorVersion.Name = "zk-2.0"
+	assert.EqualError(t, validateInstance(i, old, ov, time.Now()), "parameter NODES is no longer supported since version 2.0.0, use parameter SIZE instead")
+
+	// unless the spec is unchanged
+	assert.NoError(t, validateInstance(old.DeepCopy(), old, ov, time.Now()))
+}
+
+func TestValidateInstance_UpdatePlan(t *testing.T) {
+	ov := operatorVersion()
+	ov.Spec.Plans = map[string]v1alpha1.Plan{"deploy": {}, "rolling-restart": {}}
+	old := instance("zk-1.0", map[string]string{"PASSWORD": "secret"})
+
+	i := old.DeepCopy()
+	i.Spec.Parameters = map[string]string{"PASSWORD": "other"}
+	i.Annotations = map[string]string{v1alpha1.UpdatePlanAnnotation: "rolling-restart"}
+	assert.NoError(t, validateInstance(i, old, ov, time.Now()))
+
+	i.Annotations[v1alpha1.UpdatePlanAnnotation] = "restart"
+	err := validateInstance(i, old, ov, time.Now())
+	assert.EqualError(t, err, "plan restart requested for the update does not exist in operatorversion zk-1.0, available plans: deploy, rolling-restart")
+
+	// a requested plan that is not changed is not validated again
+	old.Annotations = map[string]string{v1alpha1.UpdatePlanAnnotation: "restart"}
+	assert.NoError(t, validateInstance(i, old, ov, time.Now()))
+}
+
+func TestInstanceValidator_Handle(t *testing.T) {
+	decoder, err := admission.NewDecoder(scheme.Scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller := true
+	instanceOwner := metav1.OwnerReference{APIVersion: "kudo.dev/v1alpha1", Kind: "Instance", Name: "parent", UID: "1", Controller: &controller}
+	otherOwner := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "parent", UID: "1", Controller: &controller}
+	valid := map[string]string{"PASSWORD": "secret"}
+
+	tests := []struct {
+		name        string
+		objs        []runtime.Object
+		ov          string
+		annotations map[string]string
+		owner       *metav1.OwnerReference
+		create      bool
+		params      map[string]string
+		allowed     bool
+	}{
+		{name: "denies upgrade without checks", objs: []runtime.Object{checkedOperatorVersion()}, ov: "zk-2.0", params: valid, allowed: false},
+		{name: "allows skipped checks", objs: []runtime.Object{checkedOperatorVersion()}, ov: "zk-2.0", annotations: map[string]string{v1alpha1.SkipPreUpgradeChecksAnnotation: "true"}, params: valid, allowed: true},
+		{name: "allows instance owned by an instance", objs: []runtime.Object{checkedOperatorVersion()}, ov: "zk-2.0", owner: &instanceOwner, params: valid, allowed: true},
+		{name: "denies instance owned by something else", objs: []runtime.Object{checkedOperatorVersion()}, ov: "zk-2.0", owner: &otherOwner, params: valid, allowed: false},
+		{name: "denies upgrade to missing operatorversion", ov: "zk-2.0", params: valid, allowed: false},
+		{name: "allows skipped checks of missing operatorversion", ov: "zk-2.0", annotations: map[string]string{v1alpha1.SkipPreUpgradeChecksAnnotation: "true"}, params: valid, allowed: true},
+		{name: "allows update without upgrade", ov: "zk-1.0", params: valid, allowed: true},
+		{name: "allows valid instance", objs: []runtime.Object{operatorVersion()}, ov: "zk-1.0", create: true, params: valid, allowed: true},
+		{name: "denies missing required parameters", objs: []runtime.Object{operatorVersion()}, ov: "zk-1.0", create: true, allowed: false},
+		{name: "allows instance of missing operatorversion", ov: "zk-1.0", create: true, allowed: true},
+	}
+
+	for _, tt := range tests {
+		v := &InstanceValidator{}
+		assert.NoError(t, v.InjectClient(fake.NewFakeClientWithScheme(scheme.Scheme, tt.objs...)))
+		assert.NoError(t, v.InjectDecoder(decoder))
+
+		i := instance(tt.ov, tt.params)
+		i.Annotations = tt.annotations
+		if tt.owner != nil {
+			i.OwnerReferences = []metav1.OwnerReference{*tt.owner}
+		}
+		req := admissionv1beta1.AdmissionRequest{Operation: admissionv1beta1.Create, Object: raw(t, i)}
+		if !tt.create {
+			req.Operation, req.OldObject = admissionv1beta1.Update, raw(t, instance("zk-1.0", tt.params))
+		}
+		resp := v.Handle(context.TODO(), admission.Request{AdmissionRequest: req})
+
+		assert.Equal(t, tt.allowed, resp.Allowed, tt.name)
+	}
+}